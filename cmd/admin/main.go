@@ -0,0 +1,171 @@
+// Command admin runs maintenance commands against the database configured
+// by DATABASE_URL:
+//
+//	admin backup [-user <id>] [-o <file>]    write an archive to file, or stdout
+//	admin restore <file>                     read an archive back, - for stdin
+//	admin seed [-users <n>] [-days <n>] ...  create demo data for development
+//	admin rotate-keys                        re-encrypt with the current field key
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	"github.com/pacerclub/zebra-backend/internal/backup"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/logging"
+	"github.com/pacerclub/zebra-backend/internal/seed"
+)
+
+// commands are the subcommands, each parsing its own arguments
+var commands = map[string]func(ctx context.Context, args []string) error{
+	"backup":      runBackup,
+	"restore":     runRestore,
+	"seed":        runSeed,
+	"rotate-keys": runRotateKeys,
+}
+
+func main() {
+	envErr := godotenv.Load()
+
+	// Logs go to stderr, keeping stdout for archives
+	level := os.Getenv("LOG_LEVEL")
+	if level == "" {
+		level = "info"
+	}
+	if err := logging.Setup(os.Stderr, level, "text"); err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid logging configuration:", err)
+		os.Exit(1)
+	}
+	if envErr != nil {
+		slog.Debug("No .env file found")
+	}
+
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		fmt.Fprintln(os.Stderr, "Usage: admin backup [-user <id>] [-o <file>]")
+		fmt.Fprintln(os.Stderr, "       admin restore <file>")
+		fmt.Fprintln(os.Stderr, "       admin seed [-users <n>] [-projects <n>] [-days <n>] [-sessions <n>] [-seed <n>]")
+		fmt.Fprintln(os.Stderr, "       admin rotate-keys")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := fieldcrypt.LoadEnv(); err != nil {
+		slog.Error("Invalid field encryption keys", "error", err)
+		os.Exit(1)
+	}
+	if err := db.InitDB(db.PoolConfig{}); err != nil {
+		slog.Error("Failed to initialize database", "error", err)
+		os.Exit(1)
+	}
+	err := commands[os.Args[1]](ctx, os.Args[2:])
+	db.CloseDB()
+	if err != nil {
+		slog.Error("Command failed", "command", os.Args[1], "error", err)
+		os.Exit(1)
+	}
+}
+
+func runBackup(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	user := flags.String("user", "", "back up only the data of the user with this ID")
+	output := flags.String("o", "-", "file to write the archive to, - for stdout")
+	flags.Parse(args)
+
+	var userID *uuid.UUID
+	if *user != "" {
+		id, err := uuid.Parse(*user)
+		if err != nil {
+			return fmt.Errorf("invalid user ID: %w", err)
+		}
+		userID = &id
+	}
+
+	if *output == "-" {
+		return backup.Backup(ctx, os.Stdout, userID)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := backup.Backup(ctx, f, userID); err != nil {
+		f.Close()
+		os.Remove(*output)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	slog.Info("Backup written", "file", *output)
+	return nil
+}
+
+func runRestore(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("restore takes the archive's file name")
+	}
+
+	var r io.Reader = os.Stdin
+	if name := flags.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	manifest, counts, err := backup.Restore(ctx, r)
+	if err != nil {
+		return err
+	}
+	for _, table := range manifest.Tables {
+		slog.Info("Restored table", "table", table, "rows", counts[table])
+	}
+	slog.Info("Backup restored", "created_at", manifest.CreatedAt, "schema_version", manifest.SchemaVersion)
+	return nil
+}
+
+func runSeed(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	opts := seed.Options{}
+	flags.IntVar(&opts.Users, "users", 5, "number of demo users")
+	flags.StringVar(&opts.EmailDomain, "domain", "example.com", "email domain of the demo users")
+	flags.StringVar(&opts.Password, "password", "password", "password of every demo user")
+	flags.IntVar(&opts.Projects, "projects", 5, "projects per user")
+	flags.IntVar(&opts.Days, "days", 90, "days of history per user")
+	flags.IntVar(&opts.SessionsPerDay, "sessions", 6, "average sessions per working day")
+	flags.Int64Var(&opts.Seed, "seed", time.Now().UnixNano(), "random seed, for reproducible data")
+	flags.Parse(args)
+	if opts.Users < 0 || opts.Projects < 0 || opts.Days < 0 || opts.SessionsPerDay < 1 {
+		return fmt.Errorf("volumes must not be negative, and -sessions at least 1")
+	}
+
+	result, err := seed.Run(ctx, opts)
+	slog.Info("Seeded demo data", "users", result.Users, "skipped_users", result.Skipped,
+		"projects", result.Projects, "sessions", result.Sessions, "seed", opts.Seed)
+	return err
+}
+
+func runRotateKeys(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("rotate-keys", flag.ExitOnError)
+	flags.Parse(args)
+	if !fieldcrypt.Enabled() {
+		return fmt.Errorf("field encryption is not configured")
+	}
+	return fieldcrypt.Rotate(ctx)
+}
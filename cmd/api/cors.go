@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/pacerclub/zebra-backend/internal/corspolicy"
+	"github.com/pacerclub/zebra-backend/internal/routes"
+)
+
+// corsPolicy is the CORS policy of the server. The web app's origins in
+// ALLOWED_ORIGINS and the browser extensions in EXTENSION_ORIGINS may call
+// the API with credentials.
+func corsPolicy() corspolicy.Policy {
+	list := envList("ALLOWED_ORIGINS")
+	if len(list) == 0 {
+		list = routes.DefaultOrigins
+	}
+	origins, err := corspolicy.ParseOrigins(append(list, envList("EXTENSION_ORIGINS")...))
+	if err != nil {
+		fatal("Invalid ALLOWED_ORIGINS or EXTENSION_ORIGINS", "error", err)
+	}
+	return routes.CORSPolicy(origins)
+}
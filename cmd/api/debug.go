@@ -0,0 +1,31 @@
+package main
+
+import (
+	"expvar"
+	"runtime"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/opstats"
+)
+
+// debugRoutes serves the pprof profiles under /debug/pprof and the expvar
+// variables at /debug/vars to admins, for profiling a running server.
+// Profiles are fetched with the admin's bearer token, as in
+// curl -H "Authorization: Bearer $TOKEN" .../debug/pprof/heap > heap.pb.gz
+func debugRoutes(r chi.Router) {
+	expvar.Publish("goroutines", expvar.Func(func() any {
+		return runtime.NumGoroutine()
+	}))
+	expvar.Publish("db_pool", expvar.Func(func() any {
+		return opstats.PoolStats()
+	}))
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Middleware)
+		r.Use(auth.RequireScopes)
+		r.Use(auth.RequireAdmin)
+		r.Mount("/debug", middleware.Profiler())
+	})
+}
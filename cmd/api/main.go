@@ -1,96 +1,464 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
 	"github.com/joho/godotenv"
-	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/audit"
+	"github.com/pacerclub/zebra-backend/internal/background"
+	"github.com/pacerclub/zebra-backend/internal/billing"
 	"github.com/pacerclub/zebra-backend/internal/db"
-	"github.com/pacerclub/zebra-backend/internal/handlers"
+	"github.com/pacerclub/zebra-backend/internal/errorreport"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/grpcapi"
+	"github.com/pacerclub/zebra-backend/internal/integrations"
+	"github.com/pacerclub/zebra-backend/internal/live"
+	"github.com/pacerclub/zebra-backend/internal/logging"
+	"github.com/pacerclub/zebra-backend/internal/mail"
+	"github.com/pacerclub/zebra-backend/internal/maintenance"
+	"github.com/pacerclub/zebra-backend/internal/notify"
+	"github.com/pacerclub/zebra-backend/internal/onboarding"
+	"github.com/pacerclub/zebra-backend/internal/outbox"
+	"github.com/pacerclub/zebra-backend/internal/ratelimit"
+	"github.com/pacerclub/zebra-backend/internal/reminders"
+	"github.com/pacerclub/zebra-backend/internal/routes"
+	"github.com/pacerclub/zebra-backend/internal/runaway"
+	"github.com/pacerclub/zebra-backend/internal/scheduler"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/storage"
+	"github.com/pacerclub/zebra-backend/internal/uploads"
+	"github.com/pacerclub/zebra-backend/internal/usage"
+	"github.com/pacerclub/zebra-backend/internal/webhooks"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
 	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found")
+	envErr := godotenv.Load()
+
+	level, format := os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT")
+	if level == "" {
+		level = "info"
+	}
+	if format == "" {
+		format = "json"
+	}
+	if err := logging.Setup(os.Stderr, level, format); err != nil {
+		fatal("Invalid logging configuration", "error", err)
+	}
+	if envErr != nil {
+		slog.Info("No .env file found")
+	}
+
+	// Report panics and server errors to Sentry or GlitchTip
+	if err := errorreport.Setup(os.Getenv("SENTRY_DSN"), os.Getenv("SENTRY_ENVIRONMENT"), os.Getenv("SENTRY_RELEASE")); err != nil {
+		fatal("Failed to set up error reporting", "error", err)
 	}
 
 	// Initialize database
-	if err := db.InitDB(); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
-	}
-	defer db.CloseDB()
-
-	r := chi.NewRouter()
-
-	// Middleware
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
-
-	// CORS configuration
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:3000", "https://zebra.pacerclub.cn", "http://localhost:8080"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
-
-	// Handle OPTIONS requests
-	r.Options("/*", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-	})
+	pool := db.PoolConfig{
+		MaxConns:          int32(envInt("DB_MAX_CONNS", 25)),
+		MinConns:          int32(envInt("DB_MIN_CONNS", 2)),
+		MaxConnLifetime:   envDuration("DB_MAX_CONN_LIFETIME", time.Hour),
+		MaxConnIdleTime:   envDuration("DB_MAX_CONN_IDLE_TIME", 30*time.Minute),
+		HealthCheckPeriod: envDuration("DB_HEALTH_CHECK_PERIOD", time.Minute),
+		ConnectTimeout:    envDuration("DB_CONNECT_TIMEOUT", 10*time.Second),
+		StatementTimeout:  envDuration("DB_STATEMENT_TIMEOUT", 0),
+		StartupTimeout:    envDuration("DB_STARTUP_TIMEOUT", time.Minute),
 
-	// Public routes
-	r.Group(func(r chi.Router) {
-		r.Route("/api/auth", func(r chi.Router) {
-			r.Post("/register", handlers.Register)
-			r.Post("/login", handlers.Login)
-		})
-	})
+		IdleInTransactionTimeout: envDuration("DB_IDLE_IN_TRANSACTION_TIMEOUT", time.Minute),
+	}
+	if err := db.InitDB(pool); err != nil {
+		fatal("Failed to initialize database", "error", err)
+	}
+	// Reports, lists and sync status may be read from a replica
+	if replicaURL := os.Getenv("DATABASE_REPLICA_URL"); replicaURL != "" {
+		// Reports on the replica may be given more time than requests on
+		// the primary
+		replicaPool := pool
+		replicaPool.StatementTimeout = envDuration("DB_REPLICA_STATEMENT_TIMEOUT", pool.StatementTimeout)
+		if err := db.InitReplica(replicaURL, replicaPool, envDuration("DB_REPLICA_MAX_LAG", 5*time.Second)); err != nil {
+			fatal("Failed to initialize database replica", "error", err)
+		}
+	}
+
+	// `api migrate ...` manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := migrateCommand(os.Args[2:])
+		db.CloseDB()
+		if err != nil {
+			fatal("Migration failed", "error", err)
+		}
+		return
+	}
+	if envBool("DB_AUTO_MIGRATE", true) {
+		if err := db.MigrateUp(context.Background()); err != nil {
+			fatal("Failed to migrate database", "error", err)
+		}
+	}
+	// Queries that no longer match the schema stop the server here rather
+	// than failing the requests that run them
+	if err := db.CheckStatements(context.Background()); err != nil {
+		fatal("Queries do not match the database schema", "error", err)
+	}
 
-	// Protected routes
-	r.Group(func(r chi.Router) {
-		r.Use(auth.Middleware)
-
-		// Timer sessions
-		r.Route("/api/auth/sessions", func(r chi.Router) {
-			r.Post("/", handlers.CreateSession)
-			r.Get("/", handlers.ListSessions)
-			r.Put("/{id}", handlers.UpdateSession)
-			r.Delete("/{id}", handlers.DeleteSession)
-		})
-
-		// Projects
-		r.Route("/api/auth/projects", func(r chi.Router) {
-			r.Post("/", handlers.CreateProject)
-			r.Get("/", handlers.ListProjects)
-			r.Put("/{id}", handlers.UpdateProject)
-			r.Delete("/{id}", handlers.DeleteProject)
-		})
-
-		// Sync
-		r.Route("/api/auth/sync", func(r chi.Router) {
-			r.Post("/", handlers.SyncData)
-			r.Get("/status", handlers.SyncStatus)
-		})
+	// Session descriptions and project names are sealed at rest when keys
+	// are configured
+	if err := fieldcrypt.LoadEnv(); err != nil {
+		fatal("Invalid field encryption keys", "error", err)
+	}
+	if err := mail.LoadEnv(); err != nil {
+		fatal("Invalid mail configuration", "error", err)
+	}
+	mail.MaxAttempts = envInt("MAIL_MAX_ATTEMPTS", mail.MaxAttempts)
+	// Avatars and project icons are uploaded to an S3-compatible bucket
+	if err := storage.LoadEnv(); err != nil {
+		fatal("Invalid storage configuration", "error", err)
+	}
+	uploads.MaxBytes = int64(envInt("UPLOAD_MAX_BYTES", int(uploads.MaxBytes)))
+	// Subscriptions to the hosted instance are sold through Stripe
+	if err := billing.LoadEnv(); err != nil {
+		fatal("Invalid billing configuration", "error", err)
+	}
+	// Sessions may be logged by mail through a Mailgun inbound route
+	if err := integrations.LoadEnv(); err != nil {
+		fatal("Invalid inbound email configuration", "error", err)
+	}
+
+	// SIGTERM and SIGINT start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// Recurring tasks, stopped by the shutdown. Each may be given a cron
+	// schedule in <NAME>_SCHEDULE instead of its interval.
+	tasks := &scheduler.Scheduler{}
+	tombstoneGC := maintenance.TombstoneGCConfig{
+		MaxAge:       envDuration("TOMBSTONE_MAX_AGE", 90*24*time.Hour),
+		ActiveWindow: envDuration("DEVICE_ACTIVE_WINDOW", 30*24*time.Hour),
+	}
+	tasks.Add("tombstone_gc", envSchedule("TOMBSTONE_GC_SCHEDULE", "TOMBSTONE_GC_INTERVAL", 6*time.Hour),
+		func(ctx context.Context) error { return maintenance.RunTombstoneGC(ctx, tombstoneGC) })
+	staleDevices := maintenance.StaleDeviceConfig{
+		StaleAfter:   envDuration("STALE_DEVICE_AFTER", 90*24*time.Hour),
+		RevokeTokens: envBool("STALE_DEVICE_REVOKE_TOKENS", false),
+	}
+	tasks.Add("stale_device_cleanup", envSchedule("STALE_DEVICE_SCHEDULE", "STALE_DEVICE_CHECK_INTERVAL", 24*time.Hour),
+		func(ctx context.Context) error { return maintenance.RunStaleDeviceCleanup(ctx, staleDevices, nil) })
+	tasks.Add("google_calendar_sync", envSchedule("GOOGLE_CALENDAR_SYNC_SCHEDULE", "GOOGLE_CALENDAR_SYNC_INTERVAL", 15*time.Minute),
+		integrations.RunGoogleCalendarSync)
+	tasks.Add("jira_export", envSchedule("JIRA_EXPORT_SCHEDULE", "JIRA_EXPORT_INTERVAL", 5*time.Minute),
+		integrations.RunJiraExport)
+	tasks.Add("notion_export", envSchedule("NOTION_EXPORT_SCHEDULE", "NOTION_EXPORT_INTERVAL", time.Hour),
+		integrations.RunNotionExport)
+	partitionsAhead := envInt("SESSION_PARTITIONS_AHEAD", 3)
+	tasks.Add("session_partitions", envSchedule("SESSION_PARTITIONS_SCHEDULE", "SESSION_PARTITIONS_INTERVAL", 24*time.Hour),
+		func(ctx context.Context) error { return maintenance.EnsureSessionPartitions(ctx, partitionsAhead) })
+	auditRetention := envDuration("AUDIT_LOG_RETENTION", 90*24*time.Hour)
+	tasks.Add("audit_log_retention", envSchedule("AUDIT_LOG_RETENTION_SCHEDULE", "AUDIT_LOG_RETENTION_INTERVAL", 24*time.Hour),
+		func(ctx context.Context) error { return audit.Prune(ctx, auditRetention) })
+	tasks.Add("field_encryption_rotation", envSchedule("FIELD_ENCRYPTION_ROTATION_SCHEDULE", "FIELD_ENCRYPTION_ROTATION_INTERVAL", 24*time.Hour),
+		fieldcrypt.Rotate)
+	outboxRetention := envDuration("OUTBOX_RETENTION", 7*24*time.Hour)
+	tasks.Add("outbox_retention", envSchedule("OUTBOX_RETENTION_SCHEDULE", "OUTBOX_RETENTION_INTERVAL", 24*time.Hour),
+		func(ctx context.Context) error { return outbox.Prune(ctx, outboxRetention) })
+	deliveryRetention := envDuration("WEBHOOK_DELIVERY_RETENTION", 30*24*time.Hour)
+	tasks.Add("webhook_delivery_retention", envSchedule("WEBHOOK_DELIVERY_RETENTION_SCHEDULE", "WEBHOOK_DELIVERY_RETENTION_INTERVAL", 24*time.Hour),
+		func(ctx context.Context) error { return webhooks.PruneDeliveries(ctx, deliveryRetention) })
+	mailRetention := envDuration("MAIL_QUEUE_RETENTION", 30*24*time.Hour)
+	tasks.Add("mail_queue_retention", envSchedule("MAIL_QUEUE_RETENTION_SCHEDULE", "MAIL_QUEUE_RETENTION_INTERVAL", 24*time.Hour),
+		func(ctx context.Context) error { return mail.Prune(ctx, mailRetention) })
+	usageRetention := envDuration("API_USAGE_RETENTION", 400*24*time.Hour)
+	tasks.Add("api_usage_retention", envSchedule("API_USAGE_RETENTION_SCHEDULE", "API_USAGE_RETENTION_INTERVAL", 24*time.Hour),
+		func(ctx context.Context) error { return usage.Prune(ctx, usageRetention) })
+	tasks.Add("reminders", envSchedule("REMINDERS_SCHEDULE", "REMINDERS_INTERVAL", 5*time.Minute), reminders.Run)
+	// Timers running past RUNAWAY_TIMER_MAX are stopped for review; 0 keeps
+	// them running
+	if maxTimer := envDuration("RUNAWAY_TIMER_MAX", 12*time.Hour); maxTimer > 0 {
+		if maxTimer > service.MaxSessionDuration {
+			fatal("RUNAWAY_TIMER_MAX must be at most 168h")
+		}
+		tasks.Add("runaway_timers", envSchedule("RUNAWAY_TIMERS_SCHEDULE", "RUNAWAY_TIMERS_INTERVAL", 5*time.Minute),
+			func(ctx context.Context) error { return runaway.Stop(ctx, maxTimer) })
+	}
+	background.Go(func() { tasks.Run(ctx) })
+
+	// API usage is counted in memory and written every USAGE_FLUSH_INTERVAL
+	usageFlush := envDuration("USAGE_FLUSH_INTERVAL", time.Minute)
+	background.Go(func() { usage.Run(ctx, usageFlush) })
+
+	// Events committed with their changes are delivered to webhooks by the
+	// outbox dispatcher, as are notifications on the channels users enabled;
+	// they also complete onboarding steps
+	outbox.Handle(webhooks.Deliver)
+	if mail.Configured() {
+		notify.RegisterSender(notify.ChannelEmail, notify.EmailSender())
+	}
+	notify.RegisterSender(notify.ChannelWebhook, notify.WebhookSender())
+	outbox.Handle(notify.Deliver)
+	outbox.Handle(onboarding.Track)
+	mailPoll := envDuration("MAIL_QUEUE_POLL_INTERVAL", 5*time.Second)
+	background.Go(func() { mail.Run(ctx, mailPoll) })
+	uploadPoll := envDuration("UPLOAD_POLL_INTERVAL", 2*time.Second)
+	background.Go(func() { uploads.Run(ctx, uploadPoll) })
+	outboxPoll := envDuration("OUTBOX_POLL_INTERVAL", time.Second)
+	background.Go(func() { outbox.Run(ctx, outboxPoll) })
+	// Changes such as a started timer are pushed to the live streams of
+	// every server
+	background.Go(func() { live.Run(ctx, 5*time.Second) })
+
+	limits := newLimiters()
+
+	audit.LocationHeaders = envList("CLIENT_LOCATION_HEADERS")
+	// The API, under /api/v1 and the unversioned /api paths deprecated
+	// until API_LEGACY_SUNSET
+	r := routes.New(limits, routes.Options{
+		TrustProxyHeaders: envBool("TRUST_PROXY_HEADERS", false),
+		CORS:              corsPolicy(),
+		LegacySunset:      envDate("API_LEGACY_SUNSET"),
+		Tasks:             tasks,
 	})
 
+	// Profiling and runtime variables, off unless DEBUG_ENDPOINTS is set
+	if envBool("DEBUG_ENDPOINTS", false) {
+		debugRoutes(r)
+	}
+
+	// TLS, when the server terminates it itself
+	tlsOpts := tlsFromEnv()
+
+	// The gRPC API for the desktop client listens on its own port
+	grpcPort := os.Getenv("GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = "9090"
+	}
+	var grpcOptions []grpc.ServerOption
+	if tlsOpts != nil {
+		grpcOptions = append(grpcOptions, grpc.Creds(credentials.NewTLS(tlsOpts.config())))
+	}
+	grpcServer := grpcapi.NewServer(limits.SyncDevice, limits.SyncUser, grpcOptions...)
+	go func() {
+		lis, err := net.Listen("tcp", ":"+grpcPort)
+		if err != nil {
+			fatal("Failed to listen on gRPC port", "error", err)
+		}
+		slog.Info("gRPC server starting", "port", grpcPort)
+		if err := grpcServer.Serve(lis); err != nil {
+			fatal("Failed to start gRPC server", "error", err)
+		}
+	}()
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
+		if tlsOpts != nil {
+			port = "443"
+		}
+	}
+
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	servers := []*http.Server{server}
+	if tlsOpts != nil {
+		// ServeTLS adds HTTP/2 to the configuration
+		server.TLSConfig = tlsOpts.config()
+		if redirectPort := os.Getenv("HTTP_REDIRECT_PORT"); redirectPort != "" {
+			redirect := tlsOpts.redirectServer(":"+redirectPort, port)
+			servers = append(servers, redirect)
+			go func() {
+				slog.Info("Redirecting HTTP to HTTPS", "port", redirectPort)
+				if err := redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					fatal("Failed to start HTTP redirect server", "error", err)
+				}
+			}()
+		}
 	}
+	go func() {
+		var err error
+		if tlsOpts != nil {
+			slog.Info("Server starting", "port", port, "tls", true)
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			slog.Info("Server starting", "port", port, "tls", false)
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("Failed to start server", "error", err)
+		}
+	}()
 
-	log.Printf("Server starting on port %s", port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	<-ctx.Done()
+	// A second signal kills the process right away
+	stop()
+	shutdown(servers, grpcServer, envDuration("SHUTDOWN_TIMEOUT", 60*time.Second))
+}
+
+// readHeaderTimeout bounds how long clients may take to send request
+// headers
+const readHeaderTimeout = 10 * time.Second
+
+// shutdown stops accepting connections, lets in-flight requests and
+// background work finish within timeout, and closes the database pool.
+// Requests still running at the deadline are cut off.
+func shutdown(servers []*http.Server, grpcServer *grpc.Server, timeout time.Duration) {
+	slog.Info("Shutting down, draining requests", "timeout", timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			slog.Warn("HTTP requests still running at the shutdown deadline", "error", err)
+			server.Close()
+		}
+	}
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		slog.Warn("gRPC calls still running at the shutdown deadline")
+		grpcServer.Stop()
+	}
+	if !background.Wait(ctx) {
+		slog.Warn("Background work still running at the shutdown deadline")
+	}
+	if err := usage.Flush(ctx); err != nil {
+		slog.Warn("Failed to record API usage at shutdown", "error", err)
+	}
+
+	db.CloseDB()
+	errorreport.Flush(2 * time.Second)
+	slog.Info("Server stopped")
+}
+
+// newLimiters creates the limiters, keeping their buckets in Redis when
+// REDIS_URL is set so that every server shares them, and in memory
+// otherwise
+func newLimiters() routes.Limiters {
+	var store ratelimit.Store = ratelimit.NewMemoryStore(envInt("RATE_LIMIT_MAX_KEYS", 100000))
+	if url := os.Getenv("REDIS_URL"); url != "" {
+		opts, err := redis.ParseURL(url)
+		if err != nil {
+			fatal("Invalid REDIS_URL", "error", err)
+		}
+		store = ratelimit.NewRedisStore(redis.NewClient(opts), "zebra:ratelimit:")
+	}
+
+	return routes.Limiters{
+		IP:         ratelimit.New(store, "ip", envInt("IP_RATE_PER_MINUTE", 600), envInt("IP_BURST", 200)),
+		User:       ratelimit.New(store, "user", envInt("USER_RATE_PER_MINUTE", 300), envInt("USER_BURST", 100)),
+		Reports:    ratelimit.New(store, "reports", envInt("REPORT_RATE_PER_MINUTE", 20), envInt("REPORT_BURST", 10)),
+		SyncDevice: ratelimit.New(store, "sync-device", envInt("SYNC_DEVICE_RATE_PER_MINUTE", 12), envInt("SYNC_DEVICE_BURST", 6)),
+		SyncUser:   ratelimit.New(store, "sync-user", envInt("SYNC_USER_RATE_PER_MINUTE", 60), envInt("SYNC_USER_BURST", 20)),
+		Kiosk:      ratelimit.New(store, "kiosk", envInt("KIOSK_RATE_PER_MINUTE", 30), envInt("KIOSK_BURST", 10)),
+	}
+}
+
+// envList reads a comma-separated list, skipping empty items
+func envList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// fatal logs an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// envSchedule reads a task's schedule from key, a cron expression or
+// "@every <duration>". Unset, the task runs every intervalKey, a duration
+// defaulting to interval.
+func envSchedule(key, intervalKey string, interval time.Duration) scheduler.Schedule {
+	if value := os.Getenv(key); value != "" {
+		schedule, err := scheduler.Parse(value)
+		if err == nil {
+			return schedule
+		}
+		slog.Warn("Invalid setting, using the interval", "key", key, "value", value, "error", err)
+	}
+	if d := envDuration(intervalKey, interval); d >= time.Second {
+		interval = d
+	}
+	return scheduler.Every(interval)
+}
+
+// envDuration reads a duration such as "6h" from the environment
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Warn("Invalid setting, using the default", "key", key, "value", value, "default", fallback)
+		return fallback
+	}
+	return d
+}
+
+// envInt reads an integer from the environment
+func envInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("Invalid setting, using the default", "key", key, "value", value, "default", fallback)
+		return fallback
+	}
+	return n
+}
+
+// envBool reads a boolean such as "true" or "0" from the environment
+func envBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("Invalid setting, using the default", "key", key, "value", value, "default", fallback)
+		return fallback
+	}
+	return b
+}
+
+// envDate reads a date such as "2027-04-01" from the environment, zero when
+// unset
+func envDate(key string) time.Time {
+	value := os.Getenv(key)
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		slog.Warn("Invalid setting, ignoring it", "key", key, "value", value)
+		return time.Time{}
 	}
+	return t
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// migrateCommand runs `api migrate <up|down [n]|version|force <version>|check>`
+// on the database, for operators managing migrations by hand. check
+// verifies the declared queries against the schema, as in CI.
+func migrateCommand(args []string) error {
+	ctx := context.Background()
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up | down [steps] | version | force <version> | check")
+	}
+
+	switch args[0] {
+	case "up":
+		if err := db.MigrateUp(ctx); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		if err := db.MigrateDown(ctx, steps); err != nil {
+			return err
+		}
+	case "version":
+	case "force":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: migrate force <version>")
+		}
+		version, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		if err := db.ForceMigrationVersion(ctx, version); err != nil {
+			return err
+		}
+	case "check":
+		if err := db.CheckStatements(ctx); err != nil {
+			return err
+		}
+		slog.Info("Every declared query matches the schema")
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+
+	version, err := db.MigrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("Database schema", "version", version)
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// tlsOptions is how the server terminates TLS itself, for self-hosters
+// running it without a reverse proxy. HTTP/2 is served over it.
+type tlsOptions struct {
+	// certificate is loaded from TLS_CERT_FILE and TLS_KEY_FILE
+	certificate tls.Certificate
+	// manager obtains and renews certificates from Let's Encrypt instead
+	manager *autocert.Manager
+}
+
+// tlsFromEnv reads the TLS settings: TLS_CERT_FILE and TLS_KEY_FILE, or
+// AUTOCERT_DOMAINS to get certificates from Let's Encrypt. It returns nil
+// when neither is set and the server speaks plain HTTP.
+func tlsFromEnv() *tlsOptions {
+	certFile, keyFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+	domains := envList("AUTOCERT_DOMAINS")
+
+	switch {
+	case len(domains) > 0:
+		if certFile != "" || keyFile != "" {
+			fatal("Set either TLS_CERT_FILE and TLS_KEY_FILE or AUTOCERT_DOMAINS, not both")
+		}
+		cacheDir := os.Getenv("AUTOCERT_CACHE_DIR")
+		if cacheDir == "" {
+			cacheDir = "certs"
+		}
+		return &tlsOptions{manager: &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(domains...),
+			Cache:      autocert.DirCache(cacheDir),
+			Email:      os.Getenv("AUTOCERT_EMAIL"),
+		}}
+	case certFile != "" || keyFile != "":
+		if certFile == "" || keyFile == "" {
+			fatal("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+		}
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			fatal("Failed to load TLS certificate", "error", err)
+		}
+		return &tlsOptions{certificate: certificate}
+	}
+	return nil
+}
+
+// config is the TLS configuration of both the HTTP and the gRPC server,
+// offering HTTP/2 through ALPN
+func (t *tlsOptions) config() *tls.Config {
+	if t.manager != nil {
+		config := t.manager.TLSConfig()
+		config.MinVersion = tls.VersionTLS12
+		return config
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+		Certificates: []tls.Certificate{t.certificate},
+	}
+}
+
+// redirectServer answers plain HTTP on addr by redirecting to HTTPS on
+// httpsPort. With Let's Encrypt it also answers the http-01 challenges.
+func (t *tlsOptions) redirectServer(addr, httpsPort string) *http.Server {
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if i := strings.LastIndex(host, ":"); i > strings.LastIndex(host, "]") {
+			host = host[:i]
+		}
+		if httpsPort != "443" {
+			host += ":" + httpsPort
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+	if t.manager != nil {
+		handler = t.manager.HTTPHandler(handler)
+	}
+	return &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: readHeaderTimeout}
+}
@@ -0,0 +1,25 @@
+// Command zebratest serves the in-memory API of package zebratest, for
+// client SDKs written in other languages to test against. It prints the
+// API's URL and serves until interrupted:
+//
+//	zebratest    http://127.0.0.1:41237/api/v1
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pacerclub/zebra-backend/zebratest"
+)
+
+func main() {
+	srv := zebratest.NewServer()
+	defer srv.Close()
+	fmt.Println(srv.URL + "/api/v1")
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+}
@@ -0,0 +1,188 @@
+// Package apierror writes the error responses of the HTTP API. Every error is
+// a JSON object carrying a stable code clients can branch on, a message meant
+// for people, in the request's language, optional details and the id of
+// the request for support:
+//
+//	{"code": "not_found", "message": "Session not found", "request_id": "host/abc-000001"}
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/pacerclub/zebra-backend/internal/errorreport"
+	"github.com/pacerclub/zebra-backend/internal/i18n"
+)
+
+// Code identifies the kind of error. Codes are part of the API: new ones may
+// be added, but existing ones keep their meaning.
+type Code string
+
+const (
+	// InvalidRequest is a parameter or field with a value the endpoint does
+	// not accept
+	InvalidRequest Code = "invalid_request"
+	// InvalidBody is a request body that is not valid JSON or does not have
+	// the documented shape
+	InvalidBody Code = "invalid_body"
+	// ValidationFailed is a body with fields failing validation;
+	// details.fields lists each field with its message
+	ValidationFailed Code = "validation_failed"
+	// UnsupportedVersion is a request for an API version the endpoint does
+	// not serve
+	UnsupportedVersion Code = "unsupported_version"
+	// StorageModeMismatch is a record whose encrypted fields do not match
+	// the user's storage mode, or a feature encrypted storage mode rules out
+	StorageModeMismatch Code = "storage_mode_mismatch"
+	// SessionLocked is a change to a session before the organization's lock
+	// date
+	SessionLocked Code = "session_locked"
+
+	// Unauthenticated is a request without a bearer token
+	Unauthenticated Code = "unauthenticated"
+	// InvalidToken is a malformed, expired or forged token
+	InvalidToken Code = "invalid_token"
+	// TokenRevoked is a token revoked by logging out or by the admin
+	TokenRevoked Code = "token_revoked"
+	// InvalidCredentials is a failed login
+	InvalidCredentials Code = "invalid_credentials"
+	// AccountDeactivated is a login or token of a user who deactivated their
+	// account; it can be reactivated by email
+	AccountDeactivated Code = "account_deactivated"
+
+	// Forbidden is a request the user's role does not allow
+	Forbidden Code = "forbidden"
+	// NotAMember is a request for an organization the user does not belong to
+	NotAMember Code = "not_a_member"
+	// InsufficientScope is a request by a third-party app whose token lacks
+	// the scope the endpoint needs
+	InsufficientScope Code = "insufficient_scope"
+	// OwnerUpgradeRequired is a request over a limit of an organization's
+	// plan, which only its owners can upgrade; details are as for
+	// UpgradeRequired
+	OwnerUpgradeRequired Code = "owner_upgrade_required"
+
+	// NotFound is a resource that does not exist or is not visible to the user
+	NotFound Code = "not_found"
+	// NotConnected is a request needing an integration the user has not linked
+	NotConnected Code = "not_connected"
+	// MethodNotAllowed is a method the route does not serve
+	MethodNotAllowed Code = "method_not_allowed"
+	// NotAcceptable is an inbound message the server does not accept
+	NotAcceptable Code = "not_acceptable"
+
+	// Conflict is a request that conflicts with the resource's current state
+	Conflict Code = "conflict"
+	// VersionMismatch is an update carrying a version of the record that is
+	// no longer current; details.current holds the stored record
+	VersionMismatch Code = "version_mismatch"
+	// IdempotencyKeyReused is an Idempotency-Key sent before with a
+	// different request
+	IdempotencyKeyReused Code = "idempotency_key_reused"
+	// DependencyFailed is a request of a batch referring to an earlier
+	// request that failed or lacks the referenced value
+	DependencyFailed Code = "dependency_failed"
+	// LimitReached is a request that would exceed a per-user limit, such as
+	// the number of webhooks
+	LimitReached Code = "limit_reached"
+	// UpgradeRequired is a request over a limit of the plan of the user's
+	// workspace, lifted by upgrading; details.limit names the limit,
+	// details.max is its value and details.plan the plan
+	UpgradeRequired Code = "upgrade_required"
+	// PayloadTooLarge is a request body or batch over the endpoint's limit
+	PayloadTooLarge Code = "payload_too_large"
+	// UnsupportedMediaType is a request body in an encoding the server does
+	// not read
+	UnsupportedMediaType Code = "unsupported_media_type"
+	// RateLimited is a request over the rate limit; retry after the number of
+	// seconds in the Retry-After header
+	RateLimited Code = "rate_limited"
+
+	// Internal is a failure on the server
+	Internal Code = "internal_error"
+	// UpstreamFailed is a failure of a third-party service the request needed
+	UpstreamFailed Code = "upstream_error"
+	// NotConfigured is a feature the server has not been configured for
+	NotConfigured Code = "not_configured"
+)
+
+// Codes lists every code, in the order they are documented
+var Codes = []Code{
+	InvalidRequest, InvalidBody, ValidationFailed, UnsupportedVersion, StorageModeMismatch, SessionLocked,
+	Unauthenticated, InvalidToken, TokenRevoked, InvalidCredentials, AccountDeactivated,
+	Forbidden, NotAMember, InsufficientScope, OwnerUpgradeRequired,
+	NotFound, NotConnected, MethodNotAllowed, NotAcceptable,
+	Conflict, VersionMismatch, IdempotencyKeyReused, DependencyFailed, LimitReached, UpgradeRequired, PayloadTooLarge, UnsupportedMediaType, RateLimited,
+	Internal, UpstreamFailed, NotConfigured,
+}
+
+// Response is the body of every error response
+type Response struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+	// Details holds code-specific data, such as the offending field
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+}
+
+// CodeForStatus is the generic code of an HTTP status
+func CodeForStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return InvalidRequest
+	case http.StatusUnauthorized:
+		return Unauthenticated
+	case http.StatusPaymentRequired:
+		return UpgradeRequired
+	case http.StatusForbidden:
+		return Forbidden
+	case http.StatusNotFound:
+		return NotFound
+	case http.StatusMethodNotAllowed:
+		return MethodNotAllowed
+	case http.StatusNotAcceptable:
+		return NotAcceptable
+	case http.StatusConflict:
+		return Conflict
+	case http.StatusRequestEntityTooLarge:
+		return PayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return UnsupportedMediaType
+	case http.StatusTooManyRequests:
+		return RateLimited
+	case http.StatusBadGateway:
+		return UpstreamFailed
+	case http.StatusServiceUnavailable:
+		return NotConfigured
+	}
+	return Internal
+}
+
+// Error replies with message and the generic code of status. It takes the
+// arguments of http.Error plus the request, whose id it reports.
+func Error(w http.ResponseWriter, r *http.Request, message string, status int) {
+	Write(w, r, status, CodeForStatus(status), message, nil)
+}
+
+// Write replies with an error of the given code and details. Server errors
+// are reported by their message, which is translated into the request's
+// locale in the response.
+func Write(w http.ResponseWriter, r *http.Request, status int, code Code, message string, details map[string]interface{}) {
+	if status >= http.StatusInternalServerError {
+		errorreport.CaptureMessage(r, message)
+	}
+	locale := i18n.Locale(r.Context())
+	message = i18n.T(locale, message)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", locale)
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Response{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: middleware.GetReqID(r.Context()),
+	})
+}
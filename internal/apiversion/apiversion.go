@@ -0,0 +1,100 @@
+package apiversion
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+)
+
+// Header carries the API version in both directions: clients send it to
+// state the version they were built against, and every response names the
+// version that served it
+const Header = "API-Version"
+
+// SupportedHeader lists every version the server still serves, so clients
+// can tell when theirs is about to go away
+const SupportedHeader = "API-Supported-Versions"
+
+// Supported are the versions mounted under /api/v<N>, oldest first. A new
+// version is added for breaking changes only; older ones stay mounted until
+// clients have moved off them.
+var Supported = []int{1}
+
+type contextKey string
+
+const versionKey contextKey = "api_version"
+
+// Middleware serves the routes it wraps as the given version. Requests
+// whose API-Version header names another version are rejected, rather than
+// getting responses shaped for a client they were not built for.
+func Middleware(version int) func(http.Handler) http.Handler {
+	supported := make([]string, len(Supported))
+	for i, v := range Supported {
+		supported[i] = strconv.Itoa(v)
+	}
+	supportedList := strings.Join(supported, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(Header, strconv.Itoa(version))
+			w.Header().Set(SupportedHeader, supportedList)
+
+			if requested := r.Header.Get(Header); requested != "" {
+				n, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(requested), "v"))
+				if err != nil || n != version {
+					apierror.Write(w, r, http.StatusBadRequest, apierror.UnsupportedVersion,
+						fmt.Sprintf("This endpoint serves API version %d; supported versions are %s", version, supportedList),
+						map[string]interface{}{"supported": Supported})
+					return
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), versionKey, version)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// FromContext returns the version a request is served as, 0 outside the
+// versioned routes
+func FromContext(ctx context.Context) int {
+	version, _ := ctx.Value(versionKey).(int)
+	return version
+}
+
+// Deprecated marks routes under prefix as aliases of the same routes under
+// successor. Responses carry a Deprecation header (RFC 9745), a Sunset
+// header (RFC 8594) when sunset is set, and a successor-version link.
+func Deprecated(prefix, successor string, deprecatedAt, sunset time.Time) func(http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", deprecation)
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			if rest, ok := strings.CutPrefix(r.URL.Path, prefix); ok {
+				w.Header().Add("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successor, rest))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Path strips the version from an API path, so /api/v1/auth/sessions and
+// the legacy /api/auth/sessions both become /api/auth/sessions
+func Path(path string) string {
+	for _, v := range Supported {
+		prefix := "/api/v" + strconv.Itoa(v)
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return "/api" + path[len(prefix):]
+		}
+	}
+	return path
+}
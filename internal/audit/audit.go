@@ -0,0 +1,142 @@
+// Package audit records the mutating API calls in the audit_log table: who
+// made them, from which device and address, on which entity and with what
+// outcome. Entries older than the retention period are pruned by a
+// recurring task.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// Entry is one recorded API call
+type Entry struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	// OrganizationID is the workspace the call acted on, nil for the
+	// personal workspace
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	DeviceID       string     `json:"device_id,omitempty"`
+	IP             string     `json:"ip,omitempty"`
+	// Location is the client's approximate location, when the proxy in
+	// front of the server reports it
+	Location  string `json:"location,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	Method    string `json:"method"`
+	// Route is the path pattern relative to the API root, such as
+	// /auth/sessions/{id}
+	Route string `json:"route"`
+	// EntityType is the resource the route acts on, such as "sessions",
+	// and EntityID the {id} of its path, if any
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id,omitempty"`
+	Status     int    `json:"status"`
+	// ImpersonatorID is the admin who made the call as the user, and
+	// Reason why the admin started impersonating them
+	ImpersonatorID *uuid.UUID `json:"impersonator_id,omitempty"`
+	Reason         string     `json:"reason,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// apiPrefix matches the mount point of the API routes
+var apiPrefix = regexp.MustCompile(`^/api(/v[0-9]+)?`)
+
+// Middleware records every POST, PUT, PATCH and DELETE request once it is
+// answered, and every request of an admin impersonating the user. It must
+// run after auth.Middleware and auth.OrganizationMiddleware. Recording is
+// best effort: a failure is logged and does not fail the request.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		impersonatorID := auth.GetImpersonatorIDFromContext(r.Context())
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			if impersonatorID == uuid.Nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		ctx := r.Context()
+		client := ClientOf(r)
+		entry := Entry{
+			UserID:    auth.GetUserIDFromContext(ctx),
+			DeviceID:  auth.GetDeviceIDFromContext(ctx),
+			IP:        client.IP,
+			Location:  client.Location,
+			UserAgent: client.UserAgent,
+			RequestID: middleware.GetReqID(ctx),
+			Method:    r.Method,
+			Status:    status,
+		}
+		if orgID := auth.GetOrganizationIDFromContext(ctx); orgID != uuid.Nil {
+			entry.OrganizationID = &orgID
+		}
+		if impersonatorID != uuid.Nil {
+			entry.ImpersonatorID = &impersonatorID
+		}
+		if rctx := chi.RouteContext(ctx); rctx != nil {
+			entry.Route = apiPrefix.ReplaceAllString(rctx.RoutePattern(), "")
+			entry.EntityID = rctx.URLParam("id")
+		}
+		entry.EntityType = entityType(entry.Route)
+
+		// The request may be canceled once answered; the entry is still
+		// written
+		Record(context.WithoutCancel(ctx), entry)
+	})
+}
+
+// entityType returns the resource of a route: its first segment after
+// /auth, as in "sessions" for /auth/sessions/{id}
+func entityType(route string) string {
+	route = strings.TrimPrefix(route, "/auth")
+	segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+	return segment
+}
+
+// Record writes an entry to the audit log, logging a failure
+func Record(ctx context.Context, entry Entry) {
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO audit_log (
+			user_id, organization_id, device_id, ip, location, user_agent, request_id,
+			method, route, entity_type, entity_id, status, impersonator_id, reason
+		) VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''),
+			$8, $9, $10, NULLIF($11, ''), $12, $13, NULLIF($14, ''))
+	`, entry.UserID, entry.OrganizationID, entry.DeviceID, entry.IP, entry.Location, entry.UserAgent, entry.RequestID,
+		entry.Method, entry.Route, entry.EntityType, entry.EntityID, entry.Status, entry.ImpersonatorID, entry.Reason)
+	if err != nil {
+		slog.Error("Failed to record audit log entry", "route", entry.Route, "error", err)
+	}
+}
+
+// Prune deletes the entries older than retention, as a scheduled task
+func Prune(ctx context.Context, retention time.Duration) error {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM audit_log WHERE created_at < $1`, time.Now().Add(-retention))
+	if err != nil {
+		return fmt.Errorf("error pruning audit log: %v", err)
+	}
+	if tag.RowsAffected() > 0 {
+		slog.Info("Pruned audit log", "entries", tag.RowsAffected())
+	}
+	return nil
+}
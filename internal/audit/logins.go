@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// LoginRoute is the route login attempts are recorded under
+const LoginRoute = "/auth/login"
+
+// LocationHeaders are the request headers a proxy or CDN in front of the
+// server sets to the client's location, such as CF-IPCity and CF-IPCountry.
+// The values present are joined, most specific first. With none set, no
+// location is recorded.
+var LocationHeaders []string
+
+// Client is who sent a request
+type Client struct {
+	IP        string
+	UserAgent string
+	Location  string
+}
+
+// ClientOf returns the client that sent r
+func ClientOf(r *http.Request) Client {
+	return Client{
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+		Location:  Location(r.Header.Get),
+	}
+}
+
+// Location returns the client's location from the LocationHeaders, looked
+// up with get
+func Location(get func(header string) string) string {
+	var parts []string
+	for _, header := range LocationHeaders {
+		if value := strings.TrimSpace(get(header)); value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// clientIP returns the address of the client, without its port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RecordLogin records a login attempt of the user from the device, with
+// the status it was answered with
+func RecordLogin(ctx context.Context, userID uuid.UUID, deviceID string, client Client, status int) {
+	Record(ctx, Entry{
+		UserID:     userID,
+		DeviceID:   deviceID,
+		IP:         client.IP,
+		Location:   client.Location,
+		UserAgent:  client.UserAgent,
+		Method:     http.MethodPost,
+		Route:      LoginRoute,
+		EntityType: "login",
+		Status:     status,
+	})
+}
+
+// Login is one attempt to log in as a user
+type Login struct {
+	Time     time.Time `json:"time"`
+	Success  bool      `json:"success"`
+	Status   int       `json:"status"`
+	IP       string    `json:"ip,omitempty"`
+	Location string    `json:"location,omitempty"`
+	DeviceID string    `json:"device_id,omitempty"`
+	// DeviceName and Platform are known once the device has synced
+	DeviceName string `json:"device_name,omitempty"`
+	Platform   string `json:"platform,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty"`
+}
+
+// Logins returns the user's latest limit login attempts, newest first
+func Logins(ctx context.Context, userID uuid.UUID, limit int) ([]Login, error) {
+	rows, err := db.ReadPool(ctx).Query(ctx, `
+		SELECT a.created_at, a.status, COALESCE(a.ip, ''), COALESCE(a.location, ''),
+			COALESCE(a.device_id, ''), COALESCE(d.device_name, ''), COALESCE(d.platform, ''),
+			COALESCE(a.user_agent, '')
+		FROM audit_log a
+		LEFT JOIN device_sync d ON d.user_id = a.user_id AND d.device_id = a.device_id
+		WHERE a.user_id = $1 AND a.entity_type = 'login'
+		ORDER BY a.created_at DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logins := []Login{}
+	for rows.Next() {
+		var l Login
+		if err := rows.Scan(&l.Time, &l.Status, &l.IP, &l.Location, &l.DeviceID, &l.DeviceName, &l.Platform, &l.UserAgent); err != nil {
+			return nil, err
+		}
+		l.Success = l.Status < http.StatusBadRequest
+		logins = append(logins, l)
+	}
+	return logins, rows.Err()
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+var isAdminSQL = db.Statement("is_admin", `SELECT is_admin FROM users WHERE id = $1`)
+
+// IsAdmin reports whether the user is an operator of the server
+func IsAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var isAdmin bool
+	err := db.Pool.QueryRow(ctx, isAdminSQL, userID).Scan(&isAdmin)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return isAdmin, err
+}
+
+// RequireAdmin rejects requests of users who are not operators of the
+// server. Admins are flagged in the database, as in
+// UPDATE users SET is_admin = TRUE WHERE email = '...'. Impersonation
+// tokens never reach the admin API. It must run after Middleware.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if GetImpersonatorIDFromContext(r.Context()) != uuid.Nil {
+			apierror.Error(w, r, "Admin access required", http.StatusForbidden)
+			return
+		}
+		isAdmin, err := IsAdmin(r.Context(), GetUserIDFromContext(r.Context()))
+		if err != nil {
+			apierror.Error(w, r, "Failed to check admin access", http.StatusInternalServerError)
+			return
+		}
+		if !isAdmin {
+			apierror.Error(w, r, "Admin access required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
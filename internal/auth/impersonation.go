@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+)
+
+// ImpersonationTTL is how long impersonation tokens last
+const ImpersonationTTL = time.Hour
+
+// ImpersonatedByHeader marks the responses to impersonated requests with
+// the admin making them
+const ImpersonatedByHeader = "X-Impersonated-By"
+
+const ImpersonatorIDKey userContextKey = "impersonator_id"
+
+// GenerateImpersonationToken creates a short-lived token acting as the user
+// on behalf of an admin, for support to reproduce what the user sees. The
+// token carries the admin in its claims and its own device ID, so the
+// requests made with it are told apart from the user's own. It returns the
+// token and when it expires.
+func GenerateImpersonationToken(userID uuid.UUID, email string, adminID uuid.UUID) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ImpersonationTTL)
+
+	claims := &Claims{
+		UserID:         userID,
+		Email:          email,
+		DeviceID:       ImpersonationDeviceID(adminID),
+		ImpersonatorID: &adminID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtKey)
+	return token, expiresAt, err
+}
+
+// ImpersonationDeviceID is the device of the tokens an admin impersonates
+// users with
+func ImpersonationDeviceID(adminID uuid.UUID) string {
+	return "impersonation-" + adminID.String()
+}
+
+// GetImpersonatorIDFromContext returns the admin impersonating the user of
+// the request, or uuid.Nil for the user's own requests
+func GetImpersonatorIDFromContext(ctx context.Context) uuid.UUID {
+	if adminID, ok := ctx.Value(ImpersonatorIDKey).(uuid.UUID); ok {
+		return adminID
+	}
+	return uuid.Nil
+}
+
+// DenyImpersonation rejects impersonated requests to routes that change
+// how the user signs in, pays or reaches their data, or that issue new
+// tokens. It must run after Middleware.
+func DenyImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if GetImpersonatorIDFromContext(r.Context()) != uuid.Nil {
+			apierror.Error(w, r, "Not allowed while impersonating", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
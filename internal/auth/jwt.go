@@ -11,6 +11,13 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/errorreport"
+	"github.com/pacerclub/zebra-backend/internal/i18n"
+	"github.com/pacerclub/zebra-backend/internal/logging"
+	"github.com/pacerclub/zebra-backend/internal/opstats"
 )
 
 var jwtKey = []byte(getJWTSecret())
@@ -26,22 +33,40 @@ func getJWTSecret() string {
 type userContextKey string
 
 const UserIDKey userContextKey = "user_id"
+const DeviceIDKey userContextKey = "device_id"
+const WorkspaceIDKey userContextKey = "workspace_id"
 
 type Claims struct {
-	UserID    uuid.UUID `json:"user_id"`
-	Email     string    `json:"email"`
-	DeviceID  string    `json:"device_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Email    string    `json:"email"`
+	DeviceID string    `json:"device_id"`
+	// WorkspaceID is the organization the token acts on by default, or nil
+	// for the personal workspace
+	WorkspaceID *uuid.UUID `json:"workspace_id,omitempty"`
+	// Scopes and GrantID are set on tokens issued to third-party apps
+	Scopes  []string   `json:"scopes,omitempty"`
+	GrantID *uuid.UUID `json:"grant_id,omitempty"`
+	// ImpersonatorID is the admin acting as the user, on impersonation
+	// tokens
+	ImpersonatorID *uuid.UUID `json:"impersonator_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // GenerateToken creates a new JWT token for a user
 func GenerateToken(userID uuid.UUID, email, deviceID string) (string, error) {
+	return GenerateWorkspaceToken(userID, email, deviceID, nil)
+}
+
+// GenerateWorkspaceToken creates a JWT token whose requests default to the
+// given workspace
+func GenerateWorkspaceToken(userID uuid.UUID, email, deviceID string, workspaceID *uuid.UUID) (string, error) {
 	expirationTime := time.Now().Add(24 * 7 * time.Hour) // 1 week
 
 	claims := &Claims{
-		UserID:   userID,
-		Email:    email,
-		DeviceID: deviceID,
+		UserID:      userID,
+		Email:       email,
+		DeviceID:    deviceID,
+		WorkspaceID: workspaceID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -74,46 +99,179 @@ func ValidateToken(tokenStr string) (*Claims, error) {
 	return claims, nil
 }
 
-// Middleware verifies the JWT token in the Authorization header
-func Middleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
-			return
-		}
+// AuthError is an authentication failure with the HTTP status and API error
+// code it maps to
+type AuthError struct {
+	Status  int
+	Code    apierror.Code
+	Message string
+}
 
-		bearerToken := strings.Split(authHeader, " ")
-		if len(bearerToken) != 2 || strings.ToLower(bearerToken[0]) != "bearer" {
-			http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
-			return
-		}
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+// Authenticate verifies the bearer token of an Authorization header value
+// and returns ctx with the token's user, device, workspace and scopes. It
+// is shared by the HTTP middleware and the gRPC interceptors.
+func Authenticate(ctx context.Context, authHeader string) (context.Context, *AuthError) {
+	if authHeader == "" {
+		return nil, &AuthError{http.StatusUnauthorized, apierror.Unauthenticated, "Authorization header required"}
+	}
+
+	bearerToken := strings.Split(authHeader, " ")
+	if len(bearerToken) != 2 || strings.ToLower(bearerToken[0]) != "bearer" {
+		return nil, &AuthError{http.StatusUnauthorized, apierror.InvalidToken, "Invalid authorization header format"}
+	}
+
+	tokenString := bearerToken[1]
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		return jwtKey, nil
+	})
+
+	if err != nil {
+		return nil, &AuthError{http.StatusUnauthorized, apierror.InvalidToken, "Invalid token"}
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, &AuthError{http.StatusUnauthorized, apierror.InvalidToken, "Invalid token claims"}
+	}
 
-		tokenString := bearerToken[1]
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
+	deactivated, revoked, err := tokenRevoked(ctx, claims)
+	if err != nil {
+		return nil, &AuthError{http.StatusInternalServerError, apierror.Internal, "Failed to verify token"}
+	}
+	if deactivated {
+		return nil, &AuthError{http.StatusUnauthorized, apierror.AccountDeactivated, "Account is deactivated"}
+	}
+	if revoked {
+		return nil, &AuthError{http.StatusUnauthorized, apierror.TokenRevoked, "Token has been revoked"}
+	}
 
+	if claims.GrantID != nil {
+		revoked, err := grantRevoked(ctx, *claims.GrantID)
 		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
+			return nil, &AuthError{http.StatusInternalServerError, apierror.Internal, "Failed to verify token"}
 		}
+		if revoked {
+			return nil, &AuthError{http.StatusUnauthorized, apierror.TokenRevoked, "Token has been revoked"}
+		}
+	}
+
+	// Add user, device and workspace to the context
+	ctx = context.WithValue(ctx, UserIDKey, claims.UserID)
+	ctx = context.WithValue(ctx, DeviceIDKey, claims.DeviceID)
+	if claims.WorkspaceID != nil {
+		ctx = context.WithValue(ctx, WorkspaceIDKey, *claims.WorkspaceID)
+	}
+	if claims.GrantID != nil {
+		ctx = context.WithValue(ctx, ScopesKey, claims.Scopes)
+	}
+	if claims.ImpersonatorID != nil {
+		ctx = context.WithValue(ctx, ImpersonatorIDKey, *claims.ImpersonatorID)
+	}
+	return ctx, nil
+}
 
-		claims, ok := token.Claims.(*Claims)
-		if !ok || !token.Valid {
-			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+// Middleware verifies the JWT token in the Authorization header
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, authErr := Authenticate(r.Context(), r.Header.Get("Authorization"))
+		if authErr != nil {
+			apierror.Write(w, r, authErr.Status, authErr.Code, authErr.Message, nil)
 			return
 		}
-
-		// Add user ID to request context
-		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+		logging.AddAttrs(ctx, "user_id", GetUserIDFromContext(ctx))
+		if adminID := GetImpersonatorIDFromContext(ctx); adminID != uuid.Nil {
+			logging.AddAttrs(ctx, "impersonator_id", adminID)
+			w.Header().Set(ImpersonatedByHeader, adminID.String())
+		}
+		errorreport.SetUser(ctx, GetUserIDFromContext(ctx).String())
+		i18n.SetUser(ctx, GetUserIDFromContext(ctx))
+		opstats.RecordActive(ctx, GetUserIDFromContext(ctx))
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// TokenState is what decides whether the tokens of a user's device are
+// still valid
+type TokenState struct {
+	Deactivated bool
+	// UserRevokedAt is when the user last signed out everywhere, and
+	// DeviceRevokedAt when the device was revoked, nil if never
+	UserRevokedAt, DeviceRevokedAt *time.Time
+}
+
+// TokenStore looks up the state of tokens
+type TokenStore interface {
+	// TokenState returns the state of the tokens of a user's device, nil if
+	// there is no such user
+	TokenState(ctx context.Context, userID uuid.UUID, deviceID string) (*TokenState, error)
+}
+
+// Tokens is where Middleware checks for revoked tokens. Tests can replace
+// it, as package memstore does.
+var Tokens TokenStore = pgTokens{}
+
+// pgTokens reads the state of tokens from Postgres
+type pgTokens struct{}
+
+var tokenRevokedSQL = db.Statement("token_revoked", `
+	SELECT u.deactivated_at IS NOT NULL, u.tokens_revoked_at, d.revoked_at
+	FROM users u
+	LEFT JOIN device_sync d ON d.user_id = u.id AND d.device_id = $2
+	WHERE u.id = $1`)
+
+func (pgTokens) TokenState(ctx context.Context, userID uuid.UUID, deviceID string) (*TokenState, error) {
+	var state TokenState
+	err := db.Pool.QueryRow(ctx, tokenRevokedSQL, userID, deviceID).Scan(&state.Deactivated, &state.UserRevokedAt, &state.DeviceRevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// tokenRevoked reports whether the token's user deactivated their account,
+// and whether the user is gone, signed out everywhere or had the token's
+// device revoked after this token was issued
+func tokenRevoked(ctx context.Context, claims *Claims) (deactivated, revoked bool, err error) {
+	state, err := Tokens.TokenState(ctx, claims.UserID, claims.DeviceID)
+	if err != nil {
+		return false, false, err
+	}
+	if state == nil {
+		return false, true, nil
+	}
+	deactivated = state.Deactivated
+	userRevokedAt, deviceRevokedAt := state.UserRevokedAt, state.DeviceRevokedAt
+	if (userRevokedAt != nil || deviceRevokedAt != nil) && claims.IssuedAt == nil {
+		return deactivated, true, nil
+	}
+	// Signing out everywhere is recorded to the second, as tokens are
+	// issued, so tokens issued right after it in the same second are kept
+	if userRevokedAt != nil && claims.IssuedAt.Time.Before(*userRevokedAt) {
+		return deactivated, true, nil
+	}
+	if deviceRevokedAt != nil && !claims.IssuedAt.Time.After(*deviceRevokedAt) {
+		return deactivated, true, nil
+	}
+	return deactivated, false, nil
+}
+
 func GetUserIDFromContext(ctx context.Context) uuid.UUID {
 	if userID, ok := ctx.Value(UserIDKey).(uuid.UUID); ok {
 		return userID
 	}
 	return uuid.Nil
 }
+
+func GetDeviceIDFromContext(ctx context.Context) string {
+	if deviceID, ok := ctx.Value(DeviceIDKey).(string); ok {
+		return deviceID
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+// OrganizationHeader selects the organization a request acts on. Without it
+// requests run in the user's personal scope.
+const OrganizationHeader = "X-Organization-ID"
+
+// WorkspaceHeader selects the workspace a request acts on: "personal" or an
+// organization ID. It overrides the workspace stored in the token.
+const WorkspaceHeader = "X-Workspace-ID"
+
+// PersonalWorkspace names the user's personal workspace in WorkspaceHeader
+const PersonalWorkspace = "personal"
+
+const OrganizationIDKey userContextKey = "organization_id"
+const OrganizationRoleKey userContextKey = "organization_role"
+
+// OrganizationMiddleware puts the active workspace's organization and the
+// user's role in it into the request context. The workspace comes from the
+// X-Workspace-ID or X-Organization-ID header, falling back to the token's
+// workspace claim. Non-members are rejected. It must run after Middleware.
+func OrganizationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, authErr := SelectWorkspace(r.Context(), r.Header.Get(WorkspaceHeader), r.Header.Get(OrganizationHeader))
+		if authErr != nil {
+			apierror.Write(w, r, authErr.Status, authErr.Code, authErr.Message, nil)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// SelectWorkspace puts the organization chosen by the workspace and
+// organization header values, and the user's role in it, into ctx. ctx must
+// come from Authenticate.
+func SelectWorkspace(ctx context.Context, workspace, organization string) (context.Context, *AuthError) {
+	orgID, err := requestedWorkspace(ctx, workspace, organization)
+	if err != nil {
+		return nil, &AuthError{http.StatusBadRequest, apierror.InvalidRequest, err.Error()}
+	}
+	if orgID == uuid.Nil {
+		return ctx, nil
+	}
+
+	role, err := models.GetMemberRole(ctx, orgID, GetUserIDFromContext(ctx))
+	if err != nil {
+		return nil, &AuthError{http.StatusInternalServerError, apierror.Internal, "Failed to verify organization membership"}
+	}
+	if role == "" {
+		return nil, &AuthError{http.StatusForbidden, apierror.NotAMember, "Not a member of this organization"}
+	}
+
+	ctx = context.WithValue(ctx, OrganizationIDKey, orgID)
+	ctx = context.WithValue(ctx, OrganizationRoleKey, role)
+	return ctx, nil
+}
+
+// requestedWorkspace returns the organization the request selects, or
+// uuid.Nil for the personal workspace
+func requestedWorkspace(ctx context.Context, workspace, organization string) (uuid.UUID, error) {
+	var orgID uuid.UUID
+	switch {
+	case workspace == PersonalWorkspace:
+		orgID = uuid.Nil
+	case workspace != "":
+		id, err := uuid.Parse(workspace)
+		if err != nil {
+			return uuid.Nil, errors.New("Invalid workspace ID")
+		}
+		orgID = id
+	case organization != "":
+		id, err := uuid.Parse(organization)
+		if err != nil {
+			return uuid.Nil, errors.New("Invalid organization ID")
+		}
+		return id, nil
+	default:
+		return GetWorkspaceIDFromContext(ctx), nil
+	}
+
+	if organization != "" {
+		if id, err := uuid.Parse(organization); err != nil || id != orgID {
+			return uuid.Nil, errors.New("X-Workspace-ID and X-Organization-ID disagree")
+		}
+	}
+	return orgID, nil
+}
+
+// GetOrganizationIDFromContext returns the active organization, or uuid.Nil
+// in personal scope
+func GetOrganizationIDFromContext(ctx context.Context) uuid.UUID {
+	if orgID, ok := ctx.Value(OrganizationIDKey).(uuid.UUID); ok {
+		return orgID
+	}
+	return uuid.Nil
+}
+
+// GetOrganizationRoleFromContext returns the user's role in the active
+// organization, or "" in personal scope
+func GetOrganizationRoleFromContext(ctx context.Context) string {
+	if role, ok := ctx.Value(OrganizationRoleKey).(string); ok {
+		return role
+	}
+	return ""
+}
+
+// GetWorkspaceIDFromContext returns the organization stored as the token's
+// workspace, or uuid.Nil for the personal workspace. Unlike
+// GetOrganizationIDFromContext it ignores the request headers.
+func GetWorkspaceIDFromContext(ctx context.Context) uuid.UUID {
+	if orgID, ok := ctx.Value(WorkspaceIDKey).(uuid.UUID); ok {
+		return orgID
+	}
+	return uuid.Nil
+}
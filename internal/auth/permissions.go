@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+// Permission is an action that organization roles may grant
+type Permission string
+
+const (
+	PermLogTime            Permission = "sessions:log"
+	PermManageProjects     Permission = "projects:manage"
+	PermViewReports        Permission = "reports:view"
+	PermManageSettings     Permission = "settings:manage"
+	PermManageMembers      Permission = "members:manage"
+	PermManageOrganization Permission = "organization:manage"
+)
+
+var rolePermissions = map[string]map[Permission]bool{
+	models.RoleOwner: {
+		PermLogTime:            true,
+		PermManageProjects:     true,
+		PermViewReports:        true,
+		PermManageSettings:     true,
+		PermManageMembers:      true,
+		PermManageOrganization: true,
+	},
+	models.RoleAdmin: {
+		PermLogTime:        true,
+		PermManageProjects: true,
+		PermViewReports:    true,
+		PermManageSettings: true,
+		PermManageMembers:  true,
+	},
+	models.RoleMember: {
+		PermLogTime: true,
+	},
+}
+
+// RoleHas reports whether an organization role grants perm
+func RoleHas(role string, perm Permission) bool {
+	return rolePermissions[role][perm]
+}
+
+// Can reports whether the request may perform perm in its active scope.
+// Everything is allowed in the personal scope.
+func Can(ctx context.Context, perm Permission) bool {
+	if GetOrganizationIDFromContext(ctx) == uuid.Nil {
+		return true
+	}
+	return RoleHas(GetOrganizationRoleFromContext(ctx), perm)
+}
+
+// RequirePermission rejects requests that may not perform perm in their
+// active scope. It must run after OrganizationMiddleware.
+func RequirePermission(perm Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !Can(r.Context(), perm) {
+				apierror.Error(w, r, "Insufficient permissions", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
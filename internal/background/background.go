@@ -0,0 +1,36 @@
+// Package background tracks work running off the request path, such as
+// webhook deliveries, imports and the periodic jobs, so that shutdown can
+// wait for it before closing the database.
+package background
+
+import (
+	"context"
+	"sync"
+)
+
+var running sync.WaitGroup
+
+// Go runs f in a new goroutine that Wait waits for
+func Go(f func()) {
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		f()
+	}()
+}
+
+// Wait waits until the work started with Go has finished or ctx is done.
+// It reports whether everything finished.
+func Wait(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,459 @@
+// Package backup exports and imports the data of the whole instance or of
+// one user as a portable archive, for self-hosters without managed database
+// backups. An archive is a gzip-compressed stream of JSON lines: a manifest,
+// then one line per row naming its table, parents before children. It is
+// read back into a database at the same schema version.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// FormatVersion is the version of the archive layout
+const FormatVersion = 1
+
+// restoreBatchSize is how many rows are inserted per statement
+const restoreBatchSize = 500
+
+var (
+	// ErrInvalidArchive is returned for input that is not an archive
+	ErrInvalidArchive = errors.New("invalid backup archive")
+	// ErrSchemaMismatch is returned for an archive taken at another schema
+	// version than the database's
+	ErrSchemaMismatch = errors.New("backup archive is from another schema version")
+)
+
+// Manifest describes an archive
+type Manifest struct {
+	FormatVersion int `json:"format_version"`
+	// SchemaVersion is the migration version of the database it was taken
+	// from
+	SchemaVersion uint64    `json:"schema_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	// UserID is the user whose data the archive holds, nil for the whole
+	// instance
+	UserID *uuid.UUID `json:"user_id,omitempty"`
+	// Tables lists the tables of the archive in the order of their rows
+	Tables []string `json:"tables"`
+}
+
+// line is one line of an archive: the manifest or a row
+type line struct {
+	Manifest *Manifest       `json:"manifest,omitempty"`
+	Table    string          `json:"table,omitempty"`
+	Row      json.RawMessage `json:"row,omitempty"`
+}
+
+// Backup writes an archive of a consistent snapshot of the instance, or of
+// the user's own data when userID is set: their account and the rows that
+// belong to it, leaving out organizations and what refers to them
+func Backup(ctx context.Context, w io.Writer, userID *uuid.UUID) error {
+	version, err := db.MigrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	// Reading a whole table takes as long as it takes; cancelling ctx still
+	// stops it
+	if err := db.SetLocalStatementTimeout(ctx, tx, 0); err != nil {
+		return err
+	}
+
+	tables, err := loadTables(ctx, tx)
+	if err != nil {
+		return err
+	}
+	filters := map[string]string{}
+	var args []interface{}
+	if userID != nil {
+		filters = userFilters(tables)
+		args = []interface{}{*userID}
+	}
+
+	manifest := Manifest{FormatVersion: FormatVersion, SchemaVersion: version, CreatedAt: time.Now().UTC(), UserID: userID}
+	for _, t := range tables {
+		if _, ok := filters[t.name]; ok || userID == nil {
+			manifest.Tables = append(manifest.Tables, t.name)
+		}
+	}
+
+	gz := gzip.NewWriter(w)
+	enc := json.NewEncoder(gz)
+	if err := enc.Encode(line{Manifest: &manifest}); err != nil {
+		return err
+	}
+	for _, name := range manifest.Tables {
+		query := `SELECT row_to_json(t)::text FROM ` + pgx.Identifier{name}.Sanitize() + ` t`
+		if filter, ok := filters[name]; ok {
+			query += ` WHERE ` + filter
+		}
+		if err := exportTable(ctx, tx, enc, name, query, args); err != nil {
+			return fmt.Errorf("error exporting %s: %v", name, err)
+		}
+	}
+	return gz.Close()
+}
+
+func exportTable(ctx context.Context, tx pgx.Tx, enc *json.Encoder, name, query string, args []interface{}) error {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row string
+		if err := rows.Scan(&row); err != nil {
+			return err
+		}
+		if err := enc.Encode(line{Table: name, Row: json.RawMessage(row)}); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Restore reads an archive into the database in one transaction and
+// returns its manifest with the number of rows inserted per table. Rows
+// whose key already exists are skipped, so an instance archive is meant
+// for an empty database and a user archive for any instance without a
+// conflicting account.
+func Restore(ctx context.Context, r io.Reader) (*Manifest, map[string]int64, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, ErrInvalidArchive
+	}
+	dec := json.NewDecoder(gz)
+
+	var first line
+	if err := dec.Decode(&first); err != nil || first.Manifest == nil {
+		return nil, nil, ErrInvalidArchive
+	}
+	manifest := first.Manifest
+	if manifest.FormatVersion != FormatVersion {
+		return nil, nil, fmt.Errorf("%w: unsupported format version %d", ErrInvalidArchive, manifest.FormatVersion)
+	}
+	version, err := db.MigrationVersion(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if manifest.SchemaVersion != version {
+		return nil, nil, fmt.Errorf("%w: archive is at %d, database at %d", ErrSchemaMismatch, manifest.SchemaVersion, version)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+	if err := db.SetLocalStatementTimeout(ctx, tx, 0); err != nil {
+		return nil, nil, err
+	}
+
+	tables, err := loadTables(ctx, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	byName := make(map[string]table, len(tables))
+	for _, t := range tables {
+		byName[t.name] = t
+	}
+
+	counts := make(map[string]int64)
+	var current string
+	var batch []json.RawMessage
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := insertRows(ctx, tx, byName[current], batch)
+		if err != nil {
+			return fmt.Errorf("error restoring %s: %v", current, err)
+		}
+		counts[current] += n
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		var l line
+		err := dec.Decode(&l)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil || l.Table == "" || l.Row == nil {
+			return nil, nil, ErrInvalidArchive
+		}
+		if _, ok := byName[l.Table]; !ok {
+			return nil, nil, fmt.Errorf("%w: unknown table %s", ErrInvalidArchive, l.Table)
+		}
+		if l.Table != current || len(batch) == restoreBatchSize {
+			if err := flush(); err != nil {
+				return nil, nil, err
+			}
+			current = l.Table
+		}
+		batch = append(batch, l.Row)
+	}
+	if err := flush(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := resetSequences(ctx, tx); err != nil {
+		return nil, nil, err
+	}
+	return manifest, counts, tx.Commit(ctx)
+}
+
+// insertRows inserts rows of t given as JSON objects, skipping existing ones
+func insertRows(ctx context.Context, tx pgx.Tx, t table, rows []json.RawMessage) (int64, error) {
+	encoded, err := json.Marshal(rows)
+	if err != nil {
+		return 0, err
+	}
+	columns := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		columns[i] = pgx.Identifier{column}.Sanitize()
+	}
+	list := strings.Join(columns, ", ")
+	name := pgx.Identifier{t.name}.Sanitize()
+	result, err := tx.Exec(ctx, `
+		INSERT INTO `+name+` (`+list+`)
+		SELECT `+list+` FROM json_populate_recordset(NULL::`+name+`, $1::json)
+		ON CONFLICT DO NOTHING
+	`, string(encoded))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// resetSequences moves the sequences of serial columns past the restored
+// values
+func resetSequences(ctx context.Context, tx pgx.Tx) error {
+	rows, err := tx.Query(ctx, `
+		SELECT table_name, column_name FROM information_schema.columns
+		WHERE table_schema = 'public' AND column_default LIKE 'nextval(%'
+	`)
+	if err != nil {
+		return err
+	}
+	type serial struct{ table, column string }
+	var serials []serial
+	for rows.Next() {
+		var s serial
+		if err := rows.Scan(&s.table, &s.column); err != nil {
+			rows.Close()
+			return err
+		}
+		serials = append(serials, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, s := range serials {
+		_, err := tx.Exec(ctx, `
+			SELECT setval(pg_get_serial_sequence($1, $2), COALESCE(MAX(`+pgx.Identifier{s.column}.Sanitize()+`), 0) + 1, false)
+			FROM `+pgx.Identifier{s.table}.Sanitize(),
+			s.table, s.column)
+		if err != nil {
+			return fmt.Errorf("error resetting sequence of %s.%s: %v", s.table, s.column, err)
+		}
+	}
+	return nil
+}
+
+// table is a table of the schema
+type table struct {
+	name string
+	// columns are the stored columns, without generated ones
+	columns []string
+	fks     []foreignKey
+}
+
+// foreignKey is a single-column foreign key
+type foreignKey struct {
+	column    string
+	refTable  string
+	refColumn string
+}
+
+// loadTables reads the tables of the schema, except schema_migrations and
+// the partitions of partitioned tables, parents before the tables
+// referring to them
+func loadTables(ctx context.Context, tx pgx.Tx) ([]table, error) {
+	byName := make(map[string]*table)
+	rows, err := tx.Query(ctx, `
+		SELECT c.relname
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = 'public' AND c.relkind IN ('r', 'p') AND NOT c.relispartition
+			AND c.relname <> 'schema_migrations'
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		byName[name] = &table{name: name}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT table_name, column_name FROM information_schema.columns
+		WHERE table_schema = 'public' AND is_generated = 'NEVER'
+		ORDER BY table_name, ordinal_position
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name, column string
+		if err := rows.Scan(&name, &column); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if t, ok := byName[name]; ok {
+			t.columns = append(t.columns, column)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT cl.relname, a.attname, rcl.relname, ra.attname
+		FROM pg_constraint con
+		JOIN pg_class cl ON cl.oid = con.conrelid
+		JOIN pg_class rcl ON rcl.oid = con.confrelid
+		JOIN pg_namespace n ON n.oid = cl.relnamespace
+		JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = con.conkey[1]
+		JOIN pg_attribute ra ON ra.attrelid = con.confrelid AND ra.attnum = con.confkey[1]
+		WHERE con.contype = 'f' AND n.nspname = 'public' AND cardinality(con.conkey) = 1
+		ORDER BY a.attname
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name string
+		var fk foreignKey
+		if err := rows.Scan(&name, &fk.column, &fk.refTable, &fk.refColumn); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		// Partitions repeat the keys of their table
+		if t, ok := byName[name]; ok {
+			t.fks = append(t.fks, fk)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sortTables(byName), nil
+}
+
+// sortTables orders tables so that each comes after the tables it refers
+// to, by name where the order is free
+func sortTables(byName map[string]*table) []table {
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sorted []table
+	done := make(map[string]bool)
+	for len(sorted) < len(names) {
+		progressed := false
+		for _, name := range names {
+			if done[name] {
+				continue
+			}
+			ready := true
+			for _, fk := range byName[name].fks {
+				if fk.refTable != name && !done[fk.refTable] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				sorted = append(sorted, *byName[name])
+				done[name] = true
+				progressed = true
+			}
+		}
+		// Tables in a reference cycle keep their name order
+		if !progressed {
+			for _, name := range names {
+				if !done[name] {
+					sorted = append(sorted, *byName[name])
+					done[name] = true
+				}
+			}
+		}
+	}
+	return sorted
+}
+
+// userFilters returns for each table holding data of the user in $1 the
+// condition selecting it: the user's row of users, and rows referring to
+// selected rows and to no other. A reference to a table outside the user's data, such
+// as an organization, must be NULL. tables are sorted by sortTables.
+func userFilters(tables []table) map[string]string {
+	filters := map[string]string{"users": "id = $1"}
+	for _, t := range tables {
+		if t.name == "users" {
+			continue
+		}
+		var conditions, owners []string
+		for _, fk := range t.fks {
+			if fk.refTable == t.name {
+				continue
+			}
+			column := pgx.Identifier{fk.column}.Sanitize()
+			parent, ok := filters[fk.refTable]
+			if !ok {
+				conditions = append(conditions, column+" IS NULL")
+				continue
+			}
+			owners = append(owners, column+" IS NOT NULL")
+			conditions = append(conditions, fmt.Sprintf("(%s IS NULL OR %s IN (SELECT %s FROM %s WHERE %s))",
+				column, column, pgx.Identifier{fk.refColumn}.Sanitize(), pgx.Identifier{fk.refTable}.Sanitize(), parent))
+		}
+		// Rows must refer to some of the user's data
+		if len(owners) > 0 {
+			conditions = append(conditions, "("+strings.Join(owners, " OR ")+")")
+			filters[t.name] = strings.Join(conditions, " AND ")
+		}
+	}
+	return filters
+}
@@ -0,0 +1,132 @@
+// Package billing sells subscriptions to the hosted instance through
+// Stripe. Users start on the free plan and subscribe to Pro or Team through
+// Stripe Checkout; Stripe's webhooks then keep the subscriptions table in
+// step with payments and cancellations. Without Stripe configured, as on
+// self-hosted servers, billing is off and every user is entitled to
+// everything.
+package billing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// ErrNotConfigured is returned when billing without Stripe configured
+var ErrNotConfigured = errors.New("billing is not configured")
+
+// Plans
+const (
+	PlanFree = "free"
+	PlanPro  = "pro"
+	PlanTeam = "team"
+)
+
+// Plans lists the plans, each including what the ones before it do
+var Plans = []string{PlanFree, PlanPro, PlanTeam}
+
+// PaidPlans are the plans users can subscribe to
+var PaidPlans = []string{PlanPro, PlanTeam}
+
+// rank orders the plans; unknown plans rank as free
+func rank(plan string) int {
+	for i, p := range Plans {
+		if p == plan {
+			return i
+		}
+	}
+	return 0
+}
+
+// ValidPaidPlan reports whether users can subscribe to plan
+func ValidPaidPlan(plan string) bool {
+	for _, p := range PaidPlans {
+		if p == plan {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription statuses, as Stripe reports them, that keep the plan
+// subscribed to. A past due subscription keeps it while Stripe retries
+// the payment.
+var activeStatuses = map[string]bool{"active": true, "trialing": true, "past_due": true}
+
+// Subscription is a user's plan and the state of its payments
+type Subscription struct {
+	// Plan is the plan the user is entitled to: the one subscribed to
+	// while the subscription is active, and free otherwise
+	Plan string `json:"plan"`
+	// Status is Stripe's status of the subscription, empty for users who
+	// never subscribed
+	Status            string     `json:"status,omitempty"`
+	CurrentPeriodEnd  *time.Time `json:"current_period_end,omitempty"`
+	CancelAtPeriodEnd bool       `json:"cancel_at_period_end"`
+	// Limits are the limits of the plan
+	Limits Limits `json:"limits"`
+
+	customerID     string
+	subscriptionID string
+}
+
+// Active reports whether the subscription entitles the user to a paid plan
+func (s *Subscription) Active() bool {
+	return s.Plan != PlanFree
+}
+
+// Get returns the user's subscription; users who never subscribed are on
+// the free plan
+func Get(ctx context.Context, userID uuid.UUID) (*Subscription, error) {
+	var s Subscription
+	var customerID, subscriptionID *string
+	err := db.Pool.QueryRow(ctx, `
+		SELECT plan, status, stripe_customer_id, stripe_subscription_id, current_period_end, cancel_at_period_end
+		FROM subscriptions WHERE user_id = $1
+	`, userID).Scan(&s.Plan, &s.Status, &customerID, &subscriptionID, &s.CurrentPeriodEnd, &s.CancelAtPeriodEnd)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &Subscription{Plan: PlanFree, Limits: PlanLimits[PlanFree]}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !activeStatuses[s.Status] {
+		s.Plan = PlanFree
+	}
+	s.Limits = PlanLimits[s.Plan]
+	if customerID != nil {
+		s.customerID = *customerID
+	}
+	if subscriptionID != nil {
+		s.subscriptionID = *subscriptionID
+	}
+	return &s, nil
+}
+
+// PlanOf returns the plan the user is entitled to. With billing off every
+// user is entitled to the top plan.
+func PlanOf(ctx context.Context, userID uuid.UUID) (string, error) {
+	if !Enabled() {
+		return Plans[len(Plans)-1], nil
+	}
+	s, err := Get(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return s.Plan, nil
+}
+
+// Entitled reports whether the user is entitled to what plan includes,
+// always true with billing off. Handlers check it before features reserved
+// to paid plans.
+func Entitled(ctx context.Context, userID uuid.UUID, plan string) (bool, error) {
+	current, err := PlanOf(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return rank(current) >= rank(plan), nil
+}
@@ -0,0 +1,193 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+// Limits cap what the workspaces on a plan may have. Zero is unlimited.
+type Limits struct {
+	// MaxProjects caps the live projects of a workspace
+	MaxProjects int `json:"max_projects"`
+	// ReportHistoryDays is how many days back reports may start
+	ReportHistoryDays int `json:"report_history_days"`
+	// MaxWebhooks caps the webhooks of a user
+	MaxWebhooks int `json:"max_webhooks"`
+	// MaxTeamMembers caps the active members of an organization
+	MaxTeamMembers int `json:"max_team_members"`
+}
+
+// Names of the limits, as reported in errors and set in the environment
+const (
+	LimitProjects      = "max_projects"
+	LimitReportHistory = "report_history_days"
+	LimitWebhooks      = "max_webhooks"
+	LimitTeamMembers   = "max_team_members"
+)
+
+// PlanLimits are the limits of each plan, overridden by LoadEnv
+var PlanLimits = map[string]Limits{
+	PlanFree: {MaxProjects: 5, ReportHistoryDays: 90, MaxWebhooks: 2, MaxTeamMembers: 3},
+	PlanPro:  {MaxWebhooks: 20, MaxTeamMembers: 10},
+	PlanTeam: {},
+}
+
+// get returns the limit named name
+func (l *Limits) get(name string) *int {
+	switch name {
+	case LimitProjects:
+		return &l.MaxProjects
+	case LimitReportHistory:
+		return &l.ReportHistoryDays
+	case LimitWebhooks:
+		return &l.MaxWebhooks
+	case LimitTeamMembers:
+		return &l.MaxTeamMembers
+	}
+	return nil
+}
+
+// loadLimits overrides the limits of each plan with PLAN_<PLAN>_<LIMIT>,
+// as in PLAN_FREE_MAX_PROJECTS=10
+func loadLimits() error {
+	for _, plan := range Plans {
+		limits := PlanLimits[plan]
+		for _, name := range []string{LimitProjects, LimitReportHistory, LimitWebhooks, LimitTeamMembers} {
+			key := "PLAN_" + strings.ToUpper(plan) + "_" + strings.ToUpper(name)
+			value := os.Getenv(key)
+			if value == "" {
+				continue
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return fmt.Errorf("%s must be a number of at least 0", key)
+			}
+			*limits.get(name) = n
+		}
+		PlanLimits[plan] = limits
+	}
+	return nil
+}
+
+// LimitError is a request over a limit of the workspace's plan
+type LimitError struct {
+	// Limit names the limit, such as LimitProjects, and Max is its value
+	Limit string
+	Max   int
+	Plan  string
+	// CanUpgrade is false in organizations the user does not own, whose
+	// owners have to upgrade instead
+	CanUpgrade bool
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("the %s plan allows %s of %d", e.Plan, e.Limit, e.Max)
+}
+
+// Message describes the limit to the user
+func (e *LimitError) Message() string {
+	var allows string
+	switch e.Limit {
+	case LimitProjects:
+		allows = fmt.Sprintf("at most %d projects", e.Max)
+	case LimitReportHistory:
+		allows = fmt.Sprintf("reports of the last %d days", e.Max)
+	case LimitWebhooks:
+		allows = fmt.Sprintf("at most %d webhooks", e.Max)
+	case LimitTeamMembers:
+		allows = fmt.Sprintf("at most %d active members", e.Max)
+	}
+	if e.CanUpgrade {
+		return fmt.Sprintf("Your plan allows %s; upgrade to raise the limit", allows)
+	}
+	return fmt.Sprintf("The organization's plan allows %s; ask an owner to upgrade", allows)
+}
+
+// Workspace is the plan a workspace is on: a user's personal workspace is
+// on the user's plan, and an organization on the best plan of its owners
+type Workspace struct {
+	Plan   string
+	Limits Limits
+	// CanUpgrade reports whether the user can raise the limits: in their
+	// personal workspace and in organizations they own
+	CanUpgrade bool
+}
+
+// WorkspaceOf returns the plan of the user's personal workspace, with a
+// nil orgID, or of the organization. With billing off every workspace is
+// unlimited.
+func WorkspaceOf(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (*Workspace, error) {
+	if !Enabled() {
+		return &Workspace{Plan: Plans[len(Plans)-1], CanUpgrade: true}, nil
+	}
+	if orgID == nil {
+		plan, err := PlanOf(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		return &Workspace{Plan: plan, Limits: PlanLimits[plan], CanUpgrade: true}, nil
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT m.user_id, COALESCE(s.plan, $3), COALESCE(s.status, '')
+		FROM memberships m
+		LEFT JOIN subscriptions s ON s.user_id = m.user_id
+		WHERE m.organization_id = $1 AND m.role = $2 AND m.deactivated_at IS NULL
+	`, *orgID, models.RoleOwner, PlanFree)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ws := &Workspace{Plan: PlanFree}
+	for rows.Next() {
+		var ownerID uuid.UUID
+		var plan, status string
+		if err := rows.Scan(&ownerID, &plan, &status); err != nil {
+			return nil, err
+		}
+		if activeStatuses[status] && rank(plan) > rank(ws.Plan) {
+			ws.Plan = plan
+		}
+		if ownerID == userID {
+			ws.CanUpgrade = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	ws.Limits = PlanLimits[ws.Plan]
+	return ws, nil
+}
+
+// CheckCount returns a *LimitError if count, what the workspace would have
+// after the change, is over the named limit
+func (ws *Workspace) CheckCount(limit string, count int) error {
+	max := *ws.Limits.get(limit)
+	if max > 0 && count > max {
+		return ws.limitError(limit, max)
+	}
+	return nil
+}
+
+// CheckHistory returns a *LimitError if a report starting at start reaches
+// further back than the plan allows. A day of leeway covers the time zone
+// the report's dates are in.
+func (ws *Workspace) CheckHistory(start time.Time) error {
+	days := ws.Limits.ReportHistoryDays
+	if days > 0 && start.Before(time.Now().AddDate(0, 0, -days-1)) {
+		return ws.limitError(LimitReportHistory, days)
+	}
+	return nil
+}
+
+func (ws *Workspace) limitError(limit string, max int) *LimitError {
+	return &LimitError{Limit: limit, Max: max, Plan: ws.Plan, CanUpgrade: ws.CanUpgrade}
+}
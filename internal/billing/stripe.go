@@ -0,0 +1,352 @@
+package billing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// ErrAlreadySubscribed is returned when checking out while subscribed;
+// plans are changed and canceled in the billing portal instead
+var ErrAlreadySubscribed = errors.New("already subscribed")
+
+// ErrNoCustomer is returned when opening the billing portal before ever
+// subscribing
+var ErrNoCustomer = errors.New("not a Stripe customer")
+
+const stripeAPI = "https://api.stripe.com/v1"
+
+// webhookTolerance rejects replayed webhooks
+const webhookTolerance = 5 * time.Minute
+
+// Stripe is the Stripe account subscriptions are sold through
+type Stripe struct {
+	SecretKey string
+	// WebhookSecret is the signing secret of the webhook endpoint
+	WebhookSecret string
+	// Prices maps each paid plan to the id of its recurring price
+	Prices map[string]string
+	// SuccessURL is where users land after checking out, and ReturnURL
+	// where they go back to from a canceled checkout or the billing portal
+	SuccessURL string
+	ReturnURL  string
+}
+
+var (
+	stripe *Stripe
+	client = &http.Client{Timeout: 30 * time.Second}
+)
+
+// Configure sets the Stripe account; nil turns billing off
+func Configure(s *Stripe) {
+	stripe = s
+}
+
+// Enabled reports whether billing is on
+func Enabled() bool {
+	return stripe != nil
+}
+
+// LoadEnv configures Stripe from STRIPE_SECRET_KEY, STRIPE_WEBHOOK_SECRET,
+// the prices in STRIPE_PRICE_PRO and STRIPE_PRICE_TEAM, BILLING_SUCCESS_URL
+// and BILLING_RETURN_URL, and the limits of the plans. Billing stays off
+// without STRIPE_SECRET_KEY.
+func LoadEnv() error {
+	if err := loadLimits(); err != nil {
+		return err
+	}
+	s := &Stripe{
+		SecretKey:     os.Getenv("STRIPE_SECRET_KEY"),
+		WebhookSecret: os.Getenv("STRIPE_WEBHOOK_SECRET"),
+		Prices: map[string]string{
+			PlanPro:  os.Getenv("STRIPE_PRICE_PRO"),
+			PlanTeam: os.Getenv("STRIPE_PRICE_TEAM"),
+		},
+		SuccessURL: os.Getenv("BILLING_SUCCESS_URL"),
+		ReturnURL:  os.Getenv("BILLING_RETURN_URL"),
+	}
+	if s.SecretKey == "" {
+		Configure(nil)
+		return nil
+	}
+	if s.WebhookSecret == "" || s.Prices[PlanPro] == "" || s.Prices[PlanTeam] == "" {
+		return errors.New("STRIPE_WEBHOOK_SECRET, STRIPE_PRICE_PRO and STRIPE_PRICE_TEAM are required with STRIPE_SECRET_KEY")
+	}
+	if s.SuccessURL == "" || s.ReturnURL == "" {
+		return errors.New("BILLING_SUCCESS_URL and BILLING_RETURN_URL are required with STRIPE_SECRET_KEY")
+	}
+	Configure(s)
+	return nil
+}
+
+// planOfPrice returns the plan sold at a price, free for unknown prices
+func planOfPrice(price string) string {
+	for plan, p := range stripe.Prices {
+		if p == price {
+			return plan
+		}
+	}
+	return PlanFree
+}
+
+// Checkout creates a Stripe Checkout session subscribing the user to plan
+// and returns its URL. The subscription is recorded once Stripe reports
+// the checkout completed.
+func Checkout(ctx context.Context, userID uuid.UUID, email, plan string) (string, error) {
+	if !Enabled() {
+		return "", ErrNotConfigured
+	}
+	sub, err := Get(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if sub.Active() {
+		return "", ErrAlreadySubscribed
+	}
+
+	form := url.Values{
+		"mode":                                 {"subscription"},
+		"line_items[0][price]":                 {stripe.Prices[plan]},
+		"line_items[0][quantity]":              {"1"},
+		"success_url":                          {stripe.SuccessURL},
+		"cancel_url":                           {stripe.ReturnURL},
+		"client_reference_id":                  {userID.String()},
+		"subscription_data[metadata][user_id]": {userID.String()},
+	}
+	// Returning customers keep their payment methods and invoices
+	if sub.customerID != "" {
+		form.Set("customer", sub.customerID)
+	} else {
+		form.Set("customer_email", email)
+	}
+
+	var session struct {
+		URL string `json:"url"`
+	}
+	if err := stripeCall(ctx, http.MethodPost, "/checkout/sessions", form, &session); err != nil {
+		return "", err
+	}
+	return session.URL, nil
+}
+
+// Portal creates a session of Stripe's billing portal, where the user
+// changes or cancels their plan and updates their payment method, and
+// returns its URL
+func Portal(ctx context.Context, userID uuid.UUID) (string, error) {
+	if !Enabled() {
+		return "", ErrNotConfigured
+	}
+	sub, err := Get(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if sub.customerID == "" {
+		return "", ErrNoCustomer
+	}
+
+	var session struct {
+		URL string `json:"url"`
+	}
+	form := url.Values{"customer": {sub.customerID}, "return_url": {stripe.ReturnURL}}
+	if err := stripeCall(ctx, http.MethodPost, "/billing_portal/sessions", form, &session); err != nil {
+		return "", err
+	}
+	return session.URL, nil
+}
+
+// VerifyWebhook checks the Stripe-Signature header of a webhook against
+// its payload
+func VerifyWebhook(payload []byte, header string) bool {
+	if !Enabled() {
+		return false
+	}
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(sec, 0)); age > webhookTolerance || age < -webhookTolerance {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(stripe.WebhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := []byte(hex.EncodeToString(mac.Sum(nil)))
+	for _, signature := range signatures {
+		if hmac.Equal(expected, []byte(signature)) {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleEvent applies a verified webhook event. Rather than trusting the
+// event's copy, the subscription it is about is fetched from Stripe, so
+// events delivered late or out of order still leave it current. Events of
+// other types are ignored.
+func HandleEvent(ctx context.Context, payload []byte) error {
+	var event struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object json.RawMessage `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("error reading Stripe event: %v", err)
+	}
+
+	var object struct {
+		ID           string `json:"id"`
+		Subscription string `json:"subscription"`
+	}
+	if err := json.Unmarshal(event.Data.Object, &object); err != nil {
+		return fmt.Errorf("error reading Stripe event %s: %v", event.ID, err)
+	}
+
+	switch event.Type {
+	case "checkout.session.completed":
+		if object.Subscription == "" {
+			return nil
+		}
+		return syncSubscription(ctx, object.Subscription)
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted":
+		return syncSubscription(ctx, object.ID)
+	case "invoice.paid", "invoice.payment_failed":
+		if object.Subscription == "" {
+			return nil
+		}
+		return syncSubscription(ctx, object.Subscription)
+	}
+	return nil
+}
+
+// stripeSubscription holds the fields of a Stripe subscription read here.
+// Newer API versions report the billing period on the items.
+type stripeSubscription struct {
+	ID                string            `json:"id"`
+	Customer          string            `json:"customer"`
+	Status            string            `json:"status"`
+	CancelAtPeriodEnd bool              `json:"cancel_at_period_end"`
+	CurrentPeriodEnd  int64             `json:"current_period_end"`
+	Metadata          map[string]string `json:"metadata"`
+	Items             struct {
+		Data []struct {
+			CurrentPeriodEnd int64 `json:"current_period_end"`
+			Price            struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// syncSubscription fetches a subscription from Stripe and records it for
+// the user in its metadata. The user's current subscription is only
+// replaced by another while that one is active, so a late event about an
+// older, canceled subscription does not override a newer one.
+func syncSubscription(ctx context.Context, subscriptionID string) error {
+	var s stripeSubscription
+	if err := stripeCall(ctx, http.MethodGet, "/subscriptions/"+url.PathEscape(subscriptionID), nil, &s); err != nil {
+		return err
+	}
+	userID, err := uuid.Parse(s.Metadata["user_id"])
+	if err != nil {
+		slog.Warn("Stripe subscription without a user", "subscription_id", s.ID)
+		return nil
+	}
+
+	plan := PlanFree
+	periodEnd := s.CurrentPeriodEnd
+	if len(s.Items.Data) > 0 {
+		plan = planOfPrice(s.Items.Data[0].Price.ID)
+		if periodEnd == 0 {
+			periodEnd = s.Items.Data[0].CurrentPeriodEnd
+		}
+	}
+	var currentPeriodEnd *time.Time
+	if periodEnd > 0 {
+		t := time.Unix(periodEnd, 0)
+		currentPeriodEnd = &t
+	}
+
+	// Users deleted in the meantime are skipped
+	_, err = db.Pool.Exec(ctx, `
+		INSERT INTO subscriptions (user_id, plan, status, stripe_customer_id, stripe_subscription_id,
+			current_period_end, cancel_at_period_end)
+		SELECT $1, $2, $3, $4, $5, $6, $7 WHERE EXISTS (SELECT 1 FROM users WHERE id = $1)
+		ON CONFLICT (user_id) DO UPDATE SET
+			plan = EXCLUDED.plan,
+			status = EXCLUDED.status,
+			stripe_customer_id = EXCLUDED.stripe_customer_id,
+			stripe_subscription_id = EXCLUDED.stripe_subscription_id,
+			current_period_end = EXCLUDED.current_period_end,
+			cancel_at_period_end = EXCLUDED.cancel_at_period_end,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE subscriptions.stripe_subscription_id IS NULL
+			OR subscriptions.stripe_subscription_id = EXCLUDED.stripe_subscription_id
+			OR subscriptions.status NOT IN ('active', 'trialing', 'past_due')
+	`, userID, plan, s.Status, s.Customer, s.ID, currentPeriodEnd, s.CancelAtPeriodEnd)
+	if err != nil {
+		return fmt.Errorf("error recording subscription %s: %v", s.ID, err)
+	}
+	return nil
+}
+
+// stripeCall runs a Stripe API request with a form body and decodes the
+// response into out
+func stripeCall(ctx context.Context, method, path string, form url.Values, out interface{}) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, stripeAPI+path, body)
+	if err != nil {
+		return err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.SetBasicAuth(stripe.SecretKey, "")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var failure struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&failure)
+		return fmt.Errorf("Stripe answered %s: %s", resp.Status, failure.Error.Message)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
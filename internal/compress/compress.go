@@ -0,0 +1,62 @@
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+)
+
+// MaxBytes is the size of the largest body accepted once decompressed, above
+// the limits of the endpoints themselves. Reading past it fails with an
+// *http.MaxBytesError, which the handlers answer with 413.
+var MaxBytes int64 = 32 << 20
+
+// readCloser closes both the decompressor and the original request body
+type readCloser struct {
+	io.Reader
+	decompressor io.Closer
+	body         io.Closer
+}
+
+func (rc *readCloser) Close() error {
+	rc.decompressor.Close()
+	return rc.body.Close()
+}
+
+// RequestMiddleware transparently decompresses request bodies sent with a
+// gzip or deflate Content-Encoding so handlers always read plain JSON
+func RequestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+
+		var decompressor io.ReadCloser
+		var err error
+		switch encoding {
+		case "", "identity":
+			next.ServeHTTP(w, r)
+			return
+		case "gzip", "x-gzip":
+			decompressor, err = gzip.NewReader(r.Body)
+		case "deflate":
+			decompressor, err = zlib.NewReader(r.Body)
+		default:
+			apierror.Error(w, r, "Unsupported Content-Encoding", http.StatusUnsupportedMediaType)
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidBody, "Invalid compressed request body", nil)
+			return
+		}
+
+		// A small body can inflate to gigabytes
+		r.Body = http.MaxBytesReader(w, &readCloser{Reader: decompressor, decompressor: decompressor, body: r.Body}, MaxBytes)
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,65 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func gzipped(t *testing.T, data []byte) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestRequestMiddlewareLimitsDecompressedBodies(t *testing.T) {
+	defer func(limit int64) { MaxBytes = limit }(MaxBytes)
+	MaxBytes = 1 << 10
+
+	// echo answers 413 as the handlers do when the body is over the limit
+	echo := RequestMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Write(body)
+	}))
+
+	tests := []struct {
+		name string
+		size int64
+		want int
+	}{
+		{"at the limit", MaxBytes, http.StatusOK},
+		{"over the limit", 64 * MaxBytes, http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plain := bytes.Repeat([]byte{'0'}, int(test.size))
+			r := httptest.NewRequest("POST", "/api/v1/sync", gzipped(t, plain))
+			r.Header.Set("Content-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			echo.ServeHTTP(w, r)
+
+			if w.Code != test.want {
+				t.Fatalf("got %d, want %d", w.Code, test.want)
+			}
+			if test.want == http.StatusOK && !bytes.Equal(w.Body.Bytes(), plain) {
+				t.Errorf("the handler read %d bytes, want %d", w.Body.Len(), len(plain))
+			}
+		})
+	}
+}
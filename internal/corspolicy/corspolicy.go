@@ -0,0 +1,152 @@
+// Package corspolicy decides which browser origins may call the API. One
+// policy covers every route: a default rule, with overrides for the paths
+// that need another, such as the public API documentation.
+package corspolicy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/cors"
+	"github.com/pacerclub/zebra-backend/internal/apiversion"
+)
+
+// Origins is a set of allowed origins. Entries are exact origins, such as
+// https://zebra.pacerclub.cn, or patterns with * in place of the leftmost
+// host labels, such as https://*.pacerclub.cn, which match every subdomain
+// but not the domain itself. A lone * matches every origin.
+type Origins struct {
+	any      bool
+	exact    map[string]bool
+	suffixes []string // scheme://.domain[:port] of the patterns
+}
+
+// ParseOrigins parses a list of origins and patterns
+func ParseOrigins(list []string) (Origins, error) {
+	origins := Origins{exact: map[string]bool{}}
+	for _, entry := range list {
+		entry = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(entry), "/"))
+		if entry == "*" {
+			origins.any = true
+			continue
+		}
+
+		u, err := url.Parse(entry)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.User != nil {
+			return Origins{}, fmt.Errorf("invalid origin %q", entry)
+		}
+		host := u.Host
+		if strings.Contains(host, "*") {
+			domain, ok := strings.CutPrefix(host, "*.")
+			if !ok || domain == "" || strings.Contains(domain, "*") {
+				return Origins{}, fmt.Errorf("invalid origin pattern %q: * may only replace the leftmost host labels", entry)
+			}
+			origins.suffixes = append(origins.suffixes, u.Scheme+"://."+domain)
+			continue
+		}
+		origins.exact[u.Scheme+"://"+host] = true
+	}
+	return origins, nil
+}
+
+// Allowed reports whether origin is in the set
+func (o Origins) Allowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if o.any || o.exact[origin] {
+		return true
+	}
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok || host == "" {
+		return false
+	}
+	for _, suffix := range o.suffixes {
+		// The subdomain labels the * stands for, followed by the domain
+		patternScheme, domain, _ := strings.Cut(suffix, "://")
+		if scheme != patternScheme || !strings.HasSuffix(host, domain) {
+			continue
+		}
+		labels := strings.TrimSuffix(host, domain)
+		if labels != "" && !strings.ContainsAny(labels, ":/@") {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule is the CORS policy of a group of routes
+type Rule struct {
+	Origins Origins
+	// Credentials lets browsers send cookies and Authorization headers
+	// with cross-origin requests
+	Credentials bool
+	Methods     []string
+	Headers     []string
+	// Exposed lists the response headers scripts may read
+	Exposed []string
+	// MaxAge is how many seconds browsers may cache a preflight
+	MaxAge int
+}
+
+// Override applies another rule to the paths under Prefix. Prefixes of API
+// routes are given without the version, as in /api/docs or /api/oauth,
+// and cover every version.
+type Override struct {
+	Prefix string
+	Rule   Rule
+}
+
+// Policy is the CORS policy of the whole server
+type Policy struct {
+	Default   Rule
+	Overrides []Override
+}
+
+// Handler answers preflight requests and sets the CORS headers of every
+// response by the rule of its path: the first override whose prefix
+// matches, or the default rule
+func (p Policy) Handler() func(http.Handler) http.Handler {
+	type route struct {
+		prefix  string
+		handler func(http.Handler) http.Handler
+	}
+	routes := make([]route, len(p.Overrides))
+	for i, override := range p.Overrides {
+		routes[i] = route{strings.TrimSuffix(override.Prefix, "/"), override.Rule.handler()}
+	}
+	fallback := p.Default.handler()
+
+	return func(next http.Handler) http.Handler {
+		wrapped := make([]http.Handler, len(routes))
+		for i, route := range routes {
+			wrapped[i] = route.handler(next)
+		}
+		defaultHandler := fallback(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := apiversion.Path(r.URL.Path)
+			for i, route := range routes {
+				if path == route.prefix || strings.HasPrefix(path, route.prefix+"/") {
+					wrapped[i].ServeHTTP(w, r)
+					return
+				}
+			}
+			defaultHandler.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (rule Rule) handler() func(http.Handler) http.Handler {
+	// Origins are matched by AllowOriginFunc, so the allowed origin is
+	// echoed back rather than answered with *, which browsers reject on
+	// requests with credentials
+	return cors.Handler(cors.Options{
+		AllowOriginFunc:  func(_ *http.Request, origin string) bool { return rule.Origins.Allowed(origin) },
+		AllowedMethods:   rule.Methods,
+		AllowedHeaders:   rule.Headers,
+		ExposedHeaders:   rule.Exposed,
+		AllowCredentials: rule.Credentials,
+		MaxAge:           rule.MaxAge,
+	})
+}
@@ -0,0 +1,20 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CopyRows writes rows into table with COPY, one stream for all of them
+// instead of a round trip and a statement per row, which is what makes
+// imports and first uploads of tens of thousands of sessions fast. Each row
+// holds the values of columns, in order. Defaults and constraints apply as
+// for INSERT, and rows go to their partitions, but a single bad row fails
+// the whole copy, so callers check rows first or run it in a savepoint.
+func CopyRows(ctx context.Context, tx pgx.Tx, table string, columns []string, rows [][]interface{}) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return tx.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(rows))
+}
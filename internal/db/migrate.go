@@ -0,0 +1,249 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migrations are the SQL files of internal/db/migrations, named as
+// golang-migrate names them: <version>_<name>.up.sql applies a migration and
+// <version>_<name>.down.sql reverts it. The applied version is kept in the
+// schema_migrations table in golang-migrate's format, so either tool can
+// take over a database.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is one version of the schema
+type Migration struct {
+	Version uint64
+	Name    string
+	up      string
+	down    string
+}
+
+var migrationName = regexp.MustCompile(`^([0-9]+)_(.+)\.(up|down)\.sql$`)
+
+// ErrDirty is returned when the recorded version is flagged dirty, as
+// golang-migrate leaves it after a failed migration. The schema has to be
+// repaired by hand and the version forced. Migrations run here are
+// transactional and never leave it dirty.
+var ErrDirty = errors.New("database schema is dirty")
+
+// Migrations returns the embedded migrations, oldest first
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+		sql, err := fs.ReadFile(migrationFiles, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if match[3] == "up" {
+			m.up = string(sql)
+		} else {
+			m.down = string(sql)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %d has no up file", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrateUp applies the migrations newer than the database's version, each
+// in its own transaction. Servers starting together wait for each other on
+// an advisory lock, so each migration runs once.
+func MigrateUp(ctx context.Context) error {
+	return withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		migrations, err := Migrations()
+		if err != nil {
+			return err
+		}
+		current, err := version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if m.Version <= current {
+				continue
+			}
+			slog.Info("Applying migration", "version", m.Version, "name", m.Name)
+			if err := apply(ctx, conn, m.up, m.Version); err != nil {
+				return fmt.Errorf("error applying migration %d_%s: %v", m.Version, m.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// MigrateDown reverts the latest steps migrations
+func MigrateDown(ctx context.Context, steps int) error {
+	return withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		migrations, err := Migrations()
+		if err != nil {
+			return err
+		}
+		current, err := version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			m := migrations[i]
+			if m.Version > current {
+				continue
+			}
+			if m.down == "" {
+				return fmt.Errorf("migration %d_%s cannot be reverted", m.Version, m.Name)
+			}
+			var previous uint64
+			if i > 0 {
+				previous = migrations[i-1].Version
+			}
+			slog.Info("Reverting migration", "version", m.Version, "name", m.Name)
+			if err := apply(ctx, conn, m.down, previous); err != nil {
+				return fmt.Errorf("error reverting migration %d_%s: %v", m.Version, m.Name, err)
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// MigrationVersion returns the version of the database's schema, 0 before
+// the first migration
+func MigrationVersion(ctx context.Context) (uint64, error) {
+	var current uint64
+	err := withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		var err error
+		current, err = version(ctx, conn)
+		return err
+	})
+	return current, err
+}
+
+// ForceMigrationVersion records version as applied and clears the dirty
+// flag without running any migration, after a failed one was repaired by
+// hand
+func ForceMigrationVersion(ctx context.Context, version uint64) error {
+	return withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			return setVersion(ctx, tx, version)
+		})
+	})
+}
+
+// withMigrationLock runs f on a connection holding the migration lock,
+// after creating the schema_migrations table if needed
+func withMigrationLock(ctx context.Context, f func(*pgxpool.Conn) error) error {
+	conn, err := Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	// Session advisory locks belong to the connection, which is held until
+	// f returns
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock(hashtext('schema_migrations'))`); err != nil {
+		return fmt.Errorf("error locking migrations: %v", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock(hashtext('schema_migrations'))`)
+
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			dirty BOOLEAN NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations: %v", err)
+	}
+	return f(conn)
+}
+
+// version returns the applied version, refusing a dirty schema
+func version(ctx context.Context, conn *pgxpool.Conn) (uint64, error) {
+	var current int64
+	var dirty bool
+	err := conn.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&current, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w at version %d", ErrDirty, current)
+	}
+	return uint64(current), nil
+}
+
+// apply runs the SQL of a migration and records version as applied, in one
+// transaction. Statements that cannot run in a transaction, such as
+// CREATE INDEX CONCURRENTLY, do not belong in migrations.
+func apply(ctx context.Context, conn *pgxpool.Conn, sql string, version uint64) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Migrations rewriting large tables may run for long
+	if err := SetLocalStatementTimeout(ctx, tx, 0); err != nil {
+		return err
+	}
+	// Without arguments the file runs as one multi-statement query
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+	if err := setVersion(ctx, tx, version); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// setVersion replaces the recorded version; 0 records none
+func setVersion(ctx context.Context, tx pgx.Tx, version uint64) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, FALSE)`, int64(version))
+	return err
+}
@@ -0,0 +1,50 @@
+-- Drop triggers
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
+DROP TRIGGER IF EXISTS update_organizations_updated_at ON organizations;
+DROP TRIGGER IF EXISTS update_projects_updated_at ON projects;
+DROP TRIGGER IF EXISTS update_timer_sessions_updated_at ON timer_sessions;
+DROP TRIGGER IF EXISTS update_sync_status_updated_at ON user_sync_status;
+DROP TRIGGER IF EXISTS update_device_sync_updated_at ON device_sync;
+DROP TRIGGER IF EXISTS update_tags_updated_at ON tags;
+DROP TRIGGER IF EXISTS update_tasks_updated_at ON tasks;
+DROP TRIGGER IF EXISTS update_session_templates_updated_at ON session_templates;
+DROP TRIGGER IF EXISTS update_user_preferences_updated_at ON user_preferences;
+DROP TRIGGER IF EXISTS update_integration_accounts_updated_at ON integration_accounts;
+
+-- Drop tables
+DROP TABLE IF EXISTS audit_log CASCADE;
+DROP TABLE IF EXISTS scheduled_tasks CASCADE;
+DROP TABLE IF EXISTS daily_stats CASCADE;
+DROP TABLE IF EXISTS user_activity CASCADE;
+DROP TABLE IF EXISTS idempotency_keys CASCADE;
+DROP TABLE IF EXISTS invoice_exports CASCADE;
+DROP TABLE IF EXISTS project_billing CASCADE;
+DROP TABLE IF EXISTS running_timers CASCADE;
+DROP TABLE IF EXISTS oauth_grants CASCADE;
+DROP TABLE IF EXISTS oauth_codes CASCADE;
+DROP TABLE IF EXISTS oauth_clients CASCADE;
+DROP TABLE IF EXISTS webhooks CASCADE;
+DROP TABLE IF EXISTS jira_worklogs CASCADE;
+DROP TABLE IF EXISTS import_jobs CASCADE;
+DROP TABLE IF EXISTS suggested_sessions CASCADE;
+DROP TABLE IF EXISTS calendar_event_links CASCADE;
+DROP TABLE IF EXISTS oauth_states CASCADE;
+DROP TABLE IF EXISTS integration_accounts CASCADE;
+DROP TABLE IF EXISTS project_transfers CASCADE;
+DROP TABLE IF EXISTS sync_conflicts CASCADE;
+DROP TABLE IF EXISTS sync_idempotency_keys CASCADE;
+DROP TABLE IF EXISTS user_sync_status CASCADE;
+DROP TABLE IF EXISTS user_preferences CASCADE;
+DROP TABLE IF EXISTS session_templates CASCADE;
+DROP TABLE IF EXISTS tasks CASCADE;
+DROP TABLE IF EXISTS tags CASCADE;
+DROP TABLE IF EXISTS timer_sessions CASCADE;
+DROP TABLE IF EXISTS projects CASCADE;
+DROP TABLE IF EXISTS memberships CASCADE;
+DROP TABLE IF EXISTS organizations CASCADE;
+DROP TABLE IF EXISTS device_sync CASCADE;
+DROP TABLE IF EXISTS users CASCADE;
+
+-- Drop functions
+DROP FUNCTION IF EXISTS update_updated_at_column();
+DROP FUNCTION IF EXISTS update_synced_timestamps();
@@ -0,0 +1,546 @@
+-- The schema as of the introduction of versioned migrations. It may run on
+-- a database created from the former schema.sql, so every statement is
+-- idempotent.
+
+-- Enable UUID extension
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+-- Create users table
+CREATE TABLE IF NOT EXISTS users (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    email VARCHAR(255) UNIQUE NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    storage_mode VARCHAR(20) NOT NULL DEFAULT 'standard',
+    -- Operators of the server, who may reach the admin API
+    is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create device sync table for tracking each device's sync progress
+CREATE TABLE IF NOT EXISTS device_sync (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device_id VARCHAR(255) NOT NULL,
+    device_name VARCHAR(255) NOT NULL DEFAULT '',
+    platform VARCHAR(50) NOT NULL DEFAULT '',
+    last_sync_time TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    acked_through TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT '-infinity',
+    needs_full_resync BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Set by the stale-device job; stale devices no longer hold back tombstone GC
+    stale_at TIMESTAMP WITH TIME ZONE,
+    -- Tokens for this device issued before revoked_at are rejected
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, device_id)
+);
+
+-- Create organizations table for groups that track time together
+CREATE TABLE IF NOT EXISTS organizations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    created_by UUID NOT NULL REFERENCES users(id),
+    -- Organization settings document, see models.OrganizationSettings
+    settings JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create memberships table linking users to organizations
+CREATE TABLE IF NOT EXISTS memberships (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL DEFAULT 'member',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deactivated_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (organization_id, user_id)
+);
+
+-- Create projects table first (since timer_sessions depends on it)
+CREATE TABLE IF NOT EXISTS projects (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    color VARCHAR(50) NOT NULL,
+    encrypted_name BYTEA,
+    encrypted_description BYTEA,
+    key_id VARCHAR(255) NOT NULL DEFAULT '',
+    -- Projects with an organization belong to it rather than to user_id
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    server_updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    device_id VARCHAR(255),
+    is_deleted BOOLEAN DEFAULT FALSE
+);
+
+-- Create sessions table for storing timer sessions
+CREATE TABLE IF NOT EXISTS timer_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE SET NULL,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    description TEXT,
+    encrypted_description BYTEA,
+    key_id VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    server_updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    device_id VARCHAR(255),
+    is_deleted BOOLEAN DEFAULT FALSE
+);
+
+-- Create tags table
+CREATE TABLE IF NOT EXISTS tags (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    color VARCHAR(50) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    server_updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    device_id VARCHAR(255) NOT NULL DEFAULT '',
+    is_deleted BOOLEAN DEFAULT FALSE
+);
+
+-- Create tasks table
+CREATE TABLE IF NOT EXISTS tasks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    is_completed BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    server_updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    device_id VARCHAR(255) NOT NULL DEFAULT '',
+    is_deleted BOOLEAN DEFAULT FALSE
+);
+
+-- Create session templates table for quick-start presets
+CREATE TABLE IF NOT EXISTS session_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    server_updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    device_id VARCHAR(255) NOT NULL DEFAULT '',
+    is_deleted BOOLEAN DEFAULT FALSE
+);
+
+-- Create user preferences table, one row per setting
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key VARCHAR(100) NOT NULL,
+    value JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    server_updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    device_id VARCHAR(255) NOT NULL DEFAULT '',
+    is_deleted BOOLEAN DEFAULT FALSE,
+    PRIMARY KEY (user_id, key)
+);
+
+-- Create user sync status table for managing user sync status
+CREATE TABLE IF NOT EXISTS user_sync_status (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    last_sync_time TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    device_id VARCHAR(255),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create sync idempotency table for replaying retried sync requests
+CREATE TABLE IF NOT EXISTS sync_idempotency_keys (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    status_code INTEGER,
+    content_type VARCHAR(100),
+    response_body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+-- Create sync conflicts table for auditing concurrent edits between devices
+CREATE TABLE IF NOT EXISTS sync_conflicts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    collection VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    server_version JSONB NOT NULL,
+    client_version JSONB NOT NULL,
+    server_device_id VARCHAR(255) NOT NULL DEFAULT '',
+    client_device_id VARCHAR(255) NOT NULL DEFAULT '',
+    resolution VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create project transfers table for handing projects to another user or
+-- organization once the recipient accepts
+CREATE TABLE IF NOT EXISTS project_transfers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    from_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    to_organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    project_ids UUID[] NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    -- Old to new ids of the copied projects and sessions, set on acceptance
+    id_map JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create integration accounts table for OAuth-linked third-party services
+CREATE TABLE IF NOT EXISTS integration_accounts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    access_token TEXT NOT NULL,
+    refresh_token TEXT NOT NULL DEFAULT '',
+    token_expires_at TIMESTAMP WITH TIME ZONE,
+    -- Provider-specific options, e.g. which calendar to use
+    settings JSONB NOT NULL DEFAULT '{}',
+    last_synced_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, provider)
+);
+
+-- Create OAuth states table tying authorization callbacks to the user who
+-- started them
+CREATE TABLE IF NOT EXISTS oauth_states (
+    state VARCHAR(64) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create calendar event links table mapping pushed sessions to their events
+CREATE TABLE IF NOT EXISTS calendar_event_links (
+    account_id UUID NOT NULL REFERENCES integration_accounts(id) ON DELETE CASCADE,
+    session_id UUID NOT NULL,
+    event_id VARCHAR(1024) NOT NULL,
+    synced_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (account_id, session_id)
+);
+
+-- Create suggested sessions table for imported events awaiting confirmation
+CREATE TABLE IF NOT EXISTS suggested_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES integration_accounts(id) ON DELETE CASCADE,
+    external_id VARCHAR(1024) NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    session_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(account_id, external_id)
+);
+
+-- Create import jobs table tracking background imports from other tools
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    created_sessions INTEGER NOT NULL DEFAULT 0,
+    created_projects INTEGER NOT NULL DEFAULT 0,
+    duplicates INTEGER NOT NULL DEFAULT 0,
+    -- Per-line problems as [{"line": n, "message": "..."}]
+    errors JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create Jira worklogs table mapping sessions to the worklogs exported for
+-- them, with the state of the retryable export
+CREATE TABLE IF NOT EXISTS jira_worklogs (
+    account_id UUID NOT NULL REFERENCES integration_accounts(id) ON DELETE CASCADE,
+    session_id UUID NOT NULL,
+    -- Issue key set by the user, overriding any key in the description
+    explicit_issue_key VARCHAR(255),
+    issue_key VARCHAR(255) NOT NULL DEFAULT '',
+    worklog_id VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP WITH TIME ZONE,
+    exported_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (account_id, session_id)
+);
+
+-- Create webhooks table of URLs subscribed to a user's events
+CREATE TABLE IF NOT EXISTS webhooks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_url TEXT NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    -- Signs deliveries so receivers can verify them
+    secret VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create OAuth clients table of third-party apps registered by users
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    redirect_uris TEXT[] NOT NULL,
+    secret_hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create OAuth codes table of authorization codes awaiting exchange
+CREATE TABLE IF NOT EXISTS oauth_codes (
+    code_hash VARCHAR(64) PRIMARY KEY,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    -- S256 PKCE challenge, or empty
+    code_challenge VARCHAR(128) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Create OAuth grants table of access users gave apps, one per consent
+CREATE TABLE IF NOT EXISTS oauth_grants (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL,
+    refresh_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create running timers table of the timer each user has running, saved as
+-- a session when stopped
+CREATE TABLE IF NOT EXISTS running_timers (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    id UUID NOT NULL,
+    project_id UUID REFERENCES projects(id) ON DELETE SET NULL,
+    description TEXT NOT NULL DEFAULT '',
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    device_id VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+-- Create project billing table saying whether and how time on a project
+-- is invoiced
+CREATE TABLE IF NOT EXISTS project_billing (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    billable BOOLEAN NOT NULL DEFAULT FALSE,
+    client_name VARCHAR(255) NOT NULL DEFAULT '',
+    hourly_rate_cents BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create invoice exports table tracking background pushes of invoices to
+-- accounting services
+CREATE TABLE IF NOT EXISTS invoice_exports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    target VARCHAR(50) NOT NULL,
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    -- One entry per client with the created invoice's ID or the error
+    results JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create idempotency keys table for replaying retried API requests
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    -- SHA-256 of the method, path and body; a key reused for another
+    -- request is rejected
+    fingerprint BYTEA NOT NULL,
+    -- NULL while the first request is being processed
+    status_code INTEGER,
+    content_type VARCHAR(100),
+    response_body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+-- Create user activity table with the days each user made a request, for
+-- counting daily active users
+CREATE TABLE IF NOT EXISTS user_activity (
+    day DATE NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (day, user_id)
+);
+
+-- Create daily stats table of counters such as sync requests and webhook
+-- deliveries, one row per UTC day and metric
+CREATE TABLE IF NOT EXISTS daily_stats (
+    day DATE NOT NULL,
+    metric VARCHAR(50) NOT NULL,
+    value BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, metric)
+);
+
+-- Create scheduled tasks table with the latest run of each recurring task,
+-- shared by every server so each run happens once
+CREATE TABLE IF NOT EXISTS scheduled_tasks (
+    name VARCHAR(100) PRIMARY KEY,
+    -- Scheduled time of the latest run
+    last_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT
+);
+
+-- Create audit log table of the mutating API calls: who made them, from
+-- which device and address, and on which entity. Entries past the
+-- retention period are pruned.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    organization_id UUID REFERENCES organizations(id) ON DELETE SET NULL,
+    device_id VARCHAR(255),
+    ip VARCHAR(45),
+    user_agent TEXT,
+    request_id VARCHAR(255),
+    method VARCHAR(10) NOT NULL,
+    -- Route pattern relative to the API root, such as /auth/sessions/{id}
+    route VARCHAR(255) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id VARCHAR(255),
+    status INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Add indexes for better query performance
+CREATE INDEX IF NOT EXISTS idx_timer_sessions_user_id ON timer_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_timer_sessions_project_id ON timer_sessions(project_id);
+CREATE INDEX IF NOT EXISTS idx_projects_user_id ON projects(user_id);
+CREATE INDEX IF NOT EXISTS idx_projects_organization_id ON projects(organization_id);
+CREATE INDEX IF NOT EXISTS idx_memberships_user_id ON memberships(user_id);
+CREATE INDEX IF NOT EXISTS idx_device_sync_user_id ON device_sync(user_id);
+CREATE INDEX IF NOT EXISTS idx_device_sync_device_id ON device_sync(device_id);
+CREATE INDEX IF NOT EXISTS idx_timer_sessions_server_updated_at ON timer_sessions(user_id, server_updated_at);
+CREATE INDEX IF NOT EXISTS idx_projects_server_updated_at ON projects(user_id, server_updated_at);
+CREATE INDEX IF NOT EXISTS idx_timer_sessions_tombstones ON timer_sessions(user_id, server_updated_at) WHERE is_deleted;
+CREATE INDEX IF NOT EXISTS idx_projects_tombstones ON projects(user_id, server_updated_at) WHERE is_deleted;
+CREATE INDEX IF NOT EXISTS idx_tags_user_id ON tags(user_id, server_updated_at);
+CREATE INDEX IF NOT EXISTS idx_tasks_user_id ON tasks(user_id, server_updated_at);
+CREATE INDEX IF NOT EXISTS idx_session_templates_user_id ON session_templates(user_id, server_updated_at);
+CREATE INDEX IF NOT EXISTS idx_user_preferences_updated_at ON user_preferences(user_id, server_updated_at);
+CREATE INDEX IF NOT EXISTS idx_sync_conflicts_user_id ON sync_conflicts(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_sync_idempotency_keys_created_at ON sync_idempotency_keys(created_at);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
+CREATE INDEX IF NOT EXISTS idx_project_transfers_from_user_id ON project_transfers(from_user_id);
+CREATE INDEX IF NOT EXISTS idx_project_transfers_to_user_id ON project_transfers(to_user_id);
+CREATE INDEX IF NOT EXISTS idx_suggested_sessions_user_id ON suggested_sessions(user_id, status);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_user_id ON import_jobs(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_jira_worklogs_status ON jira_worklogs(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_event ON webhooks(user_id, event);
+CREATE INDEX IF NOT EXISTS idx_oauth_clients_user_id ON oauth_clients(user_id);
+CREATE INDEX IF NOT EXISTS idx_oauth_grants_user_client ON oauth_grants(user_id, client_id);
+CREATE INDEX IF NOT EXISTS idx_invoice_exports_user_id ON invoice_exports(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_timer_sessions_created_at ON timer_sessions(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_user_id ON audit_log(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+
+-- Update timestamp triggers
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- Synced tables keep client-provided updated_at values and track the server
+-- change time separately in server_updated_at, which drives sync cursors
+CREATE OR REPLACE FUNCTION update_synced_timestamps()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.server_updated_at = CURRENT_TIMESTAMP;
+    IF NEW.updated_at IS NOT DISTINCT FROM OLD.updated_at THEN
+        NEW.updated_at = CURRENT_TIMESTAMP;
+    END IF;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
+CREATE TRIGGER update_users_updated_at
+    BEFORE UPDATE ON users
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_organizations_updated_at ON organizations;
+CREATE TRIGGER update_organizations_updated_at
+    BEFORE UPDATE ON organizations
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_projects_updated_at ON projects;
+CREATE TRIGGER update_projects_updated_at
+    BEFORE UPDATE ON projects
+    FOR EACH ROW
+    EXECUTE FUNCTION update_synced_timestamps();
+
+DROP TRIGGER IF EXISTS update_timer_sessions_updated_at ON timer_sessions;
+CREATE TRIGGER update_timer_sessions_updated_at
+    BEFORE UPDATE ON timer_sessions
+    FOR EACH ROW
+    EXECUTE FUNCTION update_synced_timestamps();
+
+DROP TRIGGER IF EXISTS update_sync_status_updated_at ON user_sync_status;
+CREATE TRIGGER update_sync_status_updated_at
+    BEFORE UPDATE ON user_sync_status
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_device_sync_updated_at ON device_sync;
+CREATE TRIGGER update_device_sync_updated_at
+    BEFORE UPDATE ON device_sync
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_tags_updated_at ON tags;
+CREATE TRIGGER update_tags_updated_at
+    BEFORE UPDATE ON tags
+    FOR EACH ROW
+    EXECUTE FUNCTION update_synced_timestamps();
+
+DROP TRIGGER IF EXISTS update_tasks_updated_at ON tasks;
+CREATE TRIGGER update_tasks_updated_at
+    BEFORE UPDATE ON tasks
+    FOR EACH ROW
+    EXECUTE FUNCTION update_synced_timestamps();
+
+DROP TRIGGER IF EXISTS update_session_templates_updated_at ON session_templates;
+CREATE TRIGGER update_session_templates_updated_at
+    BEFORE UPDATE ON session_templates
+    FOR EACH ROW
+    EXECUTE FUNCTION update_synced_timestamps();
+
+DROP TRIGGER IF EXISTS update_user_preferences_updated_at ON user_preferences;
+CREATE TRIGGER update_user_preferences_updated_at
+    BEFORE UPDATE ON user_preferences
+    FOR EACH ROW
+    EXECUTE FUNCTION update_synced_timestamps();
+
+DROP TRIGGER IF EXISTS update_integration_accounts_updated_at ON integration_accounts;
+CREATE TRIGGER update_integration_accounts_updated_at
+    BEFORE UPDATE ON integration_accounts
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,15 @@
+-- Domain events written in the same transaction as the change they
+-- describe, and dispatched to webhooks by the outbox dispatcher
+CREATE TABLE outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    -- The user whose webhooks receive the event
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    -- JSON rather than JSONB keeps the payload byte for byte as encoded
+    payload JSON NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    dispatched_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_outbox_events_pending ON outbox_events(id) WHERE dispatched_at IS NULL;
+CREATE INDEX idx_outbox_events_dispatched_at ON outbox_events(dispatched_at) WHERE dispatched_at IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_projects_name_tsv;
+DROP INDEX IF EXISTS idx_timer_sessions_description_tsv;
+ALTER TABLE projects DROP COLUMN IF EXISTS name_tsv;
+ALTER TABLE timer_sessions DROP COLUMN IF EXISTS description_tsv;
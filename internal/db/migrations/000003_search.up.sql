@@ -0,0 +1,11 @@
+-- Full-text search over session descriptions and project names. The
+-- 'simple' configuration lowercases words without stemming them, which
+-- suits short descriptions written in any language. Encrypted descriptions
+-- and names are stored empty and never match.
+ALTER TABLE timer_sessions ADD COLUMN description_tsv tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(description, ''))) STORED;
+ALTER TABLE projects ADD COLUMN name_tsv tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(name, ''))) STORED;
+
+CREATE INDEX idx_timer_sessions_description_tsv ON timer_sessions USING GIN (description_tsv);
+CREATE INDEX idx_projects_name_tsv ON projects USING GIN (name_tsv);
@@ -0,0 +1,48 @@
+-- Back to a single timer_sessions table
+ALTER TABLE timer_sessions RENAME TO timer_sessions_partitioned;
+ALTER TABLE timer_sessions_partitioned RENAME CONSTRAINT timer_sessions_pkey TO timer_sessions_partitioned_pkey;
+DROP TRIGGER IF EXISTS update_timer_sessions_updated_at ON timer_sessions_partitioned;
+DROP INDEX IF EXISTS idx_timer_sessions_user_id;
+DROP INDEX IF EXISTS idx_timer_sessions_project_id;
+DROP INDEX IF EXISTS idx_timer_sessions_server_updated_at;
+DROP INDEX IF EXISTS idx_timer_sessions_tombstones;
+DROP INDEX IF EXISTS idx_timer_sessions_created_at;
+DROP INDEX IF EXISTS idx_timer_sessions_description_tsv;
+
+CREATE TABLE timer_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE SET NULL,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    description TEXT,
+    encrypted_description BYTEA,
+    key_id VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    server_updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    device_id VARCHAR(255),
+    is_deleted BOOLEAN DEFAULT FALSE,
+    description_tsv tsvector
+        GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(description, ''))) STORED
+);
+
+INSERT INTO timer_sessions (id, user_id, project_id, start_time, end_time, description, encrypted_description,
+    key_id, created_at, updated_at, server_updated_at, device_id, is_deleted)
+SELECT id, user_id, project_id, start_time, end_time, description, encrypted_description,
+    key_id, created_at, updated_at, server_updated_at, device_id, is_deleted
+FROM timer_sessions_partitioned;
+
+DROP TABLE timer_sessions_partitioned;
+
+CREATE INDEX idx_timer_sessions_user_id ON timer_sessions(user_id);
+CREATE INDEX idx_timer_sessions_project_id ON timer_sessions(project_id);
+CREATE INDEX idx_timer_sessions_server_updated_at ON timer_sessions(user_id, server_updated_at);
+CREATE INDEX idx_timer_sessions_tombstones ON timer_sessions(user_id, server_updated_at) WHERE is_deleted;
+CREATE INDEX idx_timer_sessions_created_at ON timer_sessions(created_at);
+CREATE INDEX idx_timer_sessions_description_tsv ON timer_sessions USING GIN (description_tsv);
+
+CREATE TRIGGER update_timer_sessions_updated_at
+    BEFORE UPDATE ON timer_sessions
+    FOR EACH ROW
+    EXECUTE FUNCTION update_synced_timestamps();
@@ -0,0 +1,82 @@
+-- Partition timer_sessions by month of start_time. Queries bounded by
+-- start_time only read the months they cover, and old months can be
+-- detached or dropped whole. The primary key has to include the partition
+-- key, so the uniqueness of id alone is no longer enforced by an index;
+-- session ids are UUIDs, and sync checks for an existing id before
+-- inserting.
+--
+-- Months get their partition ahead of time from the session_partitions
+-- task. Sessions outside every partition, such as old imports, land in
+-- timer_sessions_default.
+
+ALTER TABLE timer_sessions RENAME TO timer_sessions_unpartitioned;
+ALTER TABLE timer_sessions_unpartitioned RENAME CONSTRAINT timer_sessions_pkey TO timer_sessions_unpartitioned_pkey;
+DROP INDEX IF EXISTS idx_timer_sessions_user_id;
+DROP INDEX IF EXISTS idx_timer_sessions_project_id;
+DROP INDEX IF EXISTS idx_timer_sessions_server_updated_at;
+DROP INDEX IF EXISTS idx_timer_sessions_tombstones;
+DROP INDEX IF EXISTS idx_timer_sessions_created_at;
+DROP INDEX IF EXISTS idx_timer_sessions_description_tsv;
+DROP TRIGGER IF EXISTS update_timer_sessions_updated_at ON timer_sessions_unpartitioned;
+
+CREATE TABLE timer_sessions (
+    id UUID NOT NULL DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE SET NULL,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    description TEXT,
+    encrypted_description BYTEA,
+    key_id VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    server_updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    device_id VARCHAR(255),
+    is_deleted BOOLEAN DEFAULT FALSE,
+    description_tsv tsvector
+        GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(description, ''))) STORED,
+    PRIMARY KEY (id, start_time)
+) PARTITION BY RANGE (start_time);
+
+CREATE TABLE timer_sessions_default PARTITION OF timer_sessions DEFAULT;
+
+-- A partition for every month holding sessions, and for the current and
+-- next three months. Bounds are months in UTC.
+DO $$
+DECLARE
+    month TIMESTAMP;
+BEGIN
+    FOR month IN
+        SELECT DISTINCT date_trunc('month', start_time AT TIME ZONE 'UTC') FROM timer_sessions_unpartitioned
+        UNION
+        SELECT date_trunc('month', now() AT TIME ZONE 'UTC') + make_interval(months => n) FROM generate_series(0, 3) AS n
+    LOOP
+        EXECUTE format(
+            'CREATE TABLE %I PARTITION OF timer_sessions FOR VALUES FROM (%L) TO (%L)',
+            'timer_sessions_' || to_char(month, 'YYYY_MM'),
+            (month AT TIME ZONE 'UTC'),
+            ((month + interval '1 month') AT TIME ZONE 'UTC'));
+    END LOOP;
+END
+$$;
+
+INSERT INTO timer_sessions (id, user_id, project_id, start_time, end_time, description, encrypted_description,
+    key_id, created_at, updated_at, server_updated_at, device_id, is_deleted)
+SELECT id, user_id, project_id, start_time, end_time, description, encrypted_description,
+    key_id, created_at, updated_at, server_updated_at, device_id, is_deleted
+FROM timer_sessions_unpartitioned;
+
+DROP TABLE timer_sessions_unpartitioned;
+
+-- Indexes on the partitioned table are created on every partition
+CREATE INDEX idx_timer_sessions_user_id ON timer_sessions(user_id, start_time DESC);
+CREATE INDEX idx_timer_sessions_project_id ON timer_sessions(project_id);
+CREATE INDEX idx_timer_sessions_server_updated_at ON timer_sessions(user_id, server_updated_at);
+CREATE INDEX idx_timer_sessions_tombstones ON timer_sessions(user_id, server_updated_at) WHERE is_deleted;
+CREATE INDEX idx_timer_sessions_created_at ON timer_sessions(created_at);
+CREATE INDEX idx_timer_sessions_description_tsv ON timer_sessions USING GIN (description_tsv);
+
+CREATE TRIGGER update_timer_sessions_updated_at
+    BEFORE UPDATE ON timer_sessions
+    FOR EACH ROW
+    EXECUTE FUNCTION update_synced_timestamps();
@@ -0,0 +1,23 @@
+-- Sealed values must be decrypted before reverting, or long project names
+-- fail the length check
+CREATE OR REPLACE FUNCTION update_synced_timestamps()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.server_updated_at = CURRENT_TIMESTAMP;
+    IF NEW.updated_at IS NOT DISTINCT FROM OLD.updated_at THEN
+        NEW.updated_at = CURRENT_TIMESTAMP;
+    END IF;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+ALTER TABLE timer_sessions DROP COLUMN description_tsv;
+ALTER TABLE timer_sessions ADD COLUMN description_tsv tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(description, ''))) STORED;
+CREATE INDEX idx_timer_sessions_description_tsv ON timer_sessions USING GIN (description_tsv);
+
+ALTER TABLE projects DROP COLUMN name_tsv;
+ALTER TABLE projects ALTER COLUMN name TYPE VARCHAR(255);
+ALTER TABLE projects ADD COLUMN name_tsv tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(name, ''))) STORED;
+CREATE INDEX idx_projects_name_tsv ON projects USING GIN (name_tsv);
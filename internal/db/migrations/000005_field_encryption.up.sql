@@ -0,0 +1,32 @@
+-- Instance-level encryption of session descriptions and project names, see
+-- package fieldcrypt. Sealed project names are longer than the 255
+-- characters allowed so far, and sealed values are kept out of the search
+-- columns.
+ALTER TABLE projects DROP COLUMN name_tsv;
+ALTER TABLE projects ALTER COLUMN name TYPE TEXT;
+ALTER TABLE projects ADD COLUMN name_tsv tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple',
+        CASE WHEN name LIKE '$zf1$%' THEN '' ELSE COALESCE(name, '') END)) STORED;
+CREATE INDEX idx_projects_name_tsv ON projects USING GIN (name_tsv);
+
+ALTER TABLE timer_sessions DROP COLUMN description_tsv;
+ALTER TABLE timer_sessions ADD COLUMN description_tsv tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple',
+        CASE WHEN description LIKE '$zf1$%' THEN '' ELSE COALESCE(description, '') END)) STORED;
+CREATE INDEX idx_timer_sessions_description_tsv ON timer_sessions USING GIN (description_tsv);
+
+-- Re-encrypting a row does not change it for clients, so the rotation
+-- task sets zebra.keep_timestamps to leave the sync timestamps alone
+CREATE OR REPLACE FUNCTION update_synced_timestamps()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF current_setting('zebra.keep_timestamps', true) = 'on' THEN
+        RETURN NEW;
+    END IF;
+    NEW.server_updated_at = CURRENT_TIMESTAMP;
+    IF NEW.updated_at IS NOT DISTINCT FROM OLD.updated_at THEN
+        NEW.updated_at = CURRENT_TIMESTAMP;
+    END IF;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
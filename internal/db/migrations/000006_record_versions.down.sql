@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS bump_timer_sessions_version ON timer_sessions;
+DROP TRIGGER IF EXISTS bump_projects_version ON projects;
+DROP FUNCTION IF EXISTS bump_record_version();
+
+ALTER TABLE timer_sessions DROP COLUMN version;
+ALTER TABLE projects DROP COLUMN version;
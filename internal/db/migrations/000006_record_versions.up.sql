@@ -0,0 +1,27 @@
+-- Optimistic concurrency for sessions and projects: every change bumps the
+-- row's version, and the update endpoints only apply a change made on the
+-- current one
+ALTER TABLE projects ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE timer_sessions ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+-- Re-encrypting a row does not change it for clients either
+CREATE OR REPLACE FUNCTION bump_record_version()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF current_setting('zebra.keep_timestamps', true) = 'on' THEN
+        RETURN NEW;
+    END IF;
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER bump_projects_version
+    BEFORE UPDATE ON projects
+    FOR EACH ROW
+    EXECUTE FUNCTION bump_record_version();
+
+CREATE TRIGGER bump_timer_sessions_version
+    BEFORE UPDATE ON timer_sessions
+    FOR EACH ROW
+    EXECUTE FUNCTION bump_record_version();
@@ -0,0 +1,6 @@
+ALTER TABLE users
+    DROP COLUMN week_start,
+    DROP COLUMN locale,
+    DROP COLUMN timezone,
+    DROP COLUMN avatar_url,
+    DROP COLUMN display_name;
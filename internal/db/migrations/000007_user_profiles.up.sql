@@ -0,0 +1,7 @@
+-- Profile fields users edit about themselves, returned with their account
+ALTER TABLE users
+    ADD COLUMN display_name VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN avatar_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    ADD COLUMN locale VARCHAR(35) NOT NULL DEFAULT 'en',
+    ADD COLUMN week_start VARCHAR(10) NOT NULL DEFAULT 'monday';
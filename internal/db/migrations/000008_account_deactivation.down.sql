@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS account_reactivations;
+
+ALTER TABLE users DROP COLUMN deactivated_at;
@@ -0,0 +1,14 @@
+-- Deactivated users cannot log in, use their tokens or sync until they
+-- reactivate; their data is kept
+ALTER TABLE users ADD COLUMN deactivated_at TIMESTAMP WITH TIME ZONE;
+
+-- Reactivation links mailed to deactivated users, stored by the hash of
+-- their token
+CREATE TABLE account_reactivations (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_account_reactivations_user_id ON account_reactivations(user_id);
@@ -0,0 +1,10 @@
+-- Notification channels users turned on or off per type; types and
+-- channels without a row use the type's defaults
+CREATE TABLE notification_settings (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, type, channel)
+);
@@ -0,0 +1,16 @@
+-- Outgoing mail waiting to be sent, and sent or failed mail kept for a
+-- while for support
+CREATE TABLE mail_queue (
+    id BIGSERIAL PRIMARY KEY,
+    message JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    -- Set once the message is given up on after its last attempt
+    failed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_mail_queue_pending ON mail_queue(next_attempt_at) WHERE sent_at IS NULL AND failed_at IS NULL;
+CREATE INDEX idx_mail_queue_created_at ON mail_queue(created_at);
@@ -0,0 +1,22 @@
+-- Onboarding steps users have completed; a step without a row is pending
+CREATE TABLE onboarding_steps (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    step VARCHAR(50) NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, step)
+);
+
+-- Existing users completed the steps their data shows
+INSERT INTO onboarding_steps (user_id, step, completed_at)
+SELECT user_id, 'created_first_project', COALESCE(MIN(created_at), CURRENT_TIMESTAMP) FROM projects GROUP BY user_id;
+
+INSERT INTO onboarding_steps (user_id, step, completed_at)
+SELECT user_id, 'tracked_first_session', COALESCE(MIN(created_at), CURRENT_TIMESTAMP) FROM timer_sessions GROUP BY user_id;
+
+INSERT INTO onboarding_steps (user_id, step, completed_at)
+SELECT user_id, 'enabled_sync', COALESCE(MIN(created_at), CURRENT_TIMESTAMP) FROM device_sync GROUP BY user_id;
+
+INSERT INTO onboarding_steps (user_id, step, completed_at)
+SELECT user_id, 'installed_mobile_app', COALESCE(MIN(created_at), CURRENT_TIMESTAMP) FROM device_sync
+WHERE LOWER(platform) IN ('ios', 'ipados', 'android')
+GROUP BY user_id;
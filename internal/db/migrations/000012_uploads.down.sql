@@ -0,0 +1,3 @@
+ALTER TABLE projects DROP COLUMN icon_url;
+
+DROP TABLE IF EXISTS uploads;
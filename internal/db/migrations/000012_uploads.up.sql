@@ -0,0 +1,27 @@
+-- Files users upload straight to storage, from the pre-signed URL they are
+-- given until the processed image is in place
+CREATE TABLE uploads (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- What the file is for: 'avatar' or 'project_icon'
+    purpose VARCHAR(20) NOT NULL,
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    object_key TEXT NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    -- 'pending' until the client completes the upload, then 'processing'
+    -- until the image is resized, 'ready' or 'failed'
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE,
+    error TEXT NOT NULL DEFAULT '',
+    -- Public URL of the processed image
+    url TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_uploads_user_id ON uploads(user_id);
+CREATE INDEX idx_uploads_processing ON uploads(next_attempt_at) WHERE status = 'processing';
+
+ALTER TABLE projects ADD COLUMN icon_url TEXT NOT NULL DEFAULT '';
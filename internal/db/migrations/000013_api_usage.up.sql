@@ -0,0 +1,13 @@
+-- Requests each user made and the bytes of their bodies, per UTC day and
+-- kind of request: 'api' or 'sync'
+CREATE TABLE api_usage (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    kind VARCHAR(10) NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    bytes_in BIGINT NOT NULL DEFAULT 0,
+    bytes_out BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day, kind)
+);
+
+CREATE INDEX idx_api_usage_day ON api_usage(day);
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN tokens_revoked_at;
+
+DROP INDEX IF EXISTS idx_audit_log_logins;
+
+ALTER TABLE audit_log DROP COLUMN location;
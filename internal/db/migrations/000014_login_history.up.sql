@@ -0,0 +1,9 @@
+-- Approximate location of the client, from the headers of the proxy or CDN
+-- in front of the server
+ALTER TABLE audit_log ADD COLUMN location VARCHAR(255);
+
+CREATE INDEX idx_audit_log_logins ON audit_log(user_id, created_at DESC) WHERE entity_type = 'login';
+
+-- Tokens of every device issued before tokens_revoked_at are rejected, as
+-- when the user signs out everywhere
+ALTER TABLE users ADD COLUMN tokens_revoked_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,16 @@
+-- Paid subscriptions of users, kept in step with Stripe by its webhooks.
+-- Users without a row are on the free plan.
+CREATE TABLE subscriptions (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    -- 'free', 'pro' or 'team'
+    plan VARCHAR(20) NOT NULL DEFAULT 'free',
+    -- Stripe's status of the subscription, such as 'active', 'past_due' or
+    -- 'canceled'; empty before the first checkout completes
+    status VARCHAR(30) NOT NULL DEFAULT '',
+    stripe_customer_id VARCHAR(255) UNIQUE,
+    stripe_subscription_id VARCHAR(255) UNIQUE,
+    current_period_end TIMESTAMP WITH TIME ZONE,
+    cancel_at_period_end BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_audit_log_impersonator;
+
+ALTER TABLE audit_log DROP COLUMN reason;
+ALTER TABLE audit_log DROP COLUMN impersonator_id;
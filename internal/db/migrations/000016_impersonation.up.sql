@@ -0,0 +1,7 @@
+-- Requests made with an impersonation token are recorded under the
+-- impersonated user with the admin who made them, and the issuing of the
+-- token with the admin's reason
+ALTER TABLE audit_log ADD COLUMN impersonator_id UUID;
+ALTER TABLE audit_log ADD COLUMN reason TEXT;
+
+CREATE INDEX idx_audit_log_impersonator ON audit_log(impersonator_id, created_at DESC) WHERE impersonator_id IS NOT NULL;
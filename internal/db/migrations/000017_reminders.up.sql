@@ -0,0 +1,11 @@
+-- Reminder notifications sent, so each is sent once: the daily reminder
+-- keyed by the user's local date, the long timer reminder by the timer
+CREATE TABLE IF NOT EXISTS reminders_sent (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(32) NOT NULL,
+    key VARCHAR(64) NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, kind, key)
+);
+
+CREATE INDEX idx_reminders_sent_at ON reminders_sent(sent_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS session_tags;
+DROP TABLE IF EXISTS tracking_rules;
@@ -0,0 +1,32 @@
+-- Automatic tracking rules: a rule matches the description of new sessions
+-- and assigns a project, tags or both. Rules are evaluated in position
+-- order; the first matching rule with a project sets it, and every
+-- matching rule adds its tags.
+CREATE TABLE IF NOT EXISTS tracking_rules (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    match_type VARCHAR(32) NOT NULL,
+    pattern VARCHAR(500) NOT NULL,
+    project_id UUID REFERENCES projects(id) ON DELETE SET NULL,
+    tag_ids UUID[] NOT NULL DEFAULT '{}',
+    position INTEGER NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_tracking_rules_user ON tracking_rules(user_id, position);
+
+-- Tags applied to sessions. timer_sessions is partitioned by start_time, so
+-- its id alone cannot be referenced; rows of deleted sessions are left in
+-- place and ignored.
+CREATE TABLE IF NOT EXISTS session_tags (
+    session_id UUID NOT NULL,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (session_id, tag_id)
+);
+
+CREATE INDEX idx_session_tags_tag ON session_tags(tag_id);
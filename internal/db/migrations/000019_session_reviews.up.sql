@@ -0,0 +1,11 @@
+-- Sessions flagged for the user to review, such as runaway timers stopped
+-- at the maximum duration. timer_sessions is partitioned by start_time, so
+-- its id alone cannot be referenced; rows of deleted sessions are ignored.
+CREATE TABLE IF NOT EXISTS session_reviews (
+    session_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_session_reviews_user ON session_reviews(user_id, created_at);
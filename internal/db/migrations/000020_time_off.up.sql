@@ -0,0 +1,25 @@
+-- Days off, such as vacations, synced like tasks. Dates have no time zone
+-- and both ends are included.
+CREATE TABLE IF NOT EXISTS time_off (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(16) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    server_updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    device_id VARCHAR(255) NOT NULL DEFAULT '',
+    is_deleted BOOLEAN DEFAULT FALSE,
+    CHECK (end_date >= start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_time_off_user_id ON time_off(user_id, server_updated_at);
+CREATE INDEX IF NOT EXISTS idx_time_off_dates ON time_off(user_id, start_date, end_date) WHERE is_deleted = false;
+
+DROP TRIGGER IF EXISTS update_time_off_updated_at ON time_off;
+CREATE TRIGGER update_time_off_updated_at
+    BEFORE UPDATE ON time_off
+    FOR EACH ROW
+    EXECUTE FUNCTION update_synced_timestamps();
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS update_timer_sessions_break_seconds ON timer_sessions;
+DROP FUNCTION IF EXISTS update_session_break_seconds();
+ALTER TABLE timer_sessions
+    DROP COLUMN IF EXISTS break_seconds,
+    DROP COLUMN IF EXISTS breaks;
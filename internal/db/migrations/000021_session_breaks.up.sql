@@ -0,0 +1,28 @@
+-- Breaks are pauses within a session, a JSON array of objects with
+-- start_time and end_time. break_seconds is their total length within the
+-- session, kept by the trigger below so that reports can subtract it.
+ALTER TABLE timer_sessions
+    ADD COLUMN IF NOT EXISTS breaks JSONB NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS break_seconds INTEGER NOT NULL DEFAULT 0;
+
+-- Breaks are clipped to the session, so a session whose times changed
+-- without its breaks never counts time outside it
+CREATE OR REPLACE FUNCTION update_session_break_seconds()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.break_seconds = COALESCE((
+        SELECT SUM(GREATEST(EXTRACT(EPOCH FROM (
+            LEAST((b->>'end_time')::timestamptz, NEW.end_time) -
+            GREATEST((b->>'start_time')::timestamptz, NEW.start_time)
+        )), 0))
+        FROM jsonb_array_elements(NEW.breaks) b
+    ), 0);
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS update_timer_sessions_break_seconds ON timer_sessions;
+CREATE TRIGGER update_timer_sessions_break_seconds
+    BEFORE INSERT OR UPDATE OF breaks, start_time, end_time ON timer_sessions
+    FOR EACH ROW
+    EXECUTE FUNCTION update_session_break_seconds();
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_memberships_kiosk_pin;
+ALTER TABLE memberships DROP COLUMN IF EXISTS kiosk_pin_hash;
+DROP TABLE IF EXISTS kiosks;
@@ -0,0 +1,23 @@
+-- Kiosks are shared devices, such as a tablet at the door of a makerspace,
+-- where members clock in and out with a PIN instead of logging in. Each
+-- kiosk records its members' time on one organization project. Only hashes
+-- of kiosk tokens are stored.
+CREATE TABLE IF NOT EXISTS kiosks (
+    id UUID PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_kiosks_organization ON kiosks(organization_id, created_at);
+
+-- Members' kiosk PINs are generated by the server and unique within the
+-- organization, so a PIN alone identifies the member
+ALTER TABLE memberships ADD COLUMN kiosk_pin_hash CHAR(64);
+
+CREATE UNIQUE INDEX idx_memberships_kiosk_pin ON memberships(organization_id, kiosk_pin_hash)
+    WHERE kiosk_pin_hash IS NOT NULL;
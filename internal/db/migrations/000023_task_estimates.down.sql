@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_timer_sessions_task;
+ALTER TABLE timer_sessions DROP COLUMN IF EXISTS task_id;
+ALTER TABLE tasks DROP COLUMN IF EXISTS estimate_seconds;
@@ -0,0 +1,7 @@
+-- Tasks carry an estimate, 0 for none, and sessions can be logged against a
+-- task, so the time spent on a task can be compared with its estimate
+ALTER TABLE tasks ADD COLUMN estimate_seconds INTEGER NOT NULL DEFAULT 0 CHECK (estimate_seconds >= 0);
+
+ALTER TABLE timer_sessions ADD COLUMN task_id UUID REFERENCES tasks(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_timer_sessions_task ON timer_sessions(task_id) WHERE task_id IS NOT NULL;
@@ -0,0 +1,23 @@
+-- Every attempt to post an event to a webhook, so integration authors can
+-- debug failures and redeliver. Deliveries go with their webhook and are
+-- pruned after WEBHOOK_DELIVERY_RETENTION.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY,
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    -- JSON rather than JSONB keeps the payload byte for byte as signed
+    payload JSON NOT NULL,
+    -- NULL when the target could not be reached, with the reason in error
+    status_code INTEGER,
+    error TEXT NOT NULL DEFAULT '',
+    -- The start of the target's answer
+    response_body TEXT NOT NULL DEFAULT '',
+    duration_ms INTEGER NOT NULL,
+    -- The delivery a manual redelivery repeated
+    retry_of UUID REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at);
+CREATE INDEX idx_webhook_deliveries_created_at ON webhook_deliveries(created_at);
@@ -0,0 +1 @@
+ALTER TABLE sync_idempotency_keys DROP COLUMN IF EXISTS fingerprint;
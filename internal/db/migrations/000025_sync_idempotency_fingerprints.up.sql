@@ -0,0 +1,4 @@
+-- Sync idempotency keys remember the batch they were first used for, so a
+-- key reused for a different batch is refused instead of replaying the
+-- response of the first. Keys stored before have none and still replay.
+ALTER TABLE sync_idempotency_keys ADD COLUMN fingerprint BYTEA;
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Replica is the pool of the read-only replica at DATABASE_REPLICA_URL, nil
+// when none is configured. Use ReadPool rather than Replica directly.
+var Replica *pgxpool.Pool
+
+var (
+	// replicaFresh is set while the replica's lag is within replicaMaxLag
+	replicaFresh  atomic.Bool
+	replicaMaxLag time.Duration
+	replicaStop   chan struct{}
+	replicaDone   sync.WaitGroup
+)
+
+// replicaLagInterval is how often the replica's lag is measured
+const replicaLagInterval = 5 * time.Second
+
+// InitReplica connects to the replica at databaseURL with the settings of
+// the primary's pool. Reads go to it while its replay lag is within maxLag.
+func InitReplica(databaseURL string, pool PoolConfig, maxLag time.Duration) error {
+	p, err := newPool(databaseURL, pool)
+	if err != nil {
+		return err
+	}
+	Replica = p
+	replicaMaxLag = maxLag
+	replicaStop = make(chan struct{})
+
+	checkReplicaLag(context.Background())
+	replicaDone.Add(1)
+	go func() {
+		defer replicaDone.Done()
+		ticker := time.NewTicker(replicaLagInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-replicaStop:
+				return
+			case <-ticker.C:
+				checkReplicaLag(context.Background())
+			}
+		}
+	}()
+	return nil
+}
+
+// checkReplicaLag measures how far the replica's replay is behind the
+// primary. A replica that has replayed everything it received counts as
+// current even if the primary has been idle, and one that cannot be reached
+// counts as stale.
+func checkReplicaLag(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, replicaLagInterval)
+	defer cancel()
+
+	var lag float64
+	err := Replica.QueryRow(ctx, `
+		SELECT CASE
+			WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END
+	`).Scan(&lag)
+	if err != nil {
+		if replicaFresh.Swap(false) {
+			slog.Warn("Replica unavailable, reading from the primary", "error", err)
+		}
+		return
+	}
+
+	fresh := time.Duration(lag*float64(time.Second)) <= replicaMaxLag
+	if replicaFresh.Swap(fresh) != fresh {
+		if fresh {
+			slog.Info("Replica caught up, reading from it again", "lag_seconds", lag)
+		} else {
+			slog.Warn("Replica lagging, reading from the primary", "lag_seconds", lag)
+		}
+	}
+}
+
+// ReplicaStaleness bounds how far behind the primary a read from ReadPool
+// may be: the maximum lag, plus the time until the lag is measured again
+func ReplicaStaleness() time.Duration {
+	return replicaMaxLag + replicaLagInterval
+}
+
+// ReplicaFresh reports whether reads are currently served by the replica
+func ReplicaFresh() bool {
+	return Replica != nil && replicaFresh.Load()
+}
+
+type primaryKey struct{}
+
+// WithPrimary marks ctx so that ReadPool returns the primary, for reads that
+// must see the caller's own recent writes
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReadPool returns the pool for a read that tolerates replication lag up to
+// the configured maximum: reports, lists and sync status. It is the replica
+// while one is configured and fresh, and the primary otherwise or when ctx
+// was marked by WithPrimary. Writes, and reads that feed a write, always use
+// Pool.
+func ReadPool(ctx context.Context) *pgxpool.Pool {
+	if primary, _ := ctx.Value(primaryKey{}).(bool); primary || !ReplicaFresh() {
+		return Pool
+	}
+	return Replica
+}
+
+func closeReplica() {
+	if Replica == nil {
+		return
+	}
+	close(replicaStop)
+	replicaDone.Wait()
+	Replica.Close()
+}
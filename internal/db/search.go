@@ -0,0 +1,60 @@
+package db
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SearchConfig is the text search configuration of the tsvector columns.
+// Queries must be parsed with the same one, as in
+//
+//	description_tsv @@ to_tsquery('simple', $1)
+const SearchConfig = "simple"
+
+// TSQuery turns what a user typed into a search box into the to_tsquery
+// syntax, matching rows that contain every term:
+//
+//	design review      both words
+//	"design review"    the words next to each other, in order
+//	desig*             any word starting with desig
+//
+// Punctuation separates words and is otherwise ignored, so the result is
+// always valid. It returns "" when nothing searchable was typed.
+func TSQuery(input string) string {
+	var terms []string
+	for i, part := range strings.Split(input, `"`) {
+		// Odd parts were between quotes
+		if i%2 == 1 {
+			if phrase := tsPhrase(part, false); phrase != "" {
+				terms = append(terms, phrase)
+			}
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			prefix := strings.HasSuffix(word, "*")
+			if phrase := tsPhrase(word, prefix); phrase != "" {
+				terms = append(terms, phrase)
+			}
+		}
+	}
+	return strings.Join(terms, " & ")
+}
+
+// tsPhrase joins the words of text with the followed-by operator, matching
+// the last one as a prefix if asked
+func tsPhrase(text string, prefix bool) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	if prefix {
+		words[len(words)-1] += ":*"
+	}
+	phrase := strings.Join(words, " <-> ")
+	if len(words) > 1 {
+		phrase = "(" + phrase + ")"
+	}
+	return phrase
+}
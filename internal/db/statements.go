@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// statements are the queries declared with Statement, by name
+var statements = make(map[string]string)
+
+// Statement declares a query under a unique name and returns it unchanged.
+// Declare queries as package variables, as in
+//
+//	var getStorageModeSQL = db.Statement("get_storage_mode", `SELECT storage_mode FROM users WHERE id = $1`)
+//
+// so that CheckStatements can verify every one of them against the schema
+// before any request runs it.
+func Statement(name, sql string) string {
+	if _, ok := statements[name]; ok {
+		panic("db: statement " + name + " declared twice")
+	}
+	statements[name] = sql
+	return sql
+}
+
+// CheckStatements prepares every declared statement without running it.
+// Postgres resolves the tables, columns and parameter types of a statement
+// when preparing it, so a query that drifted from the migrations fails here,
+// at startup or in CI, rather than in the first request that runs it.
+func CheckStatements(ctx context.Context) error {
+	conn, err := Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	names := make([]string, 0, len(statements))
+	for name := range statements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		// The unnamed statement is replaced by the next one
+		if _, err := conn.Conn().PgConn().Prepare(ctx, "", statements[name], nil); err != nil {
+			errs = append(errs, fmt.Errorf("statement %s: %v", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,172 @@
+// Package errorreport sends panics and server errors to Sentry, or a
+// compatible service such as GlitchTip, so failures in production are seen
+// beyond the logs. Reporting is off until Setup is given a DSN; every
+// function is a no-op before that.
+package errorreport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/pacerclub/zebra-backend/internal/logging"
+)
+
+var enabled atomic.Bool
+
+// Setup starts reporting to the project of dsn. Events are tagged with
+// environment and release; an empty release falls back to the VCS revision
+// the binary was built from. An empty dsn leaves reporting off.
+func Setup(dsn, environment, release string) error {
+	if dsn == "" {
+		return nil
+	}
+	if release == "" {
+		release = buildRevision()
+	}
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:              dsn,
+		Environment:      environment,
+		Release:          release,
+		AttachStacktrace: true,
+		BeforeSend:       redact,
+	})
+	if err != nil {
+		return fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+	enabled.Store(true)
+	return nil
+}
+
+// Flush waits up to timeout for queued events to be sent, before exiting
+func Flush(timeout time.Duration) {
+	if enabled.Load() {
+		sentry.Flush(timeout)
+	}
+}
+
+func buildRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return ""
+}
+
+// redact removes credentials from the text of events, as the logs do.
+// Without SendDefaultPII the SDK already leaves out cookies and the
+// Authorization header.
+func redact(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+	event.Message = logging.Redact(event.Message)
+	for i := range event.Exception {
+		event.Exception[i].Value = logging.Redact(event.Exception[i].Value)
+	}
+	if event.Request != nil {
+		event.Request.URL = logging.Redact(event.Request.URL)
+		event.Request.QueryString = logging.Redact(event.Request.QueryString)
+	}
+	return event
+}
+
+type contextKey string
+
+const requestKey contextKey = "error_report"
+
+// request is the reporting state of a request
+type request struct {
+	hub      *sentry.Hub
+	reported bool
+}
+
+// Middleware reports panics and 5xx responses of each request, with its
+// method, URL, headers, id and route. Panics are reported and re-raised for
+// middleware.Recoverer to answer, so it must run inside it. A 5xx response
+// is reported by its status unless the error behind it was reported with
+// CaptureError or CaptureMessage.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !enabled.Load() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		hub := sentry.CurrentHub().Clone()
+		hub.Scope().SetRequest(r)
+		if id := middleware.GetReqID(r.Context()); id != "" {
+			hub.Scope().SetTag("request_id", id)
+		}
+		req := &request{hub: hub}
+		ctx := context.WithValue(sentry.SetHubOnContext(r.Context(), hub), requestKey, req)
+		r = r.WithContext(ctx)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer func() {
+			if p := recover(); p != nil {
+				// Aborted handlers are how net/http cancels a response, not
+				// failures
+				if err, ok := p.(error); !ok || !errors.Is(err, http.ErrAbortHandler) {
+					setRoute(hub, r)
+					hub.RecoverWithContext(ctx, p)
+				}
+				panic(p)
+			}
+			if ww.Status() >= http.StatusInternalServerError && !req.reported {
+				setRoute(hub, r)
+				hub.CaptureMessage(fmt.Sprintf("%d %s", ww.Status(), http.StatusText(ww.Status())))
+			}
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// setRoute tags the event with the route pattern, which groups the errors
+// of one endpoint across ids in the path
+func setRoute(hub *sentry.Hub, r *http.Request) {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			hub.Scope().SetTag("route", r.Method+" "+pattern)
+		}
+	}
+}
+
+// CaptureError reports err as the cause of the request's failure
+func CaptureError(r *http.Request, err error) {
+	req, ok := r.Context().Value(requestKey).(*request)
+	if !ok {
+		return
+	}
+	setRoute(req.hub, r)
+	req.hub.CaptureException(err)
+	req.reported = true
+}
+
+// CaptureMessage reports message as the cause of the request's failure,
+// unless its error was already reported
+func CaptureMessage(r *http.Request, message string) {
+	req, ok := r.Context().Value(requestKey).(*request)
+	if !ok || req.reported {
+		return
+	}
+	setRoute(req.hub, r)
+	req.hub.CaptureMessage(message)
+	req.reported = true
+}
+
+// SetUser attributes the errors of the request ctx belongs to to a user
+func SetUser(ctx context.Context, userID string) {
+	if req, ok := ctx.Value(requestKey).(*request); ok {
+		req.hub.Scope().SetUser(sentry.User{ID: userID})
+	}
+}
@@ -0,0 +1,201 @@
+// Package fieldcrypt encrypts sensitive columns at rest with AES-256-GCM
+// under instance keys, for operators who do not want session descriptions
+// and project names readable from the database or its backups. Unlike the
+// encrypted storage mode, where clients hold the keys, the server encrypts
+// on write and decrypts on read, so the API is unchanged.
+//
+// A sealed value is stored in its text column as
+//
+//	$zf1$<key id>$<base64 of nonce and ciphertext>
+//
+// and anything else is read as plaintext, so encryption can be turned on
+// for an existing database; Rotate then seals the old rows. Several keys
+// may be configured: new values are sealed with the current one and the
+// others are kept to read values sealed before a rotation.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Field names a sealed column. It is authenticated with the value, so a
+// ciphertext copied into another column does not open.
+type Field string
+
+const (
+	SessionDescription Field = "timer_sessions.description"
+	ProjectName        Field = "projects.name"
+)
+
+// prefix starts every sealed value
+const prefix = "$zf1$"
+
+// ErrUnknownKey is returned for values sealed with a key that is not
+// configured
+var ErrUnknownKey = errors.New("value sealed with an unknown key")
+
+var (
+	keys    map[string]cipher.AEAD
+	current string
+)
+
+// Configure sets the keys, as returned by ParseKeys, and the id of the one
+// new values are sealed with. Without keys values are stored in plaintext.
+// It is called at startup, before any value is sealed or opened.
+func Configure(keySet map[string][]byte, currentID string) error {
+	if len(keySet) == 0 {
+		keys, current = nil, ""
+		return nil
+	}
+	if _, ok := keySet[currentID]; !ok {
+		return fmt.Errorf("current key %q is not configured", currentID)
+	}
+	aeads := make(map[string]cipher.AEAD, len(keySet))
+	for id, key := range keySet {
+		if id == "" || strings.Contains(id, "$") {
+			return fmt.Errorf("invalid key id %q", id)
+		}
+		if len(key) != 32 {
+			return fmt.Errorf("key %q must be 32 bytes, not %d", id, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+		aeads[id] = aead
+	}
+	keys, current = aeads, currentID
+	return nil
+}
+
+// ParseKeys reads a comma-separated list of <id>:<base64 key> pairs, the
+// format of FIELD_ENCRYPTION_KEYS. The first key is the current one.
+func ParseKeys(s string) (map[string][]byte, string, error) {
+	keySet := make(map[string][]byte)
+	currentID := ""
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, "", fmt.Errorf("key %q is not <id>:<base64 key>", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, "", fmt.Errorf("key %q is not valid base64", id)
+		}
+		if _, dup := keySet[id]; dup {
+			return nil, "", fmt.Errorf("key %q is listed twice", id)
+		}
+		keySet[id] = key
+		if currentID == "" {
+			currentID = id
+		}
+	}
+	return keySet, currentID, nil
+}
+
+// LoadEnv configures the keys in FIELD_ENCRYPTION_KEYS, or in the file
+// named by FIELD_ENCRYPTION_KEYS_FILE, as written by a KMS or secrets
+// manager agent. Encryption stays off when neither is set.
+func LoadEnv() error {
+	value := os.Getenv("FIELD_ENCRYPTION_KEYS")
+	if file := os.Getenv("FIELD_ENCRYPTION_KEYS_FILE"); file != "" {
+		if value != "" {
+			return errors.New("set FIELD_ENCRYPTION_KEYS or FIELD_ENCRYPTION_KEYS_FILE, not both")
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		value = strings.ReplaceAll(string(data), "\n", ",")
+	}
+	keySet, currentID, err := ParseKeys(value)
+	if err != nil {
+		return err
+	}
+	return Configure(keySet, currentID)
+}
+
+// Enabled reports whether new values are sealed
+func Enabled() bool {
+	return current != ""
+}
+
+// CurrentPrefix is how values sealed with the current key start, "" when
+// encryption is off. Values without it are left for Rotate.
+func CurrentPrefix() string {
+	if current == "" {
+		return ""
+	}
+	return prefix + current + "$"
+}
+
+// Seal encrypts value for field with the current key. Empty values, and
+// every value while encryption is off, are returned unchanged.
+func Seal(field Field, value string) (string, error) {
+	if current == "" || value == "" {
+		return value, nil
+	}
+	aead := keys[current]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(field))
+	return CurrentPrefix() + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value of field stored by Seal. Plaintext values are
+// returned unchanged, whether encryption is on or not.
+func Open(field Field, value string) (string, error) {
+	if !strings.HasPrefix(value, prefix) {
+		return value, nil
+	}
+	id, encoded, ok := strings.Cut(value[len(prefix):], "$")
+	if !ok {
+		return value, nil
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return value, nil
+	}
+	aead, ok := keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return "", fmt.Errorf("sealed %s is truncated", field)
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("opening %s: %w", field, err)
+	}
+	return string(plaintext), nil
+}
+
+// OpenPtr opens *value in place, if value is not nil
+func OpenPtr(field Field, value *string) error {
+	if value == nil {
+		return nil
+	}
+	opened, err := Open(field, *value)
+	if err != nil {
+		return err
+	}
+	*value = opened
+	return nil
+}
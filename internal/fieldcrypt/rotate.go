@@ -0,0 +1,113 @@
+package fieldcrypt
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// rotateBatchSize bounds the rows re-encrypted in one transaction
+const rotateBatchSize = 500
+
+// column is a sealed column and the table it is in
+type column struct {
+	table, name string
+	field       Field
+}
+
+var columns = []column{
+	{"timer_sessions", "description", SessionDescription},
+	{"projects", "name", ProjectName},
+	// Running timers become sessions with the description as stored
+	{"running_timers", "description", SessionDescription},
+}
+
+// Rotate seals every value of the sealed columns that is not sealed with
+// the current key: plaintext written before encryption was turned on or by
+// writers outside the repositories, and values sealed with a former key.
+// Once it has run, keys other than the current one can be removed. Rows
+// keep their sync timestamps, so clients do not download them again.
+func Rotate(ctx context.Context) error {
+	if !Enabled() {
+		return nil
+	}
+	for _, c := range columns {
+		total := 0
+		var after string
+		for {
+			n, last, err := rotateBatch(ctx, c, after)
+			if err != nil {
+				return err
+			}
+			total += n
+			if last == "" {
+				break
+			}
+			after = last
+		}
+		if total > 0 {
+			slog.Info("Re-encrypted column", "table", c.table, "column", c.name, "rows", total)
+		}
+	}
+	return nil
+}
+
+// rotateBatch re-encrypts the next batch of rows with an id above after,
+// returning how many it changed and the last id, "" after the last batch
+func rotateBatch(ctx context.Context, c column, after string) (int, string, error) {
+	type row struct{ id, value string }
+	var rows []row
+	var last string
+	err := pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		rows, last = nil, ""
+		result, err := tx.Query(ctx, `
+			SELECT id::text, `+c.name+` FROM `+c.table+`
+			WHERE ($1 = '' OR id > $1::uuid) AND `+c.name+` <> '' AND LEFT(`+c.name+`, LENGTH($2)) <> $2
+			ORDER BY id
+			LIMIT $3
+		`, after, CurrentPrefix(), rotateBatchSize)
+		if err != nil {
+			return err
+		}
+		for result.Next() {
+			var r row
+			if err := result.Scan(&r.id, &r.value); err != nil {
+				result.Close()
+				return err
+			}
+			rows = append(rows, r)
+		}
+		result.Close()
+		if err := result.Err(); err != nil {
+			return err
+		}
+		if len(rows) == rotateBatchSize {
+			last = rows[len(rows)-1].id
+		}
+
+		if _, err := tx.Exec(ctx, `SET LOCAL zebra.keep_timestamps = 'on'`); err != nil {
+			return err
+		}
+		for _, r := range rows {
+			plaintext, err := Open(c.field, r.value)
+			if err != nil {
+				return err
+			}
+			sealed, err := Seal(c.field, plaintext)
+			if err != nil {
+				return err
+			}
+			// Rows changed since they were read are sealed by their writer
+			_, err = tx.Exec(ctx, `
+				UPDATE `+c.table+` SET `+c.name+` = $1 WHERE id = $2::uuid AND `+c.name+` = $3
+			`, sealed, r.id, r.value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(rows), last, err
+}
@@ -0,0 +1,345 @@
+package grpcapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/grpcapi/zebrapb"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// decoder converts request messages to service types, keeping the first
+// malformed field it meets
+type decoder struct {
+	err error
+}
+
+func (d *decoder) uuid(field, s string) uuid.UUID {
+	if s == "" {
+		return uuid.Nil
+	}
+	id, err := uuid.Parse(s)
+	if err != nil && d.err == nil {
+		d.err = status.Errorf(codes.InvalidArgument, "%s is not a valid UUID", field)
+	}
+	return id
+}
+
+func (d *decoder) optionalUUID(field, s string) *uuid.UUID {
+	if s == "" {
+		return nil
+	}
+	id := d.uuid(field, s)
+	return &id
+}
+
+func (d *decoder) uuids(field string, in []string) []uuid.UUID {
+	out := make([]uuid.UUID, len(in))
+	for i, s := range in {
+		out[i] = d.uuid(fmt.Sprintf("%s[%d]", field, i), s)
+	}
+	return out
+}
+
+func timeOf(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func uuidString(id uuid.UUID) string {
+	if id == uuid.Nil {
+		return ""
+	}
+	return id.String()
+}
+
+func optionalUUIDString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+// listQuery reads the sort and page a list request asks for
+func listQuery[T any](list listquery.List[T], sort string, limit int32, cursor string) (listquery.Query, error) {
+	q, err := list.New(sort, nil, int(limit), cursor)
+	if err != nil {
+		return listquery.Query{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return q, nil
+}
+
+func convertAll[T, U any](in []T, convert func(T) U) []U {
+	out := make([]U, len(in))
+	for i, v := range in {
+		out[i] = convert(v)
+	}
+	return out
+}
+
+func (d *decoder) session(m *zebrapb.Session) service.Session {
+	return service.Session{
+		ID:                   d.uuid("id", m.GetId()),
+		ProjectID:            d.optionalUUID("project_id", m.GetProjectId()),
+		StartTime:            timeOf(m.GetStartTime()),
+		EndTime:              timeOf(m.GetEndTime()),
+		Description:          m.GetDescription(),
+		EncryptedDescription: m.GetEncryptedDescription(),
+		KeyID:                m.GetKeyId(),
+		DeviceID:             m.GetDeviceId(),
+		IsDeleted:            m.GetIsDeleted(),
+		CreatedAt:            timeOf(m.GetCreatedAt()),
+		UpdatedAt:            timeOf(m.GetUpdatedAt()),
+		Version:              m.GetVersion(),
+	}
+}
+
+func sessionMessage(s service.Session) *zebrapb.Session {
+	return &zebrapb.Session{
+		Id:                   uuidString(s.ID),
+		UserId:               uuidString(s.UserID),
+		ProjectId:            optionalUUIDString(s.ProjectID),
+		StartTime:            timestamp(s.StartTime),
+		EndTime:              timestamp(s.EndTime),
+		Description:          s.Description,
+		EncryptedDescription: s.EncryptedDescription,
+		KeyId:                s.KeyID,
+		DeviceId:             s.DeviceID,
+		IsDeleted:            s.IsDeleted,
+		CreatedAt:            timestamp(s.CreatedAt),
+		UpdatedAt:            timestamp(s.UpdatedAt),
+		Version:              s.Version,
+	}
+}
+
+func (d *decoder) project(m *zebrapb.Project) service.Project {
+	return service.Project{
+		ID:                   d.uuid("id", m.GetId()),
+		Name:                 m.GetName(),
+		Description:          m.GetDescription(),
+		Color:                m.GetColor(),
+		EncryptedName:        m.GetEncryptedName(),
+		EncryptedDescription: m.GetEncryptedDescription(),
+		KeyID:                m.GetKeyId(),
+		OrganizationID:       d.optionalUUID("organization_id", m.GetOrganizationId()),
+		DeviceID:             m.GetDeviceId(),
+		IsDeleted:            m.GetIsDeleted(),
+		CreatedAt:            timeOf(m.GetCreatedAt()),
+		UpdatedAt:            timeOf(m.GetUpdatedAt()),
+		Version:              m.GetVersion(),
+	}
+}
+
+func projectMessage(p service.Project) *zebrapb.Project {
+	return &zebrapb.Project{
+		Id:                   uuidString(p.ID),
+		UserId:               uuidString(p.UserID),
+		Name:                 p.Name,
+		Description:          p.Description,
+		Color:                p.Color,
+		EncryptedName:        p.EncryptedName,
+		EncryptedDescription: p.EncryptedDescription,
+		KeyId:                p.KeyID,
+		OrganizationId:       optionalUUIDString(p.OrganizationID),
+		DeviceId:             p.DeviceID,
+		IsDeleted:            p.IsDeleted,
+		CreatedAt:            timestamp(p.CreatedAt),
+		UpdatedAt:            timestamp(p.UpdatedAt),
+		Version:              p.Version,
+	}
+}
+
+func (d *decoder) tag(m *zebrapb.Tag) service.Tag {
+	return service.Tag{
+		ID:        d.uuid("local_tags.id", m.GetId()),
+		Name:      m.GetName(),
+		Color:     m.GetColor(),
+		DeviceID:  m.GetDeviceId(),
+		IsDeleted: m.GetIsDeleted(),
+		CreatedAt: timeOf(m.GetCreatedAt()),
+		UpdatedAt: timeOf(m.GetUpdatedAt()),
+	}
+}
+
+func tagMessage(t service.Tag) *zebrapb.Tag {
+	return &zebrapb.Tag{
+		Id:        uuidString(t.ID),
+		UserId:    uuidString(t.UserID),
+		Name:      t.Name,
+		Color:     t.Color,
+		DeviceId:  t.DeviceID,
+		IsDeleted: t.IsDeleted,
+		CreatedAt: timestamp(t.CreatedAt),
+		UpdatedAt: timestamp(t.UpdatedAt),
+	}
+}
+
+func (d *decoder) task(m *zebrapb.Task) service.Task {
+	return service.Task{
+		ID:          d.uuid("local_tasks.id", m.GetId()),
+		ProjectID:   d.optionalUUID("local_tasks.project_id", m.GetProjectId()),
+		Name:        m.GetName(),
+		Description: m.GetDescription(),
+		IsCompleted: m.GetIsCompleted(),
+		DeviceID:    m.GetDeviceId(),
+		IsDeleted:   m.GetIsDeleted(),
+		CreatedAt:   timeOf(m.GetCreatedAt()),
+		UpdatedAt:   timeOf(m.GetUpdatedAt()),
+	}
+}
+
+func taskMessage(t service.Task) *zebrapb.Task {
+	return &zebrapb.Task{
+		Id:          uuidString(t.ID),
+		UserId:      uuidString(t.UserID),
+		ProjectId:   optionalUUIDString(t.ProjectID),
+		Name:        t.Name,
+		Description: t.Description,
+		IsCompleted: t.IsCompleted,
+		DeviceId:    t.DeviceID,
+		IsDeleted:   t.IsDeleted,
+		CreatedAt:   timestamp(t.CreatedAt),
+		UpdatedAt:   timestamp(t.UpdatedAt),
+	}
+}
+
+func (d *decoder) template(m *zebrapb.SessionTemplate) service.SessionTemplate {
+	return service.SessionTemplate{
+		ID:          d.uuid("local_templates.id", m.GetId()),
+		ProjectID:   d.optionalUUID("local_templates.project_id", m.GetProjectId()),
+		Name:        m.GetName(),
+		Description: m.GetDescription(),
+		DeviceID:    m.GetDeviceId(),
+		IsDeleted:   m.GetIsDeleted(),
+		CreatedAt:   timeOf(m.GetCreatedAt()),
+		UpdatedAt:   timeOf(m.GetUpdatedAt()),
+	}
+}
+
+func templateMessage(t service.SessionTemplate) *zebrapb.SessionTemplate {
+	return &zebrapb.SessionTemplate{
+		Id:          uuidString(t.ID),
+		UserId:      uuidString(t.UserID),
+		ProjectId:   optionalUUIDString(t.ProjectID),
+		Name:        t.Name,
+		Description: t.Description,
+		DeviceId:    t.DeviceID,
+		IsDeleted:   t.IsDeleted,
+		CreatedAt:   timestamp(t.CreatedAt),
+		UpdatedAt:   timestamp(t.UpdatedAt),
+	}
+}
+
+func (d *decoder) preference(m *zebrapb.Preference) service.Preference {
+	value := m.GetValueJson()
+	if len(value) > 0 && !json.Valid(value) && d.err == nil {
+		d.err = status.Errorf(codes.InvalidArgument, "value_json of preference %q is not valid JSON", m.GetKey())
+	}
+	return service.Preference{
+		Key:       m.GetKey(),
+		Value:     value,
+		DeviceID:  m.GetDeviceId(),
+		IsDeleted: m.GetIsDeleted(),
+		UpdatedAt: timeOf(m.GetUpdatedAt()),
+	}
+}
+
+func preferenceMessage(p service.Preference) *zebrapb.Preference {
+	return &zebrapb.Preference{
+		Key:       p.Key,
+		ValueJson: p.Value,
+		DeviceId:  p.DeviceID,
+		IsDeleted: p.IsDeleted,
+		UpdatedAt: timestamp(p.UpdatedAt),
+	}
+}
+
+func itemErrorMessage(e service.SyncItemError) *zebrapb.SyncItemError {
+	return &zebrapb.SyncItemError{
+		Collection: e.Collection,
+		Index:      int32(e.Index),
+		Id:         uuidString(e.ID),
+		Key:        e.Key,
+		Field:      e.Field,
+		Message:    e.Message,
+	}
+}
+
+func conflictMessage(c service.SyncConflict) *zebrapb.SyncConflict {
+	return &zebrapb.SyncConflict{
+		Id:             uuidString(c.ID),
+		Collection:     c.Collection,
+		EntityId:       uuidString(c.EntityID),
+		ServerVersion:  c.ServerVersion,
+		ClientVersion:  c.ClientVersion,
+		ServerDeviceId: c.ServerDeviceID,
+		ClientDeviceId: c.ClientDeviceID,
+		Resolution:     c.Resolution,
+		CreatedAt:      timestamp(c.CreatedAt),
+	}
+}
+
+func (d *decoder) syncRequest(m *zebrapb.SyncRequest) *service.SyncRequest {
+	return &service.SyncRequest{
+		BatchID:            d.uuid("batch_id", m.GetBatchId()),
+		DeviceID:           m.GetDeviceId(),
+		DeviceName:         m.GetDeviceName(),
+		Platform:           m.GetPlatform(),
+		LastSyncTime:       timeOf(m.GetLastSyncTime()),
+		LocalSessions:      convertAll(m.GetLocalSessions(), d.session),
+		LocalProjects:      convertAll(m.GetLocalProjects(), d.project),
+		DeletedSessions:    d.uuids("deleted_sessions", m.GetDeletedSessions()),
+		DeletedProjects:    d.uuids("deleted_projects", m.GetDeletedProjects()),
+		LocalTags:          convertAll(m.GetLocalTags(), d.tag),
+		LocalTasks:         convertAll(m.GetLocalTasks(), d.task),
+		LocalTemplates:     convertAll(m.GetLocalTemplates(), d.template),
+		LocalPreferences:   convertAll(m.GetLocalPreferences(), d.preference),
+		DeletedTags:        d.uuids("deleted_tags", m.GetDeletedTags()),
+		DeletedTasks:       d.uuids("deleted_tasks", m.GetDeletedTasks()),
+		DeletedTemplates:   d.uuids("deleted_templates", m.GetDeletedTemplates()),
+		DeletedPreferences: m.GetDeletedPreferences(),
+	}
+}
+
+func syncResponseMessage(r *service.SyncResponse, replayed bool) *zebrapb.SyncResponse {
+	return &zebrapb.SyncResponse{
+		LastSyncTime:       timestamp(r.LastSyncTime),
+		ServerSessions:     convertAll(r.ServerSessions, sessionMessage),
+		ServerProjects:     convertAll(r.ServerProjects, projectMessage),
+		ServerTags:         convertAll(r.ServerTags, tagMessage),
+		ServerTasks:        convertAll(r.ServerTasks, taskMessage),
+		ServerTemplates:    convertAll(r.ServerTemplates, templateMessage),
+		ServerPreferences:  convertAll(r.ServerPreferences, preferenceMessage),
+		Rejected:           convertAll(r.Rejected, itemErrorMessage),
+		Conflicts:          convertAll(r.Conflicts, conflictMessage),
+		FullResyncRequired: r.FullResyncRequired,
+		Replayed:           replayed,
+	}
+}
+
+func snapshotPageMessage(p *service.SnapshotPage) *zebrapb.SnapshotPage {
+	return &zebrapb.SnapshotPage{
+		SnapshotTime: timestamp(p.SnapshotTime),
+		Projects:     convertAll(p.Projects, projectMessage),
+		Sessions:     convertAll(p.Sessions, sessionMessage),
+		Tags:         convertAll(p.Tags, tagMessage),
+		Tasks:        convertAll(p.Tasks, taskMessage),
+		Templates:    convertAll(p.Templates, templateMessage),
+		Preferences:  convertAll(p.Preferences, preferenceMessage),
+	}
+}
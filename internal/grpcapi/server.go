@@ -0,0 +1,185 @@
+// Package grpcapi serves the core operations (auth, sessions, projects and
+// sync) over gRPC for the desktop client. The services only translate
+// messages; the work is done by the service package, as for the HTTP API.
+package grpcapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/grpcapi/zebrapb"
+	"github.com/pacerclub/zebra-backend/internal/ratelimit"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/usage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=github.com/pacerclub/zebra-backend --go-grpc_out=../.. --go-grpc_opt=module=github.com/pacerclub/zebra-backend zebra/v1/zebra.proto
+
+// NewServer returns a gRPC server with every service registered. Sync
+// batches count against the same limiters as the HTTP sync endpoints.
+func NewServer(syncDeviceLimiter, syncUserLimiter *ratelimit.Limiter, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(append([]grpc.ServerOption{
+		grpc.MaxRecvMsgSize(service.MaxSyncBodyBytes),
+		grpc.ChainUnaryInterceptor(unaryAuth),
+		grpc.ChainStreamInterceptor(streamAuth),
+	}, opts...)...)
+	zebrapb.RegisterAuthServiceServer(server, authServer{})
+	zebrapb.RegisterSessionServiceServer(server, sessionServer{})
+	zebrapb.RegisterProjectServiceServer(server, projectServer{})
+	zebrapb.RegisterSyncServiceServer(server, &syncServer{
+		deviceLimiter: syncDeviceLimiter,
+		userLimiter:   syncUserLimiter,
+	})
+	return server
+}
+
+// publicMethods take no token
+var publicMethods = map[string]bool{
+	zebrapb.AuthService_Register_FullMethodName: true,
+	zebrapb.AuthService_Login_FullMethodName:    true,
+}
+
+func unaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	ctx, err := authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := handler(ctx, req)
+	usage.Record(auth.GetUserIDFromContext(ctx), methodKind(info.FullMethod), messageSize(req), messageSize(resp))
+	return resp, err
+}
+
+func streamAuth(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	counted := &authenticatedStream{ServerStream: stream, ctx: ctx}
+	err = handler(srv, counted)
+	usage.Record(auth.GetUserIDFromContext(ctx), methodKind(info.FullMethod), counted.bytesIn, counted.bytesOut)
+	return err
+}
+
+// authenticatedStream carries the context set up by authenticate, and
+// counts the bytes of the messages for usage
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx               context.Context
+	bytesIn, bytesOut int64
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authenticatedStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.bytesIn += messageSize(m)
+	}
+	return err
+}
+
+func (s *authenticatedStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.bytesOut += messageSize(m)
+	}
+	return err
+}
+
+// methodKind tells sync calls from the rest of the API, as for HTTP
+func methodKind(fullMethod string) string {
+	if strings.HasPrefix(fullMethod, "/"+zebrapb.SyncService_ServiceDesc.ServiceName+"/") {
+		return usage.KindSync
+	}
+	return usage.KindAPI
+}
+
+// messageSize is the encoded size of a message, 0 for nil responses
+func messageSize(m interface{}) int64 {
+	if msg, ok := m.(proto.Message); ok && msg != nil {
+		return int64(proto.Size(msg))
+	}
+	return 0
+}
+
+// authenticate sets up the context the way auth.Middleware and
+// auth.OrganizationMiddleware do for HTTP requests. Tokens issued to
+// third-party apps are limited to the scoped HTTP routes and rejected here,
+// as are impersonation tokens, whose requests are audited over HTTP only.
+func authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx, authErr := auth.Authenticate(ctx, first(md, "authorization"))
+	if authErr != nil {
+		return nil, authStatus(authErr)
+	}
+	if _, ok := ctx.Value(auth.ScopesKey).([]string); ok {
+		return nil, status.Error(codes.PermissionDenied, "App tokens cannot use the gRPC API")
+	}
+	if auth.GetImpersonatorIDFromContext(ctx) != uuid.Nil {
+		return nil, status.Error(codes.PermissionDenied, "Impersonation tokens cannot use the gRPC API")
+	}
+
+	ctx, authErr = auth.SelectWorkspace(ctx,
+		first(md, strings.ToLower(auth.WorkspaceHeader)), first(md, strings.ToLower(auth.OrganizationHeader)))
+	if authErr != nil {
+		return nil, authStatus(authErr)
+	}
+	return ctx, nil
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func authStatus(err *auth.AuthError) error {
+	code := codes.Internal
+	switch err.Status {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	}
+	return status.Error(code, err.Message)
+}
+
+// serviceStatus maps a service error to a gRPC status
+func serviceStatus(err error) error {
+	code := codes.Internal
+	switch service.ErrorCode(err) {
+	case service.InvalidArgument:
+		code = codes.InvalidArgument
+	case service.Unauthenticated:
+		code = codes.Unauthenticated
+	case service.PermissionDenied:
+		code = codes.PermissionDenied
+	case service.NotFound:
+		code = codes.NotFound
+	case service.TooLarge:
+		code = codes.ResourceExhausted
+	case service.Unimplemented:
+		code = codes.Unimplemented
+	case service.Conflict:
+		code = codes.Aborted
+	case service.PaymentRequired:
+		code = codes.ResourceExhausted
+	}
+	return status.Error(code, service.ErrorMessage(err))
+}
@@ -0,0 +1,264 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pacerclub/zebra-backend/internal/audit"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/grpcapi/zebrapb"
+	"github.com/pacerclub/zebra-backend/internal/ratelimit"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type authServer struct {
+	zebrapb.UnimplementedAuthServiceServer
+}
+
+func (authServer) Register(ctx context.Context, req *zebrapb.RegisterRequest) (*zebrapb.TokenResponse, error) {
+	token, _, err := service.Register(ctx, req.GetEmail(), req.GetPassword(), req.GetDeviceId())
+	if err != nil {
+		return nil, serviceStatus(err)
+	}
+	return &zebrapb.TokenResponse{Token: token}, nil
+}
+
+func (authServer) Login(ctx context.Context, req *zebrapb.LoginRequest) (*zebrapb.TokenResponse, error) {
+	token, _, err := service.Login(ctx, req.GetEmail(), req.GetPassword(), req.GetDeviceId(), clientOf(ctx))
+	if err != nil {
+		return nil, serviceStatus(err)
+	}
+	return &zebrapb.TokenResponse{Token: token}, nil
+}
+
+// clientOf returns the client of a call, its location read from the
+// metadata the proxy in front of the server sets
+func clientOf(ctx context.Context) audit.Client {
+	md, _ := metadata.FromIncomingContext(ctx)
+	get := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	client := audit.Client{UserAgent: get("user-agent"), Location: audit.Location(get)}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		client.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(client.IP); err == nil {
+			client.IP = host
+		}
+	}
+	return client
+}
+
+type sessionServer struct {
+	zebrapb.UnimplementedSessionServiceServer
+}
+
+func (sessionServer) ListSessions(ctx context.Context, req *zebrapb.ListSessionsRequest) (*zebrapb.ListSessionsResponse, error) {
+	q, err := listQuery(service.SessionList, req.GetSort(), req.GetLimit(), req.GetCursor())
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := service.ListSessions(ctx, auth.GetUserIDFromContext(ctx), req.GetAllMembers(), q)
+	if err != nil {
+		return nil, serviceStatus(err)
+	}
+	return &zebrapb.ListSessionsResponse{
+		Sessions:   convertAll(sessions.Data, sessionMessage),
+		NextCursor: sessions.NextCursor,
+		HasMore:    sessions.HasMore,
+	}, nil
+}
+
+func (sessionServer) CreateSession(ctx context.Context, req *zebrapb.Session) (*zebrapb.Session, error) {
+	var d decoder
+	session := d.session(req)
+	if d.err != nil {
+		return nil, d.err
+	}
+	session, err := service.CreateSession(ctx, auth.GetUserIDFromContext(ctx), session)
+	if err != nil {
+		return nil, serviceStatus(err)
+	}
+	return sessionMessage(session), nil
+}
+
+func (sessionServer) UpdateSession(ctx context.Context, req *zebrapb.Session) (*zebrapb.Session, error) {
+	var d decoder
+	session := d.session(req)
+	if d.err != nil {
+		return nil, d.err
+	}
+	session, err := service.UpdateSession(ctx, auth.GetUserIDFromContext(ctx), session.ID, session)
+	if err != nil {
+		return nil, serviceStatus(err)
+	}
+	return sessionMessage(session), nil
+}
+
+func (sessionServer) DeleteSession(ctx context.Context, req *zebrapb.DeleteRequest) (*emptypb.Empty, error) {
+	var d decoder
+	sessionID := d.uuid("id", req.GetId())
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err := service.DeleteSession(ctx, auth.GetUserIDFromContext(ctx), sessionID); err != nil {
+		return nil, serviceStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+type projectServer struct {
+	zebrapb.UnimplementedProjectServiceServer
+}
+
+func (projectServer) ListProjects(ctx context.Context, req *zebrapb.ListProjectsRequest) (*zebrapb.ListProjectsResponse, error) {
+	q, err := listQuery(service.ProjectList, req.GetSort(), req.GetLimit(), req.GetCursor())
+	if err != nil {
+		return nil, err
+	}
+	projects, err := service.ListProjects(ctx, auth.GetUserIDFromContext(ctx), q)
+	if err != nil {
+		return nil, serviceStatus(err)
+	}
+	return &zebrapb.ListProjectsResponse{
+		Projects:   convertAll(projects.Data, projectMessage),
+		NextCursor: projects.NextCursor,
+		HasMore:    projects.HasMore,
+	}, nil
+}
+
+func (projectServer) CreateProject(ctx context.Context, req *zebrapb.Project) (*zebrapb.Project, error) {
+	var d decoder
+	project := d.project(req)
+	if d.err != nil {
+		return nil, d.err
+	}
+	project, err := service.CreateProject(ctx, auth.GetUserIDFromContext(ctx), project)
+	if err != nil {
+		return nil, serviceStatus(err)
+	}
+	return projectMessage(project), nil
+}
+
+func (projectServer) UpdateProject(ctx context.Context, req *zebrapb.Project) (*zebrapb.Project, error) {
+	var d decoder
+	project := d.project(req)
+	if d.err != nil {
+		return nil, d.err
+	}
+	project, err := service.UpdateProject(ctx, auth.GetUserIDFromContext(ctx), project.ID, project)
+	if err != nil {
+		return nil, serviceStatus(err)
+	}
+	return projectMessage(project), nil
+}
+
+func (projectServer) DeleteProject(ctx context.Context, req *zebrapb.DeleteRequest) (*emptypb.Empty, error) {
+	var d decoder
+	projectID := d.uuid("id", req.GetId())
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err := service.DeleteProject(ctx, auth.GetUserIDFromContext(ctx), projectID); err != nil {
+		return nil, serviceStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+type syncServer struct {
+	zebrapb.UnimplementedSyncServiceServer
+	deviceLimiter *ratelimit.Limiter
+	userLimiter   *ratelimit.Limiter
+}
+
+// Sync applies batches as they arrive. Responses are stored as msgpack for
+// idempotent replays, the most compact encoding the service offers.
+func (s *syncServer) Sync(stream zebrapb.SyncService_SyncServer) error {
+	ctx := stream.Context()
+	userID := auth.GetUserIDFromContext(ctx)
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		deviceID := auth.GetDeviceIDFromContext(ctx)
+		if deviceID == "" {
+			deviceID = req.GetDeviceId()
+		}
+		if err := allow(stream, s.deviceLimiter, userID.String()+"/"+deviceID); err != nil {
+			return err
+		}
+		if err := allow(stream, s.userLimiter, userID.String()); err != nil {
+			return err
+		}
+
+		var d decoder
+		syncReq := d.syncRequest(req)
+		if d.err != nil {
+			return d.err
+		}
+		result, err := service.Sync(ctx, userID, syncReq, service.SyncOptions{ContentType: service.ContentTypeMsgpack})
+		if err != nil {
+			return serviceStatus(err)
+		}
+		if err := stream.Send(syncResponseMessage(result.Response, result.Replayed)); err != nil {
+			return err
+		}
+	}
+}
+
+// ResetSync pages through the snapshot on the server and streams every page
+func (s *syncServer) ResetSync(req *zebrapb.ResetSyncRequest, stream zebrapb.SyncService_ResetSyncServer) error {
+	ctx := stream.Context()
+	userID := auth.GetUserIDFromContext(ctx)
+	if err := allow(stream, s.userLimiter, userID.String()); err != nil {
+		return err
+	}
+
+	reset := service.SyncResetRequest{DeviceID: req.GetDeviceId(), PageSize: int(req.GetPageSize())}
+	for {
+		page, err := service.ResetSync(ctx, userID, reset)
+		if err != nil {
+			return serviceStatus(err)
+		}
+		if err := stream.Send(snapshotPageMessage(page)); err != nil {
+			return err
+		}
+		if page.NextPageToken == "" {
+			return nil
+		}
+		reset.PageToken = page.NextPageToken
+	}
+}
+
+// allow takes a token from limiter, answering ResourceExhausted with a
+// retry-after trailer when none is left
+func allow(stream grpc.ServerStream, limiter *ratelimit.Limiter, key string) error {
+	result := limiter.Take(stream.Context(), key)
+	if result.Allowed {
+		return nil
+	}
+	seconds := int(result.RetryAfter.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	stream.SetTrailer(metadata.Pairs("retry-after", strconv.Itoa(seconds)))
+	return status.Error(codes.ResourceExhausted, "Too many sync requests")
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/i18n"
+	"github.com/pacerclub/zebra-backend/internal/logging"
+	"github.com/pacerclub/zebra-backend/internal/mail"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+type deactivateRequest struct {
+	// Password confirms that the account's owner is deactivating it
+	Password string `json:"password"`
+}
+
+func (req *deactivateRequest) Validate(v *validate.Validator) {
+	v.Required("password", req.Password)
+}
+
+type reactivationRequest struct {
+	Email string `json:"email"`
+}
+
+func (req *reactivationRequest) Validate(v *validate.Validator) {
+	req.Email = strings.TrimSpace(req.Email)
+	v.Required("email", req.Email)
+	v.Email("email", req.Email)
+}
+
+type confirmReactivationRequest struct {
+	Token    string `json:"token"`
+	DeviceID string `json:"device_id"`
+}
+
+func (req *confirmReactivationRequest) Validate(v *validate.Validator) {
+	v.Required("token", req.Token)
+	v.MaxLength("device_id", req.DeviceID, service.MaxNameLength)
+}
+
+// DeactivateAccount deactivates the user's account after checking their
+// password. Their data is kept; every token stops working and they cannot
+// log in or sync until they reactivate by email.
+func DeactivateAccount(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req deactivateRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := models.GetUserByID(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch user", http.StatusInternalServerError)
+		return
+	}
+	if !user.ValidatePassword(req.Password) {
+		apierror.Write(w, r, http.StatusUnauthorized, apierror.InvalidCredentials, "Invalid credentials", nil)
+		return
+	}
+
+	if err := models.DeactivateUser(r.Context(), userID); err != nil {
+		apierror.Error(w, r, "Failed to deactivate account", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RequestReactivation mails a reactivation link to a deactivated account.
+// It answers the same whether or not the address belongs to one, so it
+// does not reveal which addresses have accounts.
+func RequestReactivation(w http.ResponseWriter, r *http.Request) {
+	if !mail.Configured() {
+		apierror.Write(w, r, http.StatusServiceUnavailable, apierror.NotConfigured, "Mail is not configured", nil)
+		return
+	}
+
+	var req reactivationRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := models.GetUserByEmail(r.Context(), req.Email)
+	if err == nil && user.DeactivatedAt != nil {
+		sendReactivation(r, user)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sendReactivation mails the user a new reactivation link, logging failures
+func sendReactivation(r *http.Request, user *models.User) {
+	token, err := models.CreateReactivation(r.Context(), user.ID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to create reactivation", "user_id", user.ID, "error", err)
+		return
+	}
+
+	// REACTIVATION_URL is the client page that confirms the token; without
+	// it users paste the token into their client
+	link := token
+	if base := os.Getenv("REACTIVATION_URL"); base != "" {
+		link = base + "?token=" + url.QueryEscape(token)
+	}
+	validFor := fmt.Sprintf("%d hours", int(models.ReactivationTTL.Hours()))
+	msg, err := mail.Render(mail.TemplateReactivation, user.Locale, user.Email, map[string]interface{}{
+		"Link":     link,
+		"ValidFor": i18n.T(i18n.Of(user.Locale), validFor),
+	})
+	if err == nil {
+		err = mail.Enqueue(r.Context(), msg)
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to send reactivation email", "user_id", user.ID, "error", err)
+	}
+}
+
+// ConfirmReactivation reactivates the account of a mailed token and logs
+// the user in on the device
+func ConfirmReactivation(w http.ResponseWriter, r *http.Request) {
+	var req confirmReactivationRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := models.Reactivate(r.Context(), req.Token)
+	if errors.Is(err, models.ErrInvalidReactivation) {
+		writeValidationError(w, r, validate.Errors{{Field: "token", Message: "token is invalid or expired"}})
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to reactivate account", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := auth.GenerateToken(user.ID, user.Email, req.DeviceID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(authResponse{Token: token, User: user})
+}
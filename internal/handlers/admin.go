@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/backup"
+	"github.com/pacerclub/zebra-backend/internal/logging"
+	"github.com/pacerclub/zebra-backend/internal/opstats"
+	"github.com/pacerclub/zebra-backend/internal/scheduler"
+)
+
+// AdminStats returns user counts and the daily statistics of the last
+// ?days= days (30 by default), for the ops dashboard
+func AdminStats(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if value := r.URL.Query().Get("days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > opstats.MaxDays {
+			apierror.Error(w, r, fmt.Sprintf("days must be between 1 and %d", opstats.MaxDays), http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	stats, err := opstats.Collect(r.Context(), days)
+	if err != nil {
+		apierror.Error(w, r, "Failed to collect statistics", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// AdminTasks lists the recurring tasks with their schedule and latest run
+func AdminTasks(tasks *scheduler.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses, err := tasks.Statuses(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Failed to fetch task runs", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(statuses)
+	}
+}
+
+// AdminHealth reports the health of the database. It answers 503 when the
+// database is unreachable, so it can also back an uptime check.
+func AdminHealth(w http.ResponseWriter, r *http.Request) {
+	health := opstats.CheckHealth(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if !health.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(health)
+}
+
+// AdminBackup downloads an archive of the instance, or of the user in
+// ?user_id=. Large instances are better backed up with `admin backup`,
+// which is not bound by the request timeout.
+func AdminBackup(w http.ResponseWriter, r *http.Request) {
+	var userID *uuid.UUID
+	if value := r.URL.Query().Get("user_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			apierror.Error(w, r, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		userID = &id
+	}
+
+	name := "zebra-" + time.Now().UTC().Format("20060102-150405")
+	if userID != nil {
+		name += "-" + userID.String()
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.jsonl.gz"`, name))
+	// Headers are sent with the first row, so a later failure can only cut
+	// the archive short, which restore rejects
+	if err := backup.Backup(r.Context(), w, userID); err != nil {
+		logging.FromContext(r.Context()).Error("Backup failed", "error", err)
+	}
+}
+
+// AdminRestore reads an archive uploaded as the request body into the
+// database and returns the number of rows restored per table
+func AdminRestore(w http.ResponseWriter, r *http.Request) {
+	manifest, counts, err := backup.Restore(r.Context(), r.Body)
+	if errors.Is(err, backup.ErrInvalidArchive) || errors.Is(err, backup.ErrSchemaMismatch) {
+		apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Restore failed", "error", err)
+		apierror.Error(w, r, "Failed to restore backup", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RestoreResult{Manifest: *manifest, Restored: counts})
+}
+
+// RestoreResult reports a restored archive
+type RestoreResult struct {
+	Manifest backup.Manifest `json:"manifest"`
+	// Restored counts the rows inserted per table; rows already present
+	// are skipped
+	Restored map[string]int64 `json:"restored"`
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/audit"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+)
+
+// auditList is how audit log entries can be sorted, by default newest first
+var auditList = listquery.List[audit.Entry]{
+	Sorts: map[string]listquery.Column[audit.Entry]{
+		"created_at": {SQL: "created_at", Key: func(e audit.Entry) time.Time { return e.CreatedAt }},
+	},
+	Default: listquery.Sort{Field: "created_at", Desc: true},
+	RowID:   func(e audit.Entry) uuid.UUID { return e.ID },
+}
+
+// ListAuditLog returns a page of the audit log of the user's own calls.
+// Supports the filters of listAuditLog.
+func ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	listAuditLog(w, r, &userID)
+}
+
+// AdminAuditLog returns a page of the audit log of every user, or of
+// ?user_id=. Supports the filters of listAuditLog.
+func AdminAuditLog(w http.ResponseWriter, r *http.Request) {
+	var userID *uuid.UUID
+	if value := r.URL.Query().Get("user_id"); value != "" {
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			apierror.Error(w, r, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		userID = &parsed
+	}
+	listAuditLog(w, r, userID)
+}
+
+// listAuditLog writes a page of the audit log of userID, or of every user
+// if nil. Supports ?entity_type=, ?entity_id=, ?device_id=, ?method=,
+// ?impersonator_id= and ?since= and ?until= as RFC 3339 times.
+func listAuditLog(w http.ResponseWriter, r *http.Request, userID *uuid.UUID) {
+	q, ok := listParams(w, r, auditList)
+	if !ok {
+		return
+	}
+
+	values := r.URL.Query()
+	var since, until *time.Time
+	for _, p := range []struct {
+		name string
+		dest **time.Time
+	}{{"since", &since}, {"until", &until}} {
+		if value := values.Get(p.name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidRequest, "Invalid "+p.name,
+					map[string]interface{}{"parameter": p.name})
+				return
+			}
+			*p.dest = &t
+		}
+	}
+
+	var impersonatorID *uuid.UUID
+	if value := values.Get("impersonator_id"); value != "" {
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidRequest, "Invalid impersonator_id",
+				map[string]interface{}{"parameter": "impersonator_id"})
+			return
+		}
+		impersonatorID = &parsed
+	}
+
+	optional := func(name string) *string {
+		if value := values.Get(name); value != "" {
+			return &value
+		}
+		return nil
+	}
+
+	where, orderBy := auditList.SQL(q, 9)
+	query := `
+		SELECT id, user_id, organization_id, COALESCE(device_id, ''), COALESCE(ip, ''),
+			COALESCE(location, ''), COALESCE(user_agent, ''), COALESCE(request_id, ''), method, route,
+			entity_type, COALESCE(entity_id, ''), status, impersonator_id, COALESCE(reason, ''), created_at
+		FROM audit_log
+		WHERE ($1::uuid IS NULL OR user_id = $1)
+		  AND ($2::text IS NULL OR entity_type = $2)
+		  AND ($3::text IS NULL OR entity_id = $3)
+		  AND ($4::text IS NULL OR device_id = $4)
+		  AND ($5::text IS NULL OR method = upper($5))
+		  AND ($6::timestamptz IS NULL OR created_at >= $6)
+		  AND ($7::timestamptz IS NULL OR created_at < $7)
+		  AND ($8::uuid IS NULL OR impersonator_id = $8)
+		  AND ` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT $11
+	`
+
+	args := []interface{}{userID, optional("entity_type"), optional("entity_id"), optional("device_id"),
+		optional("method"), since, until, impersonatorID}
+	args = append(args, q.Args()...)
+	rows, err := db.ReadPool(r.Context()).Query(r.Context(), query, append(args, q.Page.Fetch())...)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch audit log", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var entries []audit.Entry
+	for rows.Next() {
+		var entry audit.Entry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.UserID,
+			&entry.OrganizationID,
+			&entry.DeviceID,
+			&entry.IP,
+			&entry.Location,
+			&entry.UserAgent,
+			&entry.RequestID,
+			&entry.Method,
+			&entry.Route,
+			&entry.EntityType,
+			&entry.EntityID,
+			&entry.Status,
+			&entry.ImpersonatorID,
+			&entry.Reason,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			apierror.Error(w, r, "Failed to scan audit log entry", http.StatusInternalServerError)
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	writePage(w, r, auditList.Page(entries, q), q)
+}
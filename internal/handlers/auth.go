@@ -2,10 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"unicode/utf8"
 
-	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/audit"
 	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
 )
 
 type loginRequest struct {
@@ -20,67 +25,58 @@ type registerRequest struct {
 	DeviceID string `json:"device_id"`
 }
 
-type errorResponse struct {
-	Error string `json:"error"`
+const minPasswordLength = 8
+
+// authResponse is the answer to a login or registration
+type authResponse struct {
+	Token string       `json:"token"`
+	User  *models.User `json:"user"`
 }
 
-func sendError(w http.ResponseWriter, message string, code int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(errorResponse{Error: message})
+func (req *loginRequest) Validate(v *validate.Validator) {
+	req.Email = strings.TrimSpace(req.Email)
+	v.Required("email", req.Email)
+	v.Required("password", req.Password)
+}
+
+func (req *registerRequest) Validate(v *validate.Validator) {
+	req.Email = strings.TrimSpace(req.Email)
+	v.Required("email", req.Email)
+	v.Email("email", req.Email)
+	v.MaxLength("email", req.Email, service.MaxNameLength)
+	v.Check(utf8.RuneCountInString(req.Password) >= minPasswordLength, "password",
+		fmt.Sprintf("must be at least %d characters", minPasswordLength))
+	v.MaxLength("device_id", req.DeviceID, service.MaxNameLength)
 }
 
 func Register(w http.ResponseWriter, r *http.Request) {
 	var req registerRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		sendError(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	user, err := models.CreateUser(r.Context(), req.Email, req.Password)
-	if err != nil {
-		sendError(w, "Failed to create user", http.StatusInternalServerError)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	token, err := auth.GenerateToken(user.ID, user.Email, req.DeviceID)
+	token, user, err := service.Register(r.Context(), req.Email, req.Password, req.DeviceID)
 	if err != nil {
-		sendError(w, "Failed to generate token", http.StatusInternalServerError)
+		writeServiceError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"token": token,
-	})
+	json.NewEncoder(w).Encode(authResponse{Token: token, User: user})
 }
 
 func Login(w http.ResponseWriter, r *http.Request) {
 	var req loginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		sendError(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	user, err := models.GetUserByEmail(r.Context(), req.Email)
-	if err != nil {
-		sendError(w, "Invalid credentials", http.StatusUnauthorized)
-		return
-	}
-
-	if !user.ValidatePassword(req.Password) {
-		sendError(w, "Invalid credentials", http.StatusUnauthorized)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	token, err := auth.GenerateToken(user.ID, user.Email, req.DeviceID)
+	token, user, err := service.Login(r.Context(), req.Email, req.Password, req.DeviceID, audit.ClientOf(r))
 	if err != nil {
-		sendError(w, "Failed to generate token", http.StatusInternalServerError)
+		writeServiceError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"token": token,
-	})
+	json.NewEncoder(w).Encode(authResponse{Token: token, User: user})
 }
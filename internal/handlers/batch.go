@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/apiversion"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// maxBatchRequests caps how many requests one batch may carry
+const maxBatchRequests = 20
+
+// batchHeaders are the request headers every request of a batch shares with
+// the batch itself
+var batchHeaders = []string{
+	"Authorization",
+	auth.OrganizationHeader,
+	auth.WorkspaceHeader,
+	"X-Device-ID",
+	apiversion.Header,
+	"User-Agent",
+}
+
+var (
+	batchNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	// batchReference matches {{name.field}} in a path or body, where field
+	// is a dotted path into the JSON body of an earlier response, such as
+	// {{project.id}} or {{sessions.0.id}}
+	batchReference = regexp.MustCompile(`\{\{([A-Za-z0-9_-]+)((?:\.[A-Za-z0-9_-]+)*)\}\}`)
+)
+
+type batchRequest struct {
+	Requests []batchItem `json:"requests"`
+}
+
+// batchItem is one request of a batch. Path is relative to the API root,
+// such as /auth/projects, and may carry a query.
+type batchItem struct {
+	// Name lets later requests refer to this one's response
+	Name   string          `json:"name,omitempty"`
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+func (b batchRequest) Validate(v *validate.Validator) {
+	v.Check(len(b.Requests) > 0 && len(b.Requests) <= maxBatchRequests, "requests",
+		fmt.Sprintf("must hold between 1 and %d requests", maxBatchRequests))
+
+	names := map[string]bool{}
+	for i, item := range b.Requests {
+		field := fmt.Sprintf("requests[%d]", i)
+		switch item.Method {
+		case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			v.Fail(field+".method", "must be GET, POST, PUT, PATCH or DELETE")
+		}
+		v.Check(strings.HasPrefix(item.Path, "/") && !strings.HasPrefix(item.Path, "//"), field+".path", "must start with /")
+		path, _, _ := strings.Cut(item.Path, "?")
+		v.Check(strings.TrimSuffix(path, "/") != "/batch", field+".path", "must not be a batch")
+
+		// References may only point back, to requests already run
+		for _, ref := range batchReference.FindAllStringSubmatch(item.Path+string(item.Body), -1) {
+			v.Check(names[ref[1]], field, "refers to "+ref[1]+", which is not the name of an earlier request")
+		}
+		if item.Name != "" {
+			v.Check(batchNamePattern.MatchString(item.Name), field+".name", "must be up to 64 letters, digits, - or _")
+			v.Check(!names[item.Name], field+".name", "is used by an earlier request")
+			names[item.Name] = true
+		}
+	}
+}
+
+type batchResponse struct {
+	Responses []batchResult `json:"responses"`
+}
+
+// batchResult is the response to one request of a batch. Body holds the
+// JSON body as is and any other body as a string.
+type batchResult struct {
+	Name    string            `json:"name,omitempty"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// Batch runs several API requests in one round trip, in order, and returns
+// each one's response. Every request passes through api with the batch's
+// credentials and workspace, as if sent on its own, so it is authorized,
+// validated and rate limited the same way. A request failing does not stop
+// the batch, except for later requests referring to it, which fail with
+// dependency_failed.
+func Batch(api http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req batchRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+
+		// Requests are sent to the API root the batch was sent to, so a
+		// batch to /api/v1 runs v1 routes
+		root := strings.TrimSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/batch")
+		// Drop the batch's route so the requests are routed from scratch
+		ctx := context.WithValue(r.Context(), chi.RouteCtxKey, nil)
+		requestID := middleware.GetReqID(r.Context())
+
+		results := make([]batchResult, len(req.Requests))
+		named := map[string]batchResult{}
+		for i, item := range req.Requests {
+			// Each request is logged under the batch's id and its position
+			itemID := ""
+			if requestID != "" {
+				itemID = requestID + "-" + strconv.Itoa(i+1)
+			}
+			result := runBatchItem(ctx, api, r, root, itemID, item, named)
+			result.Name = item.Name
+			results[i] = result
+			if item.Name != "" {
+				named[item.Name] = result
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(batchResponse{Responses: results})
+	}
+}
+
+// runBatchItem sends one request of a batch to api and records its response
+func runBatchItem(ctx context.Context, api http.Handler, batch *http.Request, root, requestID string, item batchItem, named map[string]batchResult) batchResult {
+	recorder := httptest.NewRecorder()
+
+	path, err := resolveBatchReferences(item.Path, named, false)
+	var body []byte
+	if err == nil {
+		body, err = resolveBatchReferences(string(item.Body), named, true)
+	}
+	if err != nil {
+		apierror.Write(recorder, batch, http.StatusFailedDependency, apierror.DependencyFailed, err.Error(), nil)
+		return recordedResult(recorder)
+	}
+
+	sub, err := http.NewRequestWithContext(ctx, item.Method, root+string(path), bytes.NewReader(body))
+	if err != nil {
+		apierror.Write(recorder, batch, http.StatusBadRequest, apierror.InvalidRequest, "Invalid path", nil)
+		return recordedResult(recorder)
+	}
+	sub.RemoteAddr = batch.RemoteAddr
+	sub.Host = batch.Host
+	for _, name := range batchHeaders {
+		if value := batch.Header.Get(name); value != "" {
+			sub.Header.Set(name, value)
+		}
+	}
+	if len(body) > 0 {
+		sub.Header.Set("Content-Type", "application/json")
+	}
+	// Bodies are embedded in the batch's JSON response
+	sub.Header.Set("Accept", "application/json")
+	if requestID != "" {
+		sub.Header.Set(middleware.RequestIDHeader, requestID)
+	}
+
+	api.ServeHTTP(recorder, sub)
+	return recordedResult(recorder)
+}
+
+func recordedResult(recorder *httptest.ResponseRecorder) batchResult {
+	result := batchResult{Status: recorder.Code, Headers: map[string]string{}}
+	for name, values := range recorder.Header() {
+		result.Headers[name] = strings.Join(values, ", ")
+	}
+
+	body := bytes.TrimSpace(recorder.Body.Bytes())
+	switch {
+	case len(body) == 0:
+	case json.Valid(body):
+		result.Body = body
+	default:
+		result.Body, _ = json.Marshal(string(body))
+	}
+	return result
+}
+
+// resolveBatchReferences replaces the references in s with values from the
+// responses of earlier requests. In JSON bodies a reference is expected
+// inside a string, so string values are escaped rather than quoted.
+func resolveBatchReferences(s string, named map[string]batchResult, inJSON bool) ([]byte, error) {
+	var err error
+	resolved := batchReference.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+		match := batchReference.FindStringSubmatch(ref)
+		result := named[match[1]]
+		if result.Status < 200 || result.Status >= 300 {
+			err = fmt.Errorf("Request %s failed", match[1])
+			return ref
+		}
+
+		var value interface{}
+		if json.Unmarshal(result.Body, &value) != nil {
+			err = fmt.Errorf("Request %s has no JSON body", match[1])
+			return ref
+		}
+		for _, key := range strings.Split(strings.TrimPrefix(match[2], "."), ".") {
+			if key == "" {
+				break
+			}
+			switch v := value.(type) {
+			case map[string]interface{}:
+				value = v[key]
+			case []interface{}:
+				index, convErr := strconv.Atoi(key)
+				if convErr != nil || index < 0 || index >= len(v) {
+					value = nil
+				} else {
+					value = v[index]
+				}
+			default:
+				value = nil
+			}
+		}
+
+		switch v := value.(type) {
+		case string:
+			if !inJSON {
+				return v
+			}
+			quoted, _ := json.Marshal(v)
+			return string(quoted[1 : len(quoted)-1])
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			return strconv.FormatBool(v)
+		}
+		err = fmt.Errorf("Response of %s has no value at %s", match[1], strings.TrimPrefix(ref[2:len(ref)-2], match[1]+"."))
+		return ref
+	})
+	return []byte(resolved), err
+}
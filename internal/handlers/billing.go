@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// ProjectBilling says whether and how time on a project is invoiced
+type ProjectBilling struct {
+	ProjectID       uuid.UUID `json:"project_id"`
+	Billable        bool      `json:"billable"`
+	ClientName      string    `json:"client_name"`
+	HourlyRateCents int64     `json:"hourly_rate_cents"`
+}
+
+func (b *ProjectBilling) Validate(v *validate.Validator) {
+	b.ClientName = strings.TrimSpace(b.ClientName)
+	if b.Billable {
+		v.Required("client_name", b.ClientName)
+	}
+	v.MaxLength("client_name", b.ClientName, service.MaxNameLength)
+	v.Check(b.HourlyRateCents >= 0, "hourly_rate_cents", "must not be negative")
+}
+
+// GetProjectBilling returns the billing settings of a project in the active
+// scope; projects never configured are not billable
+func GetProjectBilling(w http.ResponseWriter, r *http.Request) {
+	billing, ok := loadProjectBilling(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(billing)
+}
+
+// UpdateProjectBilling replaces the billing settings of a project in the
+// active scope. Billable projects need a client to invoice.
+func UpdateProjectBilling(w http.ResponseWriter, r *http.Request) {
+	billing, ok := loadProjectBilling(w, r)
+	if !ok {
+		return
+	}
+
+	projectID := billing.ProjectID
+	if !decodeJSON(w, r, billing) {
+		return
+	}
+	billing.ProjectID = projectID
+
+	_, err := db.Pool.Exec(r.Context(), `
+		INSERT INTO project_billing (project_id, billable, client_name, hourly_rate_cents)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (project_id) DO UPDATE
+		SET billable = EXCLUDED.billable, client_name = EXCLUDED.client_name,
+			hourly_rate_cents = EXCLUDED.hourly_rate_cents, updated_at = CURRENT_TIMESTAMP
+	`, billing.ProjectID, billing.Billable, billing.ClientName, billing.HourlyRateCents)
+	if err != nil {
+		apierror.Error(w, r, "Failed to update billing", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(billing)
+}
+
+// loadProjectBilling fetches the billing settings of the project named in
+// the URL. It writes the error response and returns false on failure.
+func loadProjectBilling(w http.ResponseWriter, r *http.Request) (*ProjectBilling, bool) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid project ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	billing := &ProjectBilling{ProjectID: projectID}
+	var billable *bool
+	var clientName *string
+	var rate *int64
+	err = db.Pool.QueryRow(r.Context(), `
+		SELECT b.billable, b.client_name, b.hourly_rate_cents
+		FROM projects p
+		LEFT JOIN project_billing b ON b.project_id = p.id
+		WHERE p.id = $3 AND `+service.ProjectScopeSQL(1)+` AND p.is_deleted = false
+	`, userID, service.ScopeOrganization(r.Context()), projectID).Scan(&billable, &clientName, &rate)
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.Error(w, r, "Project not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch billing", http.StatusInternalServerError)
+		return nil, false
+	}
+	if billable != nil {
+		billing.Billable, billing.ClientName, billing.HourlyRateCents = *billable, *clientName, *rate
+	}
+	return billing, true
+}
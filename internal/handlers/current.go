@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/live"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// The running timer is kept on the server for clients such as browser
+// extensions that do not sync, and so every device of the user shows the
+// same timer running: changes to it are pushed to the live streams. Stopping
+// it turns it into a session.
+
+// maxCurrentDescription keeps GET /api/auth/current responses small
+const maxCurrentDescription = 256
+
+// RunningTimer is the user's running timer. Its ID becomes the ID of the
+// session it is saved as.
+type RunningTimer struct {
+	ID           uuid.UUID  `json:"id"`
+	ProjectID    *uuid.UUID `json:"project_id,omitempty"`
+	ProjectName  string     `json:"project_name,omitempty"`
+	ProjectColor string     `json:"project_color,omitempty"`
+	Description  string     `json:"description"`
+	StartTime    time.Time  `json:"start_time"`
+	Elapsed      int64      `json:"elapsed_seconds"`
+	// DeviceID is the device the timer was started on
+	DeviceID string `json:"device_id,omitempty"`
+}
+
+// maxTimerClockSkew is how far in the future a started timer may begin,
+// for devices whose clock runs ahead
+const maxTimerClockSkew = time.Minute
+
+type startTimerRequest struct {
+	ProjectID   *uuid.UUID `json:"project_id"`
+	Description string     `json:"description"`
+	// StartTime is when the timer started on the device, now by default
+	StartTime *time.Time `json:"start_time"`
+}
+
+func (req *startTimerRequest) Validate(v *validate.Validator) {
+	req.Description = strings.TrimSpace(req.Description)
+	v.MaxLength("description", req.Description, service.MaxDescriptionLength)
+	if req.StartTime != nil {
+		v.Check(req.StartTime.Before(time.Now().Add(maxTimerClockSkew)), "start_time", "must not be in the future")
+	}
+}
+
+type quickStartRequest struct {
+	Description string `json:"description"`
+}
+
+func (req *quickStartRequest) Validate(v *validate.Validator) {
+	req.Description = strings.TrimSpace(req.Description)
+	v.Required("description", req.Description)
+	v.MaxLength("description", req.Description, service.MaxDescriptionLength)
+}
+
+// GetCurrent returns the running timer, or null when none is running
+func GetCurrent(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	timer, err := runningTimer(r.Context(), db.Pool, userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch running timer", http.StatusInternalServerError)
+		return
+	}
+	if timer != nil && len(timer.Description) > maxCurrentDescription {
+		cut := maxCurrentDescription
+		for cut > 0 && !utf8.RuneStart(timer.Description[cut]) {
+			cut--
+		}
+		timer.Description = timer.Description[:cut]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(timer)
+}
+
+// QuickStart starts a timer with just a description, stopping and saving a
+// running one first. The project is the one in the active scope whose name
+// appears in the description, the longest if several do, or else the
+// project of the latest session with the same description.
+func QuickStart(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req quickStartRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	mode, err := models.GetStorageMode(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch storage mode", http.StatusInternalServerError)
+		return
+	}
+	if mode == models.StorageModeEncrypted {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.StorageModeMismatch, "Quick start is not available in encrypted storage mode", nil)
+		return
+	}
+
+	projectID, err := matchProject(r.Context(), userID, req.Description)
+	if err != nil {
+		apierror.Error(w, r, "Failed to match project", http.StatusInternalServerError)
+		return
+	}
+	startTimer(w, r, userID, projectID, req.Description, nil)
+}
+
+// StartCurrent starts a timer on a project with a description, stopping and
+// saving a running one first, and shows it running on the user's other
+// devices
+func StartCurrent(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req startTimerRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	mode, err := models.GetStorageMode(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch storage mode", http.StatusInternalServerError)
+		return
+	}
+	if mode == models.StorageModeEncrypted {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.StorageModeMismatch, "The running timer is not available in encrypted storage mode", nil)
+		return
+	}
+
+	startTimer(w, r, userID, req.ProjectID, req.Description, req.StartTime)
+}
+
+// startTimer starts the user's timer, from startTime or else now, after
+// saving a running one, and writes the response
+func startTimer(w http.ResponseWriter, r *http.Request, userID uuid.UUID, projectID *uuid.UUID, description string, startTime *time.Time) {
+	if !checkSessionProject(w, r, userID, service.Session{ProjectID: projectID}) {
+		return
+	}
+
+	tx, err := db.Pool.Begin(r.Context())
+	if err != nil {
+		apierror.Error(w, r, "Failed to start timer", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	_, ok := stopRunningTimer(w, r, tx, userID)
+	if !ok {
+		return
+	}
+	timer, err := insertRunningTimer(r.Context(), tx, userID, projectID, description, startTime, auth.GetDeviceIDFromContext(r.Context()))
+	if err != nil {
+		apierror.Error(w, r, "Failed to start timer", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		apierror.Error(w, r, "Failed to start timer", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(timer)
+}
+
+// insertRunningTimer starts the user's timer in tx, from startTime or else
+// now, and returns it, announcing it to the live streams of the user and
+// their organizations. The user must have no running timer.
+func insertRunningTimer(ctx context.Context, tx pgx.Tx, userID uuid.UUID, projectID *uuid.UUID, description string, startTime *time.Time, deviceID string) (*RunningTimer, error) {
+	sealed, err := fieldcrypt.Seal(fieldcrypt.SessionDescription, description)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO running_timers (user_id, id, project_id, description, start_time, device_id)
+		VALUES ($1, $2, $3, $4, COALESCE($5, CURRENT_TIMESTAMP), $6)
+	`, userID, uuid.New(), projectID, sealed, startTime, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := live.Notify(ctx, tx, userID, live.TopicTimer); err != nil {
+		return nil, err
+	}
+	if err := live.NotifyOrganizations(ctx, tx, userID, live.TopicPresence); err != nil {
+		return nil, err
+	}
+	return runningTimer(ctx, tx, userID)
+}
+
+// StopCurrent stops the running timer and returns the session it was saved
+// as
+func StopCurrent(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tx, err := db.Pool.Begin(r.Context())
+	if err != nil {
+		apierror.Error(w, r, "Failed to stop timer", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	session, ok := stopRunningTimer(w, r, tx, userID)
+	if !ok {
+		return
+	}
+	if session == nil {
+		apierror.Error(w, r, "No timer is running", http.StatusNotFound)
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		apierror.Error(w, r, "Failed to stop timer", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// runningTimer returns the user's running timer, or nil
+func runningTimer(ctx context.Context, q queryRower, userID uuid.UUID) (*RunningTimer, error) {
+	var t RunningTimer
+	var name, color *string
+	err := q.QueryRow(ctx, `
+		SELECT t.id, t.project_id, p.name, p.color, t.description, t.start_time,
+			GREATEST(EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - t.start_time))::bigint, 0), t.device_id
+		FROM running_timers t
+		LEFT JOIN projects p ON p.id = t.project_id AND p.key_id = ''
+		WHERE t.user_id = $1
+	`, userID).Scan(&t.ID, &t.ProjectID, &name, &color, &t.Description, &t.StartTime, &t.Elapsed, &t.DeviceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.Description, err = fieldcrypt.Open(fieldcrypt.SessionDescription, t.Description); err != nil {
+		return nil, err
+	}
+	if name != nil {
+		if t.ProjectName, err = fieldcrypt.Open(fieldcrypt.ProjectName, *name); err != nil {
+			return nil, err
+		}
+		t.ProjectColor = *color
+	}
+	return &t, nil
+}
+
+// stopRunningTimer saves the user's running timer, if any, as a session
+// ending now and returns it; see service.StopRunningTimer. It writes the
+// error response and returns false on failure.
+func stopRunningTimer(w http.ResponseWriter, r *http.Request, tx pgx.Tx, userID uuid.UUID) (*service.Session, bool) {
+	session, err := service.StopRunningTimer(r.Context(), tx, userID, nil)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return nil, false
+	}
+	return session, true
+}
+
+// matchProject finds the project a quick-start description is for, or nil.
+// Names are compared here rather than in the query, as they may be sealed
+// by fieldcrypt; sealed session descriptions never match the fallback.
+func matchProject(ctx context.Context, userID uuid.UUID, description string) (*uuid.UUID, error) {
+	if description == "" {
+		return nil, nil
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, name FROM projects
+		WHERE `+service.ProjectScopeSQL(1)+` AND is_deleted = false AND key_id = '' AND name <> ''
+		ORDER BY created_at
+	`, userID, service.ScopeOrganization(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	// The longest name contained in the description wins, then the oldest
+	lowered := strings.ToLower(description)
+	var match *uuid.UUID
+	matchLength := 0
+	for rows.Next() {
+		var id uuid.UUID
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		if name, err = fieldcrypt.Open(fieldcrypt.ProjectName, name); err != nil {
+			return nil, err
+		}
+		if len(name) > matchLength && strings.Contains(lowered, strings.ToLower(name)) {
+			match, matchLength = &id, len(name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if match != nil {
+		return match, nil
+	}
+
+	var latest *uuid.UUID
+	err = db.Pool.QueryRow(ctx, `
+		SELECT project_id FROM timer_sessions
+		WHERE `+service.SessionScopeSQL(1)+` AND is_deleted = false AND project_id IS NOT NULL AND description = $3
+		ORDER BY start_time DESC
+		LIMIT 1
+	`, userID, service.ScopeOrganization(ctx), description).Scan(&latest)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return latest, err
+}
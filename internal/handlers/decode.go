@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/i18n"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// decodeJSON decodes the request body into dst and validates it. It writes
+// the error response and returns false on failure.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			apierror.Error(w, r, "Request body too large", http.StatusRequestEntityTooLarge)
+			return false
+		}
+		apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidBody, err.Error(), nil)
+		return false
+	}
+	return checkValid(w, r, dst)
+}
+
+// checkValid validates value, writing the failing fields on failure
+func checkValid(w http.ResponseWriter, r *http.Request, value interface{}) bool {
+	err := validate.Struct(value)
+	if err == nil {
+		return true
+	}
+	writeValidationError(w, r, err)
+	return false
+}
+
+// writeValidationError writes the field errors of err as a validation_failed
+// response, their messages in the request's locale
+func writeValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	var fields validate.Errors
+	if !errors.As(err, &fields) {
+		apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	locale := i18n.Locale(r.Context())
+	translated := make(validate.Errors, len(fields))
+	for i, field := range fields {
+		translated[i] = validate.FieldError{Field: field.Field, Message: i18n.T(locale, field.Message)}
+	}
+	apierror.Write(w, r, http.StatusBadRequest, apierror.ValidationFailed, translated.Error(),
+		map[string]interface{}{"fields": translated})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+)
+
+// deviceList is how devices can be sorted, by default most recently synced
+// first
+var deviceList = listquery.List[DeviceSyncStatus]{
+	Sorts: map[string]listquery.Column[DeviceSyncStatus]{
+		"last_sync_time": {SQL: "d.last_sync_time", Key: func(d DeviceSyncStatus) time.Time { return d.LastSyncTime }},
+	},
+	Default: listquery.Sort{Field: "last_sync_time", Desc: true},
+	ID:      "d.id",
+	RowID:   func(d DeviceSyncStatus) uuid.UUID { return d.id },
+}
+
+// ListDevices returns a page of the user's devices with their sync status
+func ListDevices(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q, ok := listParams(w, r, deviceList)
+	if !ok {
+		return
+	}
+
+	where, orderBy := deviceList.SQL(q, 2)
+	query := deviceStatusQuery + `
+		WHERE d.user_id = $1 AND ` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT $4
+	`
+
+	args := append([]interface{}{userID}, q.Args()...)
+	rows, err := db.ReadPool(r.Context()).Query(r.Context(), query, append(args, q.Page.Fetch())...)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch devices", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var devices []DeviceSyncStatus
+	for rows.Next() {
+		device, err := scanDeviceStatus(rows)
+		if err != nil {
+			apierror.Error(w, r, "Failed to scan device", http.StatusInternalServerError)
+			return
+		}
+		devices = append(devices, device)
+	}
+
+	writePage(w, r, deviceList.Page(devices, q), q)
+}
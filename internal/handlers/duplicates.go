@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// duplicateSearchDays is how far back duplicates are searched for when no
+// period is given
+const duplicateSearchDays = 90
+
+type mergeSessionsRequest struct {
+	KeepID     uuid.UUID   `json:"keep_id"`
+	SessionIDs []uuid.UUID `json:"session_ids"`
+}
+
+func (req *mergeSessionsRequest) Validate(v *validate.Validator) {
+	v.UUID("keep_id", req.KeepID)
+	v.Check(len(req.SessionIDs) > 0, "session_ids", "is required")
+	if len(req.SessionIDs) > service.MaxMergeSessions {
+		v.Fail("session_ids", "must list at most %d sessions", service.MaxMergeSessions)
+	}
+}
+
+// ListDuplicateSessions groups the user's near-identical sessions in the
+// active scope, such as copies a sync bug created. The period is given by
+// ?start= and ?end= as for reports, and is the last 90 days without them.
+func ListDuplicateSessions(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	end := time.Now()
+	start := end.AddDate(0, 0, -duplicateSearchDays)
+	if query.Get("start") != "" || query.Get("end") != "" {
+		var ok bool
+		start, end, ok = reportPeriod(w, r)
+		if !ok {
+			return
+		}
+	}
+
+	groups, err := service.FindDuplicateSessions(r.Context(), userID, start, end)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+// MergeSessions merges duplicate sessions into the one the user keeps,
+// deleting the others
+func MergeSessions(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req mergeSessionsRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	result, err := service.MergeSessions(r.Context(), userID, req.KeepID, req.SessionIDs)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/i18n"
+	"github.com/pacerclub/zebra-backend/internal/integrations"
+	"github.com/pacerclub/zebra-backend/internal/logging"
+	"github.com/pacerclub/zebra-backend/internal/mail"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/outbox"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/webhooks"
+)
+
+const maxInboundEmailBytes = 10 << 20 // 10 MB
+
+// emailDevice marks sessions logged by email
+const emailDevice = "email"
+
+type emailStatus struct {
+	*integrations.Account
+	Address string `json:"address"`
+}
+
+// ConnectEmail creates the user's private inbound address, or replaces it
+// so mail to the old one is rejected
+func ConnectEmail(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if integrations.EmailDomain() == "" {
+		apierror.Error(w, r, "Inbound email is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	mode, err := models.GetStorageMode(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch storage mode", http.StatusInternalServerError)
+		return
+	}
+	if mode == models.StorageModeEncrypted {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.StorageModeMismatch, "Inbound email is not available in encrypted storage mode", nil)
+		return
+	}
+
+	token, err := integrations.NewEmailToken()
+	if err != nil {
+		apierror.Error(w, r, "Failed to create address", http.StatusInternalServerError)
+		return
+	}
+	account, err := integrations.SaveAccount(r.Context(), userID, integrations.Email, &integrations.Token{AccessToken: token}, struct{}{})
+	if err != nil {
+		apierror.Error(w, r, "Failed to save account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(emailStatus{Account: account, Address: integrations.EmailAddress(account.AccessToken)})
+}
+
+func GetEmail(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	account, err := integrations.GetAccount(r.Context(), userID, integrations.Email)
+	if errors.Is(err, integrations.ErrAccountNotFound) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotConnected, "Inbound email is not set up", nil)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch integration", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(emailStatus{Account: account, Address: integrations.EmailAddress(account.AccessToken)})
+}
+
+// DisconnectEmail removes the inbound address. Sessions logged by email are
+// kept.
+func DisconnectEmail(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err := integrations.DeleteAccount(r.Context(), userID, integrations.Email)
+	if errors.Is(err, integrations.ErrAccountNotFound) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotConnected, "Inbound email is not set up", nil)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to remove address", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// InboundEmail is the Mailgun inbound route webhook. Every line of the
+// message is logged as a personal session, back to back and ending when
+// the message arrived; if any line cannot be logged none are, and the
+// problems are mailed to the user's account address. Mail to unknown
+// addresses is refused with 406 so Mailgun does not retry it.
+func InboundEmail(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxInboundEmailBytes)
+	if err := r.ParseMultipartForm(maxInboundEmailBytes); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			apierror.Error(w, r, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !integrations.VerifyMailgun(r.FormValue("timestamp"), r.FormValue("token"), r.FormValue("signature")) {
+		apierror.Error(w, r, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	account, err := integrations.AccountByEmailRecipient(r.Context(), r.FormValue("recipient"))
+	if errors.Is(err, integrations.ErrAccountNotFound) {
+		apierror.Error(w, r, "Unknown recipient", http.StatusNotAcceptable)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch recipient", http.StatusInternalServerError)
+		return
+	}
+
+	subject := r.FormValue("subject")
+	text := r.FormValue("stripped-text")
+	if strings.TrimSpace(text) == "" {
+		text = r.FormValue("body-plain")
+	}
+	if strings.TrimSpace(text) == "" {
+		text = subject
+	}
+
+	_, problems, err := logEmailSessions(r, account.UserID, text)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Inbound email failed", "user_id", account.UserID, "error", err)
+		apierror.Error(w, r, "Failed to log sessions", http.StatusInternalServerError)
+		return
+	}
+	if len(problems) > 0 {
+		replyEmailProblems(r, account.UserID, subject, problems)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// logEmailSessions creates the sessions described by text, or returns why
+// it could not
+func logEmailSessions(r *http.Request, userID uuid.UUID, text string) ([]service.Session, []string, error) {
+	entries, problems := integrations.ParseEmailEntries(text)
+	if len(entries) == 0 && len(problems) == 0 {
+		problems = append(problems, "no lines like \"2h project-x writing docs\" were found")
+	}
+
+	mode, err := models.GetStorageMode(r.Context(), userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mode == models.StorageModeEncrypted {
+		problems = append(problems, "sessions cannot be logged by email in encrypted storage mode")
+	}
+	if len(problems) > 0 {
+		return nil, problems, nil
+	}
+
+	projects := make(map[string]uuid.UUID)
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT id, name FROM projects
+		WHERE user_id = $1 AND organization_id IS NULL AND is_deleted = false
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		if name, err = fieldcrypt.Open(fieldcrypt.ProjectName, name); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		// The oldest project wins when names collide
+		projects[integrations.EmailProjectKey(name)] = id
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	end := time.Now().UTC().Truncate(time.Second)
+	for i := len(entries) - 1; i >= 0; i-- {
+		end = end.Add(-entries[i].Duration)
+	}
+	sessions := make([]service.Session, 0, len(entries))
+	for _, entry := range entries {
+		projectID, ok := projects[integrations.EmailProjectKey(entry.Project)]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("line %d: no project named %q", entry.Line, entry.Project))
+			continue
+		}
+		sessions = append(sessions, service.Session{
+			ID:          uuid.New(),
+			UserID:      userID,
+			ProjectID:   &projectID,
+			StartTime:   end,
+			EndTime:     end.Add(entry.Duration),
+			Description: entry.Description,
+			DeviceID:    emailDevice,
+		})
+		end = end.Add(entry.Duration)
+	}
+	if len(problems) > 0 {
+		return nil, problems, nil
+	}
+
+	tx, err := db.Pool.Begin(r.Context())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(r.Context())
+	for i := range sessions {
+		session := &sessions[i]
+		description, err := fieldcrypt.Seal(fieldcrypt.SessionDescription, session.Description)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = tx.QueryRow(r.Context(), `
+			INSERT INTO timer_sessions (id, user_id, project_id, start_time, end_time, description, device_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING is_deleted, created_at, updated_at
+		`, session.ID, session.UserID, session.ProjectID, session.StartTime, session.EndTime,
+			description, session.DeviceID,
+		).Scan(&session.IsDeleted, &session.CreatedAt, &session.UpdatedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := outbox.Add(r.Context(), tx, userID, webhooks.EventSessionCreated, *session); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		return nil, nil, err
+	}
+	return sessions, nil, nil
+}
+
+// replyEmailProblems mails the user why a message logged nothing. The
+// reply goes to the account's address rather than the sender, which may be
+// forged.
+func replyEmailProblems(r *http.Request, userID uuid.UUID, subject string, problems []string) {
+	if !mail.Configured() {
+		return
+	}
+	user, err := models.GetUserByID(r.Context(), userID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to fetch user for email reply", "user_id", userID, "error", err)
+		return
+	}
+
+	locale := i18n.Of(user.Locale)
+	translated := make([]string, len(problems))
+	for i, problem := range problems {
+		translated[i] = i18n.T(locale, problem)
+	}
+	msg, err := mail.Render(mail.TemplateEmailProblems, locale, user.Email, map[string]interface{}{
+		"Subject":  subject,
+		"Problems": translated,
+	})
+	if err == nil {
+		err = mail.Enqueue(r.Context(), msg)
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to send email reply", "user_id", userID, "error", err)
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+type storageModeRequest struct {
+	StorageMode string `json:"storage_mode"`
+}
+
+func (req *storageModeRequest) Validate(v *validate.Validator) {
+	v.Check(req.StorageMode == models.StorageModeStandard || req.StorageMode == models.StorageModeEncrypted,
+		"storage_mode", "must be standard or encrypted")
+}
+
+func GetStorageMode(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	mode, err := models.GetStorageMode(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch storage mode", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(storageModeRequest{StorageMode: mode})
+}
+
+// UpdateStorageMode switches between standard and encrypted storage. Existing
+// records are not touched; clients re-upload them encrypted through sync.
+func UpdateStorageMode(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req storageModeRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := models.SetStorageMode(r.Context(), userID, req.StorageMode); err != nil {
+		apierror.Error(w, r, "Failed to update storage mode", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(req)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/billing"
+	"github.com/pacerclub/zebra-backend/internal/errorreport"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// serviceStatus maps a service error to an HTTP status
+func serviceStatus(err error) int {
+	// A reused idempotency key answers as the idempotency middleware does
+	if service.ErrorReason(err) == apierror.IdempotencyKeyReused {
+		return http.StatusUnprocessableEntity
+	}
+	switch service.ErrorCode(err) {
+	case service.InvalidArgument:
+		return http.StatusBadRequest
+	case service.Unauthenticated:
+		return http.StatusUnauthorized
+	case service.PermissionDenied:
+		return http.StatusForbidden
+	case service.NotFound:
+		return http.StatusNotFound
+	case service.TooLarge:
+		return http.StatusRequestEntityTooLarge
+	case service.Unimplemented:
+		return http.StatusNotImplemented
+	case service.Conflict:
+		return http.StatusConflict
+	case service.PaymentRequired:
+		return http.StatusPaymentRequired
+	}
+	return http.StatusInternalServerError
+}
+
+// writeServiceError writes a service error, with its specific code if it
+// has one
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	var fields validate.Errors
+	if errors.As(err, &fields) {
+		writeValidationError(w, r, fields)
+		return
+	}
+	status := serviceStatus(err)
+	code := service.ErrorReason(err)
+	if code == "" {
+		code = apierror.CodeForStatus(status)
+	}
+	if status >= http.StatusInternalServerError {
+		errorreport.CaptureError(r, err)
+	}
+	var details map[string]interface{}
+	if current := service.ErrorCurrent(err); current != nil {
+		details = map[string]interface{}{"current": current}
+	}
+	var limit *billing.LimitError
+	if errors.As(err, &limit) {
+		details = limitDetails(limit)
+	}
+	apierror.Write(w, r, status, code, service.ErrorMessage(err), details)
+}
+
+// writeLimitError writes a plan limit reached: 402 when the user can
+// upgrade, 403 when only an organization's owners can
+func writeLimitError(w http.ResponseWriter, r *http.Request, limit *billing.LimitError) {
+	status, code := http.StatusPaymentRequired, apierror.UpgradeRequired
+	if !limit.CanUpgrade {
+		status, code = http.StatusForbidden, apierror.OwnerUpgradeRequired
+	}
+	apierror.Write(w, r, status, code, limit.Message(), limitDetails(limit))
+}
+
+func limitDetails(limit *billing.LimitError) map[string]interface{} {
+	return map[string]interface{}{"limit": limit.Limit, "max": limit.Max, "plan": limit.Plan}
+}
+
+// checkLimit writes the error of a plan limit check that failed, returning
+// false, and returns true if it passed
+func checkLimit(w http.ResponseWriter, r *http.Request, err error) bool {
+	if err == nil {
+		return true
+	}
+	var limit *billing.LimitError
+	if errors.As(err, &limit) {
+		writeLimitError(w, r, limit)
+		return false
+	}
+	apierror.Error(w, r, "Failed to check plan limits", http.StatusInternalServerError)
+	return false
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/audit"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/logging"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// impersonationRoute is how issuing impersonation tokens is recorded in the
+// audit log
+const impersonationRoute = "/admin/impersonate"
+
+type impersonateRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+	// Reason is recorded in the audit log, such as the support ticket
+	Reason string `json:"reason"`
+}
+
+func (req *impersonateRequest) Validate(v *validate.Validator) {
+	req.Reason = strings.TrimSpace(req.Reason)
+	v.UUID("user_id", req.UserID)
+	v.Required("reason", req.Reason)
+	v.MaxLength("reason", req.Reason, 500)
+}
+
+// ImpersonationResponse is a token acting as a user on behalf of an admin
+type ImpersonationResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	// DeviceID is the device the token syncs as
+	DeviceID string `json:"device_id"`
+}
+
+// AdminImpersonate issues a short-lived token acting as a user, for support
+// to reproduce problems the user reports without their password. Issuing
+// the token and every request made with it are recorded in the audit log
+// with the admin. Other admins and deactivated accounts cannot be
+// impersonated.
+func AdminImpersonate(w http.ResponseWriter, r *http.Request) {
+	adminID := auth.GetUserIDFromContext(r.Context())
+
+	var req impersonateRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.UserID == adminID {
+		apierror.Error(w, r, "Cannot impersonate yourself", http.StatusBadRequest)
+		return
+	}
+
+	user, err := models.GetUserByID(r.Context(), req.UserID)
+	if err != nil {
+		apierror.Error(w, r, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.DeactivatedAt != nil {
+		apierror.Write(w, r, http.StatusConflict, apierror.AccountDeactivated, "Account is deactivated", nil)
+		return
+	}
+	isAdmin, err := auth.IsAdmin(r.Context(), user.ID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to check admin access", http.StatusInternalServerError)
+		return
+	}
+	if isAdmin {
+		apierror.Error(w, r, "Admins cannot be impersonated", http.StatusForbidden)
+		return
+	}
+
+	token, expiresAt, err := auth.GenerateImpersonationToken(user.ID, user.Email, adminID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	client := audit.ClientOf(r)
+	audit.Record(r.Context(), audit.Entry{
+		UserID:         user.ID,
+		DeviceID:       auth.GetDeviceIDFromContext(r.Context()),
+		IP:             client.IP,
+		Location:       client.Location,
+		UserAgent:      client.UserAgent,
+		RequestID:      middleware.GetReqID(r.Context()),
+		Method:         r.Method,
+		Route:          impersonationRoute,
+		EntityType:     "impersonation",
+		EntityID:       user.ID.String(),
+		Status:         http.StatusCreated,
+		ImpersonatorID: &adminID,
+		Reason:         req.Reason,
+	})
+	logging.FromContext(r.Context()).Info("Impersonation token issued", "target_user_id", user.ID, "reason", req.Reason)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ImpersonationResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		UserID:    user.ID,
+		Email:     user.Email,
+		DeviceID:  auth.ImpersonationDeviceID(adminID),
+	})
+}
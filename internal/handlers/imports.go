@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/imports"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// Imports read time entries from other tools into the user's personal
+// projects. They run in the background; clients poll the returned job.
+
+const maxImportBytes = 20 << 20 // 20 MB
+
+// togglAPIWindow is the default range fetched through the Toggl API
+const togglAPIWindow = 90 * 24 * time.Hour
+
+type togglImportRequest struct {
+	APIToken  string     `json:"api_token"`
+	StartDate *time.Time `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+}
+
+func (req *togglImportRequest) Validate(v *validate.Validator) {
+	v.Required("api_token", req.APIToken)
+	if req.StartDate != nil && req.EndDate != nil {
+		v.Check(req.StartDate.Before(*req.EndDate), "start_date", "must be before end_date")
+	}
+}
+
+// ImportToggl starts an import from Toggl Track. A JSON body with an
+// api_token reads entries through the Toggl API; any other body is read as a
+// detailed report CSV export, in the time zone given by ?timezone=.
+func ImportToggl(w http.ResponseWriter, r *http.Request) {
+	userID, ok := importUser(w, r)
+	if !ok {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var req togglImportRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		until := time.Now()
+		if req.EndDate != nil {
+			until = *req.EndDate
+		}
+		since := until.Add(-togglAPIWindow)
+		if req.StartDate != nil {
+			since = *req.StartDate
+		}
+		if !since.Before(until) {
+			apierror.Error(w, r, "start_date must be before end_date", http.StatusBadRequest)
+			return
+		}
+
+		startImport(w, r, userID, imports.SourceToggl, nil, func(ctx context.Context) ([]imports.Entry, error) {
+			return imports.FetchToggl(ctx, req.APIToken, since, until)
+		})
+		return
+	}
+
+	loc, ok := importLocation(w, r)
+	if !ok {
+		return
+	}
+	entries, lineErrors, err := imports.ParseTogglCSV(r.Body, loc)
+	if err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidBody, err.Error(), nil)
+		return
+	}
+	startImport(w, r, userID, imports.SourceToggl, lineErrors, func(context.Context) ([]imports.Entry, error) {
+		return entries, nil
+	})
+}
+
+type csvValidationResult struct {
+	Valid  int                 `json:"valid"`
+	Errors []imports.LineError `json:"errors"`
+}
+
+// ImportCSV starts an import from any CSV file. The multipart form carries
+// the file as "file" and the column mapping as JSON in "mapping". With
+// ?dry_run=true the rows are only validated and the per-line errors returned.
+func ImportCSV(w http.ResponseWriter, r *http.Request) {
+	userID, ok := importUser(w, r)
+	if !ok {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	if err := r.ParseMultipartForm(maxImportBytes); err != nil {
+		apierror.Error(w, r, "Expected a multipart form with file and mapping", http.StatusBadRequest)
+		return
+	}
+	var mapping imports.ColumnMapping
+	if err := json.Unmarshal([]byte(r.FormValue("mapping")), &mapping); err != nil {
+		apierror.Error(w, r, "Invalid mapping: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		apierror.Error(w, r, "file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	entries, lineErrors, err := imports.ParseCSV(file, mapping)
+	if err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidBody, err.Error(), nil)
+		return
+	}
+
+	if r.URL.Query().Get("dry_run") == "true" {
+		if lineErrors == nil {
+			lineErrors = []imports.LineError{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(csvValidationResult{Valid: len(entries), Errors: lineErrors})
+		return
+	}
+
+	startImport(w, r, userID, imports.SourceCSV, lineErrors, func(context.Context) ([]imports.Entry, error) {
+		return entries, nil
+	})
+}
+
+func ListImportJobs(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	jobs, err := imports.ListJobs(r.Context(), userID, 50)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch imports", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// GetImportJob reports an import's status and progress
+func GetImportJob(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	jobID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid import ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := imports.GetJob(r.Context(), userID, jobID)
+	if errors.Is(err, imports.ErrJobNotFound) {
+		apierror.Error(w, r, "Import not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch import", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// importUser returns the user of an import request. Imported data is stored
+// in plaintext, so users in encrypted storage mode cannot import. It writes
+// the error response and returns false on failure.
+func importUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+
+	mode, err := models.GetStorageMode(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch storage mode", http.StatusInternalServerError)
+		return uuid.Nil, false
+	}
+	if mode == models.StorageModeEncrypted {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.StorageModeMismatch, "Imports are not available in encrypted storage mode", nil)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// importLocation returns the time zone named by ?timezone=, UTC by default.
+// It writes the error response and returns false on failure.
+func importLocation(w http.ResponseWriter, r *http.Request) (*time.Location, bool) {
+	name := r.URL.Query().Get("timezone")
+	if name == "" {
+		return time.UTC, true
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		apierror.Error(w, r, "Invalid timezone", http.StatusBadRequest)
+		return nil, false
+	}
+	return loc, true
+}
+
+// startImport creates the job, records errors found while parsing and runs
+// load in the background. It responds with the queued job.
+func startImport(w http.ResponseWriter, r *http.Request, userID uuid.UUID, source string, lineErrors []imports.LineError, load func(context.Context) ([]imports.Entry, error)) {
+	job, err := imports.CreateJob(r.Context(), userID, source)
+	if err != nil {
+		apierror.Error(w, r, "Failed to start import", http.StatusInternalServerError)
+		return
+	}
+	if err := imports.RecordLineErrors(r.Context(), job.ID, lineErrors); err != nil {
+		apierror.Error(w, r, "Failed to start import", http.StatusInternalServerError)
+		return
+	}
+	if len(lineErrors) > 0 {
+		job.Errors = append(job.Errors, lineErrors...)
+	}
+
+	imports.Start(job, load)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
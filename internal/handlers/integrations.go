@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/integrations"
+	"github.com/pacerclub/zebra-backend/internal/outbox"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/webhooks"
+)
+
+type googleCalendarStatus struct {
+	*integrations.Account
+	Settings integrations.GoogleCalendarSettings `json:"settings"`
+}
+
+// SuggestedSession is a calendar event offered to the user as a session
+type SuggestedSession struct {
+	ID        uuid.UUID  `json:"id"`
+	Title     string     `json:"title"`
+	StartTime time.Time  `json:"start_time"`
+	EndTime   time.Time  `json:"end_time"`
+	Status    string     `json:"status"`
+	SessionID *uuid.UUID `json:"session_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type confirmSuggestionRequest struct {
+	ProjectID            *uuid.UUID `json:"project_id"`
+	Description          *string    `json:"description"`
+	EncryptedDescription []byte     `json:"encrypted_description"`
+	KeyID                string     `json:"key_id"`
+	DeviceID             string     `json:"device_id"`
+}
+
+// ConnectGoogleCalendar starts the OAuth flow and returns the URL to send
+// the user to
+func ConnectGoogleCalendar(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	cfg := integrations.GoogleCalendarOAuth()
+	if !cfg.Configured() {
+		apierror.Error(w, r, "Google Calendar integration is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	state, err := integrations.CreateState(r.Context(), userID, integrations.GoogleCalendar)
+	if err != nil {
+		apierror.Error(w, r, "Failed to start authorization", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"auth_url": cfg.AuthCodeURL(state),
+	})
+}
+
+// GoogleCalendarCallback finishes the OAuth flow. Google redirects the
+// browser here, so the user is identified by the state rather than a token.
+// With GOOGLE_CONNECTED_REDIRECT_URL set the browser is sent on there.
+func GoogleCalendarCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if message := query.Get("error"); message != "" {
+		apierror.Error(w, r, "Authorization failed: "+message, http.StatusBadRequest)
+		return
+	}
+
+	userID, err := integrations.ConsumeState(r.Context(), query.Get("state"), integrations.GoogleCalendar)
+	if errors.Is(err, integrations.ErrInvalidState) {
+		apierror.Error(w, r, "Invalid or expired authorization", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to verify authorization", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := integrations.GoogleCalendarOAuth().Exchange(r.Context(), query.Get("code"))
+	if err != nil {
+		apierror.Error(w, r, "Failed to obtain access token", http.StatusBadGateway)
+		return
+	}
+
+	_, err = integrations.SaveAccount(r.Context(), userID, integrations.GoogleCalendar, token, integrations.DefaultGoogleCalendarSettings())
+	if err != nil {
+		apierror.Error(w, r, "Failed to save account", http.StatusInternalServerError)
+		return
+	}
+
+	if redirect := os.Getenv("GOOGLE_CONNECTED_REDIRECT_URL"); redirect != "" {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("Google Calendar connected. You can close this window.\n"))
+}
+
+func GetGoogleCalendar(w http.ResponseWriter, r *http.Request) {
+	status, ok := loadGoogleCalendar(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// UpdateGoogleCalendar changes which calendar is used and whether sessions
+// are pushed and events imported. Omitted fields keep their value.
+func UpdateGoogleCalendar(w http.ResponseWriter, r *http.Request) {
+	status, ok := loadGoogleCalendar(w, r)
+	if !ok {
+		return
+	}
+
+	if !decodeJSON(w, r, &status.Settings) {
+		return
+	}
+
+	if err := integrations.UpdateSettings(r.Context(), status.ID, status.Settings); err != nil {
+		apierror.Error(w, r, "Failed to update integration", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// DisconnectGoogleCalendar forgets the account's tokens. Events already
+// pushed stay in the calendar.
+func DisconnectGoogleCalendar(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err := integrations.DeleteAccount(r.Context(), userID, integrations.GoogleCalendar)
+	if errors.Is(err, integrations.ErrAccountNotFound) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotConnected, "Google Calendar is not connected", nil)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to disconnect Google Calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSuggestedSessions returns imported events waiting to be confirmed or
+// dismissed
+func ListSuggestedSessions(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT id, title, start_time, end_time, status, session_id, created_at
+		FROM suggested_sessions
+		WHERE user_id = $1 AND status = 'pending'
+		ORDER BY start_time DESC
+	`, userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch suggestions", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	suggestions := []SuggestedSession{}
+	for rows.Next() {
+		var s SuggestedSession
+		if err := rows.Scan(&s.ID, &s.Title, &s.StartTime, &s.EndTime, &s.Status, &s.SessionID, &s.CreatedAt); err != nil {
+			apierror.Error(w, r, "Failed to scan suggestion", http.StatusInternalServerError)
+			return
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// ConfirmSuggestedSession turns a suggestion into a session in the active
+// scope. The event title becomes the description unless one is given.
+func ConfirmSuggestedSession(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	suggestion, ok := loadSuggestion(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req confirmSuggestionRequest
+	if r.ContentLength != 0 {
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+	}
+
+	session := service.Session{
+		ID:                   uuid.New(),
+		UserID:               userID,
+		ProjectID:            req.ProjectID,
+		StartTime:            suggestion.StartTime,
+		EndTime:              suggestion.EndTime,
+		Description:          suggestion.Title,
+		EncryptedDescription: req.EncryptedDescription,
+		KeyID:                req.KeyID,
+		DeviceID:             req.DeviceID,
+	}
+	if req.Description != nil {
+		session.Description = *req.Description
+	}
+	if session.KeyID != "" {
+		session.Description = ""
+	}
+
+	if !checkSessionEncryption(w, r, userID, session) {
+		return
+	}
+	if !checkSessionProject(w, r, userID, session) {
+		return
+	}
+	if !checkSessionLock(w, r, userID, uuid.Nil, session.StartTime) {
+		return
+	}
+
+	tx, err := db.Pool.Begin(r.Context())
+	if err != nil {
+		apierror.Error(w, r, "Failed to confirm suggestion", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	result, err := tx.Exec(r.Context(), `
+		UPDATE suggested_sessions SET status = 'confirmed', session_id = $2
+		WHERE id = $1 AND status = 'pending'
+	`, suggestion.ID, session.ID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to confirm suggestion", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected() == 0 {
+		apierror.Error(w, r, "Suggestion is no longer pending", http.StatusConflict)
+		return
+	}
+
+	description, err := fieldcrypt.Seal(fieldcrypt.SessionDescription, session.Description)
+	if err != nil {
+		apierror.Error(w, r, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+	err = tx.QueryRow(r.Context(), `
+		INSERT INTO timer_sessions (id, user_id, project_id, start_time, end_time, description, encrypted_description, key_id, device_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING is_deleted, created_at, updated_at
+	`, session.ID, session.UserID, session.ProjectID, session.StartTime, session.EndTime,
+		description, session.EncryptedDescription, session.KeyID, session.DeviceID,
+	).Scan(&session.IsDeleted, &session.CreatedAt, &session.UpdatedAt)
+	if err != nil {
+		apierror.Error(w, r, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+	if err := outbox.Add(r.Context(), tx, userID, webhooks.EventSessionCreated, session); err != nil {
+		apierror.Error(w, r, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		apierror.Error(w, r, "Failed to confirm suggestion", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+func DismissSuggestedSession(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	suggestion, ok := loadSuggestion(w, r, userID)
+	if !ok {
+		return
+	}
+
+	_, err := db.Pool.Exec(r.Context(),
+		"UPDATE suggested_sessions SET status = 'dismissed' WHERE id = $1 AND status = 'pending'",
+		suggestion.ID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to dismiss suggestion", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadGoogleCalendar fetches the user's linked account. It writes the error
+// response and returns false on failure.
+func loadGoogleCalendar(w http.ResponseWriter, r *http.Request) (*googleCalendarStatus, bool) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	account, err := integrations.GetAccount(r.Context(), userID, integrations.GoogleCalendar)
+	if errors.Is(err, integrations.ErrAccountNotFound) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotConnected, "Google Calendar is not connected", nil)
+		return nil, false
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch integration", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	status := &googleCalendarStatus{Account: account, Settings: integrations.DefaultGoogleCalendarSettings()}
+	if err := json.Unmarshal(account.Settings, &status.Settings); err != nil {
+		apierror.Error(w, r, "Failed to read integration settings", http.StatusInternalServerError)
+		return nil, false
+	}
+	return status, true
+}
+
+// loadSuggestion fetches the user's pending suggestion named in the URL. It
+// writes the error response and returns false on failure.
+func loadSuggestion(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (*SuggestedSession, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid suggestion ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	var s SuggestedSession
+	err = db.Pool.QueryRow(r.Context(), `
+		SELECT id, title, start_time, end_time, status, session_id, created_at
+		FROM suggested_sessions
+		WHERE id = $1 AND user_id = $2
+	`, id, userID).Scan(&s.ID, &s.Title, &s.StartTime, &s.EndTime, &s.Status, &s.SessionID, &s.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.Error(w, r, "Suggestion not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch suggestion", http.StatusInternalServerError)
+		return nil, false
+	}
+	if s.Status != "pending" {
+		apierror.Error(w, r, "Suggestion is no longer pending", http.StatusConflict)
+		return nil, false
+	}
+	return &s, true
+}
@@ -0,0 +1,507 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/billing"
+	"github.com/pacerclub/zebra-backend/internal/integrations"
+	"github.com/pacerclub/zebra-backend/internal/invoices"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/service"
+)
+
+// maxInvoicePeriod caps how long a period one export may cover
+const maxInvoicePeriod = 366 * 24 * time.Hour
+
+// invoiceRequest selects the period to invoice. Start and end are inclusive
+// dates in the time zone. Rounding and currency come from the organization
+// settings in an organization's scope and from the request otherwise, with
+// rounding falling back to the user's preference when it applies to
+// invoices.
+type invoiceRequest struct {
+	Target          string `json:"target"`
+	Start           string `json:"start"`
+	End             string `json:"end"`
+	Timezone        string `json:"timezone"`
+	RoundingMode    string `json:"rounding_mode"`
+	RoundingMinutes int    `json:"rounding_minutes"`
+	Currency        string `json:"currency"`
+}
+
+// DownloadInvoices returns the period's invoices as ?format=csv, iif or, by
+// default, JSON for a preview
+func DownloadInvoices(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := invoiceRequest{
+		Start:        query.Get("start"),
+		End:          query.Get("end"),
+		Timezone:     query.Get("timezone"),
+		RoundingMode: query.Get("rounding_mode"),
+		Currency:     query.Get("currency"),
+	}
+	if minutes := query.Get("rounding_minutes"); minutes != "" {
+		var err error
+		if req.RoundingMinutes, err = strconv.Atoi(minutes); err != nil {
+			apierror.Error(w, r, "Invalid rounding_minutes", http.StatusBadRequest)
+			return
+		}
+	}
+	opts, ok := invoiceOptions(w, r, req)
+	if !ok {
+		return
+	}
+
+	list, err := invoices.Build(r.Context(), opts)
+	if err != nil {
+		apierror.Error(w, r, "Failed to build invoices", http.StatusInternalServerError)
+		return
+	}
+
+	filename := "invoices-" + opts.Start.Format("2006-01-02")
+	switch query.Get("format") {
+	case invoices.FormatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		invoices.WriteCSV(w, list)
+	case invoices.FormatIIF:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.iif"`)
+		invoices.WriteIIF(w, list)
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	default:
+		apierror.Error(w, r, "format must be json, csv or iif", http.StatusBadRequest)
+	}
+}
+
+// ExportInvoices pushes the period's invoices to QuickBooks or Xero in the
+// background and returns the queued export
+func ExportInvoices(w http.ResponseWriter, r *http.Request) {
+	var req invoiceRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	opts, ok := invoiceOptions(w, r, req)
+	if !ok {
+		return
+	}
+
+	var provider string
+	var pusher func(*integrations.Account) (invoices.Pusher, error)
+	switch req.Target {
+	case invoices.TargetQuickBooks:
+		provider, pusher = integrations.QuickBooks, integrations.QuickBooksPusher
+	case invoices.TargetXero:
+		provider, pusher = integrations.Xero, integrations.XeroPusher
+	default:
+		apierror.Error(w, r, "target must be quickbooks or xero", http.StatusBadRequest)
+		return
+	}
+
+	account, err := integrations.GetAccount(r.Context(), opts.UserID, provider)
+	if errors.Is(err, integrations.ErrAccountNotFound) {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.NotConnected, req.Target+" is not connected", nil)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch integration", http.StatusInternalServerError)
+		return
+	}
+	push, err := pusher(account)
+	if err != nil {
+		apierror.Error(w, r, "Failed to read integration settings", http.StatusInternalServerError)
+		return
+	}
+
+	export, err := invoices.CreateExport(r.Context(), opts, req.Target)
+	if err != nil {
+		apierror.Error(w, r, "Failed to start export", http.StatusInternalServerError)
+		return
+	}
+	invoices.Start(export, opts, push)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(export)
+}
+
+func ListInvoiceExports(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	exports, err := invoices.ListExports(r.Context(), userID, 50)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch exports", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exports)
+}
+
+func GetInvoiceExport(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	exportID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid export ID", http.StatusBadRequest)
+		return
+	}
+
+	export, err := invoices.GetExport(r.Context(), userID, exportID)
+	if errors.Is(err, invoices.ErrExportNotFound) {
+		apierror.Error(w, r, "Export not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch export", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(export)
+}
+
+// ConnectQuickBooks starts the OAuth flow and returns the URL to send the
+// user to
+func ConnectQuickBooks(w http.ResponseWriter, r *http.Request) {
+	connectAccounting(w, r, integrations.QuickBooks, integrations.QuickBooksOAuth())
+}
+
+// QuickBooksCallback finishes the OAuth flow. QuickBooks names the company
+// that was authorized as realmId.
+func QuickBooksCallback(w http.ResponseWriter, r *http.Request) {
+	accountingCallback(w, r, integrations.QuickBooks, integrations.QuickBooksOAuth(),
+		func(ctx context.Context, token *integrations.Token) (interface{}, error) {
+			settings := integrations.DefaultQuickBooksSettings()
+			settings.RealmID = r.URL.Query().Get("realmId")
+			if settings.RealmID == "" {
+				return nil, errors.New("missing realmId")
+			}
+			return settings, nil
+		})
+}
+
+// ConnectXero starts the OAuth flow and returns the URL to send the user to
+func ConnectXero(w http.ResponseWriter, r *http.Request) {
+	connectAccounting(w, r, integrations.Xero, integrations.XeroOAuth())
+}
+
+// XeroCallback finishes the OAuth flow and looks up the organisation that
+// was authorized
+func XeroCallback(w http.ResponseWriter, r *http.Request) {
+	accountingCallback(w, r, integrations.Xero, integrations.XeroOAuth(),
+		func(ctx context.Context, token *integrations.Token) (interface{}, error) {
+			settings := integrations.DefaultXeroSettings()
+			tenantID, err := integrations.XeroTenant(ctx, token.AccessToken)
+			settings.TenantID = tenantID
+			return settings, err
+		})
+}
+
+func GetQuickBooks(w http.ResponseWriter, r *http.Request) {
+	getAccounting(w, r, integrations.QuickBooks)
+}
+
+func GetXero(w http.ResponseWriter, r *http.Request) {
+	getAccounting(w, r, integrations.Xero)
+}
+
+// UpdateQuickBooks changes the item_id invoice lines are booked to
+func UpdateQuickBooks(w http.ResponseWriter, r *http.Request) {
+	account, ok := loadAccounting(w, r, integrations.QuickBooks)
+	if !ok {
+		return
+	}
+	settings := integrations.DefaultQuickBooksSettings()
+	if err := json.Unmarshal(account.Settings, &settings); err != nil {
+		apierror.Error(w, r, "Failed to read integration settings", http.StatusInternalServerError)
+		return
+	}
+	var req struct {
+		ItemID string `json:"item_id"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.ItemID == "" || len(req.ItemID) > service.MaxNameLength {
+		apierror.Error(w, r, "Invalid item_id", http.StatusBadRequest)
+		return
+	}
+	settings.ItemID = req.ItemID
+	updateAccounting(w, r, account, settings)
+}
+
+// UpdateXero changes the account_code invoice lines are booked to
+func UpdateXero(w http.ResponseWriter, r *http.Request) {
+	account, ok := loadAccounting(w, r, integrations.Xero)
+	if !ok {
+		return
+	}
+	settings := integrations.DefaultXeroSettings()
+	if err := json.Unmarshal(account.Settings, &settings); err != nil {
+		apierror.Error(w, r, "Failed to read integration settings", http.StatusInternalServerError)
+		return
+	}
+	var req struct {
+		AccountCode string `json:"account_code"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.AccountCode == "" || len(req.AccountCode) > service.MaxNameLength {
+		apierror.Error(w, r, "Invalid account_code", http.StatusBadRequest)
+		return
+	}
+	settings.AccountCode = req.AccountCode
+	updateAccounting(w, r, account, settings)
+}
+
+func DisconnectQuickBooks(w http.ResponseWriter, r *http.Request) {
+	disconnectAccounting(w, r, integrations.QuickBooks)
+}
+
+func DisconnectXero(w http.ResponseWriter, r *http.Request) {
+	disconnectAccounting(w, r, integrations.Xero)
+}
+
+// invoiceOptions validates the period and works out rounding and currency.
+// Invoicing an organization covers every member's time, so it needs report
+// access. It writes the error response and returns false on failure.
+func invoiceOptions(w http.ResponseWriter, r *http.Request, req invoiceRequest) (invoices.Options, bool) {
+	opts := invoices.Options{UserID: auth.GetUserIDFromContext(r.Context())}
+	if opts.UserID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return opts, false
+	}
+
+	loc := time.UTC
+	if req.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(req.Timezone); err != nil {
+			apierror.Error(w, r, "Invalid timezone", http.StatusBadRequest)
+			return opts, false
+		}
+	}
+	start, err := time.ParseInLocation("2006-01-02", req.Start, loc)
+	if err != nil {
+		apierror.Error(w, r, "start must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+		return opts, false
+	}
+	end, err := time.ParseInLocation("2006-01-02", req.End, loc)
+	if err != nil {
+		apierror.Error(w, r, "end must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+		return opts, false
+	}
+	opts.Start, opts.End = start, end.AddDate(0, 0, 1)
+	if !opts.End.After(opts.Start) || opts.End.Sub(opts.Start) > maxInvoicePeriod {
+		apierror.Error(w, r, "The period must run forward and cover at most a year", http.StatusBadRequest)
+		return opts, false
+	}
+
+	var settings models.OrganizationSettings
+	var rounding models.Rounding
+	opts.OrganizationID = service.ScopeOrganization(r.Context())
+	if opts.OrganizationID != nil {
+		if !auth.Can(r.Context(), auth.PermViewReports) {
+			apierror.Error(w, r, "Insufficient permissions", http.StatusForbidden)
+			return opts, false
+		}
+		settings, err = models.GetOrganizationSettings(r.Context(), *opts.OrganizationID)
+		if err != nil {
+			apierror.Error(w, r, "Failed to fetch organization settings", http.StatusInternalServerError)
+			return opts, false
+		}
+		rounding = settings.Rounding(models.RoundInvoices)
+	} else {
+		settings = models.DefaultOrganizationSettings()
+		if req.RoundingMode != "" {
+			settings.RoundingMode, settings.RoundingMinutes = req.RoundingMode, req.RoundingMinutes
+		}
+		if req.Currency != "" {
+			settings.DefaultCurrency = req.Currency
+		}
+		if !checkValid(w, r, &settings) {
+			return opts, false
+		}
+		rounding = settings.Rounding(models.RoundInvoices)
+		if req.RoundingMode == "" {
+			rounding, err = models.GetRounding(r.Context(), opts.UserID, nil, models.RoundInvoices)
+			if err != nil {
+				apierror.Error(w, r, "Failed to fetch preferences", http.StatusInternalServerError)
+				return opts, false
+			}
+		}
+	}
+	ws, err := billing.WorkspaceOf(r.Context(), opts.UserID, opts.OrganizationID)
+	if err == nil {
+		err = ws.CheckHistory(opts.Start)
+	}
+	if !checkLimit(w, r, err) {
+		return opts, false
+	}
+	opts.Currency = settings.DefaultCurrency
+	opts.Round = rounding.Round
+	return opts, true
+}
+
+func connectAccounting(w http.ResponseWriter, r *http.Request, provider string, cfg integrations.OAuthConfig) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !cfg.Configured() {
+		apierror.Error(w, r, "The "+provider+" integration is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	state, err := integrations.CreateState(r.Context(), userID, provider)
+	if err != nil {
+		apierror.Error(w, r, "Failed to start authorization", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"auth_url": cfg.AuthCodeURL(state),
+	})
+}
+
+// accountingCallback finishes an OAuth flow started by connectAccounting.
+// settings works out the settings of the linked account from the token and
+// callback. With <PROVIDER>_CONNECTED_REDIRECT_URL set the browser is sent
+// on there.
+func accountingCallback(w http.ResponseWriter, r *http.Request, provider string, cfg integrations.OAuthConfig,
+	settings func(context.Context, *integrations.Token) (interface{}, error)) {
+	query := r.URL.Query()
+	if message := query.Get("error"); message != "" {
+		apierror.Error(w, r, "Authorization failed: "+message, http.StatusBadRequest)
+		return
+	}
+
+	userID, err := integrations.ConsumeState(r.Context(), query.Get("state"), provider)
+	if errors.Is(err, integrations.ErrInvalidState) {
+		apierror.Error(w, r, "Invalid or expired authorization", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to verify authorization", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := cfg.Exchange(r.Context(), query.Get("code"))
+	if err != nil {
+		apierror.Error(w, r, "Failed to obtain access token", http.StatusBadGateway)
+		return
+	}
+	linked, err := settings(r.Context(), token)
+	if err != nil {
+		apierror.Error(w, r, "Failed to read the authorized company: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	account, err := integrations.SaveAccount(r.Context(), userID, provider, token, linked)
+	if err != nil {
+		apierror.Error(w, r, "Failed to save account", http.StatusInternalServerError)
+		return
+	}
+	// Linking again may authorize another company
+	if err := integrations.UpdateSettings(r.Context(), account.ID, linked); err != nil {
+		apierror.Error(w, r, "Failed to save account", http.StatusInternalServerError)
+		return
+	}
+
+	if redirect := os.Getenv(strings.ToUpper(provider) + "_CONNECTED_REDIRECT_URL"); redirect != "" {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("Connected. You can close this window.\n"))
+}
+
+func getAccounting(w http.ResponseWriter, r *http.Request, provider string) {
+	account, ok := loadAccounting(w, r, provider)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+func updateAccounting(w http.ResponseWriter, r *http.Request, account *integrations.Account, settings interface{}) {
+	if err := integrations.UpdateSettings(r.Context(), account.ID, settings); err != nil {
+		apierror.Error(w, r, "Failed to update integration", http.StatusInternalServerError)
+		return
+	}
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		apierror.Error(w, r, "Failed to update integration", http.StatusInternalServerError)
+		return
+	}
+	account.Settings = raw
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+func disconnectAccounting(w http.ResponseWriter, r *http.Request, provider string) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err := integrations.DeleteAccount(r.Context(), userID, provider)
+	if errors.Is(err, integrations.ErrAccountNotFound) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotConnected, provider+" is not connected", nil)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to disconnect "+provider, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadAccounting fetches the user's link to an accounting service. It
+// writes the error response and returns false on failure.
+func loadAccounting(w http.ResponseWriter, r *http.Request, provider string) (*integrations.Account, bool) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	account, err := integrations.GetAccount(r.Context(), userID, provider)
+	if errors.Is(err, integrations.ErrAccountNotFound) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotConnected, provider+" is not connected", nil)
+		return nil, false
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch integration", http.StatusInternalServerError)
+		return nil, false
+	}
+	return account, true
+}
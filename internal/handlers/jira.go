@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/integrations"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+type jiraStatus struct {
+	*integrations.Account
+	Settings integrations.JiraSettings `json:"settings"`
+}
+
+type connectJiraRequest struct {
+	SiteURL        string `json:"site_url"`
+	Email          string `json:"email"`
+	APIToken       string `json:"api_token"`
+	ExportSessions *bool  `json:"export_sessions"`
+}
+
+func (req *connectJiraRequest) Validate(v *validate.Validator) {
+	req.SiteURL = strings.TrimRight(strings.TrimSpace(req.SiteURL), "/")
+	req.Email = strings.TrimSpace(req.Email)
+	v.Required("site_url", req.SiteURL)
+	v.MaxLength("site_url", req.SiteURL, service.MaxNameLength)
+	v.Required("email", req.Email)
+	v.Email("email", req.Email)
+	v.MaxLength("email", req.Email, service.MaxNameLength)
+	v.Required("api_token", req.APIToken)
+}
+
+type jiraIssueKeyRequest struct {
+	// IssueKey overrides the key in the description; "" opts the session
+	// out of the export and null goes back to the description
+	IssueKey *string `json:"issue_key"`
+}
+
+// ConnectJira links a Jira Cloud site with the user's email and API token,
+// which are checked against Jira first. Connecting again replaces them.
+func ConnectJira(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req connectJiraRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	settings := integrations.JiraSettings{
+		SiteURL:        req.SiteURL,
+		Email:          req.Email,
+		ExportSessions: true,
+	}
+	if req.ExportSessions != nil {
+		settings.ExportSessions = *req.ExportSessions
+	}
+
+	if err := integrations.VerifyJira(r.Context(), settings, req.APIToken); err != nil {
+		apierror.Error(w, r, "Jira rejected the credentials: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	account, err := integrations.SaveAccount(r.Context(), userID, integrations.Jira, &integrations.Token{AccessToken: req.APIToken}, settings)
+	if err != nil {
+		apierror.Error(w, r, "Failed to save account", http.StatusInternalServerError)
+		return
+	}
+	// A new link gets the settings on creation, a renewed one keeps its old
+	// settings, so they are always written
+	if err := integrations.UpdateSettings(r.Context(), account.ID, settings); err != nil {
+		apierror.Error(w, r, "Failed to save account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(jiraStatus{Account: account, Settings: settings})
+}
+
+func GetJira(w http.ResponseWriter, r *http.Request) {
+	status, ok := loadJira(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// UpdateJira turns the worklog export on or off. The site and credentials
+// are changed by connecting again.
+func UpdateJira(w http.ResponseWriter, r *http.Request) {
+	status, ok := loadJira(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ExportSessions *bool `json:"export_sessions"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.ExportSessions != nil {
+		status.Settings.ExportSessions = *req.ExportSessions
+	}
+
+	if err := integrations.UpdateSettings(r.Context(), status.ID, status.Settings); err != nil {
+		apierror.Error(w, r, "Failed to update integration", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// DisconnectJira forgets the credentials and the session to worklog
+// mapping. Exported worklogs stay in Jira.
+func DisconnectJira(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err := integrations.DeleteAccount(r.Context(), userID, integrations.Jira)
+	if errors.Is(err, integrations.ErrAccountNotFound) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotConnected, "Jira is not connected", nil)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to disconnect Jira", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListJiraWorklogs returns the export state of sessions, optionally only
+// those with the ?status= given
+func ListJiraWorklogs(w http.ResponseWriter, r *http.Request) {
+	status, ok := loadJira(w, r)
+	if !ok {
+		return
+	}
+
+	worklogs, err := integrations.ListWorklogs(r.Context(), status.ID, r.URL.Query().Get("status"))
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch worklogs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(worklogs)
+}
+
+// SetJiraIssueKey sets the issue a session is logged against, instead of
+// the one mentioned in its description
+func SetJiraIssueKey(w http.ResponseWriter, r *http.Request) {
+	status, ok := loadJira(w, r)
+	if !ok {
+		return
+	}
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+	var req jiraIssueKeyRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.IssueKey != nil {
+		key := strings.ToUpper(strings.TrimSpace(*req.IssueKey))
+		if key != "" && !integrations.ValidIssueKey(key) {
+			apierror.Error(w, r, "Invalid issue_key", http.StatusBadRequest)
+			return
+		}
+		req.IssueKey = &key
+	}
+
+	var exists bool
+	err = db.Pool.QueryRow(r.Context(),
+		"SELECT true FROM timer_sessions WHERE id = $1 AND user_id = $2 AND is_deleted = false",
+		sessionID, status.UserID).Scan(&exists)
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.Error(w, r, "Session not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch session", http.StatusInternalServerError)
+		return
+	}
+
+	if err := integrations.SetIssueKey(r.Context(), status.ID, sessionID, req.IssueKey); err != nil {
+		apierror.Error(w, r, "Failed to set issue key", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RetryJiraWorklogs queues failed exports again for the next run
+func RetryJiraWorklogs(w http.ResponseWriter, r *http.Request) {
+	status, ok := loadJira(w, r)
+	if !ok {
+		return
+	}
+
+	queued, err := integrations.RetryFailedWorklogs(r.Context(), status.ID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to retry worklogs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"queued": queued})
+}
+
+// loadJira fetches the user's linked Jira account. It writes the error
+// response and returns false on failure.
+func loadJira(w http.ResponseWriter, r *http.Request) (*jiraStatus, bool) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	account, err := integrations.GetAccount(r.Context(), userID, integrations.Jira)
+	if errors.Is(err, integrations.ErrAccountNotFound) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotConnected, "Jira is not connected", nil)
+		return nil, false
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch integration", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	status := &jiraStatus{Account: account}
+	if err := json.Unmarshal(account.Settings, &status.Settings); err != nil {
+		apierror.Error(w, r, "Failed to read integration settings", http.StatusInternalServerError)
+		return nil, false
+	}
+	return status, true
+}
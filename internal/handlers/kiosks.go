@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// Kiosks let members of an organization clock in and out on a shared
+// device, such as a tablet at the door of a makerspace, with a PIN instead
+// of logging in. Clocking in starts the member's running timer on the
+// kiosk's project; clocking out saves it as a session.
+
+type createKioskRequest struct {
+	Name      string    `json:"name"`
+	ProjectID uuid.UUID `json:"project_id"`
+}
+
+func (req *createKioskRequest) Validate(v *validate.Validator) {
+	req.Name = strings.TrimSpace(req.Name)
+	v.Required("name", req.Name)
+	v.MaxLength("name", req.Name, service.MaxNameLength)
+	v.UUID("project_id", req.ProjectID)
+}
+
+// CreatedKiosk is a new kiosk with its token, which is only shown once
+type CreatedKiosk struct {
+	models.Kiosk
+	Token string `json:"token"`
+}
+
+type kioskPINRequest struct {
+	PIN string `json:"pin"`
+}
+
+func (req *kioskPINRequest) Validate(v *validate.Validator) {
+	v.Required("pin", req.PIN)
+	v.MaxBytes("pin", req.PIN, models.KioskPINLength)
+}
+
+// KioskPIN is a member's new kiosk PIN
+type KioskPIN struct {
+	PIN string `json:"pin"`
+}
+
+// KioskClock is the member who clocked in or out at a kiosk, with their
+// running timer after clocking in or the session saved on clocking out
+type KioskClock struct {
+	Member  models.KioskMember `json:"member"`
+	Timer   *RunningTimer      `json:"timer,omitempty"`
+	Session *service.Session   `json:"session,omitempty"`
+}
+
+// CreateKiosk registers a kiosk recording time on one of the
+// organization's projects and returns its token
+func CreateKiosk(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := loadOrganization(w, r, auth.PermManageSettings)
+	if !ok {
+		return
+	}
+
+	var req createKioskRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	kiosk, token, err := models.CreateKiosk(r.Context(), org.ID, req.ProjectID, req.Name, auth.GetUserIDFromContext(r.Context()))
+	if errors.Is(err, models.ErrKioskProject) {
+		apierror.Error(w, r, "Project not found", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to create kiosk", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreatedKiosk{Kiosk: *kiosk, Token: token})
+}
+
+// ListKiosks returns the organization's kiosks, without their tokens
+func ListKiosks(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := loadOrganization(w, r, auth.PermManageSettings)
+	if !ok {
+		return
+	}
+
+	kiosks, err := models.ListKiosks(r.Context(), org.ID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch kiosks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(kiosks)
+}
+
+// DeleteKiosk removes a kiosk, revoking its token
+func DeleteKiosk(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := loadOrganization(w, r, auth.PermManageSettings)
+	if !ok {
+		return
+	}
+
+	kioskID, err := uuid.Parse(chi.URLParam(r, "kioskID"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid kiosk ID", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := models.DeleteKiosk(r.Context(), org.ID, kioskID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to delete kiosk", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		apierror.Error(w, r, "Kiosk not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateKioskPIN gives the user a new kiosk PIN for the organization,
+// replacing their old one
+func CreateKioskPIN(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := loadOrganization(w, r, auth.PermLogTime)
+	if !ok {
+		return
+	}
+
+	pin, err := models.NewKioskPIN(r.Context(), org.ID, auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		apierror.Error(w, r, "Failed to create kiosk PIN", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(KioskPIN{PIN: pin})
+}
+
+// DeleteKioskPIN removes the user's kiosk PIN for the organization
+func DeleteKioskPIN(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := loadOrganization(w, r, "")
+	if !ok {
+		return
+	}
+
+	if err := models.ClearKioskPIN(r.Context(), org.ID, auth.GetUserIDFromContext(r.Context())); err != nil {
+		apierror.Error(w, r, "Failed to delete kiosk PIN", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// KioskClockIn starts the running timer of the member with the PIN on the
+// kiosk's project, saving a timer they left running elsewhere
+func KioskClockIn(w http.ResponseWriter, r *http.Request) {
+	kiosk, member, ok := kioskMember(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := db.Pool.Begin(r.Context())
+	if err != nil {
+		apierror.Error(w, r, "Failed to clock in", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	running, err := runningTimer(r.Context(), tx, member.UserID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to clock in", http.StatusInternalServerError)
+		return
+	}
+	if running != nil && running.ProjectID != nil && *running.ProjectID == kiosk.ProjectID {
+		apierror.Write(w, r, http.StatusConflict, apierror.Conflict, "Already clocked in", map[string]interface{}{"member": member, "timer": running})
+		return
+	}
+	if _, ok := stopRunningTimer(w, r, tx, member.UserID); !ok {
+		return
+	}
+	timer, err := insertRunningTimer(r.Context(), tx, member.UserID, &kiosk.ProjectID, kiosk.Name, nil, kioskDeviceID(kiosk))
+	if err != nil {
+		apierror.Error(w, r, "Failed to clock in", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		apierror.Error(w, r, "Failed to clock in", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(KioskClock{Member: *member, Timer: timer})
+}
+
+// KioskClockOut saves the running timer of the member with the PIN as a
+// session, if it runs on the kiosk's project
+func KioskClockOut(w http.ResponseWriter, r *http.Request) {
+	kiosk, member, ok := kioskMember(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := db.Pool.Begin(r.Context())
+	if err != nil {
+		apierror.Error(w, r, "Failed to clock out", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	running, err := runningTimer(r.Context(), tx, member.UserID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to clock out", http.StatusInternalServerError)
+		return
+	}
+	if running == nil || running.ProjectID == nil || *running.ProjectID != kiosk.ProjectID {
+		apierror.Error(w, r, "Not clocked in", http.StatusNotFound)
+		return
+	}
+	session, ok := stopRunningTimer(w, r, tx, member.UserID)
+	if !ok {
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		apierror.Error(w, r, "Failed to clock out", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(KioskClock{Member: *member, Session: session})
+}
+
+// kioskMember authenticates the kiosk by its bearer token and finds the
+// member by the PIN in the request body. It writes the error response and
+// returns false on failure.
+func kioskMember(w http.ResponseWriter, r *http.Request) (*models.Kiosk, *models.KioskMember, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	kiosk, err := models.KioskByToken(r.Context(), token)
+	if errors.Is(err, models.ErrKioskNotFound) {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to verify kiosk token", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	var req kioskPINRequest
+	if !decodeJSON(w, r, &req) {
+		return nil, nil, false
+	}
+	member, err := models.MemberByKioskPIN(r.Context(), kiosk.OrganizationID, req.PIN)
+	if err != nil {
+		apierror.Error(w, r, "Failed to verify PIN", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if member == nil {
+		apierror.Error(w, r, "Unknown PIN", http.StatusForbidden)
+		return nil, nil, false
+	}
+	if !auth.RoleHas(member.Role, auth.PermLogTime) {
+		apierror.Error(w, r, "Insufficient permissions", http.StatusForbidden)
+		return nil, nil, false
+	}
+
+	// The running timer is stored in plaintext
+	mode, err := models.GetStorageMode(r.Context(), member.UserID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch storage mode", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if mode == models.StorageModeEncrypted {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.StorageModeMismatch, "Kiosks are not available in encrypted storage mode", nil)
+		return nil, nil, false
+	}
+	return kiosk, member, true
+}
+
+// kioskDeviceID is the device timers started at the kiosk are recorded on
+func kioskDeviceID(kiosk *models.Kiosk) string {
+	return "kiosk:" + kiosk.ID.String()
+}
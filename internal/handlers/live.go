@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/live"
+	"github.com/pacerclub/zebra-backend/internal/logging"
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+// liveKeepAlive is how often an idle live stream sends a comment, so
+// proxies do not close it
+const liveKeepAlive = 20 * time.Second
+
+// liveDeadlineMargin ends live streams this long before the request
+// timeout, and liveRetry is how soon clients reconnect afterwards
+const (
+	liveDeadlineMargin = 5 * time.Second
+	liveRetry          = time.Second
+)
+
+// LiveEvents streams changes made on the user's other devices as
+// server-sent events named after their topic, such as "timer" with the
+// running timer or null, and "presence" with who has a timer running in one
+// of the user's organizations. The stream opens with the current state of
+// every topic and ends before the request timeout; clients reconnect, as
+// EventSource does on its own, which also picks up organizations joined or
+// left meanwhile.
+func LiveEvents(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-liveDeadlineMargin))
+		defer cancel()
+	}
+
+	orgs, err := models.ListOrganizations(ctx, userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch organizations", http.StatusInternalServerError)
+		return
+	}
+	opening := make([]live.Change, 0, len(live.Topics)+len(orgs))
+	for _, topic := range live.Topics {
+		opening = append(opening, live.Change{Subject: userID, Topic: topic})
+	}
+	subjects := []uuid.UUID{userID}
+	for _, org := range orgs {
+		opening = append(opening, live.Change{Subject: org.ID, Topic: live.TopicPresence})
+		subjects = append(subjects, org.ID)
+	}
+
+	// Subscribing before loading the state misses no change in between
+	changes, unsubscribe := live.Subscribe(subjects...)
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	fmt.Fprintf(w, "retry: %d\n\n", liveRetry.Milliseconds())
+	for _, change := range opening {
+		if !sendLiveState(ctx, w, change) {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(liveKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change := <-changes:
+			if !sendLiveState(ctx, w, change) {
+				return
+			}
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// sendLiveState writes the current state of the changed topic as an event,
+// returning false if it could not be loaded
+func sendLiveState(ctx context.Context, w http.ResponseWriter, change live.Change) bool {
+	var state interface{}
+	var err error
+	topic := change.Topic
+	switch topic {
+	case live.TopicTimer:
+		state, err = runningTimer(ctx, db.Pool, change.Subject)
+	case live.TopicPresence:
+		state, err = organizationPresence(ctx, change.Subject)
+	default:
+		return true
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			logging.FromContext(ctx).Error("Failed to load live state", "topic", topic, "error", err)
+		}
+		return false
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return false
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", topic, data)
+	return true
+}
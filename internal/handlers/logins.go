@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/audit"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+// maxLogins caps how many logins one request may list
+const maxLogins = 100
+
+// ListLogins returns the user's latest successful and failed logins, newest
+// first: the last ?limit= (20 by default) kept in the audit log
+func ListLogins(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 20
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxLogins {
+			apierror.Error(w, r, fmt.Sprintf("limit must be between 1 and %d", maxLogins), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	logins, err := audit.Logins(r.Context(), userID, limit)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch logins", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logins)
+}
+
+// SignOutEverywhere revokes every token issued to the user, on every
+// device, the one making the request included
+func SignOutEverywhere(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := models.RevokeTokens(r.Context(), userID); err != nil {
+		apierror.Error(w, r, "Failed to sign out everywhere", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+type repairRequest struct {
+	Plan []service.Repair `json:"plan"`
+}
+
+func (req *repairRequest) Validate(v *validate.Validator) {
+	v.Check(len(req.Plan) > 0, "plan", "is required")
+	if len(req.Plan) > service.MaxRepairs {
+		v.Fail("plan", "must list at most %d repairs", service.MaxRepairs)
+	}
+}
+
+// VerifyData checks the user's data for damage left by past sync bugs and
+// returns the issues with a plan repairing them
+func VerifyData(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	report, err := service.VerifyUserData(r.Context(), userID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// RepairData applies a plan VerifyData returned, skipping the repairs no
+// longer needed
+func RepairData(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req repairRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	result, err := service.RepairUserData(r.Context(), userID, req.Plan)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
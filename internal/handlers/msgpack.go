@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// Sync and the session list accept and answer MessagePack as well as JSON,
+// as negotiated by negotiate.Middleware; the encoders live with the types in
+// the service package.
+
+// writeBody writes an already encoded body
+func writeBody(w http.ResponseWriter, contentType string, statusCode int, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/notify"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// notificationSettings lists whether each type of notification is sent on
+// each channel, and the channels this server can send on
+type notificationSettings struct {
+	Settings          []notify.Setting `json:"settings"`
+	AvailableChannels []string         `json:"available_channels"`
+}
+
+// notificationSettingsRequest turns channels on or off, keyed by type and
+// channel, as in {"digest": {"email": false}}
+type notificationSettingsRequest map[string]map[string]bool
+
+func (req *notificationSettingsRequest) Validate(v *validate.Validator) {
+	for notificationType, channels := range *req {
+		if !notify.ValidType(notificationType) {
+			v.Fail(notificationType, "is not a type of notification")
+			continue
+		}
+		for channel := range channels {
+			v.Check(notify.ValidChannel(channel), notificationType+"."+channel, "is not a notification channel")
+		}
+	}
+}
+
+func GetNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	writeNotificationSettings(w, r, userID)
+}
+
+// UpdateNotificationSettings turns the channels in the request on or off.
+// Types and channels missing from it keep their setting.
+func UpdateNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req notificationSettingsRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := notify.UpdateSettings(r.Context(), userID, req); err != nil {
+		apierror.Error(w, r, "Failed to update notification settings", http.StatusInternalServerError)
+		return
+	}
+	writeNotificationSettings(w, r, userID)
+}
+
+func writeNotificationSettings(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	settings, err := notify.Settings(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch notification settings", http.StatusInternalServerError)
+		return
+	}
+	available := []string{}
+	for _, channel := range notify.Channels {
+		if notify.Available(channel) {
+			available = append(available, channel)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notificationSettings{Settings: settings, AvailableChannels: available})
+}
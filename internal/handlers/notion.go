@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/integrations"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+type notionStatus struct {
+	*integrations.Account
+	Settings integrations.NotionSettings `json:"settings"`
+}
+
+type connectNotionRequest struct {
+	integrations.NotionSettings
+	Token string `json:"token"`
+}
+
+func (req *connectNotionRequest) Validate(v *validate.Validator) {
+	req.Token = strings.TrimSpace(req.Token)
+	v.Required("token", req.Token)
+	v.Required("database_id", req.DatabaseID)
+}
+
+// ConnectNotion links a Notion database with an internal integration token,
+// after checking the database's properties. Connecting again replaces the
+// token and settings.
+func ConnectNotion(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	req := connectNotionRequest{NotionSettings: integrations.DefaultNotionSettings()}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !checkNotionSettings(w, r, req.NotionSettings, req.Token) {
+		return
+	}
+
+	account, err := integrations.SaveAccount(r.Context(), userID, integrations.Notion, &integrations.Token{AccessToken: req.Token}, req.NotionSettings)
+	if err != nil {
+		apierror.Error(w, r, "Failed to save account", http.StatusInternalServerError)
+		return
+	}
+	// A renewed link keeps its old settings, so they are always written
+	if err := integrations.UpdateSettings(r.Context(), account.ID, req.NotionSettings); err != nil {
+		apierror.Error(w, r, "Failed to save account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(notionStatus{Account: account, Settings: req.NotionSettings})
+}
+
+func GetNotion(w http.ResponseWriter, r *http.Request) {
+	status, ok := loadNotion(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// UpdateNotion changes the database, property names, time zone or turns the
+// export on or off. The token is changed by connecting again.
+func UpdateNotion(w http.ResponseWriter, r *http.Request) {
+	status, ok := loadNotion(w, r)
+	if !ok {
+		return
+	}
+
+	if !decodeJSON(w, r, &status.Settings) {
+		return
+	}
+	if !checkNotionSettings(w, r, status.Settings, status.AccessToken) {
+		return
+	}
+
+	if err := integrations.UpdateSettings(r.Context(), status.ID, status.Settings); err != nil {
+		apierror.Error(w, r, "Failed to update integration", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// DisconnectNotion forgets the token. Exported rows stay in Notion.
+func DisconnectNotion(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err := integrations.DeleteAccount(r.Context(), userID, integrations.Notion)
+	if errors.Is(err, integrations.ErrAccountNotFound) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotConnected, "Notion is not connected", nil)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to disconnect Notion", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkNotionSettings validates settings against the Notion database. It
+// writes the error response and returns false on failure.
+func checkNotionSettings(w http.ResponseWriter, r *http.Request, settings integrations.NotionSettings, token string) bool {
+	for _, value := range []string{settings.DatabaseID, settings.ProjectProperty, settings.WeekProperty, settings.HoursProperty} {
+		if value == "" || len(value) > service.MaxNameLength {
+			apierror.Error(w, r, "database_id and property names must be between 1 and 255 characters", http.StatusBadRequest)
+			return false
+		}
+	}
+	if err := integrations.VerifyNotion(r.Context(), settings, token); err != nil {
+		apierror.Error(w, r, "Notion rejected the settings: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// loadNotion fetches the user's linked Notion account. It writes the error
+// response and returns false on failure.
+func loadNotion(w http.ResponseWriter, r *http.Request) (*notionStatus, bool) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	account, err := integrations.GetAccount(r.Context(), userID, integrations.Notion)
+	if errors.Is(err, integrations.ErrAccountNotFound) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotConnected, "Notion is not connected", nil)
+		return nil, false
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch integration", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	status := &notionStatus{Account: account, Settings: integrations.DefaultNotionSettings()}
+	if err := json.Unmarshal(account.Settings, &status.Settings); err != nil {
+		apierror.Error(w, r, "Failed to read integration settings", http.StatusInternalServerError)
+		return nil, false
+	}
+	return status, true
+}
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/oauth"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// maxRedirectURIs caps how many redirect URIs an app can register
+const maxRedirectURIs = 10
+
+type createOAuthClientRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+}
+
+func (req *createOAuthClientRequest) Validate(v *validate.Validator) {
+	req.Name = strings.TrimSpace(req.Name)
+	v.Required("name", req.Name)
+	v.MaxLength("name", req.Name, service.MaxNameLength)
+	v.Check(len(req.RedirectURIs) > 0 && len(req.RedirectURIs) <= maxRedirectURIs, "redirect_uris",
+		fmt.Sprintf("must list between 1 and %d URIs", maxRedirectURIs))
+	for i, uri := range req.RedirectURIs {
+		v.Check(validRedirectURI(uri), fmt.Sprintf("redirect_uris[%d]", i), "must be an https URL, or http for loopback addresses")
+	}
+}
+
+// authorizeRequest holds the parameters of an authorization request, read
+// from the query string for the consent screen and from the JSON body when
+// the user decides
+type authorizeRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	Approve             bool   `json:"approve"`
+}
+
+type consentResponse struct {
+	ClientID    uuid.UUID    `json:"client_id"`
+	ClientName  string       `json:"client_name"`
+	RedirectURI string       `json:"redirect_uri"`
+	Scopes      []auth.Scope `json:"scopes"`
+}
+
+// CreateOAuthClient registers a third-party app. The response includes the
+// client secret, which is not shown again.
+func CreateOAuthClient(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req createOAuthClientRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	count, err := oauth.CountClients(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to register app", http.StatusInternalServerError)
+		return
+	}
+	if count >= oauth.MaxClientsPerUser {
+		apierror.Write(w, r, http.StatusConflict, apierror.LimitReached, "Too many apps", nil)
+		return
+	}
+
+	client, err := oauth.CreateClient(r.Context(), userID, req.Name, req.RedirectURIs)
+	if err != nil {
+		apierror.Error(w, r, "Failed to register app", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(client)
+}
+
+func ListOAuthClients(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	clients, err := oauth.ListClients(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch apps", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clients)
+}
+
+// DeleteOAuthClient removes an app the user registered, revoking all access
+// it was granted
+func DeleteOAuthClient(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	clientID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid client ID", http.StatusBadRequest)
+		return
+	}
+
+	err = oauth.DeleteClient(r.Context(), userID, clientID)
+	if errors.Is(err, oauth.ErrClientNotFound) {
+		apierror.Error(w, r, "App not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to delete app", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetOAuthConsent checks an authorization request and describes it for the
+// consent screen
+func GetOAuthConsent(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := authorizeRequest{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	}
+	client, scopes, ok := checkAuthorizeRequest(w, r, &req)
+	if !ok {
+		return
+	}
+
+	consent := consentResponse{ClientID: client.ID, ClientName: client.Name, RedirectURI: req.RedirectURI}
+	for _, s := range auth.Scopes {
+		for _, requested := range scopes {
+			if s.Name == requested {
+				consent.Scopes = append(consent.Scopes, s)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(consent)
+}
+
+// DecideOAuthConsent records the user's answer on the consent screen and
+// returns where to send the browser: back to the app with a code, or with
+// an access_denied error
+func DecideOAuthConsent(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req authorizeRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	client, scopes, ok := checkAuthorizeRequest(w, r, &req)
+	if !ok {
+		return
+	}
+
+	params := url.Values{}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	if req.Approve {
+		code, err := oauth.CreateCode(r.Context(), client, userID, req.RedirectURI, scopes, req.CodeChallenge)
+		if err != nil {
+			apierror.Error(w, r, "Failed to authorize app", http.StatusInternalServerError)
+			return
+		}
+		params.Set("code", code)
+	} else {
+		params.Set("error", "access_denied")
+	}
+
+	separator := "?"
+	if strings.Contains(req.RedirectURI, "?") {
+		separator = "&"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"redirect_to": req.RedirectURI + separator + params.Encode(),
+	})
+}
+
+// OAuthToken is the token endpoint. Apps authenticate with HTTP basic auth
+// or client_id and client_secret form fields, and use the
+// authorization_code or refresh_token grant. Errors follow RFC 6749.
+func OAuthToken(w http.ResponseWriter, r *http.Request) {
+	client, ok := oauthClient(w, r)
+	if !ok {
+		return
+	}
+
+	var tokens *oauth.TokenResponse
+	var err error
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		tokens, err = oauth.ExchangeCode(r.Context(), client, r.PostForm.Get("code"),
+			r.PostForm.Get("redirect_uri"), r.PostForm.Get("code_verifier"))
+	case "refresh_token":
+		tokens, err = oauth.Refresh(r.Context(), client, r.PostForm.Get("refresh_token"))
+	default:
+		oauthError(w, "unsupported_grant_type", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, oauth.ErrInvalidGrant) {
+		oauthError(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		oauthError(w, "server_error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// RevokeOAuthToken revokes the grant behind a refresh token (RFC 7009)
+func RevokeOAuthToken(w http.ResponseWriter, r *http.Request) {
+	client, ok := oauthClient(w, r)
+	if !ok {
+		return
+	}
+
+	if err := oauth.RevokeToken(r.Context(), client, r.PostForm.Get("token")); err != nil {
+		oauthError(w, "server_error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// ListOAuthAuthorizations returns the apps the user has granted access to
+func ListOAuthAuthorizations(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	authorizations, err := oauth.ListAuthorizations(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch authorized apps", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(authorizations)
+}
+
+// RevokeOAuthAuthorization withdraws an app's access to the user's data
+func RevokeOAuthAuthorization(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	clientID, err := uuid.Parse(chi.URLParam(r, "clientID"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid client ID", http.StatusBadRequest)
+		return
+	}
+
+	err = oauth.RevokeAuthorization(r.Context(), userID, clientID)
+	if errors.Is(err, oauth.ErrClientNotFound) {
+		apierror.Error(w, r, "App not authorized", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to revoke app", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkAuthorizeRequest validates an authorization request and fills in
+// the redirect URI of apps with only one. Problems are reported to the user
+// rather than the app, since the redirect URI may not be trusted. It writes
+// the error response and returns false on failure.
+func checkAuthorizeRequest(w http.ResponseWriter, r *http.Request, req *authorizeRequest) (*oauth.Client, []string, bool) {
+	if req.ResponseType != "code" {
+		apierror.Error(w, r, "response_type must be code", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	clientID, err := uuid.Parse(req.ClientID)
+	if err != nil {
+		apierror.Error(w, r, "Unknown client_id", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	client, err := oauth.GetClient(r.Context(), clientID)
+	if errors.Is(err, oauth.ErrClientNotFound) {
+		apierror.Error(w, r, "Unknown client_id", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch app", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	if req.RedirectURI == "" && len(client.RedirectURIs) == 1 {
+		req.RedirectURI = client.RedirectURIs[0]
+	}
+	if !client.AllowsRedirect(req.RedirectURI) {
+		apierror.Error(w, r, "redirect_uri is not registered for this app", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	scopes, err := oauth.ParseScopes(req.Scope)
+	if err != nil {
+		apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if req.CodeChallenge != "" && req.CodeChallengeMethod != "S256" {
+		apierror.Error(w, r, "code_challenge_method must be S256", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	return client, scopes, true
+}
+
+// oauthClient parses the form and authenticates the app. It writes the
+// error response and returns false on failure.
+func oauthClient(w http.ResponseWriter, r *http.Request) (*oauth.Client, bool) {
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, "invalid_request", http.StatusBadRequest)
+		return nil, false
+	}
+
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	client, err := oauth.AuthenticateClient(r.Context(), clientID, secret)
+	if errors.Is(err, oauth.ErrInvalidClient) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="zebra"`)
+		oauthError(w, "invalid_client", http.StatusUnauthorized)
+		return nil, false
+	}
+	if err != nil {
+		oauthError(w, "server_error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return client, true
+}
+
+// oauthError writes an error in the format RFC 6749 prescribes
+func oauthError(w http.ResponseWriter, code string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+// validRedirectURI accepts https URLs, and http only for loopback addresses
+// used by native apps
+func validRedirectURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || u.Host == "" || u.Fragment != "" || len(uri) > maxTargetURLLength {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/i18n"
+	"github.com/pacerclub/zebra-backend/internal/onboarding"
+)
+
+// GetOnboarding lists the onboarding steps, described in the request's
+// locale, and which the user completed
+func GetOnboarding(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	progress, err := onboarding.Get(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch onboarding", http.StatusInternalServerError)
+		return
+	}
+	locale := i18n.Locale(r.Context())
+	for i := range progress.Steps {
+		progress.Steps[i].Description = i18n.T(locale, progress.Steps[i].Description)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(progress)
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"github.com/pacerclub/zebra-backend/internal/audit"
+	"github.com/pacerclub/zebra-backend/internal/billing"
+	"github.com/pacerclub/zebra-backend/internal/imports"
+	"github.com/pacerclub/zebra-backend/internal/integrations"
+	"github.com/pacerclub/zebra-backend/internal/invoices"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/negotiate"
+	"github.com/pacerclub/zebra-backend/internal/oauth"
+	"github.com/pacerclub/zebra-backend/internal/onboarding"
+	"github.com/pacerclub/zebra-backend/internal/openapi"
+	"github.com/pacerclub/zebra-backend/internal/opstats"
+	"github.com/pacerclub/zebra-backend/internal/pagination"
+	"github.com/pacerclub/zebra-backend/internal/rules"
+	"github.com/pacerclub/zebra-backend/internal/scheduler"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/uploads"
+	"github.com/pacerclub/zebra-backend/internal/usage"
+	"github.com/pacerclub/zebra-backend/internal/webhooks"
+)
+
+// Operations documents the API routes, keyed by method and path relative to
+// the API root. Request types are the ones the handlers decode, so request
+// bodies are validated against exactly what the handlers read; keep them in
+// step when a handler changes.
+var Operations = map[string]openapi.Operation{
+	// Authentication
+	"POST /auth/register": {Summary: "Register a new user", Tag: "Authentication", Public: true,
+		Request: registerRequest{}, Required: []string{"email", "password"}, Response: authResponse{}},
+	"POST /auth/login": {Summary: "Log in and get a token", Tag: "Authentication", Public: true,
+		Request: loginRequest{}, Required: []string{"email", "password"}, Response: authResponse{}},
+	"GET /auth/me": {Summary: "Get your account and profile", Tag: "Authentication", Response: models.User{}},
+	"PUT /auth/me": {Summary: "Update your profile", Tag: "Authentication",
+		Request: models.Profile{}, Response: models.User{}},
+	"POST /auth/deactivate": {Summary: "Deactivate your account, keeping its data", Tag: "Authentication",
+		Request: deactivateRequest{}, Required: []string{"password"}},
+	"POST /auth/reactivate": {Summary: "Mail a reactivation link to a deactivated account", Tag: "Authentication", Public: true,
+		Request: reactivationRequest{}, Required: []string{"email"}},
+	"POST /auth/reactivate/confirm": {Summary: "Reactivate an account and log in", Tag: "Authentication", Public: true,
+		Request: confirmReactivationRequest{}, Required: []string{"token"}, Response: authResponse{}},
+	"GET /auth/preferences": {Summary: "Get your preferences", Tag: "Authentication", Response: models.Preferences{}},
+	"PUT /auth/preferences": {Summary: "Update your preferences", Tag: "Authentication",
+		Request: models.Preferences{}, Response: models.Preferences{}},
+	"GET /auth/notifications/settings": {Summary: "Get the channels each type of notification is sent on", Tag: "Notifications",
+		Response: notificationSettings{}},
+	"PUT /auth/notifications/settings": {Summary: "Turn notification channels on or off", Tag: "Notifications",
+		Request: notificationSettingsRequest{}, Response: notificationSettings{}},
+	"GET /onboarding": {Summary: "List the onboarding steps and which you completed", Tag: "Onboarding",
+		Response: onboarding.Progress{}},
+	"GET /usage":              {Summary: "Get your API and sync usage per day", Tag: "Usage", Response: usage.Report{}},
+	"GET /auth/reports/hours": {Summary: "Compare your tracked time with your working hours", Tag: "Reports", Response: service.HoursReport{}},
+	"GET /auth/reports/estimates": {Summary: "Compare the time your completed tasks took with their estimates", Tag: "Reports",
+		Response: service.EstimateReport{}},
+	"GET /billing/subscription": {Summary: "Get your plan and the state of its payments", Tag: "Billing", Response: billing.Subscription{}},
+	"POST /billing/checkout": {Summary: "Start subscribing to a paid plan, returning the Stripe Checkout page", Tag: "Billing",
+		Request: checkoutRequest{}, Required: []string{"plan"}, Response: redirectResponse{}},
+	"POST /billing/portal":         {Summary: "Get the Stripe billing portal page, to change or cancel your plan", Tag: "Billing", Response: redirectResponse{}},
+	"POST /billing/stripe/webhook": {Summary: "Stripe webhook (signed with Stripe-Signature)", Tag: "Billing", Public: true},
+	"GET /auth/workspaces":         {Summary: "List your workspaces", Tag: "Workspaces", Response: []Workspace{}},
+	"POST /auth/workspace": {Summary: "Switch the default workspace and get a new token", Tag: "Workspaces",
+		Request: switchWorkspaceRequest{}, Required: []string{"workspace_id"}, Response: map[string]string{}},
+	"GET /auth/storage-mode": {Summary: "Get the storage mode", Tag: "Storage mode", Response: storageModeRequest{}},
+	"PUT /auth/storage-mode": {Summary: "Change the storage mode", Tag: "Storage mode",
+		Request: storageModeRequest{}, Required: []string{"storage_mode"}, Response: storageModeRequest{}},
+	"GET /auth/audit": {Summary: "List the audit log of your calls", Tag: "Audit log", Response: pagination.Page[audit.Entry]{}, List: auditList},
+	"GET /auth/logins": {Summary: "List your latest successful and failed logins, up to ?limit=", Tag: "Audit log",
+		Response: []audit.Login{}},
+	"POST /auth/logins/sign-out-everywhere": {Summary: "Revoke every token issued to you, on every device", Tag: "Audit log"},
+	"GET /admin/backup":                     {Summary: "Download a gzip archive of the instance or of the user in ?user_id=", Tag: "Admin"},
+	"POST /admin/restore": {Summary: "Restore a backup archive sent as the request body", Tag: "Admin",
+		Response: RestoreResult{}},
+
+	// Sessions
+	"GET /auth/sessions": {Summary: "List sessions", Tag: "Sessions", Response: pagination.Page[service.Session]{}, List: service.SessionList,
+		Encodings: []string{negotiate.Msgpack}},
+	"POST /auth/sessions": {Summary: "Create a session", Tag: "Sessions",
+		Request: service.Session{}, Required: []string{"start_time", "end_time"}, Response: service.Session{}},
+	"GET /auth/sessions/search": {Summary: "Search sessions by description", Tag: "Sessions", Response: []service.Session{}},
+	"POST /auth/sessions/bulk": {Summary: "Create up to 100 sessions, all or none", Tag: "Sessions",
+		Request: []bulkSession{}, Response: []service.Session{}},
+	"PUT /auth/sessions/{id}": {Summary: "Update a session at the version read", Tag: "Sessions",
+		Request: service.Session{}, Required: []string{"version"}, Response: service.Session{}},
+	"DELETE /auth/sessions/{id}": {Summary: "Delete a session", Tag: "Sessions"},
+	"GET /auth/sessions/review": {Summary: "List sessions flagged for review", Tag: "Sessions",
+		Response: []service.SessionReview{}},
+	"DELETE /auth/sessions/{id}/review": {Summary: "Clear a session's review flag", Tag: "Sessions"},
+	"GET /auth/sessions/duplicates": {Summary: "Find near-identical sessions, such as copies left by sync bugs", Tag: "Sessions",
+		Response: []service.DuplicateGroup{}},
+	"POST /auth/sessions/merge": {Summary: "Merge duplicate sessions into the one kept", Tag: "Sessions",
+		Request: mergeSessionsRequest{}, Required: []string{"keep_id", "session_ids"}, Response: service.MergeResult{}},
+	"PUT /auth/sessions/{id}/task": {Summary: "Log a session against a task", Tag: "Sessions",
+		Request: sessionTaskRequest{}, Required: []string{"task_id"}, Response: service.Session{}},
+	"DELETE /auth/sessions/{id}/task": {Summary: "Stop logging a session against a task", Tag: "Sessions", Response: service.Session{}},
+	"GET /auth/current":               {Summary: "Get the running timer", Tag: "Sessions", Response: &RunningTimer{}},
+	"POST /auth/quick-start": {Summary: "Start a timer from a description", Tag: "Sessions",
+		Request: quickStartRequest{}, Required: []string{"description"}, Response: RunningTimer{}},
+	"POST /auth/current/start": {Summary: "Start the running timer on every device", Tag: "Sessions",
+		Request: startTimerRequest{}, Response: RunningTimer{}},
+	"POST /auth/current/stop": {Summary: "Stop the running timer", Tag: "Sessions", Response: service.Session{}},
+	"GET /auth/live":          {Summary: "Stream changes made on your other devices and in your organizations as server-sent events", Tag: "Sessions"},
+	"GET /auth/suggestions":   {Summary: "List untracked blocks of calendar events", Tag: "Sessions", Response: []CandidateSession{}},
+
+	// Projects
+	"GET /auth/projects":        {Summary: "List projects", Tag: "Projects", Response: pagination.Page[service.Project]{}, List: service.ProjectList},
+	"GET /auth/projects/search": {Summary: "Search projects by name", Tag: "Projects", Response: []service.Project{}},
+	"POST /auth/projects": {Summary: "Create a project", Tag: "Projects",
+		Request: service.Project{}, Response: service.Project{}},
+	"PUT /auth/projects/{id}": {Summary: "Update a project at the version read", Tag: "Projects",
+		Request: service.Project{}, Required: []string{"version"}, Response: service.Project{}},
+	"DELETE /auth/projects/{id}":      {Summary: "Delete a project", Tag: "Projects"},
+	"GET /auth/projects/{id}/billing": {Summary: "Get a project's billing", Tag: "Invoices", Response: ProjectBilling{}},
+	"PUT /auth/projects/{id}/billing": {Summary: "Update a project's billing", Tag: "Invoices",
+		Request: ProjectBilling{}, Response: ProjectBilling{}},
+
+	// Tasks
+	"GET /auth/tasks": {Summary: "List tasks with the time logged against them", Tag: "Tasks", Response: []service.TaskProgress{}},
+	"GET /auth/tasks/over-budget": {Summary: "List tasks that took longer than estimated", Tag: "Tasks",
+		Response: []service.TaskProgress{}},
+	"PUT /auth/tasks/{id}/estimate": {Summary: "Set how long a task is expected to take", Tag: "Tasks",
+		Request: taskEstimateRequest{}, Required: []string{"estimate_seconds"}, Response: service.Task{}},
+
+	// Invoices
+	"GET /auth/invoices": {Summary: "Preview or download the period's invoices", Tag: "Invoices", Response: []invoices.Invoice{}},
+	"POST /auth/invoices/exports": {Summary: "Push the period's invoices to an accounting service", Tag: "Invoices",
+		Request: invoiceRequest{}, Required: []string{"target", "start", "end"}, Response: invoices.Export{}},
+	"GET /auth/invoices/exports":      {Summary: "List recent invoice exports", Tag: "Invoices", Response: []invoices.Export{}},
+	"GET /auth/invoices/exports/{id}": {Summary: "Get an invoice export", Tag: "Invoices", Response: invoices.Export{}},
+
+	// Sync
+	"POST /auth/sync": {Summary: "Sync data between devices", Tag: "Sync",
+		Request: service.SyncRequest{}, Response: service.SyncResponse{}, Encodings: []string{negotiate.Msgpack}},
+	"POST /auth/sync/reset": {Summary: "Start or continue a full resync", Tag: "Sync",
+		Request: service.SyncResetRequest{}, Response: service.SnapshotPage{}, Encodings: []string{negotiate.Msgpack}},
+	"GET /auth/sync/status":    {Summary: "Get sync status", Tag: "Sync", Response: SyncStatusResponse{}},
+	"GET /auth/sync/conflicts": {Summary: "List sync conflicts", Tag: "Sync", Response: pagination.Page[service.SyncConflict]{}, List: syncConflictList},
+	"GET /auth/sync/devices":   {Summary: "List devices with their sync status", Tag: "Sync", Response: pagination.Page[DeviceSyncStatus]{}, List: deviceList},
+	"GET /auth/sync/stats":     {Summary: "Get per-collection sync stats", Tag: "Sync", Response: SyncStatsResponse{}},
+	"POST /auth/maintenance/verify": {Summary: "Check your data for damage left by sync bugs and plan its repair", Tag: "Sync",
+		Response: service.ConsistencyReport{}},
+	"POST /auth/maintenance/repair": {Summary: "Apply a repair plan", Tag: "Sync",
+		Request: repairRequest{}, Required: []string{"plan"}, Response: service.RepairResult{}},
+
+	// Tags
+	"GET /auth/tags":               {Summary: "List tags", Tag: "Tags", Response: pagination.Page[service.Tag]{}, List: tagList},
+	"GET /auth/sessions/{id}/tags": {Summary: "List the tags on a session", Tag: "Tags", Response: []service.Tag{}},
+
+	// Tracking rules
+	"POST /auth/rules": {Summary: "Create a tracking rule", Tag: "Tracking rules",
+		Request: ruleRequest{}, Required: []string{"name", "match_type", "pattern"}, Response: rules.Rule{}},
+	"GET /auth/rules": {Summary: "List tracking rules in evaluation order", Tag: "Tracking rules", Response: []rules.Rule{}},
+	"PUT /auth/rules/{id}": {Summary: "Replace a tracking rule", Tag: "Tracking rules",
+		Request: ruleRequest{}, Required: []string{"name", "match_type", "pattern"}, Response: rules.Rule{}},
+	"DELETE /auth/rules/{id}": {Summary: "Delete a tracking rule", Tag: "Tracking rules"},
+	"POST /auth/rules/preview": {Summary: "Preview the tracking rules on past sessions", Tag: "Tracking rules",
+		Request: ruleHistoryRequest{}, Required: []string{"start", "end"}, Response: RuleHistoryResponse{}},
+	"POST /auth/rules/apply": {Summary: "Apply the tracking rules to past sessions", Tag: "Tracking rules",
+		Request: ruleHistoryRequest{}, Required: []string{"start", "end"}, Response: RuleHistoryResponse{}},
+
+	// Time off
+	"POST /auth/time-off": {Summary: "Record time off", Tag: "Time off",
+		Request: service.TimeOff{}, Required: []string{"type", "start_date", "end_date"}, Response: service.TimeOff{}},
+	"GET /auth/time-off": {Summary: "List your time off by start date", Tag: "Time off", Response: []service.TimeOff{}},
+	"PUT /auth/time-off/{id}": {Summary: "Replace time off", Tag: "Time off",
+		Request: service.TimeOff{}, Required: []string{"type", "start_date", "end_date"}, Response: service.TimeOff{}},
+	"DELETE /auth/time-off/{id}": {Summary: "Delete time off", Tag: "Time off"},
+
+	// Admin
+	"GET /admin/stats":  {Summary: "Get usage statistics per day", Tag: "Admin", Response: opstats.Stats{}},
+	"GET /admin/health": {Summary: "Get the database's health", Tag: "Admin", Response: opstats.Health{}},
+	"GET /admin/tasks":  {Summary: "List recurring tasks and their latest run", Tag: "Admin", Response: []scheduler.Status{}},
+	"GET /admin/audit":  {Summary: "List the audit log of every user", Tag: "Admin", Response: pagination.Page[audit.Entry]{}, List: auditList},
+	"POST /admin/impersonate": {Summary: "Issue a short-lived token acting as a user", Tag: "Admin",
+		Request: impersonateRequest{}, Required: []string{"user_id", "reason"}, Response: ImpersonationResponse{}},
+
+	// Batches
+	"POST /batch": {Summary: "Run up to 20 requests in one round trip", Tag: "Batches",
+		Request: batchRequest{}, Required: []string{"requests"}, Response: batchResponse{}},
+
+	// Organizations
+	"POST /auth/organizations": {Summary: "Create an organization", Tag: "Organizations",
+		Request: organizationRequest{}, Required: []string{"name"}, Response: models.Organization{}},
+	"GET /auth/organizations":      {Summary: "List your organizations", Tag: "Organizations", Response: []models.Organization{}},
+	"GET /auth/organizations/{id}": {Summary: "Get an organization", Tag: "Organizations", Response: models.Organization{}},
+	"PUT /auth/organizations/{id}": {Summary: "Rename an organization", Tag: "Organizations",
+		Request: organizationRequest{}, Required: []string{"name"}, Response: models.Organization{}},
+	"DELETE /auth/organizations/{id}":       {Summary: "Delete an organization", Tag: "Organizations"},
+	"GET /auth/organizations/{id}/settings": {Summary: "Get an organization's settings", Tag: "Organizations", Response: models.OrganizationSettings{}},
+	"PUT /auth/organizations/{id}/settings": {Summary: "Update an organization's settings", Tag: "Organizations",
+		Request: models.OrganizationSettings{}, Response: models.OrganizationSettings{}},
+	"GET /auth/organizations/{id}/members": {Summary: "List members", Tag: "Organizations", Response: []models.Member{}},
+	"POST /auth/organizations/{id}/members": {Summary: "Add a member", Tag: "Organizations",
+		Request: addMemberRequest{}, Required: []string{"email"}},
+	"GET /auth/organizations/{id}/members/activity": {Summary: "List members with their activity", Tag: "Organizations",
+		Response: pagination.Page[models.MemberActivity]{}, List: models.MemberActivityList},
+	"GET /auth/organizations/{id}/capacity": {Summary: "Compare members' tracked time with their working hours", Tag: "Organizations",
+		Response: []models.MemberCapacity{}},
+	"GET /auth/organizations/{id}/presence": {Summary: "List members with a timer running on the organization's projects", Tag: "Organizations",
+		Response: Presence{}},
+	"PUT /auth/organizations/{id}/members/{userID}": {Summary: "Change a member's role", Tag: "Organizations",
+		Request: updateMemberRequest{}, Required: []string{"role"}, Response: models.Member{}},
+	"DELETE /auth/organizations/{id}/members/{userID}":          {Summary: "Remove a member", Tag: "Organizations"},
+	"POST /auth/organizations/{id}/members/{userID}/deactivate": {Summary: "Deactivate a member", Tag: "Organizations"},
+	"POST /auth/organizations/{id}/members/{userID}/reactivate": {Summary: "Reactivate a member", Tag: "Organizations"},
+	"POST /auth/organizations/{id}/members/{userID}/transfer": {Summary: "Hand a member's projects over to another member", Tag: "Organizations",
+		Request: transferProjectsRequest{}, Required: []string{"to_user_id"}, Response: map[string]int64{}},
+
+	// Kiosks
+	"POST /auth/organizations/{id}/kiosks": {Summary: "Register a kiosk and get its token", Tag: "Kiosks",
+		Request: createKioskRequest{}, Required: []string{"name", "project_id"}, Response: CreatedKiosk{}},
+	"GET /auth/organizations/{id}/kiosks":              {Summary: "List kiosks", Tag: "Kiosks", Response: []models.Kiosk{}},
+	"DELETE /auth/organizations/{id}/kiosks/{kioskID}": {Summary: "Remove a kiosk, revoking its token", Tag: "Kiosks"},
+	"POST /auth/organizations/{id}/kiosk-pin":          {Summary: "Get a new kiosk PIN", Tag: "Kiosks", Response: KioskPIN{}},
+	"DELETE /auth/organizations/{id}/kiosk-pin":        {Summary: "Remove your kiosk PIN", Tag: "Kiosks"},
+	"POST /kiosk/clock-in": {Summary: "Clock a member in by PIN (kiosk token)", Tag: "Kiosks", Public: true,
+		Request: kioskPINRequest{}, Required: []string{"pin"}, Response: KioskClock{}},
+	"POST /kiosk/clock-out": {Summary: "Clock a member out by PIN (kiosk token)", Tag: "Kiosks", Public: true,
+		Request: kioskPINRequest{}, Required: []string{"pin"}, Response: KioskClock{}},
+
+	// Project transfers
+	"POST /auth/transfer": {Summary: "Offer projects to another user or organization", Tag: "Project transfers",
+		Request: createTransferRequest{}, Required: []string{"project_ids"}, Response: Transfer{}},
+	"GET /auth/transfer":               {Summary: "List transfers", Tag: "Project transfers", Response: []Transfer{}},
+	"POST /auth/transfer/{id}/accept":  {Summary: "Accept a transfer", Tag: "Project transfers", Response: Transfer{}},
+	"POST /auth/transfer/{id}/decline": {Summary: "Decline a transfer", Tag: "Project transfers"},
+	"DELETE /auth/transfer/{id}":       {Summary: "Cancel a transfer", Tag: "Project transfers"},
+
+	// OAuth apps
+	"POST /auth/oauth/clients": {Summary: "Register an app", Tag: "OAuth apps",
+		Request: createOAuthClientRequest{}, Required: []string{"name", "redirect_uris"}, Response: oauth.Client{}},
+	"GET /auth/oauth/clients":         {Summary: "List your apps", Tag: "OAuth apps", Response: []oauth.Client{}},
+	"DELETE /auth/oauth/clients/{id}": {Summary: "Delete an app", Tag: "OAuth apps"},
+	"GET /auth/oauth/authorize":       {Summary: "Describe an authorization request for the consent screen", Tag: "OAuth apps", Response: consentResponse{}},
+	"POST /auth/oauth/authorize": {Summary: "Approve or deny an authorization request", Tag: "OAuth apps",
+		Request: authorizeRequest{}, Required: []string{"client_id", "redirect_uri"}, Response: map[string]string{}},
+	"GET /auth/oauth/authorizations":               {Summary: "List the apps you granted access to", Tag: "OAuth apps", Response: []oauth.Authorization{}},
+	"DELETE /auth/oauth/authorizations/{clientID}": {Summary: "Revoke an app's access", Tag: "OAuth apps"},
+	"POST /oauth/token":                            {Summary: "Exchange a code or refresh token (form encoded)", Tag: "OAuth apps", Public: true, Response: oauth.TokenResponse{}},
+	"POST /oauth/revoke":                           {Summary: "Revoke a refresh token (form encoded)", Tag: "OAuth apps", Public: true},
+
+	// Webhooks
+	"POST /auth/hooks": {Summary: "Subscribe a URL to an event", Tag: "Webhooks",
+		Request: subscribeWebhookRequest{}, Required: []string{"target_url", "event"}, Response: webhooks.Webhook{}},
+	"GET /auth/hooks":         {Summary: "List webhooks", Tag: "Webhooks", Response: pagination.Page[webhooks.Webhook]{}, List: webhooks.Listing},
+	"DELETE /auth/hooks/{id}": {Summary: "Unsubscribe a webhook", Tag: "Webhooks"},
+	"GET /auth/hooks/{id}/deliveries": {Summary: "List a webhook's deliveries", Tag: "Webhooks",
+		Response: pagination.Page[webhooks.Delivery]{}, List: webhooks.DeliveryListing},
+	"POST /auth/hooks/deliveries/{id}/retry": {Summary: "Redeliver a webhook delivery", Tag: "Webhooks", Response: webhooks.Delivery{}},
+	"GET /auth/hooks/events":                 {Summary: "List webhook events", Tag: "Webhooks", Response: []string{}},
+	"GET /auth/hooks/samples/{event}":        {Summary: "Get sample payloads of an event", Tag: "Webhooks"},
+
+	// Imports
+	"POST /auth/import/toggl": {Summary: "Import from Toggl Track", Tag: "Imports",
+		Request: togglImportRequest{}, Required: []string{"api_token"}, Response: imports.Job{}},
+	"POST /auth/import/csv": {Summary: "Import a CSV file (multipart form)", Tag: "Imports", Response: imports.Job{}},
+	"GET /auth/import":      {Summary: "List import jobs", Tag: "Imports", Response: []imports.Job{}},
+	"GET /auth/import/{id}": {Summary: "Get an import job", Tag: "Imports", Response: imports.Job{}},
+
+	// Uploads
+	"POST /auth/uploads": {Summary: "Get a URL to upload an avatar or project icon to", Tag: "Uploads",
+		Request: uploadRequest{}, Required: []string{"purpose", "content_type", "size"}, Response: uploadTarget{}},
+	"GET /auth/uploads/{id}": {Summary: "Get an upload and, once processed, its image URL", Tag: "Uploads",
+		Response: uploads.Upload{}},
+	"POST /auth/uploads/{id}/complete": {Summary: "Process an uploaded file", Tag: "Uploads", Response: uploads.Upload{}},
+
+	// Integrations
+	"POST /auth/integrations/google-calendar/connect": {Summary: "Start linking Google Calendar", Tag: "Google Calendar", Response: map[string]string{}},
+	"GET /integrations/google-calendar/callback":      {Summary: "OAuth callback", Tag: "Google Calendar", Public: true},
+	"GET /auth/integrations/google-calendar":          {Summary: "Get the Google Calendar link", Tag: "Google Calendar", Response: googleCalendarStatus{}},
+	"PUT /auth/integrations/google-calendar": {Summary: "Update Google Calendar settings", Tag: "Google Calendar",
+		Request: integrations.GoogleCalendarSettings{}, Response: googleCalendarStatus{}},
+	"DELETE /auth/integrations/google-calendar": {Summary: "Unlink Google Calendar", Tag: "Google Calendar"},
+	"GET /auth/integrations/suggestions":        {Summary: "List pending suggested sessions", Tag: "Google Calendar", Response: []SuggestedSession{}},
+	"POST /auth/integrations/suggestions/{id}/confirm": {Summary: "Create a session from a suggestion", Tag: "Google Calendar",
+		Request: confirmSuggestionRequest{}, Response: service.Session{}},
+	"POST /auth/integrations/suggestions/{id}/dismiss": {Summary: "Dismiss a suggestion", Tag: "Google Calendar"},
+
+	"POST /auth/integrations/jira": {Summary: "Link Jira", Tag: "Jira",
+		Request: connectJiraRequest{}, Required: []string{"site_url", "email", "api_token"}, Response: jiraStatus{}},
+	"GET /auth/integrations/jira": {Summary: "Get the Jira link", Tag: "Jira", Response: jiraStatus{}},
+	"PUT /auth/integrations/jira": {Summary: "Update Jira settings", Tag: "Jira",
+		Request: struct {
+			ExportSessions *bool `json:"export_sessions"`
+		}{}, Response: jiraStatus{}},
+	"DELETE /auth/integrations/jira":              {Summary: "Unlink Jira", Tag: "Jira"},
+	"GET /auth/integrations/jira/worklogs":        {Summary: "List the export state of sessions", Tag: "Jira", Response: []integrations.JiraWorklog{}},
+	"POST /auth/integrations/jira/worklogs/retry": {Summary: "Queue failed exports again", Tag: "Jira", Response: map[string]int64{}},
+	"PUT /auth/integrations/jira/sessions/{id}": {Summary: "Set a session's issue key", Tag: "Jira",
+		Request: jiraIssueKeyRequest{}},
+
+	"POST /auth/integrations/email":    {Summary: "Create your inbound email address", Tag: "Inbound email", Response: emailStatus{}},
+	"GET /auth/integrations/email":     {Summary: "Get your inbound email address", Tag: "Inbound email", Response: emailStatus{}},
+	"DELETE /auth/integrations/email":  {Summary: "Remove your inbound email address", Tag: "Inbound email"},
+	"POST /integrations/email/inbound": {Summary: "Mailgun inbound webhook (form encoded)", Tag: "Inbound email", Public: true},
+
+	"POST /auth/integrations/quickbooks/connect": {Summary: "Start linking QuickBooks", Tag: "Invoices", Response: map[string]string{}},
+	"GET /integrations/quickbooks/callback":      {Summary: "OAuth callback", Tag: "Invoices", Public: true},
+	"GET /auth/integrations/quickbooks":          {Summary: "Get the QuickBooks link", Tag: "Invoices", Response: integrations.Account{}},
+	"PUT /auth/integrations/quickbooks": {Summary: "Set the item invoice lines are booked to", Tag: "Invoices",
+		Request: struct {
+			ItemID string `json:"item_id"`
+		}{}, Required: []string{"item_id"}, Response: integrations.Account{}},
+	"DELETE /auth/integrations/quickbooks": {Summary: "Unlink QuickBooks", Tag: "Invoices"},
+	"POST /auth/integrations/xero/connect": {Summary: "Start linking Xero", Tag: "Invoices", Response: map[string]string{}},
+	"GET /integrations/xero/callback":      {Summary: "OAuth callback", Tag: "Invoices", Public: true},
+	"GET /auth/integrations/xero":          {Summary: "Get the Xero link", Tag: "Invoices", Response: integrations.Account{}},
+	"PUT /auth/integrations/xero": {Summary: "Set the account invoice lines are booked to", Tag: "Invoices",
+		Request: struct {
+			AccountCode string `json:"account_code"`
+		}{}, Required: []string{"account_code"}, Response: integrations.Account{}},
+	"DELETE /auth/integrations/xero": {Summary: "Unlink Xero", Tag: "Invoices"},
+
+	"POST /auth/integrations/notion": {Summary: "Link a Notion database", Tag: "Notion",
+		Request: connectNotionRequest{}, Required: []string{"token", "database_id"}, Response: notionStatus{}},
+	"GET /auth/integrations/notion": {Summary: "Get the Notion link", Tag: "Notion", Response: notionStatus{}},
+	"PUT /auth/integrations/notion": {Summary: "Update Notion settings", Tag: "Notion",
+		Request: integrations.NotionSettings{}, Response: notionStatus{}},
+	"DELETE /auth/integrations/notion": {Summary: "Unlink Notion", Tag: "Notion"},
+
+	"POST /auth/integrations/wakatime": {Summary: "Link WakaTime and get an API key", Tag: "WakaTime", Response: map[string]interface{}{}},
+	"GET /auth/integrations/wakatime":  {Summary: "Get the WakaTime link", Tag: "WakaTime", Response: wakaTimeStatus{}},
+	"PUT /auth/integrations/wakatime": {Summary: "Update WakaTime settings", Tag: "WakaTime",
+		Request: integrations.WakaTimeSettings{}, Response: wakaTimeStatus{}},
+	"DELETE /auth/integrations/wakatime": {Summary: "Unlink WakaTime", Tag: "WakaTime"},
+	"POST /integrations/wakatime/users/current/heartbeats": {Summary: "Receive a heartbeat (WakaTime API key)", Tag: "WakaTime", Public: true,
+		Request: integrations.Heartbeat{}},
+	"POST /integrations/wakatime/users/current/heartbeats.bulk": {Summary: "Receive heartbeats (WakaTime API key)", Tag: "WakaTime", Public: true,
+		Request: []integrations.Heartbeat{}},
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+func GetOrganizationSettings(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := loadOrganization(w, r, "")
+	if !ok {
+		return
+	}
+
+	settings, err := models.GetOrganizationSettings(r.Context(), org.ID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch organization settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// UpdateOrganizationSettings replaces the settings document. Fields missing
+// from the request keep their current value.
+func UpdateOrganizationSettings(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := loadOrganization(w, r, auth.PermManageSettings)
+	if !ok {
+		return
+	}
+
+	settings, err := models.GetOrganizationSettings(r.Context(), org.ID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch organization settings", http.StatusInternalServerError)
+		return
+	}
+	if !decodeJSON(w, r, &settings) {
+		return
+	}
+
+	if err := models.UpdateOrganizationSettings(r.Context(), org.ID, settings); err != nil {
+		apierror.Error(w, r, "Failed to update organization settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
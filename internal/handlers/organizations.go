@@ -0,0 +1,497 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/billing"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+type organizationRequest struct {
+	Name string `json:"name"`
+}
+
+func (req *organizationRequest) Validate(v *validate.Validator) {
+	req.Name = strings.TrimSpace(req.Name)
+	v.Required("name", req.Name)
+	v.MaxLength("name", req.Name, service.MaxNameLength)
+}
+
+type addMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+func (req *addMemberRequest) Validate(v *validate.Validator) {
+	req.Email = strings.TrimSpace(req.Email)
+	v.Required("email", req.Email)
+	v.Email("email", req.Email)
+}
+
+type updateMemberRequest struct {
+	Role string `json:"role"`
+}
+
+type transferProjectsRequest struct {
+	ToUserID uuid.UUID `json:"to_user_id"`
+}
+
+func (req *transferProjectsRequest) Validate(v *validate.Validator) {
+	v.UUID("to_user_id", req.ToUserID)
+}
+
+func CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req organizationRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	org, err := models.CreateOrganization(r.Context(), req.Name, userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to create organization", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(org)
+}
+
+func ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	orgs, err := models.ListOrganizations(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch organizations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orgs)
+}
+
+func GetOrganization(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := loadOrganization(w, r, "")
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(org)
+}
+
+func UpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := loadOrganization(w, r, auth.PermManageSettings)
+	if !ok {
+		return
+	}
+
+	var req organizationRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	org, err := models.RenameOrganization(r.Context(), org.ID, req.Name)
+	if err != nil {
+		apierror.Error(w, r, "Failed to update organization", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(org)
+}
+
+func DeleteOrganization(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := loadOrganization(w, r, auth.PermManageOrganization)
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteOrganization(r.Context(), org.ID); err != nil {
+		apierror.Error(w, r, "Failed to delete organization", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func ListMembers(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := loadOrganization(w, r, "")
+	if !ok {
+		return
+	}
+
+	members, err := models.ListMembers(r.Context(), org.ID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch members", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+// AddMember adds a user by email. Only owners may add other owners.
+func AddMember(w http.ResponseWriter, r *http.Request) {
+	org, role, ok := loadOrganization(w, r, auth.PermManageMembers)
+	if !ok {
+		return
+	}
+
+	var req addMemberRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.RoleMember
+	}
+	if !models.ValidRole(req.Role) {
+		apierror.Error(w, r, "Invalid role", http.StatusBadRequest)
+		return
+	}
+	if req.Role == models.RoleOwner && !auth.RoleHas(role, auth.PermManageOrganization) {
+		apierror.Error(w, r, "Only owners can add owners", http.StatusForbidden)
+		return
+	}
+
+	user, err := models.GetUserByEmail(r.Context(), req.Email)
+	if err != nil {
+		apierror.Error(w, r, "User not found", http.StatusNotFound)
+		return
+	}
+
+	current, err := models.GetMemberRole(r.Context(), org.ID, user.ID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch member", http.StatusInternalServerError)
+		return
+	}
+	if current == "" && !checkTeamSize(w, r, org.ID) {
+		return
+	}
+
+	if err := models.AddMember(r.Context(), org.ID, user.ID, req.Role); err != nil {
+		apierror.Error(w, r, "Failed to add member", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveMember removes a member from an organization. Members may remove
+// themselves; removing others requires managing members, and only owners may
+// remove owners. The last owner cannot leave.
+func RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	memberID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var perm auth.Permission
+	if memberID != userID {
+		perm = auth.PermManageMembers
+	}
+	org, role, ok := loadOrganization(w, r, perm)
+	if !ok {
+		return
+	}
+
+	memberRole, err := models.GetMemberRole(r.Context(), org.ID, memberID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch member", http.StatusInternalServerError)
+		return
+	}
+	if memberRole == models.RoleOwner {
+		if memberID != userID && !auth.RoleHas(role, auth.PermManageOrganization) {
+			apierror.Error(w, r, "Only owners can remove owners", http.StatusForbidden)
+			return
+		}
+		owners, err := models.CountOwners(r.Context(), org.ID)
+		if err != nil {
+			apierror.Error(w, r, "Failed to fetch members", http.StatusInternalServerError)
+			return
+		}
+		if owners <= 1 {
+			apierror.Error(w, r, "An organization needs at least one owner", http.StatusBadRequest)
+			return
+		}
+	}
+
+	removed, err := models.RemoveMember(r.Context(), org.ID, memberID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to remove member", http.StatusInternalServerError)
+		return
+	}
+	if !removed {
+		apierror.Error(w, r, "Member not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListMemberActivity lists members, including deactivated ones, with the
+// number of sessions and time they logged against the organization's
+// projects. The time is also given rounded when the organization rounds
+// reports.
+func ListMemberActivity(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := loadOrganization(w, r, auth.PermManageMembers)
+	if !ok {
+		return
+	}
+
+	q, ok := listParams(w, r, models.MemberActivityList)
+	if !ok {
+		return
+	}
+
+	settings, err := models.GetOrganizationSettings(r.Context(), org.ID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch organization settings", http.StatusInternalServerError)
+		return
+	}
+
+	members, err := models.ListMemberActivity(r.Context(), org.ID, settings.Rounding(models.RoundReports), q)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch members", http.StatusInternalServerError)
+		return
+	}
+
+	writePage(w, r, members, q)
+}
+
+// UpdateMember changes a member's role. Only owners may grant or revoke the
+// owner role, and the last owner cannot be demoted.
+func UpdateMember(w http.ResponseWriter, r *http.Request) {
+	org, role, ok := loadOrganization(w, r, auth.PermManageMembers)
+	if !ok {
+		return
+	}
+	member, ok := loadMember(w, r, org.ID)
+	if !ok {
+		return
+	}
+
+	var req updateMemberRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !models.ValidRole(req.Role) {
+		apierror.Error(w, r, "Invalid role", http.StatusBadRequest)
+		return
+	}
+	if (req.Role == models.RoleOwner || member.Role == models.RoleOwner) && !auth.RoleHas(role, auth.PermManageOrganization) {
+		apierror.Error(w, r, "Only owners can change owners", http.StatusForbidden)
+		return
+	}
+	if member.Role == models.RoleOwner && req.Role != models.RoleOwner && !keepsOwner(w, r, org.ID, member) {
+		return
+	}
+
+	if err := models.SetMemberRole(r.Context(), org.ID, member.UserID, req.Role); err != nil {
+		apierror.Error(w, r, "Failed to update member", http.StatusInternalServerError)
+		return
+	}
+	member.Role = req.Role
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(member)
+}
+
+// DeactivateMember revokes a member's access to the organization while
+// keeping their membership and logged time
+func DeactivateMember(w http.ResponseWriter, r *http.Request) {
+	setMemberActive(w, r, false)
+}
+
+// ReactivateMember restores a deactivated member's access
+func ReactivateMember(w http.ResponseWriter, r *http.Request) {
+	setMemberActive(w, r, true)
+}
+
+func setMemberActive(w http.ResponseWriter, r *http.Request, active bool) {
+	org, role, ok := loadOrganization(w, r, auth.PermManageMembers)
+	if !ok {
+		return
+	}
+	member, ok := loadMember(w, r, org.ID)
+	if !ok {
+		return
+	}
+
+	if member.Role == models.RoleOwner && !auth.RoleHas(role, auth.PermManageOrganization) {
+		apierror.Error(w, r, "Only owners can change owners", http.StatusForbidden)
+		return
+	}
+	if !active && member.Role == models.RoleOwner && !keepsOwner(w, r, org.ID, member) {
+		return
+	}
+	if active && member.DeactivatedAt != nil && !checkTeamSize(w, r, org.ID) {
+		return
+	}
+
+	if err := models.SetMemberActive(r.Context(), org.ID, member.UserID, active); err != nil {
+		apierror.Error(w, r, "Failed to update member", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TransferMemberProjects hands the organization projects owned by a member
+// over to another active member, e.g. before the member leaves
+func TransferMemberProjects(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := loadOrganization(w, r, auth.PermManageMembers)
+	if !ok {
+		return
+	}
+	member, ok := loadMember(w, r, org.ID)
+	if !ok {
+		return
+	}
+
+	var req transferProjectsRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.ToUserID == member.UserID {
+		apierror.Error(w, r, "Cannot transfer projects to the same member", http.StatusBadRequest)
+		return
+	}
+	toRole, err := models.GetMemberRole(r.Context(), org.ID, req.ToUserID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch member", http.StatusInternalServerError)
+		return
+	}
+	if toRole == "" {
+		apierror.Error(w, r, "Recipient is not an active member", http.StatusBadRequest)
+		return
+	}
+
+	count, err := models.TransferProjects(r.Context(), org.ID, member.UserID, req.ToUserID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to transfer projects", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"transferred": count})
+}
+
+// checkTeamSize checks that the organization's plan allows another active
+// member. It writes the error response and returns false otherwise.
+func checkTeamSize(w http.ResponseWriter, r *http.Request, orgID uuid.UUID) bool {
+	userID := auth.GetUserIDFromContext(r.Context())
+	ws, err := billing.WorkspaceOf(r.Context(), userID, &orgID)
+	if err == nil {
+		var count int
+		count, err = models.CountActiveMembers(r.Context(), orgID)
+		if err == nil {
+			err = ws.CheckCount(billing.LimitTeamMembers, count+1)
+		}
+	}
+	return checkLimit(w, r, err)
+}
+
+// loadMember fetches the member named in the URL. It writes the error
+// response and returns false on failure.
+func loadMember(w http.ResponseWriter, r *http.Request, orgID uuid.UUID) (*models.Member, bool) {
+	memberID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid user ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	member, err := models.GetMember(r.Context(), orgID, memberID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch member", http.StatusInternalServerError)
+		return nil, false
+	}
+	if member == nil {
+		apierror.Error(w, r, "Member not found", http.StatusNotFound)
+		return nil, false
+	}
+	return member, true
+}
+
+// keepsOwner checks that the organization still has an active owner once
+// member stops being one. It writes the error response and returns false
+// otherwise.
+func keepsOwner(w http.ResponseWriter, r *http.Request, orgID uuid.UUID, member *models.Member) bool {
+	if member.DeactivatedAt != nil {
+		return true
+	}
+	owners, err := models.CountOwners(r.Context(), orgID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch members", http.StatusInternalServerError)
+		return false
+	}
+	if owners <= 1 {
+		apierror.Error(w, r, "An organization needs at least one owner", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// loadOrganization fetches the organization named in the URL along with the
+// user's role in it, and checks that the role grants perm (any member passes
+// an empty perm). It writes the error response and returns false on failure.
+func loadOrganization(w http.ResponseWriter, r *http.Request, perm auth.Permission) (*models.Organization, string, bool) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return nil, "", false
+	}
+
+	orgID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid organization ID", http.StatusBadRequest)
+		return nil, "", false
+	}
+
+	role, err := models.GetMemberRole(r.Context(), orgID, userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to verify organization membership", http.StatusInternalServerError)
+		return nil, "", false
+	}
+	if role == "" {
+		apierror.Error(w, r, "Organization not found", http.StatusNotFound)
+		return nil, "", false
+	}
+
+	org, err := models.GetOrganization(r.Context(), orgID)
+	if errors.Is(err, models.ErrOrganizationNotFound) {
+		apierror.Error(w, r, "Organization not found", http.StatusNotFound)
+		return nil, "", false
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch organization", http.StatusInternalServerError)
+		return nil, "", false
+	}
+
+	if perm != "" && !auth.RoleHas(role, perm) {
+		apierror.Error(w, r, "Insufficient permissions", http.StatusForbidden)
+		return nil, "", false
+	}
+	return org, role, true
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+	"github.com/pacerclub/zebra-backend/internal/negotiate"
+	"github.com/pacerclub/zebra-backend/internal/pagination"
+)
+
+// listParams reads the sort, fields and page a list request asks for. It
+// writes the error response and returns false if a parameter is malformed.
+func listParams[T any](w http.ResponseWriter, r *http.Request, list listquery.List[T]) (listquery.Query, bool) {
+	q, err := list.Parse(r.URL.Query())
+	switch err {
+	case nil:
+		return q, true
+	case pagination.ErrInvalidLimit:
+		apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidRequest, "Invalid limit",
+			map[string]interface{}{"parameter": "limit", "max": pagination.MaxLimit})
+	case listquery.ErrInvalidSort:
+		apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidRequest, "Invalid sort",
+			map[string]interface{}{"parameter": "sort", "supported": list.SortFields()})
+	case listquery.ErrInvalidFields:
+		apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidRequest, "Invalid fields",
+			map[string]interface{}{"parameter": "fields"})
+	default:
+		apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidRequest, "Invalid cursor",
+			map[string]interface{}{"parameter": "cursor"})
+	}
+	return listquery.Query{}, false
+}
+
+// writePage writes one page of a list with the fields the query selected,
+// in the negotiated encoding
+func writePage[T any](w http.ResponseWriter, r *http.Request, page pagination.Page[T], q listquery.Query) {
+	if negotiate.Response(r) == negotiate.Msgpack {
+		body, err := listquery.SelectMsgpack(page, q.Fields)
+		if err != nil {
+			apierror.Error(w, r, "Failed to encode list", http.StatusInternalServerError)
+			return
+		}
+		writeBody(w, negotiate.Msgpack, http.StatusOK, body)
+		return
+	}
+
+	body, err := listquery.Select(page, q.Fields)
+	if err != nil {
+		apierror.Error(w, r, "Failed to encode list", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// GetPreferences returns the user's preferences, with defaults for those
+// never set
+func GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	preferences, err := models.GetPreferences(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch preferences", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preferences)
+}
+
+// UpdatePreferences changes the preferences in the request. Fields missing
+// from it keep their current value, as do the fields inside rounding,
+// reminders and working_hours; only changed preferences are stored and
+// synced.
+func UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	current, err := models.GetPreferences(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch preferences", http.StatusInternalServerError)
+		return
+	}
+	// Decoding reuses the slices' arrays, which current still needs
+	preferences := current
+	preferences.Rounding.ApplyTo = append([]string(nil), current.Rounding.ApplyTo...)
+	preferences.Reminders.Weekdays = append([]int(nil), current.Reminders.Weekdays...)
+	preferences.WorkingHours.Minutes = append([]int(nil), current.WorkingHours.Minutes...)
+	if !decodeJSON(w, r, &preferences) {
+		return
+	}
+
+	if id := preferences.DefaultProjectID; id != nil && (current.DefaultProjectID == nil || *id != *current.DefaultProjectID) {
+		var exists bool
+		err := db.GetDB().QueryRow(r.Context(), `
+			SELECT EXISTS (SELECT 1 FROM projects WHERE id = $2 AND `+service.SyncedProjectSQL(1)+` AND is_deleted = false)
+		`, userID, *id).Scan(&exists)
+		if err != nil {
+			apierror.Error(w, r, "Failed to check project", http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			writeValidationError(w, r, validate.Errors{{Field: "default_project_id", Message: "default_project_id must be one of your projects"}})
+			return
+		}
+	}
+
+	if err := models.UpdatePreferences(r.Context(), userID, current, preferences); err != nil {
+		apierror.Error(w, r, "Failed to update preferences", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preferences)
+}
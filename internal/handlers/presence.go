@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+)
+
+// Presence is who in an organization has a timer running on one of its
+// projects
+type Presence struct {
+	OrganizationID uuid.UUID       `json:"organization_id"`
+	Members        []PresentMember `json:"members"`
+}
+
+// PresentMember is an active member's timer running on one of the
+// organization's projects. Its description stays private.
+type PresentMember struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"display_name"`
+	ProjectID   uuid.UUID `json:"project_id"`
+	// ProjectName is unset for projects encrypted by their clients
+	ProjectName  string    `json:"project_name,omitempty"`
+	ProjectColor string    `json:"project_color"`
+	StartTime    time.Time `json:"start_time"`
+	Elapsed      int64     `json:"elapsed_seconds"`
+}
+
+// GetOrganizationPresence lists the members with a timer running on the
+// organization's projects, longest running first
+func GetOrganizationPresence(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := loadOrganization(w, r, "")
+	if !ok {
+		return
+	}
+
+	presence, err := organizationPresence(r.Context(), org.ID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch presence", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(presence)
+}
+
+// organizationPresence returns who has a timer running on the
+// organization's projects. Timers on other projects are left out, as they
+// are none of the organization's business.
+func organizationPresence(ctx context.Context, orgID uuid.UUID) (*Presence, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT u.id, u.email, u.display_name, p.id, CASE WHEN p.key_id = '' THEN p.name END, p.color, t.start_time,
+			GREATEST(EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - t.start_time))::bigint, 0)
+		FROM running_timers t
+		JOIN projects p ON p.id = t.project_id
+		JOIN memberships m ON m.user_id = t.user_id AND m.organization_id = p.organization_id
+		JOIN users u ON u.id = t.user_id
+		WHERE p.organization_id = $1 AND p.is_deleted = false AND m.deactivated_at IS NULL
+		ORDER BY t.start_time, u.email
+	`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	presence := &Presence{OrganizationID: orgID, Members: []PresentMember{}}
+	for rows.Next() {
+		var member PresentMember
+		var name *string
+		err := rows.Scan(&member.UserID, &member.Email, &member.DisplayName, &member.ProjectID, &name,
+			&member.ProjectColor, &member.StartTime, &member.Elapsed)
+		if err != nil {
+			return nil, err
+		}
+		if name != nil {
+			if member.ProjectName, err = fieldcrypt.Open(fieldcrypt.ProjectName, *name); err != nil {
+				return nil, err
+			}
+		}
+		presence.Members = append(presence.Members, member)
+	}
+	return presence, rows.Err()
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+// GetProfile returns the authenticated user's account and profile
+func GetProfile(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := models.GetUserByID(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch user", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// UpdateProfile replaces the user's profile. Fields missing from the request
+// keep their current value.
+func UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := models.GetUserByID(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch user", http.StatusInternalServerError)
+		return
+	}
+	profile := user.Profile
+	if !decodeJSON(w, r, &profile) {
+		return
+	}
+
+	user, err = models.UpdateProfile(r.Context(), userID, profile)
+	if err != nil {
+		apierror.Error(w, r, "Failed to update profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
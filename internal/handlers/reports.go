@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/service"
+)
+
+// reportPeriod reads a report's period from ?start= and ?end=, inclusive
+// YYYY-MM-DD dates in ?timezone= (UTC by default), and returns the
+// midnights it runs between. It writes the error response and returns false
+// if they are invalid.
+func reportPeriod(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	loc, ok := importLocation(w, r)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	query := r.URL.Query()
+	start, err := time.ParseInLocation("2006-01-02", query.Get("start"), loc)
+	if err != nil {
+		apierror.Error(w, r, "start must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, false
+	}
+	end, err := time.ParseInLocation("2006-01-02", query.Get("end"), loc)
+	if err != nil {
+		apierror.Error(w, r, "end must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, false
+	}
+	end = end.AddDate(0, 0, 1)
+	if !end.After(start) || end.Sub(start) > service.MaxReportPeriod {
+		apierror.Error(w, r, "The period must run forward and cover at most a year", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, false
+	}
+	return start, end, true
+}
+
+// GetHoursReport compares the time the user tracked in the active scope
+// with their working hours, day by day, showing overtime
+func GetHoursReport(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	start, end, ok := reportPeriod(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := service.GetHoursReport(r.Context(), userID, start, end)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetEstimateReport measures how well the user's completed tasks in the
+// active scope kept to their estimates
+func GetEstimateReport(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	report, err := service.GetEstimateReport(r.Context(), userID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetOrganizationCapacity lists the active members with the time they
+// logged against the organization's projects over a period and the time
+// their working hours expect, showing how much of their capacity was used
+func GetOrganizationCapacity(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := loadOrganization(w, r, auth.PermViewReports)
+	if !ok {
+		return
+	}
+
+	start, end, ok := reportPeriod(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := models.GetOrganizationSettings(r.Context(), org.ID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch organization settings", http.StatusInternalServerError)
+		return
+	}
+
+	members, err := models.ListMemberCapacity(r.Context(), org.ID, start, end, settings.Rounding(models.RoundReports))
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch members", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
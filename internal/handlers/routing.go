@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+)
+
+// routedMethods are the methods checked when listing what a path serves
+var routedMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// NotFound answers requests for paths no route serves
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	apierror.Error(w, r, "Not found", http.StatusNotFound)
+}
+
+// MethodNotAllowed answers requests for paths of routes that do not serve
+// the method, listing the methods they do serve in the Allow header. OPTIONS
+// requests are answered with the Allow header alone; CORS preflights are
+// answered before routing.
+func MethodNotAllowed(routes chi.Routes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed := allowedMethods(routes, r.URL.Path)
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(append(allowed, http.MethodOptions), ", "))
+		}
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		apierror.Write(w, r, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed",
+			map[string]interface{}{"allowed": allowed})
+	}
+}
+
+func allowedMethods(routes chi.Routes, path string) []string {
+	allowed := []string{}
+	for _, method := range routedMethods {
+		if routes.Match(chi.NewRouteContext(), method, path) {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/rules"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// Tracking rules assign a project and tags to new sessions whose
+// description matches; see package rules. They are applied to sessions
+// created through the API, sync and imports, and to past sessions on
+// request.
+
+type ruleRequest struct {
+	Name      string      `json:"name"`
+	MatchType string      `json:"match_type"`
+	Pattern   string      `json:"pattern"`
+	ProjectID *uuid.UUID  `json:"project_id"`
+	TagIDs    []uuid.UUID `json:"tag_ids"`
+	// Position orders the rule among the user's rules, lowest first. New
+	// rules go last by default and updates keep the position.
+	Position *int `json:"position"`
+	// Enabled defaults to true
+	Enabled *bool `json:"enabled"`
+}
+
+func (req *ruleRequest) Validate(v *validate.Validator) {
+	req.Name = strings.TrimSpace(req.Name)
+	v.Required("name", req.Name)
+	v.MaxLength("name", req.Name, 255)
+	v.Check(rules.ValidMatchType(req.MatchType), "match_type", "must be one of "+strings.Join(rules.MatchTypes, ", "))
+	v.Required("pattern", strings.TrimSpace(req.Pattern))
+	v.MaxLength("pattern", req.Pattern, 500)
+	if message := rules.CheckPattern(req.MatchType, req.Pattern); message != "" {
+		v.Fail("pattern", "%s", message)
+	}
+	v.Check(req.ProjectID != nil || len(req.TagIDs) > 0, "project_id", "or tag_ids must be set")
+	v.Check(len(req.TagIDs) <= rules.MaxTags, "tag_ids", "has too many tags")
+	if req.Position != nil {
+		v.Check(*req.Position >= 0, "position", "must not be negative")
+	}
+}
+
+// rule returns the rule the request describes
+func (req *ruleRequest) rule(userID uuid.UUID) rules.Rule {
+	rule := rules.Rule{
+		UserID:    userID,
+		Name:      req.Name,
+		MatchType: req.MatchType,
+		Pattern:   req.Pattern,
+		ProjectID: req.ProjectID,
+		TagIDs:    req.TagIDs,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+	}
+	if req.Position != nil {
+		rule.Position = *req.Position
+	}
+	return rule
+}
+
+// checkRuleTargets rejects rules naming a project or tags the user cannot
+// use. It writes the error response and returns false if they are not.
+func checkRuleTargets(w http.ResponseWriter, r *http.Request, userID uuid.UUID, req *ruleRequest) bool {
+	if req.ProjectID != nil {
+		var ok bool
+		err := db.Pool.QueryRow(r.Context(), `
+			SELECT EXISTS (SELECT 1 FROM projects WHERE id = $2 AND is_deleted = false AND `+service.SyncedProjectSQL(1)+`)
+		`, userID, req.ProjectID).Scan(&ok)
+		if err != nil {
+			apierror.Error(w, r, "Failed to verify project", http.StatusInternalServerError)
+			return false
+		}
+		if !ok {
+			apierror.Error(w, r, "Project not found", http.StatusBadRequest)
+			return false
+		}
+	}
+
+	if len(req.TagIDs) > 0 {
+		var missing bool
+		err := db.Pool.QueryRow(r.Context(), `
+			SELECT EXISTS (
+				SELECT 1 FROM unnest($2::uuid[]) AS t(id)
+				WHERE NOT EXISTS (SELECT 1 FROM tags WHERE tags.id = t.id AND user_id = $1 AND is_deleted = false)
+			)
+		`, userID, req.TagIDs).Scan(&missing)
+		if err != nil {
+			apierror.Error(w, r, "Failed to verify tags", http.StatusInternalServerError)
+			return false
+		}
+		if missing {
+			apierror.Error(w, r, "Tag not found", http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
+
+// CreateRule adds a tracking rule, by default after the user's other rules
+func CreateRule(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ruleRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !checkRuleTargets(w, r, userID, &req) {
+		return
+	}
+
+	count, err := rules.Count(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to create rule", http.StatusInternalServerError)
+		return
+	}
+	if count >= rules.MaxPerUser {
+		apierror.Write(w, r, http.StatusConflict, apierror.LimitReached, "Too many tracking rules", nil)
+		return
+	}
+
+	rule, err := rules.Create(r.Context(), req.rule(userID), req.Position)
+	if err != nil {
+		apierror.Error(w, r, "Failed to create rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// ListRules returns the user's tracking rules in the order they are
+// evaluated
+func ListRules(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	list, err := rules.List(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch rules", http.StatusInternalServerError)
+		return
+	}
+	if list == nil {
+		list = []rules.Rule{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// UpdateRule replaces one of the user's tracking rules. Sessions the rule
+// was applied to keep their project and tags.
+func UpdateRule(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ruleID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ruleRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	stored, err := rules.Get(r.Context(), userID, ruleID)
+	if errors.Is(err, rules.ErrRuleNotFound) {
+		apierror.Error(w, r, "Rule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch rule", http.StatusInternalServerError)
+		return
+	}
+	if !checkRuleTargets(w, r, userID, &req) {
+		return
+	}
+
+	rule := req.rule(userID)
+	rule.ID = ruleID
+	if req.Position == nil {
+		rule.Position = stored.Position
+	}
+	updated, err := rules.Update(r.Context(), rule)
+	if errors.Is(err, rules.ErrRuleNotFound) {
+		apierror.Error(w, r, "Rule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to update rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteRule removes one of the user's tracking rules. Sessions it was
+// applied to keep their project and tags.
+func DeleteRule(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ruleID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	err = rules.Delete(r.Context(), userID, ruleID)
+	if errors.Is(err, rules.ErrRuleNotFound) {
+		apierror.Error(w, r, "Rule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to delete rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type ruleHistoryRequest struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+func (req *ruleHistoryRequest) Validate(v *validate.Validator) {
+	v.TimeRange("start", "end", req.Start, req.End)
+}
+
+// RuleHistoryResponse lists the changes the tracking rules make to past
+// sessions
+type RuleHistoryResponse struct {
+	// Applied reports whether the changes were made or only previewed
+	Applied bool                 `json:"applied"`
+	Changes []service.RuleChange `json:"changes"`
+}
+
+// PreviewRules returns what the user's enabled tracking rules would change
+// on their sessions in the active workspace starting in a time range of up
+// to a year, without changing them
+func PreviewRules(w http.ResponseWriter, r *http.Request) {
+	ruleHistory(w, r, service.PreviewRules, false)
+}
+
+// ApplyRules makes the changes PreviewRules returns: sessions without a
+// project get the project of the first matching rule, and matching sessions
+// get the tags of every matching rule
+func ApplyRules(w http.ResponseWriter, r *http.Request) {
+	ruleHistory(w, r, service.ApplyRulesToHistory, true)
+}
+
+func ruleHistory(w http.ResponseWriter, r *http.Request,
+	run func(ctx context.Context, userID uuid.UUID, start, end time.Time) ([]service.RuleChange, error), applied bool) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ruleHistoryRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	changes, err := run(r.Context(), userID, req.Start, req.End)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RuleHistoryResponse{Applied: applied, Changes: changes})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/pagination"
+	"github.com/pacerclub/zebra-backend/internal/service"
+)
+
+// SearchSessions returns the sessions of the active scope whose
+// description matches ?q=, best matches first
+func SearchSessions(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit, ok := searchLimit(w, r)
+	if !ok {
+		return
+	}
+	sessions, err := service.SearchSessions(r.Context(), userID, r.URL.Query().Get("q"), limit)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if sessions == nil {
+		sessions = []service.Session{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// SearchProjects returns the projects of the active scope whose name
+// matches ?q=, best matches first
+func SearchProjects(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit, ok := searchLimit(w, r)
+	if !ok {
+		return
+	}
+	projects, err := service.SearchProjects(r.Context(), userID, r.URL.Query().Get("q"), limit)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if projects == nil {
+		projects = []service.Project{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(projects)
+}
+
+// searchLimit reads ?limit=, bounded like the page size of lists. It
+// writes the error response and returns false if it is malformed.
+func searchLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return pagination.DefaultLimit, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > pagination.MaxLimit {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidRequest, "Invalid limit",
+			map[string]interface{}{"parameter": "limit", "max": pagination.MaxLimit})
+		return 0, false
+	}
+	return limit, true
+}
@@ -7,63 +7,26 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
 	"github.com/pacerclub/zebra-backend/internal/auth"
-	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/service"
 )
 
-type Session struct {
-	ID          uuid.UUID  `json:"id"`
-	UserID      uuid.UUID `json:"user_id"`
-	ProjectID   *uuid.UUID `json:"project_id,omitempty"`
-	StartTime   time.Time `json:"start_time"`
-	EndTime     time.Time `json:"end_time"`
-	Description string    `json:"description"`
-	DeviceID    string    `json:"device_id"`
-	IsDeleted   bool      `json:"is_deleted"`
-}
-
 func CreateSession(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == uuid.Nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var session Session
-	if err := json.NewDecoder(r.Body).Decode(&session); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var session service.Session
+	if !decodeJSON(w, r, &session) {
 		return
 	}
 
-	session.UserID = userID
-
-	query := `
-		INSERT INTO timer_sessions (id, user_id, project_id, start_time, end_time, description, device_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, user_id, project_id, start_time, end_time, description, device_id, is_deleted
-	`
-
-	err := db.Pool.QueryRow(r.Context(), query,
-		session.ID,
-		session.UserID,
-		session.ProjectID,
-		session.StartTime,
-		session.EndTime,
-		session.Description,
-		session.DeviceID,
-	).Scan(
-		&session.ID,
-		&session.UserID,
-		&session.ProjectID,
-		&session.StartTime,
-		&session.EndTime,
-		&session.Description,
-		&session.DeviceID,
-		&session.IsDeleted,
-	)
-
+	session, err := service.CreateSession(r.Context(), userID, session)
 	if err != nil {
-		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		writeServiceError(w, r, err)
 		return
 	}
 
@@ -74,94 +37,45 @@ func CreateSession(w http.ResponseWriter, r *http.Request) {
 func ListSessions(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == uuid.Nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	query := `
-		SELECT id, user_id, project_id, start_time, end_time, description, device_id, is_deleted
-		FROM timer_sessions
-		WHERE user_id = $1 AND is_deleted = false
-		ORDER BY start_time DESC
-	`
+	q, ok := listParams(w, r, service.SessionList)
+	if !ok {
+		return
+	}
 
-	rows, err := db.Pool.Query(r.Context(), query, userID)
+	sessions, err := service.ListSessions(r.Context(), userID, r.URL.Query().Get("all_members") == "true", q)
 	if err != nil {
-		http.Error(w, "Failed to fetch sessions", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var sessions []Session
-	for rows.Next() {
-		var session Session
-		err := rows.Scan(
-			&session.ID,
-			&session.UserID,
-			&session.ProjectID,
-			&session.StartTime,
-			&session.EndTime,
-			&session.Description,
-			&session.DeviceID,
-			&session.IsDeleted,
-		)
-		if err != nil {
-			http.Error(w, "Failed to scan session", http.StatusInternalServerError)
-			return
-		}
-		sessions = append(sessions, session)
+		writeServiceError(w, r, err)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(sessions)
+	writePage(w, r, sessions, q)
 }
 
 func UpdateSession(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == uuid.Nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	sessionID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		apierror.Error(w, r, "Invalid session ID", http.StatusBadRequest)
 		return
 	}
 
-	var session Session
-	if err := json.NewDecoder(r.Body).Decode(&session); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var session service.Session
+	if !decodeJSON(w, r, &session) {
 		return
 	}
 
-	query := `
-		UPDATE timer_sessions
-		SET project_id = $1, start_time = $2, end_time = $3, description = $4
-		WHERE id = $5 AND user_id = $6
-		RETURNING id, user_id, project_id, start_time, end_time, description, device_id, is_deleted
-	`
-
-	err = db.Pool.QueryRow(r.Context(), query,
-		session.ProjectID,
-		session.StartTime,
-		session.EndTime,
-		session.Description,
-		sessionID,
-		userID,
-	).Scan(
-		&session.ID,
-		&session.UserID,
-		&session.ProjectID,
-		&session.StartTime,
-		&session.EndTime,
-		&session.Description,
-		&session.DeviceID,
-		&session.IsDeleted,
-	)
-
+	session, err = service.UpdateSession(r.Context(), userID, sessionID, session)
 	if err != nil {
-		http.Error(w, "Failed to update session", http.StatusInternalServerError)
+		writeServiceError(w, r, err)
 		return
 	}
 
@@ -172,32 +86,93 @@ func UpdateSession(w http.ResponseWriter, r *http.Request) {
 func DeleteSession(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == uuid.Nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	sessionID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		apierror.Error(w, r, "Invalid session ID", http.StatusBadRequest)
 		return
 	}
 
-	query := `
-		UPDATE timer_sessions
-		SET is_deleted = true
-		WHERE id = $1 AND user_id = $2
-	`
+	if err := service.DeleteSession(r.Context(), userID, sessionID); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	result, err := db.Pool.Exec(r.Context(), query, sessionID, userID)
+// ListSessionReviews returns the user's sessions flagged for review in the
+// active scope, such as runaway timers that were stopped for them
+func ListSessionReviews(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	reviews, err := service.ListSessionReviews(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "Failed to delete session", http.StatusInternalServerError)
+		writeServiceError(w, r, err)
 		return
 	}
 
-	if result.RowsAffected() == 0 {
-		http.Error(w, "Session not found", http.StatusNotFound)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reviews)
+}
+
+// ResolveSessionReview clears a session's review flag once the user has
+// checked it
+func ResolveSessionReview(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := service.ResolveSessionReview(r.Context(), userID, sessionID); err != nil {
+		writeServiceError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// checkSessionEncryption rejects sessions that do not match the user's
+// storage mode. It writes the error response and returns false on failure.
+func checkSessionEncryption(w http.ResponseWriter, r *http.Request, userID uuid.UUID, session service.Session) bool {
+	if err := service.CheckSessionEncryption(r.Context(), userID, session); err != nil {
+		writeServiceError(w, r, err)
+		return false
+	}
+	return true
+}
+
+// checkSessionProject rejects sessions whose project is not part of the
+// active scope. It writes the error response and returns false on failure.
+func checkSessionProject(w http.ResponseWriter, r *http.Request, userID uuid.UUID, session service.Session) bool {
+	if err := service.CheckSessionProject(r.Context(), userID, session); err != nil {
+		writeServiceError(w, r, err)
+		return false
+	}
+	return true
+}
+
+// checkSessionLock rejects changes to sessions before the active
+// organization's lock date. It writes the error response and returns false
+// on failure.
+func checkSessionLock(w http.ResponseWriter, r *http.Request, userID, sessionID uuid.UUID, startTime time.Time) bool {
+	if err := service.CheckSessionLock(r.Context(), userID, sessionID, startTime); err != nil {
+		writeServiceError(w, r, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/service"
+)
+
+// maxBulkSessions caps how many sessions one bulk request may create
+const maxBulkSessions = 100
+
+// bulkSession is a session to create, optionally accepting the calendar
+// suggestion it came from
+type bulkSession struct {
+	service.Session
+	SuggestionID *uuid.UUID `json:"suggestion_id,omitempty"`
+}
+
+// CreateSessions creates several sessions at once, all or none. Sessions
+// with a suggestion_id confirm that suggestion.
+func CreateSessions(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req []bulkSession
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if len(req) == 0 || len(req) > maxBulkSessions {
+		apierror.Error(w, r, "Between 1 and 100 sessions are required", http.StatusBadRequest)
+		return
+	}
+
+	sessions := make([]service.Session, len(req))
+	suggestionIDs := make([]*uuid.UUID, len(req))
+	for i, item := range req {
+		sessions[i] = item.Session
+		suggestionIDs[i] = item.SuggestionID
+	}
+
+	sessions, err := service.CreateSessions(r.Context(), userID, sessions, suggestionIDs)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sessions)
+}
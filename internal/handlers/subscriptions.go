@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/billing"
+	"github.com/pacerclub/zebra-backend/internal/logging"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// maxStripeEventBytes bounds the webhook events read from Stripe
+const maxStripeEventBytes = 256 << 10
+
+type checkoutRequest struct {
+	Plan string `json:"plan"`
+}
+
+func (req *checkoutRequest) Validate(v *validate.Validator) {
+	if !billing.ValidPaidPlan(req.Plan) {
+		v.Fail("plan", "must be one of %s", strings.Join(billing.PaidPlans, ", "))
+	}
+}
+
+// redirectResponse is a Stripe page to send the user to
+type redirectResponse struct {
+	URL string `json:"url"`
+}
+
+// GetSubscription returns the user's plan and the state of its payments
+func GetSubscription(w http.ResponseWriter, r *http.Request) {
+	userID, ok := billingUser(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := billing.Get(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch subscription", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sub)
+}
+
+// CreateCheckout starts subscribing the user to a paid plan, returning the
+// Stripe Checkout page to send them to. Subscribed users change plans in
+// the billing portal instead.
+func CreateCheckout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := billingUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req checkoutRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := models.GetUserByID(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch user", http.StatusInternalServerError)
+		return
+	}
+	url, err := billing.Checkout(r.Context(), userID, user.Email, req.Plan)
+	if errors.Is(err, billing.ErrAlreadySubscribed) {
+		apierror.Error(w, r, "Already subscribed; change plans in the billing portal", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Stripe checkout failed", "user_id", userID, "error", err)
+		apierror.Error(w, r, "Failed to start checkout", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(redirectResponse{URL: url})
+}
+
+// CreateBillingPortal returns the Stripe billing portal page where the user
+// changes or cancels their plan
+func CreateBillingPortal(w http.ResponseWriter, r *http.Request) {
+	userID, ok := billingUser(w, r)
+	if !ok {
+		return
+	}
+
+	url, err := billing.Portal(r.Context(), userID)
+	if errors.Is(err, billing.ErrNoCustomer) {
+		apierror.Error(w, r, "Not subscribed", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Stripe billing portal failed", "user_id", userID, "error", err)
+		apierror.Error(w, r, "Failed to open the billing portal", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(redirectResponse{URL: url})
+}
+
+// StripeWebhook receives Stripe's events about checkouts, invoices and
+// subscriptions. Failures answer 500 so Stripe delivers the event again.
+func StripeWebhook(w http.ResponseWriter, r *http.Request) {
+	if !billing.Enabled() {
+		apierror.Write(w, r, http.StatusServiceUnavailable, apierror.NotConfigured, "Billing is not configured", nil)
+		return
+	}
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxStripeEventBytes))
+	if err != nil {
+		apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !billing.VerifyWebhook(payload, r.Header.Get("Stripe-Signature")) {
+		apierror.Error(w, r, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	if err := billing.HandleEvent(r.Context(), payload); err != nil {
+		logging.FromContext(r.Context()).Error("Stripe event failed", "error", err)
+		apierror.Error(w, r, "Failed to handle event", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// billingUser returns the user of a billing request, if the server sells
+// subscriptions. It writes the error response and returns false on
+// failure.
+func billingUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+	if !billing.Enabled() {
+		apierror.Write(w, r, http.StatusServiceUnavailable, apierror.NotConfigured, "Billing is not configured", nil)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/service"
+)
+
+// minUntrackedBlock is the shortest untracked block worth suggesting
+const minUntrackedBlock = 5 * time.Minute
+
+// CandidateSession is an untracked block of a calendar event, in the shape
+// POST /api/auth/sessions/bulk accepts
+type CandidateSession struct {
+	SuggestionID uuid.UUID  `json:"suggestion_id"`
+	ProjectID    *uuid.UUID `json:"project_id,omitempty"`
+	StartTime    time.Time  `json:"start_time"`
+	EndTime      time.Time  `json:"end_time"`
+	Description  string     `json:"description"`
+}
+
+type interval struct {
+	start, end time.Time
+}
+
+// ListUntrackedBlocks compares a day's sessions with the calendar events
+// imported as suggestions and returns the parts of events no session
+// covers. The day is ?date= in ?timezone=, UTC by default. Each candidate
+// takes the project of the user's latest session with the same description.
+func ListUntrackedBlocks(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	loc, ok := importLocation(w, r)
+	if !ok {
+		return
+	}
+	day, err := time.ParseInLocation("2006-01-02", r.URL.Query().Get("date"), loc)
+	if err != nil {
+		apierror.Error(w, r, "date must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	dayEnd := day.AddDate(0, 0, 1)
+
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT start_time, end_time FROM timer_sessions
+		WHERE user_id = $1 AND is_deleted = false AND start_time < $3 AND end_time > $2
+		ORDER BY start_time
+	`, userID, day, dayEnd)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch sessions", http.StatusInternalServerError)
+		return
+	}
+	var tracked []interval
+	for rows.Next() {
+		var i interval
+		if err := rows.Scan(&i.start, &i.end); err != nil {
+			rows.Close()
+			apierror.Error(w, r, "Failed to scan session", http.StatusInternalServerError)
+			return
+		}
+		tracked = append(tracked, i)
+	}
+	rows.Close()
+
+	rows, err = db.Pool.Query(r.Context(), `
+		SELECT id, title, start_time, end_time FROM suggested_sessions
+		WHERE user_id = $1 AND status = 'pending' AND start_time < $3 AND end_time > $2
+		ORDER BY start_time
+	`, userID, day, dayEnd)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch suggestions", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	candidates := []CandidateSession{}
+	for rows.Next() {
+		var id uuid.UUID
+		var title string
+		var event interval
+		if err := rows.Scan(&id, &title, &event.start, &event.end); err != nil {
+			apierror.Error(w, r, "Failed to scan suggestion", http.StatusInternalServerError)
+			return
+		}
+		if event.start.Before(day) {
+			event.start = day
+		}
+		if event.end.After(dayEnd) {
+			event.end = dayEnd
+		}
+		for _, block := range untracked(event, tracked) {
+			if block.end.Sub(block.start) < minUntrackedBlock {
+				continue
+			}
+			candidates = append(candidates, CandidateSession{
+				SuggestionID: id,
+				StartTime:    block.start,
+				EndTime:      block.end,
+				Description:  title,
+			})
+		}
+	}
+	rows.Close()
+
+	// Candidates take the project of the latest session with the same
+	// description. Descriptions sealed by fieldcrypt never match.
+	if len(candidates) > 0 {
+		descriptions := make([]string, len(candidates))
+		for i, c := range candidates {
+			descriptions[i] = c.Description
+		}
+		rows, err := db.Pool.Query(r.Context(), `
+			SELECT DISTINCT ON (description) description, project_id
+			FROM timer_sessions
+			WHERE `+service.SessionScopeSQL(1)+` AND is_deleted = false AND description = ANY($3)
+			ORDER BY description, start_time DESC
+		`, userID, service.ScopeOrganization(r.Context()), descriptions)
+		if err != nil {
+			apierror.Error(w, r, "Failed to fetch projects", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+		projects := make(map[string]*uuid.UUID)
+		for rows.Next() {
+			var description string
+			var projectID *uuid.UUID
+			if err := rows.Scan(&description, &projectID); err != nil {
+				apierror.Error(w, r, "Failed to scan project", http.StatusInternalServerError)
+				return
+			}
+			projects[description] = projectID
+		}
+		for i := range candidates {
+			candidates[i].ProjectID = projects[candidates[i].Description]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(candidates)
+}
+
+// untracked returns the parts of event that none of tracked, ordered by
+// start time, covers
+func untracked(event interval, tracked []interval) []interval {
+	var blocks []interval
+	cursor := event.start
+	for _, t := range tracked {
+		if !t.end.After(cursor) {
+			continue
+		}
+		if !t.start.Before(event.end) {
+			break
+		}
+		if t.start.After(cursor) {
+			blocks = append(blocks, interval{cursor, t.start})
+		}
+		cursor = t.end
+		if !cursor.Before(event.end) {
+			return blocks
+		}
+	}
+	return append(blocks, interval{cursor, event.end})
+}
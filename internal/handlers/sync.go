@@ -2,276 +2,156 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
 	"github.com/pacerclub/zebra-backend/internal/auth"
 	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/idempotency"
+	"github.com/pacerclub/zebra-backend/internal/negotiate"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/tinylib/msgp/msgp"
 )
 
-type SyncRequest struct {
-	DeviceID        string     `json:"device_id"`
-	LastSyncTime    time.Time  `json:"last_sync_time"`
-	LocalSessions   []Session  `json:"local_sessions"`
-	LocalProjects   []Project  `json:"local_projects"`
-	DeletedSessions []uuid.UUID `json:"deleted_sessions"`
-	DeletedProjects []uuid.UUID `json:"deleted_projects"`
-}
-
-type SyncResponse struct {
-	LastSyncTime    time.Time  `json:"last_sync_time"`
-	ServerSessions  []Session  `json:"server_sessions"`
-	ServerProjects  []Project  `json:"server_projects"`
-}
-
 func SyncData(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r.Context())
 	if userID == uuid.Nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var req SyncRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxSyncBodyBytes)
 
-	// Start a transaction
-	tx, err := db.Pool.Begin(r.Context())
+	var req service.SyncRequest
+	var err error
+	if negotiate.Request(r) == negotiate.Msgpack {
+		err = msgp.Decode(r.Body, &req)
+	} else {
+		err = json.NewDecoder(r.Body).Decode(&req)
+	}
 	if err != nil {
-		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			apierror.Error(w, r, "Sync payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidBody, err.Error(), nil)
 		return
 	}
-	defer tx.Rollback(r.Context())
 
-	// Get or create device sync record
-	var deviceLastSyncTime time.Time
-	err = tx.QueryRow(r.Context(), `
-		INSERT INTO user_sync_status (user_id, device_id, last_sync_time)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (user_id) 
-		DO UPDATE SET last_sync_time = EXCLUDED.last_sync_time,
-		              device_id = EXCLUDED.device_id
-		RETURNING last_sync_time
-	`, userID, req.DeviceID, req.LastSyncTime).Scan(&deviceLastSyncTime)
+	result, err := service.Sync(r.Context(), userID, &req, service.SyncOptions{
+		IdempotencyKey: r.Header.Get(idempotency.Header),
+		ContentType:    negotiate.Response(r),
+	})
 	if err != nil {
-		http.Error(w, "Failed to update sync status", http.StatusInternalServerError)
+		writeServiceError(w, r, err)
 		return
 	}
 
-	// Process local projects
-	for _, project := range req.LocalProjects {
-		if project.ID == uuid.Nil {
-			project.ID = uuid.New()
-		}
-		project.UserID = userID
-
-		query := `
-			INSERT INTO projects (id, user_id, name, description, color, device_id)
-			VALUES ($1, $2, $3, $4, $5, $6)
-			ON CONFLICT (id) DO UPDATE
-			SET name = EXCLUDED.name,
-				description = EXCLUDED.description,
-				color = EXCLUDED.color,
-				device_id = EXCLUDED.device_id,
-				updated_at = CURRENT_TIMESTAMP
-			WHERE projects.user_id = $2
-		`
-
-		_, err = tx.Exec(r.Context(), query,
-			project.ID,
-			project.UserID,
-			project.Name,
-			project.Description,
-			project.Color,
-			project.DeviceID,
-		)
-		if err != nil {
-			http.Error(w, "Failed to sync project", http.StatusInternalServerError)
-			return
-		}
+	if result.Replayed {
+		w.Header().Set(idempotency.ReplayedHeader, "true")
 	}
+	writeBody(w, result.ContentType, http.StatusOK, result.Body)
+}
 
-	// Process local sessions
-	for _, session := range req.LocalSessions {
-		if session.ID == uuid.Nil {
-			session.ID = uuid.New()
-		}
-		session.UserID = userID
-
-		query := `
-			INSERT INTO timer_sessions (id, user_id, project_id, start_time, end_time, description, device_id)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
-			ON CONFLICT (id) DO UPDATE
-			SET project_id = EXCLUDED.project_id,
-				start_time = EXCLUDED.start_time,
-				end_time = EXCLUDED.end_time,
-				description = EXCLUDED.description,
-				device_id = EXCLUDED.device_id,
-				updated_at = CURRENT_TIMESTAMP
-			WHERE timer_sessions.user_id = $2
-		`
-
-		_, err = tx.Exec(r.Context(), query,
-			session.ID,
-			session.UserID,
-			session.ProjectID,
-			session.StartTime,
-			session.EndTime,
-			session.Description,
-			session.DeviceID,
-		)
-		if err != nil {
-			http.Error(w, "Failed to sync session", http.StatusInternalServerError)
-			return
-		}
-	}
+// DeviceSyncStatus describes the sync progress of one of the user's devices
+type DeviceSyncStatus struct {
+	id                uuid.UUID
+	DeviceID          string    `json:"device_id"`
+	DeviceName        string    `json:"device_name"`
+	Platform          string    `json:"platform"`
+	LastSyncTime      time.Time `json:"last_sync_time"`
+	PendingTombstones int64     `json:"pending_tombstones"`
+	NeedsFullResync   bool      `json:"needs_full_resync"`
+	Stale             bool      `json:"stale"`
+}
 
-	// Process deleted sessions
-	if len(req.DeletedSessions) > 0 {
-		query := `
-			UPDATE timer_sessions
-			SET is_deleted = true,
-				updated_at = CURRENT_TIMESTAMP
-			WHERE id = ANY($1) AND user_id = $2
-		`
-		_, err = tx.Exec(r.Context(), query, req.DeletedSessions, userID)
-		if err != nil {
-			http.Error(w, "Failed to delete sessions", http.StatusInternalServerError)
-			return
-		}
-	}
+// deviceStatusQuery selects the sync status of devices, as d. Pending
+// tombstones are deletions the device has not acknowledged yet.
+const deviceStatusQuery = `
+	SELECT d.id, d.device_id, d.device_name, d.platform, d.last_sync_time, d.needs_full_resync, d.stale_at IS NOT NULL,
+		(SELECT COUNT(*) FROM (
+			SELECT server_updated_at FROM timer_sessions WHERE user_id = d.user_id AND is_deleted
+			UNION ALL
+			SELECT server_updated_at FROM projects WHERE user_id = d.user_id AND is_deleted
+			UNION ALL
+			SELECT server_updated_at FROM tags WHERE user_id = d.user_id AND is_deleted
+			UNION ALL
+			SELECT server_updated_at FROM tasks WHERE user_id = d.user_id AND is_deleted
+			UNION ALL
+			SELECT server_updated_at FROM session_templates WHERE user_id = d.user_id AND is_deleted
+			UNION ALL
+			SELECT server_updated_at FROM user_preferences WHERE user_id = d.user_id AND is_deleted
+			UNION ALL
+			SELECT server_updated_at FROM time_off WHERE user_id = d.user_id AND is_deleted
+		) tombstones WHERE tombstones.server_updated_at > d.acked_through) AS pending_tombstones
+	FROM device_sync d`
+
+func scanDeviceStatus(row pgx.Row) (DeviceSyncStatus, error) {
+	var device DeviceSyncStatus
+	err := row.Scan(
+		&device.id,
+		&device.DeviceID,
+		&device.DeviceName,
+		&device.Platform,
+		&device.LastSyncTime,
+		&device.NeedsFullResync,
+		&device.Stale,
+		&device.PendingTombstones,
+	)
+	return device, err
+}
 
-	// Process deleted projects
-	if len(req.DeletedProjects) > 0 {
-		query := `
-			UPDATE projects
-			SET is_deleted = true,
-				updated_at = CURRENT_TIMESTAMP
-			WHERE id = ANY($1) AND user_id = $2
-		`
-		_, err = tx.Exec(r.Context(), query, req.DeletedProjects, userID)
-		if err != nil {
-			http.Error(w, "Failed to delete projects", http.StatusInternalServerError)
-			return
-		}
-	}
+type SyncStatusResponse struct {
+	LastSyncTime string             `json:"last_sync_time"`
+	Devices      []DeviceSyncStatus `json:"devices"`
+}
 
-	// Get updated server data
-	var serverSessions []Session
-	sessionQuery := `
-		SELECT id, user_id, project_id, start_time, end_time, description, device_id, is_deleted
-		FROM timer_sessions
-		WHERE user_id = $1 AND updated_at > $2
-	`
-	rows, err := tx.Query(r.Context(), sessionQuery, userID, deviceLastSyncTime)
-	if err != nil {
-		http.Error(w, "Failed to fetch server sessions", http.StatusInternalServerError)
+func SyncStatus(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var session Session
-		err := rows.Scan(
-			&session.ID,
-			&session.UserID,
-			&session.ProjectID,
-			&session.StartTime,
-			&session.EndTime,
-			&session.Description,
-			&session.DeviceID,
-			&session.IsDeleted,
-		)
-		if err != nil {
-			http.Error(w, "Failed to scan session", http.StatusInternalServerError)
-			return
-		}
-		serverSessions = append(serverSessions, session)
+	// Get the last sync time for the user
+	var lastSyncTime time.Time
+	err := db.ReadPool(r.Context()).QueryRow(r.Context(),
+		"SELECT last_sync_time FROM user_sync_status WHERE user_id = $1",
+		userID).Scan(&lastSyncTime)
+	if err != nil {
+		lastSyncTime = time.Time{}
 	}
 
-	var serverProjects []Project
-	projectQuery := `
-		SELECT id, user_id, name, description, color, device_id, is_deleted
-		FROM projects
-		WHERE user_id = $1 AND updated_at > $2
+	query := deviceStatusQuery + `
+		WHERE d.user_id = $1
+		ORDER BY d.last_sync_time DESC
 	`
-	rows, err = tx.Query(r.Context(), projectQuery, userID, deviceLastSyncTime)
+
+	rows, err := db.ReadPool(r.Context()).Query(r.Context(), query, userID)
 	if err != nil {
-		http.Error(w, "Failed to fetch server projects", http.StatusInternalServerError)
+		apierror.Error(w, r, "Failed to fetch device sync status", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
+	devices := []DeviceSyncStatus{}
 	for rows.Next() {
-		var project Project
-		err := rows.Scan(
-			&project.ID,
-			&project.UserID,
-			&project.Name,
-			&project.Description,
-			&project.Color,
-			&project.DeviceID,
-			&project.IsDeleted,
-		)
+		device, err := scanDeviceStatus(rows)
 		if err != nil {
-			http.Error(w, "Failed to scan project", http.StatusInternalServerError)
+			apierror.Error(w, r, "Failed to scan device sync status", http.StatusInternalServerError)
 			return
 		}
-		serverProjects = append(serverProjects, project)
-	}
-
-	// Update device's sync time
-	now := time.Now()
-	syncQuery := `
-		UPDATE user_sync_status
-		SET last_sync_time = $1,
-			updated_at = CURRENT_TIMESTAMP
-		WHERE user_id = $2
-	`
-	_, err = tx.Exec(r.Context(), syncQuery, now, userID)
-	if err != nil {
-		http.Error(w, "Failed to update sync status", http.StatusInternalServerError)
-		return
-	}
-
-	// Commit transaction
-	if err := tx.Commit(r.Context()); err != nil {
-		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
-		return
-	}
-
-	// Send response
-	response := SyncResponse{
-		LastSyncTime:    now,
-		ServerSessions:  serverSessions,
-		ServerProjects:  serverProjects,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-func SyncStatus(w http.ResponseWriter, r *http.Request) {
-	userID := auth.GetUserIDFromContext(r.Context())
-	if userID == uuid.Nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	// Get the last sync time for the user
-	var lastSyncTime string
-	err := db.Pool.QueryRow(r.Context(),
-		"SELECT last_sync_time FROM user_sync_status WHERE user_id = $1",
-		userID).Scan(&lastSyncTime)
-	if err != nil {
-		lastSyncTime = time.Time{}.UTC().Format(time.RFC3339)
+		devices = append(devices, device)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"last_sync_time": lastSyncTime,
+	json.NewEncoder(w).Encode(SyncStatusResponse{
+		LastSyncTime: lastSyncTime.UTC().Format(time.RFC3339),
+		Devices:      devices,
 	})
 }
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+	"github.com/pacerclub/zebra-backend/internal/service"
+)
+
+// syncConflictList is how sync conflicts can be sorted, by default newest
+// first
+var syncConflictList = listquery.List[service.SyncConflict]{
+	Sorts: map[string]listquery.Column[service.SyncConflict]{
+		"created_at": {SQL: "created_at", Key: func(c service.SyncConflict) time.Time { return c.CreatedAt }},
+	},
+	Default: listquery.Sort{Field: "created_at", Desc: true},
+	RowID:   func(c service.SyncConflict) uuid.UUID { return c.ID },
+}
+
+// ListSyncConflicts returns a page of the user's sync conflicts. Supports
+// ?collection= and ?entity_id=.
+func ListSyncConflicts(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q, ok := listParams(w, r, syncConflictList)
+	if !ok {
+		return
+	}
+
+	var entityID *uuid.UUID
+	if value := r.URL.Query().Get("entity_id"); value != "" {
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			apierror.Error(w, r, "Invalid entity ID", http.StatusBadRequest)
+			return
+		}
+		entityID = &parsed
+	}
+
+	var collection *string
+	if value := r.URL.Query().Get("collection"); value != "" {
+		collection = &value
+	}
+
+	where, orderBy := syncConflictList.SQL(q, 4)
+	query := `
+		SELECT id, collection, entity_id, server_version, client_version,
+			server_device_id, client_device_id, resolution, created_at
+		FROM sync_conflicts
+		WHERE user_id = $1
+		  AND ($2::text IS NULL OR collection = $2)
+		  AND ($3::uuid IS NULL OR entity_id = $3)
+		  AND ` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT $6
+	`
+
+	args := append([]interface{}{userID, collection, entityID}, q.Args()...)
+	rows, err := db.ReadPool(r.Context()).Query(r.Context(), query, append(args, q.Page.Fetch())...)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch sync conflicts", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var conflicts []service.SyncConflict
+	for rows.Next() {
+		var conflict service.SyncConflict
+		var serverData, clientData []byte
+		err := rows.Scan(
+			&conflict.ID,
+			&conflict.Collection,
+			&conflict.EntityID,
+			&serverData,
+			&clientData,
+			&conflict.ServerDeviceID,
+			&conflict.ClientDeviceID,
+			&conflict.Resolution,
+			&conflict.CreatedAt,
+		)
+		if err != nil {
+			apierror.Error(w, r, "Failed to scan sync conflict", http.StatusInternalServerError)
+			return
+		}
+		conflict.ServerVersion = serverData
+		conflict.ClientVersion = clientData
+		conflicts = append(conflicts, conflict)
+	}
+
+	writePage(w, r, syncConflictList.Page(conflicts, q), q)
+}
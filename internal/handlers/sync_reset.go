@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/negotiate"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// ResetSync starts or continues a full resync of a device. The first call
+// (without page_token) resets the device's cursor and tombstone tracking;
+// every call returns one page of the user's live data.
+func ResetSync(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req service.SyncResetRequest
+	var err error
+	if negotiate.Request(r) == negotiate.Msgpack {
+		err = msgp.Decode(r.Body, &req)
+	} else {
+		err = json.NewDecoder(r.Body).Decode(&req)
+	}
+	if err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidBody, err.Error(), nil)
+		return
+	}
+
+	page, err := service.ResetSync(r.Context(), userID, req)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	contentType := negotiate.Response(r)
+	body, err := service.MarshalBody(contentType, page)
+	if err != nil {
+		apierror.Error(w, r, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	writeBody(w, contentType, http.StatusOK, body)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/service"
+)
+
+// CollectionStats summarizes one synced collection. ContentHash is the hex
+// MD5 of one "<id>:<updated_at in unix milliseconds>" line per live row,
+// ordered bytewise by id and joined with "\n" (preferences use their key as the id).
+// Clients compute the same hash locally to detect divergence.
+type CollectionStats struct {
+	Count        int64      `json:"count"`
+	Deleted      int64      `json:"deleted"`
+	LastChangeAt *time.Time `json:"last_change_at"`
+	ContentHash  string     `json:"content_hash"`
+}
+
+type SyncStatsResponse struct {
+	GeneratedAt time.Time                  `json:"generated_at"`
+	Collections map[string]CollectionStats `json:"collections"`
+}
+
+// syncStatsTables maps sync collection names to their table, key column and
+// the condition selecting the user's rows
+var syncStatsTables = []struct {
+	collection string
+	table      string
+	key        string
+	scope      string
+}{
+	{"sessions", "timer_sessions", "id", "user_id = $1"},
+	{"projects", "projects", "id", service.SyncedProjectSQL(1)},
+	{"tags", "tags", "id", "user_id = $1"},
+	{"tasks", "tasks", "id", "user_id = $1"},
+	{"templates", "session_templates", "id", "user_id = $1"},
+	{"preferences", "user_preferences", "key", "user_id = $1"},
+	{"time_off", "time_off", "id", "user_id = $1"},
+}
+
+// SyncStats returns entity counts, last change times and content hashes for
+// each synced collection
+func SyncStats(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	response := SyncStatsResponse{
+		GeneratedAt: time.Now().UTC(),
+		Collections: make(map[string]CollectionStats),
+	}
+
+	for _, t := range syncStatsTables {
+		query := fmt.Sprintf(`
+			SELECT COUNT(*) FILTER (WHERE NOT is_deleted),
+				COUNT(*) FILTER (WHERE is_deleted),
+				MAX(server_updated_at),
+				md5(COALESCE(string_agg(%[2]s::text || ':' || floor(extract(epoch FROM updated_at) * 1000)::bigint, E'\n' ORDER BY %[2]s::text COLLATE "C")
+					FILTER (WHERE NOT is_deleted), ''))
+			FROM %[1]s
+			WHERE %[3]s
+		`, t.table, t.key, t.scope)
+
+		var stats CollectionStats
+		err := db.ReadPool(r.Context()).QueryRow(r.Context(), query, userID).Scan(
+			&stats.Count,
+			&stats.Deleted,
+			&stats.LastChangeAt,
+			&stats.ContentHash,
+		)
+		if err != nil {
+			apierror.Error(w, r, "Failed to compute "+t.collection+" stats", http.StatusInternalServerError)
+			return
+		}
+		response.Collections[t.collection] = stats
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
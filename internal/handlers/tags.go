@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+	"github.com/pacerclub/zebra-backend/internal/service"
+)
+
+// tagList is how tags can be sorted, by default newest first
+var tagList = listquery.List[service.Tag]{
+	Sorts: map[string]listquery.Column[service.Tag]{
+		"created_at": {SQL: "created_at", Key: func(t service.Tag) time.Time { return t.CreatedAt }},
+		"updated_at": {SQL: "updated_at", Key: func(t service.Tag) time.Time { return t.UpdatedAt }},
+	},
+	Default: listquery.Sort{Field: "created_at", Desc: true},
+	RowID:   func(t service.Tag) uuid.UUID { return t.ID },
+}
+
+// ListTags returns a page of the user's tags. Tags are created and changed
+// through sync.
+func ListTags(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q, ok := listParams(w, r, tagList)
+	if !ok {
+		return
+	}
+
+	where, orderBy := tagList.SQL(q, 2)
+	query := `
+		SELECT id, user_id, name, color, device_id, is_deleted, created_at, updated_at
+		FROM tags
+		WHERE user_id = $1 AND is_deleted = false AND ` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT $4
+	`
+
+	args := append([]interface{}{userID}, q.Args()...)
+	rows, err := db.ReadPool(r.Context()).Query(r.Context(), query, append(args, q.Page.Fetch())...)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch tags", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var tags []service.Tag
+	for rows.Next() {
+		var tag service.Tag
+		err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Color, &tag.DeviceID, &tag.IsDeleted, &tag.CreatedAt, &tag.UpdatedAt)
+		if err != nil {
+			apierror.Error(w, r, "Failed to scan tag", http.StatusInternalServerError)
+			return
+		}
+		tags = append(tags, tag)
+	}
+
+	writePage(w, r, tagList.Page(tags, q), q)
+}
+
+// ListSessionTags returns the tags on one of the user's sessions, as added
+// by the tracking rules
+func ListSessionTags(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.ReadPool(r.Context()).Query(r.Context(), `
+		SELECT t.id, t.user_id, t.name, t.color, t.device_id, t.is_deleted, t.created_at, t.updated_at
+		FROM session_tags st
+		JOIN tags t ON t.id = st.tag_id
+		WHERE st.session_id = $1 AND st.user_id = $2 AND t.is_deleted = false
+		ORDER BY t.name
+	`, sessionID, userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch tags", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	tags := []service.Tag{}
+	for rows.Next() {
+		var tag service.Tag
+		err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Color, &tag.DeviceID, &tag.IsDeleted, &tag.CreatedAt, &tag.UpdatedAt)
+		if err != nil {
+			apierror.Error(w, r, "Failed to scan tag", http.StatusInternalServerError)
+			return
+		}
+		tags = append(tags, tag)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
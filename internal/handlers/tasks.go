@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+type taskEstimateRequest struct {
+	EstimateSeconds *int64 `json:"estimate_seconds"`
+}
+
+func (req *taskEstimateRequest) Validate(v *validate.Validator) {
+	if req.EstimateSeconds == nil {
+		v.Fail("estimate_seconds", "is required")
+		return
+	}
+	if *req.EstimateSeconds < 0 || *req.EstimateSeconds > service.MaxTaskEstimate {
+		v.Fail("estimate_seconds", "must be between 0 and %d", service.MaxTaskEstimate)
+	}
+}
+
+type sessionTaskRequest struct {
+	TaskID uuid.UUID `json:"task_id"`
+}
+
+func (req *sessionTaskRequest) Validate(v *validate.Validator) {
+	v.UUID("task_id", req.TaskID)
+}
+
+// ListTasks returns the user's tasks in the active scope with the time
+// logged against them and how it compares with their estimates
+func ListTasks(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tasks, err := service.ListTaskProgress(r.Context(), userID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tasks)
+}
+
+// ListTasksOverBudget returns the user's tasks in the active scope that took
+// longer than estimated, the furthest over first
+func ListTasksOverBudget(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tasks, err := service.ListTasksOverBudget(r.Context(), userID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tasks)
+}
+
+// SetTaskEstimate sets how long one of the user's tasks is expected to take,
+// 0 to remove the estimate
+func SetTaskEstimate(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	var req taskEstimateRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	task, err := service.SetTaskEstimate(r.Context(), userID, taskID, *req.EstimateSeconds)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
+// SetSessionTask logs one of the user's sessions against one of their tasks
+func SetSessionTask(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	var req sessionTaskRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	session, err := service.SetSessionTask(r.Context(), userID, sessionID, &req.TaskID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// ClearSessionTask stops counting one of the user's sessions towards a task
+func ClearSessionTask(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	session, err := service.SetSessionTask(r.Context(), userID, sessionID, nil)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/service"
+)
+
+// Time off is also synced; changes made here reach the user's devices on
+// their next sync, and the hours and capacity reports expect no time on
+// days off.
+
+// CreateTimeOff records days the user takes off
+func CreateTimeOff(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var timeOff service.TimeOff
+	if !decodeJSON(w, r, &timeOff) {
+		return
+	}
+
+	timeOff, err := service.CreateTimeOff(r.Context(), userID, timeOff)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(timeOff)
+}
+
+// ListTimeOff returns the user's time off by start date, limited to the
+// entries overlapping ?start= and ?end= when given
+func ListTimeOff(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	timeOff, err := service.ListTimeOff(r.Context(), userID, query.Get("start"), query.Get("end"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(timeOff)
+}
+
+// UpdateTimeOff replaces the type, dates and note of the user's time off
+func UpdateTimeOff(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid time off ID", http.StatusBadRequest)
+		return
+	}
+
+	var timeOff service.TimeOff
+	if !decodeJSON(w, r, &timeOff) {
+		return
+	}
+
+	timeOff, err = service.UpdateTimeOff(r.Context(), userID, id, timeOff)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(timeOff)
+}
+
+// DeleteTimeOff removes the user's time off
+func DeleteTimeOff(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid time off ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := service.DeleteTimeOff(r.Context(), userID, id); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
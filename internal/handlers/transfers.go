@@ -0,0 +1,476 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// A transfer hands personal projects and their sessions to another user or
+// an organization. Nothing moves until the recipient accepts. Accepting
+// copies the projects and sessions under new ids and tombstones the
+// originals, so the sender's devices drop them on their next sync while the
+// recipient's devices receive them as new records.
+
+// Transfer statuses
+const (
+	transferPending   = "pending"
+	transferAccepted  = "accepted"
+	transferDeclined  = "declined"
+	transferCancelled = "cancelled"
+)
+
+const maxTransferProjects = 100
+
+var errTransferConflict = errors.New("transfer conflict")
+
+type Transfer struct {
+	ID               uuid.UUID   `json:"id"`
+	FromUserID       uuid.UUID   `json:"from_user_id"`
+	ToUserID         *uuid.UUID  `json:"to_user_id,omitempty"`
+	ToOrganizationID *uuid.UUID  `json:"to_organization_id,omitempty"`
+	ProjectIDs       []uuid.UUID `json:"project_ids"`
+	Status           string      `json:"status"`
+	// IDMap maps the original project and session ids to their copies once
+	// the transfer is accepted
+	IDMap      *TransferIDMap `json:"id_map,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	ResolvedAt *time.Time     `json:"resolved_at,omitempty"`
+}
+
+type TransferIDMap struct {
+	Projects map[uuid.UUID]uuid.UUID `json:"projects"`
+	Sessions map[uuid.UUID]uuid.UUID `json:"sessions"`
+}
+
+type createTransferRequest struct {
+	ProjectIDs       []uuid.UUID `json:"project_ids"`
+	ToEmail          string      `json:"to_email"`
+	ToOrganizationID *uuid.UUID  `json:"to_organization_id"`
+}
+
+func (req *createTransferRequest) Validate(v *validate.Validator) {
+	req.ToEmail = strings.TrimSpace(req.ToEmail)
+	v.Check(len(req.ProjectIDs) > 0 && len(req.ProjectIDs) <= maxTransferProjects, "project_ids",
+		fmt.Sprintf("must list between 1 and %d projects", maxTransferProjects))
+	v.Check((req.ToEmail == "") != (req.ToOrganizationID == nil), "to_email", "or to_organization_id is required, but not both")
+	v.Email("to_email", req.ToEmail)
+}
+
+const transferColumns = `id, from_user_id, to_user_id, to_organization_id, project_ids, status, id_map, created_at, resolved_at`
+
+func scanTransfer(row pgx.Row) (*Transfer, error) {
+	var t Transfer
+	err := row.Scan(&t.ID, &t.FromUserID, &t.ToUserID, &t.ToOrganizationID, &t.ProjectIDs, &t.Status, &t.IDMap, &t.CreatedAt, &t.ResolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CreateTransfer offers personal projects to another user, named by email,
+// or to an organization
+func CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req createTransferRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	var toUserID *uuid.UUID
+	if req.ToEmail != "" {
+		user, err := models.GetUserByEmail(r.Context(), req.ToEmail)
+		if err != nil {
+			apierror.Error(w, r, "User not found", http.StatusNotFound)
+			return
+		}
+		if user.ID == userID {
+			apierror.Error(w, r, "Cannot transfer projects to yourself", http.StatusBadRequest)
+			return
+		}
+		toUserID = &user.ID
+	} else {
+		_, err := models.GetOrganization(r.Context(), *req.ToOrganizationID)
+		if errors.Is(err, models.ErrOrganizationNotFound) {
+			apierror.Error(w, r, "Organization not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			apierror.Error(w, r, "Failed to fetch organization", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Only live, unencrypted personal projects can be transferred; the
+	// recipient could not read the sender's ciphertext
+	seen := make(map[uuid.UUID]bool, len(req.ProjectIDs))
+	projectIDs := make([]uuid.UUID, 0, len(req.ProjectIDs))
+	for _, id := range req.ProjectIDs {
+		if !seen[id] {
+			seen[id] = true
+			projectIDs = append(projectIDs, id)
+		}
+	}
+	var count int
+	err := db.Pool.QueryRow(r.Context(), `
+		SELECT COUNT(*) FROM projects
+		WHERE id = ANY($1) AND user_id = $2 AND organization_id IS NULL AND is_deleted = false AND key_id = ''
+	`, projectIDs, userID).Scan(&count)
+	if err != nil {
+		apierror.Error(w, r, "Failed to verify projects", http.StatusInternalServerError)
+		return
+	}
+	if count != len(projectIDs) {
+		apierror.Error(w, r, "Only your own unencrypted personal projects can be transferred", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := scanTransfer(db.Pool.QueryRow(r.Context(), `
+		INSERT INTO project_transfers (id, from_user_id, to_user_id, to_organization_id, project_ids)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+transferColumns,
+		uuid.New(), userID, toUserID, req.ToOrganizationID, projectIDs))
+	if err != nil {
+		apierror.Error(w, r, "Failed to create transfer", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// ListTransfers returns the transfers the user sent or may accept, newest
+// first
+func ListTransfers(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT `+transferColumns+`
+		FROM project_transfers
+		WHERE from_user_id = $1 OR to_user_id = $1
+			OR to_organization_id IN (
+				SELECT organization_id FROM memberships
+				WHERE user_id = $1 AND role = ANY($2) AND deactivated_at IS NULL
+			)
+		ORDER BY created_at DESC
+	`, userID, []string{models.RoleOwner, models.RoleAdmin})
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch transfers", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	transfers := []Transfer{}
+	for rows.Next() {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			apierror.Error(w, r, "Failed to scan transfer", http.StatusInternalServerError)
+			return
+		}
+		transfers = append(transfers, *transfer)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfers)
+}
+
+// AcceptTransfer moves the projects and their sessions to the recipient. For
+// organizations any admin may accept; sessions stay with the user who logged
+// them.
+func AcceptTransfer(w http.ResponseWriter, r *http.Request) {
+	userID, transferID, ok := transferRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := db.Pool.Begin(r.Context())
+	if err != nil {
+		apierror.Error(w, r, "Failed to accept transfer", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	transfer, ok := loadPendingTransfer(w, r, tx, transferID)
+	if !ok {
+		return
+	}
+	if !mayReceiveTransfer(w, r, userID, transfer) {
+		return
+	}
+
+	idMap, err := moveTransferredProjects(r.Context(), tx, transfer, userID)
+	if errors.Is(err, errTransferConflict) {
+		apierror.Error(w, r, "Some projects were changed or deleted since the transfer was offered", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to accept transfer", http.StatusInternalServerError)
+		return
+	}
+
+	transfer, err = scanTransfer(tx.QueryRow(r.Context(), `
+		UPDATE project_transfers
+		SET status = $2, id_map = $3, resolved_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING `+transferColumns,
+		transferID, transferAccepted, idMap))
+	if err != nil {
+		apierror.Error(w, r, "Failed to accept transfer", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		apierror.Error(w, r, "Failed to accept transfer", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// DeclineTransfer lets the recipient refuse a transfer
+func DeclineTransfer(w http.ResponseWriter, r *http.Request) {
+	userID, transferID, ok := transferRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := db.Pool.Begin(r.Context())
+	if err != nil {
+		apierror.Error(w, r, "Failed to decline transfer", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	transfer, ok := loadPendingTransfer(w, r, tx, transferID)
+	if !ok {
+		return
+	}
+	if !mayReceiveTransfer(w, r, userID, transfer) {
+		return
+	}
+	if !resolveTransfer(w, r, tx, transferID, transferDeclined) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CancelTransfer lets the sender withdraw a pending transfer
+func CancelTransfer(w http.ResponseWriter, r *http.Request) {
+	userID, transferID, ok := transferRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := db.Pool.Begin(r.Context())
+	if err != nil {
+		apierror.Error(w, r, "Failed to cancel transfer", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	transfer, ok := loadPendingTransfer(w, r, tx, transferID)
+	if !ok {
+		return
+	}
+	if transfer.FromUserID != userID {
+		apierror.Error(w, r, "Transfer not found", http.StatusNotFound)
+		return
+	}
+	if !resolveTransfer(w, r, tx, transferID, transferCancelled) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// transferRequest returns the user and the transfer ID from the URL. It
+// writes the error response and returns false on failure.
+func transferRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	transferID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid transfer ID", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, transferID, true
+}
+
+// loadPendingTransfer locks a pending transfer for the rest of tx. It writes
+// the error response and returns false on failure.
+func loadPendingTransfer(w http.ResponseWriter, r *http.Request, tx pgx.Tx, transferID uuid.UUID) (*Transfer, bool) {
+	transfer, err := scanTransfer(tx.QueryRow(r.Context(),
+		"SELECT "+transferColumns+" FROM project_transfers WHERE id = $1 FOR UPDATE",
+		transferID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.Error(w, r, "Transfer not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch transfer", http.StatusInternalServerError)
+		return nil, false
+	}
+	if transfer.Status != transferPending {
+		apierror.Error(w, r, "Transfer is already "+transfer.Status, http.StatusConflict)
+		return nil, false
+	}
+	return transfer, true
+}
+
+// mayReceiveTransfer checks that the user is the recipient, or an admin of
+// the recipient organization. It writes the error response and returns
+// false otherwise.
+func mayReceiveTransfer(w http.ResponseWriter, r *http.Request, userID uuid.UUID, transfer *Transfer) bool {
+	if transfer.ToUserID != nil {
+		if *transfer.ToUserID != userID {
+			apierror.Error(w, r, "Transfer not found", http.StatusNotFound)
+			return false
+		}
+		return true
+	}
+
+	role, err := models.GetMemberRole(r.Context(), *transfer.ToOrganizationID, userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to verify organization membership", http.StatusInternalServerError)
+		return false
+	}
+	if role == "" {
+		apierror.Error(w, r, "Transfer not found", http.StatusNotFound)
+		return false
+	}
+	if !auth.RoleHas(role, auth.PermManageProjects) {
+		apierror.Error(w, r, "Insufficient permissions", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// resolveTransfer closes a pending transfer without moving anything and
+// commits tx. It writes the error response and returns false on failure.
+func resolveTransfer(w http.ResponseWriter, r *http.Request, tx pgx.Tx, transferID uuid.UUID, status string) bool {
+	_, err := tx.Exec(r.Context(),
+		"UPDATE project_transfers SET status = $2, resolved_at = CURRENT_TIMESTAMP WHERE id = $1",
+		transferID, status)
+	if err == nil {
+		err = tx.Commit(r.Context())
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to update transfer", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// moveTransferredProjects copies the transferred projects and their live
+// sessions under new ids and tombstones the originals. Projects go to the
+// accepting user, or to the organization with the accepting admin as their
+// owner. Sessions go to the recipient user, or stay with the sender inside an
+// organization.
+func moveTransferredProjects(ctx context.Context, tx pgx.Tx, transfer *Transfer, acceptedBy uuid.UUID) (*TransferIDMap, error) {
+	idMap := &TransferIDMap{
+		Projects: make(map[uuid.UUID]uuid.UUID, len(transfer.ProjectIDs)),
+		Sessions: make(map[uuid.UUID]uuid.UUID),
+	}
+	sessionOwner := acceptedBy
+	if transfer.ToOrganizationID != nil {
+		sessionOwner = transfer.FromUserID
+	}
+
+	for _, projectID := range transfer.ProjectIDs {
+		newID := uuid.New()
+		result, err := tx.Exec(ctx, `
+			INSERT INTO projects (id, user_id, name, description, color, encrypted_name, encrypted_description, key_id,
+				organization_id, device_id, created_at, updated_at)
+			SELECT $2, $3, name, description, color, encrypted_name, encrypted_description, key_id,
+				$4, device_id, created_at, updated_at
+			FROM projects
+			WHERE id = $1 AND user_id = $5 AND organization_id IS NULL AND is_deleted = false
+		`, projectID, newID, acceptedBy, transfer.ToOrganizationID, transfer.FromUserID)
+		if err != nil {
+			return nil, err
+		}
+		if result.RowsAffected() == 0 {
+			return nil, errTransferConflict
+		}
+		idMap.Projects[projectID] = newID
+
+		// The CTE is materialized, so each session keeps the id generated
+		// for it when read back
+		rows, err := tx.Query(ctx, `
+			WITH source AS (
+				SELECT uuid_generate_v4() AS new_id, *
+				FROM timer_sessions
+				WHERE project_id = $1 AND user_id = $2 AND is_deleted = false
+			), copied AS (
+				INSERT INTO timer_sessions (id, user_id, project_id, start_time, end_time, description,
+					encrypted_description, key_id, device_id, created_at, updated_at, breaks)
+				SELECT new_id, $3, $4, start_time, end_time, description,
+					encrypted_description, key_id, device_id, created_at, updated_at, breaks
+				FROM source
+			)
+			SELECT id, new_id FROM source
+		`, projectID, transfer.FromUserID, sessionOwner, newID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var oldID, copyID uuid.UUID
+			if err := rows.Scan(&oldID, &copyID); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			idMap.Sessions[oldID] = copyID
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err := tx.Exec(ctx, `
+		UPDATE timer_sessions
+		SET is_deleted = true, updated_at = CURRENT_TIMESTAMP
+		WHERE project_id = ANY($1) AND user_id = $2 AND is_deleted = false
+	`, transfer.ProjectIDs, transfer.FromUserID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE projects
+		SET is_deleted = true, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ANY($1)
+	`, transfer.ProjectIDs)
+	if err != nil {
+		return nil, err
+	}
+	return idMap, nil
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/i18n"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/storage"
+	"github.com/pacerclub/zebra-backend/internal/uploads"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// Uploads go straight from clients to storage: the client asks for an
+// upload URL, PUTs the file to it, then completes the upload and polls it
+// until the resized image is in place.
+
+type uploadRequest struct {
+	Purpose     string     `json:"purpose"`
+	ProjectID   *uuid.UUID `json:"project_id"`
+	ContentType string     `json:"content_type"`
+	Size        int64      `json:"size"`
+}
+
+func (req *uploadRequest) Validate(v *validate.Validator) {
+	if !uploads.ValidPurpose(req.Purpose) {
+		v.Fail("purpose", "must be one of %s", strings.Join(uploads.Purposes, ", "))
+	}
+	if req.Purpose == uploads.PurposeProjectIcon {
+		v.Check(req.ProjectID != nil, "project_id", "is required")
+	}
+	if !uploads.ValidContentType(req.ContentType) {
+		v.Fail("content_type", "must be one of %s", strings.Join(uploads.ContentTypes, ", "))
+	}
+	v.Check(req.Size > 0, "size", "must be positive")
+	v.Check(req.Size <= uploads.MaxBytes, "size", "must be at most "+strconv.FormatInt(uploads.MaxBytes, 10)+" bytes")
+}
+
+// uploadTarget is where the client sends the file: a PUT to URL with the
+// headers given, before ExpiresAt
+type uploadTarget struct {
+	Upload    *uploads.Upload   `json:"upload"`
+	UploadURL string            `json:"upload_url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// CreateUpload records an upload and returns the pre-signed URL to upload
+// the file to. Project icons can be uploaded by those who may manage the
+// project.
+func CreateUpload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := uploadUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req uploadRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Purpose != uploads.PurposeProjectIcon {
+		req.ProjectID = nil
+	} else {
+		if !auth.Can(r.Context(), auth.PermManageProjects) {
+			apierror.Error(w, r, "Insufficient permissions", http.StatusForbidden)
+			return
+		}
+		found, err := service.Projects.InScope(r.Context(), userID, service.ScopeOrganization(r.Context()), *req.ProjectID)
+		if err != nil {
+			apierror.Error(w, r, "Failed to fetch project", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			apierror.Error(w, r, "Project not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	upload, uploadURL, err := uploads.Create(r.Context(), userID, req.Purpose, req.ProjectID, req.ContentType, req.Size)
+	if err != nil {
+		apierror.Error(w, r, "Failed to create upload", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(uploadTarget{
+		Upload:    upload,
+		UploadURL: uploadURL,
+		Method:    http.MethodPut,
+		Headers: map[string]string{
+			"Content-Type":   upload.ContentType,
+			"Content-Length": strconv.FormatInt(upload.Size, 10),
+		},
+		ExpiresAt: upload.CreatedAt.Add(uploads.URLExpiry),
+	})
+}
+
+// CompleteUpload queues an uploaded file to be resized and put in place.
+// The upload is ready, or failed, once processed.
+func CompleteUpload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := uploadUser(w, r)
+	if !ok {
+		return
+	}
+	uploadID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid upload ID", http.StatusBadRequest)
+		return
+	}
+
+	upload, err := uploads.Complete(r.Context(), userID, uploadID)
+	if errors.Is(err, uploads.ErrNotFound) {
+		apierror.Error(w, r, "Upload not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to complete upload", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(translateUpload(r, upload))
+}
+
+// GetUpload reports an upload's status, and once ready its image URL
+func GetUpload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := uploadUser(w, r)
+	if !ok {
+		return
+	}
+	uploadID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid upload ID", http.StatusBadRequest)
+		return
+	}
+
+	upload, err := uploads.Get(r.Context(), userID, uploadID)
+	if errors.Is(err, uploads.ErrNotFound) {
+		apierror.Error(w, r, "Upload not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch upload", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(translateUpload(r, upload))
+}
+
+// uploadUser returns the user of an upload request, if the server has
+// storage for uploads. It writes the error response and returns false on
+// failure.
+func uploadUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+	if !storage.Configured() {
+		apierror.Write(w, r, http.StatusServiceUnavailable, apierror.NotConfigured, "Uploads are not configured", nil)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// translateUpload translates why a failed upload was rejected into the
+// request's locale
+func translateUpload(r *http.Request, upload *uploads.Upload) *uploads.Upload {
+	upload.Error = i18n.T(i18n.Locale(r.Context()), upload.Error)
+	return upload
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/usage"
+)
+
+// GetUsage returns the user's API and sync usage over the last ?days= days
+// (30 by default), totaled and per day
+func GetUsage(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	days := 30
+	if value := r.URL.Query().Get("days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > usage.MaxDays {
+			apierror.Error(w, r, fmt.Sprintf("days must be between 1 and %d", usage.MaxDays), http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	report, err := usage.Get(r.Context(), userID, days)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch usage", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/integrations"
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+// WakaTime plugins are pointed at /api/integrations/wakatime as their API
+// URL and authenticate with an API key issued here rather than a token.
+
+const maxHeartbeatBytes = 1 << 20 // 1 MB
+
+// maxHeartbeats caps a bulk request; plugins send at most 25 at a time
+const maxHeartbeats = 1000
+
+type wakaTimeStatus struct {
+	*integrations.Account
+	Settings integrations.WakaTimeSettings `json:"settings"`
+}
+
+// ConnectWakaTime links WakaTime, or replaces the API key of an existing
+// link. The key is only shown in this response.
+func ConnectWakaTime(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	mode, err := models.GetStorageMode(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch storage mode", http.StatusInternalServerError)
+		return
+	}
+	if mode == models.StorageModeEncrypted {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.StorageModeMismatch, "WakaTime is not available in encrypted storage mode", nil)
+		return
+	}
+
+	key, hash, err := integrations.NewWakaTimeKey()
+	if err != nil {
+		apierror.Error(w, r, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	account, err := integrations.SaveAccount(r.Context(), userID, integrations.WakaTime, &integrations.Token{AccessToken: hash}, integrations.DefaultWakaTimeSettings())
+	if err != nil {
+		apierror.Error(w, r, "Failed to save account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      account.ID,
+		"api_key": key,
+	})
+}
+
+func GetWakaTime(w http.ResponseWriter, r *http.Request) {
+	status, ok := loadWakaTime(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// UpdateWakaTime replaces the project rules, default project and idle
+// timeout. Rules may only assign personal projects.
+func UpdateWakaTime(w http.ResponseWriter, r *http.Request) {
+	status, ok := loadWakaTime(w, r)
+	if !ok {
+		return
+	}
+
+	if !decodeJSON(w, r, &status.Settings) {
+		return
+	}
+
+	projectIDs := []uuid.UUID{}
+	for _, rule := range status.Settings.Rules {
+		projectIDs = append(projectIDs, rule.ProjectID)
+	}
+	if status.Settings.DefaultProjectID != nil {
+		projectIDs = append(projectIDs, *status.Settings.DefaultProjectID)
+	}
+	var missing bool
+	err := db.Pool.QueryRow(r.Context(), `
+		SELECT EXISTS (
+			SELECT 1 FROM unnest($2::uuid[]) AS wanted(id)
+			WHERE NOT EXISTS (
+				SELECT 1 FROM projects p
+				WHERE p.id = wanted.id AND p.user_id = $1 AND p.organization_id IS NULL AND p.is_deleted = false
+			)
+		)
+	`, status.UserID, projectIDs).Scan(&missing)
+	if err != nil {
+		apierror.Error(w, r, "Failed to verify projects", http.StatusInternalServerError)
+		return
+	}
+	if missing {
+		apierror.Error(w, r, "Project not found", http.StatusBadRequest)
+		return
+	}
+
+	if err := integrations.UpdateSettings(r.Context(), status.ID, status.Settings); err != nil {
+		apierror.Error(w, r, "Failed to update integration", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// DisconnectWakaTime revokes the API key. Sessions built from heartbeats
+// are kept.
+func DisconnectWakaTime(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err := integrations.DeleteAccount(r.Context(), userID, integrations.WakaTime)
+	if errors.Is(err, integrations.ErrAccountNotFound) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotConnected, "WakaTime is not connected", nil)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to disconnect WakaTime", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WakaTimeHeartbeat accepts one heartbeat in the WakaTime API format
+func WakaTimeHeartbeat(w http.ResponseWriter, r *http.Request) {
+	account, ok := wakaTimeAccount(w, r)
+	if !ok {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxHeartbeatBytes)
+
+	var heartbeat integrations.Heartbeat
+	if !decodeJSON(w, r, &heartbeat) {
+		return
+	}
+	if _, err := integrations.IngestHeartbeats(r.Context(), account, []integrations.Heartbeat{heartbeat}); err != nil {
+		apierror.Error(w, r, "Failed to record heartbeat", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": heartbeat})
+}
+
+// WakaTimeHeartbeats accepts a bulk request of heartbeats, answering with
+// one status per heartbeat as WakaTime does
+func WakaTimeHeartbeats(w http.ResponseWriter, r *http.Request) {
+	account, ok := wakaTimeAccount(w, r)
+	if !ok {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxHeartbeatBytes)
+
+	var heartbeats []integrations.Heartbeat
+	if !decodeJSON(w, r, &heartbeats) {
+		return
+	}
+	if len(heartbeats) > maxHeartbeats {
+		apierror.Error(w, r, "Too many heartbeats", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if _, err := integrations.IngestHeartbeats(r.Context(), account, heartbeats); err != nil {
+		apierror.Error(w, r, "Failed to record heartbeats", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([][]interface{}, len(heartbeats))
+	for i := range responses {
+		responses[i] = []interface{}{nil, http.StatusCreated}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"responses": responses})
+}
+
+// wakaTimeAccount authenticates a plugin request by its API key, sent the
+// way WakaTime does as basic auth, as a bearer token or as ?api_key=. It
+// writes the error response and returns false on failure.
+func wakaTimeAccount(w http.ResponseWriter, r *http.Request) (*integrations.Account, bool) {
+	key := r.URL.Query().Get("api_key")
+	header := r.Header.Get("Authorization")
+	if strings.HasPrefix(header, "Basic ") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "Basic "))
+		if err == nil {
+			// Some plugins send "key:" as user and empty password
+			key = strings.TrimSuffix(string(decoded), ":")
+		}
+	} else if strings.HasPrefix(header, "Bearer ") {
+		key = strings.TrimPrefix(header, "Bearer ")
+	}
+	if key == "" {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	account, err := integrations.AccountByWakaTimeKey(r.Context(), key)
+	if errors.Is(err, integrations.ErrAccountNotFound) {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to verify API key", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	// Sessions from heartbeats are stored in plaintext
+	mode, err := models.GetStorageMode(r.Context(), account.UserID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch storage mode", http.StatusInternalServerError)
+		return nil, false
+	}
+	if mode == models.StorageModeEncrypted {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.StorageModeMismatch, "Heartbeats are not available in encrypted storage mode", nil)
+		return nil, false
+	}
+	return account, true
+}
+
+// loadWakaTime fetches the user's linked WakaTime account. It writes the
+// error response and returns false on failure.
+func loadWakaTime(w http.ResponseWriter, r *http.Request) (*wakaTimeStatus, bool) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	account, err := integrations.GetAccount(r.Context(), userID, integrations.WakaTime)
+	if errors.Is(err, integrations.ErrAccountNotFound) {
+		apierror.Write(w, r, http.StatusNotFound, apierror.NotConnected, "WakaTime is not connected", nil)
+		return nil, false
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch integration", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	status := &wakaTimeStatus{Account: account, Settings: integrations.DefaultWakaTimeSettings()}
+	if err := json.Unmarshal(account.Settings, &status.Settings); err != nil {
+		apierror.Error(w, r, "Failed to read integration settings", http.StatusInternalServerError)
+		return nil, false
+	}
+	return status, true
+}
@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/billing"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/notify"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+	"github.com/pacerclub/zebra-backend/internal/webhooks"
+)
+
+// Webhooks follow the REST hook pattern used by Zapier and Make: a consumer
+// subscribes a target URL to an event, unsubscribes it by id, and reads
+// sample payloads while the user sets up an automation.
+
+const maxTargetURLLength = 2048
+
+// sampleCount is how many recent records a sample endpoint returns
+const sampleCount = 3
+
+type subscribeWebhookRequest struct {
+	TargetURL string `json:"target_url"`
+	Event     string `json:"event"`
+}
+
+func (req *subscribeWebhookRequest) Validate(v *validate.Validator) {
+	req.TargetURL = strings.TrimSpace(req.TargetURL)
+	target, err := url.Parse(req.TargetURL)
+	v.Check(err == nil && target.Scheme == "https" && target.Host != "", "target_url", "must be an https URL")
+	v.MaxLength("target_url", req.TargetURL, maxTargetURLLength)
+	v.Check(webhooks.ValidEvent(req.Event), "event", "must be a known event")
+}
+
+// SubscribeWebhook subscribes a target URL to one event. The response
+// includes the secret that signs deliveries; it is not shown again.
+func SubscribeWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req subscribeWebhookRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	count, err := webhooks.Count(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to subscribe webhook", http.StatusInternalServerError)
+		return
+	}
+	if count >= webhooks.MaxPerUser {
+		apierror.Write(w, r, http.StatusConflict, apierror.LimitReached, "Too many webhooks", nil)
+		return
+	}
+	ws, err := billing.WorkspaceOf(r.Context(), userID, nil)
+	if err == nil {
+		err = ws.CheckCount(billing.LimitWebhooks, count+1)
+	}
+	if !checkLimit(w, r, err) {
+		return
+	}
+
+	hook, err := webhooks.Create(r.Context(), userID, req.TargetURL, req.Event)
+	if err != nil {
+		apierror.Error(w, r, "Failed to subscribe webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hook)
+}
+
+func ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q, ok := listParams(w, r, webhooks.Listing)
+	if !ok {
+		return
+	}
+
+	hooks, err := webhooks.List(r.Context(), userID, q)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	writePage(w, r, hooks, q)
+}
+
+func UnsubscribeWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	webhookID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	err = webhooks.Delete(r.Context(), userID, webhookID)
+	if errors.Is(err, webhooks.ErrWebhookNotFound) {
+		apierror.Error(w, r, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to unsubscribe webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries returns a page of the attempts to deliver events
+// to one of the user's webhooks, newest first, with what was sent and how
+// the target answered
+func ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	webhookID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	q, ok := listParams(w, r, webhooks.DeliveryListing)
+	if !ok {
+		return
+	}
+
+	deliveries, err := webhooks.ListDeliveries(r.Context(), userID, webhookID, q)
+	if errors.Is(err, webhooks.ErrWebhookNotFound) {
+		apierror.Error(w, r, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	writePage(w, r, deliveries, q)
+}
+
+// RetryWebhookDelivery posts the payload of a past delivery to its webhook
+// again and returns the new delivery, whether or not the target accepted it
+func RetryWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	deliveryID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		apierror.Error(w, r, "Invalid delivery ID", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := webhooks.Redeliver(r.Context(), userID, deliveryID)
+	if errors.Is(err, webhooks.ErrDeliveryNotFound) {
+		apierror.Error(w, r, "Delivery not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to retry delivery", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+func ListWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks.Events)
+}
+
+// WebhookSample returns payloads shaped like the event's deliveries, taken
+// from the user's most recent records, newest first. Users without any get
+// a made-up example so automations can still be mapped.
+func WebhookSample(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	event := chi.URLParam(r, "event")
+	if !webhooks.ValidEvent(event) {
+		apierror.Error(w, r, "Unknown event", http.StatusNotFound)
+		return
+	}
+
+	var samples interface{}
+	var err error
+	switch event {
+	case webhooks.EventSessionCreated, webhooks.EventSessionUpdated:
+		samples, err = sampleSessions(r, userID)
+	case webhooks.EventProjectCreated, webhooks.EventProjectUpdated:
+		samples, err = sampleProjects(r, userID)
+	case webhooks.EventSessionDeleted:
+		samples, err = sampleDeleted(r, userID, "timer_sessions")
+	case webhooks.EventProjectDeleted:
+		samples, err = sampleDeleted(r, userID, "projects")
+	case webhooks.EventNotificationSent:
+		samples = sampleNotifications(userID)
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch samples", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(samples)
+}
+
+func sampleSessions(r *http.Request, userID uuid.UUID) ([]service.Session, error) {
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT id, user_id, project_id, start_time, end_time, COALESCE(description, ''), encrypted_description,
+			key_id, COALESCE(device_id, ''), is_deleted, created_at, updated_at
+		FROM timer_sessions
+		WHERE user_id = $1 AND is_deleted = false
+		ORDER BY server_updated_at DESC
+		LIMIT $2
+	`, userID, sampleCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []service.Session{}
+	for rows.Next() {
+		var s service.Session
+		err := rows.Scan(&s.ID, &s.UserID, &s.ProjectID, &s.StartTime, &s.EndTime, &s.Description,
+			&s.EncryptedDescription, &s.KeyID, &s.DeviceID, &s.IsDeleted, &s.CreatedAt, &s.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if s.Description, err = fieldcrypt.Open(fieldcrypt.SessionDescription, s.Description); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(sessions) == 0 {
+		end := time.Now().UTC().Truncate(time.Minute)
+		sessions = append(sessions, service.Session{
+			ID:          uuid.New(),
+			UserID:      userID,
+			StartTime:   end.Add(-time.Hour),
+			EndTime:     end,
+			Description: "Writing documentation",
+			DeviceID:    "sample",
+			CreatedAt:   end,
+			UpdatedAt:   end,
+		})
+	}
+	return sessions, nil
+}
+
+func sampleProjects(r *http.Request, userID uuid.UUID) ([]service.Project, error) {
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT id, user_id, name, COALESCE(description, ''), color, encrypted_name, encrypted_description, key_id,
+			organization_id, COALESCE(device_id, ''), is_deleted, created_at, updated_at
+		FROM projects
+		WHERE user_id = $1 AND is_deleted = false
+		ORDER BY server_updated_at DESC
+		LIMIT $2
+	`, userID, sampleCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []service.Project{}
+	for rows.Next() {
+		var p service.Project
+		err := rows.Scan(&p.ID, &p.UserID, &p.Name, &p.Description, &p.Color, &p.EncryptedName,
+			&p.EncryptedDescription, &p.KeyID, &p.OrganizationID, &p.DeviceID, &p.IsDeleted, &p.CreatedAt, &p.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if p.Name, err = fieldcrypt.Open(fieldcrypt.ProjectName, p.Name); err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(projects) == 0 {
+		now := time.Now().UTC().Truncate(time.Minute)
+		projects = append(projects, service.Project{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Name:      "Website redesign",
+			Color:     "#3B82F6",
+			DeviceID:  "sample",
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+	return projects, nil
+}
+
+// sampleDeleted returns the user's most recently deleted records of table,
+// which is one of timer_sessions or projects
+func sampleDeleted(r *http.Request, userID uuid.UUID, table string) ([]webhooks.DeletedPayload, error) {
+	rows, err := db.Pool.Query(r.Context(), `
+		SELECT id, server_updated_at FROM `+table+`
+		WHERE user_id = $1 AND is_deleted = true
+		ORDER BY server_updated_at DESC
+		LIMIT $2
+	`, userID, sampleCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	samples := []webhooks.DeletedPayload{}
+	for rows.Next() {
+		var p webhooks.DeletedPayload
+		if err := rows.Scan(&p.ID, &p.DeletedAt); err != nil {
+			return nil, err
+		}
+		samples = append(samples, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(samples) == 0 {
+		samples = append(samples, webhooks.DeletedPayload{ID: uuid.New(), DeletedAt: time.Now().UTC().Truncate(time.Second)})
+	}
+	return samples, nil
+}
+
+// sampleNotifications returns a made-up notification, since notifications
+// are not kept once sent
+func sampleNotifications(userID uuid.UUID) []notify.Notification {
+	return []notify.Notification{{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Type:      notify.TypeBudgetAlert,
+		Title:     "Website redesign is at 90% of its budget",
+		Body:      "45 of 50 budgeted hours have been tracked on Website redesign.",
+		Data:      map[string]interface{}{"project_id": uuid.New(), "percent": 90},
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// A workspace is either the user's personal scope or one of their
+// organizations. Tokens carry a default workspace; the X-Workspace-ID header
+// overrides it per request.
+
+type Workspace struct {
+	// ID is "personal" or an organization ID
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Role   string `json:"role,omitempty"`
+	Active bool   `json:"active"`
+}
+
+type switchWorkspaceRequest struct {
+	WorkspaceID string `json:"workspace_id"`
+}
+
+func (req *switchWorkspaceRequest) Validate(v *validate.Validator) {
+	v.Required("workspace_id", req.WorkspaceID)
+}
+
+// ListWorkspaces returns the personal workspace and every organization the
+// user is an active member of, marking the token's workspace as active
+func ListWorkspaces(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	orgs, err := models.ListOrganizations(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch workspaces", http.StatusInternalServerError)
+		return
+	}
+
+	active := auth.GetWorkspaceIDFromContext(r.Context())
+	workspaces := []Workspace{{ID: auth.PersonalWorkspace, Name: "Personal", Active: active == uuid.Nil}}
+	for _, org := range orgs {
+		role, err := models.GetMemberRole(r.Context(), org.ID, userID)
+		if err != nil {
+			apierror.Error(w, r, "Failed to fetch workspaces", http.StatusInternalServerError)
+			return
+		}
+		workspaces = append(workspaces, Workspace{ID: org.ID.String(), Name: org.Name, Role: role, Active: org.ID == active})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(workspaces)
+}
+
+// SwitchWorkspace issues a token for the same user and device whose requests
+// default to the chosen workspace
+func SwitchWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		apierror.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req switchWorkspaceRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	var workspaceID *uuid.UUID
+	if req.WorkspaceID != auth.PersonalWorkspace {
+		orgID, err := uuid.Parse(req.WorkspaceID)
+		if err != nil {
+			apierror.Error(w, r, "Invalid workspace ID", http.StatusBadRequest)
+			return
+		}
+		role, err := models.GetMemberRole(r.Context(), orgID, userID)
+		if err != nil {
+			apierror.Error(w, r, "Failed to verify organization membership", http.StatusInternalServerError)
+			return
+		}
+		if role == "" {
+			apierror.Error(w, r, "Not a member of this organization", http.StatusForbidden)
+			return
+		}
+		workspaceID = &orgID
+	}
+
+	user, err := models.GetUserByID(r.Context(), userID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to fetch user", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := auth.GenerateWorkspaceToken(userID, user.Email, auth.GetDeviceIDFromContext(r.Context()), workspaceID)
+	if err != nil {
+		apierror.Error(w, r, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"token": token,
+	})
+}
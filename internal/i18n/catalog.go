@@ -0,0 +1,87 @@
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// catalogs map the English text of messages to their translation, by
+// locale. A message with %s or %d stands for every message it formats, such
+// as "Token lacks the %s scope"; its translation takes the formatted values
+// as %s, reordered with %[n]s if the language needs it.
+var catalogs = map[string]map[string]string{
+	SimplifiedChinese: zhCN,
+}
+
+type pattern struct {
+	re          *regexp.Regexp
+	translation string
+	literal     int
+}
+
+// patterns are the messages of each catalog with values, the most specific
+// first, so "Invalid JSON: %s" is tried before "Invalid %s"
+var patterns = func() map[string][]pattern {
+	verb := regexp.MustCompile(`%[sd]`)
+	compiled := map[string][]pattern{}
+	for locale, catalog := range catalogs {
+		var list []pattern
+		for message, translation := range catalog {
+			if !verb.MatchString(message) {
+				continue
+			}
+			literals := verb.Split(message, -1)
+			verbs := verb.FindAllString(message, -1)
+			expr := "^" + regexp.QuoteMeta(literals[0])
+			for i, v := range verbs {
+				if v == "%d" {
+					expr += `(-?\d+)`
+				} else {
+					expr += `(.+?)`
+				}
+				expr += regexp.QuoteMeta(literals[i+1])
+			}
+			list = append(list, pattern{
+				re:          regexp.MustCompile(expr + "$"),
+				translation: translation,
+				literal:     len(strings.Join(literals, "")),
+			})
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].literal != list[j].literal {
+				return list[i].literal > list[j].literal
+			}
+			return list[i].re.String() < list[j].re.String()
+		})
+		compiled[locale] = list
+	}
+	return compiled
+}()
+
+// T translates message into locale, returning it unchanged if the locale
+// has no translation for it. The values of a message with values are
+// translated in turn, so "Invalid request body: name is required" is
+// translated whole.
+func T(locale, message string) string {
+	catalog, ok := catalogs[locale]
+	if !ok || message == "" {
+		return message
+	}
+	if translation, ok := catalog[message]; ok {
+		return translation
+	}
+	for _, p := range patterns[locale] {
+		match := p.re.FindStringSubmatch(message)
+		if match == nil {
+			continue
+		}
+		values := make([]interface{}, len(match)-1)
+		for i, value := range match[1:] {
+			values[i] = T(locale, value)
+		}
+		return fmt.Sprintf(p.translation, values...)
+	}
+	return message
+}
@@ -0,0 +1,145 @@
+// Package i18n localizes the text people read: the messages of error
+// responses and the emails the server sends. Messages are written in
+// English in the code and translated by their English text, so a message
+// missing from a catalog is served in English:
+//
+//	apierror.Error(w, r, "Session not found", http.StatusNotFound)
+//
+// answers {"message": "未找到会话"} to a request preferring zh-CN. A request
+// is answered in the first supported language of its Accept-Language
+// header, or else in the locale of the user's profile.
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+// Locales the server translates into
+const (
+	English           = "en"
+	SimplifiedChinese = "zh-CN"
+)
+
+// Locales lists the supported locales, English first
+var Locales = []string{English, SimplifiedChinese}
+
+// Match returns the supported locale an Accept-Language header, or a single
+// language tag, prefers most, or "" if it prefers none of them. Chinese
+// without a script or region is taken as Simplified; Traditional Chinese is
+// not supported.
+func Match(accept string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(accept, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if tag != "" && q > 0 {
+			tags = append(tags, weighted{strings.ToLower(tag), q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	for _, t := range tags {
+		subtags := strings.Split(strings.ReplaceAll(t.tag, "_", "-"), "-")
+		switch subtags[0] {
+		case "en":
+			return English
+		case "zh":
+			if !traditional(subtags[1:]) {
+				return SimplifiedChinese
+			}
+		}
+	}
+	return ""
+}
+
+// traditional reports whether the subtags after zh name Traditional Chinese
+func traditional(subtags []string) bool {
+	for _, s := range subtags {
+		switch s {
+		case "hans", "cn", "sg", "my":
+			return false
+		case "hant", "tw", "hk", "mo":
+			return true
+		}
+	}
+	return false
+}
+
+// Of returns the supported locale of a language tag, such as the locale of
+// a user's profile, falling back to English
+func Of(tag string) string {
+	if locale := Match(tag); locale != "" {
+		return locale
+	}
+	return English
+}
+
+type contextKey string
+
+const requestKey contextKey = "i18n_request"
+
+// request holds what the locale of a request is chosen by. The user's
+// profile is read only when a translated message needs it.
+type request struct {
+	accept   string
+	userID   uuid.UUID
+	locale   string
+	resolved bool
+}
+
+// Middleware lets the requests it wraps be answered in their language
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &request{accept: r.Header.Get("Accept-Language")}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestKey, req)))
+	})
+}
+
+// SetUser makes the locale of the user's profile the fallback of the
+// request ctx belongs to
+func SetUser(ctx context.Context, userID uuid.UUID) {
+	if req, ok := ctx.Value(requestKey).(*request); ok && !req.resolved {
+		req.userID = userID
+	}
+}
+
+// Locale returns the locale to answer the request ctx belongs to in:
+// the preferred supported language of its Accept-Language header, else the
+// locale of the authenticated user, else English
+func Locale(ctx context.Context) string {
+	req, ok := ctx.Value(requestKey).(*request)
+	if !ok {
+		return English
+	}
+	if !req.resolved {
+		req.locale = Match(req.accept)
+		if req.locale == "" && req.userID != uuid.Nil {
+			if user, err := models.GetUserByID(ctx, req.userID); err == nil {
+				req.locale = Match(user.Locale)
+			}
+		}
+		if req.locale == "" {
+			req.locale = English
+		}
+		req.resolved = true
+	}
+	return req.locale
+}
@@ -0,0 +1,478 @@
+package i18n
+
+// zhCN is the Simplified Chinese catalog. Names of fields, parameters and
+// products stay in English, as clients send them.
+var zhCN = map[string]string{
+	// Authentication and access
+	"Account is deactivated":                                               "账户已停用",
+	"Admin access required":                                                "需要管理员权限",
+	"Admins cannot be impersonated":                                        "不能模拟管理员",
+	"Cannot impersonate yourself":                                          "不能模拟自己",
+	"Not allowed while impersonating":                                      "模拟用户时不允许此操作",
+	"App not authorized":                                                   "应用未获授权",
+	"App tokens cannot use the gRPC API":                                   "应用令牌无法使用 gRPC API",
+	"Authorization failed: %s":                                             "授权失败：%s",
+	"Authorization header required":                                        "缺少 Authorization 请求头",
+	"Insufficient permissions":                                             "权限不足",
+	"Invalid authorization header format":                                  "Authorization 请求头格式无效",
+	"Invalid client ID":                                                    "客户端 ID 无效",
+	"Invalid credentials":                                                  "邮箱或密码错误",
+	"Invalid or expired authorization":                                     "授权无效或已过期",
+	"Invalid signature":                                                    "签名无效",
+	"Invalid token":                                                        "令牌无效",
+	"Invalid token claims":                                                 "令牌声明无效",
+	"Not a member of this organization":                                    "你不是该组织的成员",
+	"Not available to third-party apps":                                    "第三方应用无法使用此功能",
+	"Token has been revoked":                                               "令牌已被撤销",
+	"Token lacks the %s scope":                                             "令牌缺少 %s 权限范围",
+	"Unauthorized":                                                         "未授权",
+	"Unknown client_id":                                                    "未知的 client_id",
+	"code_challenge_method must be S256":                                   "code_challenge_method 必须为 S256",
+	"redirect_uri is not registered for this app":                          "redirect_uri 未在此应用中注册",
+	"response_type must be code":                                           "response_type 必须为 code",
+	"token is invalid or expired":                                          "令牌无效或已过期",
+	"Mail is not configured":                                               "服务器未配置邮件",
+	"Only owners can add owners":                                           "只有所有者可以添加所有者",
+	"Only owners can change owners":                                        "只有所有者可以更改所有者",
+	"Only owners can remove owners":                                        "只有所有者可以移除所有者",
+	"An organization needs at least one owner":                             "组织至少需要一名所有者",
+	"only organization admins can create projects":                         "只有组织管理员可以创建项目",
+	"session belongs to another user":                                      "该会话属于其他用户",
+	"session is before the organization's lock date":                       "该会话早于组织的锁定日期",
+	"Session is locked":                                                    "会话已锁定",
+	"Only sessions of the same project can be merged":                      "只能合并同一项目的会话",
+	"The merged session would be longer than 7 days":                       "合并后的会话将超过 7 天",
+	"Recipient is not an active member":                                    "接收人不是活跃成员",
+	"Unknown PIN":                                                          "PIN 无效",
+	"Unknown recipient":                                                    "未知的接收人",
+	"Cannot transfer projects to the same member":                          "不能将项目转移给同一成员",
+	"Cannot transfer projects to yourself":                                 "不能将项目转移给自己",
+	"Only your own unencrypted personal projects can be transferred":       "只能转移你自己的未加密个人项目",
+	"Some projects were changed or deleted since the transfer was offered": "发起转移后，部分项目已被修改或删除",
+	"Transfer is already %s":                                               "转移已处于 %s 状态",
+	"Suggestion is no longer pending":                                      "该建议已处理",
+	"No timer is running":                                                  "没有正在运行的计时器",
+	"Already clocked in":                                                   "已经签到",
+	"Not clocked in":                                                       "尚未签到",
+
+	// Not found
+	"Not found":                "未找到",
+	"App not found":            "未找到应用",
+	"Delivery not found":       "未找到投递记录",
+	"Export not found":         "未找到导出",
+	"Import not found":         "未找到导入",
+	"Kiosk not found":          "未找到自助终端",
+	"Member not found":         "未找到成员",
+	"Organization not found":   "未找到组织",
+	"Project not found":        "未找到项目",
+	"Rule not found":           "未找到规则",
+	"Session not found":        "未找到会话",
+	"Session review not found": "未找到会话审核标记",
+	"Suggestion not found":     "未找到建议",
+	"Tag not found":            "未找到标签",
+	"Task not found":           "未找到任务",
+	"Time off not found":       "未找到休假",
+	"Transfer not found":       "未找到转移",
+	"Upload not found":         "未找到上传",
+	"User not found":           "未找到用户",
+	"Webhook not found":        "未找到 Webhook",
+	"Method not allowed":       "不支持该请求方法",
+
+	// Requests
+	"Invalid %s":                                      "%s 无效",
+	"Invalid delivery ID":                             "投递记录 ID 无效",
+	"Invalid JSON: %s":                                "JSON 无效：%s",
+	"Invalid kiosk ID":                                "自助终端 ID 无效",
+	"Invalid request body: %s":                        "请求体无效：%s",
+	"Invalid mapping: %s":                             "字段映射无效：%s",
+	"Invalid compressed request body":                 "压缩的请求体无效",
+	"Invalid cursor":                                  "游标无效",
+	"Invalid fields":                                  "字段无效",
+	"Invalid limit":                                   "limit 无效",
+	"Invalid page size":                               "分页大小无效",
+	"Invalid page token":                              "分页令牌无效",
+	"Invalid path":                                    "路径无效",
+	"Invalid role":                                    "角色无效",
+	"Invalid sort":                                    "排序方式无效",
+	"Invalid timezone":                                "时区无效",
+	"Invalid entity ID":                               "实体 ID 无效",
+	"Invalid export ID":                               "导出 ID 无效",
+	"Invalid import ID":                               "导入 ID 无效",
+	"Invalid organization ID":                         "组织 ID 无效",
+	"Invalid project ID":                              "项目 ID 无效",
+	"Invalid rule ID":                                 "规则 ID 无效",
+	"Invalid session ID":                              "会话 ID 无效",
+	"Invalid suggestion ID":                           "建议 ID 无效",
+	"Invalid task ID":                                 "任务 ID 无效",
+	"Invalid time off ID":                             "休假 ID 无效",
+	"Invalid transfer ID":                             "转移 ID 无效",
+	"Invalid upload ID":                               "上传 ID 无效",
+	"Invalid user ID":                                 "用户 ID 无效",
+	"Invalid webhook ID":                              "Webhook ID 无效",
+	"Invalid workspace ID":                            "工作区 ID 无效",
+	"invalid start date or time":                      "开始日期或时间无效",
+	"invalid end date or time":                        "结束日期或时间无效",
+	"Request body too large":                          "请求体过大",
+	"Sync payload too large":                          "同步数据过大",
+	"Unsupported Content-Encoding":                    "不支持的 Content-Encoding",
+	"Unsupported Content-Type":                        "不支持的 Content-Type",
+	"Idempotency key too long":                        "Idempotency-Key 过长",
+	"Between 1 and 100 sessions are required":         "需要 1 到 100 个会话",
+	"Expected a multipart form with file and mapping": "需要包含 file 和 mapping 的 multipart 表单",
+	"Search text is required":                         "请输入搜索内容",
+	"Session ends before it starts":                   "会话的结束时间早于开始时间",
+	"Merging needs at least one session besides the one kept":  "合并需要至少一个保留会话之外的会话",
+	"Too many sessions to merge at once":                       "一次合并的会话过多",
+	"The task belongs to another project":                      "该任务属于其他项目",
+	"Time range is too long":                                   "时间范围过长",
+	"The period must run forward and cover at most a year":     "时间段的结束必须晚于开始，且最长为一年",
+	"The request with this idempotency key failed; retry it":   "使用此 Idempotency-Key 的请求失败了，请重试",
+	"This idempotency key was used for a different sync batch": "此 Idempotency-Key 已用于其他同步批次",
+	"Unknown event":                        "未知的事件",
+	"all_members requires an organization": "all_members 需要指定组织",
+	"database_id and property names must be between 1 and 255 characters": "database_id 和属性名称的长度必须在 1 到 255 个字符之间",
+	"format must be json, csv or iif":                                     "format 必须为 json、csv 或 iif",
+	"target must be quickbooks or xero":                                   "target 必须为 quickbooks 或 xero",
+	"days must be between 1 and %d":                                       "days 必须在 1 到 %s 之间",
+	"estimate_seconds must be between 0 and %d":                           "estimate_seconds 必须在 0 到 %s 之间",
+	"limit must be between 1 and %d":                                      "limit 必须在 1 到 %s 之间",
+
+	// Limits
+	"Too many apps":                                   "应用数量已达上限",
+	"Too many heartbeats":                             "心跳数量过多",
+	"Too many requests":                               "请求过于频繁",
+	"Too many sync requests":                          "同步请求过于频繁",
+	"Too many tracking rules":                         "跟踪规则数量已达上限",
+	"Too many webhooks":                               "Webhook 数量已达上限",
+	"Your plan allows %s; upgrade to raise the limit": "你的套餐仅允许%s；升级套餐以提高上限",
+	"The organization's plan allows %s; ask an owner to upgrade": "组织的套餐仅允许%s；请联系所有者升级",
+	"at most %d projects":                 "最多 %s 个项目",
+	"reports of the last %d days":         "最近 %s 天的报表",
+	"at most %d webhooks":                 "最多 %s 个 Webhook",
+	"at most %d active members":           "最多 %s 名活跃成员",
+	"the plan's project limit is reached": "已达到套餐的项目数量上限",
+	"Failed to check plan limits":         "无法检查套餐限制",
+
+	// Features and integrations
+	"Google Calendar integration is not configured":                      "服务器未配置 Google Calendar 集成",
+	"Google Calendar is not connected":                                   "尚未连接 Google Calendar",
+	"Jira is not connected":                                              "尚未连接 Jira",
+	"Jira rejected the credentials: %s":                                  "Jira 拒绝了凭据：%s",
+	"Kiosks are not available in encrypted storage mode":                 "加密存储模式下无法使用自助终端",
+	"Notion is not connected":                                            "尚未连接 Notion",
+	"Notion rejected the settings: %s":                                   "Notion 拒绝了设置：%s",
+	"WakaTime is not connected":                                          "尚未连接 WakaTime",
+	"The %s integration is not configured":                               "服务器未配置 %s 集成",
+	"Inbound email is not configured":                                    "服务器未配置邮件记录",
+	"Inbound email is not set up":                                        "尚未设置邮件记录",
+	"Uploads are not configured":                                         "服务器未配置上传",
+	"Heartbeats are not available in encrypted storage mode":             "加密存储模式下无法使用心跳",
+	"Imports are not available in encrypted storage mode":                "加密存储模式下无法导入",
+	"Inbound email is not available in encrypted storage mode":           "加密存储模式下无法使用邮件记录",
+	"Quick start is not available in encrypted storage mode":             "加密存储模式下无法使用快速开始",
+	"The running timer is not available in encrypted storage mode":       "加密存储模式下无法使用计时器",
+	"WakaTime is not available in encrypted storage mode":                "加密存储模式下无法使用 WakaTime",
+	"Search is not available while field encryption is enabled":          "启用字段加密时无法搜索",
+	"encrypted field is too large":                                       "加密字段过大",
+	"encrypted records must not contain plaintext names or descriptions": "加密记录不能包含明文名称或描述",
+	"encrypted storage mode requires client-encrypted fields":            "加密存储模式要求字段由客户端加密",
+	"key_id is required for encrypted fields":                            "加密字段需要提供 key_id",
+	"key_id is too long":                                                 "key_id 过长",
+
+	// Field validation, as "<field> <message>"
+	"%s is required":                                                      "%s 为必填项",
+	"%s must be at most %d characters":                                    "%s 最多 %s 个字符",
+	"%s must be at least %d characters":                                   "%s 至少 %s 个字符",
+	"%s must be at most %d bytes":                                         "%s 最多 %s 字节",
+	"%s must be an email address":                                         "%s 必须是邮箱地址",
+	"%s must be a hex value like #1a2b3c":                                 "%s 必须是 #1a2b3c 这样的十六进制颜色值",
+	"%s must not be before %s":                                            "%s 不能早于 %s",
+	"%s must not be negative":                                             "%s 不能为负数",
+	"%s must not be in the future":                                        "%s 不能晚于当前时间",
+	"%s must be positive":                                                 "%s 必须为正数",
+	"%s must be one of %s":                                                "%s 必须是以下之一：%s",
+	"%s must be before end_date":                                          "%s 必须早于 end_date",
+	"%s must be formatted as YYYY-MM-DD":                                  "%s 的格式必须为 YYYY-MM-DD",
+	"%s must be between 0 and %d":                                         "%s 必须在 0 到 %s 之间",
+	"%s must be between 0 and 1440":                                       "%s 必须在 0 到 1440 之间",
+	"%s must be between 1 and 120":                                        "%s 必须在 1 到 120 之间",
+	"%s must be between 1 and 1440":                                       "%s 必须在 1 到 1440 之间",
+	"%s must be at most 7 days after start_time":                          "%s 最多只能比 start_time 晚 7 天",
+	"%s must be at most a year after start_date":                          "%s 最多只能比 start_date 晚一年",
+	"%s must be a language tag such as en-US":                             "%s 必须是 en-US 这样的语言标签",
+	"%s must be an IANA time zone name":                                   "%s 必须是 IANA 时区名称",
+	"%s must be monday or sunday":                                         "%s 必须为 monday 或 sunday",
+	"%s must be 12h or 24h":                                               "%s 必须为 12h 或 24h",
+	"%s must be none, up, down or nearest":                                "%s 必须为 none、up、down 或 nearest",
+	"%s must list reports, exports or invoices at most once":              "%s 只能列出 reports、exports 或 invoices，且每项最多一次",
+	"%s must be system, light or dark":                                    "%s 必须为 system、light 或 dark",
+	"%s must be HH:MM or empty":                                           "%s 必须为 HH:MM 格式或留空",
+	"%s must list days from 0 (Sunday) to 6 (Saturday) at most once":      "%s 必须列出 0（周日）到 6（周六）之间的日期，且每天最多一次",
+	"%s must list 7 days from Sunday to Saturday":                         "%s 必须按周日到周六列出 7 天",
+	"%s must list at most %d breaks":                                      "%s 最多 %s 个休息时段",
+	"%s must list at most %d repairs":                                     "%s 最多 %s 项修复",
+	"%s must list at most %d sessions":                                    "%s 最多 %s 个会话",
+	"%s must be in order within the session, each ending after it starts": "%s 必须按顺序位于会话之内，且每个休息时段的结束时间晚于开始时间",
+	"%s must be a project ID or null":                                     "%s 必须是项目 ID 或 null",
+	"%s must be one of your projects":                                     "%s 必须是你的项目之一",
+	"%s must be a 3-letter currency code":                                 "%s 必须是 3 个字母的货币代码",
+	"%s must be an http or https URL":                                     "%s 必须是 http 或 https 链接",
+	"%s must be an https URL":                                             "%s 必须是 https 链接",
+	"%s must be an https URL, or http for loopback addresses":             "%s 必须是 https 链接，回环地址可使用 http",
+	"%s must start with /":                                                "%s 必须以 / 开头",
+	"%s must be GET, POST, PUT, PATCH or DELETE":                          "%s 必须为 GET、POST、PUT、PATCH 或 DELETE",
+	"%s must not be a batch":                                              "%s 不能是批量请求",
+	"%s must be a known event":                                            "%s 必须是已知的事件",
+	"%s must be a valid glob pattern":                                     "%s 必须是有效的通配符模式",
+	"%s must be up to 64 letters, digits, - or _":                         "%s 最多由 64 个字母、数字、- 或 _ 组成",
+	"%s must be standard or encrypted":                                    "%s 必须为 standard 或 encrypted",
+	"%s must be an object":                                                "%s 必须是对象",
+	"%s must be an array":                                                 "%s 必须是数组",
+	"%s must be a string":                                                 "%s 必须是字符串",
+	"%s must be a UUID":                                                   "%s 必须是 UUID",
+	"%s must be an RFC 3339 date-time":                                    "%s 必须是 RFC 3339 格式的日期时间",
+	"%s must be an integer":                                               "%s 必须是整数",
+	"%s must be a number":                                                 "%s 必须是数字",
+	"%s must be a boolean":                                                "%s 必须是布尔值",
+	"%s has the wrong type":                                               "%s 的类型错误",
+	"%s has too many tags":                                                "%s 的标签过多",
+	"%s or tag_ids must be set":                                           "必须设置 %s 或 tag_ids",
+	"%s is not a valid regular expression":                                "%s 不是有效的正则表达式",
+	"%s is not a type of notification":                                    "%s 不是通知类型",
+	"%s is not a notification channel":                                    "%s 不是通知渠道",
+	"%s is used by an earlier request":                                    "%s 已被之前的请求使用",
+	"%s refers to %s, which is not the name of an earlier request":        "%s 引用了 %s，但它不是之前请求的名称",
+
+	// Server errors
+	"Failed to accept transfer":                 "无法接受转移",
+	"Failed to add member":                      "无法添加成员",
+	"Failed to authorize app":                   "无法授权应用",
+	"Failed to build invoices":                  "无法生成发票",
+	"Failed to cancel transfer":                 "无法取消转移",
+	"Failed to check admin access":              "无法检查管理员权限",
+	"Failed to check idempotency key":           "无法检查 Idempotency-Key",
+	"Failed to check project":                   "无法检查项目",
+	"Failed to check project conflicts":         "无法检查项目冲突",
+	"Failed to check session conflicts":         "无法检查会话冲突",
+	"Failed to clock in":                        "无法签到",
+	"Failed to clock out":                       "无法签退",
+	"Failed to collect statistics":              "无法收集统计数据",
+	"Failed to commit transaction":              "无法提交事务",
+	"Failed to compute %s stats":                "无法统计 %s",
+	"Failed to compute sync cursor":             "无法计算同步游标",
+	"Failed to confirm suggestion":              "无法确认建议",
+	"Failed to create API key":                  "无法创建 API 密钥",
+	"Failed to create address":                  "无法创建地址",
+	"Failed to create kiosk":                    "无法创建自助终端",
+	"Failed to create kiosk PIN":                "无法创建自助终端 PIN",
+	"Failed to create organization":             "无法创建组织",
+	"Failed to create project":                  "无法创建项目",
+	"Failed to create session":                  "无法创建会话",
+	"Failed to create sessions":                 "无法创建会话",
+	"Failed to create time off":                 "无法创建休假",
+	"Failed to create transfer":                 "无法创建转移",
+	"Failed to create upload":                   "无法创建上传",
+	"Failed to create user":                     "无法创建用户",
+	"Failed to deactivate account":              "无法停用账户",
+	"Failed to decline transfer":                "无法拒绝转移",
+	"Failed to decode idempotent response":      "无法解析幂等响应",
+	"Failed to delete %s":                       "无法删除 %s",
+	"Failed to delete app":                      "无法删除应用",
+	"Failed to delete kiosk":                    "无法删除自助终端",
+	"Failed to delete kiosk PIN":                "无法删除自助终端 PIN",
+	"Failed to delete organization":             "无法删除组织",
+	"Failed to delete preferences":              "无法删除偏好设置",
+	"Failed to delete project":                  "无法删除项目",
+	"Failed to delete projects":                 "无法删除项目",
+	"Failed to delete session":                  "无法删除会话",
+	"Failed to delete time off":                 "无法删除休假",
+	"Failed to encode breaks":                   "无法编码休息时段",
+	"Failed to disconnect %s":                   "无法断开 %s",
+	"Failed to dismiss suggestion":              "无法忽略建议",
+	"Failed to encode list":                     "无法编码列表",
+	"Failed to encode response":                 "无法编码响应",
+	"Failed to encrypt project":                 "无法加密项目",
+	"Failed to encrypt session":                 "无法加密会话",
+	"Failed to fetch %s":                        "无法获取 %s",
+	"Failed to fetch app":                       "无法获取应用",
+	"Failed to fetch apps":                      "无法获取应用",
+	"Failed to fetch audit log":                 "无法获取审计日志",
+	"Failed to fetch authorized apps":           "无法获取已授权的应用",
+	"Failed to fetch billing":                   "无法获取计费信息",
+	"Failed to fetch deliveries":                "无法获取投递记录",
+	"Failed to fetch device sync status":        "无法获取设备同步状态",
+	"Failed to fetch devices":                   "无法获取设备",
+	"Failed to fetch export":                    "无法获取导出",
+	"Failed to fetch exports":                   "无法获取导出",
+	"Failed to fetch import":                    "无法获取导入",
+	"Failed to fetch imports":                   "无法获取导入",
+	"Failed to fetch integration":               "无法获取集成",
+	"Failed to fetch kiosks":                    "无法获取自助终端",
+	"Failed to fetch member":                    "无法获取成员",
+	"Failed to fetch members":                   "无法获取成员",
+	"Failed to fetch notification settings":     "无法获取通知设置",
+	"Failed to fetch organization":              "无法获取组织",
+	"Failed to fetch organization settings":     "无法获取组织设置",
+	"Failed to fetch organizations":             "无法获取组织",
+	"Failed to fetch preferences":               "无法获取偏好设置",
+	"Failed to fetch presence":                  "无法获取在线状态",
+	"Failed to fetch projects":                  "无法获取项目",
+	"Failed to fetch project":                   "无法获取项目",
+	"Failed to fetch recipient":                 "无法获取接收人",
+	"Failed to fetch rounding settings":         "无法获取取整设置",
+	"Failed to fetch running timer":             "无法获取正在运行的计时器",
+	"Failed to fetch samples":                   "无法获取示例",
+	"Failed to fetch server preferences":        "无法获取服务器上的偏好设置",
+	"Failed to fetch server projects":           "无法获取服务器上的项目",
+	"Failed to fetch server sessions":           "无法获取服务器上的会话",
+	"Failed to fetch server tags":               "无法获取服务器上的标签",
+	"Failed to fetch server tasks":              "无法获取服务器上的任务",
+	"Failed to fetch server templates":          "无法获取服务器上的模板",
+	"Failed to fetch server time off":           "无法获取服务器上的休假",
+	"Failed to fetch session":                   "无法获取会话",
+	"Failed to fetch session reviews":           "无法获取待审核的会话",
+	"Failed to fetch sessions":                  "无法获取会话",
+	"Failed to fetch storage mode":              "无法获取存储模式",
+	"Failed to fetch suggestion":                "无法获取建议",
+	"Failed to fetch suggestions":               "无法获取建议",
+	"Failed to fetch sync conflicts":            "无法获取同步冲突",
+	"Failed to fetch tags":                      "无法获取标签",
+	"Failed to fetch task runs":                 "无法获取任务运行记录",
+	"Failed to fetch tasks":                     "无法获取任务",
+	"Failed to fetch time off":                  "无法获取休假",
+	"Failed to fetch transfer":                  "无法获取转移",
+	"Failed to fetch transfers":                 "无法获取转移",
+	"Failed to fetch user":                      "无法获取用户",
+	"Failed to fetch webhooks":                  "无法获取 Webhook",
+	"Failed to fetch worklogs":                  "无法获取工作日志",
+	"Failed to fetch workspaces":                "无法获取工作区",
+	"Failed to generate token":                  "无法生成令牌",
+	"Failed to load idempotent response":        "无法加载幂等响应",
+	"Failed to log sessions":                    "无法记录会话",
+	"Failed to match project":                   "无法匹配项目",
+	"Failed to merge sessions":                  "无法合并会话",
+	"Failed to obtain access token":             "无法获取访问令牌",
+	"Failed to reactivate account":              "无法重新激活账户",
+	"Failed to read integration settings":       "无法读取集成设置",
+	"Failed to read request body":               "无法读取请求体",
+	"Failed to read the authorized company: %s": "无法读取已授权的公司：%s",
+	"Failed to record heartbeat":                "无法记录心跳",
+	"Failed to record heartbeats":               "无法记录心跳",
+	"Failed to record sync conflicts":           "无法记录同步冲突",
+	"Failed to register app":                    "无法注册应用",
+	"Failed to remove address":                  "无法移除地址",
+	"Failed to remove member":                   "无法移除成员",
+	"Failed to render the API specification":    "无法生成 API 规范",
+	"Failed to repair data":                     "无法修复数据",
+	"Failed to reset device sync status":        "无法重置设备同步状态",
+	"Failed to resolve session review":          "无法清除会话审核标记",
+	"Failed to restore backup":                  "无法恢复备份",
+	"Failed to retry delivery":                  "无法重新投递",
+	"Failed to retry worklogs":                  "无法重试工作日志",
+	"Failed to revoke app":                      "无法撤销应用",
+	"Failed to save account":                    "无法保存账户",
+	"Failed to save session":                    "无法保存会话",
+	"Failed to scan audit log entry":            "无法读取审计日志条目",
+	"Failed to scan device":                     "无法读取设备",
+	"Failed to scan device sync status":         "无法读取设备同步状态",
+	"Failed to scan project":                    "无法读取项目",
+	"Failed to scan session":                    "无法读取会话",
+	"Failed to scan suggestion":                 "无法读取建议",
+	"Failed to scan sync conflict":              "无法读取同步冲突",
+	"Failed to scan tag":                        "无法读取标签",
+	"Failed to scan transfer":                   "无法读取转移",
+	"Failed to search projects":                 "无法搜索项目",
+	"Failed to search sessions":                 "无法搜索会话",
+	"Failed to set issue key":                   "无法设置问题编号",
+	"Failed to start authorization":             "无法开始授权",
+	"Failed to start export":                    "无法开始导出",
+	"Failed to start import":                    "无法开始导入",
+	"Failed to start timer":                     "无法启动计时器",
+	"Failed to start transaction":               "无法开始事务",
+	"Failed to stop timer":                      "无法停止计时器",
+	"Failed to store idempotent response":       "无法保存幂等响应",
+	"Failed to subscribe webhook":               "无法订阅 Webhook",
+	"Failed to sync preferences":                "无法同步偏好设置",
+	"Failed to sync projects":                   "无法同步项目",
+	"Failed to sync sessions":                   "无法同步会话",
+	"Failed to sync tags":                       "无法同步标签",
+	"Failed to sync tasks":                      "无法同步任务",
+	"Failed to sync templates":                  "无法同步模板",
+	"Failed to sync time off":                   "无法同步休假",
+	"Failed to transfer projects":               "无法转移项目",
+	"Failed to unsubscribe webhook":             "无法取消订阅 Webhook",
+	"Failed to update billing":                  "无法更新计费信息",
+	"Failed to update device sync status":       "无法更新设备同步状态",
+	"Failed to update integration":              "无法更新集成",
+	"Failed to update member":                   "无法更新成员",
+	"Failed to update notification settings":    "无法更新通知设置",
+	"Failed to update organization":             "无法更新组织",
+	"Failed to update organization settings":    "无法更新组织设置",
+	"Failed to update preferences":              "无法更新偏好设置",
+	"Failed to update profile":                  "无法更新个人资料",
+	"Failed to update project":                  "无法更新项目",
+	"Failed to update session":                  "无法更新会话",
+	"Failed to update storage mode":             "无法更新存储模式",
+	"Failed to update sync status":              "无法更新同步状态",
+	"Failed to update task":                     "无法更新任务",
+	"Failed to update time off":                 "无法更新休假",
+	"Failed to update transfer":                 "无法更新转移",
+	"Failed to validate projects":               "无法校验项目",
+	"Failed to validate sessions":               "无法校验会话",
+	"Failed to verify API key":                  "无法验证 API 密钥",
+	"Failed to verify authorization":            "无法验证授权",
+	"Failed to verify data":                     "无法检查数据",
+	"Failed to verify kiosk token":              "无法验证自助终端令牌",
+	"Failed to verify organization membership":  "无法验证组织成员身份",
+	"Failed to verify PIN":                      "无法验证 PIN",
+	"Failed to verify project":                  "无法验证项目",
+	"Failed to verify projects":                 "无法验证项目",
+	"Failed to verify token":                    "无法验证令牌",
+	"failed to store %s":                        "无法保存 %s",
+
+	"Failed to fetch onboarding":                             "无法获取新手引导进度",
+	"Failed to update onboarding":                            "无法更新新手引导进度",
+	"Failed to fetch upload":                                 "无法获取上传",
+	"Failed to complete upload":                              "无法完成上传",
+	"Failed to fetch usage":                                  "无法获取用量",
+	"Failed to fetch subscription":                           "无法获取订阅",
+	"Already subscribed; change plans in the billing portal": "已订阅；请在账单门户中更改套餐",
+	"Failed to start checkout":                               "无法开始结账",
+	"Not subscribed":                                         "未订阅",
+	"Failed to open the billing portal":                      "无法打开账单门户",
+	"Billing is not configured":                              "未配置账单功能",
+	"Failed to handle event":                                 "无法处理事件",
+	"Failed to fetch logins":                                 "无法获取登录记录",
+	"Failed to sign out everywhere":                          "无法在所有设备上退出登录",
+	"Failed to create rule":                                  "无法创建规则",
+	"Failed to fetch rules":                                  "无法获取规则",
+	"Failed to fetch rule":                                   "无法获取规则",
+	"Failed to update rule":                                  "无法更新规则",
+	"Failed to delete rule":                                  "无法删除规则",
+	"Failed to verify tags":                                  "无法验证标签",
+	"Failed to load tracking rules":                          "无法加载跟踪规则",
+	"Failed to apply tracking rules":                         "无法应用跟踪规则",
+	"Failed to tag sessions":                                 "无法为会话添加标签",
+	"Failed to fetch session tags":                           "无法获取会话标签",
+
+	// Onboarding steps
+	"Create a project to track time on": "创建一个用于记录时间的项目",
+	"Track your first session":          "记录你的第一段时间",
+	"Install the iOS or Android app":    "安装 iOS 或 Android 应用",
+	"Sync a device with your account":   "将设备与你的账户同步",
+
+	// Why uploads failed
+	"The file was not uploaded":                "文件未上传",
+	"The file is larger than allowed":          "文件超过了大小上限",
+	"The file is not a PNG, JPEG or GIF image": "文件不是 PNG、JPEG 或 GIF 图片",
+	"The image could not be read":              "无法读取图片",
+	"The image has too many pixels":            "图片像素过多",
+	"The project was deleted":                  "项目已被删除",
+	"Failed to process the image":              "无法处理图片",
+
+	// Emails
+	"%d hours": "%s 小时",
+	"no lines like \"2h project-x writing docs\" were found":       "没有找到类似“2h project-x writing docs”的行",
+	"sessions cannot be logged by email in encrypted storage mode": "加密存储模式下无法通过邮件记录会话",
+	"line %d: no project named %s":                                 "第 %s 行：没有名为 %s 的项目",
+}
@@ -0,0 +1,214 @@
+// Package idempotency makes retries of mutating API requests safe. A client
+// sends an Idempotency-Key header with a key of its choosing; the first
+// request with the key is processed and its response stored, and retries
+// with the same key get the stored response instead of repeating the
+// change, such as creating a second session.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/apiversion"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/logging"
+)
+
+const (
+	// Header carries the client's key
+	Header = "Idempotency-Key"
+	// ReplayedHeader marks responses replayed for a retry
+	ReplayedHeader = "Idempotent-Replayed"
+	// MaxKeyLength bounds the keys clients may choose
+	MaxKeyLength = 255
+)
+
+// maxBodyBytes bounds the bodies read to fingerprint a request, above the
+// largest body an endpoint accepts
+const maxBodyBytes = 32 << 20
+
+// Middleware processes mutating requests carrying an Idempotency-Key at
+// most once per user and key, replaying the stored response to retries.
+// Server errors are not stored, so a retry after one runs the request
+// again. It must run after auth.Middleware.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		userID := auth.GetUserIDFromContext(r.Context())
+		if key == "" || userID == uuid.Nil || !mutating(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > MaxKeyLength {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidRequest, "Idempotency key too long",
+				map[string]interface{}{"max_length": MaxKeyLength})
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+		var maxBytesErr *http.MaxBytesError
+		if len(body) > maxBodyBytes || errors.As(err, &maxBytesErr) {
+			apierror.Error(w, r, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidBody, "Failed to read request body", nil)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := fingerprintOf(r, body)
+
+		ctx := r.Context()
+		claimed, err := claim(ctx, userID, key, fingerprint)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to claim idempotency key", "error", err)
+			apierror.Error(w, r, "Failed to check idempotency key", http.StatusInternalServerError)
+			return
+		}
+		if !claimed {
+			replay(w, r, userID, key, fingerprint)
+			return
+		}
+
+		var response bytes.Buffer
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&response)
+		completed := false
+		defer func() {
+			// Release the key if the handler panicked or failed, so the
+			// client can retry
+			if !completed {
+				release(context.WithoutCancel(ctx), userID, key)
+			}
+		}()
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		err = save(context.WithoutCancel(ctx), userID, key, status, ww.Header().Get("Content-Type"), response.Bytes())
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to store idempotent response", "error", err)
+			return
+		}
+		completed = true
+	})
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// fingerprintOf identifies a request by its method, path and body. The
+// path is taken without the API version, so a retry reaching another mount
+// of the same route still matches.
+func fingerprintOf(r *http.Request, body []byte) []byte {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+apiversion.Path(r.URL.Path)+"?"+r.URL.RawQuery+"\n")
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// replay answers a retry with the stored response of the first request
+func replay(w http.ResponseWriter, r *http.Request, userID uuid.UUID, key string, fingerprint []byte) {
+	var stored []byte
+	var status *int
+	var contentType *string
+	var body []byte
+	err := db.Pool.QueryRow(r.Context(), `
+		SELECT fingerprint, status_code, content_type, response_body
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2
+	`, userID, key).Scan(&stored, &status, &contentType, &body)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Released by a failed first request since the claim
+		apierror.Write(w, r, http.StatusConflict, apierror.Conflict, "The request with this idempotency key failed; retry it", nil)
+		return
+	}
+	if err != nil {
+		apierror.Error(w, r, "Failed to load idempotent response", http.StatusInternalServerError)
+		return
+	}
+
+	if !bytes.Equal(stored, fingerprint) {
+		apierror.Write(w, r, http.StatusUnprocessableEntity, apierror.IdempotencyKeyReused,
+			"This idempotency key was used for a different request", nil)
+		return
+	}
+	if status == nil {
+		w.Header().Set("Retry-After", "1")
+		apierror.Write(w, r, http.StatusConflict, apierror.Conflict,
+			"A request with this idempotency key is still being processed", nil)
+		return
+	}
+
+	if contentType != nil && *contentType != "" {
+		w.Header().Set("Content-Type", *contentType)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(*status)
+	w.Write(body)
+}
+
+// claim reserves the key for a request. It returns false if the key is
+// taken by an earlier request, finished or still running.
+func claim(ctx context.Context, userID uuid.UUID, key string, fingerprint []byte) (bool, error) {
+	// Keys only need to outlive a client's retry window. Claims still
+	// unfinished after the request timeout belong to a server that died.
+	_, err := db.Pool.Exec(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1
+		  AND (created_at < CURRENT_TIMESTAMP - INTERVAL '24 hours'
+		    OR (status_code IS NULL AND created_at < CURRENT_TIMESTAMP - INTERVAL '2 minutes'))
+	`, userID)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := db.Pool.Exec(ctx, `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, fingerprint)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, idempotency_key) DO NOTHING
+	`, userID, key, fingerprint)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func save(ctx context.Context, userID uuid.UUID, key string, status int, contentType string, body []byte) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $1, content_type = $2, response_body = $3
+		WHERE user_id = $4 AND idempotency_key = $5
+	`, status, contentType, body, userID, key)
+	return err
+}
+
+func release(ctx context.Context, userID uuid.UUID, key string) {
+	_, err := db.Pool.Exec(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2 AND status_code IS NULL
+	`, userID, key)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to release idempotency key", "error", err)
+	}
+}
@@ -0,0 +1,28 @@
+package idempotency
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFingerprintOf(t *testing.T) {
+	fingerprint := func(method, target, body string) []byte {
+		return fingerprintOf(httptest.NewRequest(method, target, nil), []byte(body))
+	}
+	first := fingerprint("POST", "/api/v1/auth/sessions", `{"description":"Writing"}`)
+
+	if !bytes.Equal(first, fingerprint("POST", "/api/auth/sessions", `{"description":"Writing"}`)) {
+		t.Error("a retry on the unversioned mount does not match")
+	}
+	for _, other := range [][3]string{
+		{"PUT", "/api/v1/auth/sessions", `{"description":"Writing"}`},
+		{"POST", "/api/v1/auth/projects", `{"description":"Writing"}`},
+		{"POST", "/api/v1/auth/sessions?force=true", `{"description":"Writing"}`},
+		{"POST", "/api/v1/auth/sessions", `{"description":"Reading"}`},
+	} {
+		if bytes.Equal(first, fingerprint(other[0], other[1], other[2])) {
+			t.Errorf("%s %s %s matches the first request", other[0], other[1], other[2])
+		}
+	}
+}
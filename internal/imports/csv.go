@@ -0,0 +1,214 @@
+package imports
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourceCSV names imports from arbitrary CSV files
+const SourceCSV = "csv"
+
+// defaultTimeLayouts are tried in order when a mapping sets no time format
+var defaultTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// ColumnMapping tells the CSV importer which columns, named by their header,
+// hold which part of a session. Start is required, along with either End or
+// Duration.
+type ColumnMapping struct {
+	Start       string `json:"start"`
+	End         string `json:"end"`
+	Duration    string `json:"duration"`
+	Project     string `json:"project"`
+	Description string `json:"description"`
+	Tags        string `json:"tags"`
+	// TimeFormat is a Go time layout such as "02/01/2006 15:04"; by default
+	// ISO 8601 style times are accepted
+	TimeFormat string `json:"time_format"`
+	// Timezone applies to times without an offset, UTC by default
+	Timezone string `json:"timezone"`
+	// TagSeparator splits the tags column, "," by default
+	TagSeparator string `json:"tag_separator"`
+	// Delimiter is the field separator, "," by default
+	Delimiter string `json:"delimiter"`
+}
+
+// Validate checks the mapping and fills in defaults
+func (m *ColumnMapping) Validate() error {
+	if m.Start == "" {
+		return errors.New("mapping must name a start column")
+	}
+	if m.End == "" && m.Duration == "" {
+		return errors.New("mapping must name an end or a duration column")
+	}
+	if m.TagSeparator == "" {
+		m.TagSeparator = ","
+	}
+	if m.Delimiter == "" {
+		m.Delimiter = ","
+	}
+	if len([]rune(m.Delimiter)) != 1 {
+		return errors.New("delimiter must be a single character")
+	}
+	if _, err := time.LoadLocation(m.Timezone); err != nil {
+		return errors.New("invalid timezone")
+	}
+	return nil
+}
+
+// ParseCSV reads sessions from a CSV file with a header row according to the
+// mapping. Rows that cannot be read are returned as line errors instead of
+// failing the whole file.
+func ParseCSV(r io.Reader, m ColumnMapping) ([]Entry, []LineError, error) {
+	if err := m.Validate(); err != nil {
+		return nil, nil, err
+	}
+	loc, _ := time.LoadLocation(m.Timezone)
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comma = []rune(m.Delimiter)[0]
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading header: %v", err)
+	}
+	columns := headerColumns(header)
+	for _, name := range []string{m.Start, m.End, m.Duration, m.Project, m.Description, m.Tags} {
+		if _, ok := columns[strings.ToLower(name)]; name != "" && !ok {
+			return nil, nil, fmt.Errorf("column %q not found in header", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[strings.ToLower(name)]
+		if name == "" || !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var entries []Entry
+	var lineErrors []LineError
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			lineErrors = append(lineErrors, LineError{Line: line, Message: err.Error()})
+			continue
+		}
+
+		entry, message := mapRecord(record, field, m, loc)
+		if message != "" {
+			lineErrors = append(lineErrors, LineError{Line: line, Message: message})
+			continue
+		}
+		if message := validateEntry(entry); message != "" {
+			lineErrors = append(lineErrors, LineError{Line: line, Message: message})
+			continue
+		}
+		entry.Line = line
+		entries = append(entries, entry)
+	}
+	return entries, lineErrors, nil
+}
+
+// mapRecord turns one CSV record into an entry, or returns why it cannot
+func mapRecord(record []string, field func([]string, string) string, m ColumnMapping, loc *time.Location) (Entry, string) {
+	var entry Entry
+
+	start, err := parseTime(field(record, m.Start), m.TimeFormat, loc)
+	if err != nil {
+		return entry, "invalid start time"
+	}
+	entry.Start = start
+
+	if m.End != "" && field(record, m.End) != "" {
+		end, err := parseTime(field(record, m.End), m.TimeFormat, loc)
+		if err != nil {
+			return entry, "invalid end time"
+		}
+		entry.End = end
+	} else {
+		duration, err := parseDuration(field(record, m.Duration))
+		if err != nil {
+			return entry, "invalid duration"
+		}
+		entry.End = start.Add(duration)
+	}
+
+	entry.Project = field(record, m.Project)
+	entry.Description = field(record, m.Description)
+	if m.Tags != "" {
+		for _, tag := range strings.Split(field(record, m.Tags), m.TagSeparator) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				entry.Tags = append(entry.Tags, tag)
+			}
+		}
+	}
+	return entry, ""
+}
+
+// headerColumns maps lowercased header names to their column, ignoring a
+// leading byte order mark
+func headerColumns(header []string) map[string]int {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	return columns
+}
+
+func parseTime(value, layout string, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("empty time")
+	}
+	if layout != "" {
+		return time.ParseInLocation(layout, value, loc)
+	}
+	for _, layout := range defaultTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("unrecognized time")
+}
+
+// parseDuration accepts "1:30:00", "1:30" (hours and minutes), Go durations
+// such as "1h30m" and decimal hours such as "1.5"
+func parseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, errors.New("empty duration")
+	}
+	if parts := strings.Split(value, ":"); len(parts) == 2 || len(parts) == 3 {
+		var d time.Duration
+		units := []time.Duration{time.Hour, time.Minute, time.Second}
+		for i, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 {
+				return 0, errors.New("invalid duration")
+			}
+			d += time.Duration(n) * units[i]
+		}
+		return d, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d, nil
+	}
+	hours, err := strconv.ParseFloat(value, 64)
+	if err != nil || hours < 0 {
+		return 0, errors.New("invalid duration")
+	}
+	return time.Duration(hours * float64(time.Hour)), nil
+}
@@ -0,0 +1,488 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/background"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/rules"
+)
+
+var ErrJobNotFound = errors.New("import job not found")
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// maxLineErrors caps how many per-line errors a job keeps
+const maxLineErrors = 1000
+
+// batchSize is how many entries are written per transaction, between
+// progress updates. Sessions are copied in, so batches can be large.
+const batchSize = 2000
+
+// sessionColumns are the columns imported sessions are copied into
+var sessionColumns = []string{"id", "user_id", "project_id", "start_time", "end_time", "description", "device_id"}
+
+// Entry is one time entry read from another tool, before it is mapped onto
+// projects, tags and sessions
+type Entry struct {
+	// Line is the source line or record number, used in error reports
+	Line        int
+	Start       time.Time
+	End         time.Time
+	Project     string
+	Client      string
+	Description string
+	Tags        []string
+}
+
+// LineError reports an entry that could not be imported
+type LineError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// Job tracks one background import
+type Job struct {
+	ID              uuid.UUID   `json:"id"`
+	UserID          uuid.UUID   `json:"user_id"`
+	Source          string      `json:"source"`
+	Status          string      `json:"status"`
+	Total           int         `json:"total"`
+	Processed       int         `json:"processed"`
+	CreatedSessions int         `json:"created_sessions"`
+	CreatedProjects int         `json:"created_projects"`
+	Duplicates      int         `json:"duplicates"`
+	Errors          []LineError `json:"errors"`
+	CreatedAt       time.Time   `json:"created_at"`
+	FinishedAt      *time.Time  `json:"finished_at,omitempty"`
+}
+
+const jobColumns = `id, user_id, source, status, total, processed, created_sessions, created_projects, duplicates,
+	errors, created_at, finished_at`
+
+func scanJob(row pgx.Row) (*Job, error) {
+	var j Job
+	err := row.Scan(&j.ID, &j.UserID, &j.Source, &j.Status, &j.Total, &j.Processed, &j.CreatedSessions,
+		&j.CreatedProjects, &j.Duplicates, &j.Errors, &j.CreatedAt, &j.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// CreateJob records a queued import for the user
+func CreateJob(ctx context.Context, userID uuid.UUID, source string) (*Job, error) {
+	return scanJob(db.Pool.QueryRow(ctx,
+		`INSERT INTO import_jobs (id, user_id, source) VALUES ($1, $2, $3) RETURNING `+jobColumns,
+		uuid.New(), userID, source))
+}
+
+// GetJob returns one of the user's import jobs
+func GetJob(ctx context.Context, userID, jobID uuid.UUID) (*Job, error) {
+	job, err := scanJob(db.Pool.QueryRow(ctx,
+		`SELECT `+jobColumns+` FROM import_jobs WHERE id = $1 AND user_id = $2`,
+		jobID, userID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrJobNotFound
+	}
+	return job, err
+}
+
+// ListJobs returns the user's most recent import jobs
+func ListJobs(ctx context.Context, userID uuid.UUID, limit int) ([]Job, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+jobColumns+` FROM import_jobs WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`,
+		userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// Start runs the import in the background. load produces the entries, so
+// slow sources such as remote APIs are also read off the request path.
+func Start(job *Job, load func(context.Context) ([]Entry, error)) {
+	background.Go(func() {
+		ctx := context.Background()
+		if err := run(ctx, job, load); err != nil {
+			slog.Error("Import job failed", "job_id", job.ID, "error", err)
+			_, err := db.Pool.Exec(ctx, `
+				UPDATE import_jobs
+				SET status = $2, errors = errors || jsonb_build_array(jsonb_build_object('line', 0, 'message', $3::text)),
+					finished_at = CURRENT_TIMESTAMP
+				WHERE id = $1
+			`, job.ID, StatusFailed, err.Error())
+			if err != nil {
+				slog.Error("Failed to record import job failure", "job_id", job.ID, "error", err)
+			}
+		}
+	})
+}
+
+func run(ctx context.Context, job *Job, load func(context.Context) ([]Entry, error)) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE import_jobs SET status = $2 WHERE id = $1`, job.ID, StatusRunning)
+	if err != nil {
+		return err
+	}
+
+	entries, err := load(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = db.Pool.Exec(ctx, `UPDATE import_jobs SET total = $2 WHERE id = $1`, job.ID, len(entries))
+	if err != nil {
+		return err
+	}
+
+	im := &importer{userID: job.UserID, tags: make(map[string]bool)}
+	if err := im.loadProjects(ctx); err != nil {
+		return err
+	}
+	if err := im.loadRules(ctx); err != nil {
+		return err
+	}
+	for start := 0; start < len(entries); start += batchSize {
+		end := start + batchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		progress, err := im.importBatch(ctx, entries[start:end])
+		if err != nil {
+			return err
+		}
+		_, err = db.Pool.Exec(ctx, `
+			UPDATE import_jobs
+			SET processed = processed + $2, created_sessions = created_sessions + $3,
+				created_projects = created_projects + $4, duplicates = duplicates + $5,
+				errors = CASE WHEN jsonb_array_length(errors) < $7 THEN errors || $6 ELSE errors END
+			WHERE id = $1
+		`, job.ID, end-start, progress.CreatedSessions, progress.CreatedProjects, progress.Duplicates,
+			progress.Errors, maxLineErrors)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = db.Pool.Exec(ctx,
+		`UPDATE import_jobs SET status = $2, finished_at = CURRENT_TIMESTAMP WHERE id = $1`,
+		job.ID, StatusCompleted)
+	return err
+}
+
+// RecordLineErrors adds errors found while parsing, before the job runs
+func RecordLineErrors(ctx context.Context, jobID uuid.UUID, lineErrors []LineError) error {
+	if len(lineErrors) == 0 {
+		return nil
+	}
+	if len(lineErrors) > maxLineErrors {
+		lineErrors = lineErrors[:maxLineErrors]
+	}
+	_, err := db.Pool.Exec(ctx, `UPDATE import_jobs SET errors = errors || $2 WHERE id = $1`, jobID, lineErrors)
+	return err
+}
+
+type batchProgress struct {
+	CreatedSessions int
+	CreatedProjects int
+	Duplicates      int
+	Errors          []LineError
+}
+
+// importer maps entries onto the user's personal projects and tags, creating
+// what is missing, and remembers what it created across batches. Entries
+// without a project go through the user's tracking rules.
+type importer struct {
+	userID   uuid.UUID
+	projects map[string]*uuid.UUID
+	tags     map[string]bool
+	rules    rules.Set
+	// ruleProjects are the rule projects imported sessions may get
+	ruleProjects map[uuid.UUID]bool
+}
+
+// importBatch writes a batch of entries in one transaction, copying the new
+// sessions in at once. Entries matching an existing session's start, end and
+// description are counted as duplicates, which makes re-running an import
+// harmless.
+func (im *importer) importBatch(ctx context.Context, entries []Entry) (*batchProgress, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	existing, err := im.existingSessions(ctx, tx, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &batchProgress{Errors: []LineError{}}
+	var rows [][]interface{}
+	ruleTags := make(map[uuid.UUID][]uuid.UUID)
+	for _, entry := range entries {
+		if message := validateEntry(entry); message != "" {
+			progress.Errors = append(progress.Errors, LineError{Line: entry.Line, Message: message})
+			continue
+		}
+
+		projectID, created, err := im.project(ctx, tx, entry.Project, entry.Client)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			progress.CreatedProjects++
+		}
+		if err := im.ensureTags(ctx, tx, entry.Tags); err != nil {
+			return nil, err
+		}
+
+		// Entries repeated within the import are duplicates too
+		key := newSessionKey(entry.Start, entry.End, entry.Description)
+		if existing[key] {
+			progress.Duplicates++
+			continue
+		}
+		existing[key] = true
+
+		sessionID := uuid.New()
+		m := im.rules.Match(entry.Description, func(id uuid.UUID) bool { return im.ruleProjects[id] })
+		if projectID == nil {
+			projectID = m.ProjectID
+		}
+		if len(m.TagIDs) > 0 {
+			ruleTags[sessionID] = m.TagIDs
+		}
+
+		description, err := fieldcrypt.Seal(fieldcrypt.SessionDescription, entry.Description)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, []interface{}{sessionID, im.userID, projectID, entry.Start, entry.End, description, "import"})
+	}
+
+	created, err := db.CopyRows(ctx, tx, "timer_sessions", sessionColumns, rows)
+	if err != nil {
+		return nil, err
+	}
+	progress.CreatedSessions = int(created)
+	if err := rules.TagSessions(ctx, tx, im.userID, ruleTags); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// loadProjects reads the user's live personal projects by lowercased name,
+// the oldest winning when names collide. Names are compared here rather
+// than in queries, as they may be sealed by fieldcrypt.
+func (im *importer) loadProjects(ctx context.Context) error {
+	im.projects = make(map[string]*uuid.UUID)
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, name FROM projects
+		WHERE user_id = $1 AND organization_id IS NULL AND is_deleted = false
+		ORDER BY created_at
+	`, im.userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		if name, err = fieldcrypt.Open(fieldcrypt.ProjectName, name); err != nil {
+			return err
+		}
+		if key := strings.ToLower(strings.TrimSpace(name)); im.projects[key] == nil {
+			im.projects[key] = &id
+		}
+	}
+	return rows.Err()
+}
+
+// loadRules reads the user's tracking rules and which of their projects are
+// live personal projects, the only ones imports use
+func (im *importer) loadRules(ctx context.Context) error {
+	var err error
+	if im.rules, err = rules.Load(ctx, im.userID); err != nil {
+		return err
+	}
+	im.ruleProjects = make(map[uuid.UUID]bool)
+	if len(im.rules) == 0 {
+		return nil
+	}
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id FROM projects
+		WHERE id = ANY($1) AND user_id = $2 AND organization_id IS NULL AND is_deleted = false
+	`, im.rules.ProjectIDs(), im.userID)
+	if err != nil {
+		return err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		im.ruleProjects[id] = true
+	}
+	return nil
+}
+
+// sessionKey identifies a session for duplicate detection
+type sessionKey struct {
+	start, end  time.Time
+	description string
+}
+
+func newSessionKey(start, end time.Time, description string) sessionKey {
+	return sessionKey{start.UTC(), end.UTC(), description}
+}
+
+// existingSessions returns the keys of the user's live sessions starting
+// when one of entries starts. Descriptions are compared here rather than in
+// the query, as they may be sealed by fieldcrypt.
+func (im *importer) existingSessions(ctx context.Context, tx pgx.Tx, entries []Entry) (map[sessionKey]bool, error) {
+	starts := make([]time.Time, 0, len(entries))
+	for _, entry := range entries {
+		starts = append(starts, entry.Start)
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT start_time, end_time, COALESCE(description, '') FROM timer_sessions
+		WHERE user_id = $1 AND start_time = ANY($2) AND is_deleted = false
+	`, im.userID, starts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[sessionKey]bool)
+	for rows.Next() {
+		var start, end time.Time
+		var description string
+		if err := rows.Scan(&start, &end, &description); err != nil {
+			return nil, err
+		}
+		if description, err = fieldcrypt.Open(fieldcrypt.SessionDescription, description); err != nil {
+			return nil, err
+		}
+		existing[newSessionKey(start, end, description)] = true
+	}
+	return existing, rows.Err()
+}
+
+// project returns the personal project with the given name, creating it if
+// needed. Names match case-insensitively. Clients have no counterpart in
+// Zebra, so a new project records its client in the description.
+func (im *importer) project(ctx context.Context, tx pgx.Tx, name, client string) (*uuid.UUID, bool, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, false, nil
+	}
+	key := strings.ToLower(name)
+	if id, ok := im.projects[key]; ok {
+		return id, false, nil
+	}
+
+	description := ""
+	if client != "" {
+		description = "Client: " + client
+	}
+	sealed, err := fieldcrypt.Seal(fieldcrypt.ProjectName, name)
+	if err != nil {
+		return nil, false, err
+	}
+	id := uuid.New()
+	_, err = tx.Exec(ctx, `
+		INSERT INTO projects (id, user_id, name, description, color, device_id)
+		VALUES ($1, $2, $3, $4, $5, 'import')
+	`, id, im.userID, sealed, description, defaultProjectColor)
+	if err != nil {
+		return nil, false, err
+	}
+	im.projects[key] = &id
+	return &id, true, nil
+}
+
+// ensureTags creates the tags the user does not have yet. Imported tags only
+// become available for the user to apply; sessions get their tags from the
+// tracking rules.
+func (im *importer) ensureTags(ctx context.Context, tx pgx.Tx, names []string) error {
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || im.tags[key] {
+			continue
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO tags (id, user_id, name, device_id)
+			SELECT $1, $2, $3, 'import'
+			WHERE NOT EXISTS (
+				SELECT 1 FROM tags WHERE user_id = $2 AND LOWER(name) = $4 AND is_deleted = false
+			)
+		`, uuid.New(), im.userID, name, key)
+		if err != nil {
+			return err
+		}
+		im.tags[key] = true
+	}
+	return nil
+}
+
+// defaultProjectColor is used for projects created by imports
+const defaultProjectColor = "#808080"
+
+// Limits matching those sync applies
+const (
+	maxNameLength        = 255
+	maxDescriptionLength = 10000
+	maxSessionLength     = 7 * 24 * time.Hour
+)
+
+// validateEntry returns why an entry cannot be imported, or ""
+func validateEntry(entry Entry) string {
+	switch {
+	case entry.Start.IsZero():
+		return "start time is missing"
+	case entry.End.IsZero():
+		return "end time is missing"
+	case entry.End.Before(entry.Start):
+		return "end time is before start time"
+	case entry.End.Sub(entry.Start) > maxSessionLength:
+		return "entry is longer than 7 days"
+	case len(entry.Project) > maxNameLength:
+		return "project name is too long"
+	case utf8.RuneCountInString(entry.Description) > maxDescriptionLength:
+		return fmt.Sprintf("description is longer than %d characters", maxDescriptionLength)
+	}
+	return ""
+}
@@ -0,0 +1,188 @@
+package imports
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SourceToggl names imports from Toggl Track
+const SourceToggl = "toggl"
+
+const togglAPI = "https://api.track.toggl.com/api/v9"
+
+var togglClient = &http.Client{Timeout: 60 * time.Second}
+
+// ParseTogglCSV reads a Toggl Track detailed report export. Toggl writes
+// local times without an offset, so they are read in loc. Rows that cannot
+// be read are returned as line errors instead of failing the whole file.
+func ParseTogglCSV(r io.Reader, loc *time.Location) ([]Entry, []LineError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading header: %v", err)
+	}
+	columns := headerColumns(header)
+	for _, required := range []string{"start date", "start time", "end date", "end time"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("missing %q column; export a detailed report from Toggl Track", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	parse := func(record []string, date, clock string) (time.Time, error) {
+		return time.ParseInLocation("2006-01-02 15:04:05", field(record, date)+" "+field(record, clock), loc)
+	}
+
+	var entries []Entry
+	var lineErrors []LineError
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			lineErrors = append(lineErrors, LineError{Line: line, Message: err.Error()})
+			continue
+		}
+
+		start, err := parse(record, "start date", "start time")
+		if err != nil {
+			lineErrors = append(lineErrors, LineError{Line: line, Message: "invalid start date or time"})
+			continue
+		}
+		end, err := parse(record, "end date", "end time")
+		if err != nil {
+			lineErrors = append(lineErrors, LineError{Line: line, Message: "invalid end date or time"})
+			continue
+		}
+
+		var tags []string
+		for _, tag := range strings.Split(field(record, "tags"), ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		entries = append(entries, Entry{
+			Line:        line,
+			Start:       start,
+			End:         end,
+			Project:     field(record, "project"),
+			Client:      field(record, "client"),
+			Description: field(record, "description"),
+			Tags:        tags,
+		})
+	}
+	return entries, lineErrors, nil
+}
+
+// FetchToggl reads the time entries between since and until through the
+// Toggl Track API. Running entries are skipped.
+func FetchToggl(ctx context.Context, apiToken string, since, until time.Time) ([]Entry, error) {
+	var clients []struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := togglGet(ctx, apiToken, "/me/clients", nil, &clients); err != nil {
+		return nil, err
+	}
+	clientNames := make(map[int64]string, len(clients))
+	for _, c := range clients {
+		clientNames[c.ID] = c.Name
+	}
+
+	var projects []struct {
+		ID       int64  `json:"id"`
+		Name     string `json:"name"`
+		ClientID *int64 `json:"client_id"`
+	}
+	if err := togglGet(ctx, apiToken, "/me/projects", nil, &projects); err != nil {
+		return nil, err
+	}
+	type togglProject struct{ name, client string }
+	projectNames := make(map[int64]togglProject, len(projects))
+	for _, p := range projects {
+		project := togglProject{name: p.Name}
+		if p.ClientID != nil {
+			project.client = clientNames[*p.ClientID]
+		}
+		projectNames[p.ID] = project
+	}
+
+	var timeEntries []struct {
+		ID          int64      `json:"id"`
+		Description string     `json:"description"`
+		Start       time.Time  `json:"start"`
+		Stop        *time.Time `json:"stop"`
+		ProjectID   *int64     `json:"project_id"`
+		Tags        []string   `json:"tags"`
+	}
+	query := url.Values{
+		"start_date": {since.UTC().Format(time.RFC3339)},
+		"end_date":   {until.UTC().Format(time.RFC3339)},
+	}
+	if err := togglGet(ctx, apiToken, "/me/time_entries", query, &timeEntries); err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(timeEntries))
+	for i, te := range timeEntries {
+		if te.Stop == nil {
+			continue
+		}
+		entry := Entry{
+			Line:        i + 1,
+			Start:       te.Start,
+			End:         *te.Stop,
+			Description: te.Description,
+			Tags:        te.Tags,
+		}
+		if te.ProjectID != nil {
+			project := projectNames[*te.ProjectID]
+			entry.Project, entry.Client = project.name, project.client
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func togglGet(ctx context.Context, apiToken, path string, query url.Values, out interface{}) error {
+	endpoint := togglAPI + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(apiToken, "api_token")
+
+	resp, err := togglClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling Toggl: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return errors.New("Toggl rejected the API token")
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("Toggl %s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
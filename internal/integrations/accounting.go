@@ -0,0 +1,264 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pacerclub/zebra-backend/internal/invoices"
+)
+
+// QuickBooks and Xero link accounting services that invoices are pushed to
+const (
+	QuickBooks = "quickbooks"
+	Xero       = "xero"
+)
+
+const xeroAPI = "https://api.xero.com"
+
+// QuickBooksSettings are the per-account options of the QuickBooks Online
+// integration
+type QuickBooksSettings struct {
+	// RealmID is the QuickBooks company, set when the account is linked
+	RealmID string `json:"realm_id"`
+	// ItemID is the product or service invoice lines are booked to
+	ItemID string `json:"item_id"`
+}
+
+// XeroSettings are the per-account options of the Xero integration
+type XeroSettings struct {
+	// TenantID is the Xero organisation, set when the account is linked
+	TenantID string `json:"tenant_id"`
+	// AccountCode is the revenue account invoice lines are booked to
+	AccountCode string `json:"account_code"`
+}
+
+// DefaultQuickBooksSettings returns the settings of a newly linked account
+func DefaultQuickBooksSettings() QuickBooksSettings {
+	return QuickBooksSettings{ItemID: "1"}
+}
+
+// DefaultXeroSettings returns the settings of a newly linked account
+func DefaultXeroSettings() XeroSettings {
+	return XeroSettings{AccountCode: "200"}
+}
+
+// QuickBooksOAuth reads the OAuth client from QUICKBOOKS_CLIENT_ID,
+// QUICKBOOKS_CLIENT_SECRET and QUICKBOOKS_REDIRECT_URL
+func QuickBooksOAuth() OAuthConfig {
+	return OAuthConfig{
+		ClientID:     os.Getenv("QUICKBOOKS_CLIENT_ID"),
+		ClientSecret: os.Getenv("QUICKBOOKS_CLIENT_SECRET"),
+		AuthURL:      "https://appcenter.intuit.com/connect/oauth2",
+		TokenURL:     "https://oauth.platform.intuit.com/oauth2/v1/tokens/bearer",
+		RedirectURL:  os.Getenv("QUICKBOOKS_REDIRECT_URL"),
+		Scopes:       []string{"com.intuit.quickbooks.accounting"},
+	}
+}
+
+// XeroOAuth reads the OAuth client from XERO_CLIENT_ID, XERO_CLIENT_SECRET
+// and XERO_REDIRECT_URL
+func XeroOAuth() OAuthConfig {
+	return OAuthConfig{
+		ClientID:     os.Getenv("XERO_CLIENT_ID"),
+		ClientSecret: os.Getenv("XERO_CLIENT_SECRET"),
+		AuthURL:      "https://login.xero.com/identity/connect/authorize",
+		TokenURL:     "https://identity.xero.com/connect/token",
+		RedirectURL:  os.Getenv("XERO_REDIRECT_URL"),
+		Scopes:       []string{"offline_access", "accounting.transactions", "accounting.contacts"},
+	}
+}
+
+// quickBooksAPI is the production API, or the sandbox with
+// QUICKBOOKS_SANDBOX=true
+func quickBooksAPI() string {
+	if os.Getenv("QUICKBOOKS_SANDBOX") == "true" {
+		return "https://sandbox-quickbooks.api.intuit.com"
+	}
+	return "https://quickbooks.api.intuit.com"
+}
+
+// accountingDo calls a JSON API with a bearer token
+func accountingDo(ctx context.Context, token, method, endpoint string, header http.Header, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s %s", method, endpoint, resp.Status, bytes.TrimSpace(message))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// XeroTenant returns the first Xero organisation a new token can reach
+func XeroTenant(ctx context.Context, token string) (string, error) {
+	var connections []struct {
+		TenantID string `json:"tenantId"`
+	}
+	if err := accountingDo(ctx, token, http.MethodGet, xeroAPI+"/connections", nil, nil, &connections); err != nil {
+		return "", err
+	}
+	if len(connections) == 0 {
+		return "", errors.New("no Xero organisation was authorized")
+	}
+	return connections[0].TenantID, nil
+}
+
+// QuickBooksPusher returns a Pusher creating invoices in the account's
+// QuickBooks company. Customers are matched by display name and created
+// when missing.
+func QuickBooksPusher(account *Account) (invoices.Pusher, error) {
+	settings := DefaultQuickBooksSettings()
+	if err := json.Unmarshal(account.Settings, &settings); err != nil {
+		return nil, err
+	}
+	base := quickBooksAPI() + "/v3/company/" + url.PathEscape(settings.RealmID)
+
+	return func(ctx context.Context, inv invoices.Invoice) (string, error) {
+		token, err := accessToken(ctx, QuickBooksOAuth(), account)
+		if err != nil {
+			return "", err
+		}
+
+		var found struct {
+			QueryResponse struct {
+				Customer []struct {
+					ID string `json:"Id"`
+				} `json:"Customer"`
+			} `json:"QueryResponse"`
+		}
+		query := "select Id from Customer where DisplayName = '" + strings.ReplaceAll(inv.Client, "'", `\'`) + "'"
+		if err := accountingDo(ctx, token, http.MethodGet, base+"/query?query="+url.QueryEscape(query), nil, nil, &found); err != nil {
+			return "", err
+		}
+		var customerID string
+		if len(found.QueryResponse.Customer) > 0 {
+			customerID = found.QueryResponse.Customer[0].ID
+		} else {
+			var created struct {
+				Customer struct {
+					ID string `json:"Id"`
+				} `json:"Customer"`
+			}
+			if err := accountingDo(ctx, token, http.MethodPost, base+"/customer", nil, map[string]string{"DisplayName": inv.Client}, &created); err != nil {
+				return "", err
+			}
+			customerID = created.Customer.ID
+		}
+
+		lines := []map[string]interface{}{}
+		for _, line := range inv.Lines {
+			lines = append(lines, map[string]interface{}{
+				"DetailType":  "SalesItemLineDetail",
+				"Amount":      float64(line.AmountCents) / 100,
+				"Description": line.Description,
+				"SalesItemLineDetail": map[string]interface{}{
+					"ItemRef":   map[string]string{"value": settings.ItemID},
+					"Qty":       line.Hours,
+					"UnitPrice": float64(line.RateCents) / 100,
+				},
+			})
+		}
+		var created struct {
+			Invoice struct {
+				ID string `json:"Id"`
+			} `json:"Invoice"`
+		}
+		body := map[string]interface{}{
+			"CustomerRef": map[string]string{"value": customerID},
+			"Line":        lines,
+			"TxnDate":     inv.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02"),
+			"PrivateNote": inv.Memo(),
+			"CurrencyRef": map[string]string{"value": inv.Currency},
+		}
+		if err := accountingDo(ctx, token, http.MethodPost, base+"/invoice", nil, body, &created); err != nil {
+			return "", err
+		}
+		return created.Invoice.ID, nil
+	}, nil
+}
+
+// XeroPusher returns a Pusher creating draft invoices in the account's Xero
+// organisation. Xero matches contacts by name and creates missing ones.
+func XeroPusher(account *Account) (invoices.Pusher, error) {
+	settings := DefaultXeroSettings()
+	if err := json.Unmarshal(account.Settings, &settings); err != nil {
+		return nil, err
+	}
+	header := http.Header{"Xero-Tenant-Id": {settings.TenantID}}
+
+	return func(ctx context.Context, inv invoices.Invoice) (string, error) {
+		token, err := accessToken(ctx, XeroOAuth(), account)
+		if err != nil {
+			return "", err
+		}
+
+		lines := []map[string]interface{}{}
+		for _, line := range inv.Lines {
+			lines = append(lines, map[string]interface{}{
+				"Description": line.Description,
+				"Quantity":    line.Hours,
+				"UnitAmount":  float64(line.RateCents) / 100,
+				"AccountCode": settings.AccountCode,
+			})
+		}
+		body := map[string]interface{}{
+			"Invoices": []map[string]interface{}{{
+				"Type":         "ACCREC",
+				"Status":       "DRAFT",
+				"Contact":      map[string]string{"Name": inv.Client},
+				"Date":         inv.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02"),
+				"Reference":    inv.Memo(),
+				"CurrencyCode": inv.Currency,
+				"LineItems":    lines,
+			}},
+		}
+		var created struct {
+			Invoices []struct {
+				InvoiceID string `json:"InvoiceID"`
+			} `json:"Invoices"`
+		}
+		if err := accountingDo(ctx, token, http.MethodPost, xeroAPI+"/api.xro/2.0/Invoices", header, body, &created); err != nil {
+			return "", err
+		}
+		if len(created.Invoices) == 0 {
+			return "", errors.New("xero returned no invoice")
+		}
+		return created.Invoices[0].InvoiceID, nil
+	}, nil
+}
@@ -0,0 +1,209 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+var ErrAccountNotFound = errors.New("integration account not found")
+var ErrInvalidState = errors.New("invalid or expired OAuth state")
+
+// stateTTL is how long a user has to finish an authorization
+const stateTTL = 10 * time.Minute
+
+// refreshMargin renews access tokens this long before they expire
+const refreshMargin = time.Minute
+
+// Account is a user's link to a third-party service. Tokens never leave the
+// server.
+type Account struct {
+	ID             uuid.UUID       `json:"id"`
+	UserID         uuid.UUID       `json:"user_id"`
+	Provider       string          `json:"provider"`
+	AccessToken    string          `json:"-"`
+	RefreshToken   string          `json:"-"`
+	TokenExpiresAt *time.Time      `json:"-"`
+	Settings       json.RawMessage `json:"settings"`
+	LastSyncedAt   *time.Time      `json:"last_synced_at"`
+	LastError      string          `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+const accountColumns = `id, user_id, provider, access_token, refresh_token, token_expires_at, settings,
+	last_synced_at, last_error, created_at, updated_at`
+
+func scanAccount(row pgx.Row) (*Account, error) {
+	var a Account
+	err := row.Scan(&a.ID, &a.UserID, &a.Provider, &a.AccessToken, &a.RefreshToken, &a.TokenExpiresAt, &a.Settings,
+		&a.LastSyncedAt, &a.LastError, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// CreateState records an authorization started by the user and returns the
+// state to pass to the provider
+func CreateState(ctx context.Context, userID uuid.UUID, provider string) (string, error) {
+	state, err := newState()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.Pool.Exec(ctx,
+		`INSERT INTO oauth_states (state, user_id, provider) VALUES ($1, $2, $3)`,
+		state, userID, provider)
+	if err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+// ConsumeState returns the user who started the authorization for state and
+// forgets the state. Expired or unknown states yield ErrInvalidState.
+func ConsumeState(ctx context.Context, state, provider string) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := db.Pool.QueryRow(ctx,
+		`DELETE FROM oauth_states
+		WHERE state = $1 AND provider = $2 AND created_at > $3
+		RETURNING user_id`,
+		state, provider, time.Now().Add(-stateTTL),
+	).Scan(&userID)
+
+	if err == pgx.ErrNoRows {
+		return uuid.Nil, ErrInvalidState
+	}
+	return userID, err
+}
+
+// SaveAccount links the user to a provider, replacing the tokens of an
+// existing link but keeping its settings. Newly linked accounts start with
+// settings and their sync cursor at now.
+func SaveAccount(ctx context.Context, userID uuid.UUID, provider string, token *Token, settings interface{}) (*Account, error) {
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanAccount(db.Pool.QueryRow(ctx, `
+		INSERT INTO integration_accounts (user_id, provider, access_token, refresh_token, token_expires_at, settings, last_synced_at)
+		VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id, provider) DO UPDATE
+		SET access_token = EXCLUDED.access_token,
+			refresh_token = CASE WHEN EXCLUDED.refresh_token = '' THEN integration_accounts.refresh_token ELSE EXCLUDED.refresh_token END,
+			token_expires_at = EXCLUDED.token_expires_at,
+			last_error = ''
+		RETURNING `+accountColumns,
+		userID, provider, token.AccessToken, token.RefreshToken, token.ExpiresAt, raw))
+}
+
+// GetAccount returns the user's link to a provider
+func GetAccount(ctx context.Context, userID uuid.UUID, provider string) (*Account, error) {
+	account, err := scanAccount(db.Pool.QueryRow(ctx,
+		`SELECT `+accountColumns+` FROM integration_accounts WHERE user_id = $1 AND provider = $2`,
+		userID, provider))
+	if err == pgx.ErrNoRows {
+		return nil, ErrAccountNotFound
+	}
+	return account, err
+}
+
+// ListAccounts returns every account linked to a provider
+func ListAccounts(ctx context.Context, provider string) ([]*Account, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+accountColumns+` FROM integration_accounts WHERE provider = $1 ORDER BY id`,
+		provider)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*Account
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// UpdateSettings replaces an account's provider-specific settings
+func UpdateSettings(ctx context.Context, accountID uuid.UUID, settings interface{}) error {
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	_, err = db.Pool.Exec(ctx,
+		`UPDATE integration_accounts SET settings = $2 WHERE id = $1`,
+		accountID, raw)
+	return err
+}
+
+// DeleteAccount unlinks the user from a provider
+func DeleteAccount(ctx context.Context, userID uuid.UUID, provider string) error {
+	result, err := db.Pool.Exec(ctx,
+		`DELETE FROM integration_accounts WHERE user_id = $1 AND provider = $2`,
+		userID, provider)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// recordSync moves the account's sync cursor and records the outcome of a
+// sync run
+func recordSync(ctx context.Context, accountID uuid.UUID, syncedAt *time.Time, syncErr error) error {
+	message := ""
+	if syncErr != nil {
+		message = syncErr.Error()
+	}
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE integration_accounts
+		SET last_synced_at = COALESCE($2, last_synced_at), last_error = $3
+		WHERE id = $1
+	`, accountID, syncedAt, message)
+	return err
+}
+
+// accessToken returns a usable access token for the account, refreshing and
+// storing it first if it is about to expire
+func accessToken(ctx context.Context, cfg OAuthConfig, account *Account) (string, error) {
+	if account.TokenExpiresAt == nil || time.Until(*account.TokenExpiresAt) > refreshMargin {
+		return account.AccessToken, nil
+	}
+	if account.RefreshToken == "" {
+		return "", errors.New("access token expired and no refresh token is stored; reconnect the account")
+	}
+
+	token, err := cfg.Refresh(ctx, account.RefreshToken)
+	if err != nil {
+		return "", fmt.Errorf("error refreshing token: %v", err)
+	}
+
+	_, err = db.Pool.Exec(ctx, `
+		UPDATE integration_accounts
+		SET access_token = $2, refresh_token = $3, token_expires_at = $4
+		WHERE id = $1
+	`, account.ID, token.AccessToken, token.RefreshToken, token.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	account.AccessToken = token.AccessToken
+	account.RefreshToken = token.RefreshToken
+	account.TokenExpiresAt = token.ExpiresAt
+	return token.AccessToken, nil
+}
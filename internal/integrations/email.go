@@ -0,0 +1,207 @@
+package integrations
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// Email gives the user a private address; each line of a message sent to it
+// such as "2h project-x writing docs" is logged as a session
+const Email = "email"
+
+// MaxEmailEntries caps how many sessions one message may log
+const MaxEmailEntries = 20
+
+// maxEmailEntry is the longest session one line may log
+const maxEmailEntry = 24 * time.Hour
+
+// mailgunMaxAge is how far the timestamp of an inbound webhook may be from
+// now; tokens are remembered for as long to refuse replays
+const mailgunMaxAge = 15 * time.Minute
+
+var (
+	emailDomain       string
+	mailgunSigningKey string
+
+	// seenMailgunTokens holds the tokens of verified webhooks until their
+	// timestamp is too old to be accepted again
+	seenMailgunTokens   = map[string]time.Time{}
+	seenMailgunTokensMu sync.Mutex
+)
+
+// EmailEntry is one parsed line of an inbound message
+type EmailEntry struct {
+	Line        int
+	Duration    time.Duration
+	Project     string
+	Description string
+}
+
+// ConfigureEmail sets the domain inbound mail is received on and the key
+// Mailgun signs its webhooks with; an empty domain turns inbound mail off
+func ConfigureEmail(domain, signingKey string) {
+	emailDomain, mailgunSigningKey = domain, signingKey
+}
+
+// LoadEnv configures inbound mail from INBOUND_EMAIL_DOMAIN and
+// MAILGUN_WEBHOOK_SIGNING_KEY. Inbound mail stays off without
+// INBOUND_EMAIL_DOMAIN.
+func LoadEnv() error {
+	domain := os.Getenv("INBOUND_EMAIL_DOMAIN")
+	key := os.Getenv("MAILGUN_WEBHOOK_SIGNING_KEY")
+	if domain != "" && key == "" {
+		return errors.New("MAILGUN_WEBHOOK_SIGNING_KEY is required with INBOUND_EMAIL_DOMAIN")
+	}
+	ConfigureEmail(domain, key)
+	return nil
+}
+
+// EmailDomain is the domain inbound mail is received on, empty when
+// inbound mail is off
+func EmailDomain() string {
+	return emailDomain
+}
+
+// NewEmailToken returns a new local part for a user's inbound address
+func NewEmailToken() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "log-" + hex.EncodeToString(b), nil
+}
+
+// EmailAddress returns the inbound address for a token
+func EmailAddress(token string) string {
+	return token + "@" + EmailDomain()
+}
+
+// AccountByEmailRecipient returns the account one of the recipients, as
+// listed in a To header, belongs to
+func AccountByEmailRecipient(ctx context.Context, recipients string) (*Account, error) {
+	suffix := "@" + strings.ToLower(EmailDomain())
+	for _, recipient := range strings.Split(recipients, ",") {
+		recipient = strings.ToLower(strings.TrimSpace(recipient))
+		if i := strings.LastIndex(recipient, "<"); i >= 0 {
+			recipient = strings.TrimSuffix(recipient[i+1:], ">")
+		}
+		if !strings.HasSuffix(recipient, suffix) {
+			continue
+		}
+		account, err := scanAccount(db.Pool.QueryRow(ctx,
+			`SELECT `+accountColumns+` FROM integration_accounts WHERE provider = $1 AND access_token = $2`,
+			Email, strings.TrimSuffix(recipient, suffix)))
+		if err == pgx.ErrNoRows {
+			continue
+		}
+		return account, err
+	}
+	return nil, ErrAccountNotFound
+}
+
+// VerifyMailgun checks the signature Mailgun adds to inbound webhooks with
+// the configured signing key. A token is accepted once: a signed request
+// sent again within mailgunMaxAge is refused as a replay.
+func VerifyMailgun(timestamp, token, signature string) bool {
+	if mailgunSigningKey == "" || token == "" {
+		return false
+	}
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	sent := time.Unix(sec, 0)
+	if age := time.Since(sent); age > mailgunMaxAge || age < -mailgunMaxAge {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(mailgunSigningKey))
+	mac.Write([]byte(timestamp + token))
+	if !hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(signature)) {
+		return false
+	}
+	return firstMailgunToken(token, sent.Add(mailgunMaxAge))
+}
+
+// firstMailgunToken records a verified token until expires, reporting
+// whether it had not been seen. Expired tokens are dropped on the way.
+func firstMailgunToken(token string, expires time.Time) bool {
+	seenMailgunTokensMu.Lock()
+	defer seenMailgunTokensMu.Unlock()
+
+	now := time.Now()
+	for t, exp := range seenMailgunTokens {
+		if now.After(exp) {
+			delete(seenMailgunTokens, t)
+		}
+	}
+	if _, seen := seenMailgunTokens[token]; seen {
+		return false
+	}
+	seenMailgunTokens[token] = expires
+	return true
+}
+
+// ParseEmailEntries reads one entry per non-empty line of text in the form
+// "<duration> <project> [description]", where the duration is like 2h,
+// 1.5h, 1h30m or 45m. Reading stops at a signature separator. It returns the
+// entries and a message for each line that could not be read.
+func ParseEmailEntries(text string) ([]EmailEntry, []string) {
+	var entries []EmailEntry
+	var problems []string
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "--" {
+			break
+		}
+		if raw == "" {
+			continue
+		}
+
+		fields := strings.Fields(raw)
+		if len(fields) < 2 {
+			problems = append(problems, fmt.Sprintf("line %d: expected a duration and a project in %q", line, raw))
+			continue
+		}
+		duration, err := time.ParseDuration(strings.TrimSuffix(strings.ToLower(fields[0]), "in"))
+		if err != nil || duration <= 0 || duration > maxEmailEntry {
+			problems = append(problems, fmt.Sprintf("line %d: %q is not a duration like 2h, 1h30m or 45m", line, fields[0]))
+			continue
+		}
+		entries = append(entries, EmailEntry{
+			Line:        line,
+			Duration:    duration,
+			Project:     fields[1],
+			Description: strings.Join(fields[2:], " "),
+		})
+	}
+
+	if len(entries)+len(problems) > MaxEmailEntries {
+		problems = append(problems, fmt.Sprintf("a message may log at most %d sessions", MaxEmailEntries))
+	}
+	return entries, problems
+}
+
+// EmailProjectKey normalizes a project name so "Project X" can be written
+// as project-x
+func EmailProjectKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), "-"))
+}
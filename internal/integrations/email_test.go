@@ -0,0 +1,39 @@
+package integrations
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifyMailgunRefusesReplays(t *testing.T) {
+	ConfigureEmail("in.example.com", "key-test")
+	t.Cleanup(func() { ConfigureEmail("", "") })
+
+	sign := func(timestamp, token string) string {
+		mac := hmac.New(sha256.New, []byte("key-test"))
+		mac.Write([]byte(timestamp + token))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	if VerifyMailgun(now, "token-1", sign(now, "token-2")) {
+		t.Error("a signature of another token was accepted")
+	}
+	if VerifyMailgun(old, "token-old", sign(old, "token-old")) {
+		t.Error("a signature an hour old was accepted")
+	}
+	if !VerifyMailgun(now, "token-1", sign(now, "token-1")) {
+		t.Fatal("a valid signature was refused")
+	}
+	if VerifyMailgun(now, "token-1", sign(now, "token-1")) {
+		t.Error("a replayed signature was accepted")
+	}
+	if !VerifyMailgun(now, "token-2", sign(now, "token-2")) {
+		t.Error("a new token was refused after a replay")
+	}
+}
@@ -0,0 +1,341 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// GoogleCalendar links a Google account so tracked sessions can be pushed as
+// calendar events and calendar events imported as suggested sessions
+const GoogleCalendar = "google_calendar"
+
+const googleCalendarAPI = "https://www.googleapis.com/calendar/v3"
+
+// sessionProperty tags events created from sessions so they are not
+// imported back as suggestions
+const sessionProperty = "zebraSessionId"
+
+// importWindow is how far back calendar events are imported
+const importWindow = 7 * 24 * time.Hour
+
+// settleDelay keeps the push cursor behind transactions that may still
+// commit sessions with an earlier server_updated_at
+const settleDelay = time.Minute
+
+// GoogleCalendarSettings are the per-account options of the integration
+type GoogleCalendarSettings struct {
+	CalendarID   string `json:"calendar_id"`
+	PushSessions bool   `json:"push_sessions"`
+	ImportEvents bool   `json:"import_events"`
+}
+
+const maxCalendarIDLength = 255
+
+// Validate checks the settings and trims the calendar id
+func (s *GoogleCalendarSettings) Validate(v *validate.Validator) {
+	s.CalendarID = strings.TrimSpace(s.CalendarID)
+	v.Required("calendar_id", s.CalendarID)
+	v.MaxLength("calendar_id", s.CalendarID, maxCalendarIDLength)
+}
+
+// DefaultGoogleCalendarSettings returns the settings of a newly linked
+// account
+func DefaultGoogleCalendarSettings() GoogleCalendarSettings {
+	return GoogleCalendarSettings{CalendarID: "primary"}
+}
+
+// GoogleCalendarOAuth reads the OAuth client from GOOGLE_CLIENT_ID,
+// GOOGLE_CLIENT_SECRET and GOOGLE_REDIRECT_URL
+func GoogleCalendarOAuth() OAuthConfig {
+	return OAuthConfig{
+		ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
+		ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+		AuthURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:     "https://oauth2.googleapis.com/token",
+		RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
+		Scopes:       []string{"https://www.googleapis.com/auth/calendar.events"},
+		// Offline access with forced consent always returns a refresh token
+		AuthParams: map[string]string{"access_type": "offline", "prompt": "consent"},
+	}
+}
+
+type calendarTime struct {
+	DateTime *time.Time `json:"dateTime,omitempty"`
+	Date     string     `json:"date,omitempty"`
+}
+
+type eventProperties struct {
+	Private map[string]string `json:"private,omitempty"`
+}
+
+type calendarEvent struct {
+	ID                 string           `json:"id,omitempty"`
+	Status             string           `json:"status,omitempty"`
+	Summary            string           `json:"summary"`
+	Description        string           `json:"description,omitempty"`
+	Start              calendarTime     `json:"start"`
+	End                calendarTime     `json:"end"`
+	ExtendedProperties *eventProperties `json:"extendedProperties,omitempty"`
+}
+
+// calendarClient calls the Google Calendar API for one account
+type calendarClient struct {
+	token      string
+	calendarID string
+}
+
+func (c *calendarClient) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	endpoint := googleCalendarAPI + "/calendars/" + url.PathEscape(c.calendarID) + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Deleting an event that is already gone is not an error
+	if method == http.MethodDelete && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone) {
+		return nil
+	}
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("google calendar %s %s: %s %s", method, path, resp.Status, bytes.TrimSpace(message))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// SyncGoogleCalendar pushes the account's changed sessions and imports recent
+// events, depending on its settings
+func SyncGoogleCalendar(ctx context.Context, cfg OAuthConfig, account *Account) error {
+	settings := DefaultGoogleCalendarSettings()
+	if err := json.Unmarshal(account.Settings, &settings); err != nil {
+		return err
+	}
+	if !settings.PushSessions && !settings.ImportEvents {
+		return nil
+	}
+
+	token, err := accessToken(ctx, cfg, account)
+	if err != nil {
+		return err
+	}
+	client := &calendarClient{token: token, calendarID: settings.CalendarID}
+
+	until := time.Now().Add(-settleDelay)
+	if settings.PushSessions {
+		if err := pushSessions(ctx, client, account, until); err != nil {
+			return err
+		}
+	}
+	if settings.ImportEvents {
+		if err := importEvents(ctx, client, account); err != nil {
+			return err
+		}
+	}
+	return recordSync(ctx, account.ID, &until, nil)
+}
+
+// pushSessions creates, updates or deletes the events of sessions changed
+// since the account last synced. Encrypted descriptions are not pushed.
+func pushSessions(ctx context.Context, client *calendarClient, account *Account, until time.Time) error {
+	since := account.CreatedAt
+	if account.LastSyncedAt != nil {
+		since = *account.LastSyncedAt
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT s.id, s.start_time, s.end_time, COALESCE(s.description, ''), s.key_id, s.is_deleted,
+			COALESCE(p.name, ''), COALESCE(l.event_id, '')
+		FROM timer_sessions s
+		LEFT JOIN projects p ON p.id = s.project_id AND p.key_id = ''
+		LEFT JOIN calendar_event_links l ON l.account_id = $2 AND l.session_id = s.id
+		WHERE s.user_id = $1 AND s.server_updated_at >= $3 AND s.server_updated_at < $4
+		ORDER BY s.server_updated_at
+	`, account.UserID, account.ID, since, until)
+	if err != nil {
+		return err
+	}
+
+	type pending struct {
+		sessionID uuid.UUID
+		event     calendarEvent
+		eventID   string
+		deleted   bool
+	}
+	var sessions []pending
+	for rows.Next() {
+		var p pending
+		var start, end time.Time
+		var description, keyID, projectName string
+		if err := rows.Scan(&p.sessionID, &start, &end, &description, &keyID, &p.deleted, &projectName, &p.eventID); err != nil {
+			rows.Close()
+			return err
+		}
+		err := fieldcrypt.OpenPtr(fieldcrypt.SessionDescription, &description)
+		if err == nil {
+			err = fieldcrypt.OpenPtr(fieldcrypt.ProjectName, &projectName)
+		}
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		p.event = calendarEvent{
+			Summary:            projectName,
+			Description:        description,
+			Start:              calendarTime{DateTime: &start},
+			End:                calendarTime{DateTime: &end},
+			ExtendedProperties: &eventProperties{Private: map[string]string{sessionProperty: p.sessionID.String()}},
+		}
+		if p.event.Summary == "" {
+			p.event.Summary = "Zebra session"
+		}
+		if keyID != "" {
+			p.event.Description = ""
+		}
+		sessions = append(sessions, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range sessions {
+		switch {
+		case p.deleted && p.eventID == "":
+			continue
+		case p.deleted:
+			if err := client.do(ctx, http.MethodDelete, "/events/"+url.PathEscape(p.eventID), nil, nil, nil); err != nil {
+				return err
+			}
+			_, err = db.Pool.Exec(ctx,
+				`DELETE FROM calendar_event_links WHERE account_id = $1 AND session_id = $2`,
+				account.ID, p.sessionID)
+		case p.eventID != "":
+			err = client.do(ctx, http.MethodPatch, "/events/"+url.PathEscape(p.eventID), nil, p.event, nil)
+		default:
+			var created calendarEvent
+			if err := client.do(ctx, http.MethodPost, "/events", nil, p.event, &created); err != nil {
+				return err
+			}
+			_, err = db.Pool.Exec(ctx,
+				`INSERT INTO calendar_event_links (account_id, session_id, event_id) VALUES ($1, $2, $3)
+				ON CONFLICT (account_id, session_id) DO UPDATE SET event_id = EXCLUDED.event_id, synced_at = CURRENT_TIMESTAMP`,
+				account.ID, p.sessionID, created.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importEvents stores timed events from the recent past as suggested
+// sessions. Events already suggested, and events pushed from sessions, are
+// skipped.
+func importEvents(ctx context.Context, client *calendarClient, account *Account) error {
+	now := time.Now()
+	query := url.Values{
+		"timeMin":      {now.Add(-importWindow).Format(time.RFC3339)},
+		"timeMax":      {now.Format(time.RFC3339)},
+		"singleEvents": {"true"},
+		"orderBy":      {"startTime"},
+		"maxResults":   {"250"},
+	}
+
+	for {
+		var page struct {
+			Items         []calendarEvent `json:"items"`
+			NextPageToken string          `json:"nextPageToken"`
+		}
+		if err := client.do(ctx, http.MethodGet, "/events", query, nil, &page); err != nil {
+			return err
+		}
+
+		for _, event := range page.Items {
+			if event.Status == "cancelled" || event.Start.DateTime == nil || event.End.DateTime == nil {
+				continue
+			}
+			if event.ExtendedProperties != nil && event.ExtendedProperties.Private[sessionProperty] != "" {
+				continue
+			}
+			if !event.End.DateTime.After(*event.Start.DateTime) {
+				continue
+			}
+			_, err := db.Pool.Exec(ctx, `
+				INSERT INTO suggested_sessions (user_id, account_id, external_id, title, start_time, end_time)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				ON CONFLICT (account_id, external_id) DO NOTHING
+			`, account.UserID, account.ID, event.ID, event.Summary, *event.Start.DateTime, *event.End.DateTime)
+			if err != nil {
+				return err
+			}
+		}
+
+		if page.NextPageToken == "" {
+			return nil
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// RunGoogleCalendarSync syncs every linked Google Calendar account once,
+// as a scheduled task. It does nothing if the OAuth client is not
+// configured. Failures of single accounts are recorded on them.
+func RunGoogleCalendarSync(ctx context.Context) error {
+	cfg := GoogleCalendarOAuth()
+	if !cfg.Configured() {
+		return nil
+	}
+
+	accounts, err := ListAccounts(ctx, GoogleCalendar)
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		if err := SyncGoogleCalendar(ctx, cfg, account); err != nil {
+			slog.Warn("Google Calendar sync for user failed", "user_id", account.UserID, "error", err)
+			if err := recordSync(ctx, account.ID, nil, err); err != nil {
+				slog.Error("Failed to record Google Calendar sync error", "error", err)
+			}
+		}
+	}
+	return nil
+}
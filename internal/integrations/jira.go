@@ -0,0 +1,487 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+// Jira links a Jira Cloud site so sessions mentioning an issue are exported
+// as worklogs on that issue
+const Jira = "jira"
+
+// Worklog export statuses
+const (
+	WorklogPending  = "pending"
+	WorklogExported = "exported"
+	WorklogFailed   = "failed"
+	// WorklogSkipped marks sessions the user opted out of exporting
+	WorklogSkipped = "skipped"
+)
+
+// maxWorklogAttempts is how often a failing export is tried before it is
+// left for the user to retry
+const maxWorklogAttempts = 8
+
+// worklogBatchSize caps how many worklogs one run exports per account
+const worklogBatchSize = 200
+
+// minWorklogDuration is the shortest time Jira accepts on a worklog
+const minWorklogDuration = time.Minute
+
+var issueKeyPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9_]+-[1-9][0-9]*\b`)
+
+// JiraSettings are the per-account options of the integration. The API
+// token is stored as the account's access token.
+type JiraSettings struct {
+	SiteURL        string `json:"site_url"`
+	Email          string `json:"email"`
+	ExportSessions bool   `json:"export_sessions"`
+}
+
+// JiraWorklog is the export state of one session
+type JiraWorklog struct {
+	SessionID uuid.UUID `json:"session_id"`
+	// ExplicitIssueKey overrides the key found in the description; an empty
+	// key keeps the session from being exported
+	ExplicitIssueKey *string    `json:"explicit_issue_key"`
+	IssueKey         string     `json:"issue_key"`
+	WorklogID        string     `json:"worklog_id"`
+	Status           string     `json:"status"`
+	Attempts         int        `json:"attempts"`
+	LastError        string     `json:"last_error"`
+	NextAttemptAt    *time.Time `json:"next_attempt_at"`
+	ExportedAt       *time.Time `json:"exported_at"`
+}
+
+// ParseIssueKey returns the first Jira issue key mentioned in text, or ""
+func ParseIssueKey(text string) string {
+	return issueKeyPattern.FindString(text)
+}
+
+// ValidIssueKey reports whether key is a whole Jira issue key
+func ValidIssueKey(key string) bool {
+	return issueKeyPattern.FindString(key) == key && key != ""
+}
+
+// jiraError is a failed call to the Jira API
+type jiraError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *jiraError) Error() string {
+	return e.Message
+}
+
+// jiraClient calls the Jira Cloud REST API with basic auth for one account
+type jiraClient struct {
+	siteURL string
+	email   string
+	token   string
+}
+
+func newJiraClient(settings JiraSettings, token string) *jiraClient {
+	return &jiraClient{siteURL: strings.TrimRight(settings.SiteURL, "/"), email: settings.Email, token: token}
+}
+
+func (c *jiraClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.siteURL+"/rest/api/2"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.email, c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &jiraError{
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("jira %s %s: %s %s", method, path, resp.Status, bytes.TrimSpace(message)),
+		}
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// VerifyJira checks the site URL and credentials by fetching the current
+// Jira user
+func VerifyJira(ctx context.Context, settings JiraSettings, token string) error {
+	site, err := url.Parse(settings.SiteURL)
+	if err != nil || site.Scheme != "https" || site.Host == "" {
+		return errors.New("site_url must be an https URL such as https://example.atlassian.net")
+	}
+	return newJiraClient(settings, token).do(ctx, http.MethodGet, "/myself", nil, nil)
+}
+
+// SetIssueKey sets or, with a nil key, clears the issue key of one of the
+// user's sessions and queues it for export. Sessions given a key are
+// exported even if they changed before the account was linked.
+func SetIssueKey(ctx context.Context, accountID, sessionID uuid.UUID, key *string) error {
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO jira_worklogs (account_id, session_id, explicit_issue_key)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (account_id, session_id) DO UPDATE
+		SET explicit_issue_key = EXCLUDED.explicit_issue_key, status = $4, attempts = 0, next_attempt_at = NULL
+	`, accountID, sessionID, key, WorklogPending)
+	return err
+}
+
+// ListWorklogs returns the export state of the account's sessions, most
+// recently exported first
+func ListWorklogs(ctx context.Context, accountID uuid.UUID, status string) ([]JiraWorklog, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT session_id, explicit_issue_key, issue_key, worklog_id, status, attempts, last_error,
+			next_attempt_at, exported_at
+		FROM jira_worklogs
+		WHERE account_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY exported_at DESC NULLS FIRST, session_id
+		LIMIT 500
+	`, accountID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	worklogs := []JiraWorklog{}
+	for rows.Next() {
+		var w JiraWorklog
+		err := rows.Scan(&w.SessionID, &w.ExplicitIssueKey, &w.IssueKey, &w.WorklogID, &w.Status, &w.Attempts,
+			&w.LastError, &w.NextAttemptAt, &w.ExportedAt)
+		if err != nil {
+			return nil, err
+		}
+		worklogs = append(worklogs, w)
+	}
+	return worklogs, rows.Err()
+}
+
+// RetryFailedWorklogs queues every failed export of the account again and
+// returns how many were queued
+func RetryFailedWorklogs(ctx context.Context, accountID uuid.UUID) (int64, error) {
+	result, err := db.Pool.Exec(ctx, `
+		UPDATE jira_worklogs SET status = $2, attempts = 0, next_attempt_at = NULL
+		WHERE account_id = $1 AND status = $3
+	`, accountID, WorklogPending, WorklogFailed)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// ExportJira queues the account's sessions changed since the last run and
+// exports the queued ones. A failing worklog is retried with backoff on
+// later runs and does not stop the others.
+func ExportJira(ctx context.Context, account *Account) error {
+	var settings JiraSettings
+	if err := json.Unmarshal(account.Settings, &settings); err != nil {
+		return err
+	}
+	if !settings.ExportSessions {
+		return nil
+	}
+
+	until := time.Now().Add(-settleDelay)
+	if err := queueWorklogs(ctx, account, until); err != nil {
+		return err
+	}
+	if err := exportWorklogs(ctx, newJiraClient(settings, account.AccessToken), account); err != nil {
+		return err
+	}
+	return recordSync(ctx, account.ID, &until, nil)
+}
+
+// queueWorklogs marks sessions changed since the account last ran for
+// export when they mention an issue or already have a worklog
+func queueWorklogs(ctx context.Context, account *Account, until time.Time) error {
+	since := account.CreatedAt
+	if account.LastSyncedAt != nil {
+		since = *account.LastSyncedAt
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT s.id, COALESCE(s.description, ''), s.key_id, w.explicit_issue_key, COALESCE(w.worklog_id, '')
+		FROM timer_sessions s
+		LEFT JOIN jira_worklogs w ON w.account_id = $2 AND w.session_id = s.id
+		WHERE s.user_id = $1 AND s.server_updated_at >= $3 AND s.server_updated_at < $4
+	`, account.UserID, account.ID, since, until)
+	if err != nil {
+		return err
+	}
+
+	var queue []uuid.UUID
+	for rows.Next() {
+		var sessionID uuid.UUID
+		var description, keyID, worklogID string
+		var explicitKey *string
+		if err := rows.Scan(&sessionID, &description, &keyID, &explicitKey, &worklogID); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := fieldcrypt.OpenPtr(fieldcrypt.SessionDescription, &description); err != nil {
+			rows.Close()
+			return err
+		}
+		if worklogID != "" || sessionIssueKey(explicitKey, description, keyID) != "" {
+			queue = append(queue, sessionID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, sessionID := range queue {
+		_, err := db.Pool.Exec(ctx, `
+			INSERT INTO jira_worklogs (account_id, session_id) VALUES ($1, $2)
+			ON CONFLICT (account_id, session_id) DO UPDATE
+			SET status = $3, attempts = 0, next_attempt_at = NULL
+		`, account.ID, sessionID, WorklogPending)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sessionIssueKey returns the issue a session is logged against. Encrypted
+// descriptions cannot be read, so those sessions need an explicit key.
+func sessionIssueKey(explicitKey *string, description, keyID string) string {
+	if explicitKey != nil {
+		return *explicitKey
+	}
+	if keyID != "" {
+		return ""
+	}
+	return ParseIssueKey(description)
+}
+
+type queuedWorklog struct {
+	sessionID   uuid.UUID
+	explicitKey *string
+	issueKey    string
+	worklogID   string
+	attempts    int
+	start       *time.Time
+	end         *time.Time
+	breaks      time.Duration
+	description string
+	keyID       string
+	deleted     bool
+}
+
+type jiraWorklogBody struct {
+	Started          string `json:"started"`
+	TimeSpentSeconds int64  `json:"timeSpentSeconds"`
+	Comment          string `json:"comment,omitempty"`
+}
+
+// exportWorklogs creates, updates or deletes the worklogs of queued
+// sessions and of failed ones that are due for another attempt. Durations
+// are rounded when the user's rounding preference applies to exports.
+func exportWorklogs(ctx context.Context, client *jiraClient, account *Account) error {
+	rounding, err := models.GetRounding(ctx, account.UserID, nil, models.RoundExports)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT w.session_id, w.explicit_issue_key, w.issue_key, w.worklog_id, w.attempts,
+			s.start_time, s.end_time, COALESCE(s.break_seconds, 0), COALESCE(s.description, ''), COALESCE(s.key_id, ''),
+			s.id IS NULL OR COALESCE(s.is_deleted, false)
+		FROM jira_worklogs w
+		LEFT JOIN timer_sessions s ON s.id = w.session_id AND s.user_id = $2
+		WHERE w.account_id = $1
+			AND (w.status = $3 OR (w.status = $4 AND w.attempts < $5 AND w.next_attempt_at <= CURRENT_TIMESTAMP))
+		ORDER BY s.start_time NULLS FIRST
+		LIMIT $6
+	`, account.ID, account.UserID, WorklogPending, WorklogFailed, maxWorklogAttempts, worklogBatchSize)
+	if err != nil {
+		return err
+	}
+
+	var queue []queuedWorklog
+	for rows.Next() {
+		var q queuedWorklog
+		var breakSeconds int64
+		err := rows.Scan(&q.sessionID, &q.explicitKey, &q.issueKey, &q.worklogID, &q.attempts,
+			&q.start, &q.end, &breakSeconds, &q.description, &q.keyID, &q.deleted)
+		q.breaks = time.Duration(breakSeconds) * time.Second
+		if err == nil {
+			err = fieldcrypt.OpenPtr(fieldcrypt.SessionDescription, &q.description)
+		}
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		queue = append(queue, q)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, q := range queue {
+		exportErr := exportWorklog(ctx, client, account, rounding, &q)
+		if exportErr == nil {
+			continue
+		}
+		slog.Warn("Jira export of session failed", "session_id", q.sessionID, "error", exportErr)
+		_, err := db.Pool.Exec(ctx, `
+			UPDATE jira_worklogs
+			SET status = $3, attempts = attempts + 1, last_error = $4, next_attempt_at = $5
+			WHERE account_id = $1 AND session_id = $2
+		`, account.ID, q.sessionID, WorklogFailed, exportErr.Error(), time.Now().Add(retryDelay(q.attempts)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportWorklog brings the session's worklog in line with the session.
+// Database errors are returned as export errors too, so the worklog is
+// retried.
+func exportWorklog(ctx context.Context, client *jiraClient, account *Account, rounding models.Rounding, q *queuedWorklog) error {
+	key := ""
+	if !q.deleted {
+		key = sessionIssueKey(q.explicitKey, q.description, q.keyID)
+	}
+
+	// A worklog on another issue cannot be moved, so it is replaced
+	if q.worklogID != "" && q.issueKey != key {
+		err := client.do(ctx, http.MethodDelete, worklogPath(q.issueKey, q.worklogID), nil, nil)
+		var jiraErr *jiraError
+		if err != nil && !(errors.As(err, &jiraErr) && jiraErr.StatusCode == http.StatusNotFound) {
+			return err
+		}
+		_, err = db.Pool.Exec(ctx,
+			`UPDATE jira_worklogs SET worklog_id = '', issue_key = '' WHERE account_id = $1 AND session_id = $2`,
+			account.ID, q.sessionID)
+		if err != nil {
+			return err
+		}
+		q.worklogID = ""
+	}
+
+	if key == "" {
+		var err error
+		if q.explicitKey == nil {
+			_, err = db.Pool.Exec(ctx,
+				`DELETE FROM jira_worklogs WHERE account_id = $1 AND session_id = $2`,
+				account.ID, q.sessionID)
+		} else {
+			_, err = db.Pool.Exec(ctx, `
+				UPDATE jira_worklogs SET status = $3, attempts = 0, last_error = '', next_attempt_at = NULL
+				WHERE account_id = $1 AND session_id = $2
+			`, account.ID, q.sessionID, WorklogSkipped)
+		}
+		return err
+	}
+
+	duration := rounding.Round(q.end.Sub(*q.start) - q.breaks)
+	if duration < minWorklogDuration {
+		duration = minWorklogDuration
+	}
+	body := jiraWorklogBody{
+		Started:          q.start.Format("2006-01-02T15:04:05.000-0700"),
+		TimeSpentSeconds: int64(duration / time.Second),
+	}
+	if q.keyID == "" {
+		body.Comment = q.description
+	}
+
+	worklogID := q.worklogID
+	if worklogID != "" {
+		err := client.do(ctx, http.MethodPut, worklogPath(key, worklogID), body, nil)
+		var jiraErr *jiraError
+		if errors.As(err, &jiraErr) && jiraErr.StatusCode == http.StatusNotFound {
+			// Deleted in Jira; log the session again
+			worklogID = ""
+		} else if err != nil {
+			return err
+		}
+	}
+	if worklogID == "" {
+		var created struct {
+			ID string `json:"id"`
+		}
+		if err := client.do(ctx, http.MethodPost, "/issue/"+url.PathEscape(key)+"/worklog", body, &created); err != nil {
+			return err
+		}
+		worklogID = created.ID
+	}
+
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE jira_worklogs
+		SET status = $3, issue_key = $4, worklog_id = $5, attempts = 0, last_error = '',
+			next_attempt_at = NULL, exported_at = CURRENT_TIMESTAMP
+		WHERE account_id = $1 AND session_id = $2
+	`, account.ID, q.sessionID, WorklogExported, key, worklogID)
+	return err
+}
+
+func worklogPath(issueKey, worklogID string) string {
+	return "/issue/" + url.PathEscape(issueKey) + "/worklog/" + url.PathEscape(worklogID)
+}
+
+// retryDelay backs off exponentially from a minute up to six hours
+func retryDelay(attempts int) time.Duration {
+	delay := time.Minute << uint(attempts)
+	if attempts > 10 || delay > 6*time.Hour {
+		return 6 * time.Hour
+	}
+	return delay
+}
+
+// RunJiraExport exports the worklogs of every linked Jira account once, as
+// a scheduled task. Failures of single accounts are recorded on them.
+func RunJiraExport(ctx context.Context) error {
+	accounts, err := ListAccounts(ctx, Jira)
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		if err := ExportJira(ctx, account); err != nil {
+			slog.Warn("Jira export for user failed", "user_id", account.UserID, "error", err)
+			if err := recordSync(ctx, account.ID, nil, err); err != nil {
+				slog.Error("Failed to record Jira export error", "error", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+// Notion appends a row per project with the week's total to a Notion
+// database once every week has ended
+const Notion = "notion"
+
+const (
+	notionAPI     = "https://api.notion.com/v1"
+	notionVersion = "2022-06-28"
+)
+
+// maxNotionCatchUp caps how many past weeks one run exports, so a long
+// outage does not flood the database
+const maxNotionCatchUp = 4
+
+// NotionSettings are the per-account options of the integration. The
+// integration token is stored as the account's access token. The property
+// names must match the database's title, date and number properties.
+type NotionSettings struct {
+	DatabaseID      string `json:"database_id"`
+	ProjectProperty string `json:"project_property"`
+	WeekProperty    string `json:"week_property"`
+	HoursProperty   string `json:"hours_property"`
+	// Timezone decides where weeks, which start on Monday, begin and end
+	Timezone     string `json:"timezone"`
+	ExportWeekly bool   `json:"export_weekly"`
+}
+
+// DefaultNotionSettings returns the settings of a newly linked account
+func DefaultNotionSettings() NotionSettings {
+	return NotionSettings{
+		ProjectProperty: "Project",
+		WeekProperty:    "Week",
+		HoursProperty:   "Hours",
+		Timezone:        "UTC",
+		ExportWeekly:    true,
+	}
+}
+
+// notionError is a failed call to the Notion API
+type notionError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *notionError) Error() string {
+	return e.Message
+}
+
+func notionDo(ctx context.Context, token, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, notionAPI+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Notion-Version", notionVersion)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &notionError{
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("notion %s %s: %s %s", method, path, resp.Status, bytes.TrimSpace(message)),
+		}
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// VerifyNotion checks that the token can reach the database and that the
+// configured properties exist with the right types
+func VerifyNotion(ctx context.Context, settings NotionSettings, token string) error {
+	if _, err := time.LoadLocation(settings.Timezone); err != nil {
+		return errors.New("invalid timezone")
+	}
+
+	var database struct {
+		Properties map[string]struct {
+			Type string `json:"type"`
+		} `json:"properties"`
+	}
+	if err := notionDo(ctx, token, http.MethodGet, "/databases/"+url.PathEscape(settings.DatabaseID), nil, &database); err != nil {
+		var notionErr *notionError
+		if errors.As(err, &notionErr) && notionErr.StatusCode == http.StatusNotFound {
+			return errors.New("database not found; share it with the integration first")
+		}
+		return err
+	}
+
+	for name, kind := range map[string]string{
+		settings.ProjectProperty: "title",
+		settings.WeekProperty:    "date",
+		settings.HoursProperty:   "number",
+	} {
+		if database.Properties[name].Type != kind {
+			return fmt.Errorf("database needs a %s property named %q", kind, name)
+		}
+	}
+	return nil
+}
+
+// ExportNotion appends the totals of every week that ended since the
+// account last exported. The sync cursor is the end of the last exported
+// week.
+func ExportNotion(ctx context.Context, account *Account) error {
+	settings := DefaultNotionSettings()
+	if err := json.Unmarshal(account.Settings, &settings); err != nil {
+		return err
+	}
+	if !settings.ExportWeekly {
+		return nil
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return err
+	}
+
+	current := weekStart(time.Now().In(loc))
+	start := current.AddDate(0, 0, -7)
+	if account.LastSyncedAt != nil {
+		start = weekStart(account.LastSyncedAt.In(loc))
+		if earliest := current.AddDate(0, 0, -7*maxNotionCatchUp); start.Before(earliest) {
+			start = earliest
+		}
+	}
+
+	for week := start; week.Before(current); week = week.AddDate(0, 0, 7) {
+		end := week.AddDate(0, 0, 7)
+		if err := exportNotionWeek(ctx, settings, account, week, end); err != nil {
+			return err
+		}
+		if err := recordSync(ctx, account.ID, &end, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// weekStart returns midnight on the Monday of t's week
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
+
+// exportNotionWeek appends one row per project the user tracked time on
+// during the week. Encrypted project names cannot be read and are
+// exported as "Encrypted project". Sessions are rounded when the user's
+// rounding preference applies to exports. Notion has no idempotent inserts,
+// so a week that fails part way is appended again in full on the next run.
+func exportNotionWeek(ctx context.Context, settings NotionSettings, account *Account, start, end time.Time) error {
+	rounding, err := models.GetRounding(ctx, account.UserID, nil, models.RoundExports)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT
+			CASE
+				WHEN p.id IS NULL THEN 'No project'
+				WHEN p.key_id <> '' THEN 'Encrypted project'
+				ELSE p.name
+			END AS project,
+			SUM(`+rounding.SecondsSQL(models.NetSecondsSQL("s"))+`)::float8 / 3600
+		FROM timer_sessions s
+		LEFT JOIN projects p ON p.id = s.project_id
+		WHERE s.user_id = $1 AND s.is_deleted = false AND s.start_time >= $2 AND s.start_time < $3
+		GROUP BY 1
+		ORDER BY 1
+	`, account.UserID, start, end)
+	if err != nil {
+		return err
+	}
+
+	type total struct {
+		project string
+		hours   float64
+	}
+	var totals []total
+	// Sealed names differ for every project, so projects sharing a name
+	// are merged once opened
+	index := make(map[string]int)
+	for rows.Next() {
+		var t total
+		if err := rows.Scan(&t.project, &t.hours); err != nil {
+			rows.Close()
+			return err
+		}
+		if t.project, err = fieldcrypt.Open(fieldcrypt.ProjectName, t.project); err != nil {
+			rows.Close()
+			return err
+		}
+		if i, ok := index[t.project]; ok {
+			totals[i].hours += t.hours
+			continue
+		}
+		index[t.project] = len(totals)
+		totals = append(totals, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].project < totals[j].project })
+
+	for _, t := range totals {
+		page := map[string]interface{}{
+			"parent": map[string]string{"database_id": settings.DatabaseID},
+			"properties": map[string]interface{}{
+				settings.ProjectProperty: map[string]interface{}{
+					"title": []interface{}{map[string]interface{}{"text": map[string]string{"content": t.project}}},
+				},
+				settings.WeekProperty: map[string]interface{}{
+					"date": map[string]string{
+						"start": start.Format("2006-01-02"),
+						"end":   end.AddDate(0, 0, -1).Format("2006-01-02"),
+					},
+				},
+				settings.HoursProperty: map[string]interface{}{
+					"number": float64(int(t.hours*100+0.5)) / 100,
+				},
+			},
+		}
+		if err := notionDo(ctx, account.AccessToken, http.MethodPost, "/pages", page, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunNotionExport exports the weekly totals of every linked Notion account
+// once, as a scheduled task. Failures of single accounts are recorded on
+// them.
+func RunNotionExport(ctx context.Context) error {
+	accounts, err := ListAccounts(ctx, Notion)
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		if err := ExportNotion(ctx, account); err != nil {
+			slog.Warn("Notion export for user failed", "user_id", account.UserID, "error", err)
+			if err := recordSync(ctx, account.ID, nil, err); err != nil {
+				slog.Error("Failed to record Notion export error", "error", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package integrations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// httpClient is shared by all calls to third-party APIs
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// OAuthConfig describes an OAuth 2.0 authorization code flow
+type OAuthConfig struct {
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	RedirectURL  string
+	Scopes       []string
+	// AuthParams are extra query parameters for the authorization URL
+	AuthParams map[string]string
+}
+
+// Token is an OAuth access token together with what is needed to renew it
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    *time.Time
+}
+
+// Configured reports whether the client credentials are set
+func (c OAuthConfig) Configured() bool {
+	return c.ClientID != "" && c.ClientSecret != "" && c.RedirectURL != ""
+}
+
+// AuthCodeURL returns the URL that sends the user to the provider's consent
+// screen
+func (c OAuthConfig) AuthCodeURL(state string) string {
+	params := url.Values{
+		"response_type": {"code"},
+		"client_id":     {c.ClientID},
+		"redirect_uri":  {c.RedirectURL},
+		"scope":         {strings.Join(c.Scopes, " ")},
+		"state":         {state},
+	}
+	for key, value := range c.AuthParams {
+		params.Set(key, value)
+	}
+	return c.AuthURL + "?" + params.Encode()
+}
+
+// Exchange trades an authorization code for a token
+func (c OAuthConfig) Exchange(ctx context.Context, code string) (*Token, error) {
+	return c.token(ctx, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {c.RedirectURL},
+	})
+}
+
+// Refresh obtains a new access token. Providers that do not rotate refresh
+// tokens keep the old one.
+func (c OAuthConfig) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	token, err := c.token(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+func (c OAuthConfig) token(ctx context.Context, form url.Values) (*Token, error) {
+	form.Set("client_id", c.ClientID)
+	form.Set("client_secret", c.ClientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		Error        string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("error decoding token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		return nil, fmt.Errorf("token request failed: %s %s", resp.Status, body.Error)
+	}
+
+	token := &Token{AccessToken: body.AccessToken, RefreshToken: body.RefreshToken}
+	if body.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+		token.ExpiresAt = &expiresAt
+	}
+	return token, nil
+}
+
+// newState returns a random OAuth state value
+func newState() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,287 @@
+package integrations
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// WakaTime lets WakaTime editor plugins send heartbeats, which are coalesced
+// into coding sessions
+const WakaTime = "wakatime"
+
+// wakaTimeDevice marks sessions built from heartbeats
+const wakaTimeDevice = "wakatime"
+
+// DefaultIdleMinutes is the gap between heartbeats that ends a session
+const DefaultIdleMinutes = 15
+
+// maxHeartbeatSkew rejects heartbeats from clocks running ahead
+const maxHeartbeatSkew = 5 * time.Minute
+
+// Fields a WakaTime rule can match
+var WakaTimeRuleFields = []string{"project", "language", "branch", "entity", "category"}
+
+// Heartbeat is one activity ping from an editor, as sent by WakaTime plugins
+type Heartbeat struct {
+	Entity   string  `json:"entity"`
+	Type     string  `json:"type"`
+	Category string  `json:"category"`
+	Time     float64 `json:"time"`
+	Project  string  `json:"project"`
+	Branch   string  `json:"branch"`
+	Language string  `json:"language"`
+	IsWrite  bool    `json:"is_write"`
+}
+
+// WakaTimeRule assigns heartbeats whose field matches Pattern, a
+// case-insensitive glob such as "zebra-*", to a project
+type WakaTimeRule struct {
+	Field     string    `json:"field"`
+	Pattern   string    `json:"pattern"`
+	ProjectID uuid.UUID `json:"project_id"`
+}
+
+// WakaTimeSettings are the per-account options of the integration. Rules
+// are tried in order; heartbeats no rule matches go to the personal project
+// named like the WakaTime project, then to DefaultProjectID.
+type WakaTimeSettings struct {
+	Rules            []WakaTimeRule `json:"rules"`
+	DefaultProjectID *uuid.UUID     `json:"default_project_id"`
+	IdleMinutes      int            `json:"idle_minutes"`
+}
+
+// DefaultWakaTimeSettings returns the settings of a newly linked account
+func DefaultWakaTimeSettings() WakaTimeSettings {
+	return WakaTimeSettings{Rules: []WakaTimeRule{}, IdleMinutes: DefaultIdleMinutes}
+}
+
+// Validate checks the rules' fields and patterns
+func (s *WakaTimeSettings) Validate(v *validate.Validator) {
+	if s.Rules == nil {
+		s.Rules = []WakaTimeRule{}
+	}
+	v.Check(s.IdleMinutes >= 1 && s.IdleMinutes <= 120, "idle_minutes", "must be between 1 and 120")
+	for i, rule := range s.Rules {
+		known := false
+		for _, field := range WakaTimeRuleFields {
+			known = known || rule.Field == field
+		}
+		v.Check(known, fmt.Sprintf("rules[%d].field", i), "must be one of "+strings.Join(WakaTimeRuleFields, ", "))
+		_, err := path.Match(rule.Pattern, "")
+		v.Check(err == nil && rule.Pattern != "", fmt.Sprintf("rules[%d].pattern", i), "must be a valid glob pattern")
+	}
+}
+
+// field returns the heartbeat value a rule matches against
+func (h Heartbeat) field(name string) string {
+	switch name {
+	case "project":
+		return h.Project
+	case "language":
+		return h.Language
+	case "branch":
+		return h.Branch
+	case "entity":
+		return h.Entity
+	case "category":
+		return h.Category
+	}
+	return ""
+}
+
+// NewWakaTimeKey returns a new API key for editor plugins and the hash that
+// is stored in its place
+func NewWakaTimeKey() (string, string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key := "waka_" + hex.EncodeToString(b)
+	return key, hashKey(key), nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// AccountByWakaTimeKey returns the account an API key belongs to
+func AccountByWakaTimeKey(ctx context.Context, key string) (*Account, error) {
+	account, err := scanAccount(db.Pool.QueryRow(ctx,
+		`SELECT `+accountColumns+` FROM integration_accounts WHERE provider = $1 AND access_token = $2`,
+		WakaTime, hashKey(key)))
+	if err == pgx.ErrNoRows {
+		return nil, ErrAccountNotFound
+	}
+	return account, err
+}
+
+// IngestHeartbeats folds heartbeats into the account's coding sessions. A
+// heartbeat within the idle timeout of a session for the same project and
+// WakaTime project extends it; any other starts a new one. It returns how
+// many heartbeats were accepted.
+func IngestHeartbeats(ctx context.Context, account *Account, heartbeats []Heartbeat) (int, error) {
+	settings := DefaultWakaTimeSettings()
+	if err := json.Unmarshal(account.Settings, &settings); err != nil {
+		return 0, err
+	}
+	idle := time.Duration(settings.IdleMinutes) * time.Minute
+
+	sort.SliceStable(heartbeats, func(i, j int) bool { return heartbeats[i].Time < heartbeats[j].Time })
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	projects, err := personalProjects(ctx, tx, account.UserID)
+	if err != nil {
+		return 0, err
+	}
+	accepted := 0
+	latest := time.Now().Add(maxHeartbeatSkew)
+	for _, hb := range heartbeats {
+		if hb.Time <= 0 {
+			continue
+		}
+		sec, frac := math.Modf(hb.Time)
+		at := time.Unix(int64(sec), int64(frac*1e9)).UTC()
+		if at.After(latest) {
+			continue
+		}
+
+		projectID := heartbeatProject(settings, hb, projects)
+		description := strings.TrimSpace(hb.Project)
+		if description == "" {
+			description = "Coding"
+		}
+
+		sessionID, start, end, err := heartbeatSession(ctx, tx, account.UserID, projectID, description, at, idle)
+		switch {
+		case err == pgx.ErrNoRows:
+			var sealed string
+			if sealed, err = fieldcrypt.Seal(fieldcrypt.SessionDescription, description); err != nil {
+				break
+			}
+			_, err = tx.Exec(ctx, `
+				INSERT INTO timer_sessions (id, user_id, project_id, start_time, end_time, description, device_id)
+				VALUES ($1, $2, $3, $4, $4, $5, $6)
+			`, uuid.New(), account.UserID, projectID, at, sealed, wakaTimeDevice)
+		case err != nil:
+		case at.Before(start) || at.After(end):
+			_, err = tx.Exec(ctx, `
+				UPDATE timer_sessions SET start_time = LEAST(start_time, $2), end_time = GREATEST(end_time, $2)
+				WHERE id = $1
+			`, sessionID, at)
+		}
+		if err != nil {
+			return 0, err
+		}
+		accepted++
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return accepted, nil
+}
+
+// heartbeatSession locks the latest WakaTime session for the project and
+// description within the idle timeout of at, or returns pgx.ErrNoRows.
+// Descriptions are compared here rather than in the query, as they may be
+// sealed by fieldcrypt.
+func heartbeatSession(ctx context.Context, tx pgx.Tx, userID uuid.UUID, projectID *uuid.UUID, description string, at time.Time, idle time.Duration) (uuid.UUID, time.Time, time.Time, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT id, start_time, end_time, COALESCE(description, '') FROM timer_sessions
+		WHERE user_id = $1 AND device_id = $2 AND is_deleted = false
+			AND project_id IS NOT DISTINCT FROM $3
+			AND start_time <= $4 AND end_time >= $5
+		ORDER BY end_time DESC
+		FOR UPDATE
+	`, userID, wakaTimeDevice, projectID, at.Add(idle), at.Add(-idle))
+	if err != nil {
+		return uuid.Nil, time.Time{}, time.Time{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		var start, end time.Time
+		var stored string
+		if err := rows.Scan(&id, &start, &end, &stored); err != nil {
+			return uuid.Nil, time.Time{}, time.Time{}, err
+		}
+		if stored, err = fieldcrypt.Open(fieldcrypt.SessionDescription, stored); err != nil {
+			return uuid.Nil, time.Time{}, time.Time{}, err
+		}
+		if stored == description {
+			return id, start, end, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return uuid.Nil, time.Time{}, time.Time{}, err
+	}
+	return uuid.Nil, time.Time{}, time.Time{}, pgx.ErrNoRows
+}
+
+// personalProjects returns the user's live personal projects by lowercased
+// name, the oldest winning when names collide. Names are compared here
+// rather than in queries, as they may be sealed by fieldcrypt.
+func personalProjects(ctx context.Context, tx pgx.Tx, userID uuid.UUID) (map[string]*uuid.UUID, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT id, name FROM projects
+		WHERE user_id = $1 AND organization_id IS NULL AND is_deleted = false
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	projects := make(map[string]*uuid.UUID)
+	for rows.Next() {
+		var id uuid.UUID
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		if name, err = fieldcrypt.Open(fieldcrypt.ProjectName, name); err != nil {
+			return nil, err
+		}
+		if key := strings.ToLower(name); projects[key] == nil {
+			projects[key] = &id
+		}
+	}
+	return projects, rows.Err()
+}
+
+// heartbeatProject resolves the personal project a heartbeat is logged to,
+// or nil, from the user's projects by lowercased name
+func heartbeatProject(settings WakaTimeSettings, hb Heartbeat, projects map[string]*uuid.UUID) *uuid.UUID {
+	for _, rule := range settings.Rules {
+		value := strings.ToLower(hb.field(rule.Field))
+		if ok, _ := path.Match(strings.ToLower(rule.Pattern), value); ok && value != "" {
+			projectID := rule.ProjectID
+			return &projectID
+		}
+	}
+
+	if projectID := projects[strings.ToLower(strings.TrimSpace(hb.Project))]; projectID != nil {
+		return projectID
+	}
+	return settings.DefaultProjectID
+}
@@ -0,0 +1,164 @@
+package invoices
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/background"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+var ErrExportNotFound = errors.New("invoice export not found")
+
+// Accounting services invoices can be pushed to
+const (
+	TargetQuickBooks = "quickbooks"
+	TargetXero       = "xero"
+)
+
+// Export statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Result is the outcome of pushing one client's invoice
+type Result struct {
+	Client     string `json:"client"`
+	TotalCents int64  `json:"total_cents"`
+	ExternalID string `json:"external_id,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Export tracks one background push of invoices to an accounting service
+type Export struct {
+	ID             uuid.UUID  `json:"id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	Target         string     `json:"target"`
+	PeriodStart    time.Time  `json:"period_start"`
+	PeriodEnd      time.Time  `json:"period_end"`
+	Status         string     `json:"status"`
+	Results        []Result   `json:"results"`
+	CreatedAt      time.Time  `json:"created_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
+
+// Pusher creates an invoice in an accounting service and returns its ID
+// there
+type Pusher func(context.Context, Invoice) (string, error)
+
+const exportColumns = `id, user_id, organization_id, target, period_start, period_end, status, results,
+	created_at, finished_at`
+
+func scanExport(row pgx.Row) (*Export, error) {
+	var e Export
+	err := row.Scan(&e.ID, &e.UserID, &e.OrganizationID, &e.Target, &e.PeriodStart, &e.PeriodEnd, &e.Status,
+		&e.Results, &e.CreatedAt, &e.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// CreateExport records a queued export of the period's invoices
+func CreateExport(ctx context.Context, opts Options, target string) (*Export, error) {
+	return scanExport(db.Pool.QueryRow(ctx, `
+		INSERT INTO invoice_exports (id, user_id, organization_id, target, period_start, period_end)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+exportColumns,
+		uuid.New(), opts.UserID, opts.OrganizationID, target, opts.Start, opts.End))
+}
+
+// GetExport returns one of the user's exports
+func GetExport(ctx context.Context, userID, exportID uuid.UUID) (*Export, error) {
+	export, err := scanExport(db.Pool.QueryRow(ctx,
+		`SELECT `+exportColumns+` FROM invoice_exports WHERE id = $1 AND user_id = $2`,
+		exportID, userID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrExportNotFound
+	}
+	return export, err
+}
+
+// ListExports returns the user's most recent exports
+func ListExports(ctx context.Context, userID uuid.UUID, limit int) ([]Export, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+exportColumns+` FROM invoice_exports WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`,
+		userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := []Export{}
+	for rows.Next() {
+		export, err := scanExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, *export)
+	}
+	return exports, rows.Err()
+}
+
+// Start builds the invoices and pushes them one client at a time in the
+// background. An invoice that fails is recorded and the rest are still
+// pushed; the export fails only if none could be pushed.
+func Start(export *Export, opts Options, push Pusher) {
+	background.Go(func() {
+		ctx := context.Background()
+		if err := run(ctx, export, opts, push); err != nil {
+			slog.Error("Invoice export failed", "export_id", export.ID, "error", err)
+			_, err := db.Pool.Exec(ctx, `
+				UPDATE invoice_exports
+				SET status = $2, results = results || jsonb_build_array(jsonb_build_object('error', $3::text)),
+					finished_at = CURRENT_TIMESTAMP
+				WHERE id = $1
+			`, export.ID, StatusFailed, err.Error())
+			if err != nil {
+				slog.Error("Failed to record invoice export failure", "export_id", export.ID, "error", err)
+			}
+		}
+	})
+}
+
+func run(ctx context.Context, export *Export, opts Options, push Pusher) error {
+	if _, err := db.Pool.Exec(ctx, `UPDATE invoice_exports SET status = $2 WHERE id = $1`, export.ID, StatusRunning); err != nil {
+		return err
+	}
+
+	invoices, err := Build(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	results := []Result{}
+	pushed := 0
+	for _, inv := range invoices {
+		result := Result{Client: inv.Client, TotalCents: inv.TotalCents}
+		result.ExternalID, err = push(ctx, inv)
+		if err != nil {
+			slog.Warn("Invoice export for client failed", "export_id", export.ID, "client", inv.Client, "error", err)
+			result.Error = err.Error()
+		} else {
+			pushed++
+		}
+		results = append(results, result)
+	}
+
+	status := StatusCompleted
+	if pushed == 0 && len(invoices) > 0 {
+		status = StatusFailed
+	}
+	_, err = db.Pool.Exec(ctx, `
+		UPDATE invoice_exports SET status = $2, results = $3, finished_at = CURRENT_TIMESTAMP WHERE id = $1
+	`, export.ID, status, results)
+	return err
+}
@@ -0,0 +1,87 @@
+package invoices
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// File formats invoices can be downloaded in
+const (
+	FormatCSV = "csv"
+	// FormatIIF is the import format of QuickBooks Desktop
+	FormatIIF = "iif"
+)
+
+// Account and item names IIF invoices are booked to
+const (
+	iifReceivableAccount = "Accounts Receivable"
+	iifIncomeAccount     = "Services"
+	iifItem              = "Services"
+)
+
+// WriteCSV writes one row per invoice line
+func WriteCSV(w io.Writer, invoices []Invoice) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"client", "currency", "period_start", "period_end", "description", "hours", "rate", "amount"})
+	for _, inv := range invoices {
+		for _, line := range inv.Lines {
+			cw.Write([]string{
+				inv.Client,
+				inv.Currency,
+				inv.PeriodStart.Format("2006-01-02"),
+				inv.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02"),
+				line.Description,
+				fmt.Sprintf("%.2f", line.Hours),
+				cents(line.RateCents),
+				cents(line.AmountCents),
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteIIF writes the invoices as QuickBooks Desktop invoice transactions,
+// dated the last day of the period and booked to the Services item
+func WriteIIF(w io.Writer, invoices []Invoice) error {
+	var b strings.Builder
+	b.WriteString("!TRNS\tTRNSTYPE\tDATE\tACCNT\tNAME\tAMOUNT\tMEMO\n")
+	b.WriteString("!SPL\tTRNSTYPE\tDATE\tACCNT\tNAME\tAMOUNT\tQNTY\tPRICE\tINVITEM\tMEMO\n")
+	b.WriteString("!ENDTRNS\n")
+	for _, inv := range invoices {
+		date := inv.PeriodEnd.AddDate(0, 0, -1).Format("01/02/2006")
+		client := iifField(inv.Client)
+		fmt.Fprintf(&b, "TRNS\tINVOICE\t%s\t%s\t%s\t%s\t%s\n",
+			date, iifReceivableAccount, client, cents(inv.TotalCents), iifField(inv.Memo()))
+		for _, line := range inv.Lines {
+			fmt.Fprintf(&b, "SPL\tINVOICE\t%s\t%s\t%s\t%s\t%.2f\t%s\t%s\t%s\n",
+				date, iifIncomeAccount, client, cents(-line.AmountCents), -line.Hours,
+				cents(line.RateCents), iifItem, iifField(line.Description))
+		}
+		b.WriteString("ENDTRNS\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Memo describes the period an invoice covers
+func (inv Invoice) Memo() string {
+	return fmt.Sprintf("Time from %s to %s",
+		inv.PeriodStart.Format("2006-01-02"), inv.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02"))
+}
+
+// iifField keeps a value from breaking the tab-separated layout
+func iifField(s string) string {
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ", `"`, "'").Replace(s)
+}
+
+// cents formats an amount in cents as a decimal
+func cents(c int64) string {
+	sign := ""
+	if c < 0 {
+		sign, c = "-", -c
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, c/100, c%100)
+}
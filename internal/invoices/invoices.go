@@ -0,0 +1,127 @@
+package invoices
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+)
+
+// Line is the billable time of one project on an invoice
+type Line struct {
+	ProjectID   uuid.UUID `json:"project_id"`
+	Description string    `json:"description"`
+	Hours       float64   `json:"hours"`
+	RateCents   int64     `json:"rate_cents"`
+	AmountCents int64     `json:"amount_cents"`
+}
+
+// Invoice is the billable time of one client in a period. PeriodEnd is
+// exclusive.
+type Invoice struct {
+	Client      string    `json:"client"`
+	Currency    string    `json:"currency"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Lines       []Line    `json:"lines"`
+	TotalCents  int64     `json:"total_cents"`
+}
+
+// Options select the time that is invoiced
+type Options struct {
+	UserID uuid.UUID
+	// OrganizationID invoices every member's time on the organization's
+	// projects; without it the user's personal projects are invoiced
+	OrganizationID *uuid.UUID
+	Start          time.Time
+	End            time.Time
+	Currency       string
+	// Round is applied to each session before it is added up
+	Round func(time.Duration) time.Duration
+}
+
+// Build groups the billable sessions started in the period into one
+// invoice per client with a line per project. Names of encrypted projects
+// cannot be read and are invoiced as "Encrypted project".
+func Build(ctx context.Context, opts Options) ([]Invoice, error) {
+	rows, err := db.ReadPool(ctx).Query(ctx, `
+		SELECT p.id, CASE WHEN p.key_id <> '' THEN 'Encrypted project' ELSE p.name END,
+			b.client_name, b.hourly_rate_cents, s.start_time, s.end_time, s.break_seconds
+		FROM timer_sessions s
+		JOIN projects p ON p.id = s.project_id
+		JOIN project_billing b ON b.project_id = p.id AND b.billable
+		WHERE s.is_deleted = false AND s.start_time >= $3 AND s.start_time < $4 AND s.end_time > s.start_time
+			AND (p.organization_id = $2 OR ($2::uuid IS NULL AND p.organization_id IS NULL AND s.user_id = $1))
+	`, opts.UserID, opts.OrganizationID, opts.Start, opts.End)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type projectTime struct {
+		name     string
+		client   string
+		rate     int64
+		duration time.Duration
+	}
+	projects := make(map[uuid.UUID]*projectTime)
+	for rows.Next() {
+		var id uuid.UUID
+		var p projectTime
+		var start, end time.Time
+		var breakSeconds int64
+		if err := rows.Scan(&id, &p.name, &p.client, &p.rate, &start, &end, &breakSeconds); err != nil {
+			return nil, err
+		}
+		d := end.Sub(start) - time.Duration(breakSeconds)*time.Second
+		if opts.Round != nil {
+			d = opts.Round(d)
+		}
+		if existing, ok := projects[id]; ok {
+			existing.duration += d
+			continue
+		}
+		if p.name, err = fieldcrypt.Open(fieldcrypt.ProjectName, p.name); err != nil {
+			return nil, err
+		}
+		p.duration = d
+		projects[id] = &p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	byClient := make(map[string]*Invoice)
+	for id, p := range projects {
+		if p.duration <= 0 {
+			continue
+		}
+		inv, ok := byClient[p.client]
+		if !ok {
+			inv = &Invoice{Client: p.client, Currency: opts.Currency, PeriodStart: opts.Start, PeriodEnd: opts.End}
+			byClient[p.client] = inv
+		}
+		hours := math.Round(p.duration.Hours()*100) / 100
+		line := Line{
+			ProjectID:   id,
+			Description: p.name,
+			Hours:       hours,
+			RateCents:   p.rate,
+			AmountCents: int64(math.Round(hours * float64(p.rate))),
+		}
+		inv.Lines = append(inv.Lines, line)
+		inv.TotalCents += line.AmountCents
+	}
+
+	invoices := make([]Invoice, 0, len(byClient))
+	for _, inv := range byClient {
+		sort.Slice(inv.Lines, func(i, j int) bool { return inv.Lines[i].Description < inv.Lines[j].Description })
+		invoices = append(invoices, *inv)
+	}
+	sort.Slice(invoices, func(i, j int) bool { return invoices[i].Client < invoices[j].Client })
+	return invoices, nil
+}
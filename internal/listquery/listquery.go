@@ -0,0 +1,340 @@
+// Package listquery reads the sorting, field selection and paging parameters
+// of list endpoints and builds the SQL that serves them:
+//
+//	GET /api/v1/auth/sessions?sort=-start_time&fields=id,start_time,project_id&limit=20
+//
+// Lists sort by one of their time columns, with the row id breaking ties, so
+// every order keeps the keyset cursors of package pagination.
+package listquery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/pagination"
+	"github.com/tinylib/msgp/msgp"
+)
+
+var (
+	ErrInvalidSort   = errors.New("invalid sort")
+	ErrInvalidFields = errors.New("invalid fields")
+	// ErrCursorMismatch is a cursor from a page with another sort
+	ErrCursorMismatch = errors.New("cursor does not match the sort")
+)
+
+// Sort orders a list by a field, descending with Desc
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort reads a sort like "start_time" or "-start_time"
+func ParseSort(s string) Sort {
+	if field, ok := strings.CutPrefix(s, "-"); ok {
+		return Sort{Field: field, Desc: true}
+	}
+	return Sort{Field: s}
+}
+
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// Column is a sortable field of T: the SQL column holding it and its value
+// in a row, which becomes the key of the page's cursor
+type Column[T any] struct {
+	SQL string
+	Key func(T) time.Time
+}
+
+// List describes how a list of T can be sorted
+type List[T any] struct {
+	// Sorts maps the field names clients sort by to their columns
+	Sorts map[string]Column[T]
+	// Default is the order without ?sort=
+	Default Sort
+	// ID is the column of the row id breaking ties, "id" if empty
+	ID string
+	// RowID returns the id of a row
+	RowID func(T) uuid.UUID
+}
+
+// Query is one request for a list
+type Query struct {
+	Sort Sort
+	// Fields are the JSON fields to return, all if empty
+	Fields []string
+	Page   pagination.Params
+}
+
+// Parse reads ?sort=, ?fields=, ?limit= and ?cursor=
+func (l List[T]) Parse(values url.Values) (Query, error) {
+	limit := 0
+	if value := values.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed == 0 {
+			return Query{}, pagination.ErrInvalidLimit
+		}
+		limit = parsed
+	}
+	var fields []string
+	if value := values.Get("fields"); value != "" {
+		fields = strings.Split(value, ",")
+	}
+	return l.New(values.Get("sort"), fields, limit, values.Get("cursor"))
+}
+
+// New builds a query from the sort, fields and page a client sent. Empty
+// values select the defaults.
+func (l List[T]) New(sortBy string, fields []string, limit int, cursor string) (Query, error) {
+	q := Query{Sort: l.Default}
+	if sortBy != "" {
+		q.Sort = ParseSort(strings.TrimSpace(sortBy))
+		if _, ok := l.Sorts[q.Sort.Field]; !ok {
+			return Query{}, ErrInvalidSort
+		}
+	}
+
+	if len(fields) > 0 {
+		known := JSONFields(reflect.TypeOf((*T)(nil)).Elem())
+		for i, field := range fields {
+			fields[i] = strings.TrimSpace(field)
+			if !known[fields[i]] {
+				return Query{}, ErrInvalidFields
+			}
+		}
+		q.Fields = fields
+	}
+
+	page, err := pagination.New(limit, cursor)
+	if err != nil {
+		return Query{}, err
+	}
+	if page.After != nil && page.After.Sort != q.Sort.String() {
+		return Query{}, ErrCursorMismatch
+	}
+	q.Page = page
+	return q, nil
+}
+
+// SortFields lists the fields the list can be sorted by
+func (l List[T]) SortFields() []string {
+	fields := make([]string, 0, len(l.Sorts))
+	for field := range l.Sorts {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// DefaultSort is the order without ?sort=, as it would be sent
+func (l List[T]) DefaultSort() string {
+	return l.Default.String()
+}
+
+// SQL returns the keyset condition and the ORDER BY clause of q. The
+// condition takes the cursor's key and id, the values of Args, as
+// parameters $n and $n+1.
+func (l List[T]) SQL(q Query, n int) (where, orderBy string) {
+	column := l.Sorts[q.Sort.Field].SQL
+	id := l.ID
+	if id == "" {
+		id = "id"
+	}
+	op, dir := ">", "ASC"
+	if q.Sort.Desc {
+		op, dir = "<", "DESC"
+	}
+	where = fmt.Sprintf("($%d::timestamptz IS NULL OR (%s, %s) %s ($%d, $%d))", n, column, id, op, n, n+1)
+	orderBy = fmt.Sprintf("%s %s, %s %s", column, dir, id, dir)
+	return where, orderBy
+}
+
+// Args returns the cursor parameters of the condition built by SQL
+func (q Query) Args() []interface{} {
+	return []interface{}{q.Page.AfterTime(), q.Page.AfterID()}
+}
+
+// Slice selects from rows held in memory what the SQL of q would from a
+// table: it sorts a copy of rows, drops those up to the cursor and returns
+// up to q.Page.Fetch() of the rest, for Page. Ids break ties in byte order,
+// as Postgres compares uuids.
+func (l List[T]) Slice(rows []T, q Query) []T {
+	key := l.Sorts[q.Sort.Field].Key
+	// compare orders a row against a sort key and id, ascending
+	compare := func(row T, t time.Time, id uuid.UUID) int {
+		if k := key(row); !k.Equal(t) {
+			if k.Before(t) {
+				return -1
+			}
+			return 1
+		}
+		rowID := l.RowID(row)
+		return bytes.Compare(rowID[:], id[:])
+	}
+	if q.Sort.Desc {
+		ascending := compare
+		compare = func(row T, t time.Time, id uuid.UUID) int { return -ascending(row, t, id) }
+	}
+
+	sorted := make([]T, 0, len(rows))
+	for _, row := range rows {
+		if after := q.Page.After; after == nil || compare(row, after.Time, after.ID) > 0 {
+			sorted = append(sorted, row)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return compare(sorted[i], key(sorted[j]), l.RowID(sorted[j])) < 0
+	})
+	if fetch := q.Page.Fetch(); len(sorted) > fetch {
+		sorted = sorted[:fetch]
+	}
+	return sorted
+}
+
+// Page builds the page of q from rows fetched with q.Page.Fetch()
+func (l List[T]) Page(rows []T, q Query) pagination.Page[T] {
+	key := l.Sorts[q.Sort.Field].Key
+	return pagination.NewPage(rows, q.Page, func(row T) pagination.Cursor {
+		return pagination.Cursor{Time: key(row), ID: l.RowID(row), Sort: q.Sort.String()}
+	})
+}
+
+// Select returns the page with only the given fields of each item, or the
+// page itself if fields is empty
+func Select[T any](page pagination.Page[T], fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return page, nil
+	}
+	data := make([]map[string]json.RawMessage, len(page.Data))
+	for i, item := range page.Data {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(encoded, &all); err != nil {
+			return nil, err
+		}
+		data[i] = make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := all[field]; ok {
+				data[i][field] = value
+			}
+		}
+	}
+	return pagination.Page[map[string]json.RawMessage]{Data: data, NextCursor: page.NextCursor, HasMore: page.HasMore}, nil
+}
+
+// SelectMsgpack encodes a page as MessagePack, with the same shape and
+// field selection as Select. Items must implement msgp.Marshaler through a
+// pointer, as the generated encoders do.
+func SelectMsgpack[T any](page pagination.Page[T], fields []string) ([]byte, error) {
+	selected := map[string]bool{}
+	for _, field := range fields {
+		selected[field] = true
+	}
+
+	size := uint32(2)
+	if page.NextCursor != "" {
+		size++
+	}
+	b := msgp.AppendMapHeader(nil, size)
+	b = msgp.AppendString(b, "data")
+	b = msgp.AppendArrayHeader(b, uint32(len(page.Data)))
+	for i := range page.Data {
+		marshaler, ok := any(&page.Data[i]).(msgp.Marshaler)
+		if !ok {
+			return nil, fmt.Errorf("listquery: %T does not implement msgp.Marshaler", page.Data[i])
+		}
+		item, err := marshaler.MarshalMsg(nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(selected) > 0 {
+			if item, err = selectMsgpackFields(item, selected); err != nil {
+				return nil, err
+			}
+		}
+		b = append(b, item...)
+	}
+	if page.NextCursor != "" {
+		b = msgp.AppendString(b, "next_cursor")
+		b = msgp.AppendString(b, page.NextCursor)
+	}
+	b = msgp.AppendString(b, "has_more")
+	b = msgp.AppendBool(b, page.HasMore)
+	return b, nil
+}
+
+// selectMsgpackFields keeps the selected entries of an encoded map
+func selectMsgpackFields(item []byte, selected map[string]bool) ([]byte, error) {
+	n, rest, err := msgp.ReadMapHeaderBytes(item)
+	if err != nil {
+		return nil, err
+	}
+	var kept []byte
+	count := uint32(0)
+	for i := uint32(0); i < n; i++ {
+		key, value, err := msgp.ReadStringBytes(rest)
+		if err != nil {
+			return nil, err
+		}
+		next, err := msgp.Skip(value)
+		if err != nil {
+			return nil, err
+		}
+		if selected[key] {
+			kept = append(kept, rest[:len(rest)-len(next)]...)
+			count++
+		}
+		rest = next
+	}
+	return append(msgp.AppendMapHeader(nil, count), kept...), nil
+}
+
+// JSONFields returns the names of the fields encoding/json encodes for a
+// struct type, including the promoted fields of embedded structs
+func JSONFields(t reflect.Type) map[string]bool {
+	fields := map[string]bool{}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fields
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			for embedded := range JSONFields(field.Type) {
+				fields[embedded] = true
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = true
+	}
+	return fields
+}
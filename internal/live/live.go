@@ -0,0 +1,154 @@
+// Package live tells the connected clients of a user about changes as they
+// happen, such as a timer started on another device. Changes are announced
+// with Notify in the transaction making them; every server listens for the
+// announcements, run by Run, and passes them on to the streams its clients
+// hold open with Subscribe. Announcements concern a user, or an organization
+// for what its members see of each other, such as who has a timer running.
+// They only name what changed, and the streams load its current state, so a
+// missed one is made up by the next.
+package live
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// channel carries the announcements between the servers
+const channel = "live_events"
+
+// Topics of the announcements
+const (
+	// TopicTimer is the user's running timer
+	TopicTimer = "timer"
+	// TopicPresence is who has a timer running on an organization's
+	// projects, announced for the organization
+	TopicPresence = "presence"
+)
+
+// Topics lists the user's topics, which streams send the state of when
+// opened
+var Topics = []string{TopicTimer}
+
+// Change is an announcement received by a stream: topic changed for
+// Subject, the user or one of the organizations subscribed to
+type Change struct {
+	Subject uuid.UUID
+	Topic   string
+}
+
+// subscriberBuffer bounds the announcements waiting for a slow stream;
+// more are dropped, as the stream still loads the latest state
+const subscriberBuffer = 8
+
+var (
+	mu          sync.Mutex
+	subscribers = map[uuid.UUID]map[chan Change]struct{}{}
+)
+
+// Notify announces a change of topic for the user, or organization, in tx.
+// It reaches the streams once tx commits, and never if tx rolls back.
+func Notify(ctx context.Context, tx pgx.Tx, subject uuid.UUID, topic string) error {
+	_, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, channel, subject.String()+" "+topic)
+	return err
+}
+
+// NotifyOrganizations announces a change of topic in tx for every
+// organization the user is an active member of
+func NotifyOrganizations(ctx context.Context, tx pgx.Tx, userID uuid.UUID, topic string) error {
+	_, err := tx.Exec(ctx, `
+		SELECT pg_notify($1, organization_id::text || ' ' || $2)
+		FROM memberships
+		WHERE user_id = $3 AND deactivated_at IS NULL
+	`, channel, topic, userID)
+	return err
+}
+
+// Subscribe returns the changes announced for the subjects, a user and the
+// organizations they belong to, from now on and a function to stop
+// receiving them
+func Subscribe(subjects ...uuid.UUID) (<-chan Change, func()) {
+	c := make(chan Change, subscriberBuffer)
+	mu.Lock()
+	for _, subject := range subjects {
+		if subscribers[subject] == nil {
+			subscribers[subject] = map[chan Change]struct{}{}
+		}
+		subscribers[subject][c] = struct{}{}
+	}
+	mu.Unlock()
+
+	return c, func() {
+		mu.Lock()
+		for _, subject := range subjects {
+			delete(subscribers[subject], c)
+			if len(subscribers[subject]) == 0 {
+				delete(subscribers, subject)
+			}
+		}
+		mu.Unlock()
+	}
+}
+
+// publish passes an announcement to the subject's streams on this server
+func publish(subject uuid.UUID, topic string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for c := range subscribers[subject] {
+		select {
+		case c <- Change{Subject: subject, Topic: topic}:
+		default:
+		}
+	}
+}
+
+// Run listens for announcements until ctx is done, reconnecting after
+// retryInterval when the connection fails
+func Run(ctx context.Context, retryInterval time.Duration) {
+	for ctx.Err() == nil {
+		if err := listen(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Live listener failed", "error", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryInterval):
+			}
+		}
+	}
+}
+
+// listen publishes the announcements received on a listening connection,
+// until ctx is done or the connection fails
+func listen(ctx context.Context) error {
+	pooled, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// A connection left listening is not put back in the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		id, topic, _ := strings.Cut(n.Payload, " ")
+		subject, err := uuid.Parse(id)
+		if err != nil {
+			continue
+		}
+		publish(subject, topic)
+	}
+}
@@ -0,0 +1,126 @@
+// Package logging sets up the structured logs of the server. Logs are
+// written with log/slog at the level set by LOG_LEVEL, as JSON or text, and
+// credentials are redacted before anything is written: attributes named like
+// passwords, tokens or secrets, and bearer tokens, JWTs and credential query
+// parameters inside any text.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the credentials removed from logs
+const Redacted = "[REDACTED]"
+
+// Setup makes a redacting logger writing to w the default, for slog and
+// the standard log package alike. level is debug, info, warn or error;
+// format is json or text.
+func Setup(w io.Writer, level, format string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redactAttr}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("invalid log format %q", format)
+	}
+	slog.SetDefault(slog.New(handler))
+	// Lines from the standard log package, such as those of libraries, are
+	// logged at info
+	log.SetFlags(0)
+	return nil
+}
+
+// sensitiveKeys are parts of attribute names whose values are credentials
+var sensitiveKeys = []string{"password", "passwd", "secret", "token", "authorization", "cookie", "api_key", "apikey"}
+
+var sensitivePatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]+=*`), "$1 " + Redacted},
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), Redacted},
+	{regexp.MustCompile(`(?i)\b(password|passwd|secret|client_secret|token|access_token|refresh_token|api_key|apikey|code)=[^&\s"']+`), "$1=" + Redacted},
+}
+
+// Redact removes credentials from s
+func Redact(s string) string {
+	for _, p := range sensitivePatterns {
+		s = p.pattern.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range sensitiveKeys {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() == slog.KindGroup {
+		return a
+	}
+	if sensitiveKey(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		// Errors and other values are logged by their text, which may
+		// quote a request or URL
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+		if s, ok := a.Value.Any().(fmt.Stringer); ok {
+			return slog.String(a.Key, Redact(s.String()))
+		}
+	}
+	return a
+}
+
+type contextKey string
+
+const requestKey contextKey = "log_request"
+
+// request holds the attributes of a request's log lines, which handlers
+// down the chain add to
+type request struct {
+	attrs []any
+}
+
+// FromContext returns the logger of the request ctx belongs to, carrying
+// its id and the attributes added with AddAttrs, or the default logger
+// outside a request
+func FromContext(ctx context.Context) *slog.Logger {
+	if req, ok := ctx.Value(requestKey).(*request); ok {
+		return slog.Default().With(req.attrs...)
+	}
+	return slog.Default()
+}
+
+// AddAttrs adds attributes, such as the authenticated user, to every later
+// log line of the request ctx belongs to, including its access log line
+func AddAttrs(ctx context.Context, args ...any) {
+	if req, ok := ctx.Value(requestKey).(*request); ok {
+		req.attrs = append(req.attrs, args...)
+	}
+}
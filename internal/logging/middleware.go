@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Middleware logs every request once it is answered, with its id, method,
+// path, status, size and duration, and the attributes handlers added. Server
+// errors are logged at error level. It must run after middleware.RequestID.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		req := &request{}
+		if id := middleware.GetReqID(r.Context()); id != "" {
+			req.attrs = append(req.attrs, "request_id", id)
+		}
+		ctx := context.WithValue(r.Context(), requestKey, req)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			path := r.URL.Path
+			if r.URL.RawQuery != "" {
+				path += "?" + r.URL.RawQuery
+			}
+			slog.Default().Log(ctx, level, "Request", append(req.attrs,
+				"method", r.Method,
+				"path", path,
+				"status", status,
+				"bytes", ww.BytesWritten(),
+				"duration", time.Since(start),
+				"remote_addr", r.RemoteAddr,
+			)...)
+		}()
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+	})
+}
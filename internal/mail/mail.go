@@ -0,0 +1,124 @@
+// Package mail sends email through a provider chosen at startup: an SMTP
+// server, Amazon SES or Mailgun, or, in development, the log. Messages are
+// rendered from the templates in templates/, in the recipient's language,
+// and usually sent through the queue, which retries failed sends in the
+// background:
+//
+//	msg, err := mail.Render("reset", user.Locale, user.Email, data)
+//	...
+//	err = mail.Enqueue(ctx, msg)
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// ErrNotConfigured is returned when sending without a provider
+var ErrNotConfigured = errors.New("mail is not configured")
+
+// Message is one email to one recipient. HTML is optional; Text is what
+// clients without HTML show.
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html,omitempty"`
+}
+
+// Provider delivers messages from the configured sender address
+type Provider interface {
+	Send(ctx context.Context, from string, msg Message) error
+}
+
+var (
+	provider Provider
+	from     string
+)
+
+// Configure sets the provider and the From address of every message. It is
+// called at startup, before mail is sent; a nil provider turns mail off.
+func Configure(p Provider, fromAddress string) {
+	provider, from = p, fromAddress
+}
+
+// LoadEnv configures the provider named by MAIL_PROVIDER (smtp, ses,
+// mailgun or log) and MAIL_FROM. Without MAIL_PROVIDER, SMTP is used when
+// SMTP_HOST is set and mail stays off otherwise.
+func LoadEnv() error {
+	name := os.Getenv("MAIL_PROVIDER")
+	if name == "" && os.Getenv("SMTP_HOST") != "" {
+		name = "smtp"
+	}
+	fromAddress := os.Getenv("MAIL_FROM")
+
+	var p Provider
+	switch name {
+	case "":
+		Configure(nil, "")
+		return nil
+	case "log":
+		if fromAddress == "" {
+			fromAddress = "zebra@localhost"
+		}
+		p = Log{}
+	case "smtp":
+		cfg := SMTPFromEnv()
+		if cfg.Host == "" {
+			return errors.New("SMTP_HOST is required for the smtp mail provider")
+		}
+		p = cfg
+	case "ses":
+		cfg := SESFromEnv()
+		if cfg.Region == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+			return errors.New("AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for the ses mail provider")
+		}
+		p = cfg
+	case "mailgun":
+		cfg := MailgunFromEnv()
+		if cfg.Domain == "" || cfg.APIKey == "" {
+			return errors.New("MAILGUN_DOMAIN and MAILGUN_API_KEY are required for the mailgun mail provider")
+		}
+		p = cfg
+	default:
+		return fmt.Errorf("unknown MAIL_PROVIDER %q", name)
+	}
+	if fromAddress == "" {
+		return errors.New("MAIL_FROM is required to send mail")
+	}
+	Configure(p, fromAddress)
+	slog.Info("Mail configured", "provider", name, "from", fromAddress)
+	return nil
+}
+
+// Configured reports whether mail can be sent
+func Configured() bool {
+	return provider != nil
+}
+
+// Send sends a message right away, without retries. Most callers should
+// use Enqueue instead.
+func Send(ctx context.Context, msg Message) error {
+	if provider == nil {
+		return ErrNotConfigured
+	}
+	return provider.Send(ctx, from, msg)
+}
+
+// Log is the development provider: it logs messages instead of sending
+// them, so flows such as password resets can be followed locally
+type Log struct{}
+
+func (Log) Send(ctx context.Context, from string, msg Message) error {
+	slog.Info("Email not sent (MAIL_PROVIDER=log)", "from", from, "to", msg.To, "subject", msg.Subject, "text", msg.Text)
+	return nil
+}
+
+// headerValue keeps user-supplied text from adding headers
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,71 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// Mailgun sends through the Mailgun API, read from MAILGUN_DOMAIN,
+// MAILGUN_API_KEY and MAILGUN_API_BASE (https://api.eu.mailgun.net/v3 for
+// domains in the EU region)
+type Mailgun struct {
+	Domain  string
+	APIKey  string
+	BaseURL string
+}
+
+// MailgunFromEnv reads the Mailgun configuration from the environment
+func MailgunFromEnv() Mailgun {
+	cfg := Mailgun{
+		Domain:  os.Getenv("MAILGUN_DOMAIN"),
+		APIKey:  os.Getenv("MAILGUN_API_KEY"),
+		BaseURL: os.Getenv("MAILGUN_API_BASE"),
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.mailgun.net/v3"
+	}
+	return cfg
+}
+
+func (c Mailgun) Send(ctx context.Context, from string, msg Message) error {
+	form := url.Values{
+		"from":    {from},
+		"to":      {msg.To},
+		"subject": {msg.Subject},
+		"text":    {msg.Text},
+	}
+	if msg.HTML != "" {
+		form.Set("html", msg.HTML)
+	}
+
+	endpoint := strings.TrimSuffix(c.BaseURL, "/") + "/" + url.PathEscape(c.Domain) + "/messages"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("api", c.APIKey)
+	return doSend(req, "Mailgun")
+}
+
+// doSend runs a provider API request, failing on any status but 2xx
+func doSend(req *http.Request, provider string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s answered %s: %s", provider, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
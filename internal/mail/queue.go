@@ -0,0 +1,165 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// queueBatchSize bounds the messages sent in one transaction
+const queueBatchSize = 20
+
+// MaxAttempts is how often a message is tried before it is given up on.
+// Retries back off from a minute, doubling up to six hours, so the last
+// one comes about a day after the first attempt.
+var MaxAttempts = 12
+
+const (
+	minRetryDelay = time.Minute
+	maxRetryDelay = 6 * time.Hour
+)
+
+// Enqueue queues msg to be sent by Run, which retries it until the
+// provider accepts it. It fails only if mail is off or the message cannot
+// be stored.
+func Enqueue(ctx context.Context, msg Message) error {
+	return pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		return EnqueueTx(ctx, tx, msg)
+	})
+}
+
+// EnqueueTx queues msg in tx, so that it is sent only if tx commits
+func EnqueueTx(ctx context.Context, tx pgx.Tx, msg Message) error {
+	if provider == nil {
+		return ErrNotConfigured
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO mail_queue (message) VALUES ($1)`, body)
+	return err
+}
+
+// Run sends queued messages until ctx is done, checking for due ones every
+// pollInterval. Senders on several servers share the queue, each message
+// going to one.
+func Run(ctx context.Context, pollInterval time.Duration) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		for provider != nil {
+			n, err := sendDue(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("Failed to send queued mail", "error", err)
+				}
+				break
+			}
+			if n < queueBatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDue sends the oldest batch of due messages and records the outcome
+// of each, returning the batch size. The messages stay locked while they
+// are sent, so other senders skip them.
+func sendDue(ctx context.Context) (int, error) {
+	n := 0
+	err := pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT id, message, attempts FROM mail_queue
+			WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		`, queueBatchSize)
+		if err != nil {
+			return err
+		}
+		type queued struct {
+			id       int64
+			msg      Message
+			attempts int
+		}
+		var batch []queued
+		for rows.Next() {
+			var q queued
+			var body []byte
+			if err := rows.Scan(&q.id, &body, &q.attempts); err != nil {
+				rows.Close()
+				return err
+			}
+			if err := json.Unmarshal(body, &q.msg); err != nil {
+				rows.Close()
+				return err
+			}
+			batch = append(batch, q)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		n = len(batch)
+
+		for _, q := range batch {
+			sendErr := Send(ctx, q.msg)
+			attempts := q.attempts + 1
+			switch {
+			case sendErr == nil:
+				_, err = tx.Exec(ctx, `
+					UPDATE mail_queue SET sent_at = CURRENT_TIMESTAMP, attempts = $2, last_error = '' WHERE id = $1
+				`, q.id, attempts)
+			case attempts >= MaxAttempts:
+				slog.Error("Giving up on mail", "mail_id", q.id, "subject", q.msg.Subject, "attempts", attempts, "error", sendErr)
+				_, err = tx.Exec(ctx, `
+					UPDATE mail_queue SET failed_at = CURRENT_TIMESTAMP, attempts = $2, last_error = $3 WHERE id = $1
+				`, q.id, attempts, sendErr.Error())
+			default:
+				slog.Warn("Failed to send mail, retrying", "mail_id", q.id, "attempts", attempts, "error", sendErr)
+				_, err = tx.Exec(ctx, `
+					UPDATE mail_queue SET next_attempt_at = $2, attempts = $3, last_error = $4 WHERE id = $1
+				`, q.id, time.Now().Add(retryDelay(attempts)), attempts, sendErr.Error())
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+// retryDelay is how long to wait after the given number of failed attempts
+func retryDelay(attempts int) time.Duration {
+	delay := minRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// Prune deletes the sent and failed messages queued more than retention ago
+func Prune(ctx context.Context, retention time.Duration) error {
+	result, err := db.Pool.Exec(ctx, `
+		DELETE FROM mail_queue WHERE created_at < $1 AND (sent_at IS NOT NULL OR failed_at IS NOT NULL)
+	`, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	if n := result.RowsAffected(); n > 0 {
+		slog.Info("Pruned mail queue", "deleted", n)
+	}
+	return nil
+}
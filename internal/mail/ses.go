@@ -0,0 +1,72 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pacerclub/zebra-backend/internal/sigv4"
+)
+
+// SES sends through the Amazon SES v2 API, read from AWS_REGION,
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+type SES struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// SESFromEnv reads the SES configuration from the environment
+func SESFromEnv() SES {
+	return SES{
+		Region:          os.Getenv("AWS_REGION"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// sesContent is a subject or body part of SendEmail
+type sesContent struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+func (c SES) Send(ctx context.Context, from string, msg Message) error {
+	body := map[string]*sesContent{"Text": {Data: msg.Text, Charset: "UTF-8"}}
+	if msg.HTML != "" {
+		body["Html"] = &sesContent{Data: msg.HTML, Charset: "UTF-8"}
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"FromEmailAddress": from,
+		"Destination":      map[string][]string{"ToAddresses": {msg.To}},
+		"Content": map[string]interface{}{
+			"Simple": map[string]interface{}{
+				"Subject": sesContent{Data: msg.Subject, Charset: "UTF-8"},
+				"Body":    body,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	host := "email." + c.Region + ".amazonaws.com"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/v2/email/outbound-emails", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	sigv4.Credentials{
+		AccessKeyID:     c.AccessKeyID,
+		SecretAccessKey: c.SecretAccessKey,
+		SessionToken:    c.SessionToken,
+		Region:          c.Region,
+		Service:         "ses",
+	}.Sign(req, sigv4.PayloadHash(payload), time.Now())
+	return doSend(req, "SES")
+}
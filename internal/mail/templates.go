@@ -0,0 +1,85 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/pacerclub/zebra-backend/internal/i18n"
+)
+
+// Templates are a <name>.txt defining "subject" and holding the plain text
+// body, and a <name>.html defining "content", the HTML body placed in
+// layout.html. The English ones are in templates/, and each other locale
+// of i18n.Locales has the same files in templates/<locale>/.
+//
+//go:embed templates
+var templateFiles embed.FS
+
+// Templates of the messages the server sends
+const (
+	TemplateVerification  = "verification"
+	TemplateReset         = "reset"
+	TemplateInvite        = "invite"
+	TemplateDigest        = "digest"
+	TemplateReactivation  = "reactivation"
+	TemplateNotification  = "notification"
+	TemplateEmailProblems = "email_problems"
+)
+
+type template struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// templates are parsed once, by locale; a broken or missing template stops
+// the server at startup
+var templates = func() map[string]map[string]template {
+	parsed := map[string]map[string]template{}
+	for _, locale := range i18n.Locales {
+		dir := "templates/"
+		if locale != i18n.English {
+			dir += locale + "/"
+		}
+		parsed[locale] = map[string]template{}
+		for _, name := range []string{
+			TemplateVerification, TemplateReset, TemplateInvite, TemplateDigest,
+			TemplateReactivation, TemplateNotification, TemplateEmailProblems,
+		} {
+			parsed[locale][name] = template{
+				text: texttemplate.Must(texttemplate.ParseFS(templateFiles, dir+name+".txt")),
+				html: htmltemplate.Must(htmltemplate.ParseFS(templateFiles, dir+"layout.html", dir+name+".html")),
+			}
+		}
+	}
+	return parsed
+}()
+
+// Render builds the message of template name to the recipient in locale,
+// a language tag such as the locale of the recipient's profile, executing
+// its subject and bodies with data. Locales without templates get English.
+func Render(name, locale, to string, data interface{}) (Message, error) {
+	t, ok := templates[i18n.Of(locale)][name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown mail template %q", name)
+	}
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := t.text.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	if err := t.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
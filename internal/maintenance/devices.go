@@ -0,0 +1,66 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// StaleDeviceConfig controls when a device is considered abandoned
+type StaleDeviceConfig struct {
+	// StaleAfter is how long a device may go without syncing before it is
+	// flagged as stale
+	StaleAfter time.Duration
+	// RevokeTokens also invalidates the tokens issued to stale devices, so
+	// the device has to log in again before it can sync
+	RevokeTokens bool
+}
+
+// FlagStaleDevices marks devices that have not synced within StaleAfter as
+// stale and returns the newly flagged ones. A device stops being stale the
+// next time it syncs.
+func FlagStaleDevices(ctx context.Context, cfg StaleDeviceConfig) ([]DeviceRef, error) {
+	rows, err := db.Pool.Query(ctx, `
+		UPDATE device_sync
+		SET stale_at = CURRENT_TIMESTAMP,
+			revoked_at = CASE WHEN $2 THEN CURRENT_TIMESTAMP ELSE revoked_at END
+		WHERE stale_at IS NULL AND last_sync_time < $1
+		RETURNING user_id, device_id
+	`, time.Now().Add(-cfg.StaleAfter), cfg.RevokeTokens)
+	if err != nil {
+		return nil, fmt.Errorf("error flagging stale devices: %v", err)
+	}
+	defer rows.Close()
+
+	var devices []DeviceRef
+	for rows.Next() {
+		var device DeviceRef
+		if err := rows.Scan(&device.UserID, &device.DeviceID); err != nil {
+			return nil, fmt.Errorf("error scanning device: %v", err)
+		}
+		devices = append(devices, device)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error flagging stale devices: %v", err)
+	}
+	return devices, nil
+}
+
+// RunStaleDeviceCleanup flags stale devices once, as a scheduled task.
+// notify, if set, is called for every newly flagged device.
+func RunStaleDeviceCleanup(ctx context.Context, cfg StaleDeviceConfig, notify func(context.Context, DeviceRef)) error {
+	devices, err := FlagStaleDevices(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	for _, device := range devices {
+		slog.Info("Device is stale", "device_id", device.DeviceID, "user_id", device.UserID)
+		if notify != nil {
+			notify(ctx, device)
+		}
+	}
+	return nil
+}
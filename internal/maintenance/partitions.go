@@ -0,0 +1,94 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// sessionStoredColumns returns the columns of timer_sessions as a list for
+// SQL, without the generated ones, which cannot be written. They are read
+// from the schema so that columns added by later migrations are moved too.
+func sessionStoredColumns(ctx context.Context, tx pgx.Tx) (string, error) {
+	var columns string
+	err := tx.QueryRow(ctx, `
+		SELECT string_agg(quote_ident(column_name), ', ' ORDER BY ordinal_position)
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'timer_sessions' AND is_generated = 'NEVER'
+	`).Scan(&columns)
+	return columns, err
+}
+
+// EnsureSessionPartitions creates the monthly partitions of timer_sessions
+// for the current month and the ahead months after it, in UTC, so that new
+// sessions do not pile up in the default partition
+func EnsureSessionPartitions(ctx context.Context, ahead int) error {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i <= ahead; i++ {
+		start := month.AddDate(0, i, 0)
+		if err := ensureSessionPartition(ctx, start); err != nil {
+			return fmt.Errorf("error creating session partition for %s: %v", start.Format("2006-01"), err)
+		}
+	}
+	return nil
+}
+
+// ensureSessionPartition creates the partition of the month starting at
+// start unless it exists. Sessions of the month already stored in the
+// default partition are moved into it, since the default partition may not
+// hold rows of an attached range.
+func ensureSessionPartition(ctx context.Context, start time.Time) error {
+	end := start.AddDate(0, 1, 0)
+	name := "timer_sessions_" + start.Format("2006_01")
+
+	return pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return nil
+		}
+		// Moving a month of sessions out of the default partition may take
+		// longer than the statement timeout of requests
+		if err := db.SetLocalStatementTimeout(ctx, tx, 0); err != nil {
+			return err
+		}
+
+		columns, err := sessionStoredColumns(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		table := pgx.Identifier{name}.Sanitize()
+		_, err = tx.Exec(ctx, `CREATE TABLE `+table+` (LIKE timer_sessions INCLUDING DEFAULTS INCLUDING GENERATED)`)
+		if err != nil {
+			return err
+		}
+		moved, err := tx.Exec(ctx, `
+			WITH moved AS (
+				DELETE FROM timer_sessions_default
+				WHERE start_time >= $1 AND start_time < $2
+				RETURNING `+columns+`
+			)
+			INSERT INTO `+table+` (`+columns+`)
+			SELECT `+columns+` FROM moved
+		`, start, end)
+		if err != nil {
+			return err
+		}
+		// Partition bounds cannot be parameters
+		_, err = tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE timer_sessions ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')`,
+			table, start.Format(time.RFC3339), end.Format(time.RFC3339)))
+		if err != nil {
+			return err
+		}
+		slog.Info("Created session partition", "partition", name, "moved", moved.RowsAffected())
+		return nil
+	})
+}
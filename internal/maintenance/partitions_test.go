@@ -0,0 +1,71 @@
+package maintenance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/dbtest"
+)
+
+// storedSession is what a session keeps when it moves partition
+type storedSession struct {
+	Breaks       string
+	BreakSeconds int
+	TaskID       uuid.UUID
+	Version      int64
+}
+
+func TestEnsureSessionPartitionKeepsMovedSessions(t *testing.T) {
+	dbtest.Open(t)
+	ctx := context.Background()
+	userID := dbtest.User(t)
+
+	// A month far enough ahead that nothing else creates its partition
+	start := time.Date(2099, time.January, 1, 0, 0, 0, 0, time.UTC)
+	dropPartition := func() {
+		if _, err := db.Pool.Exec(ctx, `DROP TABLE IF EXISTS timer_sessions_2099_01`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dropPartition()
+	t.Cleanup(dropPartition)
+
+	taskID := uuid.New()
+	_, err := db.Pool.Exec(ctx, `INSERT INTO tasks (id, user_id, name) VALUES ($1, $2, 'Docs')`, taskID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := uuid.New()
+	_, err = db.Pool.Exec(ctx, `
+		INSERT INTO timer_sessions (id, user_id, start_time, end_time, description, device_id, breaks, task_id, version)
+		VALUES ($1, $2, $3, $3 + INTERVAL '2 hours', 'Writing', 'laptop', $4, $5, 7)
+	`, id, userID, start.Add(9*time.Hour),
+		`[{"start_time": "2099-01-01T10:00:00Z", "end_time": "2099-01-01T10:15:00Z"}]`, taskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func(table string) storedSession {
+		t.Helper()
+		var session storedSession
+		err := db.Pool.QueryRow(ctx, `SELECT breaks::text, break_seconds, task_id, version FROM `+table+` WHERE id = $1`, id).
+			Scan(&session.Breaks, &session.BreakSeconds, &session.TaskID, &session.Version)
+		if err != nil {
+			t.Fatalf("reading the session from %s: %v", table, err)
+		}
+		return session
+	}
+	want := read("timer_sessions_default")
+	if want.BreakSeconds != 15*60 || want.Version != 7 {
+		t.Fatalf("the session was stored as %+v", want)
+	}
+
+	if err := ensureSessionPartition(ctx, start); err != nil {
+		t.Fatal(err)
+	}
+	if got := read("timer_sessions_2099_01"); got != want {
+		t.Errorf("the moved session is %+v, want %+v", got, want)
+	}
+}
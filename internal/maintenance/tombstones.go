@@ -0,0 +1,170 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// TombstoneGCConfig controls when soft-deleted rows may be removed for good
+type TombstoneGCConfig struct {
+	// MaxAge is how long a tombstone is kept for devices that have not
+	// synced past it. Older tombstones are removed regardless.
+	MaxAge time.Duration
+	// ActiveWindow is how recently a device must have synced to be able to
+	// hold back garbage collection
+	ActiveWindow time.Duration
+}
+
+// DeviceRef identifies a single device of a user
+type DeviceRef struct {
+	UserID   uuid.UUID `json:"user_id"`
+	DeviceID string    `json:"device_id"`
+}
+
+// TombstoneGCReport summarizes a garbage collection run
+type TombstoneGCReport struct {
+	// Deleted counts removed rows per table
+	Deleted       map[string]int64 `json:"deleted"`
+	ResyncDevices []DeviceRef      `json:"resync_devices"`
+}
+
+// tombstoneTables lists every synced table that uses is_deleted tombstones
+var tombstoneTables = []string{
+	"timer_sessions",
+	"tasks",
+	"session_templates",
+	"tags",
+	"user_preferences",
+	"time_off",
+	"projects",
+}
+
+// CollectTombstones hard-deletes tombstoned sessions and projects that every
+// active device of their owner has already acknowledged, plus any tombstone
+// older than MaxAge. Devices that had not yet seen a removed tombstone are
+// flagged for a full resync and returned in the report.
+func CollectTombstones(ctx context.Context, cfg TombstoneGCConfig) (*TombstoneGCReport, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	activeSince := now.Add(-cfg.ActiveWindow)
+	expiredBefore := now.Add(-cfg.MaxAge)
+
+	// Newest removed tombstone per user, used to find devices left behind
+	newestRemoved := make(map[uuid.UUID]time.Time)
+
+	report := &TombstoneGCReport{Deleted: make(map[string]int64)}
+	for _, table := range tombstoneTables {
+		report.Deleted[table], err = deleteTombstones(ctx, tx, table, activeSince, expiredBefore, newestRemoved)
+		if err != nil {
+			return nil, fmt.Errorf("error collecting %s tombstones: %v", table, err)
+		}
+	}
+
+	for userID, newest := range newestRemoved {
+		rows, err := tx.Query(ctx, `
+			UPDATE device_sync
+			SET needs_full_resync = true
+			WHERE user_id = $1 AND acked_through < $2 AND needs_full_resync = false
+			RETURNING device_id
+		`, userID, newest)
+		if err != nil {
+			return nil, fmt.Errorf("error flagging devices for resync: %v", err)
+		}
+		for rows.Next() {
+			device := DeviceRef{UserID: userID}
+			if err := rows.Scan(&device.DeviceID); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("error scanning device: %v", err)
+			}
+			report.ResyncDevices = append(report.ResyncDevices, device)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error flagging devices for resync: %v", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %v", err)
+	}
+	return report, nil
+}
+
+// deleteTombstones removes tombstones from table that are either acknowledged
+// by all active devices of their owner or older than expiredBefore. Users
+// without any active device have nothing holding their tombstones back, and
+// devices flagged as stale never do.
+func deleteTombstones(ctx context.Context, tx pgx.Tx, table string, activeSince, expiredBefore time.Time, newestRemoved map[uuid.UUID]time.Time) (int64, error) {
+	query := fmt.Sprintf(`
+		WITH safe AS (
+			SELECT user_id, MIN(acked_through) AS acked_through
+			FROM device_sync
+			WHERE last_sync_time >= $1 AND stale_at IS NULL
+			GROUP BY user_id
+		),
+		deleted AS (
+			DELETE FROM %[1]s t
+			WHERE t.is_deleted = true
+			  AND (
+			      t.server_updated_at < $2
+			      OR t.server_updated_at <= COALESCE(
+			          (SELECT safe.acked_through FROM safe WHERE safe.user_id = t.user_id),
+			          'infinity'::timestamptz)
+			  )
+			RETURNING t.user_id, t.server_updated_at
+		)
+		SELECT user_id, MAX(server_updated_at), COUNT(*)
+		FROM deleted
+		GROUP BY user_id
+	`, table)
+
+	rows, err := tx.Query(ctx, query, activeSince, expiredBefore)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var total int64
+	for rows.Next() {
+		var userID uuid.UUID
+		var newest time.Time
+		var count int64
+		if err := rows.Scan(&userID, &newest, &count); err != nil {
+			return 0, err
+		}
+		if newest.After(newestRemoved[userID]) {
+			newestRemoved[userID] = newest
+		}
+		total += count
+	}
+	return total, rows.Err()
+}
+
+// RunTombstoneGC collects tombstones once and logs what it removed, as a
+// scheduled task
+func RunTombstoneGC(ctx context.Context, cfg TombstoneGCConfig) error {
+	report, err := CollectTombstones(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	for table, count := range report.Deleted {
+		if count > 0 {
+			slog.Info("Tombstone GC removed rows", "table", table, "count", count)
+		}
+	}
+	for _, device := range report.ResyncDevices {
+		slog.Info("Device needs a full resync", "device_id", device.DeviceID, "user_id", device.UserID)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+// Package memstore keeps sessions, projects, users and tracking rules in
+// memory, implementing the repositories of package service and the token
+// store of package auth without Postgres. It serves tests and the hermetic
+// server of package zebratest.
+//
+// The stores follow the scoping, versioning and soft deletion of the
+// Postgres repositories, with some differences:
+//
+//   - changes record no webhook events
+//   - search results come newest first rather than by rank
+//   - calendar suggestions are not confirmed
+//   - users have no tracking rules
+//   - tokens are revoked only by deactivation, not by signing out
+//   - sync applies sessions and projects of the personal scope only, and
+//     writes them as sent, recording no conflicts
+//
+// Data lives as long as the Store.
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/rules"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Store holds the data of the repositories it returns
+type Store struct {
+	mu       sync.Mutex
+	sessions map[uuid.UUID]service.Session
+	projects map[uuid.UUID]service.Project
+	users    map[uuid.UUID]models.User
+
+	// changed is when each session and project was last written, as
+	// server_updated_at orders changes for sync; clock is the latest
+	changed map[uuid.UUID]time.Time
+	clock   time.Time
+	// syncs are the results of sync batches by user and idempotency key
+	syncs map[uuid.UUID]map[string]storedSync
+}
+
+// New returns an empty store
+func New() *Store {
+	return &Store{
+		sessions: make(map[uuid.UUID]service.Session),
+		projects: make(map[uuid.UUID]service.Project),
+		users:    make(map[uuid.UUID]models.User),
+		changed:  make(map[uuid.UUID]time.Time),
+		syncs:    make(map[uuid.UUID]map[string]storedSync),
+	}
+}
+
+// Install makes package service keep its data in s, until the returned
+// function restores the repositories it replaced. The repositories are
+// package variables, so tests installing stores must not run in parallel.
+func (s *Store) Install() (restore func()) {
+	repos := service.Use(service.Repositories{
+		Sessions: s.Sessions(),
+		Projects: s.Projects(),
+		Users:    s.Users(),
+		Rules:    s.Rules(),
+		Syncs:    s.Syncs(),
+	})
+	tokens := auth.Tokens
+	auth.Tokens = s.Tokens()
+	return func() {
+		auth.Tokens = tokens
+		repos()
+	}
+}
+
+// Sessions, Projects, Users, Rules and Syncs return the repositories
+// keeping their data in s
+func (s *Store) Sessions() service.SessionRepo { return sessionRepo{s} }
+func (s *Store) Projects() service.ProjectRepo { return projectRepo{s} }
+func (s *Store) Users() service.UserRepo       { return userRepo{s} }
+func (s *Store) Rules() service.RuleRepo       { return ruleRepo{} }
+func (s *Store) Syncs() service.SyncRepo       { return syncRepo{s} }
+
+// Tokens returns the revocation state of the tokens of the users in s
+func (s *Store) Tokens() auth.TokenStore { return tokenStore{s} }
+
+// now is the current time at the precision Postgres stores
+func now() time.Time {
+	return time.Now().Round(time.Microsecond)
+}
+
+// tick returns a time after any returned before, for stamping a write
+// the way server_updated_at does. The caller holds s.mu.
+func (s *Store) tick() time.Time {
+	t := now()
+	if !t.After(s.clock) {
+		t = s.clock.Add(time.Microsecond)
+	}
+	s.clock = t
+	return t
+}
+
+// touch returns the updated_at of a changed row, as the
+// update_synced_timestamps trigger sets it: the time written, or now if the
+// write kept the stored one
+func touch(stored, written time.Time) time.Time {
+	if written.Equal(stored) {
+		return now()
+	}
+	return written
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &service.Error{Code: service.NotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func conflict(current interface{}, format string, args ...interface{}) error {
+	return &service.Error{Code: service.Conflict, Reason: apierror.VersionMismatch, Message: fmt.Sprintf(format, args...), Current: current}
+}
+
+// matches reports whether text matches a query built by db.TSQuery, as
+// the simple search configuration does: every term of the query must
+// match, a phrase as consecutive words and a word ending in :* as a prefix
+func matches(text, tsquery string) bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, term := range strings.Split(tsquery, " & ") {
+		if !matchesPhrase(words, strings.Split(strings.Trim(term, "()"), " <-> ")) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesPhrase(words, phrase []string) bool {
+	for start := 0; start+len(phrase) <= len(words); start++ {
+		found := true
+		for i, want := range phrase {
+			if prefix, ok := strings.CutSuffix(want, ":*"); ok {
+				found = strings.HasPrefix(words[start+i], prefix)
+			} else {
+				found = words[start+i] == want
+			}
+			if !found {
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// userRepo keeps users
+type userRepo struct{ s *Store }
+
+func (r userRepo) Create(ctx context.Context, email, password string) (*models.User, error) {
+	// The lowest cost keeps tests fast; ValidatePassword accepts any
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		return nil, err
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, user := range r.s.users {
+		if user.Email == email {
+			return nil, errors.New("email already registered")
+		}
+	}
+	created := now()
+	user := models.User{
+		ID:          uuid.New(),
+		Email:       email,
+		Password:    string(hashedPassword),
+		StorageMode: models.StorageModeStandard,
+		Profile:     models.Profile{Timezone: "UTC", Locale: "en", WeekStart: "monday"},
+		CreatedAt:   created,
+		UpdatedAt:   created,
+	}
+	r.s.users[user.ID] = user
+	return &user, nil
+}
+
+func (r userRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, user := range r.s.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r userRepo) StorageMode(ctx context.Context, userID uuid.UUID) (string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.users[userID]
+	if !ok {
+		return "", errors.New("user not found")
+	}
+	return user.StorageMode, nil
+}
+
+// tokenStore reports deactivated users. Users cannot revoke their tokens
+// here, so no token is revoked by time.
+type tokenStore struct{ s *Store }
+
+func (t tokenStore) TokenState(ctx context.Context, userID uuid.UUID, deviceID string) (*auth.TokenState, error) {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	user, ok := t.s.users[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &auth.TokenState{Deactivated: user.DeactivatedAt != nil}, nil
+}
+
+// ruleRepo has no tracking rules for anyone. Rules adding tags would need
+// the session_tags table to store them.
+type ruleRepo struct{}
+
+func (ruleRepo) Load(ctx context.Context, userID uuid.UUID) (rules.Set, error) {
+	return nil, nil
+}
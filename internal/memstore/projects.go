@@ -0,0 +1,160 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+	"github.com/pacerclub/zebra-backend/internal/service"
+)
+
+// projectRepo keeps projects
+type projectRepo struct{ s *Store }
+
+// projectInScope reports whether a project is in the scope of orgID, as
+// service.ProjectScopeSQL does
+func projectInScope(project service.Project, userID uuid.UUID, orgID *uuid.UUID) bool {
+	if orgID == nil {
+		return project.OrganizationID == nil && project.UserID == userID
+	}
+	return project.OrganizationID != nil && *project.OrganizationID == *orgID
+}
+
+// copyProject returns a project sharing no memory with the stored one
+func copyProject(project service.Project) service.Project {
+	project.OrganizationID = copyID(project.OrganizationID)
+	project.EncryptedName = append([]byte(nil), project.EncryptedName...)
+	project.EncryptedDescription = append([]byte(nil), project.EncryptedDescription...)
+	return project
+}
+
+// liveProjects returns the live projects in the scope of orgID that keep
+// selects. The caller holds s.mu.
+func (s *Store) liveProjects(userID uuid.UUID, orgID *uuid.UUID, keep func(service.Project) bool) []service.Project {
+	var projects []service.Project
+	for _, project := range s.projects {
+		if !project.IsDeleted && projectInScope(project, userID, orgID) && keep(project) {
+			projects = append(projects, copyProject(project))
+		}
+	}
+	return projects
+}
+
+func (r projectRepo) List(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, q listquery.Query) ([]service.Project, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	projects := r.s.liveProjects(userID, orgID, func(service.Project) bool { return true })
+	return service.ProjectList.Slice(projects, q), nil
+}
+
+func (r projectRepo) Search(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, tsquery string, limit int) ([]service.Project, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	projects := r.s.liveProjects(userID, orgID, func(project service.Project) bool {
+		return matches(project.Name, tsquery)
+	})
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].CreatedAt.After(projects[j].CreatedAt)
+	})
+	if len(projects) > limit {
+		projects = projects[:limit]
+	}
+	return projects, nil
+}
+
+func (r projectRepo) Create(ctx context.Context, project service.Project) (service.Project, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, exists := r.s.projects[project.ID]; exists {
+		return service.Project{}, errors.New("duplicate project id")
+	}
+
+	stored := copyProject(service.Project{
+		ID:                   project.ID,
+		UserID:               project.UserID,
+		Name:                 project.Name,
+		Description:          project.Description,
+		Color:                project.Color,
+		EncryptedName:        project.EncryptedName,
+		EncryptedDescription: project.EncryptedDescription,
+		KeyID:                project.KeyID,
+		OrganizationID:       project.OrganizationID,
+		DeviceID:             project.DeviceID,
+		CreatedAt:            project.CreatedAt.Round(time.Microsecond),
+		UpdatedAt:            project.UpdatedAt.Round(time.Microsecond),
+		Version:              1,
+	})
+	r.s.projects[stored.ID] = stored
+	r.s.changed[stored.ID] = r.s.tick()
+	return copyProject(stored), nil
+}
+
+func (r projectRepo) Update(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, projectID uuid.UUID, project service.Project) (service.Project, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stored, ok := r.s.projects[projectID]
+	if !ok || !projectInScope(stored, userID, orgID) {
+		return service.Project{}, notFound("Project not found")
+	}
+	if stored.Version != project.Version {
+		return service.Project{}, conflict(copyProject(stored), "Project was changed since version %d", project.Version)
+	}
+
+	stored.Name = project.Name
+	stored.Description = project.Description
+	stored.Color = project.Color
+	stored.EncryptedName = append([]byte(nil), project.EncryptedName...)
+	stored.EncryptedDescription = append([]byte(nil), project.EncryptedDescription...)
+	stored.KeyID = project.KeyID
+	stored.UpdatedAt = touch(stored.UpdatedAt, project.UpdatedAt.Round(time.Microsecond))
+	stored.Version++
+	r.s.projects[projectID] = stored
+	r.s.changed[projectID] = r.s.tick()
+	return copyProject(stored), nil
+}
+
+func (r projectRepo) Delete(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, projectID uuid.UUID) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stored, ok := r.s.projects[projectID]
+	if !ok || !projectInScope(stored, userID, orgID) {
+		return false, nil
+	}
+	stored.IsDeleted = true
+	stored.UpdatedAt = now()
+	stored.Version++
+	r.s.projects[projectID] = stored
+	r.s.changed[projectID] = r.s.tick()
+	return true, nil
+}
+
+func (r projectRepo) SetIcon(ctx context.Context, userID, projectID uuid.UUID, iconURL string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stored, ok := r.s.projects[projectID]
+	if !ok || stored.IsDeleted {
+		return false, nil
+	}
+	stored.IconURL = iconURL
+	stored.UpdatedAt = now()
+	stored.Version++
+	r.s.projects[projectID] = stored
+	r.s.changed[projectID] = r.s.tick()
+	return true, nil
+}
+
+func (r projectRepo) InScope(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, projectID uuid.UUID) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	project, ok := r.s.projects[projectID]
+	return ok && !project.IsDeleted && projectInScope(project, userID, orgID), nil
+}
+
+func (r projectRepo) Count(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return len(r.s.liveProjects(userID, orgID, func(service.Project) bool { return true })), nil
+}
@@ -0,0 +1,208 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+	"github.com/pacerclub/zebra-backend/internal/service"
+)
+
+// sessionRepo keeps timer sessions
+type sessionRepo struct{ s *Store }
+
+// sessionInScope reports whether a session is one of the user's in the
+// scope of orgID, as service.SessionScopeSQL does. The caller holds s.mu.
+func (s *Store) sessionInScope(session service.Session, userID uuid.UUID, orgID *uuid.UUID) bool {
+	if session.UserID != userID {
+		return false
+	}
+	if session.ProjectID == nil {
+		return orgID == nil
+	}
+	project, ok := s.projects[*session.ProjectID]
+	if !ok {
+		return false
+	}
+	if orgID == nil {
+		return project.OrganizationID == nil
+	}
+	return project.OrganizationID != nil && *project.OrganizationID == *orgID
+}
+
+// copySession returns a session sharing no memory with the stored one
+func copySession(session service.Session) service.Session {
+	session.ProjectID = copyID(session.ProjectID)
+	session.TaskID = copyID(session.TaskID)
+	session.EncryptedDescription = append([]byte(nil), session.EncryptedDescription...)
+	session.Breaks = append([]service.Break{}, session.Breaks...)
+	return session
+}
+
+func copyID(id *uuid.UUID) *uuid.UUID {
+	if id == nil {
+		return nil
+	}
+	copied := *id
+	return &copied
+}
+
+// netSeconds is the length of a session less its breaks clipped to it, as
+// the update_session_break_seconds trigger counts them
+func netSeconds(session service.Session) int64 {
+	var breaks time.Duration
+	for _, b := range session.Breaks {
+		start, end := b.StartTime, b.EndTime
+		if start.Before(session.StartTime) {
+			start = session.StartTime
+		}
+		if end.After(session.EndTime) {
+			end = session.EndTime
+		}
+		if end.After(start) {
+			breaks += end.Sub(start)
+		}
+	}
+	// Postgres rounds both to whole seconds
+	return int64(math.Round(session.EndTime.Sub(session.StartTime).Seconds())) - int64(math.Round(breaks.Seconds()))
+}
+
+// liveSessions returns the live sessions keep selects. The caller holds
+// s.mu.
+func (s *Store) liveSessions(keep func(service.Session) bool) []service.Session {
+	var sessions []service.Session
+	for _, session := range s.sessions {
+		if !session.IsDeleted && keep(session) {
+			sessions = append(sessions, copySession(session))
+		}
+	}
+	return sessions
+}
+
+func (r sessionRepo) List(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, q listquery.Query) ([]service.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	sessions := r.s.liveSessions(func(session service.Session) bool {
+		return r.s.sessionInScope(session, userID, orgID)
+	})
+	return service.SessionList.Slice(sessions, q), nil
+}
+
+func (r sessionRepo) ListOrganization(ctx context.Context, orgID uuid.UUID, q listquery.Query) ([]service.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	sessions := r.s.liveSessions(func(session service.Session) bool {
+		if session.ProjectID == nil {
+			return false
+		}
+		project, ok := r.s.projects[*session.ProjectID]
+		return ok && project.OrganizationID != nil && *project.OrganizationID == orgID
+	})
+	return service.SessionList.Slice(sessions, q), nil
+}
+
+func (r sessionRepo) Search(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, tsquery string, limit int) ([]service.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	sessions := r.s.liveSessions(func(session service.Session) bool {
+		return r.s.sessionInScope(session, userID, orgID) && matches(session.Description, tsquery)
+	})
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].StartTime.After(sessions[j].StartTime)
+	})
+	if len(sessions) > limit {
+		sessions = sessions[:limit]
+	}
+	return sessions, nil
+}
+
+func (r sessionRepo) Create(ctx context.Context, sessions []service.Session, suggestionIDs []*uuid.UUID) ([]service.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, session := range sessions {
+		if _, exists := r.s.sessions[session.ID]; exists {
+			return nil, errors.New("duplicate session id")
+		}
+	}
+
+	created := make([]service.Session, len(sessions))
+	for i, session := range sessions {
+		stored := copySession(service.Session{
+			ID:                   session.ID,
+			UserID:               session.UserID,
+			ProjectID:            session.ProjectID,
+			StartTime:            session.StartTime.Round(time.Microsecond),
+			EndTime:              session.EndTime.Round(time.Microsecond),
+			Description:          session.Description,
+			EncryptedDescription: session.EncryptedDescription,
+			KeyID:                session.KeyID,
+			DeviceID:             session.DeviceID,
+			CreatedAt:            now(),
+			Version:              1,
+			Breaks:               session.Breaks,
+		})
+		stored.UpdatedAt = stored.CreatedAt
+		stored.NetSeconds = netSeconds(stored)
+		r.s.sessions[stored.ID] = stored
+		r.s.changed[stored.ID] = r.s.tick()
+		created[i] = copySession(stored)
+	}
+	return created, nil
+}
+
+func (r sessionRepo) Update(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, sessionID uuid.UUID, session service.Session) (service.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stored, ok := r.s.sessions[sessionID]
+	if !ok || !r.s.sessionInScope(stored, userID, orgID) {
+		return service.Session{}, notFound("Session not found")
+	}
+	if stored.Version != session.Version {
+		return service.Session{}, conflict(copySession(stored), "Session was changed since version %d", session.Version)
+	}
+
+	stored.ProjectID = copyID(session.ProjectID)
+	stored.StartTime = session.StartTime.Round(time.Microsecond)
+	stored.EndTime = session.EndTime.Round(time.Microsecond)
+	stored.Description = session.Description
+	stored.EncryptedDescription = append([]byte(nil), session.EncryptedDescription...)
+	stored.KeyID = session.KeyID
+	if session.Breaks != nil {
+		stored.Breaks = append([]service.Break{}, session.Breaks...)
+	}
+	stored.UpdatedAt = now()
+	stored.Version++
+	stored.NetSeconds = netSeconds(stored)
+	r.s.sessions[sessionID] = stored
+	r.s.changed[sessionID] = r.s.tick()
+	return copySession(stored), nil
+}
+
+func (r sessionRepo) Delete(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, sessionID uuid.UUID) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stored, ok := r.s.sessions[sessionID]
+	if !ok || !r.s.sessionInScope(stored, userID, orgID) {
+		return false, nil
+	}
+	stored.IsDeleted = true
+	stored.UpdatedAt = now()
+	stored.Version++
+	r.s.sessions[sessionID] = stored
+	r.s.changed[sessionID] = r.s.tick()
+	return true, nil
+}
+
+func (r sessionRepo) StartTime(ctx context.Context, userID, sessionID uuid.UUID) (*time.Time, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stored, ok := r.s.sessions[sessionID]
+	if !ok || stored.UserID != userID {
+		return nil, nil
+	}
+	return &stored.StartTime, nil
+}
@@ -0,0 +1,196 @@
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/service"
+)
+
+// syncRepo applies sync batches of sessions and projects
+type syncRepo struct{ s *Store }
+
+// storedSync is the result of a batch, kept for replaying its retries
+type storedSync struct {
+	fingerprint []byte
+	result      service.SyncResult
+}
+
+// Apply holds s.mu throughout, so syncs are applied one after another.
+// Local changes are written as sent: no record is a conflict.
+func (r syncRepo) Apply(ctx context.Context, userID uuid.UUID, batch service.SyncBatch) (*service.SyncResult, error) {
+	req := batch.Request
+	if len(req.LocalTags)+len(req.LocalTasks)+len(req.LocalTemplates)+len(req.LocalPreferences)+len(req.LocalTimeOff) > 0 ||
+		len(req.DeletedTags)+len(req.DeletedTasks)+len(req.DeletedTemplates)+len(req.DeletedPreferences)+len(req.DeletedTimeOff) > 0 {
+		return nil, &service.Error{Code: service.Unimplemented, Message: "Only sessions and projects are synced by this server"}
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user, ok := r.s.users[userID]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+
+	if batch.IdempotencyKey != "" {
+		if stored, ok := r.s.syncs[userID][batch.IdempotencyKey]; ok {
+			if !bytes.Equal(stored.fingerprint, batch.Fingerprint) {
+				return nil, &service.Error{Code: service.Conflict, Reason: apierror.IdempotencyKeyReused, Message: "This idempotency key was used for a different sync batch"}
+			}
+			result := stored.result
+			result.Replayed = true
+			return &result, nil
+		}
+	}
+
+	// The writes of this sync are stamped with its start, which is also the
+	// cursor returned, so they are not echoed back now or later
+	start := r.s.tick()
+	receivedAt := time.Now()
+	var rejected []service.SyncItemError
+
+	knownProjects := make(map[uuid.UUID]bool)
+	for id, project := range r.s.projects {
+		if !project.IsDeleted && projectInScope(project, userID, nil) {
+			knownProjects[id] = true
+		}
+	}
+
+	for i, project := range req.LocalProjects {
+		if project.ID == uuid.Nil {
+			project.ID = uuid.New()
+		}
+		stored, exists := r.s.projects[project.ID]
+		if exists && !projectInScope(stored, userID, nil) {
+			rejected = append(rejected, service.SyncItemError{Collection: "projects", Index: i, ID: project.ID, Field: "id", Message: "project belongs to another user"})
+			continue
+		}
+		if project.OrganizationID != nil {
+			rejected = append(rejected, service.SyncItemError{Collection: "projects", Index: i, ID: project.ID, Field: "organization_id", Message: "only organization admins can create projects"})
+			continue
+		}
+		if itemErr := service.ValidateSyncProject(i, project, user.StorageMode); itemErr != nil {
+			rejected = append(rejected, *itemErr)
+			continue
+		}
+
+		createdAt, updatedAt := service.ClientTimestamps(project.CreatedAt, project.UpdatedAt, receivedAt)
+		if !exists {
+			stored = service.Project{ID: project.ID, UserID: userID, CreatedAt: createdAt.Round(time.Microsecond)}
+		}
+		stored.Name = project.Name
+		stored.Description = project.Description
+		stored.Color = project.Color
+		stored.EncryptedName = append([]byte(nil), project.EncryptedName...)
+		stored.EncryptedDescription = append([]byte(nil), project.EncryptedDescription...)
+		stored.KeyID = project.KeyID
+		stored.DeviceID = project.DeviceID
+		stored.UpdatedAt = updatedAt.Round(time.Microsecond)
+		stored.Version++
+		r.s.projects[project.ID] = stored
+		r.s.changed[project.ID] = start
+		knownProjects[project.ID] = !stored.IsDeleted
+	}
+
+	for i, session := range req.LocalSessions {
+		if session.ID == uuid.Nil {
+			session.ID = uuid.New()
+		}
+		stored, exists := r.s.sessions[session.ID]
+		if exists && stored.UserID != userID {
+			rejected = append(rejected, service.SyncItemError{Collection: "sessions", Index: i, ID: session.ID, Field: "id", Message: "session belongs to another user"})
+			continue
+		}
+		if itemErr := service.ValidateSyncSession(i, session, knownProjects, user.StorageMode); itemErr != nil {
+			rejected = append(rejected, *itemErr)
+			continue
+		}
+
+		createdAt, updatedAt := service.ClientTimestamps(session.CreatedAt, session.UpdatedAt, receivedAt)
+		if !exists {
+			stored = service.Session{ID: session.ID, UserID: userID, CreatedAt: createdAt.Round(time.Microsecond), Breaks: []service.Break{}}
+		}
+		stored.ProjectID = copyID(session.ProjectID)
+		stored.StartTime = session.StartTime.Round(time.Microsecond)
+		stored.EndTime = session.EndTime.Round(time.Microsecond)
+		stored.Description = session.Description
+		stored.EncryptedDescription = append([]byte(nil), session.EncryptedDescription...)
+		stored.KeyID = session.KeyID
+		stored.DeviceID = session.DeviceID
+		if session.Breaks != nil {
+			stored.Breaks = append([]service.Break{}, session.Breaks...)
+		}
+		stored.UpdatedAt = updatedAt.Round(time.Microsecond)
+		stored.Version++
+		stored.NetSeconds = netSeconds(stored)
+		r.s.sessions[session.ID] = stored
+		r.s.changed[session.ID] = start
+	}
+
+	for _, id := range req.DeletedSessions {
+		if stored, ok := r.s.sessions[id]; ok && stored.UserID == userID && !stored.IsDeleted {
+			stored.IsDeleted = true
+			stored.UpdatedAt = now()
+			stored.Version++
+			r.s.sessions[id] = stored
+			r.s.changed[id] = start
+		}
+	}
+	for i, id := range req.DeletedProjects {
+		stored, ok := r.s.projects[id]
+		if !ok || stored.IsDeleted {
+			continue
+		}
+		if !projectInScope(stored, userID, nil) {
+			rejected = append(rejected, service.SyncItemError{Collection: "deleted_projects", Index: i, ID: id, Message: "project belongs to another user"})
+			continue
+		}
+		stored.IsDeleted = true
+		stored.UpdatedAt = now()
+		stored.Version++
+		r.s.projects[id] = stored
+		r.s.changed[id] = start
+	}
+
+	// Return what was written since the cursor by anything but this sync,
+	// tombstones included
+	response := service.SyncResponse{LastSyncTime: start, Rejected: rejected}
+	unseen := func(id uuid.UUID) bool {
+		changed := r.s.changed[id]
+		return changed.After(req.LastSyncTime) && changed.Before(start)
+	}
+	for id, session := range r.s.sessions {
+		if session.UserID == userID && unseen(id) {
+			response.ServerSessions = append(response.ServerSessions, copySession(session))
+		}
+	}
+	for id, project := range r.s.projects {
+		if projectInScope(project, userID, nil) && unseen(id) {
+			response.ServerProjects = append(response.ServerProjects, copyProject(project))
+		}
+	}
+	sort.Slice(response.ServerSessions, func(i, j int) bool {
+		return r.s.changed[response.ServerSessions[i].ID].Before(r.s.changed[response.ServerSessions[j].ID])
+	})
+	sort.Slice(response.ServerProjects, func(i, j int) bool {
+		return r.s.changed[response.ServerProjects[i].ID].Before(r.s.changed[response.ServerProjects[j].ID])
+	})
+
+	body, err := service.MarshalBody(batch.ContentType, &response)
+	if err != nil {
+		return nil, err
+	}
+	result := service.SyncResult{Response: &response, ContentType: batch.ContentType, Body: body}
+	if batch.IdempotencyKey != "" {
+		if r.s.syncs[userID] == nil {
+			r.s.syncs[userID] = make(map[string]storedSync)
+		}
+		r.s.syncs[userID][batch.IdempotencyKey] = storedSync{fingerprint: batch.Fingerprint, result: result}
+	}
+	return &result, nil
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// ReactivationTTL is how long a reactivation link can be used
+const ReactivationTTL = 24 * time.Hour
+
+// ErrInvalidReactivation is returned for reactivation tokens that are
+// unknown, used or expired
+var ErrInvalidReactivation = errors.New("invalid or expired reactivation token")
+
+// Queries, checked against the schema by db.CheckStatements
+var (
+	deactivateUserSQL = db.Statement("deactivate_user", `
+		UPDATE users SET deactivated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deactivated_at IS NULL`)
+	createReactivationSQL = db.Statement("create_reactivation", `
+		INSERT INTO account_reactivations (token_hash, user_id, expires_at)
+		VALUES ($1, $2, $3)`)
+	deleteExpiredReactivationsSQL = db.Statement("delete_expired_reactivations", `
+		DELETE FROM account_reactivations WHERE user_id = $1 AND expires_at < CURRENT_TIMESTAMP`)
+	useReactivationSQL = db.Statement("use_reactivation", `
+		DELETE FROM account_reactivations
+		WHERE token_hash = $1 AND expires_at > CURRENT_TIMESTAMP
+		RETURNING user_id`)
+	reactivateUserSQL = db.Statement("reactivate_user", `
+		UPDATE users SET deactivated_at = NULL
+		WHERE id = $1
+		RETURNING `+userColumns)
+	deleteReactivationsSQL = db.Statement("delete_reactivations", `
+		DELETE FROM account_reactivations WHERE user_id = $1`)
+)
+
+// DeactivateUser deactivates the user's account. Their data is kept, but
+// they can no longer log in, use their tokens or sync until they
+// reactivate.
+func DeactivateUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := db.GetDB().Exec(ctx, deactivateUserSQL, userID)
+	return err
+}
+
+// CreateReactivation returns a token that reactivates the user's account
+// within ReactivationTTL. Only its hash is stored.
+func CreateReactivation(ctx context.Context, userID uuid.UUID) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	err := pgx.BeginFunc(ctx, db.GetDB(), func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, deleteExpiredReactivationsSQL, userID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, createReactivationSQL, hashReactivation(token), userID, time.Now().Add(ReactivationTTL))
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Reactivate uses a token from CreateReactivation to reactivate its user's
+// account, and returns the user. Every other reactivation token of the
+// user stops working.
+func Reactivate(ctx context.Context, token string) (*User, error) {
+	var user *User
+	err := pgx.BeginFunc(ctx, db.GetDB(), func(tx pgx.Tx) error {
+		var userID uuid.UUID
+		err := tx.QueryRow(ctx, useReactivationSQL, hashReactivation(token)).Scan(&userID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidReactivation
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, deleteReactivationsSQL, userID); err != nil {
+			return err
+		}
+		user, err = scanUser(tx.QueryRow(ctx, reactivateUserSQL, userID))
+		return err
+	})
+	return user, err
+}
+
+func hashReactivation(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+var (
+	ErrKioskNotFound = errors.New("kiosk not found")
+	// ErrKioskProject is returned for kiosks on a project that is not one
+	// of the organization's
+	ErrKioskProject = errors.New("project is not one of the organization's")
+)
+
+// KioskPINLength is the number of digits of a member's kiosk PIN
+const KioskPINLength = 6
+
+// Kiosk is a shared device where an organization's members clock in and out
+// with their PIN, recording time on the kiosk's project
+type Kiosk struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	ProjectID      uuid.UUID  `json:"project_id"`
+	Name           string     `json:"name"`
+	CreatedBy      *uuid.UUID `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+}
+
+const kioskColumns = "id, organization_id, project_id, name, created_by, created_at, last_used_at"
+
+func scanKiosk(row pgx.Row) (*Kiosk, error) {
+	var k Kiosk
+	err := row.Scan(&k.ID, &k.OrganizationID, &k.ProjectID, &k.Name, &k.CreatedBy, &k.CreatedAt, &k.LastUsedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrKioskNotFound
+	}
+	return &k, err
+}
+
+func hashKioskSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateKiosk registers a kiosk recording time on projectID, which must be
+// a project of the organization, and returns it with its token. Only a hash
+// of the token is stored, so it cannot be shown again.
+func CreateKiosk(ctx context.Context, orgID, projectID uuid.UUID, name string, createdBy uuid.UUID) (*Kiosk, string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	token := "kiosk_" + hex.EncodeToString(b)
+
+	kiosk, err := scanKiosk(db.GetDB().QueryRow(ctx, `
+		INSERT INTO kiosks (id, organization_id, project_id, name, token_hash, created_by)
+		SELECT $1, $2, id, $4, $5, $6 FROM projects
+		WHERE id = $3 AND organization_id = $2 AND is_deleted = false
+		RETURNING `+kioskColumns,
+		uuid.New(), orgID, projectID, name, hashKioskSecret(token), createdBy))
+	if errors.Is(err, ErrKioskNotFound) {
+		return nil, "", ErrKioskProject
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return kiosk, token, nil
+}
+
+// ListKiosks returns the organization's kiosks, oldest first
+func ListKiosks(ctx context.Context, orgID uuid.UUID) ([]Kiosk, error) {
+	rows, err := db.GetDB().Query(ctx,
+		`SELECT `+kioskColumns+` FROM kiosks WHERE organization_id = $1 ORDER BY created_at`,
+		orgID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Kiosk, error) {
+		k, err := scanKiosk(row)
+		if err != nil {
+			return Kiosk{}, err
+		}
+		return *k, nil
+	})
+}
+
+// DeleteKiosk removes a kiosk of the organization, revoking its token
+func DeleteKiosk(ctx context.Context, orgID, kioskID uuid.UUID) (bool, error) {
+	result, err := db.GetDB().Exec(ctx,
+		`DELETE FROM kiosks WHERE id = $1 AND organization_id = $2`,
+		kioskID, orgID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// KioskByToken returns the kiosk a token belongs to, recording that it was
+// used. Kiosks whose project was deleted no longer work.
+func KioskByToken(ctx context.Context, token string) (*Kiosk, error) {
+	return scanKiosk(db.GetDB().QueryRow(ctx, `
+		UPDATE kiosks SET last_used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND project_id IN (SELECT id FROM projects WHERE is_deleted = false)
+		RETURNING `+kioskColumns,
+		hashKioskSecret(token)))
+}
+
+// NewKioskPIN gives the active member a new random kiosk PIN, replacing
+// their old one, and returns it. PINs are unique within the organization.
+func NewKioskPIN(ctx context.Context, orgID, userID uuid.UUID) (string, error) {
+	limit := big.NewInt(1)
+	for i := 0; i < KioskPINLength; i++ {
+		limit.Mul(limit, big.NewInt(10))
+	}
+
+	// Retry the rare PIN another member already has
+	for attempt := 0; attempt < 10; attempt++ {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+		pin := fmt.Sprintf("%0*d", KioskPINLength, n)
+
+		hash := kioskPINHash(orgID, pin)
+		result, err := db.GetDB().Exec(ctx,
+			`UPDATE memberships SET kiosk_pin_hash = $3
+			WHERE organization_id = $1 AND user_id = $2 AND deactivated_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM memberships WHERE organization_id = $1 AND kiosk_pin_hash = $3)`,
+			orgID, userID, hash)
+		if err != nil {
+			return "", err
+		}
+		if result.RowsAffected() > 0 {
+			return pin, nil
+		}
+	}
+	return "", errors.New("no free kiosk PIN found")
+}
+
+// ClearKioskPIN removes the member's kiosk PIN, so they can no longer clock
+// in at kiosks
+func ClearKioskPIN(ctx context.Context, orgID, userID uuid.UUID) error {
+	_, err := db.GetDB().Exec(ctx,
+		`UPDATE memberships SET kiosk_pin_hash = NULL WHERE organization_id = $1 AND user_id = $2`,
+		orgID, userID)
+	return err
+}
+
+// KioskMember is the member a kiosk PIN identifies. Kiosks are shared, so
+// they only see the member's display name.
+type KioskMember struct {
+	UserID      uuid.UUID `json:"user_id"`
+	DisplayName string    `json:"display_name"`
+	Role        string    `json:"-"`
+}
+
+// MemberByKioskPIN returns the active member of the organization with the
+// PIN, or nil if there is none
+func MemberByKioskPIN(ctx context.Context, orgID uuid.UUID, pin string) (*KioskMember, error) {
+	var member KioskMember
+	err := db.GetDB().QueryRow(ctx,
+		`SELECT u.id, u.display_name, m.role
+		FROM memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1 AND m.kiosk_pin_hash = $2 AND m.deactivated_at IS NULL`,
+		orgID, kioskPINHash(orgID, pin),
+	).Scan(&member.UserID, &member.DisplayName, &member.Role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// kioskPINHash hashes a PIN together with its organization, so equal PINs
+// of different organizations do not share a hash
+func kioskPINHash(orgID uuid.UUID, pin string) string {
+	return hashKioskSecret(orgID.String() + ":" + pin)
+}
@@ -0,0 +1,362 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+	"github.com/pacerclub/zebra-backend/internal/pagination"
+)
+
+var ErrOrganizationNotFound = errors.New("organization not found")
+
+// Membership roles. Owners manage the organization itself, admins manage
+// its members and projects, members log time against its projects.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// ValidRole reports whether role is one of the membership roles
+func ValidRole(role string) bool {
+	return role == RoleOwner || role == RoleAdmin || role == RoleMember
+}
+
+type Organization struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy uuid.UUID `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Member struct {
+	UserID        uuid.UUID  `json:"user_id"`
+	Email         string     `json:"email"`
+	Role          string     `json:"role"`
+	JoinedAt      time.Time  `json:"joined_at"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+}
+
+// MemberActivity is a member together with the time they logged against the
+// organization's projects
+type MemberActivity struct {
+	Member
+	SessionCount   int   `json:"session_count"`
+	TrackedSeconds int64 `json:"tracked_seconds"`
+	// RoundedSeconds is TrackedSeconds with each session rounded, set when
+	// the organization rounds reports
+	RoundedSeconds *int64     `json:"rounded_seconds,omitempty"`
+	LastActiveAt   *time.Time `json:"last_active_at"`
+}
+
+// CreateOrganization creates an organization with the creator as its owner
+func CreateOrganization(ctx context.Context, name string, createdBy uuid.UUID) (*Organization, error) {
+	tx, err := db.GetDB().Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	org := &Organization{ID: uuid.New()}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO organizations (id, name, created_by)
+		VALUES ($1, $2, $3)
+		RETURNING id, name, created_by, created_at, updated_at`,
+		org.ID, name, createdBy,
+	).Scan(&org.ID, &org.Name, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO memberships (organization_id, user_id, role) VALUES ($1, $2, $3)`,
+		org.ID, createdBy, RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// GetOrganization retrieves an organization by ID
+func GetOrganization(ctx context.Context, orgID uuid.UUID) (*Organization, error) {
+	org := &Organization{}
+	err := db.GetDB().QueryRow(ctx,
+		`SELECT id, name, created_by, created_at, updated_at
+		FROM organizations WHERE id = $1`,
+		orgID,
+	).Scan(&org.ID, &org.Name, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt)
+
+	if err == pgx.ErrNoRows {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// ListOrganizations returns the organizations the user is a member of
+func ListOrganizations(ctx context.Context, userID uuid.UUID) ([]Organization, error) {
+	rows, err := db.GetDB().Query(ctx,
+		`SELECT o.id, o.name, o.created_by, o.created_at, o.updated_at
+		FROM organizations o
+		JOIN memberships m ON m.organization_id = o.id
+		WHERE m.user_id = $1 AND m.deactivated_at IS NULL
+		ORDER BY o.name`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []Organization{}
+	for rows.Next() {
+		var org Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+// RenameOrganization changes an organization's name
+func RenameOrganization(ctx context.Context, orgID uuid.UUID, name string) (*Organization, error) {
+	org := &Organization{}
+	err := db.GetDB().QueryRow(ctx,
+		`UPDATE organizations SET name = $1 WHERE id = $2
+		RETURNING id, name, created_by, created_at, updated_at`,
+		name, orgID,
+	).Scan(&org.ID, &org.Name, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt)
+
+	if err == pgx.ErrNoRows {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// DeleteOrganization removes an organization together with its memberships
+// and projects
+func DeleteOrganization(ctx context.Context, orgID uuid.UUID) error {
+	result, err := db.GetDB().Exec(ctx, `DELETE FROM organizations WHERE id = $1`, orgID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrOrganizationNotFound
+	}
+	return nil
+}
+
+// GetMemberRole returns the user's role in the organization, or "" if the
+// user is not an active member
+func GetMemberRole(ctx context.Context, orgID, userID uuid.UUID) (string, error) {
+	var role string
+	err := db.GetDB().QueryRow(ctx,
+		`SELECT role FROM memberships WHERE organization_id = $1 AND user_id = $2 AND deactivated_at IS NULL`,
+		orgID, userID,
+	).Scan(&role)
+
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// CountOwners returns how many active owners the organization has
+func CountOwners(ctx context.Context, orgID uuid.UUID) (int, error) {
+	var count int
+	err := db.GetDB().QueryRow(ctx,
+		`SELECT COUNT(*) FROM memberships WHERE organization_id = $1 AND role = $2 AND deactivated_at IS NULL`,
+		orgID, RoleOwner,
+	).Scan(&count)
+	return count, err
+}
+
+// CountActiveMembers returns how many active members the organization has
+func CountActiveMembers(ctx context.Context, orgID uuid.UUID) (int, error) {
+	var count int
+	err := db.GetDB().QueryRow(ctx,
+		`SELECT COUNT(*) FROM memberships WHERE organization_id = $1 AND deactivated_at IS NULL`,
+		orgID,
+	).Scan(&count)
+	return count, err
+}
+
+// ListMembers returns the members of an organization
+func ListMembers(ctx context.Context, orgID uuid.UUID) ([]Member, error) {
+	rows, err := db.GetDB().Query(ctx,
+		`SELECT u.id, u.email, m.role, m.created_at, m.deactivated_at
+		FROM memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1
+		ORDER BY m.created_at`,
+		orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []Member{}
+	for rows.Next() {
+		var member Member
+		if err := rows.Scan(&member.UserID, &member.Email, &member.Role, &member.JoinedAt, &member.DeactivatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// AddMember adds the user to the organization with the given role. Adding an
+// existing member is a no-op.
+func AddMember(ctx context.Context, orgID, userID uuid.UUID, role string) error {
+	if !ValidRole(role) {
+		return errors.New("invalid role")
+	}
+
+	_, err := db.GetDB().Exec(ctx,
+		`INSERT INTO memberships (organization_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id, user_id) DO NOTHING`,
+		orgID, userID, role)
+	return err
+}
+
+// RemoveMember removes the user from the organization
+func RemoveMember(ctx context.Context, orgID, userID uuid.UUID) (bool, error) {
+	result, err := db.GetDB().Exec(ctx,
+		`DELETE FROM memberships WHERE organization_id = $1 AND user_id = $2`,
+		orgID, userID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// MemberActivityList is how member activity can be sorted, by default in the
+// order members joined
+var MemberActivityList = listquery.List[MemberActivity]{
+	Sorts: map[string]listquery.Column[MemberActivity]{
+		"joined_at": {SQL: "m.created_at", Key: func(m MemberActivity) time.Time { return m.JoinedAt }},
+	},
+	Default: listquery.Sort{Field: "joined_at"},
+	ID:      "u.id",
+	RowID:   func(m MemberActivity) uuid.UUID { return m.UserID },
+}
+
+// ListMemberActivity returns a page of the members of an organization with
+// the number of sessions and the time they logged against its projects,
+// also rounded unless rounding is NoRounding
+func ListMemberActivity(ctx context.Context, orgID uuid.UUID, rounding Rounding, q listquery.Query) (pagination.Page[MemberActivity], error) {
+	seconds := NetSecondsSQL("s")
+	where, orderBy := MemberActivityList.SQL(q, 2)
+	args := append([]interface{}{orgID}, q.Args()...)
+	rows, err := db.ReadPool(ctx).Query(ctx,
+		`SELECT u.id, u.email, m.role, m.created_at, m.deactivated_at,
+			COUNT(s.id),
+			COALESCE(SUM(`+seconds+`), 0)::BIGINT,
+			COALESCE(SUM(`+rounding.SecondsSQL(seconds)+`), 0)::BIGINT,
+			MAX(s.end_time)
+		FROM memberships m
+		JOIN users u ON u.id = m.user_id
+		LEFT JOIN timer_sessions s ON s.user_id = m.user_id AND s.is_deleted = false
+			AND s.project_id IN (SELECT id FROM projects WHERE organization_id = $1)
+		WHERE m.organization_id = $1 AND `+where+`
+		GROUP BY u.id, u.email, m.role, m.created_at, m.deactivated_at
+		ORDER BY `+orderBy+`
+		LIMIT $4`,
+		append(args, q.Page.Fetch())...)
+	if err != nil {
+		return pagination.Page[MemberActivity]{}, err
+	}
+	defer rows.Close()
+
+	var members []MemberActivity
+	for rows.Next() {
+		var member MemberActivity
+		var rounded int64
+		err := rows.Scan(&member.UserID, &member.Email, &member.Role, &member.JoinedAt, &member.DeactivatedAt,
+			&member.SessionCount, &member.TrackedSeconds, &rounded, &member.LastActiveAt)
+		if err != nil {
+			return pagination.Page[MemberActivity]{}, err
+		}
+		if rounding != NoRounding {
+			member.RoundedSeconds = &rounded
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return pagination.Page[MemberActivity]{}, err
+	}
+	return MemberActivityList.Page(members, q), nil
+}
+
+// GetMember returns a member of the organization, including deactivated
+// members, or nil if the user is not a member
+func GetMember(ctx context.Context, orgID, userID uuid.UUID) (*Member, error) {
+	member := &Member{}
+	err := db.GetDB().QueryRow(ctx,
+		`SELECT u.id, u.email, m.role, m.created_at, m.deactivated_at
+		FROM memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1 AND m.user_id = $2`,
+		orgID, userID,
+	).Scan(&member.UserID, &member.Email, &member.Role, &member.JoinedAt, &member.DeactivatedAt)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// SetMemberRole changes a member's role
+func SetMemberRole(ctx context.Context, orgID, userID uuid.UUID, role string) error {
+	if !ValidRole(role) {
+		return errors.New("invalid role")
+	}
+
+	_, err := db.GetDB().Exec(ctx,
+		`UPDATE memberships SET role = $3 WHERE organization_id = $1 AND user_id = $2`,
+		orgID, userID, role)
+	return err
+}
+
+// SetMemberActive deactivates or reactivates a member. Deactivated members
+// keep their history but lose access to the organization.
+func SetMemberActive(ctx context.Context, orgID, userID uuid.UUID, active bool) error {
+	_, err := db.GetDB().Exec(ctx,
+		`UPDATE memberships
+		SET deactivated_at = CASE WHEN $3 THEN NULL ELSE COALESCE(deactivated_at, CURRENT_TIMESTAMP) END
+		WHERE organization_id = $1 AND user_id = $2`,
+		orgID, userID, active)
+	return err
+}
+
+// TransferProjects hands the organization projects owned by one member over to
+// another and returns how many were moved
+func TransferProjects(ctx context.Context, orgID, fromUserID, toUserID uuid.UUID) (int64, error) {
+	result, err := db.GetDB().Exec(ctx,
+		`UPDATE projects SET user_id = $3 WHERE organization_id = $1 AND user_id = $2`,
+		orgID, fromUserID, toUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+const maxAllowedTags = 500
+
+// OrganizationSettings are defaults an organization applies to its members'
+// time. They are stored as one JSON document so new settings need no
+// schema change.
+type OrganizationSettings struct {
+	DefaultCurrency string `json:"default_currency"`
+	// WeekStart is "monday" or "sunday"
+	WeekStart string `json:"week_start"`
+	// Rounding rounds session durations to multiples of RoundingMinutes
+	// in what RoundingApplyTo lists: reports, exports and invoices
+	RoundingMode    string   `json:"rounding_mode"`
+	RoundingMinutes int      `json:"rounding_minutes"`
+	RoundingApplyTo []string `json:"rounding_apply_to"`
+	// Sessions starting before LockedBefore can no longer be created,
+	// changed or deleted
+	LockedBefore *time.Time `json:"locked_before"`
+	// AllowedTags limits the tags members may use; empty allows any tag
+	AllowedTags []string `json:"allowed_tags"`
+}
+
+// DefaultOrganizationSettings returns the settings of a new organization
+func DefaultOrganizationSettings() OrganizationSettings {
+	return OrganizationSettings{
+		DefaultCurrency: "USD",
+		WeekStart:       "monday",
+		RoundingMode:    RoundingNone,
+		RoundingApplyTo: []string{RoundInvoices},
+		AllowedTags:     []string{},
+	}
+}
+
+// Validate checks the settings and normalizes the currency code
+func (s *OrganizationSettings) Validate(v *validate.Validator) {
+	s.DefaultCurrency = strings.ToUpper(strings.TrimSpace(s.DefaultCurrency))
+	v.Check(len(s.DefaultCurrency) == 3, "default_currency", "must be a 3-letter currency code")
+	v.Check(s.WeekStart == "monday" || s.WeekStart == "sunday", "week_start", "must be monday or sunday")
+	if s.RoundingApplyTo == nil {
+		s.RoundingApplyTo = []string{}
+	}
+	validateRounding(v, "rounding_mode", "rounding_minutes", "rounding_apply_to", s.RoundingMode, s.RoundingMinutes, s.RoundingApplyTo)
+	if s.AllowedTags == nil {
+		s.AllowedTags = []string{}
+	}
+	v.Check(len(s.AllowedTags) <= maxAllowedTags, "allowed_tags", "has too many tags")
+}
+
+// Rounding returns the organization's rounding if it applies to target
+func (s OrganizationSettings) Rounding(target string) Rounding {
+	return roundingFor(s.RoundingMode, s.RoundingMinutes, s.RoundingApplyTo, target)
+}
+
+// TagAllowed reports whether members may use the tag
+func (s OrganizationSettings) TagAllowed(name string) bool {
+	if len(s.AllowedTags) == 0 {
+		return true
+	}
+	for _, tag := range s.AllowedTags {
+		if strings.EqualFold(tag, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetOrganizationSettings returns an organization's settings, with defaults
+// for anything that was never set
+func GetOrganizationSettings(ctx context.Context, orgID uuid.UUID) (OrganizationSettings, error) {
+	settings := DefaultOrganizationSettings()
+
+	var raw []byte
+	err := db.GetDB().QueryRow(ctx,
+		`SELECT settings FROM organizations WHERE id = $1`,
+		orgID,
+	).Scan(&raw)
+
+	if err == pgx.ErrNoRows {
+		return settings, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return settings, err
+	}
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return settings, err
+	}
+	return settings, nil
+}
+
+// UpdateOrganizationSettings replaces an organization's settings
+func UpdateOrganizationSettings(ctx context.Context, orgID uuid.UUID, settings OrganizationSettings) error {
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.GetDB().Exec(ctx,
+		`UPDATE organizations SET settings = $2 WHERE id = $1`,
+		orgID, raw)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrOrganizationNotFound
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// Queries, checked against the schema by db.CheckStatements
+var (
+	getPreferencesSQL = db.Statement("get_preferences", `
+		SELECT key, value FROM user_preferences
+		WHERE user_id = $1 AND key = ANY($2) AND is_deleted = false`)
+	setPreferenceSQL = db.Statement("set_preference", `
+		INSERT INTO user_preferences (user_id, key, value, device_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, key) DO UPDATE
+		SET value = EXCLUDED.value,
+			device_id = EXCLUDED.device_id,
+			is_deleted = false,
+			updated_at = CURRENT_TIMESTAMP`)
+)
+
+// PreferencesDevice marks preferences changed through the API rather than
+// by a synced device
+const PreferencesDevice = "api"
+
+// Time formats clients show times in
+const (
+	TimeFormat12h = "12h"
+	TimeFormat24h = "24h"
+)
+
+// Themes clients can be shown in
+const (
+	ThemeSystem = "system"
+	ThemeLight  = "light"
+	ThemeDark   = "dark"
+)
+
+// Preferences are a user's client settings. Each field is stored as its own
+// key in user_preferences, the table sync already carries, so devices
+// change them independently and other keys clients store there are left
+// alone.
+type Preferences struct {
+	// DefaultProjectID is the project new timers start on, null for none
+	DefaultProjectID *uuid.UUID `json:"default_project_id"`
+	// TimeFormat is "12h" or "24h"
+	TimeFormat string             `json:"time_format"`
+	Rounding   RoundingPreference `json:"rounding"`
+	Reminders  ReminderPreference `json:"reminders"`
+	// WorkingHours is the time expected each weekday, for reports
+	WorkingHours WorkingHoursPreference `json:"working_hours"`
+	// Theme is "system", "light" or "dark"
+	Theme string `json:"theme"`
+}
+
+// RoundingPreference rounds the durations clients show to multiples of
+// Minutes, using the same modes as organization settings. The server also
+// rounds the user's personal time in what ApplyTo lists: reports, exports
+// and invoices.
+type RoundingPreference struct {
+	Mode    string   `json:"mode"`
+	Minutes int      `json:"minutes"`
+	ApplyTo []string `json:"apply_to"`
+}
+
+// For returns the rounding preference if it applies to target
+func (p RoundingPreference) For(target string) Rounding {
+	return roundingFor(p.Mode, p.Minutes, p.ApplyTo, target)
+}
+
+// ReminderPreference controls the reminders clients show while tracking,
+// and the reminder notifications the server sends
+type ReminderPreference struct {
+	Enabled bool `json:"enabled"`
+	// IdleMinutes reminds about a running timer after that long without
+	// activity, 0 for never
+	IdleMinutes int `json:"idle_minutes"`
+	// LongTimerMinutes reminds about a timer running that long, 0 for never
+	LongTimerMinutes int `json:"long_timer_minutes"`
+	// DailyAt reminds to track time at that time of day if none was
+	// tracked yet, "HH:MM" in the user's time zone, or "" for never
+	DailyAt string `json:"daily_at"`
+	// Weekdays limits daily reminders to these days, 0 for Sunday to 6 for
+	// Saturday
+	Weekdays []int `json:"weekdays"`
+}
+
+// DefaultPreferences returns the preferences of a user who has set none
+func DefaultPreferences() Preferences {
+	return Preferences{
+		TimeFormat:   TimeFormat24h,
+		Rounding:     RoundingPreference{Mode: RoundingNone, ApplyTo: []string{}},
+		Reminders:    ReminderPreference{IdleMinutes: 10, LongTimerMinutes: 6 * 60, Weekdays: []int{1, 2, 3, 4, 5}},
+		WorkingHours: defaultWorkingHours(),
+		Theme:        ThemeSystem,
+	}
+}
+
+var timeOfDayPattern = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+// Validate checks the preferences
+func (p *Preferences) Validate(v *validate.Validator) {
+	v.Check(p.DefaultProjectID == nil || *p.DefaultProjectID != uuid.Nil, "default_project_id", "must be a project ID or null")
+	v.Check(p.TimeFormat == TimeFormat12h || p.TimeFormat == TimeFormat24h, "time_format", "must be 12h or 24h")
+	if p.Rounding.ApplyTo == nil {
+		p.Rounding.ApplyTo = []string{}
+	}
+	validateRounding(v, "rounding.mode", "rounding.minutes", "rounding.apply_to", p.Rounding.Mode, p.Rounding.Minutes, p.Rounding.ApplyTo)
+	v.Check(p.Reminders.IdleMinutes >= 0 && p.Reminders.IdleMinutes <= 24*60, "reminders.idle_minutes", "must be between 0 and 1440")
+	v.Check(p.Reminders.LongTimerMinutes >= 0 && p.Reminders.LongTimerMinutes <= 24*60, "reminders.long_timer_minutes", "must be between 0 and 1440")
+	v.Check(p.Reminders.DailyAt == "" || timeOfDayPattern.MatchString(p.Reminders.DailyAt), "reminders.daily_at", "must be HH:MM or empty")
+	if p.Reminders.Weekdays == nil {
+		p.Reminders.Weekdays = []int{}
+	}
+	var seen [7]bool
+	for _, day := range p.Reminders.Weekdays {
+		if day < 0 || day > 6 || seen[day] {
+			v.Fail("reminders.weekdays", "must list days from 0 (Sunday) to 6 (Saturday) at most once")
+			break
+		}
+		seen[day] = true
+	}
+	p.WorkingHours.validate(v)
+	v.Check(p.Theme == ThemeSystem || p.Theme == ThemeLight || p.Theme == ThemeDark, "theme", "must be system, light or dark")
+}
+
+// preferenceValues splits the preferences into their stored keys
+func (p Preferences) preferenceValues() (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]json.RawMessage
+	err = json.Unmarshal(raw, &values)
+	return values, err
+}
+
+// preferenceKeys are the keys of user_preferences that Preferences reads
+var preferenceKeys = func() []string {
+	values, err := DefaultPreferences().preferenceValues()
+	if err != nil {
+		panic(err)
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	return keys
+}()
+
+// IsPreferenceKey reports whether key is one of the typed preferences
+func IsPreferenceKey(key string) bool {
+	for _, k := range preferenceKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePreference checks a value synced for key. Keys other than the
+// typed preferences take any JSON.
+func ValidatePreference(key string, value json.RawMessage) error {
+	if !IsPreferenceKey(key) {
+		return nil
+	}
+	p := DefaultPreferences()
+	if err := p.set(key, value); err != nil {
+		return validate.Errors{{Field: key, Message: key + " has the wrong type"}}
+	}
+	return validate.Struct(&p)
+}
+
+// set decodes the stored value of one key into p
+func (p *Preferences) set(key string, value []byte) error {
+	raw, err := json.Marshal(map[string]json.RawMessage{key: value})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, p)
+}
+
+// GetPreferences returns the user's preferences, with defaults for those
+// never set. Stored values of the wrong type, written by an older client,
+// read as the default too.
+func GetPreferences(ctx context.Context, userID uuid.UUID) (Preferences, error) {
+	preferences := DefaultPreferences()
+	rows, err := db.GetDB().Query(ctx, getPreferencesSQL, userID, preferenceKeys)
+	if err != nil {
+		return preferences, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		var value []byte
+		if err := rows.Scan(&key, &value); err != nil {
+			return preferences, err
+		}
+		if scratch := DefaultPreferences(); scratch.set(key, value) == nil {
+			preferences.set(key, value)
+		}
+	}
+	return preferences, rows.Err()
+}
+
+// UpdatePreferences stores the keys of preferences that differ from
+// current. Unchanged keys are not written, so they do not sync back to
+// devices and do not overwrite what another device set meanwhile.
+func UpdatePreferences(ctx context.Context, userID uuid.UUID, current, preferences Preferences) error {
+	before, err := current.preferenceValues()
+	if err != nil {
+		return err
+	}
+	after, err := preferences.preferenceValues()
+	if err != nil {
+		return err
+	}
+	return pgx.BeginFunc(ctx, db.GetDB(), func(tx pgx.Tx) error {
+		for key, value := range after {
+			if bytes.Equal(before[key], value) {
+				continue
+			}
+			if _, err := tx.Exec(ctx, setPreferenceSQL, userID, key, []byte(value), PreferencesDevice); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// Rounding modes applied to session durations in reports
+const (
+	RoundingNone    = "none"
+	RoundingUp      = "up"
+	RoundingDown    = "down"
+	RoundingNearest = "nearest"
+)
+
+// Where rounding applies besides the durations clients show: reports of
+// tracked time, exports to other tools and invoices
+const (
+	RoundReports  = "reports"
+	RoundExports  = "exports"
+	RoundInvoices = "invoices"
+)
+
+// Rounding rounds session durations to multiples of Minutes. Each session
+// is rounded on its own before durations are added up; stored sessions keep
+// their exact times.
+type Rounding struct {
+	Mode    string
+	Minutes int
+}
+
+// NoRounding leaves durations as they are
+var NoRounding = Rounding{Mode: RoundingNone}
+
+// roundingFor returns the rounding rule if it applies to target
+func roundingFor(mode string, minutes int, applyTo []string, target string) Rounding {
+	for _, t := range applyTo {
+		if t == target {
+			return Rounding{Mode: mode, Minutes: minutes}
+		}
+	}
+	return NoRounding
+}
+
+// Round applies the rounding rule to a duration
+func (r Rounding) Round(d time.Duration) time.Duration {
+	if r.Mode == RoundingNone || r.Minutes <= 0 {
+		return d
+	}
+	step := time.Duration(r.Minutes) * time.Minute
+	switch r.Mode {
+	case RoundingUp:
+		return (d + step - 1) / step * step
+	case RoundingDown:
+		return d / step * step
+	default:
+		return d.Round(step)
+	}
+}
+
+// SecondsSQL rounds seconds, an SQL expression of one session's length in
+// seconds, as Round does
+func (r Rounding) SecondsSQL(seconds string) string {
+	if r.Mode == RoundingNone || r.Minutes <= 0 {
+		return seconds
+	}
+	round := "ROUND"
+	switch r.Mode {
+	case RoundingUp:
+		round = "CEIL"
+	case RoundingDown:
+		round = "FLOOR"
+	}
+	step := r.Minutes * 60
+	return fmt.Sprintf("(%s((%s) / %d.0) * %d)", round, seconds, step, step)
+}
+
+// NetSecondsSQL is the length in seconds of a session of timer_sessions,
+// referenced as table, less its breaks
+func NetSecondsSQL(table string) string {
+	return fmt.Sprintf("(EXTRACT(EPOCH FROM (%[1]s.end_time - %[1]s.start_time)) - %[1]s.break_seconds)", table)
+}
+
+// validateRounding checks a rounding rule and the targets it applies to,
+// reporting problems on the named fields
+func validateRounding(v *validate.Validator, modeField, minutesField, applyToField string, mode string, minutes int, applyTo []string) {
+	switch mode {
+	case RoundingNone:
+	case RoundingUp, RoundingDown, RoundingNearest:
+		v.Check(minutes > 0 && minutes <= 24*60, minutesField, "must be between 1 and 1440")
+	default:
+		v.Fail(modeField, "must be none, up, down or nearest")
+	}
+	seen := make(map[string]bool)
+	for _, target := range applyTo {
+		if target != RoundReports && target != RoundExports && target != RoundInvoices || seen[target] {
+			v.Fail(applyToField, "must list reports, exports or invoices at most once")
+			break
+		}
+		seen[target] = true
+	}
+}
+
+// GetRounding returns the rounding applied to target for the user's time
+// in a workspace: the organization's settings in an organization, and the
+// user's rounding preference in the personal workspace (orgID nil)
+func GetRounding(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, target string) (Rounding, error) {
+	if orgID != nil {
+		settings, err := GetOrganizationSettings(ctx, *orgID)
+		if err != nil {
+			return NoRounding, err
+		}
+		return settings.Rounding(target), nil
+	}
+	preferences, err := GetPreferences(ctx, userID)
+	if err != nil {
+		return NoRounding, err
+	}
+	return preferences.Rounding.For(target), nil
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// DaysOff maps the YYYY-MM-DD dates a user takes off to the type of time
+// off, such as vacation
+type DaysOff map[string]string
+
+// ListDaysOff returns the days off of each of userIDs from start up to end,
+// midnights in the same location. Users without any are left out.
+func ListDaysOff(ctx context.Context, userIDs []uuid.UUID, start, end time.Time) (map[uuid.UUID]DaysOff, error) {
+	rows, err := db.ReadPool(ctx).Query(ctx, `
+		SELECT t.user_id, to_char(d, 'YYYY-MM-DD'), t.type
+		FROM time_off t,
+			generate_series(GREATEST(t.start_date, $2::date), LEAST(t.end_date, $3::date), '1 day') d
+		WHERE t.user_id = ANY($1) AND t.is_deleted = false AND t.end_date >= $2::date AND t.start_date <= $3::date
+		ORDER BY t.start_date, t.id
+	`, userIDs, start.Format("2006-01-02"), end.AddDate(0, 0, -1).Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	daysOff := make(map[uuid.UUID]DaysOff)
+	for rows.Next() {
+		var userID uuid.UUID
+		var date, timeOffType string
+		if err := rows.Scan(&userID, &date, &timeOffType); err != nil {
+			return nil, err
+		}
+		if daysOff[userID] == nil {
+			daysOff[userID] = make(DaysOff)
+		}
+		// Overlapping entries keep the type of the earliest
+		if _, ok := daysOff[userID][date]; !ok {
+			daysOff[userID][date] = timeOffType
+		}
+	}
+	return daysOff, rows.Err()
+}
+
+// GetDaysOff returns the user's days off from start up to end, midnights in
+// the same location
+func GetDaysOff(ctx context.Context, userID uuid.UUID, start, end time.Time) (DaysOff, error) {
+	daysOff, err := ListDaysOff(ctx, []uuid.UUID{userID}, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return daysOff[userID], nil
+}
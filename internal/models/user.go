@@ -3,20 +3,114 @@ package models
 import (
 	"context"
 	"errors"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/validate"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Storage modes a user can choose. In encrypted mode the server only stores
+// client-encrypted project names and session descriptions.
+const (
+	StorageModeStandard  = "standard"
+	StorageModeEncrypted = "encrypted"
+)
+
+const userColumns = "id, email, password_hash, storage_mode, display_name, avatar_url, timezone, locale, week_start, deactivated_at, created_at, updated_at"
+
+// Queries, checked against the schema by db.CheckStatements
+var (
+	createUserSQL = db.Statement("create_user", `
+		INSERT INTO users (id, email, password_hash)
+		VALUES ($1, $2, $3)
+		RETURNING `+userColumns)
+	getUserByEmailSQL = db.Statement("get_user_by_email", `
+		SELECT `+userColumns+`
+		FROM users WHERE email = $1`)
+	getUserByIDSQL = db.Statement("get_user_by_id", `
+		SELECT `+userColumns+`
+		FROM users WHERE id = $1`)
+	updateProfileSQL = db.Statement("update_profile", `
+		UPDATE users
+		SET display_name = $2, avatar_url = $3, timezone = $4, locale = $5, week_start = $6
+		WHERE id = $1
+		RETURNING `+userColumns)
+	setAvatarURLSQL   = db.Statement("set_avatar_url", `UPDATE users SET avatar_url = $2 WHERE id = $1`)
+	revokeTokensSQL   = db.Statement("revoke_tokens", `UPDATE users SET tokens_revoked_at = date_trunc('second', CURRENT_TIMESTAMP) WHERE id = $1`)
+	updateLastSyncSQL = db.Statement("update_last_sync", `
+		INSERT INTO device_sync (user_id, device_id, platform, device_name)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, device_id)
+		DO UPDATE SET last_sync_time = CURRENT_TIMESTAMP,
+		              platform = EXCLUDED.platform,
+		              device_name = EXCLUDED.device_name`)
+	getStorageModeSQL = db.Statement("get_storage_mode", `SELECT storage_mode FROM users WHERE id = $1`)
+	setStorageModeSQL = db.Statement("set_storage_mode", `UPDATE users SET storage_mode = $1 WHERE id = $2`)
+)
+
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"` // Never send password in JSON
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          uuid.UUID `json:"id"`
+	Email       string    `json:"email"`
+	Password    string    `json:"-"` // Never send password in JSON
+	StorageMode string    `json:"storage_mode"`
+	Profile
+	// DeactivatedAt is when the user deactivated their account, nil while
+	// it is active
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Profile holds what users say about themselves, for clients to greet them
+// and show times the way they expect
+type Profile struct {
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	// Timezone is an IANA time zone name, such as "Europe/Berlin"
+	Timezone string `json:"timezone"`
+	// Locale is a BCP 47 language tag, such as "en-US"
+	Locale string `json:"locale"`
+	// WeekStart is "monday" or "sunday"
+	WeekStart string `json:"week_start"`
+}
+
+const (
+	maxDisplayNameLength = 255
+	maxAvatarURLLength   = 2048
+)
+
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{1,8})*$`)
+
+// Validate checks the profile and trims the display name
+func (p *Profile) Validate(v *validate.Validator) {
+	p.DisplayName = strings.TrimSpace(p.DisplayName)
+	v.MaxLength("display_name", p.DisplayName, maxDisplayNameLength)
+	p.AvatarURL = strings.TrimSpace(p.AvatarURL)
+	if p.AvatarURL != "" {
+		avatar, err := url.Parse(p.AvatarURL)
+		v.Check(err == nil && (avatar.Scheme == "https" || avatar.Scheme == "http") && avatar.Host != "",
+			"avatar_url", "must be an http or https URL")
+		v.MaxLength("avatar_url", p.AvatarURL, maxAvatarURLLength)
+	}
+	_, err := time.LoadLocation(p.Timezone)
+	v.Check(p.Timezone != "" && err == nil, "timezone", "must be an IANA time zone name")
+	v.Check(len(p.Locale) <= 35 && localePattern.MatchString(p.Locale), "locale", "must be a language tag such as en-US")
+	v.Check(p.WeekStart == "monday" || p.WeekStart == "sunday", "week_start", "must be monday or sunday")
+}
+
+// scanUser reads a row of userColumns
+func scanUser(row pgx.Row) (*User, error) {
+	user := &User{}
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.StorageMode,
+		&user.DisplayName, &user.AvatarURL, &user.Timezone, &user.Locale, &user.WeekStart,
+		&user.DeactivatedAt, &user.CreatedAt, &user.UpdatedAt)
+	return user, err
 }
 
 // CreateUser creates a new user in the database
@@ -26,14 +120,9 @@ func CreateUser(ctx context.Context, email, password string) (*User, error) {
 		return nil, err
 	}
 
-	user := &User{ID: uuid.New()}
-	err = db.GetDB().QueryRow(ctx,
-		`INSERT INTO users (id, email, password_hash) 
-		VALUES ($1, $2, $3) 
-		RETURNING id, email, created_at, updated_at`,
-		user.ID, email, string(hashedPassword),
-	).Scan(&user.ID, &user.Email, &user.CreatedAt, &user.UpdatedAt)
-
+	user, err := scanUser(db.GetDB().QueryRow(ctx, createUserSQL,
+		uuid.New(), email, string(hashedPassword),
+	))
 	if err != nil {
 		return nil, err
 	}
@@ -43,12 +132,25 @@ func CreateUser(ctx context.Context, email, password string) (*User, error) {
 
 // GetUserByEmail retrieves a user by email
 func GetUserByEmail(ctx context.Context, email string) (*User, error) {
-	user := &User{}
-	err := db.GetDB().QueryRow(ctx,
-		`SELECT id, email, password_hash, created_at, updated_at 
-		FROM users WHERE email = $1`,
+	user, err := scanUser(db.GetDB().QueryRow(ctx, getUserByEmailSQL,
 		email,
-	).Scan(&user.ID, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt)
+	))
+
+	if err == pgx.ErrNoRows {
+		return nil, errors.New("user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetUserByID retrieves a user by ID
+func GetUserByID(ctx context.Context, userID uuid.UUID) (*User, error) {
+	user, err := scanUser(db.GetDB().QueryRow(ctx, getUserByIDSQL,
+		userID,
+	))
 
 	if err == pgx.ErrNoRows {
 		return nil, errors.New("user not found")
@@ -60,6 +162,33 @@ func GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	return user, nil
 }
 
+// UpdateProfile replaces the user's profile and returns the updated user
+func UpdateProfile(ctx context.Context, userID uuid.UUID, profile Profile) (*User, error) {
+	user, err := scanUser(db.GetDB().QueryRow(ctx, updateProfileSQL,
+		userID, profile.DisplayName, profile.AvatarURL, profile.Timezone, profile.Locale, profile.WeekStart,
+	))
+	if err == pgx.ErrNoRows {
+		return nil, errors.New("user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetAvatarURL replaces the user's avatar, as uploaded
+func SetAvatarURL(ctx context.Context, userID uuid.UUID, avatarURL string) error {
+	_, err := db.GetDB().Exec(ctx, setAvatarURLSQL, userID, avatarURL)
+	return err
+}
+
+// RevokeTokens signs the user out everywhere: every token issued to the
+// user so far is rejected, whatever its device
+func RevokeTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := db.GetDB().Exec(ctx, revokeTokensSQL, userID)
+	return err
+}
+
 // ValidatePassword checks if the provided password matches the stored hash
 func (u *User) ValidatePassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
@@ -67,14 +196,32 @@ func (u *User) ValidatePassword(password string) bool {
 }
 
 // UpdateLastSync updates the last sync time for a user's device
-func UpdateLastSync(ctx context.Context, userID uuid.UUID, deviceID, deviceType, deviceName string) error {
-	_, err := db.GetDB().Exec(ctx,
-		`INSERT INTO device_sync (user_id, device_id, device_type, device_name)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (user_id, device_id)
-		DO UPDATE SET last_sync_time = CURRENT_TIMESTAMP,
-		              device_type = EXCLUDED.device_type,
-		              device_name = EXCLUDED.device_name`,
-		userID, deviceID, deviceType, deviceName)
+func UpdateLastSync(ctx context.Context, userID uuid.UUID, deviceID, platform, deviceName string) error {
+	_, err := db.GetDB().Exec(ctx, updateLastSyncSQL,
+		userID, deviceID, platform, deviceName)
+	return err
+}
+
+// GetStorageMode returns the user's storage mode
+func GetStorageMode(ctx context.Context, userID uuid.UUID) (string, error) {
+	var mode string
+	err := db.GetDB().QueryRow(ctx, getStorageModeSQL,
+		userID,
+	).Scan(&mode)
+
+	if err == pgx.ErrNoRows {
+		return "", errors.New("user not found")
+	}
+	return mode, err
+}
+
+// SetStorageMode changes the user's storage mode
+func SetStorageMode(ctx context.Context, userID uuid.UUID, mode string) error {
+	if mode != StorageModeStandard && mode != StorageModeEncrypted {
+		return errors.New("invalid storage mode")
+	}
+
+	_, err := db.GetDB().Exec(ctx, setStorageModeSQL,
+		mode, userID)
 	return err
 }
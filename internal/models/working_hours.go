@@ -0,0 +1,143 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// WorkingHoursPreference is how long the user expects to work on each day
+// of the week, which reports compare the tracked time with
+type WorkingHoursPreference struct {
+	// Minutes holds the expected minutes for each weekday, from Sunday to
+	// Saturday
+	Minutes []int `json:"minutes"`
+}
+
+// defaultWorkingHours expects eight hours from Monday to Friday
+func defaultWorkingHours() WorkingHoursPreference {
+	return WorkingHoursPreference{Minutes: []int{0, 8 * 60, 8 * 60, 8 * 60, 8 * 60, 8 * 60, 0}}
+}
+
+func (p WorkingHoursPreference) validate(v *validate.Validator) {
+	if len(p.Minutes) != 7 {
+		v.Fail("working_hours.minutes", "must list 7 days from Sunday to Saturday")
+		return
+	}
+	for _, minutes := range p.Minutes {
+		if minutes < 0 || minutes > 24*60 {
+			v.Fail("working_hours.minutes", "must be between 0 and 1440")
+			return
+		}
+	}
+}
+
+// Expected returns the time expected on a day of the week
+func (p WorkingHoursPreference) Expected(day time.Weekday) time.Duration {
+	if int(day) >= len(p.Minutes) {
+		return 0
+	}
+	return time.Duration(p.Minutes[day]) * time.Minute
+}
+
+// ExpectedBetween returns the time expected on the days from start up to
+// end, midnights in the same location, other than the days off
+func (p WorkingHoursPreference) ExpectedBetween(start, end time.Time, off DaysOff) time.Duration {
+	var expected time.Duration
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if _, ok := off[day.Format("2006-01-02")]; !ok {
+			expected += p.Expected(day.Weekday())
+		}
+	}
+	return expected
+}
+
+// MemberCapacity compares the time a member logged against an
+// organization's projects with the time their working hours expect, less
+// their time off
+type MemberCapacity struct {
+	Member
+	TrackedSeconds  int64 `json:"tracked_seconds"`
+	ExpectedSeconds int64 `json:"expected_seconds"`
+	// DaysOff counts the days of the period the member takes off
+	DaysOff int `json:"days_off"`
+	// TimeOffSeconds is the time their working hours expect on those days
+	TimeOffSeconds int64 `json:"time_off_seconds"`
+	// Utilization is tracked time over expected time, unset when no time
+	// is expected
+	Utilization *float64 `json:"utilization,omitempty"`
+}
+
+// ListMemberCapacity returns the active members of an organization, by
+// email, with the time they logged against its projects in sessions starting
+// from start up to end, midnights in the same location, and the time their
+// working hours expect then outside their time off
+func ListMemberCapacity(ctx context.Context, orgID uuid.UUID, start, end time.Time, rounding Rounding) ([]MemberCapacity, error) {
+	rows, err := db.ReadPool(ctx).Query(ctx, `
+		SELECT u.id, u.email, m.role, m.created_at, m.deactivated_at,
+			COALESCE((
+				SELECT SUM(`+rounding.SecondsSQL(NetSecondsSQL("s"))+`)
+				FROM timer_sessions s
+				WHERE s.user_id = m.user_id AND s.is_deleted = false AND s.start_time >= $2 AND s.start_time < $3
+					AND s.project_id IN (SELECT id FROM projects WHERE organization_id = $1)
+			), 0)::BIGINT,
+			p.value
+		FROM memberships m
+		JOIN users u ON u.id = m.user_id
+		LEFT JOIN user_preferences p ON p.user_id = m.user_id AND p.key = 'working_hours' AND p.is_deleted = false
+		WHERE m.organization_id = $1 AND m.deactivated_at IS NULL
+		ORDER BY u.email`,
+		orgID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []MemberCapacity{}
+	hours := make(map[uuid.UUID]WorkingHoursPreference)
+	for rows.Next() {
+		var member MemberCapacity
+		var value []byte
+		err := rows.Scan(&member.UserID, &member.Email, &member.Role, &member.JoinedAt, &member.DeactivatedAt,
+			&member.TrackedSeconds, &value)
+		if err != nil {
+			return nil, err
+		}
+		hours[member.UserID] = DefaultPreferences().WorkingHours
+		if value != nil {
+			// Values of the wrong type read as the default, as in GetPreferences
+			if p := DefaultPreferences(); p.set("working_hours", value) == nil && validate.Struct(&p) == nil {
+				hours[member.UserID] = p.WorkingHours
+			}
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(members))
+	for _, member := range members {
+		userIDs = append(userIDs, member.UserID)
+	}
+	daysOff, err := ListDaysOff(ctx, userIDs, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for i := range members {
+		member := &members[i]
+		off := daysOff[member.UserID]
+		expected := hours[member.UserID].ExpectedBetween(start, end, off)
+		member.DaysOff = len(off)
+		member.ExpectedSeconds = int64(expected / time.Second)
+		member.TimeOffSeconds = int64((hours[member.UserID].ExpectedBetween(start, end, nil) - expected) / time.Second)
+		if member.ExpectedSeconds > 0 {
+			utilization := float64(member.TrackedSeconds) / float64(member.ExpectedSeconds)
+			member.Utilization = &utilization
+		}
+	}
+	return members, nil
+}
@@ -0,0 +1,149 @@
+// Package negotiate picks the encodings of request and response bodies.
+// JSON is always served; routes wrapped in Middleware also read and write
+// the encodings they offer, such as MessagePack for the high-volume routes
+// the mobile apps call.
+package negotiate
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+)
+
+// Encodings the API reads and writes
+const (
+	JSON    = "application/json"
+	Msgpack = "application/msgpack"
+)
+
+// aliases are other names clients use for the encodings
+var aliases = map[string]string{
+	"application/x-msgpack":   Msgpack,
+	"application/vnd.msgpack": Msgpack,
+}
+
+type contextKey string
+
+const (
+	requestKey  contextKey = "request_encoding"
+	responseKey contextKey = "response_encoding"
+)
+
+// Middleware negotiates the encodings of the routes it wraps, which read
+// and write the offered encodings besides JSON. Request bodies in another
+// Content-Type are rejected with 415. Responses use the offered encoding the
+// Accept header prefers, JSON when it prefers none of them.
+func Middleware(offers ...string) func(http.Handler) http.Handler {
+	available := append([]string{JSON}, offers...)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request := JSON
+			if ct := r.Header.Get("Content-Type"); ct != "" {
+				mediaType, _, err := mime.ParseMediaType(ct)
+				if err == nil {
+					request = canonical(mediaType)
+				}
+				if err != nil || !contains(available, request) {
+					apierror.Write(w, r, http.StatusUnsupportedMediaType, apierror.UnsupportedMediaType,
+						"Unsupported Content-Type", map[string]interface{}{"supported": available})
+					return
+				}
+			}
+
+			w.Header().Add("Vary", "Accept")
+			ctx := context.WithValue(r.Context(), requestKey, request)
+			ctx = context.WithValue(ctx, responseKey, preferred(r.Header.Get("Accept"), available))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Request returns the encoding of the request body, JSON outside
+// Middleware
+func Request(r *http.Request) string {
+	if encoding, ok := r.Context().Value(requestKey).(string); ok {
+		return encoding
+	}
+	return JSON
+}
+
+// Response returns the encoding to answer with, JSON outside Middleware
+func Response(r *http.Request) string {
+	if encoding, ok := r.Context().Value(responseKey).(string); ok {
+		return encoding
+	}
+	return JSON
+}
+
+// preferred picks the encoding accept ranks highest. Ties go to the most
+// specific range, then to the range listed first, then to JSON.
+func preferred(accept string, available []string) string {
+	best := JSON
+	bestQ, bestSpecificity, bestPosition := 0.0, -1, 0
+	for position, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		for _, encoding := range available {
+			specificity := matches(mediaType, encoding)
+			if specificity < 0 {
+				continue
+			}
+			better := q > bestQ ||
+				q == bestQ && specificity > bestSpecificity ||
+				q == bestQ && specificity == bestSpecificity && position < bestPosition
+			if better {
+				best, bestQ, bestSpecificity, bestPosition = encoding, q, specificity, position
+			}
+			// Wildcards match JSON first, keeping it the default
+			break
+		}
+	}
+	return best
+}
+
+// matches reports how specifically mediaRange matches encoding: 2 for the
+// type itself, 1 for type/*, 0 for */*, and -1 if it does not match
+func matches(mediaRange, encoding string) int {
+	switch {
+	case canonical(mediaRange) == encoding:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(encoding, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	}
+	return -1
+}
+
+func canonical(mediaType string) string {
+	if alias, ok := aliases[mediaType]; ok {
+		return alias
+	}
+	return mediaType
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
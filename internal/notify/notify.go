@@ -0,0 +1,194 @@
+// Package notify sends users notifications, such as digests and alerts, on
+// the channels they opted in to. Features raise a notification with Send
+// or SendTx; it goes through the outbox, so it is sent exactly when the
+// change raising it commits, and Deliver, the outbox handler, sends it on
+// each channel the user enabled for its type.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/outbox"
+)
+
+// event is the outbox event notifications travel as. No webhook can
+// subscribe to it; the webhook channel sends them as
+// webhooks.EventNotificationSent.
+const event = "notification"
+
+// Types of notifications
+const (
+	TypeDigest        = "digest"
+	TypeGoalAlert     = "goal_alert"
+	TypeBudgetAlert   = "budget_alert"
+	TypeTeamReminder  = "team_reminder"
+	TypeDailyReminder = "daily_reminder"
+	TypeLongTimer     = "long_timer"
+	TypeRunawayTimer  = "runaway_timer"
+)
+
+// Channels notifications are sent on
+const (
+	ChannelEmail   = "email"
+	ChannelPush    = "push"
+	ChannelWebhook = "webhook"
+)
+
+// Channels lists every channel in the order they are documented
+var Channels = []string{ChannelEmail, ChannelPush, ChannelWebhook}
+
+// Type describes a type of notification and the channels it is sent on for
+// users who have not chosen
+type Type struct {
+	Name        string
+	Description string
+	Defaults    map[string]bool
+}
+
+// Types lists every type in the order they are documented
+var Types = []Type{
+	{TypeDigest, "Summary of the time tracked over the last day or week",
+		map[string]bool{ChannelEmail: true}},
+	{TypeGoalAlert, "A tracking goal was reached or is at risk",
+		map[string]bool{ChannelEmail: true, ChannelPush: true}},
+	{TypeBudgetAlert, "A project is close to or over its budget",
+		map[string]bool{ChannelEmail: true, ChannelPush: true}},
+	{TypeTeamReminder, "A reminder from an organization to track or submit time",
+		map[string]bool{ChannelPush: true}},
+	{TypeDailyReminder, "No time was tracked yet today by your reminder time",
+		map[string]bool{ChannelEmail: true, ChannelPush: true}},
+	{TypeLongTimer, "A timer has been running longer than your reminder threshold",
+		map[string]bool{ChannelEmail: true, ChannelPush: true}},
+	{TypeRunawayTimer, "A timer ran past the maximum duration and was stopped for you to review",
+		map[string]bool{ChannelEmail: true, ChannelPush: true}},
+}
+
+// lookupType returns the type named name
+func lookupType(name string) (Type, bool) {
+	for _, t := range Types {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Type{}, false
+}
+
+// ValidChannel reports whether channel is one notifications are sent on
+func ValidChannel(channel string) bool {
+	for _, c := range Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// Notification is a message to one user
+type Notification struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	Type   string    `json:"type"`
+	// Title is a short summary, used as the email subject and push title
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	// Data holds type-specific fields for clients and webhook consumers,
+	// such as the project of a budget alert
+	Data      map[string]interface{} `json:"data,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Sender sends notifications on one channel to a user
+type Sender interface {
+	Send(ctx context.Context, user *models.User, n Notification) error
+}
+
+// SenderFunc adapts a function to a Sender
+type SenderFunc func(ctx context.Context, user *models.User, n Notification) error
+
+func (f SenderFunc) Send(ctx context.Context, user *models.User, n Notification) error {
+	return f(ctx, user, n)
+}
+
+var senders = map[string]Sender{}
+
+// RegisterSender sets the sender of a channel. Senders are registered at
+// startup, before notifications are delivered; channels without one, such
+// as push until a provider is configured, are skipped.
+func RegisterSender(channel string, s Sender) {
+	senders[channel] = s
+}
+
+// Available reports whether notifications can be sent on channel
+func Available(channel string) bool {
+	return senders[channel] != nil
+}
+
+// Send raises a notification in its own transaction
+func Send(ctx context.Context, n Notification) error {
+	return pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		return SendTx(ctx, tx, n)
+	})
+}
+
+// SendTx raises a notification in tx. It is sent once tx commits, and
+// never if tx rolls back.
+func SendTx(ctx context.Context, tx pgx.Tx, n Notification) error {
+	if _, ok := lookupType(n.Type); !ok {
+		return fmt.Errorf("unknown notification type %q", n.Type)
+	}
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now().UTC()
+	}
+	return outbox.Add(ctx, tx, n.UserID, event, n)
+}
+
+// Deliver sends a notification on every channel its user enabled for its
+// type. It is the outbox handler of notifications and ignores other
+// events. Failures of a channel are logged and dropped, so one bad channel
+// does not resend the others; only failing to look up the user or their
+// settings returns an error, so that the event is dispatched again.
+// Deactivated users get nothing.
+func Deliver(ctx context.Context, e outbox.Event) error {
+	if e.Type != event {
+		return nil
+	}
+	var n Notification
+	if err := json.Unmarshal(e.Payload, &n); err != nil {
+		slog.Error("Dropping malformed notification", "event_id", e.ID, "error", err)
+		return nil
+	}
+
+	user, err := models.GetUserByID(ctx, n.UserID)
+	if err != nil {
+		return err
+	}
+	if user.DeactivatedAt != nil {
+		return nil
+	}
+	channels, err := enabledChannels(ctx, n.UserID, n.Type)
+	if err != nil {
+		return err
+	}
+	for _, channel := range channels {
+		sender := senders[channel]
+		if sender == nil {
+			continue
+		}
+		if err := sender.Send(ctx, user, n); err != nil {
+			slog.Warn("Notification delivery failed", "notification_id", n.ID, "type", n.Type,
+				"channel", channel, "user_id", n.UserID, "error", err)
+		}
+	}
+	return nil
+}
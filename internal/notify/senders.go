@@ -0,0 +1,34 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pacerclub/zebra-backend/internal/mail"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/webhooks"
+)
+
+// EmailSender queues notifications to the account's address, with the
+// title as the subject; the mail queue retries failed sends
+func EmailSender() Sender {
+	return SenderFunc(func(ctx context.Context, user *models.User, n Notification) error {
+		msg, err := mail.Render(mail.TemplateNotification, user.Locale, user.Email, n)
+		if err != nil {
+			return err
+		}
+		return mail.Enqueue(ctx, msg)
+	})
+}
+
+// WebhookSender posts notifications to the user's webhooks subscribed to
+// webhooks.EventNotificationSent
+func WebhookSender() Sender {
+	return SenderFunc(func(ctx context.Context, user *models.User, n Notification) error {
+		body, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		return webhooks.Send(ctx, user.ID, webhooks.EventNotificationSent, body)
+	})
+}
@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// Setting is whether one type of notification is sent on each channel
+type Setting struct {
+	Type        string          `json:"type"`
+	Description string          `json:"description"`
+	Channels    map[string]bool `json:"channels"`
+}
+
+// Settings returns the user's choices for every type, with the type's
+// defaults for channels they never chose
+func Settings(ctx context.Context, userID uuid.UUID) ([]Setting, error) {
+	chosen, err := choices(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	settings := make([]Setting, len(Types))
+	for i, t := range Types {
+		channels := make(map[string]bool, len(Channels))
+		for _, channel := range Channels {
+			channels[channel] = t.enabled(chosen, channel)
+		}
+		settings[i] = Setting{Type: t.Name, Description: t.Description, Channels: channels}
+	}
+	return settings, nil
+}
+
+// UpdateSettings stores the user's choices, keyed by type and channel.
+// Types and channels missing from it keep their setting. Callers check
+// that every type and channel is known.
+func UpdateSettings(ctx context.Context, userID uuid.UUID, update map[string]map[string]bool) error {
+	return pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		for notificationType, channels := range update {
+			for channel, enabled := range channels {
+				_, err := tx.Exec(ctx, `
+					INSERT INTO notification_settings (user_id, type, channel, enabled)
+					VALUES ($1, $2, $3, $4)
+					ON CONFLICT (user_id, type, channel) DO UPDATE
+					SET enabled = EXCLUDED.enabled, updated_at = CURRENT_TIMESTAMP
+				`, userID, notificationType, channel, enabled)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// ValidType reports whether name is a type of notification
+func ValidType(name string) bool {
+	_, ok := lookupType(name)
+	return ok
+}
+
+// enabledChannels returns the channels the user gets notifications of the
+// type on
+func enabledChannels(ctx context.Context, userID uuid.UUID, notificationType string) ([]string, error) {
+	t, _ := lookupType(notificationType)
+	chosen, err := choices(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var channels []string
+	for _, channel := range Channels {
+		if t.enabled(chosen, channel) {
+			channels = append(channels, channel)
+		}
+	}
+	return channels, nil
+}
+
+// enabled reports whether the type is sent on channel, given the user's
+// stored choices
+func (t Type) enabled(chosen map[string]map[string]bool, channel string) bool {
+	if enabled, ok := chosen[t.Name][channel]; ok {
+		return enabled
+	}
+	return t.Defaults[channel]
+}
+
+// choices returns the settings the user stored, by type and channel
+func choices(ctx context.Context, userID uuid.UUID) (map[string]map[string]bool, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT type, channel, enabled FROM notification_settings WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chosen := map[string]map[string]bool{}
+	for rows.Next() {
+		var notificationType, channel string
+		var enabled bool
+		if err := rows.Scan(&notificationType, &channel, &enabled); err != nil {
+			return nil, err
+		}
+		if chosen[notificationType] == nil {
+			chosen[notificationType] = map[string]bool{}
+		}
+		chosen[notificationType][channel] = enabled
+	}
+	return chosen, rows.Err()
+}
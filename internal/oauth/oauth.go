@@ -0,0 +1,366 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// Zebra acts as an OAuth 2.0 authorization server so third-party apps can
+// use the API with limited scopes. Apps use the authorization code flow,
+// optionally with PKCE, and receive short-lived access tokens and rotating
+// refresh tokens.
+
+var (
+	ErrClientNotFound = errors.New("oauth client not found")
+	ErrInvalidClient  = errors.New("invalid client credentials")
+	ErrInvalidGrant   = errors.New("invalid, expired or revoked grant")
+)
+
+// codeTTL is how long an authorization code can be exchanged
+const codeTTL = 10 * time.Minute
+
+// MaxClientsPerUser caps how many apps a user can register
+const MaxClientsPerUser = 25
+
+// Client is a registered third-party app
+type Client struct {
+	ID           uuid.UUID `json:"client_id"`
+	UserID       uuid.UUID `json:"user_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	// Secret is only returned when the client is registered
+	Secret    string    `json:"client_secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AllowsRedirect reports whether uri is one of the client's redirect URIs
+func (c *Client) AllowsRedirect(uri string) bool {
+	for _, allowed := range c.RedirectURIs {
+		if allowed == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenResponse is the token endpoint's response
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// Authorization is an app the user has granted access to
+type Authorization struct {
+	ClientID   uuid.UUID `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CreateClient registers an app owned by the user
+func CreateClient(ctx context.Context, userID uuid.UUID, name string, redirectURIs []string) (*Client, error) {
+	secret, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	c := Client{ID: uuid.New(), UserID: userID, Name: name, RedirectURIs: redirectURIs, Secret: secret}
+	err = db.Pool.QueryRow(ctx, `
+		INSERT INTO oauth_clients (id, user_id, name, redirect_uris, secret_hash)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`, c.ID, userID, name, redirectURIs, hashToken(secret)).Scan(&c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CountClients returns how many apps the user has registered
+func CountClients(ctx context.Context, userID uuid.UUID) (int, error) {
+	var n int
+	err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM oauth_clients WHERE user_id = $1`, userID).Scan(&n)
+	return n, err
+}
+
+// ListClients returns the apps the user registered
+func ListClients(ctx context.Context, userID uuid.UUID) ([]Client, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, user_id, name, redirect_uris, created_at FROM oauth_clients
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := []Client{}
+	for rows.Next() {
+		var c Client
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.RedirectURIs, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		clients = append(clients, c)
+	}
+	return clients, rows.Err()
+}
+
+// DeleteClient removes one of the user's apps, revoking every grant to it
+func DeleteClient(ctx context.Context, userID, clientID uuid.UUID) error {
+	result, err := db.Pool.Exec(ctx, `DELETE FROM oauth_clients WHERE id = $1 AND user_id = $2`, clientID, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrClientNotFound
+	}
+	return nil
+}
+
+// GetClient returns a registered app by its client ID
+func GetClient(ctx context.Context, clientID uuid.UUID) (*Client, error) {
+	c, _, err := getClient(ctx, clientID)
+	return c, err
+}
+
+func getClient(ctx context.Context, clientID uuid.UUID) (*Client, string, error) {
+	var c Client
+	var secretHash string
+	err := db.Pool.QueryRow(ctx, `
+		SELECT id, user_id, name, redirect_uris, secret_hash, created_at FROM oauth_clients WHERE id = $1
+	`, clientID).Scan(&c.ID, &c.UserID, &c.Name, &c.RedirectURIs, &secretHash, &c.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, "", ErrClientNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return &c, secretHash, nil
+}
+
+// AuthenticateClient returns the app whose credentials these are
+func AuthenticateClient(ctx context.Context, clientID, secret string) (*Client, error) {
+	id, err := uuid.Parse(clientID)
+	if err != nil {
+		return nil, ErrInvalidClient
+	}
+	c, secretHash, err := getClient(ctx, id)
+	if errors.Is(err, ErrClientNotFound) {
+		return nil, ErrInvalidClient
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(secretHash)) != 1 {
+		return nil, ErrInvalidClient
+	}
+	return c, nil
+}
+
+// CreateCode records the user's consent and returns the authorization code
+// to send back to the app. codeChallenge is an S256 PKCE challenge, or "".
+func CreateCode(ctx context.Context, client *Client, userID uuid.UUID, redirectURI string, scopes []string, codeChallenge string) (string, error) {
+	code, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = db.Pool.Exec(ctx, `
+		INSERT INTO oauth_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, hashToken(code), client.ID, userID, redirectURI, scopes, codeChallenge, time.Now().Add(codeTTL))
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// ExchangeCode trades an authorization code for tokens. Codes work once.
+func ExchangeCode(ctx context.Context, client *Client, code, redirectURI, codeVerifier string) (*TokenResponse, error) {
+	var userID uuid.UUID
+	var storedRedirect, challenge string
+	var scopes []string
+	var expiresAt time.Time
+	err := db.Pool.QueryRow(ctx, `
+		DELETE FROM oauth_codes WHERE code_hash = $1 AND client_id = $2
+		RETURNING user_id, redirect_uri, scopes, code_challenge, expires_at
+	`, hashToken(code), client.ID).Scan(&userID, &storedRedirect, &scopes, &challenge, &expiresAt)
+	if err == pgx.ErrNoRows {
+		return nil, ErrInvalidGrant
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(expiresAt) || storedRedirect != redirectURI {
+		return nil, ErrInvalidGrant
+	}
+	if challenge != "" && pkceChallenge(codeVerifier) != challenge {
+		return nil, ErrInvalidGrant
+	}
+
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	var grantID uuid.UUID
+	err = db.Pool.QueryRow(ctx, `
+		INSERT INTO oauth_grants (id, user_id, client_id, scopes, refresh_token_hash)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, uuid.New(), userID, client.ID, scopes, hashToken(refreshToken)).Scan(&grantID)
+	if err != nil {
+		return nil, err
+	}
+	return issue(userID, grantID, scopes, refreshToken)
+}
+
+// Refresh issues new tokens for a grant. The refresh token is rotated, so
+// the old one stops working.
+func Refresh(ctx context.Context, client *Client, refreshToken string) (*TokenResponse, error) {
+	next, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var grantID, userID uuid.UUID
+	var scopes []string
+	err = db.Pool.QueryRow(ctx, `
+		UPDATE oauth_grants SET refresh_token_hash = $3, last_used_at = CURRENT_TIMESTAMP
+		WHERE refresh_token_hash = $1 AND client_id = $2 AND revoked_at IS NULL
+		RETURNING id, user_id, scopes
+	`, hashToken(refreshToken), client.ID, hashToken(next)).Scan(&grantID, &userID, &scopes)
+	if err == pgx.ErrNoRows {
+		return nil, ErrInvalidGrant
+	}
+	if err != nil {
+		return nil, err
+	}
+	return issue(userID, grantID, scopes, next)
+}
+
+// RevokeToken revokes the grant behind a refresh token. Unknown tokens are
+// ignored, as RFC 7009 asks.
+func RevokeToken(ctx context.Context, client *Client, refreshToken string) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE oauth_grants SET revoked_at = CURRENT_TIMESTAMP
+		WHERE refresh_token_hash = $1 AND client_id = $2 AND revoked_at IS NULL
+	`, hashToken(refreshToken), client.ID)
+	return err
+}
+
+// ListAuthorizations returns the apps the user has granted access to, with
+// the scopes of their grants combined
+func ListAuthorizations(ctx context.Context, userID uuid.UUID) ([]Authorization, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT c.id, c.name,
+			ARRAY(
+				SELECT DISTINCT s FROM oauth_grants g2, unnest(g2.scopes) AS s
+				WHERE g2.user_id = $1 AND g2.client_id = c.id AND g2.revoked_at IS NULL
+				ORDER BY s
+			),
+			MIN(g.created_at)
+		FROM oauth_grants g
+		JOIN oauth_clients c ON c.id = g.client_id
+		WHERE g.user_id = $1 AND g.revoked_at IS NULL
+		GROUP BY c.id, c.name
+		ORDER BY MIN(g.created_at)
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	authorizations := []Authorization{}
+	for rows.Next() {
+		var a Authorization
+		if err := rows.Scan(&a.ClientID, &a.ClientName, &a.Scopes, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		authorizations = append(authorizations, a)
+	}
+	return authorizations, rows.Err()
+}
+
+// RevokeAuthorization revokes every grant the user gave an app. Its access
+// tokens stop working right away.
+func RevokeAuthorization(ctx context.Context, userID, clientID uuid.UUID) error {
+	result, err := db.Pool.Exec(ctx, `
+		UPDATE oauth_grants SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND client_id = $2 AND revoked_at IS NULL
+	`, userID, clientID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrClientNotFound
+	}
+	return nil
+}
+
+// ParseScopes splits a space-separated scope parameter, rejecting unknown
+// scopes and duplicates
+func ParseScopes(scope string) ([]string, error) {
+	seen := make(map[string]bool)
+	scopes := []string{}
+	for _, s := range strings.Fields(scope) {
+		if !auth.ValidScope(s) {
+			return nil, errors.New("unknown scope " + s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("scope is required")
+	}
+	return scopes, nil
+}
+
+func issue(userID, grantID uuid.UUID, scopes []string, refreshToken string) (*TokenResponse, error) {
+	accessToken, err := auth.GenerateOAuthToken(userID, grantID, scopes)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(auth.OAuthTokenTTL / time.Second),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(scopes, " "),
+	}, nil
+}
+
+// pkceChallenge returns the S256 challenge of a PKCE code verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
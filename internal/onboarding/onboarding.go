@@ -0,0 +1,128 @@
+// Package onboarding tracks the first steps a new user takes, so clients
+// can show what is left to set up. Steps complete by themselves as the
+// server sees them happen: creating and tracking through the outbox events
+// of those changes, syncing and the mobile apps when a device syncs.
+package onboarding
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/outbox"
+	"github.com/pacerclub/zebra-backend/internal/webhooks"
+)
+
+// Steps of onboarding
+const (
+	CreatedFirstProject = "created_first_project"
+	TrackedFirstSession = "tracked_first_session"
+	InstalledMobileApp  = "installed_mobile_app"
+	EnabledSync         = "enabled_sync"
+)
+
+// Steps lists every step, in the order clients show them
+var Steps = []struct {
+	Name        string
+	Description string
+}{
+	{CreatedFirstProject, "Create a project to track time on"},
+	{TrackedFirstSession, "Track your first session"},
+	{InstalledMobileApp, "Install the iOS or Android app"},
+	{EnabledSync, "Sync a device with your account"},
+}
+
+// mobilePlatforms are the device platforms of the mobile apps
+var mobilePlatforms = []string{"ios", "ipados", "android"}
+
+// Step is one step and whether the user completed it
+type Step struct {
+	Step        string     `json:"step"`
+	Description string     `json:"description"`
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+// Progress is the user's onboarding; Completed is set once every step is
+type Progress struct {
+	Steps     []Step `json:"steps"`
+	Completed bool   `json:"completed"`
+}
+
+// Get returns the user's progress through every step
+func Get(ctx context.Context, userID uuid.UUID) (*Progress, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT step, completed_at FROM onboarding_steps WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	completed := map[string]time.Time{}
+	for rows.Next() {
+		var step string
+		var at time.Time
+		if err := rows.Scan(&step, &at); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		completed[step] = at
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	progress := &Progress{Steps: make([]Step, len(Steps)), Completed: true}
+	for i, s := range Steps {
+		step := Step{Step: s.Name, Description: s.Description}
+		if at, ok := completed[s.Name]; ok {
+			step.Completed, step.CompletedAt = true, &at
+		} else {
+			progress.Completed = false
+		}
+		progress.Steps[i] = step
+	}
+	return progress, nil
+}
+
+// Synced completes the steps a device sync shows, in the sync's tx: sync
+// itself, the mobile app for a mobile platform, and the first project and
+// session, which sync stores without outbox events
+func Synced(ctx context.Context, tx pgx.Tx, userID uuid.UUID, platform string) error {
+	mobile := false
+	for _, p := range mobilePlatforms {
+		mobile = mobile || strings.EqualFold(platform, p)
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO onboarding_steps (user_id, step)
+		SELECT $1, step FROM (VALUES
+			($3, TRUE),
+			($4, $2::boolean),
+			($5, EXISTS (SELECT 1 FROM projects WHERE user_id = $1)),
+			($6, EXISTS (SELECT 1 FROM timer_sessions WHERE user_id = $1))
+		) AS done (step, completed)
+		WHERE completed
+		ON CONFLICT (user_id, step) DO NOTHING
+	`, userID, mobile, EnabledSync, InstalledMobileApp, CreatedFirstProject, TrackedFirstSession)
+	return err
+}
+
+// Track is the outbox handler of onboarding, completing the steps of
+// created projects and sessions. Steps completed before keep their time.
+func Track(ctx context.Context, event outbox.Event) error {
+	var step string
+	switch event.Type {
+	case webhooks.EventProjectCreated:
+		step = CreatedFirstProject
+	case webhooks.EventSessionCreated:
+		step = TrackedFirstSession
+	default:
+		return nil
+	}
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO onboarding_steps (user_id, step) VALUES ($1, $2)
+		ON CONFLICT (user_id, step) DO NOTHING
+	`, event.UserID, step)
+	return err
+}
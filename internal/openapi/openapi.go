@@ -0,0 +1,267 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/pagination"
+)
+
+// Operation documents one route. Operations are keyed by method and path
+// relative to the API root, such as "POST /auth/sessions".
+type Operation struct {
+	Summary string
+	Tag     string
+	// Request is a value of the JSON body type, nil for routes without one
+	Request interface{}
+	// Required lists the body fields that must be present
+	Required []string
+	// Response is a value of the JSON response type, nil when the response
+	// is not JSON
+	Response interface{}
+	// Public routes take no bearer token
+	Public bool
+	// Encodings lists the media types the route reads and writes besides
+	// JSON, with the same schemas
+	Encodings []string
+	// List is set on list routes, which return a pagination.Page and take
+	// the ?sort=, ?fields=, ?limit= and ?cursor= parameters
+	List List
+}
+
+// List is how a list route can be sorted; see listquery.List
+type List interface {
+	SortFields() []string
+	DefaultSort() string
+}
+
+// Spec generates an OpenAPI 3 document from a router and the operations
+// documented for its routes, and validates request bodies against it
+type Spec struct {
+	title      string
+	version    string
+	operations map[string]Operation
+	gen        *generator
+	bodies     map[string]*Schema
+	responses  map[string]*Schema
+	errors     *Schema
+
+	once sync.Once
+	doc  []byte
+	err  error
+}
+
+// New builds the schemas of the documented operations
+func New(title, version string, operations map[string]Operation) *Spec {
+	s := &Spec{
+		title:      title,
+		version:    version,
+		operations: operations,
+		gen:        newGenerator(),
+		bodies:     map[string]*Schema{},
+		responses:  map[string]*Schema{},
+	}
+
+	// Every error response is an apierror.Response; its code is one of the
+	// catalog's
+	errorType := reflect.TypeOf(apierror.Response{})
+	s.errors = s.gen.schema(errorType)
+	codes := make([]string, len(apierror.Codes))
+	for i, code := range apierror.Codes {
+		codes[i] = string(code)
+	}
+	s.gen.components[s.gen.names[errorType]].Properties["code"].Enum = codes
+
+	keys := make([]string, 0, len(operations))
+	for key := range operations {
+		keys = append(keys, key)
+	}
+	// Sorted so component names are stable when two types share a name
+	sort.Strings(keys)
+	for _, key := range keys {
+		op := operations[key]
+		if op.Request != nil {
+			body := s.gen.schema(reflect.TypeOf(op.Request))
+			if len(op.Required) > 0 {
+				body = &Schema{AllOf: []*Schema{body}, Required: op.Required}
+			}
+			s.bodies[key] = body
+		}
+		if op.Response != nil {
+			s.responses[key] = s.gen.schema(reflect.TypeOf(op.Response))
+		}
+	}
+	return s
+}
+
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// Document renders the spec of every route of routes under prefix, which
+// becomes the server URL. Routes without a documented operation are still
+// listed.
+func (s *Spec) Document(routes chi.Routes, prefix string) ([]byte, error) {
+	paths := map[string]map[string]interface{}{}
+	err := chi.Walk(routes, func(method, route string, handler http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if !strings.HasPrefix(route, prefix+"/") || method == http.MethodOptions {
+			return nil
+		}
+		path := normalize(strings.TrimPrefix(route, prefix))
+		key := method + " " + path
+		op := s.operations[key]
+
+		operation := map[string]interface{}{"responses": s.responsesOf(key, op.Encodings)}
+		if name := handlerName(handler); name != "" {
+			operation["operationId"] = name
+		}
+		if op.Summary != "" {
+			operation["summary"] = op.Summary
+		}
+		if op.Tag != "" {
+			operation["tags"] = []string{op.Tag}
+		}
+		if op.Public {
+			operation["security"] = []interface{}{}
+		}
+		var params []map[string]interface{}
+		for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+			params = append(params, map[string]interface{}{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   &Schema{Type: "string"},
+			})
+		}
+		if op.List != nil {
+			var sorts []string
+			for _, field := range op.List.SortFields() {
+				sorts = append(sorts, field, "-"+field)
+			}
+			params = append(params,
+				map[string]interface{}{
+					"name":        "sort",
+					"in":          "query",
+					"description": fmt.Sprintf("Field to sort by, descending with a leading -; %s by default", op.List.DefaultSort()),
+					"schema":      &Schema{Type: "string", Enum: sorts},
+				},
+				map[string]interface{}{
+					"name":        "fields",
+					"in":          "query",
+					"description": "Comma-separated fields to return of each item, all by default",
+					"schema":      &Schema{Type: "string"},
+				},
+				map[string]interface{}{
+					"name":        "limit",
+					"in":          "query",
+					"description": fmt.Sprintf("Page size, from 1 to %d; %d by default", pagination.MaxLimit, pagination.DefaultLimit),
+					"schema":      &Schema{Type: "integer"},
+				},
+				map[string]interface{}{
+					"name":        "cursor",
+					"in":          "query",
+					"description": "The next_cursor of the previous page",
+					"schema":      &Schema{Type: "string"},
+				})
+		}
+		if params != nil {
+			operation["parameters"] = params
+		}
+		if body, ok := s.bodies[key]; ok {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  content(body, op.Encodings),
+			}
+		}
+
+		docPath := pathParam.ReplaceAllString(path, "{$1}")
+		if paths[docPath] == nil {
+			paths[docPath] = map[string]interface{}{}
+		}
+		paths[docPath][strings.ToLower(method)] = operation
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]string{"title": s.title, "version": s.version},
+		"servers": []map[string]string{{"url": prefix}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": s.gen.components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]string{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+		"security": []map[string][]string{{"bearerAuth": {}}},
+	}, "", "  ")
+}
+
+func (s *Spec) responsesOf(key string, encodings []string) map[string]interface{} {
+	ok := map[string]interface{}{"description": "OK"}
+	if schema, found := s.responses[key]; found {
+		ok["content"] = content(schema, encodings)
+	}
+	return map[string]interface{}{
+		"200": ok,
+		"default": map[string]interface{}{
+			"description": "Error",
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": s.errors}},
+		},
+	}
+}
+
+// Handler serves the document of routes under prefix, rendered on first
+// use once every route is registered
+func (s *Spec) Handler(routes chi.Routes, prefix string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.once.Do(func() {
+			s.doc, s.err = s.Document(routes, prefix)
+		})
+		if s.err != nil {
+			apierror.Error(w, r, "Failed to render the API specification", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s.doc)
+	}
+}
+
+// content maps JSON and the other encodings to schema
+func content(schema *Schema, encodings []string) map[string]interface{} {
+	media := map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+	for _, encoding := range encodings {
+		media[encoding] = map[string]interface{}{"schema": schema}
+	}
+	return media
+}
+
+// normalize drops the trailing slash chi leaves on routes registered as
+// "/" inside a subrouter
+func normalize(path string) string {
+	path = strings.TrimSuffix(path, "/*")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+// handlerName names an operation after its handler function
+func handlerName(handler http.Handler) string {
+	v := reflect.ValueOf(handler)
+	if v.Kind() != reflect.Func {
+		return ""
+	}
+	name := runtime.FuncForPC(v.Pointer()).Name()
+	return name[strings.LastIndex(name, ".")+1:]
+}
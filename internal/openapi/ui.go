@@ -0,0 +1,37 @@
+package openapi
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// swaggerUIVersion pins the Swagger UI release loaded from the CDN
+const swaggerUIVersion = "5.17.14"
+
+var swaggerUI = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@{{.Version}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@{{.Version}}/swagger-ui-bundle.js" crossorigin></script>
+<script>
+window.onload = function () {
+  window.ui = SwaggerUIBundle({ url: {{.SpecURL}}, dom_id: "#swagger-ui" });
+};
+</script>
+</body>
+</html>
+`))
+
+// SwaggerUI serves a Swagger UI page rendering the spec at specURL
+func SwaggerUI(title, specURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		swaggerUI.Execute(w, struct{ Title, Version, SpecURL string }{title, swaggerUIVersion, specURL})
+	}
+}
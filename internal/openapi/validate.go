@@ -0,0 +1,201 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+)
+
+// maxValidatedBody bounds the bodies read for validation; larger ones are
+// left to the handler
+const maxValidatedBody = 10 << 20
+
+// Validate rejects JSON request bodies that do not match the documented
+// request schema of the route they reach in routes. Other content types,
+// such as MessagePack, and undocumented routes pass through unchecked.
+// Unknown fields are allowed so older servers accept newer clients.
+func (s *Spec) Validate(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.ContentLength > maxValidatedBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if ct := r.Header.Get("Content-Type"); ct != "" {
+				if mediaType, _, _ := mime.ParseMediaType(ct); mediaType != "application/json" {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			path := r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+				path = rctx.RoutePath
+			}
+			match := chi.NewRouteContext()
+			if !routes.Match(match, r.Method, path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			body, ok := s.bodies[r.Method+" "+normalize(match.RoutePattern())]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			data, err := io.ReadAll(io.LimitReader(r.Body, maxValidatedBody+1))
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				apierror.Error(w, r, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				apierror.Error(w, r, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = readCloser{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+			if len(data) > maxValidatedBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// Empty bodies are reported by the handlers themselves
+			if len(bytes.TrimSpace(data)) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var value interface{}
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.UseNumber()
+			if err := decoder.Decode(&value); err != nil {
+				apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidBody, "Invalid JSON: "+err.Error(), nil)
+				return
+			}
+			if err := s.check(body, value, ""); err != nil {
+				apierror.Write(w, r, http.StatusBadRequest, apierror.InvalidBody, "Invalid request body: "+err.Error(), nil)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// readCloser replays the part of a body read for validation before the
+// rest
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// check reports the first place value does not match schema
+func (s *Spec) check(schema *Schema, value interface{}, at string) error {
+	if schema.Ref != "" {
+		return s.check(s.gen.components[strings.TrimPrefix(schema.Ref, "#/components/schemas/")], value, at)
+	}
+	// encoding/json leaves the zero value for null, whatever the type
+	if value == nil {
+		return nil
+	}
+	for _, sub := range schema.AllOf {
+		if err := s.check(sub, value, at); err != nil {
+			return err
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", field(at))
+		}
+		for name, property := range schema.Properties {
+			if v, present := object[name]; present {
+				if err := s.check(property, v, join(at, name)); err != nil {
+					return err
+				}
+			}
+		}
+		if schema.AdditionalProperties != nil {
+			for name, v := range object {
+				if err := s.check(schema.AdditionalProperties, v, join(at, name)); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an array", field(at))
+		}
+		for i, item := range items {
+			if err := s.check(schema.Items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", field(at))
+		}
+		switch schema.Format {
+		case "uuid":
+			if _, err := uuid.Parse(str); err != nil {
+				return fmt.Errorf("%s must be a UUID", field(at))
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return fmt.Errorf("%s must be an RFC 3339 date-time", field(at))
+			}
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s must be an integer", field(at))
+		}
+		if _, err := n.Int64(); err != nil {
+			return fmt.Errorf("%s must be an integer", field(at))
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return fmt.Errorf("%s must be a number", field(at))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", field(at))
+		}
+	}
+
+	if len(schema.Required) > 0 {
+		object, _ := value.(map[string]interface{})
+		for _, name := range schema.Required {
+			if _, present := object[name]; !present {
+				return fmt.Errorf("%s is required", field(join(at, name)))
+			}
+		}
+	}
+	return nil
+}
+
+func join(at, name string) string {
+	if at == "" {
+		return name
+	}
+	return at + "." + name
+}
+
+func field(at string) string {
+	if at == "" {
+		return "body"
+	}
+	return at
+}
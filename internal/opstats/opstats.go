@@ -0,0 +1,273 @@
+// Package opstats records and reports operational statistics for the admin
+// API: users and their daily activity, sessions created, sync volume,
+// webhook delivery outcomes and the health of the database. Counters are
+// kept per UTC day.
+package opstats
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// Daily counters
+const (
+	SyncRequests      = "sync_requests"
+	SyncItemsReceived = "sync_items_received"
+	SyncItemsSent     = "sync_items_sent"
+	WebhookDeliveries = "webhook_deliveries"
+	WebhookFailures   = "webhook_failures"
+)
+
+// MaxDays caps how many days of statistics one request may ask for
+const MaxDays = 90
+
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// Add adds n to today's value of metric. Statistics are best effort: a
+// failure is logged and does not fail the work being counted.
+func Add(ctx context.Context, metric string, n int64) {
+	if n == 0 {
+		return
+	}
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO daily_stats (day, metric, value)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (day, metric) DO UPDATE SET value = daily_stats.value + EXCLUDED.value
+	`, today(), metric, n)
+	if err != nil {
+		slog.Error("Failed to record statistic", "metric", metric, "error", err)
+	}
+}
+
+// active holds the users already recorded as active today, so each user
+// is written once a day per server
+var active struct {
+	sync.Mutex
+	day   time.Time
+	users map[uuid.UUID]bool
+}
+
+// RecordActive counts the user as active today
+func RecordActive(ctx context.Context, userID uuid.UUID) {
+	day := today()
+	active.Lock()
+	if !active.day.Equal(day) {
+		active.day = day
+		active.users = make(map[uuid.UUID]bool)
+	}
+	seen := active.users[userID]
+	active.users[userID] = true
+	active.Unlock()
+	if seen {
+		return
+	}
+
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO user_activity (day, user_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, day, userID)
+	if err != nil {
+		slog.Error("Failed to record user activity", "user_id", userID, "error", err)
+		active.Lock()
+		delete(active.users, userID)
+		active.Unlock()
+	}
+}
+
+// Day is the statistics of one UTC day
+type Day struct {
+	Date              string `json:"date"`
+	NewUsers          int64  `json:"new_users"`
+	ActiveUsers       int64  `json:"active_users"`
+	SessionsCreated   int64  `json:"sessions_created"`
+	SyncRequests      int64  `json:"sync_requests"`
+	SyncItemsReceived int64  `json:"sync_items_received"`
+	SyncItemsSent     int64  `json:"sync_items_sent"`
+	WebhookDeliveries int64  `json:"webhook_deliveries"`
+	WebhookFailures   int64  `json:"webhook_failures"`
+	// WebhookFailureRate is the share of deliveries that failed, from 0 to 1
+	WebhookFailureRate float64 `json:"webhook_failure_rate"`
+}
+
+// Stats summarizes the server's usage, with one entry per day oldest first
+type Stats struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	TotalUsers  int64     `json:"total_users"`
+	// ActiveUsers counts the users active on any of the days
+	ActiveUsers int64 `json:"active_users"`
+	Days        []Day `json:"days"`
+}
+
+// Collect returns the statistics of the last days days, today included
+func Collect(ctx context.Context, days int) (*Stats, error) {
+	end := today()
+	start := end.AddDate(0, 0, -(days - 1))
+	stats := &Stats{GeneratedAt: time.Now().UTC()}
+
+	err := db.ReadPool(ctx).QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM users),
+			(SELECT COUNT(DISTINCT user_id) FROM user_activity WHERE day BETWEEN $1 AND $2)
+	`, start, end).Scan(&stats.TotalUsers, &stats.ActiveUsers)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.ReadPool(ctx).Query(ctx, `
+		SELECT d::date,
+			(SELECT COUNT(*) FROM users
+				WHERE created_at >= d AT TIME ZONE 'UTC' AND created_at < (d + interval '1 day') AT TIME ZONE 'UTC'),
+			(SELECT COUNT(*) FROM user_activity WHERE day = d::date),
+			(SELECT COUNT(*) FROM timer_sessions
+				WHERE created_at >= d AT TIME ZONE 'UTC' AND created_at < (d + interval '1 day') AT TIME ZONE 'UTC')
+		FROM generate_series($1::timestamp, $2::timestamp, interval '1 day') d
+		ORDER BY d
+	`, start, end)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int)
+	for rows.Next() {
+		var date time.Time
+		var day Day
+		if err := rows.Scan(&date, &day.NewUsers, &day.ActiveUsers, &day.SessionsCreated); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		day.Date = date.Format("2006-01-02")
+		index[day.Date] = len(stats.Days)
+		stats.Days = append(stats.Days, day)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.ReadPool(ctx).Query(ctx, `
+		SELECT day, metric, value FROM daily_stats WHERE day BETWEEN $1 AND $2
+	`, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var date time.Time
+		var metric string
+		var value int64
+		if err := rows.Scan(&date, &metric, &value); err != nil {
+			return nil, err
+		}
+		i, ok := index[date.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		day := &stats.Days[i]
+		switch metric {
+		case SyncRequests:
+			day.SyncRequests = value
+		case SyncItemsReceived:
+			day.SyncItemsReceived = value
+		case SyncItemsSent:
+			day.SyncItemsSent = value
+		case WebhookDeliveries:
+			day.WebhookDeliveries = value
+		case WebhookFailures:
+			day.WebhookFailures = value
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range stats.Days {
+		if day := &stats.Days[i]; day.WebhookDeliveries > 0 {
+			day.WebhookFailureRate = float64(day.WebhookFailures) / float64(day.WebhookDeliveries)
+		}
+	}
+	return stats, nil
+}
+
+// Health describes the database as the server sees it
+type Health struct {
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+	// PingMillis is how long a round trip to the database took
+	PingMillis float64 `json:"ping_ms"`
+	SizeBytes  int64   `json:"size_bytes"`
+	// Connections are those of this server's pool
+	Connections Connections `json:"connections"`
+}
+
+// Connections counts the connections of the pool by state
+type Connections struct {
+	Total    int32 `json:"total"`
+	Acquired int32 `json:"acquired"`
+	Idle     int32 `json:"idle"`
+	Max      int32 `json:"max"`
+	// Constructing are connections being opened
+	Constructing int32 `json:"constructing"`
+	// The counts below are since the server started. WaitCount is how many
+	// acquisitions had to wait for a connection, CanceledCount how many
+	// gave up waiting, as when their request timed out.
+	AcquireCount  int64 `json:"acquire_count"`
+	WaitCount     int64 `json:"wait_count"`
+	CanceledCount int64 `json:"canceled_count"`
+	// AvgAcquireMillis is the average time taken to acquire a connection
+	AvgAcquireMillis float64 `json:"avg_acquire_ms"`
+	// Opened counts the connections opened, LifetimeClosed and IdleClosed
+	// those closed for reaching their maximum lifetime or idle time
+	Opened         int64 `json:"opened"`
+	LifetimeClosed int64 `json:"lifetime_closed"`
+	IdleClosed     int64 `json:"idle_closed"`
+}
+
+// PoolStats returns the connections of this server's pool
+func PoolStats() Connections {
+	stat := db.Pool.Stat()
+	conns := Connections{
+		Total:          stat.TotalConns(),
+		Acquired:       stat.AcquiredConns(),
+		Idle:           stat.IdleConns(),
+		Max:            stat.MaxConns(),
+		Constructing:   stat.ConstructingConns(),
+		AcquireCount:   stat.AcquireCount(),
+		WaitCount:      stat.EmptyAcquireCount(),
+		CanceledCount:  stat.CanceledAcquireCount(),
+		Opened:         stat.NewConnsCount(),
+		LifetimeClosed: stat.MaxLifetimeDestroyCount(),
+		IdleClosed:     stat.MaxIdleDestroyCount(),
+	}
+	if conns.AcquireCount > 0 {
+		conns.AvgAcquireMillis = float64(stat.AcquireDuration().Microseconds()) / float64(conns.AcquireCount) / 1000
+	}
+	return conns
+}
+
+// CheckHealth pings the database and reports its size and the pool's
+// connections. An unreachable database is reported, not returned as an
+// error.
+func CheckHealth(ctx context.Context) Health {
+	health := Health{Connections: PoolStats()}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := db.Pool.Ping(ctx); err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	health.PingMillis = float64(time.Since(start).Microseconds()) / 1000
+
+	if err := db.Pool.QueryRow(ctx, `SELECT pg_database_size(current_database())`).Scan(&health.SizeBytes); err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	health.Healthy = true
+	return health
+}
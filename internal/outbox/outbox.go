@@ -0,0 +1,188 @@
+// Package outbox implements a transactional outbox for domain events.
+// Changes write their events with Add in the same transaction as the data,
+// so an event exists exactly when its change was committed, and the
+// dispatcher run by Run hands committed events to the consumers registered
+// with Handle, such as webhook delivery, at least once.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// channel is notified when events are committed, waking the dispatchers
+const channel = "outbox_events"
+
+// batchSize bounds the events dispatched in one transaction
+const batchSize = 100
+
+// Event is a committed change
+type Event struct {
+	ID int64
+	// UserID is the user whose consumers receive the event
+	UserID    uuid.UUID
+	Type      string
+	Payload   json.RawMessage
+	CreatedAt time.Time
+}
+
+// Handler consumes dispatched events. An error leaves the event and the
+// rest of its batch in the outbox to be dispatched again, to every handler.
+type Handler func(ctx context.Context, event Event) error
+
+var handlers []Handler
+
+// Handle registers a consumer of the events. Handlers are registered at
+// startup, before Run.
+func Handle(h Handler) {
+	handlers = append(handlers, h)
+}
+
+// Add writes an event to the outbox in tx. It is dispatched once tx
+// commits, and never if tx rolls back.
+func Add(ctx context.Context, tx pgx.Tx, userID uuid.UUID, eventType string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO outbox_events (user_id, event, payload) VALUES ($1, $2, $3)
+	`, userID, eventType, string(body))
+	if err != nil {
+		return err
+	}
+	// Notifications are delivered on commit only
+	_, err = tx.Exec(ctx, `SELECT pg_notify($1, '')`, channel)
+	return err
+}
+
+// Run dispatches events until ctx is done. It wakes up when events are
+// committed and every pollInterval, which also retries failed batches.
+// Dispatchers on several servers share the work, each event going to one.
+func Run(ctx context.Context, pollInterval time.Duration) {
+	for ctx.Err() == nil {
+		if err := listen(ctx, pollInterval); err != nil && ctx.Err() == nil {
+			slog.Error("Outbox dispatcher failed", "error", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(pollInterval):
+			}
+		}
+	}
+}
+
+// listen dispatches on a connection listening for new events, until ctx is
+// done or the connection fails
+func listen(ctx context.Context, pollInterval time.Duration) error {
+	pooled, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// A connection left listening is not put back in the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+	for {
+		for {
+			n, err := dispatch(ctx)
+			if err != nil {
+				slog.Error("Failed to dispatch outbox events", "error", err)
+				break
+			}
+			if n < batchSize {
+				break
+			}
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, pollInterval)
+		_, err := conn.WaitForNotification(waitCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+	}
+}
+
+// dispatch hands the oldest batch of pending events to the handlers and
+// marks it dispatched, returning its size. The events stay locked while
+// they are handled, so other dispatchers skip them.
+func dispatch(ctx context.Context) (int, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, user_id, event, payload::text, created_at
+		FROM outbox_events
+		WHERE dispatched_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	var events []Event
+	var ids []int64
+	for rows.Next() {
+		var event Event
+		var payload string
+		if err := rows.Scan(&event.ID, &event.UserID, &event.Type, &payload, &event.CreatedAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		event.Payload = json.RawMessage(payload)
+		events = append(events, event)
+		ids = append(ids, event.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	for _, event := range events {
+		for _, h := range handlers {
+			if err := h(ctx, event); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE outbox_events SET dispatched_at = CURRENT_TIMESTAMP WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return 0, err
+	}
+	return len(events), tx.Commit(ctx)
+}
+
+// Prune deletes the events dispatched more than retention ago
+func Prune(ctx context.Context, retention time.Duration) error {
+	result, err := db.Pool.Exec(ctx, `
+		DELETE FROM outbox_events WHERE dispatched_at < $1
+	`, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	if n := result.RowsAffected(); n > 0 {
+		slog.Info("Pruned outbox events", "deleted", n)
+	}
+	return nil
+}
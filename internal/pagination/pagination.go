@@ -0,0 +1,127 @@
+// Package pagination pages the list endpoints. Every list is ordered by a
+// timestamp and the row id, and a page ends with an opaque cursor naming its
+// last row; the next page starts right after that row, so rows added or
+// removed between requests never shift the pages.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultLimit is the page size when the request does not set one
+	DefaultLimit = 50
+	// MaxLimit is the largest page size a request may ask for
+	MaxLimit = 200
+)
+
+var (
+	ErrInvalidLimit  = errors.New("limit must be between 1 and 200")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Page is the envelope of every list response. NextCursor is set when
+// HasMore is; pass it as ?cursor= to fetch the next page.
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// Cursor is the sort key and id of the last row of a page
+type Cursor struct {
+	Time time.Time `json:"t"`
+	ID   uuid.UUID `json:"i"`
+	// Sort is the order of the list the cursor pages through
+	Sort string `json:"s,omitempty"`
+}
+
+// Encode returns the cursor in its opaque form
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Params selects one page of a list
+type Params struct {
+	Limit int
+	// After is the cursor the page starts after, nil for the first page
+	After *Cursor
+}
+
+// New returns the params for a page size and cursor as sent by the client.
+// A zero limit and an empty cursor select the first page of default size.
+func New(limit int, cursor string) (Params, error) {
+	p := Params{Limit: limit}
+	if p.Limit == 0 {
+		p.Limit = DefaultLimit
+	}
+	if p.Limit < 1 || p.Limit > MaxLimit {
+		return Params{}, ErrInvalidLimit
+	}
+	if cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return Params{}, err
+		}
+		p.After = after
+	}
+	return p, nil
+}
+
+// Fetch is the number of rows to query: one more than the page holds, to
+// learn whether another page follows
+func (p Params) Fetch() int {
+	return p.Limit + 1
+}
+
+// AfterTime is the sort key of the cursor, nil on the first page. With
+// AfterID it fills the keyset condition of a query, as in
+//
+//	AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3))
+func (p Params) AfterTime() *time.Time {
+	if p.After == nil {
+		return nil
+	}
+	return &p.After.Time
+}
+
+// AfterID is the row id of the cursor, nil on the first page
+func (p Params) AfterID() *uuid.UUID {
+	if p.After == nil {
+		return nil
+	}
+	return &p.After.ID
+}
+
+// NewPage builds the page from rows queried with Fetch. cursor returns the
+// sort key and id of a row.
+func NewPage[T any](rows []T, p Params, cursor func(T) Cursor) Page[T] {
+	page := Page[T]{Data: rows}
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+	if len(page.Data) > p.Limit {
+		page.Data = page.Data[:p.Limit]
+		page.HasMore = true
+		page.NextCursor = cursor(page.Data[len(page.Data)-1]).Encode()
+	}
+	return page
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+)
+
+// UserKey limits per authenticated user
+func UserKey(r *http.Request) string {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		return ""
+	}
+	return userID.String()
+}
+
+// DeviceKey limits per device of an authenticated user. The device comes from
+// the token, falling back to the X-Device-ID header.
+func DeviceKey(r *http.Request) string {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == uuid.Nil {
+		return ""
+	}
+	deviceID := auth.GetDeviceIDFromContext(r.Context())
+	if deviceID == "" {
+		deviceID = r.Header.Get("X-Device-ID")
+	}
+	return userID.String() + "/" + deviceID
+}
+
+// BearerKey limits per bearer token, for clients such as kiosks that
+// authenticate with a token of their own rather than as a user. Tokens are
+// hashed so buckets do not hold them.
+func BearerKey(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IPKey limits per client IP address. Behind a proxy, run
+// middleware.RealIP first so the address is the client's.
+func IPKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
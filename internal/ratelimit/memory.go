@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// bucket is a token bucket that refills continuously
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// MemoryStore keeps buckets in memory, for a single server. It holds at most
+// a fixed number of buckets and evicts the least recently used one to make
+// room; an evicted bucket starts over full, as it would after going idle.
+type MemoryStore struct {
+	mu      sync.Mutex
+	maxKeys int
+	order   *list.List // of *bucket, most recently used first
+	buckets map[string]*list.Element
+}
+
+// NewMemoryStore creates a store holding up to maxKeys buckets
+func NewMemoryStore(maxKeys int) *MemoryStore {
+	if maxKeys < 1 {
+		maxKeys = 1
+	}
+	return &MemoryStore{
+		maxKeys: maxKeys,
+		order:   list.New(),
+		buckets: make(map[string]*list.Element),
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, rate, burst float64) (float64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var b *bucket
+	if e, ok := s.buckets[key]; ok {
+		s.order.MoveToFront(e)
+		b = e.Value.(*bucket)
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	} else {
+		if s.order.Len() >= s.maxKeys {
+			oldest := s.order.Back()
+			s.order.Remove(oldest)
+			delete(s.buckets, oldest.Value.(*bucket).key)
+		}
+		b = &bucket{key: key, tokens: burst}
+		s.buckets[key] = s.order.PushFront(b)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return b.tokens, true, nil
+	}
+	return b.tokens, false, nil
+}
@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/logging"
+)
+
+// Header names of the quota every limited response reports
+const (
+	LimitHeader     = "X-RateLimit-Limit"
+	RemainingHeader = "X-RateLimit-Remaining"
+	ResetHeader     = "X-RateLimit-Reset"
+)
+
+// Store keeps the token buckets of limiters. Buckets refill continuously at
+// rate tokens per second up to burst tokens.
+type Store interface {
+	// Take takes a token from the bucket of key and returns how many are
+	// left, less than 1 when none could be taken
+	Take(ctx context.Context, key string, rate, burst float64) (tokens float64, allowed bool, err error)
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket
+	Limit int
+	// Remaining is the number of requests left right now
+	Remaining int
+	// RetryAfter is how long to wait for the next token when not allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// Limiter is a token bucket limiter keyed by an arbitrary string
+type Limiter struct {
+	store Store
+	name  string
+	rate  float64 // tokens per second
+	burst float64
+}
+
+// New creates a limiter allowing perMinute requests per key on average with
+// bursts of up to burst requests. Limiters sharing a store need distinct
+// names.
+func New(store Store, name string, perMinute, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		store: store,
+		name:  name,
+		rate:  float64(perMinute) / 60,
+		burst: float64(burst),
+	}
+}
+
+// Take takes a token for key. Requests are allowed when the store fails,
+// so an outage of a shared store does not take the API down with it.
+func (l *Limiter) Take(ctx context.Context, key string) Result {
+	tokens, allowed, err := l.store.Take(ctx, l.name+":"+key, l.rate, l.burst)
+	if err != nil {
+		logging.FromContext(ctx).Error("Rate limit store failed", "limiter", l.name, "error", err)
+		return Result{Allowed: true, Limit: int(l.burst), Remaining: int(l.burst)}
+	}
+
+	result := Result{
+		Allowed:   allowed,
+		Limit:     int(l.burst),
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     l.refill(l.burst - tokens),
+	}
+	if !allowed {
+		result.RetryAfter = l.refill(1 - tokens)
+	}
+	return result
+}
+
+// refill is how long the bucket takes to gain tokens
+func (l *Limiter) refill(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	if l.rate <= 0 {
+		return time.Minute
+	}
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// Middleware rejects requests with 429 once the bucket for key(r) is empty.
+// Requests for which key returns "" are not limited. Limited responses
+// report the quota in the X-RateLimit headers; when several limiters apply,
+// the innermost one's quota is reported.
+func (l *Limiter) Middleware(key func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k != "" {
+				result := l.Take(r.Context(), k)
+				w.Header().Set(LimitHeader, strconv.Itoa(result.Limit))
+				w.Header().Set(RemainingHeader, strconv.Itoa(result.Remaining))
+				w.Header().Set(ResetHeader, strconv.Itoa(seconds(result.Reset)))
+				if !result.Allowed {
+					w.Header().Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+					apierror.Error(w, r, "Too many requests", http.StatusTooManyRequests)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
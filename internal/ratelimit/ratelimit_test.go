@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiterTake(t *testing.T) {
+	ctx := context.Background()
+	// No refill, so that the test does not depend on the clock
+	limiter := New(NewMemoryStore(10), "test", 0, 3)
+
+	for i := 2; i >= 0; i-- {
+		result := limiter.Take(ctx, "alice")
+		if !result.Allowed || result.Remaining != i || result.Limit != 3 {
+			t.Fatalf("request %d got %+v, want allowed with %d left", 3-i, result, i)
+		}
+	}
+	result := limiter.Take(ctx, "alice")
+	if result.Allowed || result.Remaining != 0 || result.RetryAfter <= 0 {
+		t.Errorf("a request past the burst got %+v", result)
+	}
+	if !limiter.Take(ctx, "bob").Allowed {
+		t.Error("another key shares the bucket")
+	}
+	if !New(limiter.store, "other", 0, 3).Take(ctx, "alice").Allowed {
+		t.Error("another limiter of the same store shares the bucket")
+	}
+}
+
+func TestMemoryStoreRefills(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(10)
+	limiter := New(store, "test", 60, 2)
+
+	limiter.Take(ctx, "alice")
+	limiter.Take(ctx, "alice")
+	result := limiter.Take(ctx, "alice")
+	if result.Allowed {
+		t.Fatal("a request past the burst was allowed")
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > time.Second {
+		t.Errorf("retry after %v, want within the second one token takes", result.RetryAfter)
+	}
+
+	// Idle for a minute, the bucket fills up to the burst and no further
+	store.buckets["test:alice"].Value.(*bucket).last = time.Now().Add(-time.Minute)
+	if result := limiter.Take(ctx, "alice"); !result.Allowed || result.Remaining != 1 {
+		t.Errorf("after refilling got %+v, want allowed with 1 left", result)
+	}
+}
+
+func TestMemoryStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	limiter := New(NewMemoryStore(2), "test", 0, 1)
+
+	limiter.Take(ctx, "alice")
+	limiter.Take(ctx, "bob")
+	limiter.Take(ctx, "alice") // alice is now the most recently used
+	limiter.Take(ctx, "carol") // evicting bob
+
+	if len(limiter.store.(*MemoryStore).buckets) != 2 {
+		t.Errorf("the store holds %d buckets, want 2", len(limiter.store.(*MemoryStore).buckets))
+	}
+	if limiter.Take(ctx, "alice").Allowed {
+		t.Error("the most recently used bucket was evicted")
+	}
+	if !limiter.Take(ctx, "bob").Allowed {
+		t.Error("the least recently used bucket was kept")
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, float64, float64) (float64, bool, error) {
+	return 0, false, errors.New("store unavailable")
+}
+
+func TestLimiterAllowsWhenTheStoreFails(t *testing.T) {
+	if !New(failingStore{}, "test", 0, 1).Take(context.Background(), "alice").Allowed {
+		t.Error("a request was refused because the store failed")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	limiter := New(NewMemoryStore(10), "test", 30, 1)
+	handler := limiter.Middleware(func(r *http.Request) string {
+		return r.Header.Get("X-User")
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(user string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/api/v1/auth/sessions", nil)
+		r.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := serve("alice"); w.Code != http.StatusOK || w.Header().Get(LimitHeader) != "1" || w.Header().Get(RemainingHeader) != "0" {
+		t.Errorf("the first request got %d with headers %v", w.Code, w.Header())
+	}
+	w := serve("alice")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("a request past the limit got %d, want 429", w.Code)
+	}
+	// At 30 a minute a token takes 2 seconds
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After is %q, want 2", got)
+	}
+	for i := 0; i < 3; i++ {
+		if w := serve(""); w.Code != http.StatusOK || w.Header().Get(LimitHeader) != "" {
+			t.Fatalf("an unkeyed request got %d with headers %v", w.Code, w.Header())
+		}
+	}
+}
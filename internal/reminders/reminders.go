@@ -0,0 +1,229 @@
+// Package reminders sends the reminder notifications users opt in to with
+// their reminder preferences: a daily reminder when no time was tracked by
+// the set time on the chosen weekdays, except on days off, and a reminder
+// about a timer left running longer than the set threshold. Run checks for
+// due reminders as a recurring task; each reminder is sent once.
+package reminders
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/notify"
+)
+
+// Kinds of reminders, as recorded once sent
+const (
+	kindDaily     = "daily"
+	kindLongTimer = "long_timer"
+)
+
+// retention is how long sent reminders are remembered; longer than any
+// timer runs and than a day in any time zone
+const retention = 30 * 24 * time.Hour
+
+// Run sends the reminders that are due, as a recurring task. Running it
+// every few minutes sends them close to their time.
+func Run(ctx context.Context) error {
+	if err := sendDaily(ctx); err != nil {
+		return fmt.Errorf("error sending daily reminders: %v", err)
+	}
+	if err := sendLongTimers(ctx); err != nil {
+		return fmt.Errorf("error sending long timer reminders: %v", err)
+	}
+	if _, err := db.Pool.Exec(ctx, `DELETE FROM reminders_sent WHERE sent_at < $1`, time.Now().Add(-retention)); err != nil {
+		return fmt.Errorf("error pruning sent reminders: %v", err)
+	}
+	return nil
+}
+
+// preference decodes stored reminder preferences over the defaults
+func preference(value []byte) (models.ReminderPreference, error) {
+	p := models.DefaultPreferences().Reminders
+	err := json.Unmarshal(value, &p)
+	return p, err
+}
+
+// dailyCandidate is a user with a daily reminder set
+type dailyCandidate struct {
+	userID   uuid.UUID
+	timezone string
+	value    []byte
+}
+
+// sendDaily reminds the users whose daily reminder time has passed today,
+// in their time zone and on one of their weekdays, and who tracked nothing
+// today, have no timer running and are not taking the day off
+func sendDaily(ctx context.Context) error {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT u.id, u.timezone, p.value
+		FROM user_preferences p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.key = 'reminders' AND p.is_deleted = false AND u.deactivated_at IS NULL
+		  AND p.value->>'enabled' = 'true' AND COALESCE(p.value->>'daily_at', '') <> ''
+	`)
+	if err != nil {
+		return err
+	}
+	candidates, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (dailyCandidate, error) {
+		var c dailyCandidate
+		err := row.Scan(&c.userID, &c.timezone, &c.value)
+		return c, err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, c := range candidates {
+		p, err := preference(c.value)
+		if err != nil {
+			continue
+		}
+		loc, err := time.LoadLocation(c.timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		now := time.Now().In(loc)
+		if !onWeekday(p.Weekdays, now.Weekday()) {
+			continue
+		}
+		at, err := time.ParseInLocation("15:04", p.DailyAt, loc)
+		if err != nil {
+			continue
+		}
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+		if now.Before(today.Add(time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute)) {
+			continue
+		}
+
+		date := today.Format("2006-01-02")
+		var skip bool
+		err = db.Pool.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM timer_sessions WHERE user_id = $1 AND is_deleted = false AND end_time > $2)
+				OR EXISTS (SELECT 1 FROM running_timers WHERE user_id = $1)
+				OR EXISTS (SELECT 1 FROM time_off WHERE user_id = $1 AND is_deleted = false AND $3::date BETWEEN start_date AND end_date)
+		`, c.userID, today, date).Scan(&skip)
+		if err != nil {
+			return err
+		}
+		if skip {
+			continue
+		}
+
+		err = sendOnce(ctx, c.userID, kindDaily, date, notify.Notification{
+			UserID: c.userID,
+			Type:   notify.TypeDailyReminder,
+			Title:  "Nothing tracked today",
+			Body:   "You have not tracked any time today. Start a timer or log what you worked on.",
+			Data:   map[string]interface{}{"date": date},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// longTimer is a running timer of a user with reminders on
+type longTimer struct {
+	userID    uuid.UUID
+	timerID   uuid.UUID
+	startTime time.Time
+	value     []byte
+}
+
+// sendLongTimers reminds users whose timer has been running longer than
+// their threshold, once per timer
+func sendLongTimers(ctx context.Context) error {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT t.user_id, t.id, t.start_time, p.value
+		FROM running_timers t
+		JOIN user_preferences p ON p.user_id = t.user_id AND p.key = 'reminders' AND p.is_deleted = false
+		JOIN users u ON u.id = t.user_id
+		WHERE u.deactivated_at IS NULL AND p.value->>'enabled' = 'true'
+	`)
+	if err != nil {
+		return err
+	}
+	timers, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (longTimer, error) {
+		var t longTimer
+		err := row.Scan(&t.userID, &t.timerID, &t.startTime, &t.value)
+		return t, err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, t := range timers {
+		p, err := preference(t.value)
+		if err != nil || p.LongTimerMinutes == 0 {
+			continue
+		}
+		elapsed := time.Since(t.startTime)
+		if elapsed < time.Duration(p.LongTimerMinutes)*time.Minute {
+			continue
+		}
+
+		err = sendOnce(ctx, t.userID, kindLongTimer, t.timerID.String(), notify.Notification{
+			UserID: t.userID,
+			Type:   notify.TypeLongTimer,
+			Title:  "Timer still running",
+			Body:   fmt.Sprintf("Your timer has been running for %s. Stop it if you forgot to.", describe(elapsed)),
+			Data: map[string]interface{}{
+				"timer_id":        t.timerID,
+				"start_time":      t.startTime,
+				"elapsed_seconds": int64(elapsed.Seconds()),
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendOnce sends a notification unless the reminder it is was sent
+// already, recording it in the same transaction
+func sendOnce(ctx context.Context, userID uuid.UUID, kind, key string, n notify.Notification) error {
+	return pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO reminders_sent (user_id, kind, key) VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING
+		`, userID, kind, key)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+		return notify.SendTx(ctx, tx, n)
+	})
+}
+
+// onWeekday reports whether day is one of days, numbered from 0 for Sunday
+func onWeekday(days []int, day time.Weekday) bool {
+	for _, d := range days {
+		if d == int(day) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe writes how long a timer ran in whole hours, or minutes under an
+// hour
+func describe(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%d minutes", int(d.Minutes()))
+	}
+	if d < 2*time.Hour {
+		return "over an hour"
+	}
+	return fmt.Sprintf("over %d hours", int(d.Hours()))
+}
@@ -0,0 +1,73 @@
+// Package replica keeps a user's reads on the primary database right after
+// they wrote, so that a client listing what it just created or synced never
+// gets an answer from a replica that has not replayed the change yet.
+package replica
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+var (
+	mu sync.Mutex
+	// lastWrite is when each user's latest write was answered
+	lastWrite = make(map[uuid.UUID]time.Time)
+	lastSweep time.Time
+)
+
+// Middleware routes the reads of a user who wrote within
+// db.ReplicaStaleness to the primary, and records the time of every POST,
+// PUT, PATCH and DELETE request. It must run after auth.Middleware. Writes
+// are tracked per server, so behind a load balancer without sticky
+// sessions a read may still reach a server that has not seen the write.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := auth.GetUserIDFromContext(r.Context())
+		if db.Replica == nil || userID == uuid.Nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			// Reads made while handling a write see the write too
+			next.ServeHTTP(w, r.WithContext(db.WithPrimary(r.Context())))
+			noteWrite(userID)
+		default:
+			if wroteRecently(userID) {
+				r = r.WithContext(db.WithPrimary(r.Context()))
+			}
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func noteWrite(userID uuid.UUID) {
+	now := time.Now()
+	mu.Lock()
+	defer mu.Unlock()
+	lastWrite[userID] = now
+
+	// Forget the writes the replica has certainly replayed, at most once
+	// per staleness window
+	if now.Sub(lastSweep) > db.ReplicaStaleness() {
+		lastSweep = now
+		for id, t := range lastWrite {
+			if now.Sub(t) > db.ReplicaStaleness() {
+				delete(lastWrite, id)
+			}
+		}
+	}
+}
+
+func wroteRecently(userID uuid.UUID) bool {
+	mu.Lock()
+	t, ok := lastWrite[userID]
+	mu.Unlock()
+	return ok && time.Since(t) <= db.ReplicaStaleness()
+}
@@ -0,0 +1,101 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/pacerclub/zebra-backend/internal/apiversion"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/compress"
+	"github.com/pacerclub/zebra-backend/internal/corspolicy"
+	"github.com/pacerclub/zebra-backend/internal/errorreport"
+	"github.com/pacerclub/zebra-backend/internal/handlers"
+	"github.com/pacerclub/zebra-backend/internal/i18n"
+	"github.com/pacerclub/zebra-backend/internal/idempotency"
+	"github.com/pacerclub/zebra-backend/internal/logging"
+	"github.com/pacerclub/zebra-backend/internal/ratelimit"
+	"github.com/pacerclub/zebra-backend/internal/scheduler"
+)
+
+// DefaultOrigins are allowed when ALLOWED_ORIGINS is not set
+var DefaultOrigins = []string{"http://localhost:3000", "https://zebra.pacerclub.cn", "http://localhost:8080"}
+
+// Options configure the router of the API
+type Options struct {
+	// TrustProxyHeaders takes the client's address from the headers of a
+	// reverse proxy
+	TrustProxyHeaders bool
+	// CORS is the cross-origin policy of every route
+	CORS corspolicy.Policy
+	// LegacySunset is when the deprecated /api paths go away, zero if not
+	// planned
+	LegacySunset time.Time
+	// Tasks are the recurring tasks the admin API reports on
+	Tasks *scheduler.Scheduler
+}
+
+// New returns the router of the API with its root middleware: request IDs,
+// logging, panic recovery, error reporting, locales, timeouts,
+// compression, CORS and the per-address rate limit
+func New(limits Limiters, opts Options) chi.Router {
+	r := chi.NewRouter()
+
+	if opts.TrustProxyHeaders {
+		r.Use(middleware.RealIP)
+	}
+	r.Use(middleware.RequestID)
+	r.Use(logging.Middleware)
+	r.Use(middleware.Recoverer)
+	r.Use(errorreport.Middleware)
+	r.Use(i18n.Middleware)
+	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(compress.RequestMiddleware)
+	r.Use(middleware.Compress(5, "application/json", "application/msgpack"))
+
+	// CORS, with a single policy for every route
+	r.Use(opts.CORS.Handler())
+	r.Use(limits.IP.Middleware(ratelimit.IPKey))
+
+	// Unknown paths and methods get the standard error body, in every
+	// mounted router; OPTIONS requests are answered with the Allow header
+	r.NotFound(handlers.NotFound)
+	r.MethodNotAllowed(handlers.MethodNotAllowed(r))
+
+	mount(r, limits, opts.Tasks, opts.LegacySunset)
+	return r
+}
+
+// CORSPolicy is the CORS policy of the API. origins, the web app's and the
+// browser extensions', may call the API with credentials; the
+// documentation is readable from anywhere.
+func CORSPolicy(origins corspolicy.Origins) corspolicy.Policy {
+	anyOrigin, _ := corspolicy.ParseOrigins([]string{"*"})
+
+	return corspolicy.Policy{
+		Default: corspolicy.Rule{
+			Origins:     origins,
+			Credentials: true,
+			Methods:     []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
+			Headers: []string{
+				"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Content-Encoding", "X-Device-ID",
+				idempotency.Header, middleware.RequestIDHeader, auth.OrganizationHeader, auth.WorkspaceHeader, apiversion.Header,
+			},
+			Exposed: []string{
+				"Link", "Retry-After", "Deprecation", "Sunset", idempotency.ReplayedHeader,
+				ratelimit.LimitHeader, ratelimit.RemainingHeader, ratelimit.ResetHeader,
+				apiversion.Header, apiversion.SupportedHeader,
+			},
+			MaxAge: 300,
+		},
+		Overrides: []corspolicy.Override{
+			// API clients and documentation tools may fetch the spec
+			{Prefix: "/api/docs", Rule: corspolicy.Rule{
+				Origins: anyOrigin,
+				Methods: []string{http.MethodGet},
+				MaxAge:  300,
+			}},
+		},
+	}
+}
@@ -0,0 +1,409 @@
+// Package routes is the router of the HTTP API, its root middleware and
+// route table, shared by the server of cmd/api and the in-memory server of
+// package zebratest
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pacerclub/zebra-backend/internal/apiversion"
+	"github.com/pacerclub/zebra-backend/internal/audit"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/handlers"
+	"github.com/pacerclub/zebra-backend/internal/idempotency"
+	"github.com/pacerclub/zebra-backend/internal/negotiate"
+	"github.com/pacerclub/zebra-backend/internal/openapi"
+	"github.com/pacerclub/zebra-backend/internal/ratelimit"
+	"github.com/pacerclub/zebra-backend/internal/replica"
+	"github.com/pacerclub/zebra-backend/internal/scheduler"
+	"github.com/pacerclub/zebra-backend/internal/usage"
+)
+
+// LegacyDeprecatedAt is when the unversioned /api paths were deprecated
+// in favor of /api/v1
+var LegacyDeprecatedAt = time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC)
+
+// Limiters are the rate limiters of the API, shared by every mount of the
+// routes and by the gRPC API
+type Limiters struct {
+	// IP limits every request per client address, User every
+	// authenticated request per user
+	IP, User *ratelimit.Limiter
+	// Reports limits the endpoints aggregating many rows per user
+	Reports *ratelimit.Limiter
+	// Sync is the heaviest write path, so it is throttled both per device
+	// and per user to contain clients stuck in a retry loop
+	SyncDevice, SyncUser *ratelimit.Limiter
+	// Kiosk limits clock-ins and clock-outs per kiosk, which bounds how
+	// fast member PINs can be guessed
+	Kiosk *ratelimit.Limiter
+}
+
+// mount registers the API on the root router r. The API is mounted under
+// /api/v1; the unversioned /api paths it replaced stay as deprecated
+// aliases until legacySunset, or for good when it is zero. JSON bodies are
+// validated against the OpenAPI spec of both, which is served under
+// /api/docs.
+func mount(r chi.Router, limits Limiters, tasks *scheduler.Scheduler, legacySunset time.Time) {
+	spec := openapi.New("Zebra API", "1", handlers.Operations)
+	// Requests of a batch go through the whole router, middleware included
+	batch := handlers.Batch(r)
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(apiversion.Middleware(1))
+		r.Use(spec.Validate(r))
+		apiRoutes(r, limits, batch, tasks)
+	})
+	r.Route("/api", func(r chi.Router) {
+		r.Use(apiversion.Deprecated("/api", "/api/v1", LegacyDeprecatedAt, legacySunset))
+		r.Use(apiversion.Middleware(1))
+		r.Use(spec.Validate(r))
+		apiRoutes(r, limits, batch, tasks)
+	})
+
+	// API documentation
+	r.Get("/api/docs", openapi.SwaggerUI("Zebra API", "/api/docs/openapi.json"))
+	r.Get("/api/docs/openapi.json", spec.Handler(r, "/api/v1"))
+}
+
+// apiRoutes registers every API route relative to the API root, so the
+// same routes can be mounted once per version and under the legacy
+// unversioned prefix. batch serves batches of requests to the API; tasks
+// are the recurring tasks the admin API reports on.
+func apiRoutes(r chi.Router, limits Limiters, batch http.HandlerFunc, tasks *scheduler.Scheduler) {
+	// High-volume routes of the mobile apps also speak MessagePack
+	msgpack := negotiate.Middleware(negotiate.Msgpack)
+
+	// Public routes
+	r.Group(func(r chi.Router) {
+		r.Route("/auth", func(r chi.Router) {
+			r.Post("/register", handlers.Register)
+			r.Post("/login", handlers.Login)
+			// Deactivated users cannot authenticate, so they reactivate by
+			// email
+			r.Post("/reactivate", handlers.RequestReactivation)
+			r.Post("/reactivate/confirm", handlers.ConfirmReactivation)
+		})
+
+		// OAuth callbacks are reached by browser redirect, without a token
+		r.Get("/integrations/google-calendar/callback", handlers.GoogleCalendarCallback)
+		r.Get("/integrations/quickbooks/callback", handlers.QuickBooksCallback)
+		r.Get("/integrations/xero/callback", handlers.XeroCallback)
+		// Third-party apps authenticate with their client credentials
+		r.Post("/oauth/token", handlers.OAuthToken)
+		r.Post("/oauth/revoke", handlers.RevokeOAuthToken)
+		// WakaTime plugins authenticate with their own API key
+		r.Post("/integrations/wakatime/users/current/heartbeats", handlers.WakaTimeHeartbeat)
+		r.Post("/integrations/wakatime/users/current/heartbeats.bulk", handlers.WakaTimeHeartbeats)
+		// Mailgun signs inbound mail webhooks
+		r.Post("/integrations/email/inbound", handlers.InboundEmail)
+		// Stripe signs billing webhooks
+		r.Post("/billing/stripe/webhook", handlers.StripeWebhook)
+		// Kiosks authenticate with their own token, members with a PIN
+		r.Group(func(r chi.Router) {
+			r.Use(limits.Kiosk.Middleware(ratelimit.BearerKey))
+			r.Post("/kiosk/clock-in", handlers.KioskClockIn)
+			r.Post("/kiosk/clock-out", handlers.KioskClockOut)
+		})
+	})
+
+	// Workspace switching skips OrganizationMiddleware so a token whose
+	// workspace is no longer accessible can still switch away from it
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Middleware)
+		r.Use(usage.Middleware)
+		r.Use(replica.Middleware)
+		r.Use(limits.User.Middleware(ratelimit.UserKey))
+		r.Use(auth.RequireScopes)
+		r.Use(audit.Middleware)
+		r.Use(idempotency.Middleware)
+		r.Get("/auth/workspaces", handlers.ListWorkspaces)
+		r.With(auth.DenyImpersonation).Post("/auth/workspace", handlers.SwitchWorkspace)
+	})
+
+	// Batches skip scopes and workspace checks, which each of their
+	// requests passes on its own
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Middleware)
+		r.Use(limits.User.Middleware(ratelimit.UserKey))
+		r.Use(idempotency.Middleware)
+		r.Post("/batch", batch)
+	})
+
+	// Admin API for operators of the server, outside any workspace
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Middleware)
+		r.Use(usage.Middleware)
+		r.Use(limits.User.Middleware(ratelimit.UserKey))
+		r.Use(auth.RequireScopes)
+		r.Use(auth.RequireAdmin)
+		r.Get("/admin/stats", handlers.AdminStats)
+		r.Get("/admin/health", handlers.AdminHealth)
+		r.Get("/admin/tasks", handlers.AdminTasks(tasks))
+		r.Get("/admin/audit", handlers.AdminAuditLog)
+		r.Get("/admin/backup", handlers.AdminBackup)
+		r.Post("/admin/restore", handlers.AdminRestore)
+		r.Post("/admin/impersonate", handlers.AdminImpersonate)
+	})
+
+	// Protected routes
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Middleware)
+		r.Use(usage.Middleware)
+		r.Use(replica.Middleware)
+		r.Use(limits.User.Middleware(ratelimit.UserKey))
+		r.Use(auth.RequireScopes)
+		r.Use(auth.OrganizationMiddleware)
+		r.Use(audit.Middleware)
+		r.Use(idempotency.Middleware)
+		reports := limits.Reports.Middleware(ratelimit.UserKey)
+
+		// The user's own account and profile
+		r.Get("/auth/me", handlers.GetProfile)
+		r.Put("/auth/me", handlers.UpdateProfile)
+		r.With(auth.DenyImpersonation).Post("/auth/deactivate", handlers.DeactivateAccount)
+		r.Get("/auth/preferences", handlers.GetPreferences)
+		r.Put("/auth/preferences", handlers.UpdatePreferences)
+		r.Get("/auth/notifications/settings", handlers.GetNotificationSettings)
+		r.Put("/auth/notifications/settings", handlers.UpdateNotificationSettings)
+		r.Get("/onboarding", handlers.GetOnboarding)
+		r.Get("/usage", handlers.GetUsage)
+
+		// Reports of tracked time
+		r.With(reports).Get("/auth/reports/hours", handlers.GetHoursReport)
+		r.With(reports).Get("/auth/reports/estimates", handlers.GetEstimateReport)
+
+		// Subscriptions to the hosted instance
+		r.Get("/billing/subscription", handlers.GetSubscription)
+		r.With(auth.DenyImpersonation).Post("/billing/checkout", handlers.CreateCheckout)
+		r.With(auth.DenyImpersonation).Post("/billing/portal", handlers.CreateBillingPortal)
+
+		// Storage mode (standard or client-encrypted)
+		r.Get("/auth/storage-mode", handlers.GetStorageMode)
+		r.With(auth.DenyImpersonation).Put("/auth/storage-mode", handlers.UpdateStorageMode)
+
+		// The user's own audit log
+		r.Get("/auth/audit", handlers.ListAuditLog)
+		r.Get("/auth/logins", handlers.ListLogins)
+		r.With(auth.DenyImpersonation).Post("/auth/logins/sign-out-everywhere", handlers.SignOutEverywhere)
+
+		// Organizations
+		r.Route("/auth/organizations", func(r chi.Router) {
+			r.Post("/", handlers.CreateOrganization)
+			r.Get("/", handlers.ListOrganizations)
+			r.Get("/{id}", handlers.GetOrganization)
+			r.Put("/{id}", handlers.UpdateOrganization)
+			r.Delete("/{id}", handlers.DeleteOrganization)
+			r.Get("/{id}/settings", handlers.GetOrganizationSettings)
+			r.Put("/{id}/settings", handlers.UpdateOrganizationSettings)
+			r.Get("/{id}/members", handlers.ListMembers)
+			r.Post("/{id}/members", handlers.AddMember)
+			r.With(reports).Get("/{id}/members/activity", handlers.ListMemberActivity)
+			r.With(reports).Get("/{id}/capacity", handlers.GetOrganizationCapacity)
+			r.Get("/{id}/presence", handlers.GetOrganizationPresence)
+			r.Put("/{id}/members/{userID}", handlers.UpdateMember)
+			r.Delete("/{id}/members/{userID}", handlers.RemoveMember)
+			r.Post("/{id}/members/{userID}/deactivate", handlers.DeactivateMember)
+			r.Post("/{id}/members/{userID}/reactivate", handlers.ReactivateMember)
+			r.Post("/{id}/members/{userID}/transfer", handlers.TransferMemberProjects)
+			r.Post("/{id}/kiosks", handlers.CreateKiosk)
+			r.Get("/{id}/kiosks", handlers.ListKiosks)
+			r.Delete("/{id}/kiosks/{kioskID}", handlers.DeleteKiosk)
+			r.Post("/{id}/kiosk-pin", handlers.CreateKioskPIN)
+			r.Delete("/{id}/kiosk-pin", handlers.DeleteKioskPIN)
+		})
+
+		// OAuth apps: registration, the consent screen and granted access
+		r.Route("/auth/oauth", func(r chi.Router) {
+			r.Post("/clients", handlers.CreateOAuthClient)
+			r.Get("/clients", handlers.ListOAuthClients)
+			r.Delete("/clients/{id}", handlers.DeleteOAuthClient)
+			r.Get("/authorize", handlers.GetOAuthConsent)
+			r.With(auth.DenyImpersonation).Post("/authorize", handlers.DecideOAuthConsent)
+			r.Get("/authorizations", handlers.ListOAuthAuthorizations)
+			r.Delete("/authorizations/{clientID}", handlers.RevokeOAuthAuthorization)
+		})
+
+		// Third-party integrations
+		r.Route("/auth/integrations", func(r chi.Router) {
+			r.Post("/google-calendar/connect", handlers.ConnectGoogleCalendar)
+			r.Get("/google-calendar", handlers.GetGoogleCalendar)
+			r.Put("/google-calendar", handlers.UpdateGoogleCalendar)
+			r.Delete("/google-calendar", handlers.DisconnectGoogleCalendar)
+			r.Post("/jira", handlers.ConnectJira)
+			r.Get("/jira", handlers.GetJira)
+			r.Put("/jira", handlers.UpdateJira)
+			r.Delete("/jira", handlers.DisconnectJira)
+			r.Get("/jira/worklogs", handlers.ListJiraWorklogs)
+			r.Post("/jira/worklogs/retry", handlers.RetryJiraWorklogs)
+			r.Put("/jira/sessions/{id}", handlers.SetJiraIssueKey)
+			r.Post("/email", handlers.ConnectEmail)
+			r.Get("/email", handlers.GetEmail)
+			r.Delete("/email", handlers.DisconnectEmail)
+			r.Post("/quickbooks/connect", handlers.ConnectQuickBooks)
+			r.Get("/quickbooks", handlers.GetQuickBooks)
+			r.Put("/quickbooks", handlers.UpdateQuickBooks)
+			r.Delete("/quickbooks", handlers.DisconnectQuickBooks)
+			r.Post("/xero/connect", handlers.ConnectXero)
+			r.Get("/xero", handlers.GetXero)
+			r.Put("/xero", handlers.UpdateXero)
+			r.Delete("/xero", handlers.DisconnectXero)
+			r.Post("/notion", handlers.ConnectNotion)
+			r.Get("/notion", handlers.GetNotion)
+			r.Put("/notion", handlers.UpdateNotion)
+			r.Delete("/notion", handlers.DisconnectNotion)
+			r.Post("/wakatime", handlers.ConnectWakaTime)
+			r.Get("/wakatime", handlers.GetWakaTime)
+			r.Put("/wakatime", handlers.UpdateWakaTime)
+			r.Delete("/wakatime", handlers.DisconnectWakaTime)
+			r.Get("/suggestions", handlers.ListSuggestedSessions)
+			r.With(auth.RequirePermission(auth.PermLogTime)).Post("/suggestions/{id}/confirm", handlers.ConfirmSuggestedSession)
+			r.Post("/suggestions/{id}/dismiss", handlers.DismissSuggestedSession)
+		})
+
+		// Running timer, shared by every device of the user
+		r.Get("/auth/current", handlers.GetCurrent)
+		r.With(auth.RequirePermission(auth.PermLogTime)).Post("/auth/current/start", handlers.StartCurrent)
+		r.With(auth.RequirePermission(auth.PermLogTime)).Post("/auth/current/stop", handlers.StopCurrent)
+		r.With(auth.RequirePermission(auth.PermLogTime)).Post("/auth/quick-start", handlers.QuickStart)
+		r.Get("/auth/live", handlers.LiveEvents)
+
+		// Untracked blocks of calendar events
+		r.Get("/auth/suggestions", handlers.ListUntrackedBlocks)
+
+		// Webhooks, following the REST hook pattern of Zapier and Make
+		r.Route("/auth/hooks", func(r chi.Router) {
+			r.Post("/", handlers.SubscribeWebhook)
+			r.Get("/", handlers.ListWebhooks)
+			r.Delete("/{id}", handlers.UnsubscribeWebhook)
+			r.Get("/{id}/deliveries", handlers.ListWebhookDeliveries)
+			r.Post("/deliveries/{id}/retry", handlers.RetryWebhookDelivery)
+			r.Get("/events", handlers.ListWebhookEvents)
+			r.Get("/samples/{event}", handlers.WebhookSample)
+		})
+
+		// Tracking rules, applied to new sessions and to history on request
+		r.Route("/auth/rules", func(r chi.Router) {
+			r.Post("/", handlers.CreateRule)
+			r.Get("/", handlers.ListRules)
+			r.Put("/{id}", handlers.UpdateRule)
+			r.Delete("/{id}", handlers.DeleteRule)
+			r.Post("/preview", handlers.PreviewRules)
+			r.With(auth.RequirePermission(auth.PermLogTime)).Post("/apply", handlers.ApplyRules)
+		})
+
+		// Vacations, sick days and holidays, also written through sync
+		r.Route("/auth/time-off", func(r chi.Router) {
+			r.Post("/", handlers.CreateTimeOff)
+			r.Get("/", handlers.ListTimeOff)
+			r.Put("/{id}", handlers.UpdateTimeOff)
+			r.Delete("/{id}", handlers.DeleteTimeOff)
+		})
+
+		// Imports from other time trackers
+		r.Route("/auth/import", func(r chi.Router) {
+			r.Post("/toggl", handlers.ImportToggl)
+			r.Post("/csv", handlers.ImportCSV)
+			r.Get("/", handlers.ListImportJobs)
+			r.Get("/{id}", handlers.GetImportJob)
+		})
+
+		// Avatars and project icons, uploaded straight to storage
+		r.Route("/auth/uploads", func(r chi.Router) {
+			r.Post("/", handlers.CreateUpload)
+			r.Get("/{id}", handlers.GetUpload)
+			r.Post("/{id}/complete", handlers.CompleteUpload)
+		})
+
+		// Invoices of billable time, downloaded or pushed to accounting
+		r.Route("/auth/invoices", func(r chi.Router) {
+			r.With(reports).Get("/", handlers.DownloadInvoices)
+			r.With(reports).Post("/exports", handlers.ExportInvoices)
+			r.Get("/exports", handlers.ListInvoiceExports)
+			r.Get("/exports/{id}", handlers.GetInvoiceExport)
+		})
+
+		// Project transfers between users and organizations
+		r.Route("/auth/transfer", func(r chi.Router) {
+			r.Post("/", handlers.CreateTransfer)
+			r.Get("/", handlers.ListTransfers)
+			r.Post("/{id}/accept", handlers.AcceptTransfer)
+			r.Post("/{id}/decline", handlers.DeclineTransfer)
+			r.Delete("/{id}", handlers.CancelTransfer)
+		})
+
+		// Timer sessions
+		r.Route("/auth/sessions", func(r chi.Router) {
+			r.With(msgpack).Get("/", handlers.ListSessions)
+			r.Get("/search", handlers.SearchSessions)
+			r.Get("/{id}/tags", handlers.ListSessionTags)
+			r.Get("/review", handlers.ListSessionReviews)
+			r.Get("/duplicates", handlers.ListDuplicateSessions)
+			r.Group(func(r chi.Router) {
+				r.Use(auth.RequirePermission(auth.PermLogTime))
+				r.Post("/", handlers.CreateSession)
+				r.Post("/bulk", handlers.CreateSessions)
+				r.Post("/merge", handlers.MergeSessions)
+				r.Put("/{id}", handlers.UpdateSession)
+				r.Delete("/{id}", handlers.DeleteSession)
+				r.Delete("/{id}/review", handlers.ResolveSessionReview)
+				r.Put("/{id}/task", handlers.SetSessionTask)
+				r.Delete("/{id}/task", handlers.ClearSessionTask)
+			})
+		})
+
+		// Tasks, which are written through sync, and their estimates
+		r.Route("/auth/tasks", func(r chi.Router) {
+			r.Get("/", handlers.ListTasks)
+			r.Get("/over-budget", handlers.ListTasksOverBudget)
+			r.Put("/{id}/estimate", handlers.SetTaskEstimate)
+		})
+
+		// Projects
+		r.Route("/auth/projects", func(r chi.Router) {
+			r.Get("/", handlers.ListProjects)
+			r.Get("/search", handlers.SearchProjects)
+			r.Get("/{id}/billing", handlers.GetProjectBilling)
+			r.Group(func(r chi.Router) {
+				r.Use(auth.RequirePermission(auth.PermManageProjects))
+				r.Post("/", handlers.CreateProject)
+				r.Put("/{id}", handlers.UpdateProject)
+				r.Delete("/{id}", handlers.DeleteProject)
+				r.Put("/{id}/billing", handlers.UpdateProjectBilling)
+			})
+		})
+
+		// Tags, written through sync
+		r.Get("/auth/tags", handlers.ListTags)
+
+		// Checks for damage left by past sync bugs, and its repair
+		r.With(reports).Post("/auth/maintenance/verify", handlers.VerifyData)
+		r.With(reports, auth.RequirePermission(auth.PermLogTime)).Post("/auth/maintenance/repair", handlers.RepairData)
+	})
+
+	// Sync replays retried batches itself, in the same transaction as their
+	// changes, so it is kept out of the idempotency middleware
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Middleware)
+		r.Use(usage.Middleware)
+		r.Use(replica.Middleware)
+		r.Use(limits.User.Middleware(ratelimit.UserKey))
+		r.Use(auth.RequireScopes)
+		r.Use(auth.OrganizationMiddleware)
+		r.Use(audit.Middleware)
+
+		r.Route("/auth/sync", func(r chi.Router) {
+			r.With(
+				limits.SyncDevice.Middleware(ratelimit.DeviceKey),
+				limits.SyncUser.Middleware(ratelimit.UserKey),
+				msgpack,
+			).Post("/", handlers.SyncData)
+			r.With(limits.SyncUser.Middleware(ratelimit.UserKey), msgpack).Post("/reset", handlers.ResetSync)
+			r.Get("/status", handlers.SyncStatus)
+			r.Get("/devices", handlers.ListDevices)
+			r.Get("/conflicts", handlers.ListSyncConflicts)
+			r.With(limits.Reports.Middleware(ratelimit.UserKey)).Get("/stats", handlers.SyncStats)
+		})
+	})
+}
@@ -0,0 +1,318 @@
+// Package rules stores the user's automatic tracking rules and matches them
+// against session descriptions. A rule such as "description contains
+// 'standup'" assigns a project, tags or both to the sessions it matches.
+// Rules are evaluated in position order: the first matching rule with a
+// project sets the project, and every matching rule adds its tags. Package
+// service applies them to new sessions.
+package rules
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+var ErrRuleNotFound = errors.New("tracking rule not found")
+
+// How a rule's pattern is compared with descriptions, ignoring case
+const (
+	MatchContains   = "contains"
+	MatchStartsWith = "starts_with"
+	MatchEquals     = "equals"
+	// MatchRegex takes the pattern as a regular expression in RE2 syntax
+	MatchRegex = "regex"
+)
+
+// MatchTypes lists every match type in the order they are documented
+var MatchTypes = []string{MatchContains, MatchStartsWith, MatchEquals, MatchRegex}
+
+// MaxPerUser caps how many rules a user can have
+const MaxPerUser = 100
+
+// MaxTags caps how many tags one rule adds
+const MaxTags = 20
+
+// Rule assigns a project, tags or both to sessions whose description
+// matches its pattern
+type Rule struct {
+	ID        uuid.UUID   `json:"id"`
+	UserID    uuid.UUID   `json:"user_id"`
+	Name      string      `json:"name"`
+	MatchType string      `json:"match_type"`
+	Pattern   string      `json:"pattern"`
+	ProjectID *uuid.UUID  `json:"project_id,omitempty"`
+	TagIDs    []uuid.UUID `json:"tag_ids"`
+	// Position orders the rules, lowest first
+	Position  int       `json:"position"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ValidMatchType reports whether matchType is known
+func ValidMatchType(matchType string) bool {
+	for _, t := range MatchTypes {
+		if t == matchType {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPattern returns why pattern cannot be used with matchType, or ""
+func CheckPattern(matchType, pattern string) string {
+	if matchType != MatchRegex {
+		return ""
+	}
+	if _, err := regexp.Compile("(?i)" + pattern); err != nil {
+		return "is not a valid regular expression"
+	}
+	return ""
+}
+
+const ruleColumns = "id, user_id, name, match_type, pattern, project_id, tag_ids, position, enabled, created_at, updated_at"
+
+func scanRule(row pgx.Row) (*Rule, error) {
+	var rule Rule
+	err := row.Scan(&rule.ID, &rule.UserID, &rule.Name, &rule.MatchType, &rule.Pattern, &rule.ProjectID,
+		&rule.TagIDs, &rule.Position, &rule.Enabled, &rule.CreatedAt, &rule.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRuleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// List returns the user's rules in the order they are evaluated
+func List(ctx context.Context, userID uuid.UUID) ([]Rule, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+ruleColumns+`
+		FROM tracking_rules
+		WHERE user_id = $1
+		ORDER BY position, created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Rule, error) {
+		rule, err := scanRule(row)
+		if err != nil {
+			return Rule{}, err
+		}
+		return *rule, nil
+	})
+}
+
+// Get returns one of the user's rules
+func Get(ctx context.Context, userID, ruleID uuid.UUID) (*Rule, error) {
+	return scanRule(db.Pool.QueryRow(ctx,
+		`SELECT `+ruleColumns+` FROM tracking_rules WHERE id = $1 AND user_id = $2`, ruleID, userID))
+}
+
+// Count returns how many rules the user has
+func Count(ctx context.Context, userID uuid.UUID) (int, error) {
+	var n int
+	err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM tracking_rules WHERE user_id = $1`, userID).Scan(&n)
+	return n, err
+}
+
+// Create stores a new rule of rule.UserID. A nil position puts it after
+// the user's other rules.
+func Create(ctx context.Context, rule Rule, position *int) (*Rule, error) {
+	return scanRule(db.Pool.QueryRow(ctx, `
+		INSERT INTO tracking_rules (id, user_id, name, match_type, pattern, project_id, tag_ids, position, enabled)
+		SELECT $1, $2, $3, $4, $5, $6, $7,
+			COALESCE($8, (SELECT COALESCE(MAX(position) + 1, 0) FROM tracking_rules WHERE user_id = $2)), $9
+		RETURNING `+ruleColumns,
+		uuid.New(), rule.UserID, rule.Name, rule.MatchType, rule.Pattern, rule.ProjectID, tagIDs(rule.TagIDs),
+		position, rule.Enabled))
+}
+
+// Update replaces the editable fields of one of the user's rules
+func Update(ctx context.Context, rule Rule) (*Rule, error) {
+	return scanRule(db.Pool.QueryRow(ctx, `
+		UPDATE tracking_rules
+		SET name = $3, match_type = $4, pattern = $5, project_id = $6, tag_ids = $7, position = $8,
+			enabled = $9, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+ruleColumns,
+		rule.ID, rule.UserID, rule.Name, rule.MatchType, rule.Pattern, rule.ProjectID, tagIDs(rule.TagIDs),
+		rule.Position, rule.Enabled))
+}
+
+// Delete removes one of the user's rules
+func Delete(ctx context.Context, userID, ruleID uuid.UUID) error {
+	result, err := db.Pool.Exec(ctx, `DELETE FROM tracking_rules WHERE id = $1 AND user_id = $2`, ruleID, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// tagIDs stores a missing tag list as empty
+func tagIDs(ids []uuid.UUID) []uuid.UUID {
+	if ids == nil {
+		return []uuid.UUID{}
+	}
+	return ids
+}
+
+// Set is a list of enabled rules, ready to match descriptions
+type Set []compiled
+
+type compiled struct {
+	rule  Rule
+	match func(description string) bool
+}
+
+// Load returns the user's enabled rules
+func Load(ctx context.Context, userID uuid.UUID) (Set, error) {
+	rules, err := List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return NewSet(rules), nil
+}
+
+// NewSet compiles the enabled rules among rules, in their order. Rules
+// whose pattern no longer compiles are left out.
+func NewSet(rules []Rule) Set {
+	var set Set
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		pattern := strings.ToLower(strings.TrimSpace(rule.Pattern))
+		c := compiled{rule: rule}
+		switch rule.MatchType {
+		case MatchContains:
+			c.match = func(d string) bool { return strings.Contains(d, pattern) }
+		case MatchStartsWith:
+			c.match = func(d string) bool { return strings.HasPrefix(d, pattern) }
+		case MatchEquals:
+			c.match = func(d string) bool { return d == pattern }
+		case MatchRegex:
+			re, err := regexp.Compile("(?i)" + rule.Pattern)
+			if err != nil {
+				continue
+			}
+			c.match = func(d string) bool { return re.MatchString(d) }
+		default:
+			continue
+		}
+		set = append(set, c)
+	}
+	return set
+}
+
+// Match is what the rules matching a description assign
+type Match struct {
+	// ProjectID is the project of the first matching rule with one
+	ProjectID *uuid.UUID `json:"project_id,omitempty"`
+	// TagIDs are the tags of every matching rule, without repeats
+	TagIDs []uuid.UUID `json:"tag_ids,omitempty"`
+	// RuleIDs are the matching rules
+	RuleIDs []uuid.UUID `json:"rule_ids"`
+}
+
+// Match runs the rules over a description. usable tells whether a rule's
+// project may be assigned, as for a project outside the active scope; a
+// nil usable accepts every project.
+func (s Set) Match(description string, usable func(projectID uuid.UUID) bool) Match {
+	var m Match
+	description = strings.ToLower(strings.TrimSpace(description))
+	if description == "" {
+		return m
+	}
+	seen := make(map[uuid.UUID]bool)
+	for _, c := range s {
+		if !c.match(description) {
+			continue
+		}
+		m.RuleIDs = append(m.RuleIDs, c.rule.ID)
+		if m.ProjectID == nil && c.rule.ProjectID != nil && (usable == nil || usable(*c.rule.ProjectID)) {
+			m.ProjectID = c.rule.ProjectID
+		}
+		for _, id := range c.rule.TagIDs {
+			if !seen[id] {
+				seen[id] = true
+				m.TagIDs = append(m.TagIDs, id)
+			}
+		}
+	}
+	return m
+}
+
+// ProjectIDs returns the projects the rules assign
+func (s Set) ProjectIDs() []uuid.UUID {
+	var ids []uuid.UUID
+	for _, c := range s {
+		if c.rule.ProjectID != nil {
+			ids = append(ids, *c.rule.ProjectID)
+		}
+	}
+	return ids
+}
+
+// TagSessions adds tags to sessions of the user in tx, by session. Tags the
+// user deleted or does not own are skipped, as are tags already on a
+// session and sessions that were not stored.
+func TagSessions(ctx context.Context, tx pgx.Tx, userID uuid.UUID, tags map[uuid.UUID][]uuid.UUID) error {
+	var sessionIDs, tagIDs []uuid.UUID
+	for sessionID, ids := range tags {
+		for _, id := range ids {
+			sessionIDs = append(sessionIDs, sessionID)
+			tagIDs = append(tagIDs, id)
+		}
+	}
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO session_tags (session_id, tag_id, user_id)
+		SELECT s.session_id, t.id, t.user_id
+		FROM unnest($1::uuid[], $2::uuid[]) AS s(session_id, tag_id)
+		JOIN tags t ON t.id = s.tag_id AND t.user_id = $3 AND t.is_deleted = false
+		WHERE EXISTS (SELECT 1 FROM timer_sessions WHERE id = s.session_id AND user_id = $3)
+		ON CONFLICT DO NOTHING
+	`, sessionIDs, tagIDs, userID)
+	return err
+}
+
+// SessionTags returns the tags on sessions of the user, by session
+func SessionTags(ctx context.Context, userID uuid.UUID, sessionIDs []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	tags := make(map[uuid.UUID][]uuid.UUID)
+	if len(sessionIDs) == 0 {
+		return tags, nil
+	}
+	rows, err := db.Pool.Query(ctx, `
+		SELECT st.session_id, st.tag_id
+		FROM session_tags st
+		JOIN tags t ON t.id = st.tag_id
+		WHERE st.user_id = $1 AND st.session_id = ANY($2) AND t.is_deleted = false
+		ORDER BY st.created_at
+	`, userID, sessionIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sessionID, tagID uuid.UUID
+		if err := rows.Scan(&sessionID, &tagID); err != nil {
+			return nil, err
+		}
+		tags[sessionID] = append(tags[sessionID], tagID)
+	}
+	return tags, rows.Err()
+}
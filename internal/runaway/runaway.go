@@ -0,0 +1,111 @@
+// Package runaway stops running timers left on past a maximum duration,
+// such as one forgotten over a weekend, so they do not turn into sessions
+// tens of hours long. A runaway timer is saved as a session ending at the
+// maximum duration, flagged for the user to review, and the user is
+// notified. Stop runs as a recurring task.
+package runaway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/notify"
+	"github.com/pacerclub/zebra-backend/internal/service"
+)
+
+// batchSize bounds how many timers one run stops; the rest wait for the
+// next run
+const batchSize = 500
+
+// timer is a running timer past the maximum duration
+type timer struct {
+	userID    uuid.UUID
+	timerID   uuid.UUID
+	startTime time.Time
+}
+
+// Stop saves the running timers that started more than max ago as
+// sessions of length max, flags them for review and notifies their users
+func Stop(ctx context.Context, max time.Duration) error {
+	if max <= 0 || max > service.MaxSessionDuration {
+		return fmt.Errorf("maximum timer duration must be between 0 and %s", service.MaxSessionDuration)
+	}
+	rows, err := db.Pool.Query(ctx, `
+		SELECT user_id, id, start_time FROM running_timers
+		WHERE start_time < $1
+		ORDER BY start_time
+		LIMIT $2
+	`, time.Now().Add(-max), batchSize)
+	if err != nil {
+		return err
+	}
+	timers, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (timer, error) {
+		var t timer
+		err := row.Scan(&t.userID, &t.timerID, &t.startTime)
+		return t, err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, t := range timers {
+		if err := stop(ctx, t, max); err != nil {
+			return fmt.Errorf("error stopping timer %s: %v", t.timerID, err)
+		}
+	}
+	return nil
+}
+
+// stop saves one runaway timer, unless the user stopped or replaced it
+// since it was found
+func stop(ctx context.Context, t timer, max time.Duration) error {
+	return pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		var id uuid.UUID
+		err := tx.QueryRow(ctx, `SELECT id FROM running_timers WHERE user_id = $1 AND id = $2 FOR UPDATE`,
+			t.userID, t.timerID).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		end := t.startTime.Add(max)
+		session, err := service.StopRunningTimer(ctx, tx, t.userID, &end)
+		if err != nil || session == nil {
+			return err
+		}
+		if err := service.FlagSession(ctx, tx, t.userID, session.ID, service.ReviewRunawayTimer); err != nil {
+			return err
+		}
+		return notify.SendTx(ctx, tx, notify.Notification{
+			UserID: t.userID,
+			Type:   notify.TypeRunawayTimer,
+			Title:  "Timer stopped",
+			Body: fmt.Sprintf("Your timer ran for more than %s, so it was stopped and saved as a session of that length. Check the session and correct its end time if needed.",
+				describe(max)),
+			Data: map[string]interface{}{
+				"session_id": session.ID,
+				"start_time": session.StartTime,
+				"end_time":   session.EndTime,
+			},
+		})
+	})
+}
+
+// describe writes the maximum duration in whole hours, or minutes under an
+// hour
+func describe(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%d minutes", int(d.Minutes()))
+	}
+	if d < 2*time.Hour {
+		return "an hour"
+	}
+	return fmt.Sprintf("%d hours", int(d.Hours()))
+}
@@ -0,0 +1,183 @@
+// Package scheduler runs recurring tasks, such as tombstone GC and the
+// integration exports, on cron-style schedules. When several servers share
+// the database each run happens on one of them: the server running a task
+// holds a Postgres advisory lock on it, and the run times of every server
+// line up, so a run another server already made is skipped.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// Task is a recurring piece of work
+type Task struct {
+	// Name identifies the task across servers and in the admin API
+	Name     string
+	Schedule Schedule
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs tasks on their schedules
+type Scheduler struct {
+	tasks []Task
+}
+
+// Add registers a task. Tasks are added before Run.
+func (s *Scheduler) Add(name string, schedule Schedule, run func(ctx context.Context) error) {
+	s.tasks = append(s.tasks, Task{Name: name, Schedule: schedule, Run: run})
+}
+
+// Tasks lists the registered tasks
+func (s *Scheduler) Tasks() []Task {
+	return s.tasks
+}
+
+// Run runs every task at its scheduled times until ctx is cancelled, then
+// waits for the running ones to return
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, task := range s.tasks {
+		wg.Add(1)
+		go func(task Task) {
+			defer wg.Done()
+			s.loop(ctx, task)
+		}(task)
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, task Task) {
+	for {
+		due := task.Schedule.Next(time.Now())
+		if due.IsZero() {
+			slog.Error("Scheduled task never runs", "task", task.Name, "schedule", task.Schedule.String())
+			return
+		}
+		timer := time.NewTimer(time.Until(due))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		ran, err := runOnce(ctx, task, due)
+		switch {
+		case err != nil:
+			slog.Error("Scheduled task failed", "task", task.Name, "error", err)
+		case ran:
+			slog.Debug("Scheduled task ran", "task", task.Name)
+		}
+	}
+}
+
+// runOnce runs the task for its run at due unless another server holds it
+// or already made that run. It reports whether the task ran.
+func runOnce(ctx context.Context, task Task, due time.Time) (bool, error) {
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	// Session advisory locks belong to the connection, which is held until
+	// the task returns
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtext('scheduler:' || $1))`, task.Name).Scan(&locked); err != nil {
+		return false, err
+	}
+	if !locked {
+		return false, nil
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock(hashtext('scheduler:' || $1))`, task.Name)
+
+	claimed, err := conn.Exec(ctx, `
+		INSERT INTO scheduled_tasks (name, last_run_at, started_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (name) DO UPDATE SET last_run_at = EXCLUDED.last_run_at, started_at = EXCLUDED.started_at
+		WHERE scheduled_tasks.last_run_at < EXCLUDED.last_run_at
+	`, task.Name, due)
+	if err != nil {
+		return false, err
+	}
+	if claimed.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	runErr := run(ctx, task)
+	var lastError *string
+	if runErr != nil {
+		message := runErr.Error()
+		lastError = &message
+	}
+	_, err = conn.Exec(context.Background(), `
+		UPDATE scheduled_tasks SET finished_at = CURRENT_TIMESTAMP, last_error = $2 WHERE name = $1
+	`, task.Name, lastError)
+	if runErr != nil {
+		return true, runErr
+	}
+	return true, err
+}
+
+// run runs the task, turning a panic into an error so one failing task
+// does not stop the server
+func run(ctx context.Context, task Task) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return task.Run(ctx)
+}
+
+// Status is the latest run of a task
+type Status struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	// NextRunAt is when this server next runs the task
+	NextRunAt time.Time `json:"next_run_at"`
+	// LastRunAt is the scheduled time of the latest run on any server
+	LastRunAt  *time.Time `json:"last_run_at"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	LastError  *string    `json:"last_error"`
+}
+
+// Statuses returns the latest run of every task
+func (s *Scheduler) Statuses(ctx context.Context) ([]Status, error) {
+	statuses := make([]Status, len(s.tasks))
+	index := make(map[string]int)
+	names := make([]string, len(s.tasks))
+	now := time.Now()
+	for i, task := range s.tasks {
+		statuses[i] = Status{Name: task.Name, Schedule: task.Schedule.String(), NextRunAt: task.Schedule.Next(now).UTC()}
+		index[task.Name] = i
+		names[i] = task.Name
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT name, last_run_at, started_at, finished_at, last_error
+		FROM scheduled_tasks WHERE name = ANY($1)
+	`, names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var status Status
+		if err := rows.Scan(&name, &status.LastRunAt, &status.StartedAt, &status.FinishedAt, &status.LastError); err != nil {
+			return nil, err
+		}
+		i := index[name]
+		statuses[i].LastRunAt, statuses[i].StartedAt = status.LastRunAt, status.StartedAt
+		statuses[i].FinishedAt, statuses[i].LastError = status.FinishedAt, status.LastError
+	}
+	return statuses, rows.Err()
+}
@@ -0,0 +1,193 @@
+// Package seed fills a development database with demo users, projects and
+// randomized session histories, for frontend work and load tests. Data goes
+// through the service repositories, so it is stored the way the API would
+// store it.
+package seed
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/service"
+)
+
+// DeviceID is the device of the seeded projects and sessions
+const DeviceID = "seed"
+
+// sessionBatchSize is how many sessions are stored per transaction
+const sessionBatchSize = 500
+
+// Options sets the volume of seeded data
+type Options struct {
+	// Users is the number of demo users, named demo1@<EmailDomain> and so on
+	Users       int
+	EmailDomain string
+	// Password is the password of every demo user
+	Password string
+	// Projects is the number of projects of each user
+	Projects int
+	// Days is how many days of history each user gets, up to today
+	Days int
+	// SessionsPerDay is the average number of sessions on a working day
+	SessionsPerDay int
+	// Seed makes the generated data reproducible
+	Seed int64
+}
+
+// Result counts the seeded rows
+type Result struct {
+	Users    int
+	Skipped  int
+	Projects int
+	Sessions int
+}
+
+var projectNames = []string{
+	"Website redesign", "Mobile app", "Internal tools", "Marketing site",
+	"API platform", "Customer support", "Data pipeline", "Design system",
+	"Onboarding flow", "Billing", "Documentation", "Infrastructure",
+}
+
+var projectColors = []string{
+	"#EF4444", "#F97316", "#EAB308", "#22C55E", "#14B8A6",
+	"#3B82F6", "#6366F1", "#A855F7", "#EC4899", "#64748B",
+}
+
+var activities = []string{
+	"Implement", "Review", "Fix", "Plan", "Test", "Refactor", "Document", "Discuss", "Design", "Deploy",
+}
+
+var subjects = []string{
+	"login form", "search results", "release notes", "sprint backlog", "payment flow",
+	"dashboard charts", "API errors", "onboarding emails", "settings page", "database indexes",
+	"CI pipeline", "user feedback", "design review", "weekly sync", "performance issues",
+}
+
+// Run creates the demo users with their projects and sessions. Users whose
+// email is taken are skipped, so running it again adds nothing for them.
+func Run(ctx context.Context, opts Options) (Result, error) {
+	rng := rand.New(rand.NewSource(opts.Seed))
+	var result Result
+
+	for i := 1; i <= opts.Users; i++ {
+		email := fmt.Sprintf("demo%d@%s", i, opts.EmailDomain)
+		if _, err := service.Users.GetByEmail(ctx, email); err == nil {
+			slog.Info("Demo user exists, skipping", "email", email)
+			result.Skipped++
+			continue
+		}
+		user, err := service.Users.Create(ctx, email, opts.Password)
+		if err != nil {
+			return result, fmt.Errorf("creating %s: %w", email, err)
+		}
+		result.Users++
+
+		projects, err := seedProjects(ctx, rng, user.ID, opts.Projects)
+		result.Projects += len(projects)
+		if err != nil {
+			return result, fmt.Errorf("creating projects of %s: %w", email, err)
+		}
+
+		n, err := seedSessions(ctx, rng, user.ID, projects, opts)
+		result.Sessions += n
+		if err != nil {
+			return result, fmt.Errorf("creating sessions of %s: %w", email, err)
+		}
+		slog.Info("Seeded demo user", "email", email, "projects", len(projects), "sessions", n)
+	}
+	return result, nil
+}
+
+func seedProjects(ctx context.Context, rng *rand.Rand, userID uuid.UUID, count int) ([]service.Project, error) {
+	var projects []service.Project
+	for i, nameIndex := range rng.Perm(len(projectNames)) {
+		if i == count {
+			break
+		}
+		now := time.Now()
+		project, err := service.Projects.Create(ctx, service.Project{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Name:      projectNames[nameIndex],
+			Color:     projectColors[rng.Intn(len(projectColors))],
+			DeviceID:  DeviceID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return projects, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, nil
+}
+
+// seedSessions stores a history of working days: sessions of 15 minutes to
+// 3 hours between 8:00 and 19:00 UTC with short breaks between them, on
+// the user's projects or none. Weekends are mostly free.
+func seedSessions(ctx context.Context, rng *rand.Rand, userID uuid.UUID, projects []service.Project, opts Options) (int, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	var batch []service.Session
+	stored := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := service.Sessions.Create(ctx, batch, nil); err != nil {
+			return err
+		}
+		stored += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for day := opts.Days - 1; day >= 0; day-- {
+		date := today.AddDate(0, 0, -day)
+		weekend := date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+		if weekend && rng.Intn(5) != 0 {
+			continue
+		}
+
+		count := opts.SessionsPerDay/2 + rng.Intn(opts.SessionsPerDay+1)
+		if weekend {
+			count = 1 + rng.Intn(2)
+		}
+		start := date.Add(8*time.Hour + time.Duration(rng.Intn(90))*time.Minute)
+		end := date.Add(19 * time.Hour)
+		for i := 0; i < count && start.Before(end); i++ {
+			stop := start.Add(time.Duration(15+rng.Intn(166)) * time.Minute)
+			if stop.After(end) {
+				stop = end
+			}
+			// Today's history ends now
+			if stop.After(time.Now()) {
+				break
+			}
+
+			session := service.Session{
+				ID:          uuid.New(),
+				UserID:      userID,
+				StartTime:   start,
+				EndTime:     stop,
+				Description: activities[rng.Intn(len(activities))] + " " + subjects[rng.Intn(len(subjects))],
+				DeviceID:    DeviceID,
+			}
+			if len(projects) > 0 && rng.Intn(10) != 0 {
+				session.ProjectID = &projects[rng.Intn(len(projects))].ID
+			}
+			batch = append(batch, session)
+			if len(batch) == sessionBatchSize {
+				if err := flush(); err != nil {
+					return stored, err
+				}
+			}
+
+			start = stop.Add(time.Duration(rng.Intn(45)) * time.Minute)
+		}
+	}
+	return stored, flush()
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/audit"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+// Register creates a user and returns a token for the device, with the user
+func Register(ctx context.Context, email, password, deviceID string) (string, *models.User, error) {
+	user, err := Users.Create(ctx, email, password)
+	if err != nil {
+		return "", nil, internalError("Failed to create user", err)
+	}
+
+	token, err := auth.GenerateToken(user.ID, user.Email, deviceID)
+	if err != nil {
+		return "", nil, internalError("Failed to generate token", err)
+	}
+	return token, user, nil
+}
+
+// Login checks the user's credentials and returns a token for the device,
+// with the user. Deactivated users must reactivate first. Attempts on an
+// existing account are recorded in its login history, along with the
+// client making them.
+func Login(ctx context.Context, email, password, deviceID string, client audit.Client) (string, *models.User, error) {
+	user, err := Users.GetByEmail(ctx, email)
+	if err != nil {
+		return "", nil, reasonf(Unauthenticated, apierror.InvalidCredentials, "Invalid credentials")
+	}
+
+	// The attempt is recorded even if the client goes away before the answer
+	ctx = context.WithoutCancel(ctx)
+	if !user.ValidatePassword(password) {
+		audit.RecordLogin(ctx, user.ID, deviceID, client, http.StatusUnauthorized)
+		return "", nil, reasonf(Unauthenticated, apierror.InvalidCredentials, "Invalid credentials")
+	}
+	if user.DeactivatedAt != nil {
+		audit.RecordLogin(ctx, user.ID, deviceID, client, http.StatusForbidden)
+		return "", nil, reasonf(PermissionDenied, apierror.AccountDeactivated, "Account is deactivated")
+	}
+
+	token, err := auth.GenerateToken(user.ID, user.Email, deviceID)
+	if err != nil {
+		return "", nil, internalError("Failed to generate token", err)
+	}
+	audit.RecordLogin(ctx, user.ID, deviceID, client, http.StatusOK)
+	return token, user, nil
+}
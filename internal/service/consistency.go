@@ -0,0 +1,362 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/outbox"
+	"github.com/pacerclub/zebra-backend/internal/webhooks"
+)
+
+// Past sync bugs left data the API no longer lets clients write. The
+// consistency check finds it in the user's sessions and plans repairs,
+// which the user applies after reviewing them.
+
+// Kinds of consistency issues
+const (
+	// IssueDeletedProject is a session on a deleted project
+	IssueDeletedProject = "deleted_project"
+	// IssueEndsBeforeStart is a session ending before it starts
+	IssueEndsBeforeStart = "ends_before_start"
+	// IssueOrphanedTombstone is the tags of a deleted session, left behind
+	// after its tombstone was collected
+	IssueOrphanedTombstone = "orphaned_tombstone"
+	// IssueDuplicateID is a session stored more than once under the same
+	// id, each copy written by a different device. The id is only unique
+	// together with the start time, by which sessions are partitioned.
+	IssueDuplicateID = "duplicate_id"
+)
+
+// Repair actions
+const (
+	// RepairClearProject moves a session off its deleted project
+	RepairClearProject = "clear_project"
+	// RepairDeleteSession deletes a session that cannot be fixed, such as a
+	// session on a deleted project of an organization, where every session
+	// needs a project
+	RepairDeleteSession = "delete_session"
+	// RepairSwapTimes swaps the start and end of a session
+	RepairSwapTimes = "swap_times"
+	// RepairRemoveTags removes the tags of a session that no longer exists
+	RepairRemoveTags = "remove_tags"
+	// RepairRemoveCopies keeps the most recently synced copy of a session and
+	// removes the others
+	RepairRemoveCopies = "remove_copies"
+)
+
+// MaxConsistencyIssues bounds the issues of each kind one check reports.
+// Checking again after repairing finds the rest.
+const MaxConsistencyIssues = 500
+
+// MaxRepairs bounds the repairs of a plan, the most one check plans
+const MaxRepairs = 4 * MaxConsistencyIssues
+
+// Repair is a change to one session that fixes an issue
+type Repair struct {
+	Action    string    `json:"action"`
+	SessionID uuid.UUID `json:"session_id"`
+}
+
+// ConsistencyIssue is data breaking an invariant, and its repair
+type ConsistencyIssue struct {
+	Kind      string    `json:"kind"`
+	SessionID uuid.UUID `json:"session_id"`
+	// Repair is nil for issues that are left alone, such as those of
+	// sessions before the organization's lock date
+	Repair *Repair `json:"repair"`
+}
+
+// ConsistencyReport is the issues found in the user's data and the plan
+// repairing them
+type ConsistencyReport struct {
+	Issues []ConsistencyIssue `json:"issues"`
+	Plan   []Repair           `json:"plan"`
+}
+
+// RepairResult is the repairs of a plan that were applied, and those skipped
+// because their issue was gone
+type RepairResult struct {
+	Applied []Repair `json:"applied"`
+	Skipped []Repair `json:"skipped"`
+}
+
+// VerifyUserData checks the user's sessions in the active scope, and the
+// tags left behind by any of their sessions, for invariant violations
+func VerifyUserData(ctx context.Context, userID uuid.UUID) (*ConsistencyReport, error) {
+	tx, err := db.Pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, internalError("Failed to verify data", err)
+	}
+	defer tx.Rollback(ctx)
+
+	report, err := verifyUserData(ctx, tx, userID)
+	if err != nil {
+		return nil, internalError("Failed to verify data", err)
+	}
+	return report, nil
+}
+
+// RepairUserData applies the repairs of plan that the user's data still
+// needs. Repairs are only applied if checking again plans them, so a plan
+// can only fix the issues it was made for.
+func RepairUserData(ctx context.Context, userID uuid.UUID, plan []Repair) (*RepairResult, error) {
+	result := &RepairResult{Applied: []Repair{}, Skipped: []Repair{}}
+	err := pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		report, err := verifyUserData(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		planned := make(map[Repair]bool, len(report.Plan))
+		for _, repair := range report.Plan {
+			planned[repair] = true
+		}
+
+		for _, repair := range plan {
+			if !planned[repair] {
+				result.Skipped = append(result.Skipped, repair)
+				continue
+			}
+			// Once applied, a repair is no longer needed
+			delete(planned, repair)
+			if err := applyRepair(ctx, tx, userID, repair); err != nil {
+				return err
+			}
+			result.Applied = append(result.Applied, repair)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, internalError("Failed to repair data", err)
+	}
+	return result, nil
+}
+
+// verifyUserData runs every check in tx. Copies of a session come first in
+// the plan, so removing them leaves one copy for the other repairs.
+func verifyUserData(ctx context.Context, tx pgx.Tx, userID uuid.UUID) (*ConsistencyReport, error) {
+	orgID := ScopeOrganization(ctx)
+	var lockedBefore *time.Time
+	if orgID != nil {
+		settings, err := models.GetOrganizationSettings(ctx, *orgID)
+		if err != nil {
+			return nil, err
+		}
+		lockedBefore = settings.LockedBefore
+	}
+	locked := func(start time.Time) bool {
+		return lockedBefore != nil && start.Before(*lockedBefore)
+	}
+
+	report := &ConsistencyReport{Issues: []ConsistencyIssue{}, Plan: []Repair{}}
+	add := func(kind string, sessionID uuid.UUID, action string) {
+		issue := ConsistencyIssue{Kind: kind, SessionID: sessionID}
+		if action != "" {
+			issue.Repair = &Repair{Action: action, SessionID: sessionID}
+			report.Plan = append(report.Plan, *issue.Repair)
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+
+	// Copies of one session. A locked copy is left alone with the others.
+	rows, err := tx.Query(ctx, `
+		SELECT id, MIN(start_time), MAX(end_time)
+		FROM timer_sessions
+		WHERE `+SessionScopeSQL(1)+`
+		GROUP BY id
+		HAVING COUNT(*) > 1
+		ORDER BY id
+		LIMIT $3
+	`, userID, orgID, MaxConsistencyIssues)
+	if err != nil {
+		return nil, err
+	}
+	err = forEachSession(rows, func(id uuid.UUID, start, _ time.Time) {
+		if locked(start) {
+			add(IssueDuplicateID, id, "")
+		} else {
+			add(IssueDuplicateID, id, RepairRemoveCopies)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Sessions on deleted projects. Organization sessions cannot go without
+	// a project, so they are deleted instead.
+	rows, err = tx.Query(ctx, `
+		SELECT id, start_time, end_time
+		FROM timer_sessions
+		WHERE `+SessionScopeSQL(1)+` AND is_deleted = false
+			AND project_id IN (SELECT id FROM projects WHERE is_deleted = true)
+		ORDER BY start_time, id
+		LIMIT $3
+	`, userID, orgID, MaxConsistencyIssues)
+	if err != nil {
+		return nil, err
+	}
+	err = forEachSession(rows, func(id uuid.UUID, start, _ time.Time) {
+		switch {
+		case locked(start):
+			add(IssueDeletedProject, id, "")
+		case orgID != nil:
+			add(IssueDeletedProject, id, RepairDeleteSession)
+		default:
+			add(IssueDeletedProject, id, RepairClearProject)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Sessions ending before they start. Swapped, those longer than a
+	// session may be are deleted instead.
+	rows, err = tx.Query(ctx, `
+		SELECT id, start_time, end_time
+		FROM timer_sessions
+		WHERE `+SessionScopeSQL(1)+` AND is_deleted = false AND end_time < start_time
+		ORDER BY start_time, id
+		LIMIT $3
+	`, userID, orgID, MaxConsistencyIssues)
+	if err != nil {
+		return nil, err
+	}
+	err = forEachSession(rows, func(id uuid.UUID, start, end time.Time) {
+		// Swapping makes the end the start
+		switch {
+		case locked(end):
+			add(IssueEndsBeforeStart, id, "")
+		case start.Sub(end) <= MaxSessionDuration:
+			add(IssueEndsBeforeStart, id, RepairSwapTimes)
+		default:
+			add(IssueEndsBeforeStart, id, RepairDeleteSession)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Tags of sessions that no longer exist. Their scope is lost with them.
+	rows, err = tx.Query(ctx, `
+		SELECT DISTINCT session_id
+		FROM session_tags st
+		WHERE user_id = $1 AND NOT EXISTS (SELECT 1 FROM timer_sessions s WHERE s.id = st.session_id)
+		ORDER BY session_id
+		LIMIT $2
+	`, userID, MaxConsistencyIssues)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		add(IssueOrphanedTombstone, id, RepairRemoveTags)
+	}
+	return report, nil
+}
+
+// forEachSession calls fn with the id, start and end time of every row
+func forEachSession(rows pgx.Rows, fn func(id uuid.UUID, start, end time.Time)) error {
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		var start, end time.Time
+		if err := rows.Scan(&id, &start, &end); err != nil {
+			return err
+		}
+		fn(id, start, end)
+	}
+	return rows.Err()
+}
+
+// applyRepair applies one planned repair in tx, recording the changes to
+// sessions in the outbox so devices and webhooks learn of them
+func applyRepair(ctx context.Context, tx pgx.Tx, userID uuid.UUID, repair Repair) error {
+	switch repair.Action {
+	case RepairClearProject:
+		return updateRepairedSession(ctx, tx, userID, `
+			UPDATE timer_sessions SET project_id = NULL
+			WHERE id = $1 AND user_id = $2 AND is_deleted = false
+				AND project_id IN (SELECT id FROM projects WHERE is_deleted = true)
+			RETURNING `+sessionColumns, repair.SessionID)
+
+	case RepairSwapTimes:
+		// Breaks cannot lie within a session ending before it starts
+		return updateRepairedSession(ctx, tx, userID, `
+			UPDATE timer_sessions SET start_time = end_time, end_time = start_time, breaks = '[]'
+			WHERE id = $1 AND user_id = $2 AND is_deleted = false AND end_time < start_time
+			RETURNING `+sessionColumns, repair.SessionID)
+
+	case RepairDeleteSession:
+		result, err := tx.Exec(ctx, `
+			UPDATE timer_sessions SET is_deleted = true
+			WHERE id = $1 AND user_id = $2 AND is_deleted = false
+				AND (end_time < start_time OR project_id IN (SELECT id FROM projects WHERE is_deleted = true))
+		`, repair.SessionID, userID)
+		if err != nil || result.RowsAffected() == 0 {
+			return err
+		}
+		return outbox.Add(ctx, tx, userID, webhooks.EventSessionDeleted, webhooks.DeletedPayload{ID: repair.SessionID, DeletedAt: time.Now()})
+
+	case RepairRemoveTags:
+		_, err := tx.Exec(ctx, `
+			DELETE FROM session_tags st
+			WHERE session_id = $1 AND user_id = $2
+				AND NOT EXISTS (SELECT 1 FROM timer_sessions s WHERE s.id = st.session_id)
+		`, repair.SessionID, userID)
+		return err
+
+	case RepairRemoveCopies:
+		// The copy synced last is the one devices saw last. Touching it makes
+		// every device download it again.
+		_, err := tx.Exec(ctx, `
+			DELETE FROM timer_sessions
+			WHERE id = $1 AND user_id = $2 AND start_time <> (
+				SELECT start_time FROM timer_sessions WHERE id = $1 AND user_id = $2
+				ORDER BY server_updated_at DESC, version DESC
+				LIMIT 1
+			)
+		`, repair.SessionID, userID)
+		if err != nil {
+			return err
+		}
+		session, err := scanSession(tx.QueryRow(ctx, `
+			UPDATE timer_sessions SET updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND user_id = $2
+			RETURNING `+sessionColumns, repair.SessionID, userID))
+		if err != nil {
+			return err
+		}
+		if session.IsDeleted {
+			return nil
+		}
+		return outbox.Add(ctx, tx, userID, webhooks.EventSessionUpdated, session)
+	}
+	return nil
+}
+
+// updateRepairedSession runs a repair updating one session, given its id
+// as $1 and the user as $2, and records the update if there was one
+func updateRepairedSession(ctx context.Context, tx pgx.Tx, userID uuid.UUID, query string, sessionID uuid.UUID) error {
+	rows, err := tx.Query(ctx, query, sessionID, userID)
+	if err != nil {
+		return err
+	}
+	sessions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Session, error) {
+		return scanSession(row)
+	})
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err := outbox.Add(ctx, tx, userID, webhooks.EventSessionUpdated, session); err != nil {
+			return err
+		}
+	}
+	return nil
+}
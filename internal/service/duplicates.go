@@ -0,0 +1,248 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/outbox"
+	"github.com/pacerclub/zebra-backend/internal/webhooks"
+)
+
+// MaxMergeSessions bounds how many sessions one merge removes
+const MaxMergeSessions = 100
+
+// DuplicateGroup is a set of near-identical sessions, typically copies a
+// sync bug created: the same project, overlapping times and similar
+// descriptions
+type DuplicateGroup struct {
+	// Sessions are the duplicates by start time
+	Sessions []Session `json:"sessions"`
+}
+
+// MergeResult is the session duplicates were merged into and the sessions
+// removed
+type MergeResult struct {
+	Session    Session     `json:"session"`
+	RemovedIDs []uuid.UUID `json:"removed_ids"`
+}
+
+// FindDuplicateSessions groups the user's sessions in the active scope
+// starting between start and end that duplicate each other
+func FindDuplicateSessions(ctx context.Context, userID uuid.UUID, start, end time.Time) ([]DuplicateGroup, error) {
+	if !end.After(start) || end.Sub(start) > MaxReportPeriod {
+		return nil, errorf(InvalidArgument, "The period must run forward and cover at most a year")
+	}
+
+	rows, err := db.ReadPool(ctx).Query(ctx, `
+		SELECT `+sessionColumns+`
+		FROM timer_sessions
+		WHERE `+SessionScopeSQL(1)+` AND is_deleted = false AND start_time >= $3 AND start_time < $4
+		ORDER BY start_time, id
+	`, userID, ScopeOrganization(ctx), start, end)
+	if err != nil {
+		return nil, internalError("Failed to fetch sessions", err)
+	}
+	sessions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Session, error) {
+		return scanSession(row)
+	})
+	if err != nil {
+		return nil, internalError("Failed to fetch sessions", err)
+	}
+
+	// Sessions are sorted by start, so each is only compared with the ones
+	// starting before it ends. Duplicates of duplicates join one group.
+	parent := make([]int, len(sessions))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(i int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+	for i, a := range sessions {
+		for j := i + 1; j < len(sessions); j++ {
+			b := sessions[j]
+			if b.StartTime.After(a.EndTime) || (b.StartTime.Equal(a.EndTime) && !a.EndTime.Equal(a.StartTime)) {
+				break
+			}
+			if duplicates(a, b) {
+				parent[root(j)] = root(i)
+			}
+		}
+	}
+
+	byRoot := make(map[int][]Session)
+	var roots []int
+	for i, session := range sessions {
+		r := root(i)
+		if _, ok := byRoot[r]; !ok {
+			roots = append(roots, r)
+		}
+		byRoot[r] = append(byRoot[r], session)
+	}
+	groups := []DuplicateGroup{}
+	for _, r := range roots {
+		if len(byRoot[r]) > 1 {
+			groups = append(groups, DuplicateGroup{Sessions: byRoot[r]})
+		}
+	}
+	return groups, nil
+}
+
+// duplicates reports whether two overlapping sessions look like copies of
+// each other: the same project and similar descriptions
+func duplicates(a, b Session) bool {
+	if (a.ProjectID == nil) != (b.ProjectID == nil) || (a.ProjectID != nil && *a.ProjectID != *b.ProjectID) {
+		return false
+	}
+	if a.KeyID != "" || b.KeyID != "" {
+		// Encrypted descriptions can only be compared as they are
+		return a.KeyID == b.KeyID && bytes.Equal(a.EncryptedDescription, b.EncryptedDescription)
+	}
+	return similarDescriptions(a.Description, b.Description)
+}
+
+// similarDescriptions reports whether two descriptions are the same but for
+// case, spacing and a word or so, or one of them is empty
+func similarDescriptions(a, b string) bool {
+	wordsA := strings.Fields(strings.ToLower(a))
+	wordsB := strings.Fields(strings.ToLower(b))
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return true
+	}
+
+	// Jaccard similarity of the sets of words
+	setA := make(map[string]bool, len(wordsA))
+	for _, w := range wordsA {
+		setA[w] = true
+	}
+	union := len(setA)
+	common := 0
+	seen := make(map[string]bool, len(wordsB))
+	for _, w := range wordsB {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		if setA[w] {
+			common++
+		} else {
+			union++
+		}
+	}
+	return float64(common)/float64(union) >= 0.8
+}
+
+// MergeSessions merges the user's sessions in the active scope into the
+// one with keepID: it is stretched to cover them all and takes their tags,
+// and the others are deleted, leaving tombstones that sync removes from the
+// user's devices. All the sessions must share a project.
+func MergeSessions(ctx context.Context, userID, keepID uuid.UUID, sessionIDs []uuid.UUID) (*MergeResult, error) {
+	ids := []uuid.UUID{keepID}
+	seen := map[uuid.UUID]bool{keepID: true}
+	for _, id := range sessionIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) < 2 {
+		return nil, errorf(InvalidArgument, "Merging needs at least one session besides the one kept")
+	}
+	if len(ids) > MaxMergeSessions+1 {
+		return nil, errorf(InvalidArgument, "Too many sessions to merge at once")
+	}
+
+	result := &MergeResult{RemovedIDs: ids[1:]}
+	err := pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT `+sessionColumns+`
+			FROM timer_sessions
+			WHERE `+SessionScopeSQL(1)+` AND is_deleted = false AND id = ANY($3)
+			FOR UPDATE
+		`, userID, ScopeOrganization(ctx), ids)
+		if err != nil {
+			return err
+		}
+		sessions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Session, error) {
+			return scanSession(row)
+		})
+		if err != nil {
+			return err
+		}
+		if len(sessions) != len(ids) {
+			return errorf(NotFound, "Session not found")
+		}
+		for i := range sessions {
+			if sessions[i].ID == keepID {
+				sessions[0], sessions[i] = sessions[i], sessions[0]
+			}
+		}
+
+		kept := sessions[0]
+		start, end := kept.StartTime, kept.EndTime
+		for _, session := range sessions[1:] {
+			if (session.ProjectID == nil) != (kept.ProjectID == nil) || (session.ProjectID != nil && *session.ProjectID != *kept.ProjectID) {
+				return errorf(InvalidArgument, "Only sessions of the same project can be merged")
+			}
+			if session.StartTime.Before(start) {
+				start = session.StartTime
+			}
+			if session.EndTime.After(end) {
+				end = session.EndTime
+			}
+		}
+		if end.Sub(start) > MaxSessionDuration {
+			return errorf(InvalidArgument, "The merged session would be longer than 7 days")
+		}
+		if err := CheckSessionLock(ctx, userID, uuid.Nil, start); err != nil {
+			return err
+		}
+
+		result.Session, err = scanSession(tx.QueryRow(ctx, `
+			UPDATE timer_sessions SET start_time = $2, end_time = $3
+			WHERE id = $1
+			RETURNING `+sessionColumns,
+			keepID, start, end))
+		if err != nil {
+			return err
+		}
+		if err := outbox.Add(ctx, tx, userID, webhooks.EventSessionUpdated, result.Session); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO session_tags (session_id, tag_id, user_id)
+			SELECT $1, tag_id, user_id FROM session_tags WHERE session_id = ANY($2)
+			ON CONFLICT DO NOTHING
+		`, keepID, result.RemovedIDs)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE timer_sessions SET is_deleted = true WHERE id = ANY($1)`, result.RemovedIDs); err != nil {
+			return err
+		}
+		for _, id := range result.RemovedIDs {
+			payload := webhooks.DeletedPayload{ID: id, DeletedAt: time.Now()}
+			if err := outbox.Add(ctx, tx, userID, webhooks.EventSessionDeleted, payload); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if code := ErrorCode(err); code != Internal {
+			return nil, err
+		}
+		return nil, internalError("Failed to merge sessions", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+// In encrypted storage mode clients encrypt project names and descriptions
+// and session descriptions themselves. The server stores the ciphertext and
+// the id of the client key next to it and never looks inside.
+
+const maxCiphertextBytes = 64 << 10 // 64 KB
+
+// EncryptionError checks a record's encrypted fields against the user's
+// storage mode. It returns the offending field and a message, or an empty
+// message if the record is acceptable.
+func EncryptionError(storageMode, keyID string, hasPlaintext bool, ciphertexts ...[]byte) (string, string) {
+	hasCiphertext := false
+	for _, ciphertext := range ciphertexts {
+		if len(ciphertext) > maxCiphertextBytes {
+			return "", "encrypted field is too large"
+		}
+		if len(ciphertext) > 0 {
+			hasCiphertext = true
+		}
+	}
+
+	switch {
+	case len(keyID) > MaxNameLength:
+		return "key_id", "key_id is too long"
+	case hasCiphertext && keyID == "":
+		return "key_id", "key_id is required for encrypted fields"
+	case keyID != "" && hasPlaintext:
+		return "", "encrypted records must not contain plaintext names or descriptions"
+	case storageMode == models.StorageModeEncrypted && keyID == "":
+		return "key_id", "encrypted storage mode requires client-encrypted fields"
+	}
+	return "", ""
+}
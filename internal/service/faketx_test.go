@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeTx stands in for a transaction in tests of writes made through
+// savepoints. Each statement writes the row named by its first argument,
+// unless the row is one of failing, which fails as a violated constraint
+// would and aborts the transaction until it is rolled back. Savepoints
+// keep their rows until committed into their parent.
+type fakeTx struct {
+	pgx.Tx // the methods left out panic
+	root   *fakeRoot
+	parent *fakeTx
+	rows   []interface{}
+	// aborted is set by a failed statement, as Postgres aborts the
+	// transaction then
+	aborted bool
+	done    bool
+}
+
+// fakeRoot is what the savepoints of a fakeTx share
+type fakeRoot struct {
+	failing map[interface{}]bool
+	// statements, batches and copies count the round trips made
+	statements, batches, copies int
+}
+
+var errFakeConstraint = errors.New("violates a constraint")
+
+func newFakeTx(failing ...interface{}) *fakeTx {
+	root := &fakeRoot{failing: make(map[interface{}]bool)}
+	for _, row := range failing {
+		root.failing[row] = true
+	}
+	return &fakeTx{root: root}
+}
+
+// write stores row, failing for the rows that fail
+func (t *fakeTx) write(row interface{}) error {
+	if t.done {
+		return pgx.ErrTxClosed
+	}
+	if t.aborted {
+		return errors.New("current transaction is aborted")
+	}
+	if t.root.failing[row] {
+		t.aborted = true
+		return fmt.Errorf("row %v %w", row, errFakeConstraint)
+	}
+	t.rows = append(t.rows, row)
+	return nil
+}
+
+func (t *fakeTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	if t.aborted {
+		return nil, errors.New("current transaction is aborted")
+	}
+	return &fakeTx{root: t.root, parent: t}, nil
+}
+
+func (t *fakeTx) Commit(ctx context.Context) error {
+	if t.done {
+		return pgx.ErrTxClosed
+	}
+	t.done = true
+	if t.aborted {
+		if t.parent != nil {
+			t.parent.aborted = true
+		}
+		return pgx.ErrTxCommitRollback
+	}
+	if t.parent != nil {
+		t.parent.rows = append(t.parent.rows, t.rows...)
+	}
+	return nil
+}
+
+func (t *fakeTx) Rollback(ctx context.Context) error {
+	if t.done {
+		return pgx.ErrTxClosed
+	}
+	t.done = true
+	t.rows = nil
+	return nil
+}
+
+func (t *fakeTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	t.root.statements++
+	if err := t.write(args[0]); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (t *fakeTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	t.root.batches++
+	for _, query := range b.QueuedQueries {
+		if err := t.write(query.Arguments[0]); err != nil {
+			return fakeBatchResults{err: err}
+		}
+	}
+	return fakeBatchResults{}
+}
+
+func (t *fakeTx) CopyFrom(ctx context.Context, table pgx.Identifier, columns []string, src pgx.CopyFromSource) (int64, error) {
+	t.root.copies++
+	var n int64
+	for src.Next() {
+		values, err := src.Values()
+		if err != nil {
+			return 0, err
+		}
+		if err := t.write(values[0]); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, src.Err()
+}
+
+// fakeBatchResults reports the outcome of a batch on Close, as the batch
+// writer reads it
+type fakeBatchResults struct {
+	pgx.BatchResults
+	err error
+}
+
+func (r fakeBatchResults) Close() error {
+	return r.err
+}
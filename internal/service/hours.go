@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+// MaxReportPeriod bounds the span of one report
+const MaxReportPeriod = 366 * 24 * time.Hour
+
+// DayHours compares the time tracked on a day with the time expected
+type DayHours struct {
+	// Date is the day as YYYY-MM-DD in the report's time zone
+	Date            string `json:"date"`
+	TrackedSeconds  int64  `json:"tracked_seconds"`
+	ExpectedSeconds int64  `json:"expected_seconds"`
+	// OvertimeSeconds is the time tracked beyond the expected time
+	OvertimeSeconds int64 `json:"overtime_seconds"`
+	// TimeOff is the type of time off taken on the day, if any; no time is
+	// expected then
+	TimeOff string `json:"time_off,omitempty"`
+}
+
+// HoursReport compares the time a user tracked with their working hours,
+// day by day
+type HoursReport struct {
+	Days            []DayHours `json:"days"`
+	TrackedSeconds  int64      `json:"tracked_seconds"`
+	ExpectedSeconds int64      `json:"expected_seconds"`
+	OvertimeSeconds int64      `json:"overtime_seconds"`
+	// BalanceSeconds is the tracked time less the expected time, negative
+	// when short of it
+	BalanceSeconds int64 `json:"balance_seconds"`
+}
+
+// GetHoursReport compares the time the user tracked in the active scope
+// with their working hours on each day from start up to end, midnights in
+// the report's time zone. Sessions count on the day they start, rounded
+// when the scope's rounding applies to reports. Days off expect no time.
+func GetHoursReport(ctx context.Context, userID uuid.UUID, start, end time.Time) (*HoursReport, error) {
+	if !end.After(start) || end.Sub(start) > MaxReportPeriod {
+		return nil, errorf(InvalidArgument, "The period must run forward and cover at most a year")
+	}
+	preferences, err := models.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, internalError("Failed to fetch preferences", err)
+	}
+	rounding, err := models.GetRounding(ctx, userID, ScopeOrganization(ctx), models.RoundReports)
+	if err != nil {
+		return nil, internalError("Failed to fetch rounding settings", err)
+	}
+	daysOff, err := models.GetDaysOff(ctx, userID, start, end)
+	if err != nil {
+		return nil, internalError("Failed to fetch time off", err)
+	}
+
+	rows, err := db.ReadPool(ctx).Query(ctx, `
+		SELECT to_char(start_time AT TIME ZONE $5, 'YYYY-MM-DD'),
+			SUM(`+rounding.SecondsSQL(models.NetSecondsSQL("timer_sessions"))+`)::BIGINT
+		FROM timer_sessions
+		WHERE `+SessionScopeSQL(1)+` AND is_deleted = false AND start_time >= $3 AND start_time < $4
+		GROUP BY 1
+	`, userID, ScopeOrganization(ctx), start, end, start.Location().String())
+	if err != nil {
+		return nil, internalError("Failed to fetch sessions", err)
+	}
+	defer rows.Close()
+	tracked := make(map[string]int64)
+	for rows.Next() {
+		var date string
+		var seconds int64
+		if err := rows.Scan(&date, &seconds); err != nil {
+			return nil, internalError("Failed to fetch sessions", err)
+		}
+		tracked[date] = seconds
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError("Failed to fetch sessions", err)
+	}
+
+	report := &HoursReport{Days: []DayHours{}}
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		d := DayHours{
+			Date:           date,
+			TrackedSeconds: tracked[date],
+			TimeOff:        daysOff[date],
+		}
+		if d.TimeOff == "" {
+			d.ExpectedSeconds = int64(preferences.WorkingHours.Expected(day.Weekday()) / time.Second)
+		}
+		if d.TrackedSeconds > d.ExpectedSeconds {
+			d.OvertimeSeconds = d.TrackedSeconds - d.ExpectedSeconds
+		}
+		report.Days = append(report.Days, d)
+		report.TrackedSeconds += d.TrackedSeconds
+		report.ExpectedSeconds += d.ExpectedSeconds
+		report.OvertimeSeconds += d.OvertimeSeconds
+	}
+	report.BalanceSeconds = report.TrackedSeconds - report.ExpectedSeconds
+	return report, nil
+}
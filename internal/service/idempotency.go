@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// MaxIdempotencyKeyLength bounds the keys clients may choose
+const MaxIdempotencyKeyLength = 255
+
+// syncFingerprint identifies a sync batch by its content, so that a key
+// reused for a different batch can be told from a retry. Retries decode to
+// the same request whichever encoding they are sent in.
+func syncFingerprint(req *SyncRequest) ([]byte, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	return sum[:], nil
+}
+
+// claimIdempotencyKey reserves the key for the user's batch with
+// fingerprint inside tx. It returns false if the key was already used by a
+// previous (committed) request. A concurrent request with the same key
+// blocks here until the first one commits or rolls back.
+func claimIdempotencyKey(ctx context.Context, tx pgx.Tx, userID uuid.UUID, key string, fingerprint []byte) (bool, error) {
+	// Keys only need to outlive a client's retry window
+	_, err := tx.Exec(ctx, `
+		DELETE FROM sync_idempotency_keys
+		WHERE user_id = $1 AND created_at < CURRENT_TIMESTAMP - INTERVAL '24 hours'
+	`, userID)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.Exec(ctx, `
+		INSERT INTO sync_idempotency_keys (user_id, idempotency_key, fingerprint)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, idempotency_key) DO NOTHING
+	`, userID, key, fingerprint)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// loadIdempotentResponse returns the fingerprint of the batch a previously
+// processed key was used for, nil for keys stored before fingerprints, and
+// the content type and body of its response
+func loadIdempotentResponse(ctx context.Context, tx pgx.Tx, userID uuid.UUID, key string) ([]byte, string, []byte, error) {
+	var fingerprint []byte
+	var contentType *string
+	var body []byte
+	err := tx.QueryRow(ctx, `
+		SELECT fingerprint, content_type, response_body
+		FROM sync_idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2
+	`, userID, key).Scan(&fingerprint, &contentType, &body)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if contentType == nil {
+		return fingerprint, ContentTypeJSON, body, nil
+	}
+	return fingerprint, *contentType, body, nil
+}
+
+// saveIdempotentResponse stores the response for a claimed key so that
+// retries can be answered without re-applying the request
+func saveIdempotentResponse(ctx context.Context, tx pgx.Tx, userID uuid.UUID, key string, contentType string, body []byte) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE sync_idempotency_keys
+		SET status_code = 200, content_type = $1, response_body = $2
+		WHERE user_id = $3 AND idempotency_key = $4
+	`, contentType, body, userID, key)
+	return err
+}
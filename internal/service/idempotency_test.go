@@ -0,0 +1,142 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/dbtest"
+)
+
+// testSyncRequest is a batch uploading one session
+func testSyncRequest(sessionID uuid.UUID, description string) *SyncRequest {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	return &SyncRequest{
+		DeviceID: "laptop",
+		LocalSessions: []Session{{
+			ID:          sessionID,
+			StartTime:   start,
+			EndTime:     start.Add(time.Hour),
+			Description: description,
+		}},
+	}
+}
+
+func TestSyncFingerprint(t *testing.T) {
+	id := uuid.New()
+	first, err := syncFingerprint(testSyncRequest(id, "Writing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry, err := syncFingerprint(testSyncRequest(id, "Writing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, retry) {
+		t.Error("a retry of the same batch has a different fingerprint")
+	}
+
+	other, err := syncFingerprint(testSyncRequest(id, "Reading"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first, other) {
+		t.Error("a different batch has the same fingerprint")
+	}
+}
+
+func TestSyncReplaysRetries(t *testing.T) {
+	dbtest.Open(t)
+	ctx := context.Background()
+	userID := dbtest.User(t)
+	opts := SyncOptions{IdempotencyKey: uuid.NewString()}
+	id := uuid.New()
+
+	first, err := Sync(ctx, userID, testSyncRequest(id, "Writing"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Replayed {
+		t.Fatal("the first request was replayed")
+	}
+
+	retry, err := Sync(ctx, userID, testSyncRequest(id, "Writing"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !retry.Replayed {
+		t.Error("the retry was applied again instead of replayed")
+	}
+	if !bytes.Equal(retry.Body, first.Body) {
+		t.Errorf("the retry got %s, want the first response %s", retry.Body, first.Body)
+	}
+}
+
+func TestSyncRefusesReusedKeys(t *testing.T) {
+	dbtest.Open(t)
+	ctx := context.Background()
+	userID := dbtest.User(t)
+	opts := SyncOptions{IdempotencyKey: uuid.NewString()}
+
+	if _, err := Sync(ctx, userID, testSyncRequest(uuid.New(), "Writing"), opts); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Sync(ctx, userID, testSyncRequest(uuid.New(), "Reading"), opts)
+	if ErrorCode(err) != Conflict || ErrorReason(err) != apierror.IdempotencyKeyReused {
+		t.Fatalf("reusing the key for another batch got %v, want %s", err, apierror.IdempotencyKeyReused)
+	}
+
+	var count int
+	err = db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM timer_sessions WHERE user_id = $1`, userID).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("the user has %d sessions, want only the first batch's", count)
+	}
+}
+
+func TestClaimIdempotencyKeyPrunesExpiredKeys(t *testing.T) {
+	dbtest.Open(t)
+	ctx := context.Background()
+	userID := dbtest.User(t)
+
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO sync_idempotency_keys (user_id, idempotency_key, status_code, created_at)
+		VALUES ($1, 'expired', 200, CURRENT_TIMESTAMP - INTERVAL '25 hours'),
+			($1, 'recent', 200, CURRENT_TIMESTAMP - INTERVAL '23 hours')
+	`, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+	for key, want := range map[string]bool{"new": true, "recent": false} {
+		claimed, err := claimIdempotencyKey(ctx, tx, userID, key, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claimed != want {
+			t.Errorf("claiming %q returned %v, want %v", key, claimed, want)
+		}
+	}
+
+	var expired bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM sync_idempotency_keys WHERE user_id = $1 AND idempotency_key = 'expired')
+	`, userID).Scan(&expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired {
+		t.Error("a key older than 24 hours was kept")
+	}
+}
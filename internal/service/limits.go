@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/billing"
+)
+
+// limitError reports a plan limit reached. The user can lift it by
+// upgrading, or in an organization they do not own ask its owners to.
+func limitError(err *billing.LimitError) error {
+	if err.CanUpgrade {
+		return &Error{Code: PaymentRequired, Reason: apierror.UpgradeRequired, Message: err.Message(), Err: err}
+	}
+	return &Error{Code: PermissionDenied, Reason: apierror.OwnerUpgradeRequired, Message: err.Message(), Err: err}
+}
+
+// checkProjectLimit fails if adding a project to the scope of orgID would
+// take it over the project limit of its plan
+func checkProjectLimit(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) error {
+	ws, err := billing.WorkspaceOf(ctx, userID, orgID)
+	if err != nil {
+		return internalError("Failed to check plan limits", err)
+	}
+	if ws.Limits.MaxProjects == 0 {
+		return nil
+	}
+	count, err := Projects.Count(ctx, userID, orgID)
+	if err != nil {
+		return internalError("Failed to check plan limits", err)
+	}
+	var limit *billing.LimitError
+	if errors.As(ws.CheckCount(billing.LimitProjects, count+1), &limit) {
+		return limitError(limit)
+	}
+	return nil
+}
+
+// projectQuota counts the projects one sync creates against the project
+// limit of each workspace's plan
+type projectQuota struct {
+	userID uuid.UUID
+	// left is how many more projects each workspace may have, by
+	// organization and uuid.Nil for the personal workspace; -1 is unlimited
+	left map[uuid.UUID]int
+}
+
+func newProjectQuota(userID uuid.UUID) *projectQuota {
+	return &projectQuota{userID: userID, left: make(map[uuid.UUID]int)}
+}
+
+// take reports whether one more project fits in the scope of orgID, and
+// if so counts it
+func (q *projectQuota) take(ctx context.Context, orgID *uuid.UUID) (bool, error) {
+	key := uuid.Nil
+	if orgID != nil {
+		key = *orgID
+	}
+	left, ok := q.left[key]
+	if !ok {
+		ws, err := billing.WorkspaceOf(ctx, q.userID, orgID)
+		if err != nil {
+			return false, err
+		}
+		left = -1
+		if limit := ws.Limits.MaxProjects; limit > 0 {
+			count, err := Projects.Count(ctx, q.userID, orgID)
+			if err != nil {
+				return false, err
+			}
+			left = max(limit-count, 0)
+		}
+	}
+	if left == 0 {
+		q.left[key] = 0
+		return false, nil
+	}
+	if left > 0 {
+		left--
+	}
+	q.left[key] = left
+	return true, nil
+}
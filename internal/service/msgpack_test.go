@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSessionBreaksRoundTripOverMsgpack(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		breaks []Break
+	}{
+		{"left out", nil},
+		{"cleared", []Break{}},
+		{"one break", []Break{{StartTime: start.Add(10 * time.Minute), EndTime: start.Add(20 * time.Minute)}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sent := SyncRequest{DeviceID: "phone", LocalSessions: []Session{{
+				ID:        uuid.New(),
+				StartTime: start,
+				EndTime:   start.Add(time.Hour),
+				Breaks:    test.breaks,
+			}}}
+			body, err := MarshalBody(ContentTypeMsgpack, &sent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var received SyncRequest
+			if err := unmarshalBody(ContentTypeMsgpack, body, &received); err != nil {
+				t.Fatal(err)
+			}
+
+			breaks := received.LocalSessions[0].Breaks
+			if (breaks == nil) != (test.breaks == nil) || len(breaks) != len(test.breaks) {
+				t.Fatalf("decoded breaks %#v, want %#v", breaks, test.breaks)
+			}
+			for i, b := range breaks {
+				if !b.StartTime.Equal(test.breaks[i].StartTime) || !b.EndTime.Equal(test.breaks[i].EndTime) {
+					t.Errorf("decoded break %d as %v, want %v", i, b, test.breaks[i])
+				}
+			}
+
+			// A nil list keeps the stored breaks, an empty one clears them
+			stored, err := received.LocalSessions[0].breaksJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (stored == nil) != (test.breaks == nil) || (len(test.breaks) == 0 && stored != nil && string(stored) != "[]") {
+				t.Errorf("stored breaks %q for %#v", stored, test.breaks)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+	"github.com/pacerclub/zebra-backend/internal/pagination"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+//msgp:tag json
+//msgp:newtime
+//msgp:shim uuid.UUID as:[]byte using:uuidToBytes/uuidFromBytes
+
+type Project struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Color       string    `json:"color"`
+	// EncryptedName, EncryptedDescription and KeyID are set instead of Name
+	// and Description for users in encrypted storage mode
+	EncryptedName        []byte `json:"encrypted_name,omitempty"`
+	EncryptedDescription []byte `json:"encrypted_description,omitempty"`
+	KeyID                string `json:"key_id,omitempty"`
+	// OrganizationID is set for projects shared with an organization
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	DeviceID       string     `json:"device_id"`
+	IsDeleted      bool       `json:"is_deleted"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	// Version counts the changes to the project, as Session.Version
+	Version int64 `json:"version"`
+	// IconURL is set by uploading an icon, not by clients directly
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+// Validate checks the fields clients send. Encrypted projects carry
+// encrypted_name instead of a name.
+func (p Project) Validate(v *validate.Validator) {
+	if p.KeyID != "" {
+		v.Check(len(p.EncryptedName) > 0, "encrypted_name", "is required")
+	} else {
+		v.Required("name", p.Name)
+	}
+	v.MaxLength("name", p.Name, MaxNameLength)
+	v.MaxLength("description", p.Description, MaxDescriptionLength)
+	v.Color("color", p.Color)
+	v.MaxBytes("key_id", p.KeyID, MaxNameLength)
+	v.MaxBytes("device_id", p.DeviceID, maxDeviceIDLength)
+}
+
+// ProjectList is how project lists can be sorted, by default newest first
+var ProjectList = listquery.List[Project]{
+	Sorts: map[string]listquery.Column[Project]{
+		"created_at": {SQL: "created_at", Key: func(p Project) time.Time { return p.CreatedAt }},
+		"updated_at": {SQL: "updated_at", Key: func(p Project) time.Time { return p.UpdatedAt }},
+	},
+	Default: listquery.Sort{Field: "created_at", Desc: true},
+	RowID:   func(p Project) uuid.UUID { return p.ID },
+}
+
+// ListProjects returns a page of the projects of the active scope
+func ListProjects(ctx context.Context, userID uuid.UUID, q listquery.Query) (pagination.Page[Project], error) {
+	projects, err := Projects.List(ctx, userID, ScopeOrganization(ctx), q)
+	if err != nil {
+		return pagination.Page[Project]{}, internalError("Failed to fetch projects", err)
+	}
+	return ProjectList.Page(projects, q), nil
+}
+
+// SearchProjects returns up to limit of the projects of the active scope
+// whose name matches text, as parsed by db.TSQuery, best matches first
+func SearchProjects(ctx context.Context, userID uuid.UUID, text string, limit int) ([]Project, error) {
+	// Sealed values are kept out of the search index
+	if fieldcrypt.Enabled() {
+		return nil, errorf(Unimplemented, "Search is not available while field encryption is enabled")
+	}
+	tsquery := db.TSQuery(text)
+	if tsquery == "" {
+		return nil, errorf(InvalidArgument, "Search text is required")
+	}
+	projects, err := Projects.Search(ctx, userID, ScopeOrganization(ctx), tsquery, limit)
+	if err != nil {
+		return nil, internalError("Failed to search projects", err)
+	}
+	return projects, nil
+}
+
+// CreateProject stores a new project in the active scope
+func CreateProject(ctx context.Context, userID uuid.UUID, project Project) (Project, error) {
+	project.UserID = userID
+	project.ID = uuid.New()
+	project.OrganizationID = ScopeOrganization(ctx)
+
+	if err := validate.Struct(project); err != nil {
+		return Project{}, invalidFields(err)
+	}
+	if err := CheckProjectEncryption(ctx, userID, project); err != nil {
+		return Project{}, err
+	}
+	if err := checkProjectLimit(ctx, userID, project.OrganizationID); err != nil {
+		return Project{}, err
+	}
+
+	project.CreatedAt = time.Now()
+	project.UpdatedAt = time.Now()
+
+	project, err := Projects.Create(ctx, project)
+	if err != nil {
+		return Project{}, internalError("Failed to create project", err)
+	}
+	return project, nil
+}
+
+// UpdateProject replaces the editable fields of a project in the active
+// scope, if project.Version is still its current version
+func UpdateProject(ctx context.Context, userID, projectID uuid.UUID, project Project) (Project, error) {
+	if err := validate.Struct(project); err != nil {
+		return Project{}, invalidFields(err)
+	}
+	if err := checkVersion(project.Version); err != nil {
+		return Project{}, err
+	}
+	if err := CheckProjectEncryption(ctx, userID, project); err != nil {
+		return Project{}, err
+	}
+
+	project.UpdatedAt = time.Now()
+
+	project, err := Projects.Update(ctx, userID, ScopeOrganization(ctx), projectID, project)
+	if code := ErrorCode(err); code == NotFound || code == Conflict {
+		return Project{}, err
+	}
+	if err != nil {
+		return Project{}, internalError("Failed to update project", err)
+	}
+	return project, nil
+}
+
+// DeleteProject marks a project in the active scope deleted
+func DeleteProject(ctx context.Context, userID, projectID uuid.UUID) error {
+	deleted, err := Projects.Delete(ctx, userID, ScopeOrganization(ctx), projectID)
+	if err != nil {
+		return internalError("Failed to delete project", err)
+	}
+	if !deleted {
+		return errorf(NotFound, "Project not found")
+	}
+	return nil
+}
+
+// SetProjectIcon sets the icon of a project on behalf of userID, who was
+// allowed to change it when uploading the icon
+func SetProjectIcon(ctx context.Context, userID, projectID uuid.UUID, iconURL string) error {
+	found, err := Projects.SetIcon(ctx, userID, projectID, iconURL)
+	if err != nil {
+		return internalError("Failed to update project", err)
+	}
+	if !found {
+		return errorf(NotFound, "Project not found")
+	}
+	return nil
+}
+
+// CheckProjectEncryption rejects projects that do not match the user's
+// storage mode
+func CheckProjectEncryption(ctx context.Context, userID uuid.UUID, project Project) error {
+	mode, err := Users.StorageMode(ctx, userID)
+	if err != nil {
+		return internalError("Failed to fetch storage mode", err)
+	}
+	hasPlaintext := project.Name != "" || project.Description != ""
+	if _, message := EncryptionError(mode, project.KeyID, hasPlaintext, project.EncryptedName, project.EncryptedDescription); message != "" {
+		return reasonf(InvalidArgument, apierror.StorageModeMismatch, "%s", message)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+	"github.com/pacerclub/zebra-backend/internal/outbox"
+	"github.com/pacerclub/zebra-backend/internal/webhooks"
+)
+
+const projectColumns = "id, user_id, name, description, color, encrypted_name, encrypted_description, key_id, organization_id, device_id, is_deleted, created_at, updated_at, version, icon_url"
+
+func scanProject(row pgx.Row) (Project, error) {
+	var project Project
+	err := row.Scan(
+		&project.ID,
+		&project.UserID,
+		&project.Name,
+		&project.Description,
+		&project.Color,
+		&project.EncryptedName,
+		&project.EncryptedDescription,
+		&project.KeyID,
+		&project.OrganizationID,
+		&project.DeviceID,
+		&project.IsDeleted,
+		&project.CreatedAt,
+		&project.UpdatedAt,
+		&project.Version,
+		&project.IconURL,
+	)
+	if err != nil {
+		return project, err
+	}
+	project.Name, err = fieldcrypt.Open(fieldcrypt.ProjectName, project.Name)
+	return project, err
+}
+
+// pgProjects stores projects in the projects table, with names sealed by
+// fieldcrypt. Changes record their webhook events in the outbox in the same
+// transaction.
+type pgProjects struct{}
+
+// Queries, checked against the schema by db.CheckStatements. The list query
+// is checked in its default order.
+var (
+	insertProjectSQL = db.Statement("insert_project", `
+		INSERT INTO projects (id, user_id, name, description, color, encrypted_name, encrypted_description, key_id, organization_id, device_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING `+projectColumns)
+	updateProjectSQL = db.Statement("update_project", `
+		UPDATE projects
+		SET name = $1, description = $2, color = $3, encrypted_name = $4,
+			encrypted_description = $5, key_id = $6, updated_at = $7
+		WHERE id = $8 AND `+ProjectScopeSQL(9)+` AND version = $11
+		RETURNING `+projectColumns)
+	getProjectSQL = db.Statement("get_project", `
+		SELECT `+projectColumns+`
+		FROM projects
+		WHERE id = $1 AND `+ProjectScopeSQL(2))
+	deleteProjectSQL = db.Statement("delete_project", `
+		UPDATE projects
+		SET is_deleted = true
+		WHERE id = $1 AND `+ProjectScopeSQL(2))
+	searchProjectsSQL = db.Statement("search_projects", `
+		SELECT `+projectColumns+`
+		FROM projects
+		WHERE `+ProjectScopeSQL(1)+` AND is_deleted = false
+			AND name_tsv @@ to_tsquery('`+db.SearchConfig+`', $3)
+		ORDER BY ts_rank(name_tsv, to_tsquery('`+db.SearchConfig+`', $3)) DESC, created_at DESC
+		LIMIT $4`)
+	setProjectIconSQL = db.Statement("set_project_icon", `
+		UPDATE projects
+		SET icon_url = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND is_deleted = false
+		RETURNING `+projectColumns)
+	projectInScopeSQL = db.Statement("project_in_scope",
+		`SELECT EXISTS (SELECT 1 FROM projects WHERE id = $3 AND is_deleted = false AND `+ProjectScopeSQL(1)+`)`)
+	countProjectsSQL = db.Statement("count_projects",
+		`SELECT COUNT(*) FROM projects WHERE is_deleted = false AND `+ProjectScopeSQL(1))
+
+	_ = db.Statement("list_projects", listProjectsSQL(listquery.Query{Sort: ProjectList.Default}))
+)
+
+// listProjectsSQL selects a page of the projects in scope. The arguments
+// are the user, the organization, q.Args() and q.Page.Fetch().
+func listProjectsSQL(q listquery.Query) string {
+	where, orderBy := ProjectList.SQL(q, 3)
+	return `
+		SELECT ` + projectColumns + `
+		FROM projects
+		WHERE ` + ProjectScopeSQL(1) + ` AND is_deleted = false AND ` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT $5
+	`
+}
+
+func (pgProjects) List(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, q listquery.Query) ([]Project, error) {
+	args := append([]interface{}{userID, orgID}, q.Args()...)
+	return queryProjects(ctx, listProjectsSQL(q), append(args, q.Page.Fetch())...)
+}
+
+func (pgProjects) Search(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, tsquery string, limit int) ([]Project, error) {
+	return queryProjects(ctx, searchProjectsSQL, userID, orgID, tsquery, limit)
+}
+
+func queryProjects(ctx context.Context, query string, args ...interface{}) ([]Project, error) {
+	rows, err := db.ReadPool(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []Project
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+func (pgProjects) Create(ctx context.Context, project Project) (Project, error) {
+	name, err := fieldcrypt.Seal(fieldcrypt.ProjectName, project.Name)
+	if err != nil {
+		return Project{}, err
+	}
+	err = pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		var err error
+		project, err = scanProject(tx.QueryRow(ctx, insertProjectSQL,
+			project.ID,
+			project.UserID,
+			name,
+			project.Description,
+			project.Color,
+			project.EncryptedName,
+			project.EncryptedDescription,
+			project.KeyID,
+			project.OrganizationID,
+			project.DeviceID,
+			project.CreatedAt,
+			project.UpdatedAt,
+		))
+		if err != nil {
+			return err
+		}
+		return outbox.Add(ctx, tx, project.UserID, webhooks.EventProjectCreated, project)
+	})
+	return project, err
+}
+
+func (pgProjects) Update(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, projectID uuid.UUID, project Project) (Project, error) {
+	name, err := fieldcrypt.Seal(fieldcrypt.ProjectName, project.Name)
+	if err != nil {
+		return Project{}, err
+	}
+	var updated Project
+	err = pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		var err error
+		updated, err = scanProject(tx.QueryRow(ctx, updateProjectSQL,
+			name,
+			project.Description,
+			project.Color,
+			project.EncryptedName,
+			project.EncryptedDescription,
+			project.KeyID,
+			project.UpdatedAt,
+			projectID,
+			userID,
+			orgID,
+			project.Version,
+		))
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Either there is no such project or it has moved on
+			current, err := scanProject(tx.QueryRow(ctx, getProjectSQL, projectID, userID, orgID))
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errorf(NotFound, "Project not found")
+			}
+			if err != nil {
+				return err
+			}
+			return conflictf(current, "Project was changed since version %d", project.Version)
+		}
+		if err != nil {
+			return err
+		}
+		return outbox.Add(ctx, tx, userID, webhooks.EventProjectUpdated, updated)
+	})
+	return updated, err
+}
+
+func (pgProjects) Delete(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, projectID uuid.UUID) (bool, error) {
+	var deleted bool
+	err := pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, deleteProjectSQL, projectID, userID, orgID)
+		if err != nil {
+			return err
+		}
+		deleted = result.RowsAffected() > 0
+		if !deleted {
+			return nil
+		}
+		return outbox.Add(ctx, tx, userID, webhooks.EventProjectDeleted, webhooks.DeletedPayload{ID: projectID, DeletedAt: time.Now()})
+	})
+	return deleted, err
+}
+
+func (pgProjects) SetIcon(ctx context.Context, userID, projectID uuid.UUID, iconURL string) (bool, error) {
+	var found bool
+	err := pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		updated, err := scanProject(tx.QueryRow(ctx, setProjectIconSQL, projectID, iconURL))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		return outbox.Add(ctx, tx, userID, webhooks.EventProjectUpdated, updated)
+	})
+	return found, err
+}
+
+func (pgProjects) InScope(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, projectID uuid.UUID) (bool, error) {
+	var exists bool
+	err := db.Pool.QueryRow(ctx, projectInScopeSQL, userID, orgID, projectID).Scan(&exists)
+	return exists, err
+}
+
+func (pgProjects) Count(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (int, error) {
+	var n int
+	err := db.Pool.QueryRow(ctx, countProjectsSQL, userID, orgID).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/rules"
+)
+
+// The repositories below store sessions, projects, users and tracking
+// rules, and apply sync batches. The service functions keep the business
+// rules (validation, scoping, locking and encryption checks) and leave the
+// storage to them, so another database driver, or the fakes of package
+// memstore, can replace them. The session and project repositories also
+// record the webhook event of each change, in the same transaction as the
+// change. orgID is the active organization as returned by
+// ScopeOrganization, nil in personal scope.
+var (
+	Sessions SessionRepo = pgSessions{}
+	Projects ProjectRepo = pgProjects{}
+	Users    UserRepo    = pgUsers{}
+	Rules    RuleRepo    = pgRules{}
+	Syncs    SyncRepo    = pgSyncs{}
+)
+
+// Repositories is the storage of the service
+type Repositories struct {
+	Sessions SessionRepo
+	Projects ProjectRepo
+	Users    UserRepo
+	Rules    RuleRepo
+	Syncs    SyncRepo
+}
+
+// Use makes the service keep its data in repos, until the returned
+// function restores the repositories it replaced
+func Use(repos Repositories) (restore func()) {
+	previous := Repositories{Sessions: Sessions, Projects: Projects, Users: Users, Rules: Rules, Syncs: Syncs}
+	Sessions, Projects, Users, Rules, Syncs = repos.Sessions, repos.Projects, repos.Users, repos.Rules, repos.Syncs
+	return func() {
+		Sessions, Projects, Users, Rules, Syncs = previous.Sessions, previous.Projects, previous.Users, previous.Rules, previous.Syncs
+	}
+}
+
+// SessionRepo stores timer sessions
+type SessionRepo interface {
+	// List returns the user's live sessions in the scope of orgID, sorted
+	// and paged by q
+	List(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, q listquery.Query) ([]Session, error)
+	// ListOrganization returns the live sessions of every member on the
+	// organization's projects
+	ListOrganization(ctx context.Context, orgID uuid.UUID, q listquery.Query) ([]Session, error)
+	// Search returns up to limit of the user's live sessions in scope whose
+	// description matches tsquery, as built by db.TSQuery, best first
+	Search(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, tsquery string, limit int) ([]Session, error)
+	// Create stores sessions, all or none, marking the calendar suggestion
+	// each came from confirmed. suggestionIDs is nil or has one entry per
+	// session, nil for sessions without a suggestion.
+	Create(ctx context.Context, sessions []Session, suggestionIDs []*uuid.UUID) ([]Session, error)
+	// Update replaces the editable fields of a session in scope if it is
+	// still at session.Version. It returns a NotFound error if there is
+	// none, and a Conflict error carrying the stored session if its version
+	// differs.
+	Update(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, sessionID uuid.UUID, session Session) (Session, error)
+	// Delete marks a session in scope deleted, reporting whether there was
+	// one
+	Delete(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, sessionID uuid.UUID) (bool, error)
+	// StartTime returns the stored start time of one of the user's
+	// sessions, nil if there is none
+	StartTime(ctx context.Context, userID, sessionID uuid.UUID) (*time.Time, error)
+}
+
+// ProjectRepo stores projects
+type ProjectRepo interface {
+	// List returns the live projects in the scope of orgID, sorted and
+	// paged by q
+	List(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, q listquery.Query) ([]Project, error)
+	// Search returns up to limit of the live projects in scope whose name
+	// matches tsquery, as built by db.TSQuery, best first
+	Search(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, tsquery string, limit int) ([]Project, error)
+	Create(ctx context.Context, project Project) (Project, error)
+	// Update replaces the editable fields of a project in scope if it is
+	// still at project.Version. It returns a NotFound error if there is
+	// none, and a Conflict error carrying the stored project if its version
+	// differs.
+	Update(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, projectID uuid.UUID, project Project) (Project, error)
+	// Delete marks a project in scope deleted, reporting whether there was
+	// one
+	Delete(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, projectID uuid.UUID) (bool, error)
+	// SetIcon sets the icon of a live project, changed by userID,
+	// reporting whether there was one
+	SetIcon(ctx context.Context, userID, projectID uuid.UUID, iconURL string) (bool, error)
+	// InScope reports whether a live project is in the scope of orgID
+	InScope(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, projectID uuid.UUID) (bool, error)
+	// Count returns how many live projects are in the scope of orgID
+	Count(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (int, error)
+}
+
+// UserRepo stores user accounts
+type UserRepo interface {
+	// Create stores a user with the bcrypt hash of password
+	Create(ctx context.Context, email, password string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	StorageMode(ctx context.Context, userID uuid.UUID) (string, error)
+}
+
+// RuleRepo stores tracking rules
+type RuleRepo interface {
+	// Load returns the user's enabled tracking rules, compiled
+	Load(ctx context.Context, userID uuid.UUID) (rules.Set, error)
+}
+
+// SyncRepo applies sync batches
+type SyncRepo interface {
+	// Apply writes the device's local changes, rejecting those that fail
+	// ValidateSyncSession or ValidateSyncProject, and returns the server
+	// changes the device has not seen yet. A batch whose idempotency key
+	// was used before gets the stored result, marked replayed, or a
+	// Conflict error if the key was used for another batch.
+	Apply(ctx context.Context, userID uuid.UUID, batch SyncBatch) (*SyncResult, error)
+}
@@ -0,0 +1,16 @@
+package service
+
+import "testing"
+
+func TestUseRestoresRepositories(t *testing.T) {
+	repos := Repositories{Sessions: pgSessions{}, Rules: pgRules{}}
+	previous := Projects
+	restore := Use(repos)
+	if Projects != nil {
+		t.Errorf("Use kept %T", Projects)
+	}
+	restore()
+	if Projects != previous {
+		t.Errorf("restore left %T", Projects)
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// Reasons a session is flagged for review
+const (
+	// ReviewRunawayTimer is a timer stopped at the maximum duration
+	ReviewRunawayTimer = "runaway_timer"
+)
+
+// SessionReview is a session flagged for the user to check, and why
+type SessionReview struct {
+	Session   Session   `json:"session"`
+	Reason    string    `json:"reason"`
+	FlaggedAt time.Time `json:"flagged_at"`
+}
+
+// FlagSession flags one of the user's sessions for review in tx
+func FlagSession(ctx context.Context, tx pgx.Tx, userID, sessionID uuid.UUID, reason string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO session_reviews (session_id, user_id, reason) VALUES ($1, $2, $3)
+		ON CONFLICT (session_id) DO UPDATE SET reason = EXCLUDED.reason, created_at = CURRENT_TIMESTAMP
+	`, sessionID, userID, reason)
+	return err
+}
+
+// ListSessionReviews returns the user's flagged sessions in the active
+// scope, most recently flagged first. Deleted sessions are left out.
+func ListSessionReviews(ctx context.Context, userID uuid.UUID) ([]SessionReview, error) {
+	rows, err := db.ReadPool(ctx).Query(ctx,
+		`SELECT session_id, reason, created_at FROM session_reviews WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, internalError("Failed to fetch session reviews", err)
+	}
+	flagged, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (SessionReview, error) {
+		var review SessionReview
+		err := row.Scan(&review.Session.ID, &review.Reason, &review.FlaggedAt)
+		return review, err
+	})
+	if err != nil {
+		return nil, internalError("Failed to fetch session reviews", err)
+	}
+	reviews := []SessionReview{}
+	if len(flagged) == 0 {
+		return reviews, nil
+	}
+
+	ids := make([]uuid.UUID, len(flagged))
+	byID := make(map[uuid.UUID]SessionReview, len(flagged))
+	for i, review := range flagged {
+		ids[i] = review.Session.ID
+		byID[review.Session.ID] = review
+	}
+	rows, err = db.ReadPool(ctx).Query(ctx, `
+		SELECT `+sessionColumns+`
+		FROM timer_sessions
+		WHERE `+SessionScopeSQL(1)+` AND is_deleted = false AND id = ANY($3)
+	`, userID, ScopeOrganization(ctx), ids)
+	if err != nil {
+		return nil, internalError("Failed to fetch session reviews", err)
+	}
+	sessions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Session, error) {
+		return scanSession(row)
+	})
+	if err != nil {
+		return nil, internalError("Failed to fetch session reviews", err)
+	}
+	for _, session := range sessions {
+		review := byID[session.ID]
+		review.Session = session
+		reviews = append(reviews, review)
+	}
+	sort.Slice(reviews, func(i, j int) bool { return reviews[i].FlaggedAt.After(reviews[j].FlaggedAt) })
+	return reviews, nil
+}
+
+// ResolveSessionReview clears the review flag of one of the user's sessions
+func ResolveSessionReview(ctx context.Context, userID, sessionID uuid.UUID) error {
+	result, err := db.Pool.Exec(ctx,
+		`DELETE FROM session_reviews WHERE session_id = $1 AND user_id = $2`, sessionID, userID)
+	if err != nil {
+		return internalError("Failed to resolve session review", err)
+	}
+	if result.RowsAffected() == 0 {
+		return errorf(NotFound, "Session review not found")
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/outbox"
+	"github.com/pacerclub/zebra-backend/internal/rules"
+	"github.com/pacerclub/zebra-backend/internal/webhooks"
+)
+
+// MaxRuleHistory bounds the span of history the tracking rules are
+// previewed or applied on at once
+const MaxRuleHistory = 366 * 24 * time.Hour
+
+// RuleChange is what the tracking rules change on a stored session
+type RuleChange struct {
+	SessionID   uuid.UUID `json:"session_id"`
+	StartTime   time.Time `json:"start_time"`
+	Description string    `json:"description"`
+	// ProjectID is set when the rules assign the session a project
+	ProjectID *uuid.UUID `json:"project_id,omitempty"`
+	// TagIDs are the tags the rules add to the session
+	TagIDs  []uuid.UUID `json:"tag_ids,omitempty"`
+	RuleIDs []uuid.UUID `json:"rule_ids"`
+}
+
+// ApplyRules runs the user's tracking rules over a new session, setting its
+// project if it has none, and returns the tags they add, to be stored with
+// rules.TagSessions once the session is. Rule projects outside the active
+// scope are not assigned. Encrypted descriptions cannot be read, so those
+// sessions are left alone.
+func ApplyRules(ctx context.Context, userID uuid.UUID, session *Session) ([]uuid.UUID, error) {
+	sessions := []Session{*session}
+	tags, err := applyRules(ctx, userID, sessions)
+	if err != nil {
+		return nil, err
+	}
+	*session = sessions[0]
+	return tags[0], nil
+}
+
+// applyRules runs the user's tracking rules over new sessions in place, as
+// ApplyRules does, and returns the tags they add by session index
+func applyRules(ctx context.Context, userID uuid.UUID, sessions []Session) (map[int][]uuid.UUID, error) {
+	tags := make(map[int][]uuid.UUID)
+	set, err := Rules.Load(ctx, userID)
+	if err != nil {
+		return nil, internalError("Failed to load tracking rules", err)
+	}
+	if len(set) == 0 {
+		return tags, nil
+	}
+	usable, err := projectsInScope(ctx, userID, set.ProjectIDs())
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		session := &sessions[i]
+		if session.KeyID != "" {
+			continue
+		}
+		m := set.Match(session.Description, func(id uuid.UUID) bool { return usable[id] })
+		if session.ProjectID == nil {
+			session.ProjectID = m.ProjectID
+		}
+		if len(m.TagIDs) > 0 {
+			tags[i] = m.TagIDs
+		}
+	}
+	return tags, nil
+}
+
+// projectsInScope returns which of projectIDs sessions may reference in the
+// active scope
+func projectsInScope(ctx context.Context, userID uuid.UUID, projectIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	inScope := make(map[uuid.UUID]bool)
+	for _, id := range projectIDs {
+		if _, checked := inScope[id]; checked {
+			continue
+		}
+		ok, err := Projects.InScope(ctx, userID, ScopeOrganization(ctx), id)
+		if err != nil {
+			return nil, internalError("Failed to verify project", err)
+		}
+		inScope[id] = ok
+	}
+	return inScope, nil
+}
+
+// tagNewSessions stores the tags the tracking rules add to sessions just
+// created, by session index
+func tagNewSessions(ctx context.Context, userID uuid.UUID, sessions []Session, tags map[int][]uuid.UUID) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	bySession := make(map[uuid.UUID][]uuid.UUID, len(tags))
+	for i, ids := range tags {
+		bySession[sessions[i].ID] = ids
+	}
+	err := pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		return rules.TagSessions(ctx, tx, userID, bySession)
+	})
+	if err != nil {
+		return internalError("Failed to tag sessions", err)
+	}
+	return nil
+}
+
+// PreviewRules returns what the user's enabled tracking rules would change
+// on their sessions in the active scope starting between start and end,
+// without changing them. Sessions without a project get one; every matching
+// session gets the rules' tags it lacks. Encrypted and locked sessions are
+// left out.
+func PreviewRules(ctx context.Context, userID uuid.UUID, start, end time.Time) ([]RuleChange, error) {
+	if end.Sub(start) > MaxRuleHistory {
+		return nil, errorf(InvalidArgument, "Time range is too long")
+	}
+
+	set, err := Rules.Load(ctx, userID)
+	if err != nil {
+		return nil, internalError("Failed to load tracking rules", err)
+	}
+	changes := []RuleChange{}
+	if len(set) == 0 {
+		return changes, nil
+	}
+	usable, err := projectsInScope(ctx, userID, set.ProjectIDs())
+	if err != nil {
+		return nil, err
+	}
+
+	orgID := ScopeOrganization(ctx)
+	if orgID != nil {
+		settings, err := models.GetOrganizationSettings(ctx, *orgID)
+		if err != nil {
+			return nil, internalError("Failed to fetch organization settings", err)
+		}
+		if settings.LockedBefore != nil && start.Before(*settings.LockedBefore) {
+			start = *settings.LockedBefore
+		}
+	}
+
+	rows, err := db.ReadPool(ctx).Query(ctx, `
+		SELECT `+sessionColumns+`
+		FROM timer_sessions
+		WHERE `+SessionScopeSQL(1)+` AND is_deleted = false AND start_time >= $3 AND start_time < $4
+		ORDER BY start_time
+	`, userID, orgID, start, end)
+	if err != nil {
+		return nil, internalError("Failed to fetch sessions", err)
+	}
+	sessions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Session, error) {
+		return scanSession(row)
+	})
+	if err != nil {
+		return nil, internalError("Failed to fetch sessions", err)
+	}
+
+	var sessionIDs []uuid.UUID
+	for _, session := range sessions {
+		sessionIDs = append(sessionIDs, session.ID)
+	}
+	existing, err := rules.SessionTags(ctx, userID, sessionIDs)
+	if err != nil {
+		return nil, internalError("Failed to fetch session tags", err)
+	}
+
+	for _, session := range sessions {
+		if session.KeyID != "" {
+			continue
+		}
+		m := set.Match(session.Description, func(id uuid.UUID) bool { return usable[id] })
+		change := RuleChange{
+			SessionID:   session.ID,
+			StartTime:   session.StartTime,
+			Description: session.Description,
+			RuleIDs:     m.RuleIDs,
+		}
+		if session.ProjectID == nil {
+			change.ProjectID = m.ProjectID
+		}
+		for _, id := range m.TagIDs {
+			if !containsID(existing[session.ID], id) {
+				change.TagIDs = append(change.TagIDs, id)
+			}
+		}
+		if change.ProjectID != nil || len(change.TagIDs) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// ApplyRulesToHistory makes the changes PreviewRules returns and returns
+// them. Sessions given a project record their update webhook event.
+func ApplyRulesToHistory(ctx context.Context, userID uuid.UUID, start, end time.Time) ([]RuleChange, error) {
+	changes, err := PreviewRules(db.WithPrimary(ctx), userID, start, end)
+	if err != nil || len(changes) == 0 {
+		return changes, err
+	}
+
+	tags := make(map[uuid.UUID][]uuid.UUID)
+	err = pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		for _, change := range changes {
+			if len(change.TagIDs) > 0 {
+				tags[change.SessionID] = change.TagIDs
+			}
+			if change.ProjectID == nil {
+				continue
+			}
+			updated, err := scanSession(tx.QueryRow(ctx, `
+				UPDATE timer_sessions SET project_id = $3
+				WHERE id = $1 AND user_id = $2 AND project_id IS NULL AND is_deleted = false
+				RETURNING `+sessionColumns,
+				change.SessionID, userID, change.ProjectID))
+			if errors.Is(err, pgx.ErrNoRows) {
+				// Changed since the preview; the rules no longer apply
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := outbox.Add(ctx, tx, userID, webhooks.EventSessionUpdated, updated); err != nil {
+				return err
+			}
+		}
+		return rules.TagSessions(ctx, tx, userID, tags)
+	})
+	if err != nil {
+		return nil, internalError("Failed to apply tracking rules", err)
+	}
+	return changes, nil
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/rules"
+)
+
+// pgRules stores tracking rules in the tracking_rules table, through
+// package rules
+type pgRules struct{}
+
+func (pgRules) Load(ctx context.Context, userID uuid.UUID) (rules.Set, error) {
+	return rules.Load(ctx, userID)
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+)
+
+// Requests run either in the user's personal scope or, with the
+// X-Organization-ID header, in an organization's scope. Personal projects
+// have no organization; organization projects are shared by its members.
+// Sessions always belong to the user who logged them and follow the scope of
+// their project.
+
+// ScopeOrganization returns the active organization, or nil in personal
+// scope. It is passed as a query argument to the *ScopeSQL conditions.
+func ScopeOrganization(ctx context.Context) *uuid.UUID {
+	orgID := auth.GetOrganizationIDFromContext(ctx)
+	if orgID == uuid.Nil {
+		return nil
+	}
+	return &orgID
+}
+
+// ProjectScopeSQL limits projects to the active scope. $userArg is the user
+// ID and $userArg+1 the result of ScopeOrganization.
+func ProjectScopeSQL(userArg int) string {
+	return fmt.Sprintf(
+		"(organization_id = $%[2]d OR ($%[2]d::uuid IS NULL AND organization_id IS NULL AND user_id = $%[1]d))",
+		userArg, userArg+1)
+}
+
+// SessionScopeSQL limits timer_sessions to the user's sessions in the active
+// scope. Arguments are as for ProjectScopeSQL.
+func SessionScopeSQL(userArg int) string {
+	return fmt.Sprintf(`(user_id = $%[1]d AND (
+		project_id IN (SELECT id FROM projects WHERE organization_id = $%[2]d)
+		OR ($%[2]d::uuid IS NULL AND (project_id IS NULL OR project_id IN (SELECT id FROM projects WHERE organization_id IS NULL)))
+	))`, userArg, userArg+1)
+}
+
+// SyncedProjectSQL limits projects to those synced to the user: personal
+// projects plus the projects of every organization the user is an active
+// member of. $userArg is the user ID.
+func SyncedProjectSQL(userArg int) string {
+	return fmt.Sprintf(
+		"((user_id = $%[1]d AND organization_id IS NULL) OR organization_id IN (SELECT organization_id FROM memberships WHERE user_id = $%[1]d AND deactivated_at IS NULL))",
+		userArg)
+}
+
+// ProjectInScope reports whether a session may reference projectID in the
+// active scope. Organization sessions must reference one of its projects.
+func ProjectInScope(ctx context.Context, userID uuid.UUID, projectID *uuid.UUID) (bool, error) {
+	orgID := ScopeOrganization(ctx)
+	if projectID == nil {
+		return orgID == nil, nil
+	}
+
+	return Projects.InScope(ctx, userID, orgID, *projectID)
+}
@@ -0,0 +1,131 @@
+// Package service holds the business logic shared by the HTTP handlers and
+// the gRPC server: authentication, sessions, projects and sync. Functions take
+// the authenticated user explicitly and read the active organization from the
+// context, the same way the HTTP middleware sets it up.
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// Code classifies an Error independently of the transport reporting it
+type Code int
+
+const (
+	Internal Code = iota
+	InvalidArgument
+	Unauthenticated
+	PermissionDenied
+	NotFound
+	TooLarge
+	// Unimplemented marks features this instance is configured without
+	Unimplemented
+	// Conflict is a change made on a stale version of a record
+	Conflict
+	// PaymentRequired is a change over a limit of the user's plan
+	PaymentRequired
+)
+
+// Error is a failure the caller may report to the client. Message is safe to
+// show; the underlying cause, if any, is kept in Err.
+type Error struct {
+	Code Code
+	// Reason is the API error code when one more specific than Code
+	// applies, such as apierror.SessionLocked
+	Reason  apierror.Code
+	Message string
+	// Current is the stored record a Conflict error is about, for clients
+	// to redo their change on
+	Current interface{}
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func errorf(code Code, format string, args ...interface{}) error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// reasonf is errorf with a specific API error code
+func reasonf(code Code, reason apierror.Code, format string, args ...interface{}) error {
+	return &Error{Code: code, Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+// conflictf is errorf for a change that lost the race against the stored
+// version current
+func conflictf(current interface{}, format string, args ...interface{}) error {
+	return &Error{Code: Conflict, Reason: apierror.VersionMismatch, Message: fmt.Sprintf(format, args...), Current: current}
+}
+
+// invalidFields wraps the validate.Errors of a request
+func invalidFields(err error) error {
+	return &Error{Code: InvalidArgument, Reason: apierror.ValidationFailed, Message: err.Error(), Err: err}
+}
+
+// checkVersion requires the version an update was made on, so that clients
+// cannot overwrite changes they have not seen
+func checkVersion(version int64) error {
+	v := validate.New()
+	v.Check(version > 0, "version", "is required")
+	if err := v.Err(); err != nil {
+		return invalidFields(err)
+	}
+	return nil
+}
+
+// internalError wraps a database or encoding failure
+func internalError(message string, err error) error {
+	return &Error{Code: Internal, Message: message, Err: err}
+}
+
+// ErrorCode returns the code of err, Internal for errors not raised by this
+// package
+func ErrorCode(err error) Code {
+	var serviceErr *Error
+	if errors.As(err, &serviceErr) {
+		return serviceErr.Code
+	}
+	return Internal
+}
+
+// ErrorReason returns the specific API error code of err, empty if there is
+// none
+func ErrorReason(err error) apierror.Code {
+	var serviceErr *Error
+	if errors.As(err, &serviceErr) {
+		return serviceErr.Reason
+	}
+	return ""
+}
+
+// ErrorCurrent returns the stored record of a Conflict error, nil for other
+// errors
+func ErrorCurrent(err error) interface{} {
+	var serviceErr *Error
+	if errors.As(err, &serviceErr) {
+		return serviceErr.Current
+	}
+	return nil
+}
+
+// ErrorMessage returns the client-facing message of err
+func ErrorMessage(err error) string {
+	var serviceErr *Error
+	if errors.As(err, &serviceErr) {
+		return serviceErr.Message
+	}
+	return "Internal server error"
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/pagination"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+//msgp:tag json
+//msgp:newtime
+//msgp:shim uuid.UUID as:[]byte using:uuidToBytes/uuidFromBytes
+
+type Session struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	ProjectID   *uuid.UUID `json:"project_id,omitempty"`
+	StartTime   time.Time  `json:"start_time"`
+	EndTime     time.Time  `json:"end_time"`
+	Description string     `json:"description"`
+	// EncryptedDescription and KeyID are set instead of Description for
+	// users in encrypted storage mode
+	EncryptedDescription []byte    `json:"encrypted_description,omitempty"`
+	KeyID                string    `json:"key_id,omitempty"`
+	DeviceID             string    `json:"device_id"`
+	IsDeleted            bool      `json:"is_deleted"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+	// Version counts the changes to the session. Updates carry the version
+	// they were made on and fail with a conflict if it changed since.
+	Version int64 `json:"version"`
+	// Breaks are pauses within the session, in order. Writes without breaks
+	// keep the stored ones, so clients unaware of them do not drop them.
+	// allownil makes MessagePack decode an empty array as an empty slice,
+	// which clears the breaks, rather than as nil.
+	Breaks []Break `json:"breaks,allownil"`
+	// NetSeconds is the length of the session less its breaks, computed by
+	// the server
+	NetSeconds int64 `json:"net_seconds"`
+	// TaskID is the task the session was logged against. It is only set
+	// through SetSessionTask; other writes keep it.
+	TaskID *uuid.UUID `json:"task_id,omitempty"`
+}
+
+// Break is a pause within a session
+type Break struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// MaxBreaks bounds the breaks of one session
+const MaxBreaks = 100
+
+// Validate checks the fields clients send
+func (s Session) Validate(v *validate.Validator) {
+	v.TimeRange("start_time", "end_time", s.StartTime, s.EndTime)
+	if !s.StartTime.IsZero() {
+		v.Check(s.EndTime.Sub(s.StartTime) <= MaxSessionDuration, "end_time", "must be at most 7 days after start_time")
+	}
+	v.MaxLength("description", s.Description, MaxDescriptionLength)
+	v.MaxBytes("key_id", s.KeyID, MaxNameLength)
+	v.MaxBytes("device_id", s.DeviceID, maxDeviceIDLength)
+	s.validateBreaks(v)
+}
+
+// validateBreaks checks that the breaks lie within the session, in order
+// and without overlapping
+func (s Session) validateBreaks(v *validate.Validator) {
+	if len(s.Breaks) > MaxBreaks {
+		v.Fail("breaks", "must list at most %d breaks", MaxBreaks)
+		return
+	}
+	previousEnd := s.StartTime
+	for _, b := range s.Breaks {
+		if !b.EndTime.After(b.StartTime) || b.StartTime.Before(previousEnd) || b.EndTime.After(s.EndTime) {
+			v.Fail("breaks", "must be in order within the session, each ending after it starts")
+			return
+		}
+		previousEnd = b.EndTime
+	}
+}
+
+// breaksJSON encodes the breaks to store, nil to keep the stored ones
+func (s Session) breaksJSON() ([]byte, error) {
+	if s.Breaks == nil {
+		return nil, nil
+	}
+	return json.Marshal(s.Breaks)
+}
+
+// SessionList is how session lists can be sorted, by default newest first
+var SessionList = listquery.List[Session]{
+	Sorts: map[string]listquery.Column[Session]{
+		"start_time": {SQL: "start_time", Key: func(s Session) time.Time { return s.StartTime }},
+		"end_time":   {SQL: "end_time", Key: func(s Session) time.Time { return s.EndTime }},
+		"created_at": {SQL: "created_at", Key: func(s Session) time.Time { return s.CreatedAt }},
+		"updated_at": {SQL: "updated_at", Key: func(s Session) time.Time { return s.UpdatedAt }},
+	},
+	Default: listquery.Sort{Field: "start_time", Desc: true},
+	RowID:   func(s Session) uuid.UUID { return s.ID },
+}
+
+// ListSessions returns a page of the user's sessions in the active scope.
+// With allMembers, members with report access get everyone's sessions on
+// the organization's projects.
+func ListSessions(ctx context.Context, userID uuid.UUID, allMembers bool, q listquery.Query) (pagination.Page[Session], error) {
+	var sessions []Session
+	var err error
+	if allMembers {
+		orgID := ScopeOrganization(ctx)
+		if orgID == nil {
+			return pagination.Page[Session]{}, errorf(InvalidArgument, "all_members requires an organization")
+		}
+		if !auth.Can(ctx, auth.PermViewReports) {
+			return pagination.Page[Session]{}, errorf(PermissionDenied, "Insufficient permissions")
+		}
+		sessions, err = Sessions.ListOrganization(ctx, *orgID, q)
+	} else {
+		sessions, err = Sessions.List(ctx, userID, ScopeOrganization(ctx), q)
+	}
+	if err != nil {
+		return pagination.Page[Session]{}, internalError("Failed to fetch sessions", err)
+	}
+	return SessionList.Page(sessions, q), nil
+}
+
+// SearchSessions returns up to limit of the user's sessions in the active
+// scope whose description matches text, as parsed by db.TSQuery, best
+// matches first
+func SearchSessions(ctx context.Context, userID uuid.UUID, text string, limit int) ([]Session, error) {
+	// Sealed values are kept out of the search index
+	if fieldcrypt.Enabled() {
+		return nil, errorf(Unimplemented, "Search is not available while field encryption is enabled")
+	}
+	tsquery := db.TSQuery(text)
+	if tsquery == "" {
+		return nil, errorf(InvalidArgument, "Search text is required")
+	}
+	sessions, err := Sessions.Search(ctx, userID, ScopeOrganization(ctx), tsquery, limit)
+	if err != nil {
+		return nil, internalError("Failed to search sessions", err)
+	}
+	return sessions, nil
+}
+
+// CreateSession stores a new session of the user, applying their tracking
+// rules
+func CreateSession(ctx context.Context, userID uuid.UUID, session Session) (Session, error) {
+	if session.ID == uuid.Nil {
+		session.ID = uuid.New()
+	}
+	session.UserID = userID
+
+	if err := validate.Struct(session); err != nil {
+		return Session{}, invalidFields(err)
+	}
+	sessions := []Session{session}
+	tags, err := applyRules(ctx, userID, sessions)
+	if err != nil {
+		return Session{}, err
+	}
+	if err := checkNewSession(ctx, userID, sessions[0]); err != nil {
+		return Session{}, err
+	}
+
+	created, err := Sessions.Create(ctx, sessions, nil)
+	if err != nil {
+		return Session{}, internalError("Failed to create session", err)
+	}
+	if err := tagNewSessions(ctx, userID, created, tags); err != nil {
+		return Session{}, err
+	}
+	return created[0], nil
+}
+
+// CreateSessions stores several new sessions of the user, all or none,
+// applying their tracking rules. suggestionIDs is nil or names for each
+// session the calendar suggestion it confirms, if any.
+func CreateSessions(ctx context.Context, userID uuid.UUID, sessions []Session, suggestionIDs []*uuid.UUID) ([]Session, error) {
+	for i := range sessions {
+		session := &sessions[i]
+		if session.ID == uuid.Nil {
+			session.ID = uuid.New()
+		}
+		session.UserID = userID
+		if session.EndTime.Before(session.StartTime) {
+			return nil, errorf(InvalidArgument, "Session ends before it starts")
+		}
+		v := validate.New()
+		session.validateBreaks(v)
+		if err := v.Err(); err != nil {
+			return nil, invalidFields(err)
+		}
+	}
+	tags, err := applyRules(ctx, userID, sessions)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		if err := checkNewSession(ctx, userID, session); err != nil {
+			return nil, err
+		}
+	}
+
+	created, err := Sessions.Create(ctx, sessions, suggestionIDs)
+	if err != nil {
+		return nil, internalError("Failed to create sessions", err)
+	}
+	if err := tagNewSessions(ctx, userID, created, tags); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// checkNewSession applies the encryption, project and lock rules to a
+// session about to be created
+func checkNewSession(ctx context.Context, userID uuid.UUID, session Session) error {
+	if err := CheckSessionEncryption(ctx, userID, session); err != nil {
+		return err
+	}
+	if err := CheckSessionProject(ctx, userID, session); err != nil {
+		return err
+	}
+	return CheckSessionLock(ctx, userID, uuid.Nil, session.StartTime)
+}
+
+// UpdateSession replaces the editable fields of one of the user's sessions,
+// if session.Version is still its current version
+func UpdateSession(ctx context.Context, userID, sessionID uuid.UUID, session Session) (Session, error) {
+	if err := validate.Struct(session); err != nil {
+		return Session{}, invalidFields(err)
+	}
+	if err := checkVersion(session.Version); err != nil {
+		return Session{}, err
+	}
+	if err := CheckSessionEncryption(ctx, userID, session); err != nil {
+		return Session{}, err
+	}
+	if err := CheckSessionProject(ctx, userID, session); err != nil {
+		return Session{}, err
+	}
+	if err := CheckSessionLock(ctx, userID, sessionID, session.StartTime); err != nil {
+		return Session{}, err
+	}
+
+	session, err := Sessions.Update(ctx, userID, ScopeOrganization(ctx), sessionID, session)
+	if code := ErrorCode(err); code == NotFound || code == Conflict {
+		return Session{}, err
+	}
+	if err != nil {
+		return Session{}, internalError("Failed to update session", err)
+	}
+	return session, nil
+}
+
+// DeleteSession marks one of the user's sessions deleted
+func DeleteSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if err := CheckSessionLock(ctx, userID, sessionID, time.Time{}); err != nil {
+		return err
+	}
+
+	deleted, err := Sessions.Delete(ctx, userID, ScopeOrganization(ctx), sessionID)
+	if err != nil {
+		return internalError("Failed to delete session", err)
+	}
+	if !deleted {
+		return errorf(NotFound, "Session not found")
+	}
+	return nil
+}
+
+// CheckSessionEncryption rejects sessions that do not match the user's
+// storage mode
+func CheckSessionEncryption(ctx context.Context, userID uuid.UUID, session Session) error {
+	mode, err := Users.StorageMode(ctx, userID)
+	if err != nil {
+		return internalError("Failed to fetch storage mode", err)
+	}
+	if _, message := EncryptionError(mode, session.KeyID, session.Description != "", session.EncryptedDescription); message != "" {
+		return reasonf(InvalidArgument, apierror.StorageModeMismatch, "%s", message)
+	}
+	return nil
+}
+
+// CheckSessionProject rejects sessions whose project is not part of the
+// active scope
+func CheckSessionProject(ctx context.Context, userID uuid.UUID, session Session) error {
+	ok, err := ProjectInScope(ctx, userID, session.ProjectID)
+	if err != nil {
+		return internalError("Failed to verify project", err)
+	}
+	if !ok {
+		return errorf(InvalidArgument, "Project not found")
+	}
+	return nil
+}
+
+// CheckSessionLock rejects changes to sessions before the active
+// organization's lock date. sessionID names an existing session whose stored
+// start time must also be unlocked; startTime is the new start time, if any.
+func CheckSessionLock(ctx context.Context, userID, sessionID uuid.UUID, startTime time.Time) error {
+	orgID := ScopeOrganization(ctx)
+	if orgID == nil {
+		return nil
+	}
+
+	settings, err := models.GetOrganizationSettings(ctx, *orgID)
+	if err != nil {
+		return internalError("Failed to fetch organization settings", err)
+	}
+	if settings.LockedBefore == nil {
+		return nil
+	}
+
+	if sessionID != uuid.Nil {
+		stored, err := Sessions.StartTime(ctx, userID, sessionID)
+		if err != nil {
+			return internalError("Failed to fetch session", err)
+		}
+		if stored != nil && stored.Before(*settings.LockedBefore) {
+			return reasonf(InvalidArgument, apierror.SessionLocked, "Session is locked")
+		}
+	}
+	if !startTime.IsZero() && startTime.Before(*settings.LockedBefore) {
+		return reasonf(InvalidArgument, apierror.SessionLocked, "Session is locked")
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+	"github.com/pacerclub/zebra-backend/internal/outbox"
+	"github.com/pacerclub/zebra-backend/internal/webhooks"
+)
+
+const sessionColumns = "id, user_id, project_id, start_time, end_time, description, encrypted_description, key_id, device_id, is_deleted, created_at, updated_at, version, breaks, " +
+	"EXTRACT(EPOCH FROM (end_time - start_time))::BIGINT - break_seconds, task_id"
+
+func scanSession(row pgx.Row) (Session, error) {
+	var session Session
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.ProjectID,
+		&session.StartTime,
+		&session.EndTime,
+		&session.Description,
+		&session.EncryptedDescription,
+		&session.KeyID,
+		&session.DeviceID,
+		&session.IsDeleted,
+		&session.CreatedAt,
+		&session.UpdatedAt,
+		&session.Version,
+		&session.Breaks,
+		&session.NetSeconds,
+		&session.TaskID,
+	)
+	if err != nil {
+		return session, err
+	}
+	session.Description, err = fieldcrypt.Open(fieldcrypt.SessionDescription, session.Description)
+	return session, err
+}
+
+// pgSessions stores sessions in the timer_sessions table, with descriptions
+// sealed by fieldcrypt. Changes record their webhook events in the outbox in
+// the same transaction.
+type pgSessions struct{}
+
+// Queries, checked against the schema by db.CheckStatements. The list
+// queries are checked in their default order.
+var (
+	insertSessionSQL = db.Statement("insert_session", `
+		INSERT INTO timer_sessions (id, user_id, project_id, start_time, end_time, description, encrypted_description, key_id, device_id, breaks)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10::jsonb, '[]'))
+		RETURNING `+sessionColumns)
+	confirmSuggestionSQL = db.Statement("confirm_suggestion", `
+		UPDATE suggested_sessions SET status = 'confirmed', session_id = $3
+		WHERE id = $1 AND user_id = $2 AND status = 'pending'`)
+	updateSessionSQL = db.Statement("update_session", `
+		UPDATE timer_sessions
+		SET project_id = $1, start_time = $2, end_time = $3, description = $4,
+			encrypted_description = $5, key_id = $6, breaks = COALESCE($11::jsonb, breaks)
+		WHERE id = $7 AND `+SessionScopeSQL(8)+` AND version = $10
+		RETURNING `+sessionColumns)
+	getSessionSQL = db.Statement("get_session", `
+		SELECT `+sessionColumns+`
+		FROM timer_sessions
+		WHERE id = $1 AND `+SessionScopeSQL(2))
+	deleteSessionSQL = db.Statement("delete_session", `
+		UPDATE timer_sessions
+		SET is_deleted = true
+		WHERE id = $1 AND `+SessionScopeSQL(2))
+	sessionStartTimeSQL = db.Statement("session_start_time",
+		`SELECT start_time FROM timer_sessions WHERE id = $1 AND user_id = $2`)
+	searchSessionsSQL = db.Statement("search_sessions", `
+		SELECT `+sessionColumns+`
+		FROM timer_sessions
+		WHERE `+SessionScopeSQL(1)+` AND is_deleted = false
+			AND description_tsv @@ to_tsquery('`+db.SearchConfig+`', $3)
+		ORDER BY ts_rank(description_tsv, to_tsquery('`+db.SearchConfig+`', $3)) DESC, start_time DESC
+		LIMIT $4`)
+
+	_ = db.Statement("list_sessions", listSessionsSQL(listquery.Query{Sort: SessionList.Default}))
+	_ = db.Statement("list_organization_sessions", listOrganizationSessionsSQL(listquery.Query{Sort: SessionList.Default}))
+)
+
+// listSessionsSQL selects a page of a user's sessions in scope. The
+// arguments are the user, the organization, q.Args() and q.Page.Fetch().
+func listSessionsSQL(q listquery.Query) string {
+	where, orderBy := SessionList.SQL(q, 3)
+	return `
+		SELECT ` + sessionColumns + `
+		FROM timer_sessions
+		WHERE ` + SessionScopeSQL(1) + ` AND is_deleted = false AND ` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT $5
+	`
+}
+
+// listOrganizationSessionsSQL selects a page of the sessions on an
+// organization's projects. The arguments are the organization, q.Args()
+// and q.Page.Fetch().
+func listOrganizationSessionsSQL(q listquery.Query) string {
+	where, orderBy := SessionList.SQL(q, 2)
+	return `
+		SELECT ` + sessionColumns + `
+		FROM timer_sessions
+		WHERE project_id IN (SELECT id FROM projects WHERE organization_id = $1) AND is_deleted = false AND ` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT $4
+	`
+}
+
+func (pgSessions) List(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, q listquery.Query) ([]Session, error) {
+	args := append([]interface{}{userID, orgID}, q.Args()...)
+	return querySessions(ctx, listSessionsSQL(q), append(args, q.Page.Fetch())...)
+}
+
+func (pgSessions) ListOrganization(ctx context.Context, orgID uuid.UUID, q listquery.Query) ([]Session, error) {
+	args := append([]interface{}{orgID}, q.Args()...)
+	return querySessions(ctx, listOrganizationSessionsSQL(q), append(args, q.Page.Fetch())...)
+}
+
+func (pgSessions) Search(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, tsquery string, limit int) ([]Session, error) {
+	return querySessions(ctx, searchSessionsSQL, userID, orgID, tsquery, limit)
+}
+
+func querySessions(ctx context.Context, query string, args ...interface{}) ([]Session, error) {
+	rows, err := db.ReadPool(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (pgSessions) Create(ctx context.Context, sessions []Session, suggestionIDs []*uuid.UUID) ([]Session, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	created := make([]Session, len(sessions))
+	for i, session := range sessions {
+		description, err := fieldcrypt.Seal(fieldcrypt.SessionDescription, session.Description)
+		if err != nil {
+			return nil, err
+		}
+		breaks, err := session.breaksJSON()
+		if err != nil {
+			return nil, err
+		}
+		created[i], err = scanSession(tx.QueryRow(ctx, insertSessionSQL,
+			session.ID,
+			session.UserID,
+			session.ProjectID,
+			session.StartTime,
+			session.EndTime,
+			description,
+			session.EncryptedDescription,
+			session.KeyID,
+			session.DeviceID,
+			breaks,
+		))
+		if err != nil {
+			return nil, err
+		}
+		if err := outbox.Add(ctx, tx, session.UserID, webhooks.EventSessionCreated, created[i]); err != nil {
+			return nil, err
+		}
+
+		// A suggestion split into several blocks is confirmed by the first
+		if i < len(suggestionIDs) && suggestionIDs[i] != nil {
+			_, err := tx.Exec(ctx, confirmSuggestionSQL, *suggestionIDs[i], session.UserID, session.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return created, tx.Commit(ctx)
+}
+
+func (pgSessions) Update(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, sessionID uuid.UUID, session Session) (Session, error) {
+	description, err := fieldcrypt.Seal(fieldcrypt.SessionDescription, session.Description)
+	if err != nil {
+		return Session{}, err
+	}
+	breaks, err := session.breaksJSON()
+	if err != nil {
+		return Session{}, err
+	}
+	var updated Session
+	err = pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		var err error
+		updated, err = scanSession(tx.QueryRow(ctx, updateSessionSQL,
+			session.ProjectID,
+			session.StartTime,
+			session.EndTime,
+			description,
+			session.EncryptedDescription,
+			session.KeyID,
+			sessionID,
+			userID,
+			orgID,
+			session.Version,
+			breaks,
+		))
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Either there is no such session or it has moved on
+			current, err := scanSession(tx.QueryRow(ctx, getSessionSQL, sessionID, userID, orgID))
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errorf(NotFound, "Session not found")
+			}
+			if err != nil {
+				return err
+			}
+			return conflictf(current, "Session was changed since version %d", session.Version)
+		}
+		if err != nil {
+			return err
+		}
+		return outbox.Add(ctx, tx, userID, webhooks.EventSessionUpdated, updated)
+	})
+	return updated, err
+}
+
+func (pgSessions) Delete(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, sessionID uuid.UUID) (bool, error) {
+	var deleted bool
+	err := pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, deleteSessionSQL, sessionID, userID, orgID)
+		if err != nil {
+			return err
+		}
+		deleted = result.RowsAffected() > 0
+		if !deleted {
+			return nil
+		}
+		return outbox.Add(ctx, tx, userID, webhooks.EventSessionDeleted, webhooks.DeletedPayload{ID: sessionID, DeletedAt: time.Now()})
+	})
+	return deleted, err
+}
+
+func (pgSessions) StartTime(ctx context.Context, userID, sessionID uuid.UUID) (*time.Time, error) {
+	var stored time.Time
+	err := db.Pool.QueryRow(ctx, sessionStartTimeSQL, sessionID, userID).Scan(&stored)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
@@ -0,0 +1,761 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/onboarding"
+	"github.com/pacerclub/zebra-backend/internal/opstats"
+	"github.com/pacerclub/zebra-backend/internal/rules"
+)
+
+//msgp:tag json
+//msgp:newtime
+//msgp:shim uuid.UUID as:[]byte using:uuidToBytes/uuidFromBytes
+//msgp:shim json.RawMessage as:[]byte using:rawToBytes/bytesToRaw
+//msgp:ignore SyncOptions SyncResult SyncBatch
+
+type SyncRequest struct {
+	// BatchID identifies the request for idempotent retries when the
+	// client cannot send an Idempotency-Key header
+	BatchID         uuid.UUID   `json:"batch_id"`
+	DeviceID        string      `json:"device_id"`
+	DeviceName      string      `json:"device_name"`
+	Platform        string      `json:"platform"`
+	LastSyncTime    time.Time   `json:"last_sync_time"`
+	LocalSessions   []Session   `json:"local_sessions"`
+	LocalProjects   []Project   `json:"local_projects"`
+	DeletedSessions []uuid.UUID `json:"deleted_sessions"`
+	DeletedProjects []uuid.UUID `json:"deleted_projects"`
+
+	LocalTags          []Tag             `json:"local_tags"`
+	LocalTasks         []Task            `json:"local_tasks"`
+	LocalTemplates     []SessionTemplate `json:"local_templates"`
+	LocalPreferences   []Preference      `json:"local_preferences"`
+	LocalTimeOff       []TimeOff         `json:"local_time_off"`
+	DeletedTags        []uuid.UUID       `json:"deleted_tags"`
+	DeletedTasks       []uuid.UUID       `json:"deleted_tasks"`
+	DeletedTemplates   []uuid.UUID       `json:"deleted_templates"`
+	DeletedPreferences []string          `json:"deleted_preferences"`
+	DeletedTimeOff     []uuid.UUID       `json:"deleted_time_off"`
+}
+
+type SyncResponse struct {
+	LastSyncTime   time.Time `json:"last_sync_time"`
+	ServerSessions []Session `json:"server_sessions"`
+	ServerProjects []Project `json:"server_projects"`
+
+	ServerTags        []Tag             `json:"server_tags"`
+	ServerTasks       []Task            `json:"server_tasks"`
+	ServerTemplates   []SessionTemplate `json:"server_templates"`
+	ServerPreferences []Preference      `json:"server_preferences"`
+	ServerTimeOff     []TimeOff         `json:"server_time_off"`
+
+	// Rejected lists local records that failed validation and were not applied
+	Rejected []SyncItemError `json:"rejected,omitempty"`
+	// Conflicts lists records that were also changed by another device and
+	// how they were resolved
+	Conflicts []SyncConflict `json:"conflicts,omitempty"`
+	// FullResyncRequired is set when tombstones this device never saw were
+	// garbage collected; the client must sync again from a zero cursor
+	FullResyncRequired bool `json:"full_resync_required,omitempty"`
+}
+
+// SyncOptions carries the transport details of a sync
+type SyncOptions struct {
+	// IdempotencyKey makes retries of the same batch replay the stored
+	// response instead of applying the batch again; reusing it for a
+	// different batch is a Conflict. It defaults to the request's batch ID.
+	IdempotencyKey string
+	// ContentType is the encoding the response is stored in for replays,
+	// ContentTypeJSON or ContentTypeMsgpack
+	ContentType string
+}
+
+// SyncBatch is a sync request checked by Sync, as the sync repository
+// applies it
+type SyncBatch struct {
+	Request *SyncRequest
+	// IdempotencyKey is shared by the retries of the batch, empty if it has
+	// none. Fingerprint identifies the batch the key is for.
+	IdempotencyKey string
+	Fingerprint    []byte
+	// ContentType is the encoding the response is stored in for replays
+	ContentType string
+}
+
+// SyncResult is the outcome of a sync
+type SyncResult struct {
+	Response *SyncResponse
+	// ContentType and Body are the encoded response, as stored for replays
+	ContentType string
+	Body        []byte
+	// Replayed is set when the batch was applied by an earlier request
+	Replayed bool
+}
+
+// syncSessionColumns are the columns of the arguments of a session write,
+// for copying new sessions in
+var syncSessionColumns = []string{"id", "user_id", "project_id", "start_time", "end_time", "description",
+	"encrypted_description", "key_id", "device_id", "created_at", "updated_at", "breaks"}
+
+// Sync applies a device's local changes and returns the server changes it
+// has not seen yet
+func Sync(ctx context.Context, userID uuid.UUID, req *SyncRequest, opts SyncOptions) (*SyncResult, error) {
+	if err := checkSyncBatchSizes(req); err != nil {
+		return nil, &Error{Code: TooLarge, Message: err.Error()}
+	}
+
+	idempotencyKey := opts.IdempotencyKey
+	if idempotencyKey == "" && req.BatchID != uuid.Nil {
+		idempotencyKey = req.BatchID.String()
+	}
+	if len(idempotencyKey) > MaxIdempotencyKeyLength {
+		return nil, errorf(InvalidArgument, "Idempotency key too long")
+	}
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = ContentTypeJSON
+	}
+	var fingerprint []byte
+	if idempotencyKey != "" {
+		var err error
+		if fingerprint, err = syncFingerprint(req); err != nil {
+			return nil, internalError("Failed to fingerprint sync batch", err)
+		}
+	}
+
+	return Syncs.Apply(ctx, userID, SyncBatch{
+		Request:        req,
+		IdempotencyKey: idempotencyKey,
+		Fingerprint:    fingerprint,
+		ContentType:    contentType,
+	})
+}
+
+// pgSyncs applies sync batches to Postgres
+type pgSyncs struct{}
+
+// Apply runs in one transaction per user, so concurrent syncs of the same
+// user are applied one after another
+func (pgSyncs) Apply(ctx context.Context, userID uuid.UUID, batch SyncBatch) (*SyncResult, error) {
+	req, idempotencyKey, fingerprint, contentType := batch.Request, batch.IdempotencyKey, batch.Fingerprint, batch.ContentType
+
+	// Start a transaction
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, internalError("Failed to start transaction", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Replay the stored response if this batch was already applied
+	if idempotencyKey != "" {
+		claimed, err := claimIdempotencyKey(ctx, tx, userID, idempotencyKey, fingerprint)
+		if err != nil {
+			return nil, internalError("Failed to check idempotency key", err)
+		}
+		if !claimed {
+			storedFingerprint, storedType, body, err := loadIdempotentResponse(ctx, tx, userID, idempotencyKey)
+			if err != nil {
+				return nil, internalError("Failed to load idempotent response", err)
+			}
+			if storedFingerprint != nil && !bytes.Equal(storedFingerprint, fingerprint) {
+				return nil, reasonf(Conflict, apierror.IdempotencyKeyReused, "This idempotency key was used for a different sync batch")
+			}
+			var response SyncResponse
+			if err := unmarshalBody(storedType, body, &response); err != nil {
+				return nil, internalError("Failed to decode idempotent response", err)
+			}
+			return &SyncResult{Response: &response, ContentType: storedType, Body: body, Replayed: true}, nil
+		}
+	}
+
+	// The response covers changes between the client's cursor and the start
+	// of this transaction; writes made here are not echoed back
+	deviceLastSyncTime := req.LastSyncTime
+	txStart, cursor, err := syncCursor(ctx, tx)
+	if err != nil {
+		return nil, internalError("Failed to compute sync cursor", err)
+	}
+
+	// Record the new cursor up front; this also locks the user's sync status
+	// so concurrent syncs of the same user run one after another
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_sync_status (user_id, device_id, last_sync_time)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id)
+		DO UPDATE SET last_sync_time = EXCLUDED.last_sync_time,
+		              device_id = EXCLUDED.device_id
+	`, userID, req.DeviceID, cursor)
+	if err != nil {
+		return nil, internalError("Failed to update sync status", err)
+	}
+
+	// Record how far this device has acknowledged server changes so
+	// tombstones can be garbage collected safely
+	var fullResyncRequired bool
+	if req.DeviceID != "" {
+		fullResyncRequired, err = recordDeviceAck(ctx, tx, userID, req.DeviceID, req.DeviceName, req.Platform, req.LastSyncTime)
+		if err != nil {
+			return nil, internalError("Failed to update device sync status", err)
+		}
+	}
+
+	storageMode, err := Users.StorageMode(ctx, userID)
+	if err != nil {
+		return nil, internalError("Failed to fetch storage mode", err)
+	}
+
+	var rejected []SyncItemError
+	receivedAt := time.Now()
+
+	// Assign IDs up front so ownership can be checked in one query each
+	projectIDs := make([]uuid.UUID, 0, len(req.LocalProjects))
+	for i := range req.LocalProjects {
+		if req.LocalProjects[i].ID == uuid.Nil {
+			req.LocalProjects[i].ID = uuid.New()
+		}
+		projectIDs = append(projectIDs, req.LocalProjects[i].ID)
+	}
+	sessionIDs := make([]uuid.UUID, 0, len(req.LocalSessions))
+	referencedProjects := make([]uuid.UUID, 0, len(req.LocalSessions))
+	for i := range req.LocalSessions {
+		if req.LocalSessions[i].ID == uuid.Nil {
+			req.LocalSessions[i].ID = uuid.New()
+		}
+		sessionIDs = append(sessionIDs, req.LocalSessions[i].ID)
+		if req.LocalSessions[i].ProjectID != nil {
+			referencedProjects = append(referencedProjects, *req.LocalSessions[i].ProjectID)
+		}
+	}
+
+	for _, task := range req.LocalTasks {
+		if task.ProjectID != nil {
+			referencedProjects = append(referencedProjects, *task.ProjectID)
+		}
+	}
+	for _, template := range req.LocalTemplates {
+		if template.ProjectID != nil {
+			referencedProjects = append(referencedProjects, *template.ProjectID)
+		}
+	}
+
+	// The tracking rules may give new sessions one of the user's projects
+	var ruleSet rules.Set
+	if len(req.LocalSessions) > 0 {
+		ruleSet, err = Rules.Load(ctx, userID)
+		if err != nil {
+			return nil, internalError("Failed to load tracking rules", err)
+		}
+		referencedProjects = append(referencedProjects, ruleSet.ProjectIDs()...)
+	}
+
+	readOnlyProjectIDs, err := readOnlyProjects(ctx, tx, userID, projectIDs)
+	if err != nil {
+		return nil, internalError("Failed to validate projects", err)
+	}
+	existingProjects, err := syncedProjectIDs(ctx, tx, userID, projectIDs)
+	if err != nil {
+		return nil, internalError("Failed to validate projects", err)
+	}
+	foreignSessions, err := foreignIDs(ctx, tx, "timer_sessions", userID, sessionIDs)
+	if err != nil {
+		return nil, internalError("Failed to validate sessions", err)
+	}
+	knownProjects, err := syncedProjectIDs(ctx, tx, userID, referencedProjects)
+	if err != nil {
+		return nil, internalError("Failed to validate sessions", err)
+	}
+	lockedProjects, err := projectLocks(ctx, tx, referencedProjects)
+	if err != nil {
+		return nil, internalError("Failed to validate sessions", err)
+	}
+
+	// Find records another device changed since this device last synced
+	resolver := newConflictResolver(userID, req.DeviceID, deviceLastSyncTime)
+	if err := resolver.load(ctx, tx, "projects", "projects", projectIDs); err != nil {
+		return nil, internalError("Failed to check project conflicts", err)
+	}
+	if err := resolver.load(ctx, tx, "sessions", "timer_sessions", sessionIDs); err != nil {
+		return nil, internalError("Failed to check session conflicts", err)
+	}
+
+	// Writes are queued and sent in one batch per collection
+	var writer batchWriter
+
+	// New organization projects need a role that may manage projects
+	orgRoles := make(map[uuid.UUID]string)
+	orgRole := func(orgID uuid.UUID) (string, error) {
+		role, ok := orgRoles[orgID]
+		if ok {
+			return role, nil
+		}
+		role, err := models.GetMemberRole(ctx, orgID, userID)
+		orgRoles[orgID] = role
+		return role, err
+	}
+
+	// New projects count towards the project limit of their workspace
+	quota := newProjectQuota(userID)
+
+	// Process local projects
+	for i, project := range req.LocalProjects {
+		project.UserID = userID
+
+		if message, ok := readOnlyProjectIDs[project.ID]; ok {
+			rejected = append(rejected, SyncItemError{Collection: "projects", Index: i, ID: project.ID, Field: "id", Message: message})
+			continue
+		}
+		if project.OrganizationID != nil {
+			role, err := orgRole(*project.OrganizationID)
+			if err != nil {
+				return nil, internalError("Failed to verify organization membership", err)
+			}
+			if !auth.RoleHas(role, auth.PermManageProjects) {
+				rejected = append(rejected, SyncItemError{Collection: "projects", Index: i, ID: project.ID, Field: "organization_id", Message: "only organization admins can create projects"})
+				continue
+			}
+		}
+		if itemErr := ValidateSyncProject(i, project, storageMode); itemErr != nil {
+			rejected = append(rejected, *itemErr)
+			continue
+		}
+		if !existingProjects[project.ID] {
+			ok, err := quota.take(ctx, project.OrganizationID)
+			if err != nil {
+				return nil, internalError("Failed to check plan limits", err)
+			}
+			if !ok {
+				rejected = append(rejected, SyncItemError{Collection: "projects", Index: i, ID: project.ID, Field: "id", Message: "the plan's project limit is reached"})
+				continue
+			}
+		}
+		project.CreatedAt, project.UpdatedAt = ClientTimestamps(project.CreatedAt, project.UpdatedAt, receivedAt)
+		if !resolver.resolve("projects", project.ID, project, project.UpdatedAt) {
+			knownProjects[project.ID] = true
+			continue
+		}
+
+		query := `
+			INSERT INTO projects (id, user_id, name, description, color, encrypted_name, encrypted_description, key_id,
+				organization_id, device_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			ON CONFLICT (id) DO UPDATE
+			SET name = EXCLUDED.name,
+				description = EXCLUDED.description,
+				color = EXCLUDED.color,
+				encrypted_name = EXCLUDED.encrypted_name,
+				encrypted_description = EXCLUDED.encrypted_description,
+				key_id = EXCLUDED.key_id,
+				device_id = EXCLUDED.device_id,
+				updated_at = EXCLUDED.updated_at
+			WHERE projects.user_id = $2 OR projects.organization_id IS NOT NULL
+		`
+
+		storeErr := SyncItemError{Collection: "projects", Index: i, ID: project.ID, Message: "failed to store project"}
+		name, err := fieldcrypt.Seal(fieldcrypt.ProjectName, project.Name)
+		if err != nil {
+			return nil, internalError("Failed to encrypt project", err)
+		}
+		writer.queue(func() {
+			rejected = append(rejected, storeErr)
+			delete(knownProjects, storeErr.ID)
+		}, query,
+			project.ID,
+			project.UserID,
+			name,
+			project.Description,
+			project.Color,
+			project.EncryptedName,
+			project.EncryptedDescription,
+			project.KeyID,
+			project.OrganizationID,
+			project.DeviceID,
+			project.CreatedAt,
+			project.UpdatedAt,
+		)
+		knownProjects[project.ID] = true
+	}
+	if err := writer.flush(ctx, tx); err != nil {
+		return nil, internalError("Failed to sync projects", err)
+	}
+
+	// A device's first sync may upload its whole history; the sessions the
+	// server does not have yet are copied in. Those sessions also go
+	// through the tracking rules.
+	var storedSessions, newSessions map[uuid.UUID]bool
+	if deviceLastSyncTime.IsZero() || len(ruleSet) > 0 {
+		storedSessions, err = storedIDs(ctx, tx, "timer_sessions", sessionIDs)
+		if err != nil {
+			return nil, internalError("Failed to validate sessions", err)
+		}
+	}
+	if deviceLastSyncTime.IsZero() {
+		newSessions = make(map[uuid.UUID]bool)
+		for _, id := range sessionIDs {
+			newSessions[id] = !storedSessions[id]
+		}
+		writer.copyInto("timer_sessions", syncSessionColumns)
+	}
+	ruleTags := make(map[uuid.UUID][]uuid.UUID)
+	var ruleProjectSessions []uuid.UUID
+
+	// Process local sessions
+	for i, session := range req.LocalSessions {
+		session.UserID = userID
+
+		if foreignSessions[session.ID] {
+			rejected = append(rejected, SyncItemError{Collection: "sessions", Index: i, ID: session.ID, Field: "id", Message: "session belongs to another user"})
+			continue
+		}
+		if itemErr := ValidateSyncSession(i, session, knownProjects, storageMode); itemErr != nil {
+			rejected = append(rejected, *itemErr)
+			continue
+		}
+		if len(ruleSet) > 0 && !storedSessions[session.ID] && session.KeyID == "" {
+			m := ruleSet.Match(session.Description, func(id uuid.UUID) bool { return knownProjects[id] })
+			if session.ProjectID == nil && m.ProjectID != nil {
+				session.ProjectID = m.ProjectID
+				ruleProjectSessions = append(ruleProjectSessions, session.ID)
+			}
+			if len(m.TagIDs) > 0 {
+				ruleTags[session.ID] = m.TagIDs
+			}
+		}
+		if session.ProjectID != nil {
+			if lockedBefore, ok := lockedProjects[*session.ProjectID]; ok && session.StartTime.Before(lockedBefore) {
+				rejected = append(rejected, SyncItemError{Collection: "sessions", Index: i, ID: session.ID, Field: "start_time", Message: "session is before the organization's lock date"})
+				continue
+			}
+		}
+		session.CreatedAt, session.UpdatedAt = ClientTimestamps(session.CreatedAt, session.UpdatedAt, receivedAt)
+		if !resolver.resolve("sessions", session.ID, session, session.UpdatedAt) {
+			continue
+		}
+
+		// timer_sessions is partitioned by start_time, so no unique index
+		// covers id alone for ON CONFLICT. The update moves a session whose
+		// start changed month to its new partition; the insert sees the
+		// table as it was before the update and skips any existing id.
+		query := `
+			WITH updated AS (
+				UPDATE timer_sessions
+				SET project_id = $3,
+					start_time = $4,
+					end_time = $5,
+					description = $6,
+					encrypted_description = $7,
+					key_id = $8,
+					device_id = $9,
+					updated_at = $11,
+					breaks = COALESCE($12::jsonb, breaks)
+				WHERE id = $1 AND user_id = $2
+				RETURNING id
+			)
+			INSERT INTO timer_sessions (id, user_id, project_id, start_time, end_time, description, encrypted_description, key_id,
+				device_id, created_at, updated_at, breaks)
+			SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10::timestamptz, $11, COALESCE($12::jsonb, '[]')
+			WHERE NOT EXISTS (SELECT 1 FROM updated)
+				AND NOT EXISTS (SELECT 1 FROM timer_sessions WHERE id = $1)
+		`
+
+		storeErr := SyncItemError{Collection: "sessions", Index: i, ID: session.ID, Message: "failed to store session"}
+		description, err := fieldcrypt.Seal(fieldcrypt.SessionDescription, session.Description)
+		if err != nil {
+			return nil, internalError("Failed to encrypt session", err)
+		}
+		breaks, err := session.breaksJSON()
+		if err != nil {
+			return nil, internalError("Failed to encode breaks", err)
+		}
+		queue := writer.queue
+		if newSessions[session.ID] {
+			// Later copies of the session in the batch update it
+			newSessions[session.ID] = false
+			queue = writer.queueInsert
+			if breaks == nil {
+				breaks = []byte("[]")
+			}
+		}
+		queue(func() {
+			rejected = append(rejected, storeErr)
+		}, query,
+			session.ID,
+			session.UserID,
+			session.ProjectID,
+			session.StartTime,
+			session.EndTime,
+			description,
+			session.EncryptedDescription,
+			session.KeyID,
+			session.DeviceID,
+			session.CreatedAt,
+			session.UpdatedAt,
+			breaks,
+		)
+	}
+	if err := writer.flush(ctx, tx); err != nil {
+		return nil, internalError("Failed to sync sessions", err)
+	}
+	if err := rules.TagSessions(ctx, tx, userID, ruleTags); err != nil {
+		return nil, internalError("Failed to tag sessions", err)
+	}
+
+	// Process the remaining collections
+	tagErrors, err := applyLocalTags(ctx, tx, userID, req.LocalTags, resolver, receivedAt)
+	if err != nil {
+		return nil, internalError("Failed to sync tags", err)
+	}
+	taskErrors, err := applyLocalTasks(ctx, tx, userID, req.LocalTasks, knownProjects, resolver, receivedAt)
+	if err != nil {
+		return nil, internalError("Failed to sync tasks", err)
+	}
+	templateErrors, err := applyLocalTemplates(ctx, tx, userID, req.LocalTemplates, knownProjects, resolver, receivedAt)
+	if err != nil {
+		return nil, internalError("Failed to sync templates", err)
+	}
+	rejected = append(rejected, tagErrors...)
+	rejected = append(rejected, taskErrors...)
+	rejected = append(rejected, templateErrors...)
+	preferenceErrors, err := applyLocalPreferences(ctx, tx, userID, req.LocalPreferences, receivedAt)
+	if err != nil {
+		return nil, internalError("Failed to sync preferences", err)
+	}
+	rejected = append(rejected, preferenceErrors...)
+	timeOffErrors, err := applyLocalTimeOff(ctx, tx, userID, req.LocalTimeOff, resolver, receivedAt)
+	if err != nil {
+		return nil, internalError("Failed to sync time off", err)
+	}
+	rejected = append(rejected, timeOffErrors...)
+
+	if err := resolver.save(ctx, tx); err != nil {
+		return nil, internalError("Failed to record sync conflicts", err)
+	}
+
+	// Process deletions
+	deletions := []struct {
+		name  string
+		table string
+		ids   []uuid.UUID
+	}{
+		{"sessions", "timer_sessions", req.DeletedSessions},
+		{"tags", "tags", req.DeletedTags},
+		{"tasks", "tasks", req.DeletedTasks},
+		{"templates", "session_templates", req.DeletedTemplates},
+		{"time off", "time_off", req.DeletedTimeOff},
+	}
+	for _, deletion := range deletions {
+		if err := markDeleted(ctx, tx, deletion.table, userID, deletion.ids); err != nil {
+			return nil, internalError("Failed to delete "+deletion.name, err)
+		}
+	}
+	if err := markPreferencesDeleted(ctx, tx, userID, req.DeletedPreferences); err != nil {
+		return nil, internalError("Failed to delete preferences", err)
+	}
+
+	// Projects may be shared with an organization, so deletions are checked
+	// the same way as updates
+	readOnlyDeleted, err := readOnlyProjects(ctx, tx, userID, req.DeletedProjects)
+	if err != nil {
+		return nil, internalError("Failed to delete projects", err)
+	}
+	deletedProjects := make([]uuid.UUID, 0, len(req.DeletedProjects))
+	for i, id := range req.DeletedProjects {
+		if message, ok := readOnlyDeleted[id]; ok {
+			rejected = append(rejected, SyncItemError{Collection: "deleted_projects", Index: i, ID: id, Message: message})
+			continue
+		}
+		deletedProjects = append(deletedProjects, id)
+	}
+	if err := markProjectsDeleted(ctx, tx, deletedProjects); err != nil {
+		return nil, internalError("Failed to delete projects", err)
+	}
+
+	// Get updated server data
+	var serverSessions []Session
+	sessionQuery := `
+		SELECT ` + sessionColumns + `
+		FROM timer_sessions
+		WHERE user_id = $1 AND server_updated_at > $2 AND server_updated_at < $3
+	`
+	rows, err := tx.Query(ctx, sessionQuery, userID, deviceLastSyncTime, txStart)
+	if err != nil {
+		return nil, internalError("Failed to fetch server sessions", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, internalError("Failed to scan session", err)
+		}
+		serverSessions = append(serverSessions, session)
+	}
+
+	// The device does not know the projects the tracking rules assigned, so
+	// those sessions are echoed back
+	if len(ruleProjectSessions) > 0 {
+		rows, err := tx.Query(ctx, `
+			SELECT `+sessionColumns+`
+			FROM timer_sessions
+			WHERE id = ANY($1) AND user_id = $2 AND is_deleted = false
+		`, ruleProjectSessions, userID)
+		if err != nil {
+			return nil, internalError("Failed to fetch server sessions", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			session, err := scanSession(rows)
+			if err != nil {
+				return nil, internalError("Failed to scan session", err)
+			}
+			serverSessions = append(serverSessions, session)
+		}
+	}
+
+	var serverProjects []Project
+	projectQuery := `
+		SELECT ` + projectColumns + `
+		FROM projects
+		WHERE ` + SyncedProjectSQL(1) + ` AND server_updated_at > $2 AND server_updated_at < $3
+	`
+	rows, err = tx.Query(ctx, projectQuery, userID, deviceLastSyncTime, txStart)
+	if err != nil {
+		return nil, internalError("Failed to fetch server projects", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, internalError("Failed to scan project", err)
+		}
+		serverProjects = append(serverProjects, project)
+	}
+
+	serverTags, err := changedTags(ctx, tx, userID, deviceLastSyncTime, txStart)
+	if err != nil {
+		return nil, internalError("Failed to fetch server tags", err)
+	}
+	serverTasks, err := changedTasks(ctx, tx, userID, deviceLastSyncTime, txStart)
+	if err != nil {
+		return nil, internalError("Failed to fetch server tasks", err)
+	}
+	serverTemplates, err := changedTemplates(ctx, tx, userID, deviceLastSyncTime, txStart)
+	if err != nil {
+		return nil, internalError("Failed to fetch server templates", err)
+	}
+	serverPreferences, err := changedPreferences(ctx, tx, userID, deviceLastSyncTime, txStart)
+	if err != nil {
+		return nil, internalError("Failed to fetch server preferences", err)
+	}
+	serverTimeOff, err := changedTimeOff(ctx, tx, userID, deviceLastSyncTime, txStart)
+	if err != nil {
+		return nil, internalError("Failed to fetch server time off", err)
+	}
+
+	response := SyncResponse{
+		LastSyncTime:       cursor,
+		ServerSessions:     serverSessions,
+		ServerProjects:     serverProjects,
+		ServerTags:         serverTags,
+		ServerTasks:        serverTasks,
+		ServerTemplates:    serverTemplates,
+		ServerPreferences:  serverPreferences,
+		ServerTimeOff:      serverTimeOff,
+		Rejected:           rejected,
+		Conflicts:          resolver.conflicts,
+		FullResyncRequired: fullResyncRequired,
+	}
+
+	body, err := MarshalBody(contentType, &response)
+	if err != nil {
+		return nil, internalError("Failed to encode response", err)
+	}
+
+	if err := onboarding.Synced(ctx, tx, userID, req.Platform); err != nil {
+		return nil, internalError("Failed to update onboarding", err)
+	}
+
+	// Store the response with the key so retries replay it
+	if idempotencyKey != "" {
+		if err := saveIdempotentResponse(ctx, tx, userID, idempotencyKey, contentType, body); err != nil {
+			return nil, internalError("Failed to store idempotent response", err)
+		}
+	}
+
+	// Commit transaction
+	if err := tx.Commit(ctx); err != nil {
+		return nil, internalError("Failed to commit transaction", err)
+	}
+
+	opstats.Add(ctx, opstats.SyncRequests, 1)
+	opstats.Add(ctx, opstats.SyncItemsReceived, int64(syncRequestItems(req)))
+	opstats.Add(ctx, opstats.SyncItemsSent, int64(syncResponseItems(&response)))
+
+	return &SyncResult{Response: &response, ContentType: contentType, Body: body}, nil
+}
+
+// syncRequestItems counts the changes a device sent
+func syncRequestItems(req *SyncRequest) int {
+	return len(req.LocalSessions) + len(req.LocalProjects) + len(req.LocalTags) + len(req.LocalTasks) +
+		len(req.LocalTemplates) + len(req.LocalPreferences) + len(req.DeletedSessions) + len(req.DeletedProjects) +
+		len(req.DeletedTags) + len(req.DeletedTasks) + len(req.DeletedTemplates) + len(req.DeletedPreferences) +
+		len(req.LocalTimeOff) + len(req.DeletedTimeOff)
+}
+
+// syncResponseItems counts the changes sent back to a device
+func syncResponseItems(response *SyncResponse) int {
+	return len(response.ServerSessions) + len(response.ServerProjects) + len(response.ServerTags) +
+		len(response.ServerTasks) + len(response.ServerTemplates) + len(response.ServerPreferences) +
+		len(response.ServerTimeOff)
+}
+
+// syncCursor returns the start time of tx and the cursor a client may resume
+// from after this sync. Rows are stamped with their transaction's start time,
+// so the cursor is held back below the start of the oldest other open
+// transaction: its rows are not visible yet but will sort before tx's, and
+// may be stamped with the very microsecond it started. Transactions that
+// have not written yet count too, as they may write before they commit.
+func syncCursor(ctx context.Context, tx pgx.Tx) (time.Time, time.Time, error) {
+	var txStart, cursor time.Time
+	err := tx.QueryRow(ctx, `
+		SELECT CURRENT_TIMESTAMP,
+			LEAST(CURRENT_TIMESTAMP, COALESCE(MIN(xact_start) - INTERVAL '1 microsecond', CURRENT_TIMESTAMP))
+		FROM pg_stat_activity
+		WHERE datname = current_database()
+		  AND backend_type = 'client backend'
+		  AND xact_start IS NOT NULL
+		  AND pid <> pg_backend_pid()
+	`).Scan(&txStart, &cursor)
+	return txStart, cursor, err
+}
+
+// recordDeviceAck marks that the device has seen every change up to
+// lastSyncTime. A zero lastSyncTime means the device is doing a full sync,
+// which clears any pending resync flag. Empty names keep the stored value.
+func recordDeviceAck(ctx context.Context, tx pgx.Tx, userID uuid.UUID, deviceID, deviceName, platform string, lastSyncTime time.Time) (bool, error) {
+	var needsFullResync bool
+	err := tx.QueryRow(ctx, `
+		INSERT INTO device_sync (user_id, device_id, device_name, platform, acked_through)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, device_id) DO UPDATE
+		SET last_sync_time = CURRENT_TIMESTAMP,
+			device_name = COALESCE(NULLIF(EXCLUDED.device_name, ''), device_sync.device_name),
+			platform = COALESCE(NULLIF(EXCLUDED.platform, ''), device_sync.platform),
+			acked_through = GREATEST(device_sync.acked_through, EXCLUDED.acked_through),
+			needs_full_resync = device_sync.needs_full_resync AND $6,
+			stale_at = NULL
+		RETURNING needs_full_resync
+	`, userID, deviceID, deviceName, platform, lastSyncTime, !lastSyncTime.IsZero()).Scan(&needsFullResync)
+	return needsFullResync, err
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// minCopyRows is how many new rows it takes for flush to copy them in
+// rather than send their writes in a batch
+const minCopyRows = 100
+
+// batchWriter collects single-row sync writes and sends them to the database
+// in one round trip. Large offline batches would otherwise cost one round trip
+// (plus a savepoint) per row.
+type batchWriter struct {
+	items []batchItem
+	// inserts are rows known not to exist yet, as queued by queueInsert
+	inserts     []batchItem
+	copyTable   string
+	copyColumns []string
+}
+
+type batchItem struct {
+	query   string
+	args    []interface{}
+	onError func()
+}
+
+// queue adds a write. onError is called if the row cannot be stored.
+func (b *batchWriter) queue(onError func(), query string, args ...interface{}) {
+	b.items = append(b.items, batchItem{query: query, args: args, onError: onError})
+}
+
+// copyInto sets the table and columns rows queued with queueInsert are
+// copied into
+func (b *batchWriter) copyInto(table string, columns []string) {
+	b.copyTable, b.copyColumns = table, columns
+}
+
+// queueInsert adds a write of a row that does not exist yet, such as those
+// of a device's first upload. args are the row's values in the order of
+// the copyInto columns, and also the arguments of query, which writes the
+// row when there are too few of them to copy or the copy fails.
+func (b *batchWriter) queueInsert(onError func(), query string, args ...interface{}) {
+	b.inserts = append(b.inserts, batchItem{query: query, args: args, onError: onError})
+}
+
+// flush runs every queued write inside a savepoint. If any of them fails the
+// savepoint is rolled back and the writes are replayed one by one, so only
+// the offending rows are rejected. Queued inserts are copied in first.
+func (b *batchWriter) flush(ctx context.Context, tx pgx.Tx) error {
+	items, err := b.copyInserts(ctx, tx)
+	if err != nil {
+		return err
+	}
+	items = append(items, b.items...)
+	b.items = nil
+	if len(items) == 0 {
+		return nil
+	}
+
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	batch := &pgx.Batch{}
+	for _, item := range items {
+		batch.Queue(item.query, item.args...)
+	}
+	if err := savepoint.SendBatch(ctx, batch).Close(); err == nil {
+		return savepoint.Commit(ctx)
+	}
+	if err := savepoint.Rollback(ctx); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if err := execItem(ctx, tx, item.query, item.args...); err != nil {
+			item.onError()
+		}
+	}
+	return nil
+}
+
+// copyInserts copies the queued inserts in a savepoint, returning those that
+// are left to write one by one: all of them if there are only a few or the
+// copy fails, as then the failing rows are to be found
+func (b *batchWriter) copyInserts(ctx context.Context, tx pgx.Tx) ([]batchItem, error) {
+	inserts := b.inserts
+	b.inserts = nil
+	if len(inserts) < minCopyRows {
+		return inserts, nil
+	}
+
+	rows := make([][]interface{}, len(inserts))
+	for i, item := range inserts {
+		rows[i] = item.args
+	}
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.CopyRows(ctx, savepoint, b.copyTable, b.copyColumns, rows); err == nil {
+		return nil, savepoint.Commit(ctx)
+	}
+	return inserts, savepoint.Rollback(ctx)
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// queueRows queues a write of each row, returning the rows reported as
+// rejected
+func queueRows(writer *batchWriter, insert bool, rows ...string) *[]string {
+	var rejected []string
+	for _, row := range rows {
+		row := row
+		onError := func() { rejected = append(rejected, row) }
+		if insert {
+			writer.queueInsert(onError, "INSERT", row)
+		} else {
+			writer.queue(onError, "UPSERT", row)
+		}
+	}
+	return &rejected
+}
+
+// rowNames names n rows with prefix
+func rowNames(prefix string, n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s%d", prefix, i)
+	}
+	return names
+}
+
+func TestBatchWriterFlush(t *testing.T) {
+	ctx := context.Background()
+	many := rowNames("insert", minCopyRows)
+
+	tests := []struct {
+		name    string
+		inserts []string
+		updates []string
+		failing []interface{}
+		// round trips expected
+		copies, batches, statements int
+	}{
+		{
+			name:    "a batch",
+			inserts: []string{"insert"},
+			updates: []string{"update0", "update1"},
+			batches: 1,
+		},
+		{
+			name:       "a failed batch replayed row by row",
+			inserts:    []string{"insert"},
+			updates:    []string{"update0", "bad", "update1"},
+			failing:    []interface{}{"bad"},
+			batches:    1,
+			statements: 4,
+		},
+		{
+			name:    "a copy",
+			inserts: many,
+			updates: []string{"update0"},
+			copies:  1,
+			batches: 1,
+		},
+		{
+			name:       "a failed copy replayed row by row",
+			inserts:    append(append([]string{}, many[:50]...), append([]string{"bad"}, many[50:]...)...),
+			updates:    []string{"update0"},
+			failing:    []interface{}{"bad"},
+			copies:     1,
+			batches:    1,
+			statements: minCopyRows + 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx := newFakeTx(test.failing...)
+			writer := &batchWriter{}
+			writer.copyInto("timer_sessions", []string{"id"})
+			rejectedInserts := queueRows(writer, true, test.inserts...)
+			rejectedUpdates := queueRows(writer, false, test.updates...)
+
+			if err := writer.flush(ctx, tx); err != nil {
+				t.Fatal(err)
+			}
+
+			var want []interface{}
+			var wantRejected []string
+			for _, row := range append(append([]string{}, test.inserts...), test.updates...) {
+				if row == "bad" {
+					wantRejected = append(wantRejected, row)
+					continue
+				}
+				want = append(want, row)
+			}
+			if !reflect.DeepEqual(tx.rows, want) {
+				t.Errorf("wrote %v, want %v", tx.rows, want)
+			}
+			if rejected := append(*rejectedInserts, *rejectedUpdates...); !reflect.DeepEqual(rejected, wantRejected) {
+				t.Errorf("rejected %v, want %v", rejected, wantRejected)
+			}
+			if tx.aborted {
+				t.Error("the transaction was left aborted")
+			}
+			root := tx.root
+			if root.copies != test.copies || root.batches != test.batches || root.statements != test.statements {
+				t.Errorf("made %d copies, %d batches and %d statements, want %d, %d and %d",
+					root.copies, root.batches, root.statements, test.copies, test.batches, test.statements)
+			}
+			if len(writer.items) != 0 || len(writer.inserts) != 0 {
+				t.Error("flush left writes queued")
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+)
+
+//msgp:tag json
+//msgp:newtime
+//msgp:shim uuid.UUID as:[]byte using:uuidToBytes/uuidFromBytes
+//msgp:shim json.RawMessage as:[]byte using:rawToBytes/bytesToRaw
+
+// Conflict resolutions recorded in sync_conflicts
+const (
+	resolutionClientWins = "client_wins"
+	resolutionServerWins = "server_wins"
+)
+
+// SyncConflict is a record that was changed both on the server and on the
+// syncing device since that device's last sync
+type SyncConflict struct {
+	ID             uuid.UUID       `json:"id"`
+	Collection     string          `json:"collection"`
+	EntityID       uuid.UUID       `json:"entity_id"`
+	ServerVersion  json.RawMessage `json:"server_version"`
+	ClientVersion  json.RawMessage `json:"client_version"`
+	ServerDeviceID string          `json:"server_device_id"`
+	ClientDeviceID string          `json:"client_device_id"`
+	Resolution     string          `json:"resolution"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// serverVersion is the stored state of a row that changed after the
+// syncing device's cursor
+type serverVersion struct {
+	data      []byte
+	deviceID  string
+	updatedAt time.Time
+}
+
+// conflictResolver detects concurrent edits during a sync and resolves them
+// by last-writer-wins on updated_at, logging every conflict it sees
+type conflictResolver struct {
+	userID    uuid.UUID
+	deviceID  string
+	since     time.Time
+	changed   map[string]map[uuid.UUID]serverVersion
+	conflicts []SyncConflict
+}
+
+func newConflictResolver(userID uuid.UUID, deviceID string, since time.Time) *conflictResolver {
+	return &conflictResolver{
+		userID:   userID,
+		deviceID: deviceID,
+		since:    since,
+		changed:  make(map[string]map[uuid.UUID]serverVersion),
+	}
+}
+
+// load fetches the rows among ids in table that another device changed
+// after the cursor
+func (c *conflictResolver) load(ctx context.Context, tx pgx.Tx, collection, table string, ids []uuid.UUID) error {
+	changed := make(map[uuid.UUID]serverVersion)
+	c.changed[collection] = changed
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(`
+		SELECT t.id, row_to_json(t)::text, COALESCE(t.device_id, ''), t.updated_at
+		FROM %s t
+		WHERE t.user_id = $1 AND t.id = ANY($2) AND t.server_updated_at > $3
+		  AND COALESCE(t.device_id, '') <> $4
+	`, table), c.userID, ids, c.since, c.deviceID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var version serverVersion
+		var data string
+		if err := rows.Scan(&id, &data, &version.deviceID, &version.updatedAt); err != nil {
+			return err
+		}
+		if version.data, err = openVersion(collection, []byte(data)); err != nil {
+			return err
+		}
+		changed[id] = version
+	}
+	return rows.Err()
+}
+
+// sealedColumns are the columns of the synced collections that fieldcrypt
+// seals, keyed by collection
+var sealedColumns = map[string]struct {
+	name  string
+	field fieldcrypt.Field
+}{
+	"sessions": {"description", fieldcrypt.SessionDescription},
+	"projects": {"name", fieldcrypt.ProjectName},
+}
+
+// openVersion decrypts the sealed column of a row of collection encoded by
+// row_to_json, so that conflicts show both versions in plaintext
+func openVersion(collection string, data []byte) ([]byte, error) {
+	column, ok := sealedColumns[collection]
+	if !ok {
+		return data, nil
+	}
+	var row map[string]json.RawMessage
+	if err := json.Unmarshal(data, &row); err != nil {
+		return nil, err
+	}
+	raw, ok := row[column.name]
+	if !ok {
+		return data, nil
+	}
+	var value *string
+	if err := json.Unmarshal(raw, &value); err != nil || value == nil {
+		return data, err
+	}
+	opened, err := fieldcrypt.Open(column.field, *value)
+	if err != nil || opened == *value {
+		return data, err
+	}
+	if row[column.name], err = json.Marshal(opened); err != nil {
+		return nil, err
+	}
+	return json.Marshal(row)
+}
+
+// resolve reports whether the client's version of the entity should be
+// written. Conflicts are recorded either way.
+func (c *conflictResolver) resolve(collection string, id uuid.UUID, clientVersion interface{}, clientUpdatedAt time.Time) bool {
+	server, ok := c.changed[collection][id]
+	if !ok {
+		return true
+	}
+
+	resolution := resolutionClientWins
+	if clientUpdatedAt.Before(server.updatedAt) {
+		resolution = resolutionServerWins
+	}
+
+	clientData, err := json.Marshal(clientVersion)
+	if err != nil {
+		clientData = []byte("null")
+	}
+	c.conflicts = append(c.conflicts, SyncConflict{
+		ID:             uuid.New(),
+		Collection:     collection,
+		EntityID:       id,
+		ServerVersion:  server.data,
+		ClientVersion:  clientData,
+		ServerDeviceID: server.deviceID,
+		ClientDeviceID: c.deviceID,
+		Resolution:     resolution,
+		CreatedAt:      time.Now(),
+	})
+	return resolution == resolutionClientWins
+}
+
+// save persists the detected conflicts
+func (c *conflictResolver) save(ctx context.Context, tx pgx.Tx) error {
+	for _, conflict := range c.conflicts {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO sync_conflicts (id, user_id, collection, entity_id, server_version, client_version,
+				server_device_id, client_device_id, resolution, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`, conflict.ID, c.userID, conflict.Collection, conflict.EntityID, []byte(conflict.ServerVersion),
+			[]byte(conflict.ClientVersion), conflict.ServerDeviceID, conflict.ClientDeviceID, conflict.Resolution,
+			conflict.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestConflictResolverResolve(t *testing.T) {
+	edited := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name            string
+		clientUpdatedAt time.Time
+		resolution      string
+	}{
+		{"client edited later", edited.Add(time.Minute), resolutionClientWins},
+		{"client edited at the same time", edited, resolutionClientWins},
+		{"client edited earlier", edited.Add(-time.Minute), resolutionServerWins},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id := uuid.New()
+			resolver := newConflictResolver(uuid.New(), "laptop", edited.Add(-time.Hour))
+			resolver.changed["sessions"] = map[uuid.UUID]serverVersion{
+				id: {data: []byte(`{"description":"Reading"}`), deviceID: "phone", updatedAt: edited},
+			}
+			before := time.Now()
+
+			write := resolver.resolve("sessions", id, Session{ID: id, Description: "Writing"}, test.clientUpdatedAt)
+			if write != (test.resolution == resolutionClientWins) {
+				t.Errorf("resolve returned %v for %s", write, test.resolution)
+			}
+			if len(resolver.conflicts) != 1 {
+				t.Fatalf("recorded %d conflicts, want 1", len(resolver.conflicts))
+			}
+			conflict := resolver.conflicts[0]
+			if conflict.Resolution != test.resolution || conflict.EntityID != id ||
+				conflict.ServerDeviceID != "phone" || conflict.ClientDeviceID != "laptop" {
+				t.Errorf("recorded %+v", conflict)
+			}
+			if conflict.CreatedAt.Before(before) {
+				t.Errorf("the conflict was created at %v, before it was detected", conflict.CreatedAt)
+			}
+		})
+	}
+
+	resolver := newConflictResolver(uuid.New(), "laptop", edited)
+	if !resolver.resolve("sessions", uuid.New(), Session{}, edited) || len(resolver.conflicts) != 0 {
+		t.Error("an entity unchanged on the server was treated as a conflict")
+	}
+}
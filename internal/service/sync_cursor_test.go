@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/dbtest"
+)
+
+func TestSyncCursorWaitsForOverlappingWriters(t *testing.T) {
+	dbtest.Open(t)
+	ctx := context.Background()
+	userID := dbtest.User(t)
+
+	// The first writer starts before the sync but only writes after it
+	first, err := db.Pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Rollback(ctx)
+	var firstStart time.Time
+	if err := first.QueryRow(ctx, `SELECT CURRENT_TIMESTAMP`).Scan(&firstStart); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second writer syncs while the first is still open
+	synced, err := Sync(ctx, userID, testSyncRequest(uuid.New(), "Writing"), SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor := synced.Response.LastSyncTime
+	if !cursor.Before(firstStart) {
+		t.Errorf("the cursor %v is not before the open transaction started at %v", cursor, firstStart)
+	}
+
+	id := uuid.New()
+	_, err = first.Exec(ctx, `
+		INSERT INTO timer_sessions (id, user_id, start_time, end_time, description, device_id)
+		VALUES ($1, $2, $3, $3 + INTERVAL '1 hour', 'Reading', 'phone')
+	`, id, userID, firstStart.Add(-2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Resuming from the cursor picks up what the first writer committed
+	resumed, err := Sync(ctx, userID, &SyncRequest{DeviceID: "laptop", LastSyncTime: cursor}, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, session := range resumed.Response.ServerSessions {
+		if session.ID == id {
+			return
+		}
+	}
+	t.Errorf("the session written by the overlapping transaction was never sent")
+}
@@ -0,0 +1,531 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+//msgp:tag json
+//msgp:newtime
+//msgp:shim uuid.UUID as:[]byte using:uuidToBytes/uuidFromBytes
+//msgp:shim json.RawMessage as:[]byte using:rawToBytes/bytesToRaw
+
+// Entities below are only exchanged through sync. They follow the same rules
+// as sessions and projects: last write wins on upsert, and deletions are
+// tombstones (is_deleted) that are garbage collected later.
+
+type Tag struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	DeviceID  string    `json:"device_id"`
+	IsDeleted bool      `json:"is_deleted"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Task struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	ProjectID   *uuid.UUID `json:"project_id,omitempty"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	IsCompleted bool       `json:"is_completed"`
+	// EstimateSeconds is the time the task is expected to take, 0 for no
+	// estimate. Writes without it keep the stored one, so clients unaware of
+	// estimates do not drop them.
+	EstimateSeconds *int64    `json:"estimate_seconds,omitempty"`
+	DeviceID        string    `json:"device_id"`
+	IsDeleted       bool      `json:"is_deleted"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type SessionTemplate struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	ProjectID   *uuid.UUID `json:"project_id,omitempty"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	DeviceID    string     `json:"device_id"`
+	IsDeleted   bool       `json:"is_deleted"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Preference is a single keyed user setting with an arbitrary JSON value
+type Preference struct {
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	DeviceID  string          `json:"device_id"`
+	IsDeleted bool            `json:"is_deleted"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// TimeOff is a range of days the user is away, such as a vacation. Dates
+// are YYYY-MM-DD without a time zone and both ends are included. Unlike the
+// entities above, time off can also be written through the API.
+type TimeOff struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	// Type is one of TimeOffTypes
+	Type      string    `json:"type"`
+	StartDate string    `json:"start_date"`
+	EndDate   string    `json:"end_date"`
+	Note      string    `json:"note"`
+	DeviceID  string    `json:"device_id"`
+	IsDeleted bool      `json:"is_deleted"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const (
+	maxSyncEntityItems     = 1000
+	maxPreferenceKeyLength = 100
+	maxPreferenceBytes     = 16 << 10 // 16 KB
+)
+
+var preferenceKeyPattern = regexp.MustCompile(`^[a-z0-9_.\-]+$`)
+
+// applyLocalTags upserts the client's tags and returns the rejected ones
+func applyLocalTags(ctx context.Context, tx pgx.Tx, userID uuid.UUID, tags []Tag, resolver *conflictResolver, now time.Time) ([]SyncItemError, error) {
+	ids := make([]uuid.UUID, 0, len(tags))
+	for i := range tags {
+		if tags[i].ID == uuid.Nil {
+			tags[i].ID = uuid.New()
+		}
+		ids = append(ids, tags[i].ID)
+	}
+	foreign, err := foreignIDs(ctx, tx, "tags", userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolver.load(ctx, tx, "tags", "tags", ids); err != nil {
+		return nil, err
+	}
+
+	var rejected []SyncItemError
+	var writer batchWriter
+	for i, tag := range tags {
+		fail := func(field, message string) {
+			rejected = append(rejected, SyncItemError{Collection: "tags", Index: i, ID: tag.ID, Field: field, Message: message})
+		}
+
+		switch {
+		case foreign[tag.ID]:
+			fail("id", "tag belongs to another user")
+			continue
+		case tag.Name == "":
+			fail("name", "name is required")
+			continue
+		case utf8.RuneCountInString(tag.Name) > MaxNameLength:
+			fail("name", fmt.Sprintf("name must be at most %d characters", MaxNameLength))
+			continue
+		case tag.Color != "" && !validate.ValidColor(tag.Color):
+			fail("color", "color must be a hex value like #1a2b3c")
+			continue
+		case len(tag.DeviceID) > maxDeviceIDLength:
+			fail("device_id", fmt.Sprintf("device_id must be at most %d bytes", maxDeviceIDLength))
+			continue
+		}
+
+		createdAt, updatedAt := ClientTimestamps(tag.CreatedAt, tag.UpdatedAt, now)
+		if !resolver.resolve("tags", tag.ID, tag, updatedAt) {
+			continue
+		}
+		storeErr := SyncItemError{Collection: "tags", Index: i, ID: tag.ID, Message: "failed to store tag"}
+		writer.queue(func() { rejected = append(rejected, storeErr) }, `
+			INSERT INTO tags (id, user_id, name, color, device_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (id) DO UPDATE
+			SET name = EXCLUDED.name,
+				color = EXCLUDED.color,
+				device_id = EXCLUDED.device_id,
+				updated_at = EXCLUDED.updated_at
+			WHERE tags.user_id = $2
+		`, tag.ID, userID, tag.Name, tag.Color, tag.DeviceID, createdAt, updatedAt)
+	}
+	if err := writer.flush(ctx, tx); err != nil {
+		return nil, err
+	}
+	return rejected, nil
+}
+
+// applyLocalTasks upserts the client's tasks and returns the rejected ones
+func applyLocalTasks(ctx context.Context, tx pgx.Tx, userID uuid.UUID, tasks []Task, knownProjects map[uuid.UUID]bool, resolver *conflictResolver, now time.Time) ([]SyncItemError, error) {
+	ids := make([]uuid.UUID, 0, len(tasks))
+	for i := range tasks {
+		if tasks[i].ID == uuid.Nil {
+			tasks[i].ID = uuid.New()
+		}
+		ids = append(ids, tasks[i].ID)
+	}
+	foreign, err := foreignIDs(ctx, tx, "tasks", userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolver.load(ctx, tx, "tasks", "tasks", ids); err != nil {
+		return nil, err
+	}
+
+	var rejected []SyncItemError
+	var writer batchWriter
+	for i, task := range tasks {
+		fail := func(field, message string) {
+			rejected = append(rejected, SyncItemError{Collection: "tasks", Index: i, ID: task.ID, Field: field, Message: message})
+		}
+
+		switch {
+		case foreign[task.ID]:
+			fail("id", "task belongs to another user")
+			continue
+		case task.Name == "":
+			fail("name", "name is required")
+			continue
+		case utf8.RuneCountInString(task.Name) > MaxNameLength:
+			fail("name", fmt.Sprintf("name must be at most %d characters", MaxNameLength))
+			continue
+		case utf8.RuneCountInString(task.Description) > MaxDescriptionLength:
+			fail("description", fmt.Sprintf("description must be at most %d characters", MaxDescriptionLength))
+			continue
+		case len(task.DeviceID) > maxDeviceIDLength:
+			fail("device_id", fmt.Sprintf("device_id must be at most %d bytes", maxDeviceIDLength))
+			continue
+		case task.EstimateSeconds != nil && (*task.EstimateSeconds < 0 || *task.EstimateSeconds > MaxTaskEstimate):
+			fail("estimate_seconds", fmt.Sprintf("estimate_seconds must be between 0 and %d", MaxTaskEstimate))
+			continue
+		case task.ProjectID != nil && !knownProjects[*task.ProjectID]:
+			fail("project_id", "project does not exist")
+			continue
+		}
+
+		createdAt, updatedAt := ClientTimestamps(task.CreatedAt, task.UpdatedAt, now)
+		if !resolver.resolve("tasks", task.ID, task, updatedAt) {
+			continue
+		}
+		storeErr := SyncItemError{Collection: "tasks", Index: i, ID: task.ID, Message: "failed to store task"}
+		writer.queue(func() { rejected = append(rejected, storeErr) }, `
+			INSERT INTO tasks (id, user_id, project_id, name, description, is_completed, device_id, created_at, updated_at, estimate_seconds)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10::integer, 0))
+			ON CONFLICT (id) DO UPDATE
+			SET project_id = EXCLUDED.project_id,
+				name = EXCLUDED.name,
+				description = EXCLUDED.description,
+				is_completed = EXCLUDED.is_completed,
+				device_id = EXCLUDED.device_id,
+				updated_at = EXCLUDED.updated_at,
+				estimate_seconds = COALESCE($10::integer, tasks.estimate_seconds)
+			WHERE tasks.user_id = $2
+		`, task.ID, userID, task.ProjectID, task.Name, task.Description, task.IsCompleted, task.DeviceID, createdAt, updatedAt, task.EstimateSeconds)
+	}
+	if err := writer.flush(ctx, tx); err != nil {
+		return nil, err
+	}
+	return rejected, nil
+}
+
+// applyLocalTemplates upserts the client's session templates and returns the
+// rejected ones
+func applyLocalTemplates(ctx context.Context, tx pgx.Tx, userID uuid.UUID, templates []SessionTemplate, knownProjects map[uuid.UUID]bool, resolver *conflictResolver, now time.Time) ([]SyncItemError, error) {
+	ids := make([]uuid.UUID, 0, len(templates))
+	for i := range templates {
+		if templates[i].ID == uuid.Nil {
+			templates[i].ID = uuid.New()
+		}
+		ids = append(ids, templates[i].ID)
+	}
+	foreign, err := foreignIDs(ctx, tx, "session_templates", userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolver.load(ctx, tx, "templates", "session_templates", ids); err != nil {
+		return nil, err
+	}
+
+	var rejected []SyncItemError
+	var writer batchWriter
+	for i, template := range templates {
+		fail := func(field, message string) {
+			rejected = append(rejected, SyncItemError{Collection: "templates", Index: i, ID: template.ID, Field: field, Message: message})
+		}
+
+		switch {
+		case foreign[template.ID]:
+			fail("id", "template belongs to another user")
+			continue
+		case template.Name == "":
+			fail("name", "name is required")
+			continue
+		case utf8.RuneCountInString(template.Name) > MaxNameLength:
+			fail("name", fmt.Sprintf("name must be at most %d characters", MaxNameLength))
+			continue
+		case utf8.RuneCountInString(template.Description) > MaxDescriptionLength:
+			fail("description", fmt.Sprintf("description must be at most %d characters", MaxDescriptionLength))
+			continue
+		case len(template.DeviceID) > maxDeviceIDLength:
+			fail("device_id", fmt.Sprintf("device_id must be at most %d bytes", maxDeviceIDLength))
+			continue
+		case template.ProjectID != nil && !knownProjects[*template.ProjectID]:
+			fail("project_id", "project does not exist")
+			continue
+		}
+
+		createdAt, updatedAt := ClientTimestamps(template.CreatedAt, template.UpdatedAt, now)
+		if !resolver.resolve("templates", template.ID, template, updatedAt) {
+			continue
+		}
+		storeErr := SyncItemError{Collection: "templates", Index: i, ID: template.ID, Message: "failed to store template"}
+		writer.queue(func() { rejected = append(rejected, storeErr) }, `
+			INSERT INTO session_templates (id, user_id, project_id, name, description, device_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (id) DO UPDATE
+			SET project_id = EXCLUDED.project_id,
+				name = EXCLUDED.name,
+				description = EXCLUDED.description,
+				device_id = EXCLUDED.device_id,
+				updated_at = EXCLUDED.updated_at
+			WHERE session_templates.user_id = $2
+		`, template.ID, userID, template.ProjectID, template.Name, template.Description, template.DeviceID, createdAt, updatedAt)
+	}
+	if err := writer.flush(ctx, tx); err != nil {
+		return nil, err
+	}
+	return rejected, nil
+}
+
+// applyLocalTimeOff upserts the client's time off and returns the rejected
+// entries
+func applyLocalTimeOff(ctx context.Context, tx pgx.Tx, userID uuid.UUID, timeOff []TimeOff, resolver *conflictResolver, now time.Time) ([]SyncItemError, error) {
+	ids := make([]uuid.UUID, 0, len(timeOff))
+	for i := range timeOff {
+		if timeOff[i].ID == uuid.Nil {
+			timeOff[i].ID = uuid.New()
+		}
+		ids = append(ids, timeOff[i].ID)
+	}
+	foreign, err := foreignIDs(ctx, tx, "time_off", userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolver.load(ctx, tx, "time_off", "time_off", ids); err != nil {
+		return nil, err
+	}
+
+	var rejected []SyncItemError
+	var writer batchWriter
+	for i, t := range timeOff {
+		if foreign[t.ID] {
+			rejected = append(rejected, SyncItemError{Collection: "time_off", Index: i, ID: t.ID, Field: "id", Message: "time off belongs to another user"})
+			continue
+		}
+		if rejectedItem := rejection("time_off", i, t.ID, t); rejectedItem != nil {
+			rejected = append(rejected, *rejectedItem)
+			continue
+		}
+
+		createdAt, updatedAt := ClientTimestamps(t.CreatedAt, t.UpdatedAt, now)
+		if !resolver.resolve("time_off", t.ID, t, updatedAt) {
+			continue
+		}
+		storeErr := SyncItemError{Collection: "time_off", Index: i, ID: t.ID, Message: "failed to store time off"}
+		writer.queue(func() { rejected = append(rejected, storeErr) }, `
+			INSERT INTO time_off (id, user_id, type, start_date, end_date, note, device_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (id) DO UPDATE
+			SET type = EXCLUDED.type,
+				start_date = EXCLUDED.start_date,
+				end_date = EXCLUDED.end_date,
+				note = EXCLUDED.note,
+				device_id = EXCLUDED.device_id,
+				updated_at = EXCLUDED.updated_at
+			WHERE time_off.user_id = $2
+		`, t.ID, userID, t.Type, t.StartDate, t.EndDate, t.Note, t.DeviceID, createdAt, updatedAt)
+	}
+	if err := writer.flush(ctx, tx); err != nil {
+		return nil, err
+	}
+	return rejected, nil
+}
+
+// applyLocalPreferences upserts the client's preferences and returns the
+// rejected ones
+func applyLocalPreferences(ctx context.Context, tx pgx.Tx, userID uuid.UUID, preferences []Preference, now time.Time) ([]SyncItemError, error) {
+	var rejected []SyncItemError
+	var writer batchWriter
+	for i, preference := range preferences {
+		fail := func(field, message string) {
+			rejected = append(rejected, SyncItemError{Collection: "preferences", Index: i, Key: preference.Key, Field: field, Message: message})
+		}
+
+		switch {
+		case preference.Key == "" || len(preference.Key) > maxPreferenceKeyLength || !preferenceKeyPattern.MatchString(preference.Key):
+			fail("key", "key must be 1-100 characters of a-z, 0-9, '_', '.' or '-'")
+			continue
+		case len(preference.Value) == 0 || !json.Valid(preference.Value):
+			fail("value", "value must be valid JSON")
+			continue
+		case len(preference.Value) > maxPreferenceBytes:
+			fail("value", "value is too large")
+			continue
+		case len(preference.DeviceID) > maxDeviceIDLength:
+			fail("device_id", fmt.Sprintf("device_id must be at most %d bytes", maxDeviceIDLength))
+			continue
+		}
+		// The typed preferences the API serves must stay readable by it
+		if err := models.ValidatePreference(preference.Key, preference.Value); err != nil {
+			fail("value", err.Error())
+			continue
+		}
+
+		_, updatedAt := ClientTimestamps(preference.UpdatedAt, preference.UpdatedAt, now)
+		storeErr := SyncItemError{Collection: "preferences", Index: i, Key: preference.Key, Message: "failed to store preference"}
+		writer.queue(func() { rejected = append(rejected, storeErr) }, `
+			INSERT INTO user_preferences (user_id, key, value, device_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $5)
+			ON CONFLICT (user_id, key) DO UPDATE
+			SET value = EXCLUDED.value,
+				device_id = EXCLUDED.device_id,
+				is_deleted = false,
+				updated_at = EXCLUDED.updated_at
+		`, userID, preference.Key, []byte(preference.Value), preference.DeviceID, updatedAt)
+	}
+	if err := writer.flush(ctx, tx); err != nil {
+		return nil, err
+	}
+	return rejected, nil
+}
+
+// markDeleted tombstones the user's rows in table with the given ids
+func markDeleted(ctx context.Context, tx pgx.Tx, table string, userID uuid.UUID, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, fmt.Sprintf(`
+		UPDATE %s
+		SET is_deleted = true,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ANY($1) AND user_id = $2
+	`, table), ids, userID)
+	return err
+}
+
+// markProjectsDeleted tombstones the projects with the given ids. Callers
+// must have checked that the user may change them.
+func markProjectsDeleted(ctx context.Context, tx pgx.Tx, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		UPDATE projects
+		SET is_deleted = true,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ANY($1)
+	`, ids)
+	return err
+}
+
+// markPreferencesDeleted tombstones the user's preferences with the given keys
+func markPreferencesDeleted(ctx context.Context, tx pgx.Tx, userID uuid.UUID, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		UPDATE user_preferences
+		SET is_deleted = true,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE key = ANY($1) AND user_id = $2
+	`, keys, userID)
+	return err
+}
+
+// changedRows runs query with args and scans every row with scan
+func changedRows[T any](ctx context.Context, tx pgx.Tx, query string, scan func(pgx.Rows) (T, error), args ...interface{}) ([]T, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []T
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func changedTags(ctx context.Context, tx pgx.Tx, userID uuid.UUID, since, until time.Time) ([]Tag, error) {
+	return changedRows(ctx, tx, `
+		SELECT id, user_id, name, color, device_id, is_deleted, created_at, updated_at
+		FROM tags
+		WHERE user_id = $1 AND server_updated_at > $2 AND server_updated_at < $3
+	`, func(rows pgx.Rows) (Tag, error) {
+		var tag Tag
+		err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Color, &tag.DeviceID,
+			&tag.IsDeleted, &tag.CreatedAt, &tag.UpdatedAt)
+		return tag, err
+	}, userID, since, until)
+}
+
+func changedTasks(ctx context.Context, tx pgx.Tx, userID uuid.UUID, since, until time.Time) ([]Task, error) {
+	return changedRows(ctx, tx, `
+		SELECT id, user_id, project_id, name, description, is_completed, estimate_seconds, device_id, is_deleted, created_at, updated_at
+		FROM tasks
+		WHERE user_id = $1 AND server_updated_at > $2 AND server_updated_at < $3
+	`, func(rows pgx.Rows) (Task, error) {
+		var task Task
+		err := rows.Scan(&task.ID, &task.UserID, &task.ProjectID, &task.Name, &task.Description,
+			&task.IsCompleted, &task.EstimateSeconds, &task.DeviceID, &task.IsDeleted, &task.CreatedAt, &task.UpdatedAt)
+		return task, err
+	}, userID, since, until)
+}
+
+func changedTemplates(ctx context.Context, tx pgx.Tx, userID uuid.UUID, since, until time.Time) ([]SessionTemplate, error) {
+	return changedRows(ctx, tx, `
+		SELECT id, user_id, project_id, name, description, device_id, is_deleted, created_at, updated_at
+		FROM session_templates
+		WHERE user_id = $1 AND server_updated_at > $2 AND server_updated_at < $3
+	`, func(rows pgx.Rows) (SessionTemplate, error) {
+		var template SessionTemplate
+		err := rows.Scan(&template.ID, &template.UserID, &template.ProjectID, &template.Name,
+			&template.Description, &template.DeviceID, &template.IsDeleted, &template.CreatedAt, &template.UpdatedAt)
+		return template, err
+	}, userID, since, until)
+}
+
+func changedTimeOff(ctx context.Context, tx pgx.Tx, userID uuid.UUID, since, until time.Time) ([]TimeOff, error) {
+	return changedRows(ctx, tx, `
+		SELECT `+timeOffColumns+`
+		FROM time_off
+		WHERE user_id = $1 AND server_updated_at > $2 AND server_updated_at < $3
+	`, func(rows pgx.Rows) (TimeOff, error) {
+		return scanTimeOff(rows)
+	}, userID, since, until)
+}
+
+func changedPreferences(ctx context.Context, tx pgx.Tx, userID uuid.UUID, since, until time.Time) ([]Preference, error) {
+	return changedRows(ctx, tx, `
+		SELECT key, value, device_id, is_deleted, updated_at
+		FROM user_preferences
+		WHERE user_id = $1 AND server_updated_at > $2 AND server_updated_at < $3
+	`, func(rows pgx.Rows) (Preference, error) {
+		var preference Preference
+		var value []byte
+		err := rows.Scan(&preference.Key, &value, &preference.DeviceID, &preference.IsDeleted, &preference.UpdatedAt)
+		preference.Value = value
+		return preference, err
+	}, userID, since, until)
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+//msgp:tag json
+//msgp:newtime
+//msgp:shim uuid.UUID as:[]byte using:uuidToBytes/uuidFromBytes
+//msgp:ignore snapshotToken
+
+const (
+	defaultSnapshotPageSize = 500
+	maxSnapshotPageSize     = 2000
+)
+
+// Snapshot collections in the order they are paged through. Projects come
+// first so that sessions, tasks and templates never reference a project the
+// client has not received yet. New collections go last, so that page tokens
+// issued before they were added stay valid.
+var snapshotCollections = []string{"projects", "tags", "tasks", "templates", "preferences", "sessions", "time_off"}
+
+type SyncResetRequest struct {
+	DeviceID  string `json:"device_id"`
+	PageToken string `json:"page_token"`
+	PageSize  int    `json:"page_size"`
+}
+
+// SnapshotPage is one page of a full resync. After the last page the client
+// continues with regular syncs using SnapshotTime as its last_sync_time.
+type SnapshotPage struct {
+	SnapshotTime  time.Time         `json:"snapshot_time"`
+	Projects      []Project         `json:"projects"`
+	Sessions      []Session         `json:"sessions"`
+	Tags          []Tag             `json:"tags"`
+	Tasks         []Task            `json:"tasks"`
+	Templates     []SessionTemplate `json:"templates"`
+	Preferences   []Preference      `json:"preferences"`
+	TimeOff       []TimeOff         `json:"time_off"`
+	NextPageToken string            `json:"next_page_token,omitempty"`
+}
+
+// snapshotToken records where the next snapshot page starts
+type snapshotToken struct {
+	SnapshotTime time.Time `json:"t"`
+	Collection   int       `json:"c"`
+	After        string    `json:"a"`
+}
+
+func (t snapshotToken) encode() string {
+	data, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSnapshotToken(s string) (snapshotToken, error) {
+	var t snapshotToken
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return t, err
+	}
+	err = json.Unmarshal(data, &t)
+	return t, err
+}
+
+// ResetSync starts or continues a full resync of a device. The first call
+// (without a page token) resets the device's cursor and tombstone tracking;
+// every call returns one page of the user's live data.
+func ResetSync(ctx context.Context, userID uuid.UUID, req SyncResetRequest) (*SnapshotPage, error) {
+	pageSize := req.PageSize
+	if pageSize == 0 {
+		pageSize = defaultSnapshotPageSize
+	}
+	if pageSize < 1 || pageSize > maxSnapshotPageSize {
+		return nil, errorf(InvalidArgument, "Invalid page size")
+	}
+
+	var token snapshotToken
+	var err error
+	if req.PageToken != "" {
+		token, err = decodeSnapshotToken(req.PageToken)
+		if err != nil || token.Collection < 0 || token.Collection >= len(snapshotCollections) {
+			return nil, errorf(InvalidArgument, "Invalid page token")
+		}
+	} else if req.DeviceID == "" {
+		return nil, errorf(InvalidArgument, "device_id is required")
+	}
+
+	tx, err := db.Pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return nil, internalError("Failed to start transaction", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if req.PageToken == "" {
+		_, token.SnapshotTime, err = syncCursor(ctx, tx)
+		if err != nil {
+			return nil, internalError("Failed to compute sync cursor", err)
+		}
+
+		// The device is about to receive everything that exists now, so
+		// older tombstones no longer need to be kept for it
+		_, err = tx.Exec(ctx, `
+			INSERT INTO device_sync (user_id, device_id, acked_through)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, device_id) DO UPDATE
+			SET last_sync_time = CURRENT_TIMESTAMP,
+				acked_through = EXCLUDED.acked_through,
+				needs_full_resync = false,
+				stale_at = NULL
+		`, userID, req.DeviceID, token.SnapshotTime)
+		if err != nil {
+			return nil, internalError("Failed to reset device sync status", err)
+		}
+	}
+
+	page := SnapshotPage{SnapshotTime: token.SnapshotTime}
+	remaining := pageSize
+	for token.Collection < len(snapshotCollections) && remaining > 0 {
+		collection := snapshotCollections[token.Collection]
+		n, last, err := fillSnapshotPage(ctx, tx, userID, &page, collection, token.After, remaining)
+		if err != nil {
+			return nil, internalError("Failed to fetch "+collection, err)
+		}
+		remaining -= n
+		if remaining > 0 {
+			// Collection exhausted, move on to the next one
+			token.Collection++
+			token.After = ""
+		} else {
+			token.After = last
+		}
+	}
+	if token.Collection < len(snapshotCollections) {
+		page.NextPageToken = token.encode()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, internalError("Failed to commit transaction", err)
+	}
+	return &page, nil
+}
+
+// fillSnapshotPage appends up to limit live rows of collection that sort after
+// the given key. It returns how many rows were added and the key of the last.
+func fillSnapshotPage(ctx context.Context, tx pgx.Tx, userID uuid.UUID, page *SnapshotPage, collection, after string, limit int) (int, string, error) {
+	afterID := uuid.Nil
+	if after != "" && collection != "preferences" {
+		parsed, err := uuid.Parse(after)
+		if err != nil {
+			return 0, "", err
+		}
+		afterID = parsed
+	}
+
+	switch collection {
+	case "projects":
+		items, err := changedRows(ctx, tx, `
+			SELECT `+projectColumns+`
+			FROM projects
+			WHERE `+SyncedProjectSQL(1)+` AND is_deleted = false AND id > $2
+			ORDER BY id
+			LIMIT $3
+		`, func(rows pgx.Rows) (Project, error) {
+			return scanProject(rows)
+		}, userID, afterID, limit)
+		if err != nil || len(items) == 0 {
+			return 0, "", err
+		}
+		page.Projects = items
+		return len(items), items[len(items)-1].ID.String(), nil
+
+	case "sessions":
+		items, err := changedRows(ctx, tx, `
+			SELECT `+sessionColumns+`
+			FROM timer_sessions
+			WHERE user_id = $1 AND is_deleted = false AND id > $2
+			ORDER BY id
+			LIMIT $3
+		`, func(rows pgx.Rows) (Session, error) {
+			return scanSession(rows)
+		}, userID, afterID, limit)
+		if err != nil || len(items) == 0 {
+			return 0, "", err
+		}
+		page.Sessions = items
+		return len(items), items[len(items)-1].ID.String(), nil
+
+	case "tags":
+		items, err := changedRows(ctx, tx, `
+			SELECT id, user_id, name, color, device_id, is_deleted, created_at, updated_at
+			FROM tags
+			WHERE user_id = $1 AND is_deleted = false AND id > $2
+			ORDER BY id
+			LIMIT $3
+		`, func(rows pgx.Rows) (Tag, error) {
+			var tag Tag
+			err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Color, &tag.DeviceID,
+				&tag.IsDeleted, &tag.CreatedAt, &tag.UpdatedAt)
+			return tag, err
+		}, userID, afterID, limit)
+		if err != nil || len(items) == 0 {
+			return 0, "", err
+		}
+		page.Tags = items
+		return len(items), items[len(items)-1].ID.String(), nil
+
+	case "tasks":
+		items, err := changedRows(ctx, tx, `
+			SELECT id, user_id, project_id, name, description, is_completed, estimate_seconds, device_id, is_deleted, created_at, updated_at
+			FROM tasks
+			WHERE user_id = $1 AND is_deleted = false AND id > $2
+			ORDER BY id
+			LIMIT $3
+		`, func(rows pgx.Rows) (Task, error) {
+			var task Task
+			err := rows.Scan(&task.ID, &task.UserID, &task.ProjectID, &task.Name, &task.Description,
+				&task.IsCompleted, &task.EstimateSeconds, &task.DeviceID, &task.IsDeleted, &task.CreatedAt, &task.UpdatedAt)
+			return task, err
+		}, userID, afterID, limit)
+		if err != nil || len(items) == 0 {
+			return 0, "", err
+		}
+		page.Tasks = items
+		return len(items), items[len(items)-1].ID.String(), nil
+
+	case "templates":
+		items, err := changedRows(ctx, tx, `
+			SELECT id, user_id, project_id, name, description, device_id, is_deleted, created_at, updated_at
+			FROM session_templates
+			WHERE user_id = $1 AND is_deleted = false AND id > $2
+			ORDER BY id
+			LIMIT $3
+		`, func(rows pgx.Rows) (SessionTemplate, error) {
+			var template SessionTemplate
+			err := rows.Scan(&template.ID, &template.UserID, &template.ProjectID, &template.Name,
+				&template.Description, &template.DeviceID, &template.IsDeleted, &template.CreatedAt, &template.UpdatedAt)
+			return template, err
+		}, userID, afterID, limit)
+		if err != nil || len(items) == 0 {
+			return 0, "", err
+		}
+		page.Templates = items
+		return len(items), items[len(items)-1].ID.String(), nil
+
+	case "preferences":
+		items, err := changedRows(ctx, tx, `
+			SELECT key, value, device_id, is_deleted, updated_at
+			FROM user_preferences
+			WHERE user_id = $1 AND is_deleted = false AND key > $2
+			ORDER BY key
+			LIMIT $3
+		`, func(rows pgx.Rows) (Preference, error) {
+			var preference Preference
+			var value []byte
+			err := rows.Scan(&preference.Key, &value, &preference.DeviceID, &preference.IsDeleted, &preference.UpdatedAt)
+			preference.Value = value
+			return preference, err
+		}, userID, after, limit)
+		if err != nil || len(items) == 0 {
+			return 0, "", err
+		}
+		page.Preferences = items
+		return len(items), items[len(items)-1].Key, nil
+
+	case "time_off":
+		items, err := changedRows(ctx, tx, `
+			SELECT `+timeOffColumns+`
+			FROM time_off
+			WHERE user_id = $1 AND is_deleted = false AND id > $2
+			ORDER BY id
+			LIMIT $3
+		`, func(rows pgx.Rows) (TimeOff, error) {
+			return scanTimeOff(rows)
+		}, userID, afterID, limit)
+		if err != nil || len(items) == 0 {
+			return 0, "", err
+		}
+		page.TimeOff = items
+		return len(items), items[len(items)-1].ID.String(), nil
+	}
+	return 0, "", nil
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+//msgp:tag json
+//msgp:newtime
+//msgp:shim uuid.UUID as:[]byte using:uuidToBytes/uuidFromBytes
+
+// Limits applied to a single sync request
+const (
+	MaxSyncBodyBytes    = 10 << 20 // 10 MB
+	maxSyncSessions     = 5000
+	maxSyncProjects     = 1000
+	maxSyncDeletedItems = 5000
+
+	MaxNameLength        = 255
+	MaxDescriptionLength = 10000
+	maxDeviceIDLength    = 255
+	MaxSessionDuration   = 7 * 24 * time.Hour
+
+	// maxClockSkew is how far ahead of the server clock a client timestamp
+	// may be before it is clamped
+	maxClockSkew = 5 * time.Minute
+)
+
+// SyncItemError describes a single record that was rejected during sync
+type SyncItemError struct {
+	Collection string    `json:"collection"`
+	Index      int       `json:"index"`
+	ID         uuid.UUID `json:"id"`
+	Key        string    `json:"key,omitempty"` // set instead of ID for preferences
+	Field      string    `json:"field,omitempty"`
+	Message    string    `json:"message"`
+}
+
+// checkSyncBatchSizes returns an error if any collection exceeds its limit
+func checkSyncBatchSizes(req *SyncRequest) error {
+	if len(req.LocalSessions) > maxSyncSessions {
+		return fmt.Errorf("too many sessions in one sync (max %d)", maxSyncSessions)
+	}
+	if len(req.LocalProjects) > maxSyncProjects {
+		return fmt.Errorf("too many projects in one sync (max %d)", maxSyncProjects)
+	}
+	if len(req.LocalTags) > maxSyncEntityItems || len(req.LocalTasks) > maxSyncEntityItems ||
+		len(req.LocalTemplates) > maxSyncEntityItems || len(req.LocalPreferences) > maxSyncEntityItems ||
+		len(req.LocalTimeOff) > maxSyncEntityItems {
+		return fmt.Errorf("too many tags, tasks, templates, preferences or time off in one sync (max %d each)", maxSyncEntityItems)
+	}
+	for _, deleted := range []int{
+		len(req.DeletedSessions), len(req.DeletedProjects), len(req.DeletedTags),
+		len(req.DeletedTasks), len(req.DeletedTemplates), len(req.DeletedPreferences), len(req.DeletedTimeOff),
+	} {
+		if deleted > maxSyncDeletedItems {
+			return fmt.Errorf("too many deletions in one sync (max %d)", maxSyncDeletedItems)
+		}
+	}
+	return nil
+}
+
+// ValidateSyncProject checks the fields of the project at index of a sync
+// batch, returning why it is rejected or nil
+func ValidateSyncProject(index int, project Project, storageMode string) *SyncItemError {
+	hasPlaintext := project.Name != "" || project.Description != ""
+	if field, message := EncryptionError(storageMode, project.KeyID, hasPlaintext, project.EncryptedName, project.EncryptedDescription); message != "" {
+		return &SyncItemError{Collection: "projects", Index: index, ID: project.ID, Field: field, Message: message}
+	}
+	return rejection("projects", index, project.ID, project)
+}
+
+// ValidateSyncSession checks the fields of the session at index of a sync
+// batch, returning why it is rejected or nil. knownProjects holds the
+// project IDs the user may reference.
+func ValidateSyncSession(index int, session Session, knownProjects map[uuid.UUID]bool, storageMode string) *SyncItemError {
+	fail := func(field, message string) *SyncItemError {
+		return &SyncItemError{Collection: "sessions", Index: index, ID: session.ID, Field: field, Message: message}
+	}
+
+	if field, message := EncryptionError(storageMode, session.KeyID, session.Description != "", session.EncryptedDescription); message != "" {
+		return fail(field, message)
+	}
+	if rejected := rejection("sessions", index, session.ID, session); rejected != nil {
+		return rejected
+	}
+	if session.ProjectID != nil && !knownProjects[*session.ProjectID] {
+		return fail("project_id", "project does not exist")
+	}
+	return nil
+}
+
+// rejection reports the first failing field of value, nil if it is valid
+func rejection(collection string, index int, id uuid.UUID, value validate.Validatable) *SyncItemError {
+	var fields validate.Errors
+	if !errors.As(validate.Struct(value), &fields) {
+		return nil
+	}
+	return &SyncItemError{Collection: collection, Index: index, ID: id, Field: fields[0].Field, Message: fields[0].Message}
+}
+
+// ClientTimestamps bounds client-provided created/updated times by the
+// server clock. Missing or future values fall back to now, and created_at
+// never ends up after updated_at.
+func ClientTimestamps(createdAt, updatedAt, now time.Time) (time.Time, time.Time) {
+	if updatedAt.IsZero() || updatedAt.After(now.Add(maxClockSkew)) {
+		updatedAt = now
+	}
+	if createdAt.IsZero() || createdAt.After(updatedAt) {
+		createdAt = updatedAt
+	}
+	return createdAt, updatedAt
+}
+
+// storedIDs returns the subset of ids that already exist in table, whoever
+// they belong to
+func storedIDs(ctx context.Context, tx pgx.Tx, table string, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+	stored := make(map[uuid.UUID]bool)
+	if len(ids) == 0 {
+		return stored, nil
+	}
+
+	rows, err := tx.Query(ctx, fmt.Sprintf("SELECT id FROM %s WHERE id = ANY($1)", table), ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		stored[id] = true
+	}
+	return stored, rows.Err()
+}
+
+// foreignIDs returns the subset of ids that already exist in table but belong
+// to a different user. Such rows must never be overwritten by a sync.
+func foreignIDs(ctx context.Context, tx pgx.Tx, table string, userID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+	foreign := make(map[uuid.UUID]bool)
+	if len(ids) == 0 {
+		return foreign, nil
+	}
+
+	rows, err := tx.Query(ctx,
+		fmt.Sprintf("SELECT id FROM %s WHERE id = ANY($1) AND user_id <> $2", table),
+		ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		foreign[id] = true
+	}
+	return foreign, rows.Err()
+}
+
+// syncedProjectIDs returns which of ids are existing projects synced to the
+// user, i.e. personal projects and those of the user's organizations
+func syncedProjectIDs(ctx context.Context, tx pgx.Tx, userID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+	owned := make(map[uuid.UUID]bool)
+	if len(ids) == 0 {
+		return owned, nil
+	}
+
+	rows, err := tx.Query(ctx,
+		"SELECT id FROM projects WHERE id = ANY($1) AND "+SyncedProjectSQL(2),
+		ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		owned[id] = true
+	}
+	return owned, rows.Err()
+}
+
+// readOnlyProjects returns the subset of ids naming existing projects the user
+// may not change through sync, mapped to the reason. Organization projects
+// can only be changed by members allowed to manage projects.
+func readOnlyProjects(ctx context.Context, tx pgx.Tx, userID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]string, error) {
+	readOnly := make(map[uuid.UUID]string)
+	if len(ids) == 0 {
+		return readOnly, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT p.id, p.user_id, p.organization_id, COALESCE(m.role, '')
+		FROM projects p
+		LEFT JOIN memberships m ON m.organization_id = p.organization_id AND m.user_id = $2 AND m.deactivated_at IS NULL
+		WHERE p.id = ANY($1)
+	`, ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, ownerID uuid.UUID
+		var orgID *uuid.UUID
+		var role string
+		if err := rows.Scan(&id, &ownerID, &orgID, &role); err != nil {
+			return nil, err
+		}
+		switch {
+		case orgID == nil && ownerID != userID, orgID != nil && role == "":
+			readOnly[id] = "project belongs to another user"
+		case orgID != nil && !auth.RoleHas(role, auth.PermManageProjects):
+			readOnly[id] = "only organization admins can change this project"
+		}
+	}
+	return readOnly, rows.Err()
+}
+
+// projectLocks returns the lock date of each of the given projects whose
+// organization has one set
+func projectLocks(ctx context.Context, tx pgx.Tx, ids []uuid.UUID) (map[uuid.UUID]time.Time, error) {
+	locks := make(map[uuid.UUID]time.Time)
+	if len(ids) == 0 {
+		return locks, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT p.id, (o.settings->>'locked_before')::timestamptz
+		FROM projects p
+		JOIN organizations o ON o.id = p.organization_id
+		WHERE p.id = ANY($1) AND o.settings->>'locked_before' IS NOT NULL
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var lockedBefore time.Time
+		if err := rows.Scan(&id, &lockedBefore); err != nil {
+			return nil, err
+		}
+		locks[id] = lockedBefore
+	}
+	return locks, rows.Err()
+}
+
+// execItem runs a single-row write inside a savepoint so a failing record
+// does not abort the surrounding sync transaction
+func execItem(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) error {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := savepoint.Exec(ctx, query, args...); err != nil {
+		savepoint.Rollback(ctx)
+		return err
+	}
+	return savepoint.Commit(ctx)
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+func TestValidateSyncSession(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	known, unknown := uuid.New(), uuid.New()
+	valid := Session{ID: uuid.New(), StartTime: start, EndTime: start.Add(time.Hour), Description: "Writing"}
+
+	tests := []struct {
+		name        string
+		change      func(*Session)
+		storageMode string
+		// field is the field rejected, "-" for none
+		field string
+	}{
+		{"valid", func(s *Session) {}, models.StorageModeStandard, "-"},
+		{"known project", func(s *Session) { s.ProjectID = &known }, models.StorageModeStandard, "-"},
+		{"unknown project", func(s *Session) { s.ProjectID = &unknown }, models.StorageModeStandard, "project_id"},
+		{"ends before it starts", func(s *Session) { s.EndTime = start.Add(-time.Minute) }, models.StorageModeStandard, "end_time"},
+		{"longer than a week", func(s *Session) { s.EndTime = start.Add(MaxSessionDuration + time.Second) }, models.StorageModeStandard, "end_time"},
+		{"description too long", func(s *Session) { s.Description = strings.Repeat("a", MaxDescriptionLength+1) }, models.StorageModeStandard, "description"},
+		{"break outside the session", func(s *Session) {
+			s.Breaks = []Break{{StartTime: start.Add(30 * time.Minute), EndTime: start.Add(2 * time.Hour)}}
+		}, models.StorageModeStandard, "breaks"},
+		{"plaintext in encrypted mode", func(s *Session) {}, models.StorageModeEncrypted, "key_id"},
+		{"encrypted in encrypted mode", func(s *Session) {
+			s.Description, s.EncryptedDescription, s.KeyID = "", []byte("sealed"), "key-1"
+		}, models.StorageModeEncrypted, "-"},
+		{"ciphertext without key", func(s *Session) {
+			s.Description, s.EncryptedDescription = "", []byte("sealed")
+		}, models.StorageModeStandard, "key_id"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			session := valid
+			test.change(&session)
+			rejected := ValidateSyncSession(3, session, map[uuid.UUID]bool{known: true}, test.storageMode)
+			if test.field == "-" {
+				if rejected != nil {
+					t.Fatalf("rejected %+v", *rejected)
+				}
+				return
+			}
+			if rejected == nil {
+				t.Fatalf("accepted, want %s rejected", test.field)
+			}
+			want := SyncItemError{Collection: "sessions", Index: 3, ID: session.ID, Field: test.field, Message: rejected.Message}
+			if *rejected != want || rejected.Message == "" {
+				t.Errorf("rejected %+v, want %+v", *rejected, want)
+			}
+		})
+	}
+}
+
+func TestValidateSyncProject(t *testing.T) {
+	valid := Project{ID: uuid.New(), Name: "Book", Color: "#ff0000"}
+
+	tests := []struct {
+		name        string
+		change      func(*Project)
+		storageMode string
+		field       string
+	}{
+		{"valid", func(p *Project) {}, models.StorageModeStandard, "-"},
+		{"no name", func(p *Project) { p.Name = "" }, models.StorageModeStandard, "name"},
+		{"name too long", func(p *Project) { p.Name = strings.Repeat("a", MaxNameLength+1) }, models.StorageModeStandard, "name"},
+		{"plaintext in encrypted mode", func(p *Project) {}, models.StorageModeEncrypted, "key_id"},
+		{"encrypted with plaintext", func(p *Project) {
+			p.EncryptedName, p.KeyID = []byte("sealed"), "key-1"
+		}, models.StorageModeEncrypted, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			project := valid
+			test.change(&project)
+			rejected := ValidateSyncProject(0, project, test.storageMode)
+			if test.field == "-" {
+				if rejected != nil {
+					t.Fatalf("rejected %+v", *rejected)
+				}
+				return
+			}
+			if rejected == nil {
+				t.Fatalf("accepted, want %q rejected", test.field)
+			}
+			if rejected.Collection != "projects" || rejected.ID != project.ID || rejected.Field != test.field {
+				t.Errorf("rejected %+v, want field %q of project %s", *rejected, test.field, project.ID)
+			}
+		})
+	}
+}
+
+func TestExecItemKeepsTheTransactionUsable(t *testing.T) {
+	ctx := context.Background()
+	tx := newFakeTx("bad")
+
+	for _, row := range []string{"first", "bad", "second"} {
+		err := execItem(ctx, tx, "INSERT", row)
+		if failed := err != nil; failed != (row == "bad") {
+			t.Errorf("writing %s returned %v", row, err)
+		}
+	}
+	if want := []interface{}{"first", "second"}; !reflect.DeepEqual(tx.rows, want) {
+		t.Errorf("wrote %v, want %v", tx.rows, want)
+	}
+	if tx.aborted {
+		t.Error("the failing row aborted the surrounding transaction")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Errorf("committing returned %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/outbox"
+	"github.com/pacerclub/zebra-backend/internal/webhooks"
+)
+
+// Tasks are written through sync. Sessions logged against a task count
+// towards it, so its actual time can be compared with its estimate. Tasks
+// follow the scope of their project, as sessions do.
+
+// MaxTaskEstimate bounds a task's estimate, 10,000 hours
+const MaxTaskEstimate = 10000 * 60 * 60
+
+const taskColumns = "id, user_id, project_id, name, description, is_completed, estimate_seconds, device_id, is_deleted, created_at, updated_at"
+
+func scanTask(row pgx.Row) (Task, error) {
+	var task Task
+	err := row.Scan(&task.ID, &task.UserID, &task.ProjectID, &task.Name, &task.Description,
+		&task.IsCompleted, &task.EstimateSeconds, &task.DeviceID, &task.IsDeleted, &task.CreatedAt, &task.UpdatedAt)
+	return task, err
+}
+
+// TaskProgress is a task with the time logged against it
+type TaskProgress struct {
+	Task
+	// ActualSeconds is the time of the sessions logged against the task,
+	// less their breaks
+	ActualSeconds int64 `json:"actual_seconds"`
+	// Progress is the actual time as a share of the estimate, nil for tasks
+	// without an estimate
+	Progress *float64 `json:"progress"`
+	// OverBudgetSeconds is the actual time beyond the estimate
+	OverBudgetSeconds int64 `json:"over_budget_seconds"`
+}
+
+// EstimateReport measures how well the user's completed tasks with an
+// estimate kept to it
+type EstimateReport struct {
+	TaskCount        int   `json:"task_count"`
+	EstimatedSeconds int64 `json:"estimated_seconds"`
+	ActualSeconds    int64 `json:"actual_seconds"`
+	// Ratio is the actual time as a share of the estimated time, above 1
+	// when tasks took longer than planned; nil without tasks
+	Ratio *float64 `json:"ratio"`
+	// OverBudgetCount is how many of the tasks took longer than estimated
+	OverBudgetCount int `json:"over_budget_count"`
+	// MeanErrorPercent is the mean difference between the actual and the
+	// estimated time of a task, either way, as a percentage of its estimate
+	MeanErrorPercent *float64 `json:"mean_error_percent"`
+}
+
+// ListTaskProgress returns the user's tasks in the active scope with the
+// time logged against them, oldest first
+func ListTaskProgress(ctx context.Context, userID uuid.UUID) ([]TaskProgress, error) {
+	rows, err := db.ReadPool(ctx).Query(ctx, `
+		SELECT `+taskColumns+`, COALESCE(logged.seconds, 0)
+		FROM tasks
+		LEFT JOIN (
+			SELECT s.task_id, SUM(`+models.NetSecondsSQL("s")+`)::BIGINT AS seconds
+			FROM timer_sessions s
+			WHERE s.user_id = $1 AND s.task_id IS NOT NULL AND s.is_deleted = false
+			GROUP BY s.task_id
+		) logged ON logged.task_id = tasks.id
+		WHERE `+SessionScopeSQL(1)+` AND is_deleted = false
+		ORDER BY created_at, id
+	`, userID, ScopeOrganization(ctx))
+	if err != nil {
+		return nil, internalError("Failed to fetch tasks", err)
+	}
+	tasks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (TaskProgress, error) {
+		var p TaskProgress
+		err := row.Scan(&p.ID, &p.UserID, &p.ProjectID, &p.Name, &p.Description, &p.IsCompleted,
+			&p.EstimateSeconds, &p.DeviceID, &p.IsDeleted, &p.CreatedAt, &p.UpdatedAt, &p.ActualSeconds)
+		if err != nil {
+			return p, err
+		}
+		if estimate := *p.EstimateSeconds; estimate > 0 {
+			progress := float64(p.ActualSeconds) / float64(estimate)
+			p.Progress = &progress
+			p.OverBudgetSeconds = max(p.ActualSeconds-estimate, 0)
+		}
+		return p, nil
+	})
+	if err != nil {
+		return nil, internalError("Failed to fetch tasks", err)
+	}
+	return tasks, nil
+}
+
+// ListTasksOverBudget returns the user's tasks in the active scope that
+// took longer than estimated, the furthest over first
+func ListTasksOverBudget(ctx context.Context, userID uuid.UUID) ([]TaskProgress, error) {
+	tasks, err := ListTaskProgress(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	over := []TaskProgress{}
+	for _, task := range tasks {
+		if task.OverBudgetSeconds > 0 {
+			over = append(over, task)
+		}
+	}
+	sort.SliceStable(over, func(i, j int) bool { return over[i].OverBudgetSeconds > over[j].OverBudgetSeconds })
+	return over, nil
+}
+
+// GetEstimateReport measures how well the user's completed tasks in the
+// active scope kept to their estimates
+func GetEstimateReport(ctx context.Context, userID uuid.UUID) (*EstimateReport, error) {
+	tasks, err := ListTaskProgress(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &EstimateReport{}
+	var errorSum float64
+	for _, task := range tasks {
+		estimate := *task.EstimateSeconds
+		if !task.IsCompleted || estimate == 0 {
+			continue
+		}
+		report.TaskCount++
+		report.EstimatedSeconds += estimate
+		report.ActualSeconds += task.ActualSeconds
+		if task.OverBudgetSeconds > 0 {
+			report.OverBudgetCount++
+		}
+		diff := task.ActualSeconds - estimate
+		if diff < 0 {
+			diff = -diff
+		}
+		errorSum += float64(diff) / float64(estimate) * 100
+	}
+	if report.TaskCount > 0 {
+		ratio := float64(report.ActualSeconds) / float64(report.EstimatedSeconds)
+		meanError := errorSum / float64(report.TaskCount)
+		report.Ratio, report.MeanErrorPercent = &ratio, &meanError
+	}
+	return report, nil
+}
+
+// SetTaskEstimate sets the estimate of the user's task, 0 to remove it. The
+// change reaches the user's devices on their next sync.
+func SetTaskEstimate(ctx context.Context, userID, taskID uuid.UUID, seconds int64) (Task, error) {
+	if seconds < 0 || seconds > MaxTaskEstimate {
+		return Task{}, errorf(InvalidArgument, "estimate_seconds must be between 0 and %d", MaxTaskEstimate)
+	}
+
+	task, err := scanTask(db.Pool.QueryRow(ctx, `
+		UPDATE tasks SET estimate_seconds = $3
+		WHERE id = $1 AND user_id = $2 AND is_deleted = false
+		RETURNING `+taskColumns,
+		taskID, userID, seconds))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Task{}, errorf(NotFound, "Task not found")
+	}
+	if err != nil {
+		return Task{}, internalError("Failed to update task", err)
+	}
+	return task, nil
+}
+
+// SetSessionTask logs the user's session in the active scope against one of
+// their tasks, or against none when taskID is nil. A task on a project only
+// takes sessions of that project.
+func SetSessionTask(ctx context.Context, userID, sessionID uuid.UUID, taskID *uuid.UUID) (Session, error) {
+	if err := CheckSessionLock(ctx, userID, sessionID, time.Time{}); err != nil {
+		return Session{}, err
+	}
+
+	var session Session
+	err := pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		var taskProjectID *uuid.UUID
+		if taskID != nil {
+			err := tx.QueryRow(ctx,
+				`SELECT project_id FROM tasks WHERE id = $1 AND user_id = $2 AND is_deleted = false`,
+				*taskID, userID).Scan(&taskProjectID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errorf(NotFound, "Task not found")
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		var err error
+		session, err = scanSession(tx.QueryRow(ctx, `
+			UPDATE timer_sessions SET task_id = $4
+			WHERE id = $1 AND `+SessionScopeSQL(2)+` AND is_deleted = false
+				AND ($5::uuid IS NULL OR project_id = $5)
+			RETURNING `+sessionColumns,
+			sessionID, userID, ScopeOrganization(ctx), taskID, taskProjectID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			exists, err := sessionExists(ctx, tx, userID, sessionID)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return errorf(InvalidArgument, "The task belongs to another project")
+			}
+			return errorf(NotFound, "Session not found")
+		}
+		if err != nil {
+			return err
+		}
+		return outbox.Add(ctx, tx, userID, webhooks.EventSessionUpdated, session)
+	})
+	if code := ErrorCode(err); code != Internal {
+		return Session{}, err
+	}
+	if err != nil {
+		return Session{}, internalError("Failed to update session", err)
+	}
+	return session, nil
+}
+
+// sessionExists reports whether the user has the session in the active
+// scope
+func sessionExists(ctx context.Context, tx pgx.Tx, userID, sessionID uuid.UUID) (bool, error) {
+	var exists bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM timer_sessions WHERE id = $1 AND `+SessionScopeSQL(2)+` AND is_deleted = false)
+	`, sessionID, userID, ScopeOrganization(ctx)).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/validate"
+)
+
+// Types of time off
+const (
+	TimeOffVacation = "vacation"
+	TimeOffSick     = "sick"
+	TimeOffHoliday  = "holiday"
+)
+
+// TimeOffTypes lists every type of time off in the order they are
+// documented
+var TimeOffTypes = []string{TimeOffVacation, TimeOffSick, TimeOffHoliday}
+
+// MaxTimeOffDays bounds the days one entry of time off covers
+const MaxTimeOffDays = 366
+
+// ValidTimeOffType reports whether timeOffType is known
+func ValidTimeOffType(timeOffType string) bool {
+	for _, t := range TimeOffTypes {
+		if t == timeOffType {
+			return true
+		}
+	}
+	return false
+}
+
+const timeOffColumns = "id, user_id, type, to_char(start_date, 'YYYY-MM-DD'), to_char(end_date, 'YYYY-MM-DD'), note, device_id, is_deleted, created_at, updated_at"
+
+func scanTimeOff(row pgx.Row) (TimeOff, error) {
+	var t TimeOff
+	err := row.Scan(&t.ID, &t.UserID, &t.Type, &t.StartDate, &t.EndDate, &t.Note,
+		&t.DeviceID, &t.IsDeleted, &t.CreatedAt, &t.UpdatedAt)
+	return t, err
+}
+
+// Validate checks the fields clients send
+func (t TimeOff) Validate(v *validate.Validator) {
+	v.Check(ValidTimeOffType(t.Type), "type", "must be one of "+strings.Join(TimeOffTypes, ", "))
+	start, startErr := time.Parse("2006-01-02", t.StartDate)
+	v.Check(startErr == nil, "start_date", "must be formatted as YYYY-MM-DD")
+	end, endErr := time.Parse("2006-01-02", t.EndDate)
+	v.Check(endErr == nil, "end_date", "must be formatted as YYYY-MM-DD")
+	if startErr == nil && endErr == nil {
+		v.Check(!end.Before(start), "end_date", "must not be before start_date")
+		v.Check(end.Sub(start) < MaxTimeOffDays*24*time.Hour, "end_date", "must be at most a year after start_date")
+	}
+	v.MaxLength("note", t.Note, MaxDescriptionLength)
+	v.MaxBytes("device_id", t.DeviceID, maxDeviceIDLength)
+}
+
+// ListTimeOff returns the user's time off overlapping the dates from start
+// to end, both YYYY-MM-DD and optional, by start date
+func ListTimeOff(ctx context.Context, userID uuid.UUID, start, end string) ([]TimeOff, error) {
+	if _, err := time.Parse("2006-01-02", start); start != "" && err != nil {
+		return nil, errorf(InvalidArgument, "start must be formatted as YYYY-MM-DD")
+	}
+	if _, err := time.Parse("2006-01-02", end); end != "" && err != nil {
+		return nil, errorf(InvalidArgument, "end must be formatted as YYYY-MM-DD")
+	}
+
+	rows, err := db.ReadPool(ctx).Query(ctx, `
+		SELECT `+timeOffColumns+`
+		FROM time_off
+		WHERE user_id = $1 AND is_deleted = false
+			AND end_date >= COALESCE(NULLIF($2, '')::date, '-infinity')
+			AND start_date <= COALESCE(NULLIF($3, '')::date, 'infinity')
+		ORDER BY start_date, id
+	`, userID, start, end)
+	if err != nil {
+		return nil, internalError("Failed to fetch time off", err)
+	}
+	timeOff, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (TimeOff, error) {
+		return scanTimeOff(row)
+	})
+	if err != nil {
+		return nil, internalError("Failed to fetch time off", err)
+	}
+	return timeOff, nil
+}
+
+// CreateTimeOff stores new time off for the user
+func CreateTimeOff(ctx context.Context, userID uuid.UUID, t TimeOff) (TimeOff, error) {
+	if err := validate.Struct(t); err != nil {
+		return TimeOff{}, invalidFields(err)
+	}
+
+	t, err := scanTimeOff(db.Pool.QueryRow(ctx, `
+		INSERT INTO time_off (id, user_id, type, start_date, end_date, note, device_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+timeOffColumns,
+		uuid.New(), userID, t.Type, t.StartDate, t.EndDate, t.Note, t.DeviceID))
+	if err != nil {
+		return TimeOff{}, internalError("Failed to create time off", err)
+	}
+	return t, nil
+}
+
+// UpdateTimeOff replaces the editable fields of the user's time off
+func UpdateTimeOff(ctx context.Context, userID, id uuid.UUID, t TimeOff) (TimeOff, error) {
+	if err := validate.Struct(t); err != nil {
+		return TimeOff{}, invalidFields(err)
+	}
+
+	t, err := scanTimeOff(db.Pool.QueryRow(ctx, `
+		UPDATE time_off
+		SET type = $3, start_date = $4, end_date = $5, note = $6, device_id = $7
+		WHERE id = $1 AND user_id = $2 AND is_deleted = false
+		RETURNING `+timeOffColumns,
+		id, userID, t.Type, t.StartDate, t.EndDate, t.Note, t.DeviceID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return TimeOff{}, errorf(NotFound, "Time off not found")
+	}
+	if err != nil {
+		return TimeOff{}, internalError("Failed to update time off", err)
+	}
+	return t, nil
+}
+
+// DeleteTimeOff tombstones the user's time off, so that sync removes it
+// from their devices
+func DeleteTimeOff(ctx context.Context, userID, id uuid.UUID) error {
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE time_off SET is_deleted = true
+		WHERE id = $1 AND user_id = $2 AND is_deleted = false
+	`, id, userID)
+	if err != nil {
+		return internalError("Failed to delete time off", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errorf(NotFound, "Time off not found")
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/fieldcrypt"
+	"github.com/pacerclub/zebra-backend/internal/live"
+	"github.com/pacerclub/zebra-backend/internal/outbox"
+	"github.com/pacerclub/zebra-backend/internal/rules"
+	"github.com/pacerclub/zebra-backend/internal/webhooks"
+)
+
+// StopRunningTimer saves the user's running timer in tx, if any, as a
+// session ending at end, or now if end is nil, applying the user's tracking
+// rules, and returns it, announcing the change to the live streams of the
+// user and their organizations. It returns nil if no timer is running.
+func StopRunningTimer(ctx context.Context, tx pgx.Tx, userID uuid.UUID, end *time.Time) (*Session, error) {
+	session := Session{UserID: userID}
+	err := tx.QueryRow(ctx, `
+		DELETE FROM running_timers WHERE user_id = $1
+		RETURNING id, project_id, description, start_time, device_id
+	`, userID).Scan(&session.ID, &session.ProjectID, &session.Description, &session.StartTime, &session.DeviceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError("Failed to stop timer", err)
+	}
+
+	// The description is stored as sealed in the running timer
+	sealed := session.Description
+	session.Description, err = fieldcrypt.Open(fieldcrypt.SessionDescription, sealed)
+	if err != nil {
+		return nil, internalError("Failed to save session", err)
+	}
+	tags, err := ApplyRules(ctx, userID, &session)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO timer_sessions (id, user_id, project_id, start_time, end_time, description, device_id)
+		VALUES ($1, $2, $3, $4, COALESCE($7, CURRENT_TIMESTAMP), $5, $6)
+		RETURNING end_time, is_deleted, created_at, updated_at
+	`, session.ID, session.UserID, session.ProjectID, session.StartTime, sealed, session.DeviceID, end,
+	).Scan(&session.EndTime, &session.IsDeleted, &session.CreatedAt, &session.UpdatedAt)
+	if err == nil {
+		err = rules.TagSessions(ctx, tx, userID, map[uuid.UUID][]uuid.UUID{session.ID: tags})
+	}
+	if err == nil {
+		err = outbox.Add(ctx, tx, userID, webhooks.EventSessionCreated, session)
+	}
+	if err == nil {
+		err = live.Notify(ctx, tx, userID, live.TopicTimer)
+	}
+	if err == nil {
+		err = live.NotifyOrganizations(ctx, tx, userID, live.TopicPresence)
+	}
+	if err != nil {
+		return nil, internalError("Failed to save session", err)
+	}
+	return &session, nil
+}
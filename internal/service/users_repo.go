@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/models"
+)
+
+// pgUsers stores users in the users table, through package models
+type pgUsers struct{}
+
+func (pgUsers) Create(ctx context.Context, email, password string) (*models.User, error) {
+	return models.CreateUser(ctx, email, password)
+}
+
+func (pgUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return models.GetUserByEmail(ctx, email)
+}
+
+func (pgUsers) StorageMode(ctx context.Context, userID uuid.UUID) (string, error) {
+	return models.GetStorageMode(ctx, userID)
+}
@@ -0,0 +1,78 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pacerclub/zebra-backend/internal/apierror"
+	"github.com/pacerclub/zebra-backend/internal/memstore"
+	"github.com/pacerclub/zebra-backend/internal/service"
+)
+
+func TestUpdatesRefuseStaleVersions(t *testing.T) {
+	defer memstore.New().Install()()
+	ctx := context.Background()
+	user, err := service.Users.Create(ctx, "ada@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("sessions", func(t *testing.T) {
+		start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+		created, err := service.CreateSession(ctx, user.ID, service.Session{
+			StartTime: start, EndTime: start.Add(time.Hour), Description: "Writing",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The web app saves first
+		web := created
+		web.Description = "Writing chapter 2"
+		saved, err := service.UpdateSession(ctx, user.ID, created.ID, web)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saved.Version != created.Version+1 {
+			t.Errorf("saved version %d, want %d", saved.Version, created.Version+1)
+		}
+
+		// The phone's edit was made on the version before
+		phone := created
+		phone.Description = "Reading"
+		_, err = service.UpdateSession(ctx, user.ID, created.ID, phone)
+		if service.ErrorCode(err) != service.Conflict || service.ErrorReason(err) != apierror.VersionMismatch {
+			t.Fatalf("a stale update returned %v, want %s", err, apierror.VersionMismatch)
+		}
+		current, ok := service.ErrorCurrent(err).(service.Session)
+		if !ok || current.Version != saved.Version || current.Description != web.Description {
+			t.Errorf("the conflict carries %+v, want the web app's version", service.ErrorCurrent(err))
+		}
+	})
+
+	t.Run("projects", func(t *testing.T) {
+		created, err := service.CreateProject(ctx, user.ID, service.Project{Name: "Book", Color: "#ff0000"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		web := created
+		web.Name = "Novel"
+		saved, err := service.UpdateProject(ctx, user.ID, created.ID, web)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		phone := created
+		phone.Color = "#00ff00"
+		_, err = service.UpdateProject(ctx, user.ID, created.ID, phone)
+		if service.ErrorCode(err) != service.Conflict || service.ErrorReason(err) != apierror.VersionMismatch {
+			t.Fatalf("a stale update returned %v, want %s", err, apierror.VersionMismatch)
+		}
+		current, ok := service.ErrorCurrent(err).(service.Project)
+		if !ok || current.Version != saved.Version || current.Name != web.Name {
+			t.Errorf("the conflict carries %+v, want the web app's version", service.ErrorCurrent(err))
+		}
+	})
+}
@@ -0,0 +1,191 @@
+// Package sigv4 signs requests to AWS APIs, and to S3-compatible services
+// such as MinIO, with Signature Version 4: in the Authorization header, or
+// in the query string of a pre-signed URL handed to a client.
+package sigv4
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UnsignedPayload stands for the body of pre-signed requests, which is
+// not known when signing
+const UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// Credentials sign requests for Region and Service, such as "ses" or "s3"
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Region          string
+	Service         string
+}
+
+// PayloadHash is the hex SHA-256 of a request body, as signed
+func PayloadHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign adds the signature of req, whose body hashes to payloadHash, in the
+// Authorization header. Host, Content-Type and the X-Amz- headers are
+// signed; other headers set later are not.
+func (c Credentials) Sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	headers := map[string]string{"host": host(req)}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	signedHeaders, signature := c.signature(req.Method, req.URL, headers, payloadHash, now)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+c.AccessKeyID+"/"+c.scope(now)+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// Presign returns the URL of req with its signature in the query string,
+// valid for expires. The client sending the request must send the headers
+// given, such as Content-Type and Content-Length, with the same values.
+func (c Credentials) Presign(req *http.Request, headers map[string]string, expires time.Duration, now time.Time) string {
+	now = now.UTC()
+	signed := map[string]string{"host": host(req)}
+	for name, value := range headers {
+		signed[strings.ToLower(name)] = strings.TrimSpace(value)
+	}
+
+	u := *req.URL
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", c.AccessKeyID+"/"+c.scope(now))
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", strings.Join(sortedKeys(signed), ";"))
+	if c.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+	u.RawQuery = canonicalQuery(query)
+
+	_, signature := c.signature(req.Method, &u, signed, UnsignedPayload, now)
+	u.RawQuery += "&X-Amz-Signature=" + signature
+	return u.String()
+}
+
+// signature computes the signature of a request with the given signed
+// headers, keyed by lower-case name
+func (c Credentials) signature(method string, u *url.URL, headers map[string]string, payloadHash string, now time.Time) (signedHeaders, signature string) {
+	names := sortedKeys(headers)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders = strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		method,
+		canonicalPath(u),
+		canonicalQuery(u.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + c.scope(now) + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, c.Service)
+	key = hmacSHA256(key, "aws4_request")
+	return signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func (c Credentials) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + c.Region + "/" + c.Service + "/aws4_request"
+}
+
+func host(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+// canonicalPath escapes every segment of the path, keeping the slashes
+func canonicalPath(u *url.URL) string {
+	path := u.Path
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = escape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery sorts the parameters by name, then value
+func canonicalQuery(query url.Values) string {
+	escaped := make(map[string][]string, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			escaped[escape(name)] = append(escaped[escape(name)], escape(value))
+		}
+	}
+	names := make([]string, 0, len(escaped))
+	for name := range escaped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pairs []string
+	for _, name := range names {
+		values := escaped[name]
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, name+"="+value)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// escape percent-encodes everything but the unreserved characters of
+// RFC 3986, as AWS expects
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,212 @@
+// Package storage keeps files, such as uploaded avatars, in an
+// S3-compatible bucket: Amazon S3, or MinIO and the like for self-hosters.
+// Clients upload straight to the bucket through pre-signed URLs, so file
+// bodies never pass through the API servers; the server reads and writes
+// objects itself only to process them.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pacerclub/zebra-backend/internal/sigv4"
+)
+
+// ErrNotConfigured is returned when using storage without a bucket
+var ErrNotConfigured = errors.New("storage is not configured")
+
+// ErrNotFound is returned when reading an object that does not exist
+var ErrNotFound = errors.New("object not found")
+
+// ErrTooLarge is returned when reading an object larger than allowed
+var ErrTooLarge = errors.New("object is too large")
+
+// Bucket is a bucket of an S3-compatible service, addressed by path, as in
+// https://minio.example.com/bucket/key
+type Bucket struct {
+	// Endpoint is the service's base URL, such as https://s3.eu-west-1.amazonaws.com
+	Endpoint        string
+	Region          string
+	Name            string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PublicURL is where objects are served from, such as a CDN; by default
+	// the bucket's own URL, which must then allow public reads
+	PublicURL string
+}
+
+var (
+	bucket *Bucket
+	client = &http.Client{Timeout: time.Minute}
+)
+
+// Configure sets the bucket files are kept in; nil turns storage off
+func Configure(b *Bucket) {
+	bucket = b
+}
+
+// LoadEnv configures the bucket in STORAGE_BUCKET at STORAGE_ENDPOINT,
+// with STORAGE_REGION (us-east-1 by default), STORAGE_ACCESS_KEY_ID,
+// STORAGE_SECRET_ACCESS_KEY and STORAGE_PUBLIC_URL. Storage stays off
+// without STORAGE_BUCKET.
+func LoadEnv() error {
+	b := &Bucket{
+		Endpoint:        strings.TrimSuffix(os.Getenv("STORAGE_ENDPOINT"), "/"),
+		Region:          os.Getenv("STORAGE_REGION"),
+		Name:            os.Getenv("STORAGE_BUCKET"),
+		AccessKeyID:     os.Getenv("STORAGE_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("STORAGE_SECRET_ACCESS_KEY"),
+		PublicURL:       strings.TrimSuffix(os.Getenv("STORAGE_PUBLIC_URL"), "/"),
+	}
+	if b.Name == "" {
+		Configure(nil)
+		return nil
+	}
+	if b.Endpoint == "" || b.AccessKeyID == "" || b.SecretAccessKey == "" {
+		return errors.New("STORAGE_ENDPOINT, STORAGE_ACCESS_KEY_ID and STORAGE_SECRET_ACCESS_KEY are required with STORAGE_BUCKET")
+	}
+	if _, err := url.Parse(b.Endpoint); err != nil {
+		return fmt.Errorf("invalid STORAGE_ENDPOINT: %w", err)
+	}
+	if b.Region == "" {
+		b.Region = "us-east-1"
+	}
+	if b.PublicURL == "" {
+		b.PublicURL = b.Endpoint + "/" + b.Name
+	}
+	Configure(b)
+	return nil
+}
+
+// Configured reports whether files can be stored
+func Configured() bool {
+	return bucket != nil
+}
+
+func (b *Bucket) credentials() sigv4.Credentials {
+	return sigv4.Credentials{
+		AccessKeyID:     b.AccessKeyID,
+		SecretAccessKey: b.SecretAccessKey,
+		Region:          b.Region,
+		Service:         "s3",
+	}
+}
+
+func (b *Bucket) objectURL(key string) string {
+	return b.Endpoint + "/" + b.Name + "/" + key
+}
+
+// URL returns the public URL of the object at key
+func URL(key string) string {
+	if bucket == nil {
+		return ""
+	}
+	return bucket.PublicURL + "/" + key
+}
+
+// PresignPut returns a URL a client can PUT one file to key at, valid for
+// expires. The client must send the Content-Type and Content-Length given,
+// so the file has the type and size it was allowed with.
+func PresignPut(key, contentType string, size int64, expires time.Duration) (string, error) {
+	if bucket == nil {
+		return "", ErrNotConfigured
+	}
+	req, err := http.NewRequest(http.MethodPut, bucket.objectURL(key), nil)
+	if err != nil {
+		return "", err
+	}
+	return bucket.credentials().Presign(req, map[string]string{
+		"Content-Type":   contentType,
+		"Content-Length": strconv.FormatInt(size, 10),
+	}, expires, time.Now()), nil
+}
+
+// Get reads the object at key, failing with ErrTooLarge if it is larger
+// than maxBytes
+func Get(ctx context.Context, key string, maxBytes int64) ([]byte, error) {
+	if bucket == nil {
+		return nil, ErrNotConfigured
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bucket.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	bucket.credentials().Sign(req, sigv4.PayloadHash(nil), time.Now())
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, ErrTooLarge
+	}
+	return body, nil
+}
+
+// Put writes body to key, replacing any object there
+func Put(ctx context.Context, key, contentType string, body []byte) error {
+	if bucket == nil {
+		return ErrNotConfigured
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, bucket.objectURL(key), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	bucket.credentials().Sign(req, sigv4.PayloadHash(body), time.Now())
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp)
+}
+
+// Delete removes the object at key; deleting a missing object succeeds
+func Delete(ctx context.Context, key string) error {
+	if bucket == nil {
+		return ErrNotConfigured
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, bucket.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	bucket.credentials().Sign(req, sigv4.PayloadHash(nil), time.Now())
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkStatus(resp)
+}
+
+// checkStatus fails on any status but 2xx, quoting the service's error
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("storage answered %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
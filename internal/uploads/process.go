@@ -0,0 +1,294 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/models"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/internal/storage"
+)
+
+// processBatchSize bounds the uploads processed in one transaction. Each
+// holds a decoded image in memory.
+const processBatchSize = 5
+
+// MaxAttempts is how often processing an upload is tried when storage or
+// the database fail. Invalid images fail on the first attempt.
+var MaxAttempts = 5
+
+// Side of the square images each purpose is resized to, in pixels
+var imageSizes = map[string]int{
+	PurposeAvatar:      256,
+	PurposeProjectIcon: 128,
+}
+
+// maxPixels bounds the images decoded, as a small file can hold a huge
+// image
+const maxPixels = 5000 * 5000
+
+const (
+	minRetryDelay = time.Minute
+	maxRetryDelay = time.Hour
+)
+
+// rejectedError is an upload that can never be processed; its message is
+// shown to the user
+type rejectedError string
+
+func (e rejectedError) Error() string { return string(e) }
+
+// Run processes completed uploads until ctx is done, checking for new ones
+// every pollInterval. Processors on several servers share the uploads, each
+// going to one.
+func Run(ctx context.Context, pollInterval time.Duration) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		for storage.Configured() {
+			n, err := processDue(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("Failed to process uploads", "error", err)
+				}
+				break
+			}
+			if n < processBatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processDue processes the oldest batch of due uploads and records the
+// outcome of each, returning the batch size. The uploads stay locked while
+// they are processed, so other processors skip them.
+func processDue(ctx context.Context) (int, error) {
+	n := 0
+	err := pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT `+uploadColumns+` FROM uploads
+			WHERE status = $1 AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		`, StatusProcessing, processBatchSize)
+		if err != nil {
+			return err
+		}
+		var batch []*Upload
+		for rows.Next() {
+			upload, err := scanUpload(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			batch = append(batch, upload)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		n = len(batch)
+
+		for _, upload := range batch {
+			key, url, processErr := process(ctx, upload)
+			attempts := upload.attempts + 1
+			var rejected rejectedError
+			switch {
+			case processErr == nil:
+				_, err = tx.Exec(ctx, `
+					UPDATE uploads SET status = $2, object_key = $3, url = $4, attempts = $5, error = '', updated_at = CURRENT_TIMESTAMP
+					WHERE id = $1
+				`, upload.ID, StatusReady, key, url, attempts)
+				if err == nil {
+					err = removeReplaced(ctx, tx, upload)
+				}
+			case errors.As(processErr, &rejected) || attempts >= MaxAttempts:
+				slog.Warn("Upload failed", "upload_id", upload.ID, "attempts", attempts, "error", processErr)
+				message := "Failed to process the image"
+				if errors.As(processErr, &rejected) {
+					message = string(rejected)
+				}
+				_, err = tx.Exec(ctx, `
+					UPDATE uploads SET status = $2, attempts = $3, error = $4, updated_at = CURRENT_TIMESTAMP
+					WHERE id = $1
+				`, upload.ID, StatusFailed, attempts, message)
+			default:
+				slog.Warn("Failed to process upload, retrying", "upload_id", upload.ID, "attempts", attempts, "error", processErr)
+				_, err = tx.Exec(ctx, `
+					UPDATE uploads SET next_attempt_at = $2, attempts = $3 WHERE id = $1
+				`, upload.ID, time.Now().Add(retryDelay(attempts)), attempts)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+// process checks and resizes the uploaded image, stores it and sets it as
+// the avatar or icon it is for, returning its key and URL. The original
+// file is removed.
+func process(ctx context.Context, upload *Upload) (key, url string, err error) {
+	body, err := storage.Get(ctx, upload.objectKey, MaxBytes)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", "", rejectedError("The file was not uploaded")
+	}
+	if errors.Is(err, storage.ErrTooLarge) {
+		return "", "", rejectedError("The file is larger than allowed")
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	// The declared type is not trusted; the image is read from its content
+	if !ValidContentType(http.DetectContentType(body)) {
+		return "", "", rejectedError("The file is not a PNG, JPEG or GIF image")
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		return "", "", rejectedError("The image could not be read")
+	}
+	if config.Width*config.Height > maxPixels {
+		return "", "", rejectedError("The image has too many pixels")
+	}
+	img, _, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return "", "", rejectedError("The image could not be read")
+	}
+
+	// Photos stay JPEG; images that may be transparent become PNG
+	var out bytes.Buffer
+	thumb := thumbnail(img, imageSizes[upload.Purpose])
+	contentType, ext := "image/png", ".png"
+	if format == "jpeg" {
+		contentType, ext = "image/jpeg", ".jpg"
+		err = jpeg.Encode(&out, thumb, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(&out, thumb)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	switch upload.Purpose {
+	case PurposeAvatar:
+		key = "avatars/" + upload.UserID.String() + "/" + upload.ID.String() + ext
+	case PurposeProjectIcon:
+		key = "project-icons/" + upload.ProjectID.String() + "/" + upload.ID.String() + ext
+	}
+	if err := storage.Put(ctx, key, contentType, out.Bytes()); err != nil {
+		return "", "", err
+	}
+	url = storage.URL(key)
+
+	switch upload.Purpose {
+	case PurposeAvatar:
+		err = models.SetAvatarURL(ctx, upload.UserID, url)
+	case PurposeProjectIcon:
+		err = service.SetProjectIcon(ctx, upload.UserID, *upload.ProjectID, url)
+		if service.ErrorCode(err) == service.NotFound {
+			err = rejectedError("The project was deleted")
+		}
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := storage.Delete(ctx, upload.objectKey); err != nil {
+		slog.Warn("Failed to delete uploaded file", "upload_id", upload.ID, "error", err)
+	}
+	return key, url, nil
+}
+
+// removeReplaced deletes the images the upload replaced, the earlier ready
+// uploads of the same avatar or icon
+func removeReplaced(ctx context.Context, tx pgx.Tx, upload *Upload) error {
+	owner, ownerID := "user_id", upload.UserID
+	if upload.Purpose == PurposeProjectIcon {
+		owner, ownerID = "project_id", *upload.ProjectID
+	}
+	rows, err := tx.Query(ctx, `
+		DELETE FROM uploads
+		WHERE `+owner+` = $1 AND purpose = $2 AND status = $3 AND id <> $4
+		RETURNING object_key
+	`, ownerID, upload.Purpose, StatusReady, upload.ID)
+	if err != nil {
+		return err
+	}
+	keys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := storage.Delete(ctx, key); err != nil {
+			slog.Warn("Failed to delete replaced image", "key", key, "error", err)
+		}
+	}
+	return nil
+}
+
+// thumbnail crops img to a centered square and scales it down to size
+// pixels a side, averaging the pixels each one covers. Smaller images are
+// only cropped.
+func thumbnail(img image.Image, size int) *image.RGBA {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	src := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(src, src.Bounds(), img, image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2), draw.Src)
+	if side <= size {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0, y1 := y*side/size, (y+1)*side/size
+		for x := 0; x < size; x++ {
+			x0, x1 := x*side/size, (x+1)*side/size
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			pixel := dst.Pix[y*dst.Stride+x*4:]
+			for c := range sum {
+				pixel[c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
+
+// retryDelay is how long to wait after the given number of failed attempts
+func retryDelay(attempts int) time.Duration {
+	delay := minRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
@@ -0,0 +1,147 @@
+// Package uploads lets users upload images, such as their avatar and the
+// icons of projects, straight to storage. Create records the upload and
+// returns a pre-signed URL the client PUTs the file to; once the client
+// completes the upload, Run checks and resizes the image in the background
+// and puts it in place.
+package uploads
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/storage"
+)
+
+var ErrNotFound = errors.New("upload not found")
+
+// What uploads are for
+const (
+	PurposeAvatar      = "avatar"
+	PurposeProjectIcon = "project_icon"
+)
+
+// Purposes lists what files can be uploaded for
+var Purposes = []string{PurposeAvatar, PurposeProjectIcon}
+
+// Upload statuses
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusReady      = "ready"
+	StatusFailed     = "failed"
+)
+
+// ContentTypes are the types of image that can be uploaded
+var ContentTypes = []string{"image/png", "image/jpeg", "image/gif"}
+
+// MaxBytes is the size of the largest file that can be uploaded
+var MaxBytes int64 = 5 << 20
+
+// URLExpiry is how long clients have to upload a file once given its URL
+const URLExpiry = 15 * time.Minute
+
+// Upload is one file a user uploaded, or is about to
+type Upload struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Purpose     string     `json:"purpose"`
+	ProjectID   *uuid.UUID `json:"project_id,omitempty"`
+	ContentType string     `json:"content_type"`
+	Size        int64      `json:"size"`
+	Status      string     `json:"status"`
+	// Error says why a failed upload was rejected
+	Error string `json:"error,omitempty"`
+	// URL is where the processed image is served from, once ready
+	URL       string    `json:"url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// objectKey is where the uploaded file is kept, and once ready where
+	// the processed image is
+	objectKey string
+	attempts  int
+}
+
+const uploadColumns = `id, user_id, purpose, project_id, content_type, size, status, error, url, created_at, updated_at,
+	object_key, attempts`
+
+func scanUpload(row pgx.Row) (*Upload, error) {
+	var u Upload
+	err := row.Scan(&u.ID, &u.UserID, &u.Purpose, &u.ProjectID, &u.ContentType, &u.Size, &u.Status, &u.Error,
+		&u.URL, &u.CreatedAt, &u.UpdatedAt, &u.objectKey, &u.attempts)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// ValidPurpose reports whether files can be uploaded for purpose
+func ValidPurpose(purpose string) bool {
+	for _, p := range Purposes {
+		if p == purpose {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidContentType reports whether files of contentType can be uploaded
+func ValidContentType(contentType string) bool {
+	for _, t := range ContentTypes {
+		if t == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// Create records a pending upload for the user, projectID being set for
+// project icons only, and returns it with the URL to PUT the file to. The
+// caller checks the user may change what the upload is for.
+func Create(ctx context.Context, userID uuid.UUID, purpose string, projectID *uuid.UUID, contentType string, size int64) (*Upload, string, error) {
+	id := uuid.New()
+	key := "uploads/" + userID.String() + "/" + id.String()
+	uploadURL, err := storage.PresignPut(key, contentType, size, URLExpiry)
+	if err != nil {
+		return nil, "", err
+	}
+	upload, err := scanUpload(db.Pool.QueryRow(ctx, `
+		INSERT INTO uploads (id, user_id, purpose, project_id, object_key, content_type, size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+uploadColumns,
+		id, userID, purpose, projectID, key, contentType, size))
+	if err != nil {
+		return nil, "", err
+	}
+	return upload, uploadURL, nil
+}
+
+// Get returns one of the user's uploads
+func Get(ctx context.Context, userID, uploadID uuid.UUID) (*Upload, error) {
+	upload, err := scanUpload(db.Pool.QueryRow(ctx,
+		`SELECT `+uploadColumns+` FROM uploads WHERE id = $1 AND user_id = $2`,
+		uploadID, userID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return upload, err
+}
+
+// Complete queues a pending upload for processing, once the client has
+// uploaded the file. Completing an upload again returns it unchanged.
+func Complete(ctx context.Context, userID, uploadID uuid.UUID) (*Upload, error) {
+	upload, err := scanUpload(db.Pool.QueryRow(ctx, `
+		UPDATE uploads
+		SET status = $3, next_attempt_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2 AND status = $4
+		RETURNING `+uploadColumns,
+		uploadID, userID, StatusProcessing, StatusPending))
+	if err == pgx.ErrNoRows {
+		return Get(ctx, userID, uploadID)
+	}
+	return upload, err
+}
@@ -0,0 +1,54 @@
+package usage
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+)
+
+// Middleware records the usage of authenticated requests. It must run after
+// auth.Middleware. Bodies are counted as the handlers see them, after
+// request decompression and before response compression.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := auth.GetUserIDFromContext(r.Context())
+		if userID == uuid.Nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body := &countingReader{ReadCloser: r.Body}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = body
+		}
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer func() {
+			Record(userID, requestKind(r), body.n, int64(ww.BytesWritten()))
+		}()
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// requestKind tells sync requests from the rest of the API
+func requestKind(r *http.Request) string {
+	if strings.Contains(r.URL.Path, "/auth/sync") {
+		return KindSync
+	}
+	return KindAPI
+}
+
+// countingReader counts the bytes read from a request body
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,210 @@
+// Package usage counts the API and sync requests each user makes and the
+// bytes of their bodies, rolled up per UTC day, so users can see their
+// consumption. Counts are kept in memory and written every flush interval,
+// so the last minute or so of usage may not show yet.
+package usage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+)
+
+// Kinds of request
+const (
+	KindAPI  = "api"
+	KindSync = "sync"
+)
+
+// MaxDays caps how many days of usage one request may ask for
+const MaxDays = 90
+
+type key struct {
+	userID uuid.UUID
+	day    time.Time
+	kind   string
+}
+
+type counts struct {
+	requests, bytesIn, bytesOut int64
+}
+
+// pending holds the usage not yet written
+var pending struct {
+	sync.Mutex
+	counts map[key]*counts
+}
+
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// Record counts one request of the user, of kind, with bodies of bytesIn
+// and bytesOut bytes
+func Record(userID uuid.UUID, kind string, bytesIn, bytesOut int64) {
+	k := key{userID: userID, day: today(), kind: kind}
+	pending.Lock()
+	defer pending.Unlock()
+	if pending.counts == nil {
+		pending.counts = make(map[key]*counts)
+	}
+	c := pending.counts[k]
+	if c == nil {
+		c = &counts{}
+		pending.counts[k] = c
+	}
+	c.requests++
+	c.bytesIn += bytesIn
+	c.bytesOut += bytesOut
+}
+
+// Flush writes the usage recorded so far. Usage that could not be written
+// is kept for the next flush.
+func Flush(ctx context.Context) error {
+	pending.Lock()
+	flushing := pending.counts
+	pending.counts = nil
+	pending.Unlock()
+	if len(flushing) == 0 {
+		return nil
+	}
+
+	err := pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+		for k, c := range flushing {
+			// Usage of users deleted in the meantime is dropped
+			batch.Queue(`
+				INSERT INTO api_usage (user_id, day, kind, requests, bytes_in, bytes_out)
+				SELECT $1, $2, $3, $4, $5, $6 WHERE EXISTS (SELECT 1 FROM users WHERE id = $1)
+				ON CONFLICT (user_id, day, kind) DO UPDATE SET
+					requests = api_usage.requests + EXCLUDED.requests,
+					bytes_in = api_usage.bytes_in + EXCLUDED.bytes_in,
+					bytes_out = api_usage.bytes_out + EXCLUDED.bytes_out
+			`, k.userID, k.day, k.kind, c.requests, c.bytesIn, c.bytesOut)
+		}
+		return tx.SendBatch(ctx, batch).Close()
+	})
+	if err != nil {
+		pending.Lock()
+		if pending.counts == nil {
+			pending.counts = make(map[key]*counts)
+		}
+		for k, c := range flushing {
+			if p := pending.counts[k]; p != nil {
+				p.requests += c.requests
+				p.bytesIn += c.bytesIn
+				p.bytesOut += c.bytesOut
+			} else {
+				pending.counts[k] = c
+			}
+		}
+		pending.Unlock()
+		return fmt.Errorf("error writing API usage: %v", err)
+	}
+	return nil
+}
+
+// Run writes the recorded usage every interval until ctx is done. What is
+// left at shutdown is written by a last Flush once requests have drained.
+func Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := Flush(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Failed to record API usage", "error", err)
+		}
+	}
+}
+
+// Prune deletes the usage of days older than retention
+func Prune(ctx context.Context, retention time.Duration) error {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM api_usage WHERE day < $1`, today().Add(-retention))
+	if err != nil {
+		return fmt.Errorf("error pruning API usage: %v", err)
+	}
+	if tag.RowsAffected() > 0 {
+		slog.Info("Pruned API usage", "rows", tag.RowsAffected())
+	}
+	return nil
+}
+
+// Counts is the usage of one kind of request
+type Counts struct {
+	Requests int64 `json:"requests"`
+	BytesIn  int64 `json:"bytes_in"`
+	BytesOut int64 `json:"bytes_out"`
+}
+
+// Day is the user's usage on one UTC day
+type Day struct {
+	Date string `json:"date"`
+	API  Counts `json:"api"`
+	Sync Counts `json:"sync"`
+}
+
+// Report is the user's usage over a period, with one entry per day oldest
+// first
+type Report struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	API   Counts `json:"api"`
+	Sync  Counts `json:"sync"`
+	Days  []Day  `json:"days"`
+}
+
+// Get returns the user's usage over the last days days, today included
+func Get(ctx context.Context, userID uuid.UUID, days int) (*Report, error) {
+	end := today()
+	start := end.AddDate(0, 0, -(days - 1))
+	report := &Report{Start: start.Format("2006-01-02"), End: end.Format("2006-01-02"), Days: make([]Day, days)}
+	for i := range report.Days {
+		report.Days[i].Date = start.AddDate(0, 0, i).Format("2006-01-02")
+	}
+
+	rows, err := db.ReadPool(ctx).Query(ctx, `
+		SELECT day, kind, requests, bytes_in, bytes_out FROM api_usage
+		WHERE user_id = $1 AND day BETWEEN $2 AND $3
+	`, userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var date time.Time
+		var kind string
+		var c Counts
+		if err := rows.Scan(&date, &kind, &c.Requests, &c.BytesIn, &c.BytesOut); err != nil {
+			return nil, err
+		}
+		i := int(date.Sub(start).Hours() / 24)
+		if i < 0 || i >= days {
+			continue
+		}
+		switch kind {
+		case KindAPI:
+			report.Days[i].API = c
+			report.API.add(c)
+		case KindSync:
+			report.Days[i].Sync = c
+			report.Sync.add(c)
+		}
+	}
+	return report, rows.Err()
+}
+
+func (c *Counts) add(other Counts) {
+	c.Requests += other.Requests
+	c.BytesIn += other.BytesIn
+	c.BytesOut += other.BytesOut
+}
@@ -0,0 +1,169 @@
+// Package validate checks decoded request bodies field by field. Request
+// types implement Validatable; the handlers run it on every body they decode
+// and report all failing fields at once instead of storing bad data.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Validatable is implemented by types that check their own fields. Validate
+// may normalize fields, such as trimming spaces, before checking them.
+type Validatable interface {
+	Validate(v *Validator)
+}
+
+// FieldError is one failing field. Field is the path of the field in the
+// body, such as "name" or "[2].end_time".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors lists every failing field of a value
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validator collects the field errors of one value
+type Validator struct {
+	prefix string
+	errors *Errors
+}
+
+// New returns an empty validator
+func New() *Validator {
+	return &Validator{errors: &Errors{}}
+}
+
+// Struct runs the Validate method of value, or of each element if value is a
+// slice, and returns the field errors found. Values that are not Validatable
+// pass.
+func Struct(value interface{}) error {
+	v := New()
+	v.Value("", value)
+	return v.Err()
+}
+
+// Value validates a nested value under field
+func (v *Validator) Value(field string, value interface{}) {
+	if validatable, ok := value.(Validatable); ok {
+		validatable.Validate(v.at(field))
+		return
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			v.Value(fmt.Sprintf("%s[%d]", field, i), rv.Index(i).Addr().Interface())
+		}
+	}
+}
+
+// at returns a validator whose fields are nested under field
+func (v *Validator) at(field string) *Validator {
+	return &Validator{prefix: v.path(field), errors: v.errors}
+}
+
+func (v *Validator) path(field string) string {
+	switch {
+	case v.prefix == "":
+		return field
+	case field == "" || strings.HasPrefix(field, "["):
+		return v.prefix + field
+	}
+	return v.prefix + "." + field
+}
+
+// Err returns the field errors found so far, nil if there are none
+func (v *Validator) Err() error {
+	if len(*v.errors) == 0 {
+		return nil
+	}
+	return *v.errors
+}
+
+// Fail records an error for field. The message follows the field's path,
+// as in "name is required".
+func (v *Validator) Fail(field, format string, args ...interface{}) {
+	path := v.path(field)
+	*v.errors = append(*v.errors, FieldError{Field: path, Message: path + " " + fmt.Sprintf(format, args...)})
+}
+
+// Check records message for field unless ok
+func (v *Validator) Check(ok bool, field, message string) {
+	if !ok {
+		v.Fail(field, "%s", message)
+	}
+}
+
+// Required checks that a string field is not blank
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, "is required")
+}
+
+// MaxLength checks that a string field has at most max characters
+func (v *Validator) MaxLength(field, value string, max int) {
+	v.Check(utf8.RuneCountInString(value) <= max, field, fmt.Sprintf("must be at most %d characters", max))
+}
+
+// Email checks that a field holds a bare email address. Blank values pass;
+// combine with Required where the address is mandatory.
+func (v *Validator) Email(field, value string) {
+	if value == "" {
+		return
+	}
+	address, err := mail.ParseAddress(value)
+	v.Check(err == nil && address.Address == value, field, "must be an email address")
+}
+
+// MaxBytes checks that a string field is at most max bytes long, for
+// identifiers stored in columns sized in bytes
+func (v *Validator) MaxBytes(field, value string, max int) {
+	v.Check(len(value) <= max, field, fmt.Sprintf("must be at most %d bytes", max))
+}
+
+var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// ValidColor reports whether s is a hex color like #1a2b3c
+func ValidColor(s string) bool {
+	return colorPattern.MatchString(s)
+}
+
+// Color checks that a field holds a hex color like #1a2b3c
+func (v *Validator) Color(field, value string) {
+	v.Check(ValidColor(value), field, "must be a hex value like #1a2b3c")
+}
+
+// UUID checks that a UUID field is set
+func (v *Validator) UUID(field string, value uuid.UUID) {
+	v.Check(value != uuid.Nil, field, "is required")
+}
+
+// TimeRange checks that both times are set and end is not before start
+func (v *Validator) TimeRange(startField, endField string, start, end time.Time) {
+	switch {
+	case start.IsZero():
+		v.Fail(startField, "is required")
+	case end.IsZero():
+		v.Fail(endField, "is required")
+	case end.Before(start):
+		v.Fail(endField, "must not be before %s", v.path(startField))
+	}
+}
@@ -0,0 +1,161 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+	"github.com/pacerclub/zebra-backend/internal/pagination"
+)
+
+var ErrDeliveryNotFound = errors.New("delivery not found")
+
+// Delivery statuses
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// MaxResponseBody bounds how much of a target's answer a delivery keeps
+const MaxResponseBody = 1024
+
+// Delivery is one attempt to post an event to a webhook
+type Delivery struct {
+	ID        uuid.UUID `json:"id"`
+	WebhookID uuid.UUID `json:"webhook_id"`
+	Event     string    `json:"event"`
+	// Payload is the body posted, as signed
+	Payload json.RawMessage `json:"payload"`
+	// Status is StatusSucceeded when the target answered 2xx
+	Status string `json:"status"`
+	// StatusCode is the target's answer, nil if it could not be reached
+	StatusCode *int `json:"status_code"`
+	// Error is why the target could not be reached
+	Error string `json:"error,omitempty"`
+	// ResponseBody is the first MaxResponseBody bytes of the answer
+	ResponseBody string `json:"response_body,omitempty"`
+	DurationMS   int64  `json:"duration_ms"`
+	// RetryOf is the delivery this one redelivered, if any
+	RetryOf   *uuid.UUID `json:"retry_of,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func deliveryStatus(statusCode *int) string {
+	if statusCode != nil && *statusCode >= 200 && *statusCode < 300 {
+		return StatusSucceeded
+	}
+	return StatusFailed
+}
+
+// record stores a delivery of one of the user's webhooks
+func record(ctx context.Context, userID uuid.UUID, d Delivery) error {
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO webhook_deliveries (id, webhook_id, user_id, event, payload, status_code, error, response_body, duration_ms, retry_of, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, d.ID, d.WebhookID, userID, d.Event, []byte(d.Payload), d.StatusCode, d.Error, d.ResponseBody, d.DurationMS, d.RetryOf, d.CreatedAt)
+	return err
+}
+
+// DeliveryListing is how delivery lists can be sorted, by default newest
+// first
+var DeliveryListing = listquery.List[Delivery]{
+	Sorts: map[string]listquery.Column[Delivery]{
+		"created_at": {SQL: "created_at", Key: func(d Delivery) time.Time { return d.CreatedAt }},
+	},
+	Default: listquery.Sort{Field: "created_at", Desc: true},
+	RowID:   func(d Delivery) uuid.UUID { return d.ID },
+}
+
+// ListDeliveries returns a page of the deliveries of one of the user's
+// webhooks
+func ListDeliveries(ctx context.Context, userID, webhookID uuid.UUID, q listquery.Query) (pagination.Page[Delivery], error) {
+	var exists bool
+	err := db.Pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM webhooks WHERE id = $1 AND user_id = $2)`,
+		webhookID, userID).Scan(&exists)
+	if err != nil {
+		return pagination.Page[Delivery]{}, err
+	}
+	if !exists {
+		return pagination.Page[Delivery]{}, ErrWebhookNotFound
+	}
+
+	where, orderBy := DeliveryListing.SQL(q, 2)
+	args := append([]interface{}{webhookID}, q.Args()...)
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, webhook_id, event, payload, status_code, error, response_body, duration_ms, retry_of, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND `+where+`
+		ORDER BY `+orderBy+`
+		LIMIT $4
+	`, append(args, q.Page.Fetch())...)
+	if err != nil {
+		return pagination.Page[Delivery]{}, err
+	}
+	defer rows.Close()
+
+	var deliveries []Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return pagination.Page[Delivery]{}, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return pagination.Page[Delivery]{}, err
+	}
+	return DeliveryListing.Page(deliveries, q), nil
+}
+
+func scanDelivery(row pgx.Row) (Delivery, error) {
+	var d Delivery
+	var payload []byte
+	err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.StatusCode, &d.Error, &d.ResponseBody,
+		&d.DurationMS, &d.RetryOf, &d.CreatedAt)
+	d.Payload = payload
+	d.Status = deliveryStatus(d.StatusCode)
+	return d, err
+}
+
+// Redeliver posts the payload of one of the user's deliveries to its
+// webhook again, with a fresh signature, and returns the new delivery. It
+// is recorded like any other, whatever the answer.
+func Redeliver(ctx context.Context, userID, deliveryID uuid.UUID) (Delivery, error) {
+	t := target{userID: userID}
+	var event string
+	var payload []byte
+	err := db.Pool.QueryRow(ctx, `
+		SELECT d.event, d.payload, w.id, w.target_url, w.secret
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.id = $1 AND d.user_id = $2
+	`, deliveryID, userID).Scan(&event, &payload, &t.id, &t.url, &t.secret)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return Delivery{}, err
+	}
+	return attempt(ctx, t, event, payload, &deliveryID), nil
+}
+
+// PruneDeliveries deletes the deliveries made more than retention ago
+func PruneDeliveries(ctx context.Context, retention time.Duration) error {
+	result, err := db.Pool.Exec(ctx, `
+		DELETE FROM webhook_deliveries WHERE created_at < $1
+	`, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	if n := result.RowsAffected(); n > 0 {
+		slog.Info("Pruned webhook deliveries", "deleted", n)
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/listquery"
+	"github.com/pacerclub/zebra-backend/internal/opstats"
+	"github.com/pacerclub/zebra-backend/internal/outbox"
+	"github.com/pacerclub/zebra-backend/internal/pagination"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// Events that can be subscribed to
+const (
+	EventSessionCreated = "session.created"
+	EventSessionUpdated = "session.updated"
+	EventSessionDeleted = "session.deleted"
+	EventProjectCreated = "project.created"
+	EventProjectUpdated = "project.updated"
+	EventProjectDeleted = "project.deleted"
+	// EventNotificationSent carries the user's notifications on the webhook
+	// channel; see package notify
+	EventNotificationSent = "notification.sent"
+)
+
+// Events lists every event in the order they are documented
+var Events = []string{
+	EventSessionCreated,
+	EventSessionUpdated,
+	EventSessionDeleted,
+	EventProjectCreated,
+	EventProjectUpdated,
+	EventProjectDeleted,
+	EventNotificationSent,
+}
+
+// MaxPerUser caps how many webhooks a user can subscribe
+const MaxPerUser = 50
+
+// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the body,
+// keyed with the webhook's secret
+const SignatureHeader = "X-Zebra-Signature"
+
+// EventHeader names the event a delivery is for
+const EventHeader = "X-Zebra-Event"
+
+// DeletedPayload is sent for deletion events
+type DeletedPayload struct {
+	ID        uuid.UUID `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// Webhook is a URL that receives one event of a user's
+type Webhook struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	TargetURL string    `json:"target_url"`
+	Event     string    `json:"event"`
+	// Secret is only returned when the webhook is created
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ValidEvent reports whether event can be subscribed to
+func ValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Create subscribes targetURL to the user's event
+func Create(ctx context.Context, userID uuid.UUID, targetURL, event string) (*Webhook, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	w := Webhook{UserID: userID, TargetURL: targetURL, Event: event, Secret: secret}
+	err = db.Pool.QueryRow(ctx, `
+		INSERT INTO webhooks (id, user_id, target_url, event, secret)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, uuid.New(), userID, targetURL, event, secret).Scan(&w.ID, &w.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// Count returns how many webhooks the user has
+func Count(ctx context.Context, userID uuid.UUID) (int, error) {
+	var n int
+	err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM webhooks WHERE user_id = $1`, userID).Scan(&n)
+	return n, err
+}
+
+// Listing is how webhook lists can be sorted, by default oldest first
+var Listing = listquery.List[Webhook]{
+	Sorts: map[string]listquery.Column[Webhook]{
+		"created_at": {SQL: "created_at", Key: func(w Webhook) time.Time { return w.CreatedAt }},
+	},
+	Default: listquery.Sort{Field: "created_at"},
+	RowID:   func(w Webhook) uuid.UUID { return w.ID },
+}
+
+// List returns a page of the user's webhooks without their secrets
+func List(ctx context.Context, userID uuid.UUID, q listquery.Query) (pagination.Page[Webhook], error) {
+	where, orderBy := Listing.SQL(q, 2)
+	args := append([]interface{}{userID}, q.Args()...)
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, user_id, target_url, event, created_at
+		FROM webhooks
+		WHERE user_id = $1 AND `+where+`
+		ORDER BY `+orderBy+`
+		LIMIT $4
+	`, append(args, q.Page.Fetch())...)
+	if err != nil {
+		return pagination.Page[Webhook]{}, err
+	}
+	defer rows.Close()
+
+	var hooks []Webhook
+	for rows.Next() {
+		var w Webhook
+		if err := rows.Scan(&w.ID, &w.UserID, &w.TargetURL, &w.Event, &w.CreatedAt); err != nil {
+			return pagination.Page[Webhook]{}, err
+		}
+		hooks = append(hooks, w)
+	}
+	if err := rows.Err(); err != nil {
+		return pagination.Page[Webhook]{}, err
+	}
+	return Listing.Page(hooks, q), nil
+}
+
+// Delete unsubscribes one of the user's webhooks
+func Delete(ctx context.Context, userID, webhookID uuid.UUID) error {
+	result, err := db.Pool.Exec(ctx, `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, webhookID, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Deliver posts an outbox event to the user's webhooks for it. It is the
+// outbox handler of webhooks; changes record their events with outbox.Add.
+// A target answering 410 Gone is unsubscribed, as REST hook consumers such
+// as Zapier expect; other failures are recorded in the delivery log and
+// dropped. Only failing to look up the webhooks returns an error, so that
+// the event is dispatched again.
+func Deliver(ctx context.Context, event outbox.Event) error {
+	return Send(ctx, event.UserID, event.Type, event.Payload)
+}
+
+// target is a webhook as deliveries need it
+type target struct {
+	id     uuid.UUID
+	userID uuid.UUID
+	url    string
+	secret string
+}
+
+// Send posts payload to the user's webhooks for event, as Deliver does for
+// outbox events
+func Send(ctx context.Context, userID uuid.UUID, event string, payload []byte) error {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id, target_url, secret FROM webhooks WHERE user_id = $1 AND event = $2`,
+		userID, event)
+	if err != nil {
+		return err
+	}
+	var targets []target
+	for rows.Next() {
+		t := target{userID: userID}
+		if err := rows.Scan(&t.id, &t.url, &t.secret); err != nil {
+			rows.Close()
+			return err
+		}
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var failures int64
+	for _, t := range targets {
+		if d := attempt(ctx, t, event, payload, nil); d.Status != StatusSucceeded {
+			failures++
+		}
+	}
+	opstats.Add(ctx, opstats.WebhookDeliveries, int64(len(targets)))
+	opstats.Add(ctx, opstats.WebhookFailures, failures)
+	return nil
+}
+
+// attempt posts payload to a webhook and records the delivery. A target
+// answering 410 Gone is unsubscribed, taking its deliveries with it.
+func attempt(ctx context.Context, t target, event string, payload []byte, retryOf *uuid.UUID) Delivery {
+	started := time.Now()
+	status, body, err := deliver(ctx, t.url, t.secret, event, payload)
+	d := Delivery{
+		ID:         uuid.New(),
+		WebhookID:  t.id,
+		Event:      event,
+		Payload:    payload,
+		DurationMS: time.Since(started).Milliseconds(),
+		RetryOf:    retryOf,
+		CreatedAt:  started,
+	}
+	if err != nil {
+		slog.Warn("Webhook delivery failed", "webhook_id", t.id, "event", event, "error", err)
+		d.Error = err.Error()
+	} else {
+		d.StatusCode = &status
+		d.ResponseBody = body
+	}
+	d.Status = deliveryStatus(d.StatusCode)
+
+	// The attempt is recorded even if the caller went away during it
+	if err := record(context.WithoutCancel(ctx), t.userID, d); err != nil {
+		slog.Error("Failed to record webhook delivery", "webhook_id", t.id, "error", err)
+	}
+	if status == http.StatusGone {
+		if _, err := db.Pool.Exec(ctx, `DELETE FROM webhooks WHERE id = $1`, t.id); err != nil {
+			slog.Error("Failed to unsubscribe webhook", "webhook_id", t.id, "error", err)
+		}
+	}
+	return d
+}
+
+// deliver posts one event and returns the response status with the start
+// of the response body
+func deliver(ctx context.Context, targetURL, secret, event string, body []byte) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(SignatureHeader, Sign(secret, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	// An answer that cannot be read is left out. Postgres text holds valid
+	// UTF-8 without NUL bytes, so the rest is dropped.
+	answer, _ := io.ReadAll(io.LimitReader(resp.Body, MaxResponseBody))
+	return resp.StatusCode, strings.ToValidUTF8(strings.ReplaceAll(string(answer), "\x00", ""), ""), nil
+}
+
+// Sign returns the signature of body sent in SignatureHeader
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,114 @@
+// Package zebratest runs the Zebra API on a random local port with its
+// sessions, projects and users in memory, for client SDKs and integration
+// tests to run against without Postgres:
+//
+//	srv := zebratest.NewServer()
+//	defer srv.Close()
+//	resp, err := http.Post(srv.URL+"/api/v1/auth/register", "application/json", body)
+//
+// The server is the router of cmd/api, with its root middleware and route
+// table, over the repositories of package memstore. What works without
+// Postgres is registration, login, the session and project routes, and the
+// sync of sessions and projects, with the differences memstore lists.
+// Tokens are refused once their user is gone or deactivated.
+//
+// Every other route still needs Postgres and answers 500: reports, live
+// streams, webhooks, tracking rules, the sync of tags, tasks, templates,
+// preferences and time off, and sync reset and status. The audit log,
+// login history and usage counts, which the API writes on the side, are
+// not kept. Rate limits are high enough for tests not to meet them.
+package zebratest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pacerclub/zebra-backend/internal/corspolicy"
+	"github.com/pacerclub/zebra-backend/internal/db"
+	"github.com/pacerclub/zebra-backend/internal/memstore"
+	"github.com/pacerclub/zebra-backend/internal/ratelimit"
+	"github.com/pacerclub/zebra-backend/internal/routes"
+	"github.com/pacerclub/zebra-backend/internal/scheduler"
+)
+
+// Server is a running in-memory API. The service keeps its data in
+// package variables, so a process runs one Server at a time.
+type Server struct {
+	*httptest.Server
+	restore func()
+}
+
+// NewServer starts a server with no data. It must be closed to stop it.
+func NewServer() *Server {
+	restoreRepos := memstore.New().Install()
+	pool := db.Pool
+	db.Pool = offlinePool()
+	return &Server{
+		Server: httptest.NewServer(Handler()),
+		restore: func() {
+			db.Pool.Close()
+			db.Pool = pool
+			restoreRepos()
+		},
+	}
+}
+
+// Close stops the server and restores the Postgres repositories and pool
+func (s *Server) Close() {
+	s.Server.Close()
+	s.restore()
+}
+
+// Handler returns the API as the server of cmd/api routes it, under
+// /api/v1 and the deprecated /api, allowing the default CORS origins. It
+// serves whatever repositories are installed in package service.
+func Handler() http.Handler {
+	origins, err := corspolicy.ParseOrigins(routes.DefaultOrigins)
+	if err != nil {
+		panic(err)
+	}
+	return routes.New(limiters(), routes.Options{
+		CORS:  routes.CORSPolicy(origins),
+		Tasks: &scheduler.Scheduler{},
+	})
+}
+
+// limiters returns limiters no test is expected to reach
+func limiters() routes.Limiters {
+	store := ratelimit.NewMemoryStore(10000)
+	limiter := func(name string) *ratelimit.Limiter {
+		return ratelimit.New(store, name, 60000, 10000)
+	}
+	return routes.Limiters{
+		IP:         limiter("ip"),
+		User:       limiter("user"),
+		Reports:    limiter("reports"),
+		SyncDevice: limiter("sync-device"),
+		SyncUser:   limiter("sync-user"),
+		Kiosk:      limiter("kiosk"),
+	}
+}
+
+// errNoDatabase is the error of every query the server makes to Postgres
+var errNoDatabase = errors.New("zebratest has no database")
+
+// offlinePool returns a pool whose connections all fail, so the code
+// still needing Postgres gets an error instead of a nil pool
+func offlinePool() *pgxpool.Pool {
+	config, err := pgxpool.ParseConfig("postgres://zebratest@127.0.0.1/zebratest?sslmode=disable")
+	if err != nil {
+		panic(err)
+	}
+	config.ConnConfig.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, errNoDatabase
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		panic(err)
+	}
+	return pool
+}
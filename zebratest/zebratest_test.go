@@ -0,0 +1,142 @@
+package zebratest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pacerclub/zebra-backend/internal/auth"
+	"github.com/pacerclub/zebra-backend/internal/service"
+	"github.com/pacerclub/zebra-backend/zebratest"
+)
+
+// client calls the API of a test server as a signed in user
+type client struct {
+	t     *testing.T
+	url   string
+	token string
+}
+
+// do sends body as JSON and decodes the response into out, failing the
+// test unless the status is want
+func (c *client) do(method, path string, body, out interface{}, want int) {
+	c.t.Helper()
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	req, err := http.NewRequest(method, c.url+path, bytes.NewReader(encoded))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != want {
+		var problem bytes.Buffer
+		problem.ReadFrom(resp.Body)
+		c.t.Fatalf("%s %s answered %d, want %d: %s", method, path, resp.StatusCode, want, problem.String())
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			c.t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+}
+
+func TestRegisterLoginCreateSync(t *testing.T) {
+	srv := zebratest.NewServer()
+	defer srv.Close()
+	c := &client{t: t, url: srv.URL + "/api/v1"}
+
+	credentials := map[string]string{"email": "ada@example.com", "password": "correct horse battery"}
+	c.do("POST", "/auth/register", credentials, nil, http.StatusOK)
+	var login struct {
+		Token string `json:"token"`
+	}
+	credentials["device_id"] = "laptop"
+	c.do("POST", "/auth/login", credentials, &login, http.StatusOK)
+	if login.Token == "" {
+		t.Fatal("login returned no token")
+	}
+	c.token = login.Token
+
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	var created service.Session
+	c.do("POST", "/auth/sessions", service.Session{StartTime: start, EndTime: start.Add(time.Hour), Description: "Writing"}, &created, http.StatusOK)
+
+	// A phone syncing for the first time gets the laptop's session and
+	// uploads its own
+	phoneSession := service.Session{ID: uuid.New(), StartTime: start.Add(2 * time.Hour), EndTime: start.Add(3 * time.Hour), Description: "Reading"}
+	var synced service.SyncResponse
+	c.do("POST", "/auth/sync", service.SyncRequest{DeviceID: "phone", LocalSessions: []service.Session{phoneSession}}, &synced, http.StatusOK)
+	if len(synced.Rejected) > 0 {
+		t.Fatalf("sync rejected %+v", synced.Rejected)
+	}
+	if len(synced.ServerSessions) != 1 || synced.ServerSessions[0].ID != created.ID {
+		t.Fatalf("sync returned %+v, want only the created session", synced.ServerSessions)
+	}
+
+	// Syncing again from the cursor returned gets nothing new
+	var again service.SyncResponse
+	c.do("POST", "/auth/sync", service.SyncRequest{DeviceID: "phone", LastSyncTime: synced.LastSyncTime}, &again, http.StatusOK)
+	if len(again.ServerSessions) != 0 {
+		t.Errorf("syncing again returned %+v, want no sessions", again.ServerSessions)
+	}
+
+	var page struct {
+		Data []service.Session `json:"data"`
+	}
+	c.do("GET", "/auth/sessions", nil, &page, http.StatusOK)
+	if len(page.Data) != 2 {
+		t.Fatalf("listed %d sessions, want the created and the synced one", len(page.Data))
+	}
+
+	// The update moved the version on, so repeating it is refused
+	created.Description = "Editing"
+	c.do("PUT", "/auth/sessions/"+created.ID.String(), created, nil, http.StatusOK)
+	c.do("PUT", "/auth/sessions/"+created.ID.String(), created, nil, http.StatusConflict)
+}
+
+func TestTokensOfUnknownUsersAreRefused(t *testing.T) {
+	srv := zebratest.NewServer()
+	defer srv.Close()
+	token, err := auth.GenerateToken(uuid.New(), "gone@example.com", "laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &client{t: t, url: srv.URL + "/api/v1", token: token}
+
+	c.do("GET", "/auth/sessions", nil, nil, http.StatusUnauthorized)
+}
+
+func TestServesTheRootMiddlewareOfTheAPI(t *testing.T) {
+	srv := zebratest.NewServer()
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", srv.URL+"/api/v1/auth/sessions", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Origin", "http://localhost:3000")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "http://localhost:3000" {
+		t.Errorf("a request from the web app was allowed for %q", got)
+	}
+	if resp.Header.Get("X-RateLimit-Limit") == "" {
+		t.Error("the response has no rate limit headers")
+	}
+}